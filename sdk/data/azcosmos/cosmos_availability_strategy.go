@@ -0,0 +1,197 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+)
+
+// AvailabilityStrategy reduces the tail latency of reads and queries on items in accounts with several regions.
+// When the first region doesn't respond within Threshold, the request is also sent to the next region of
+// ClientOptions.PreferredRegions, and the first final response is returned while the other requests are canceled.
+// Requests sent to other regions are recorded in the diagnostics of the operation.
+type AvailabilityStrategy struct {
+	// Threshold is the time to wait for a response before sending the request to the next preferred region.
+	Threshold time.Duration
+	// ThresholdStep is the time to wait before sending the request to each following preferred region.
+	// When zero, the request is sent to the next preferred region only.
+	ThresholdStep time.Duration
+}
+
+// maxRequests returns the maximum number of concurrent requests of an operation, given the number of read regions.
+func (s *AvailabilityStrategy) maxRequests(regions int) int {
+	if s.ThresholdStep <= 0 {
+		return min(regions, 2)
+	}
+	return regions
+}
+
+type hedgedResult struct {
+	response *http.Response
+	err      error
+	cancel   context.CancelFunc
+}
+
+// doWithAvailabilityStrategy sends a request through the pipeline, applying the availability strategy of the client
+// to reads and queries on items.
+func (c *Client) doWithAvailabilityStrategy(request *policy.Request) (*http.Response, error) {
+	var operationContext pipelineRequestOptions
+	strategy := c.availabilityStrategy
+	if strategy == nil || !request.OperationValue(&operationContext) ||
+		operationContext.isWriteOperation || operationContext.resourceType != resourceTypeDocument {
+		return c.internal.Pipeline().Do(request)
+	}
+
+	endpoints, err := c.gem.GetReadEndpoints()
+	if err != nil || len(endpoints) < 2 {
+		return c.internal.Pipeline().Do(request)
+	}
+	maxRequests := strategy.maxRequests(len(endpoints))
+
+	var body []byte
+	if request.Body() != nil {
+		if body, err = io.ReadAll(request.Body()); err != nil {
+			return nil, err
+		}
+		if err = request.RewindBody(); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := request.Raw().Context()
+	diagnostics := diagnosticsFromContext(ctx)
+	// The pipeline policies update the headers and the URL of requests in flight, so hedged requests are cloned
+	// from a copy of the request taken before sending it.
+	template := request.Raw().Clone(ctx)
+	results := make(chan hedgedResult, maxRequests)
+	cancels := make([]context.CancelFunc, 0, maxRequests)
+	send := func(locationIndex int) error {
+		requestCtx, cancel := context.WithCancel(ctx)
+		req := request.WithContext(requestCtx)
+		if locationIndex > 0 {
+			// Hedged requests need their own body and operation values, as the pipeline policies set operation values.
+			raw := template.Clone(requestCtx)
+			if body != nil {
+				raw.Body = streaming.NopCloser(bytes.NewReader(body))
+			}
+			hedged, err := azruntime.NewRequestFromRequest(raw)
+			if err != nil {
+				cancel()
+				return err
+			}
+			req = hedged
+			hedgedContext := operationContext
+			hedgedContext.locationIndex = locationIndex
+			req.SetOperationValue(hedgedContext)
+
+			endpoint := endpoints[locationIndex%len(endpoints)]
+			diagnostics.addEvent(DiagnosticsEvent{
+				Kind:      DiagnosticsEventHedgedRequest,
+				StartTime: time.Now(),
+				Region:    c.gem.GetEndpointLocation(endpoint),
+				Endpoint:  endpoint.String(),
+				Reason:    "AvailabilityStrategyThreshold",
+			})
+		}
+		cancels = append(cancels, cancel)
+		go func() {
+			response, err := c.internal.Pipeline().Do(req)
+			results <- hedgedResult{response: response, err: err, cancel: cancel}
+		}()
+		return nil
+	}
+
+	if err := send(0); err != nil {
+		return nil, err
+	}
+	sent, pending := 1, 1
+	timer := time.NewTimer(strategy.Threshold)
+	defer timer.Stop()
+
+	// last is the latest transient response, returned when there's no other region to send the request to.
+	var result, last hedgedResult
+loop:
+	for {
+		var hedge bool
+		select {
+		case r := <-results:
+			pending--
+			if isFinalResponse(r.response, r.err) || (pending == 0 && sent == maxRequests) {
+				result = r
+				break loop
+			}
+			// The response is transient, and another region may still serve the request.
+			if last.cancel != nil {
+				closeHedgedResult(last)
+			}
+			last = r
+			hedge = pending == 0
+		case <-timer.C:
+			hedge = true
+		case <-ctx.Done():
+			result.err = ctx.Err()
+			break loop
+		}
+		if hedge && sent < maxRequests {
+			if err := send(sent); err != nil {
+				// Keep waiting for the requests in flight, if any.
+				maxRequests = sent
+				if pending == 0 {
+					result, last = last, hedgedResult{}
+					break loop
+				}
+				continue
+			}
+			sent++
+			pending++
+			timer.Reset(strategy.ThresholdStep)
+		}
+	}
+	if last.cancel != nil {
+		closeHedgedResult(last)
+	}
+
+	// Cancel the requests still in flight, and release their responses.
+	// The pipeline downloads response bodies, so the returned response is readable once its request is canceled.
+	for _, cancel := range cancels {
+		cancel()
+	}
+	go func(pending int) {
+		for i := 0; i < pending; i++ {
+			closeHedgedResult(<-results)
+		}
+	}(pending)
+
+	return result.response, result.err
+}
+
+// isFinalResponse reports whether a response is returned as is by the availability strategy, rather than waiting
+// for the response of another region.
+func isFinalResponse(response *http.Response, err error) bool {
+	if err != nil {
+		return false
+	}
+	switch response.StatusCode {
+	case http.StatusRequestTimeout, http.StatusGone, http.StatusTooManyRequests:
+		return false
+	case http.StatusNotFound:
+		return response.Header.Get(cosmosHeaderSubstatus) != subStatusReadSessionNotAvailable
+	}
+	return response.StatusCode < http.StatusInternalServerError
+}
+
+func closeHedgedResult(r hedgedResult) {
+	if r.response != nil && r.response.Body != nil {
+		_ = r.response.Body.Close()
+	}
+	r.cancel()
+}
@@ -0,0 +1,226 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newMultiRegionTestContainer returns a container whose account has a read region "East US" served by primary,
+// and a read region "West US" served by secondary.
+func newMultiRegionTestContainer(t *testing.T, primary *mock.Server, secondary *mock.Server) (*ContainerClient, *Client) {
+	primaryEndpoint, err := url.Parse(primary.URL())
+	require.NoError(t, err)
+	secondaryEndpoint, err := url.Parse(secondary.URL())
+	require.NoError(t, err)
+
+	lc := &locationCache{
+		defaultEndpoint: *primaryEndpoint,
+		locationInfo: databaseAccountLocationsInfo{
+			prefLocations:                 []string{"East US", "West US"},
+			availWriteLocations:           []string{"East US"},
+			availReadLocations:            []string{"East US", "West US"},
+			availWriteEndpointsByLocation: map[string]url.URL{"East US": *primaryEndpoint},
+			availReadEndpointsByLocation:  map[string]url.URL{"East US": *primaryEndpoint, "West US": *secondaryEndpoint},
+			writeEndpoints:                []url.URL{*primaryEndpoint},
+			readEndpoints:                 []url.URL{*primaryEndpoint, *secondaryEndpoint},
+		},
+		locationUnavailabilityInfoMap:     map[url.URL]locationUnavailabilityInfo{},
+		unavailableLocationExpirationTime: defaultExpirationTime,
+		enableCrossRegionRetries:          true,
+	}
+	gem := &globalEndpointManager{
+		clientEndpoint:      primary.URL(),
+		preferredLocations:  []string{"East US", "West US"},
+		locationCache:       lc,
+		refreshTimeInterval: defaultExpirationTime,
+	}
+
+	// Both mock servers share the TLS certificate of httptest, so the transport of the primary reaches the secondary.
+	container := newTestContainer(t, primary, gem,
+		azruntime.PipelineOptions{PerRetry: []policy.Policy{&clientRetryPolicy{gem: gem}}},
+		policy.RetryOptions{MaxRetries: -1})
+	return container, container.database.client
+}
+
+func diagnosticsEventsOfKind(d *Diagnostics, kind DiagnosticsEventKind) []DiagnosticsEvent {
+	var events []DiagnosticsEvent
+	for _, event := range d.Events() {
+		if event.Kind == kind {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestAvailabilityStrategyHedgesSlowRead(t *testing.T) {
+	primary, closePrimary := mock.NewTLSServer()
+	defer closePrimary()
+	secondary, closeSecondary := mock.NewTLSServer()
+	defer closeSecondary()
+
+	primary.SetResponse(mock.WithSlowResponse(500*time.Millisecond), mock.WithStatusCode(200), mock.WithBody([]byte(`{"id":"primary"}`)))
+	secondary.SetResponse(mock.WithStatusCode(200), mock.WithBody([]byte(`{"id":"secondary"}`)))
+
+	container, client := newMultiRegionTestContainer(t, primary, secondary)
+	client.availabilityStrategy = &AvailabilityStrategy{Threshold: 50 * time.Millisecond}
+
+	start := time.Now()
+	resp, err := container.ReadItem(context.Background(), NewPartitionKeyString("1"), "doc1", nil)
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+	require.JSONEq(t, `{"id":"secondary"}`, string(resp.Value))
+
+	hedged := diagnosticsEventsOfKind(resp.Diagnostics, DiagnosticsEventHedgedRequest)
+	require.Len(t, hedged, 1)
+	require.Equal(t, "West US", hedged[0].Region)
+	require.Equal(t, "AvailabilityStrategyThreshold", hedged[0].Reason)
+	require.Contains(t, resp.Diagnostics.RegionsContacted(), "West US")
+}
+
+func TestAvailabilityStrategyHedgesTransientResponse(t *testing.T) {
+	primary, closePrimary := mock.NewTLSServer()
+	defer closePrimary()
+	secondary, closeSecondary := mock.NewTLSServer()
+	defer closeSecondary()
+
+	primary.SetResponse(mock.WithStatusCode(http.StatusGone))
+	secondary.SetResponse(mock.WithStatusCode(200), mock.WithBody([]byte(`{"id":"secondary"}`)))
+
+	container, client := newMultiRegionTestContainer(t, primary, secondary)
+	client.availabilityStrategy = &AvailabilityStrategy{Threshold: time.Minute}
+
+	// The transient response of the first region sends the request to the next region without waiting for the threshold.
+	resp, err := container.ReadItem(context.Background(), NewPartitionKeyString("1"), "doc1", nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":"secondary"}`, string(resp.Value))
+	require.Len(t, diagnosticsEventsOfKind(resp.Diagnostics, DiagnosticsEventHedgedRequest), 1)
+}
+
+// drainedBody is a request body that's empty when first read, and fails to be read again.
+type drainedBody struct {
+	read bool
+}
+
+func (b *drainedBody) Read(p []byte) (int, error) {
+	if b.read {
+		return 0, errors.New("body already read")
+	}
+	b.read = true
+	return 0, io.EOF
+}
+
+func (b *drainedBody) Close() error {
+	return nil
+}
+
+func TestAvailabilityStrategyReturnsTransientResponseWhenHedgingFails(t *testing.T) {
+	primary, closePrimary := mock.NewTLSServer()
+	defer closePrimary()
+	secondary, closeSecondary := mock.NewTLSServer()
+	defer closeSecondary()
+
+	primary.SetResponse(mock.WithStatusCode(http.StatusGone))
+	secondary.SetResponse(mock.WithStatusCode(200))
+
+	_, client := newMultiRegionTestContainer(t, primary, secondary)
+	client.availabilityStrategy = &AvailabilityStrategy{Threshold: time.Minute}
+
+	req, err := azruntime.NewRequest(context.Background(), http.MethodGet, primary.URL())
+	require.NoError(t, err)
+	req.SetOperationValue(pipelineRequestOptions{resourceType: resourceTypeDocument})
+	// The hedged request copies the body of the raw request, so it can't be created.
+	req.Raw().Body = &drainedBody{}
+
+	// The transient response is returned rather than waiting for a request that was never sent.
+	resp, err := client.doWithAvailabilityStrategy(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusGone, resp.StatusCode)
+	require.Equal(t, 0, secondary.Requests())
+}
+
+func TestAvailabilityStrategyCanceled(t *testing.T) {
+	primary, closePrimary := mock.NewTLSServer()
+	defer closePrimary()
+	secondary, closeSecondary := mock.NewTLSServer()
+	defer closeSecondary()
+
+	primary.SetResponse(mock.WithSlowResponse(time.Second), mock.WithStatusCode(200))
+	secondary.SetResponse(mock.WithSlowResponse(time.Second), mock.WithStatusCode(200))
+
+	container, client := newMultiRegionTestContainer(t, primary, secondary)
+	client.availabilityStrategy = &AvailabilityStrategy{Threshold: 10 * time.Millisecond}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := container.ReadItem(ctx, NewPartitionKeyString("1"), "doc1", nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestAvailabilityStrategyFastRead(t *testing.T) {
+	primary, closePrimary := mock.NewTLSServer()
+	defer closePrimary()
+	secondary, closeSecondary := mock.NewTLSServer()
+	defer closeSecondary()
+
+	primary.SetResponse(mock.WithStatusCode(200), mock.WithBody([]byte(`{"id":"primary"}`)))
+	secondary.SetResponse(mock.WithStatusCode(200), mock.WithBody([]byte(`{"id":"secondary"}`)))
+
+	container, client := newMultiRegionTestContainer(t, primary, secondary)
+	client.availabilityStrategy = &AvailabilityStrategy{Threshold: time.Minute}
+
+	resp, err := container.ReadItem(context.Background(), NewPartitionKeyString("1"), "doc1", nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":"primary"}`, string(resp.Value))
+	require.Empty(t, diagnosticsEventsOfKind(resp.Diagnostics, DiagnosticsEventHedgedRequest))
+}
+
+func TestAvailabilityStrategyDoesNotHedgeWrites(t *testing.T) {
+	primary, closePrimary := mock.NewTLSServer()
+	defer closePrimary()
+	secondary, closeSecondary := mock.NewTLSServer()
+	defer closeSecondary()
+
+	primary.SetResponse(mock.WithSlowResponse(200*time.Millisecond), mock.WithStatusCode(201), mock.WithBody([]byte(`{"id":"primary"}`)))
+	secondary.SetResponse(mock.WithStatusCode(201), mock.WithBody([]byte(`{"id":"secondary"}`)))
+
+	container, client := newMultiRegionTestContainer(t, primary, secondary)
+	client.availabilityStrategy = &AvailabilityStrategy{Threshold: 10 * time.Millisecond}
+
+	resp, err := container.CreateItem(context.Background(), NewPartitionKeyString("1"), []byte(`{"id":"doc1"}`), &ItemOptions{EnableContentResponseOnWrite: true})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":"primary"}`, string(resp.Value))
+	require.Empty(t, diagnosticsEventsOfKind(resp.Diagnostics, DiagnosticsEventHedgedRequest))
+}
+
+func TestIsFinalResponse(t *testing.T) {
+	response := func(status int, subStatus string) *http.Response {
+		r := &http.Response{StatusCode: status, Header: http.Header{}}
+		if subStatus != "" {
+			r.Header.Set(cosmosHeaderSubstatus, subStatus)
+		}
+		return r
+	}
+
+	require.True(t, isFinalResponse(response(200, ""), nil))
+	require.True(t, isFinalResponse(response(404, ""), nil))
+	require.True(t, isFinalResponse(response(409, ""), nil))
+	require.False(t, isFinalResponse(response(404, subStatusReadSessionNotAvailable), nil))
+	require.False(t, isFinalResponse(response(408, ""), nil))
+	require.False(t, isFinalResponse(response(410, ""), nil))
+	require.False(t, isFinalResponse(response(429, ""), nil))
+	require.False(t, isFinalResponse(response(503, ""), nil))
+	require.False(t, isFinalResponse(nil, context.DeadlineExceeded))
+}
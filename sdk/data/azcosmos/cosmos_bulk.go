@@ -0,0 +1,518 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// Retry limits of bulk operations.
+const (
+	maxBulkThrottlingRetries = 9
+	maxBulkPartitionRetries  = 10
+)
+
+// defaultBulkRetryAfter is the delay before retrying throttled operations when the service didn't specify one.
+const defaultBulkRetryAfter = 100 * time.Millisecond
+
+// BulkOperation is an item operation executed by ExecuteBulk.
+type BulkOperation struct {
+	partitionKey PartitionKey
+	operation    batchOperation
+}
+
+// NewBulkCreateOperation creates an operation creating an item. Creates have no options, since an item that
+// doesn't exist yet has no ETag to match.
+func NewBulkCreateOperation(partitionKey PartitionKey, item []byte) BulkOperation {
+	return BulkOperation{
+		partitionKey: partitionKey,
+		operation: batchOperationCreate{
+			operationType: "Create",
+			resourceBody:  item},
+	}
+}
+
+// NewBulkUpsertOperation creates an operation creating or replacing an item.
+func NewBulkUpsertOperation(partitionKey PartitionKey, item []byte, o *BulkItemOptions) BulkOperation {
+	if o == nil {
+		o = &BulkItemOptions{}
+	}
+	return BulkOperation{
+		partitionKey: partitionKey,
+		operation: batchOperationUpsert{
+			operationType: "Upsert",
+			resourceBody:  item,
+			ifMatch:       o.IfMatchETag},
+	}
+}
+
+// NewBulkReplaceOperation creates an operation replacing an item.
+func NewBulkReplaceOperation(partitionKey PartitionKey, itemID string, item []byte, o *BulkItemOptions) BulkOperation {
+	if o == nil {
+		o = &BulkItemOptions{}
+	}
+	return BulkOperation{
+		partitionKey: partitionKey,
+		operation: batchOperationReplace{
+			operationType: "Replace",
+			id:            itemID,
+			resourceBody:  item,
+			ifMatch:       o.IfMatchETag},
+	}
+}
+
+// NewBulkDeleteOperation creates an operation deleting an item.
+func NewBulkDeleteOperation(partitionKey PartitionKey, itemID string, o *BulkItemOptions) BulkOperation {
+	if o == nil {
+		o = &BulkItemOptions{}
+	}
+	return BulkOperation{
+		partitionKey: partitionKey,
+		operation: batchOperationDelete{
+			operationType: "Delete",
+			id:            itemID,
+			ifMatch:       o.IfMatchETag},
+	}
+}
+
+// NewBulkPatchOperation creates an operation patching an item.
+func NewBulkPatchOperation(partitionKey PartitionKey, itemID string, p PatchOperations, o *BulkItemOptions) BulkOperation {
+	if o == nil {
+		o = &BulkItemOptions{}
+	}
+	return BulkOperation{
+		partitionKey: partitionKey,
+		operation: batchOperationPatch{
+			operationType:   "Patch",
+			id:              itemID,
+			patchOperations: p,
+			ifMatch:         o.IfMatchETag},
+	}
+}
+
+// PartitionKey returns the partition key of the item the operation applies to.
+func (op BulkOperation) PartitionKey() PartitionKey {
+	return op.partitionKey
+}
+
+// marshal serializes the operation with its partition key, which identifies the item within the batch.
+func (op BulkOperation) marshal() (json.RawMessage, error) {
+	if op.operation == nil {
+		return nil, errors.New("the bulk operation was not created with a NewBulk*Operation function")
+	}
+	body, err := json.Marshal(op.operation)
+	if err != nil {
+		return nil, err
+	}
+	pkJSON, err := op.partitionKey.toJsonString()
+	if err != nil {
+		return nil, err
+	}
+	pkField, err := json.Marshal(pkJSON)
+	if err != nil {
+		return nil, err
+	}
+	marshalled := make([]byte, 0, len(body)+len(pkField)+len(`{"partitionKey":,`))
+	marshalled = append(marshalled, `{"partitionKey":`...)
+	marshalled = append(marshalled, pkField...)
+	marshalled = append(marshalled, ',')
+	return append(marshalled, body[1:]...), nil
+}
+
+// bulkBatchOperation is a serialized bulk operation sent in a batch request.
+type bulkBatchOperation struct {
+	operationType operationType
+	body          json.RawMessage
+}
+
+func (b bulkBatchOperation) getOperationType() operationType {
+	return b.operationType
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (b bulkBatchOperation) MarshalJSON() ([]byte, error) {
+	return b.body, nil
+}
+
+// ExecuteBulk executes a stream of item operations. Operations are grouped by partition key range and sent as
+// non-atomic batch requests, so every operation succeeds or fails independently.
+// The concurrency of the requests to a partition key range is reduced when they are throttled, and operations
+// throttled by the service or sent to a partition key range that was split are retried.
+// ctx - The context for the requests. When it is done, operations that weren't executed fail with its error.
+// operations - The operations to execute. Close the channel once all operations were sent.
+// o - Options for the operations.
+// The returned channel receives one result per operation, in no particular order, and is closed once all operations
+// completed. It must be drained, as operations wait for their results to be received.
+func (c *ContainerClient) ExecuteBulk(ctx context.Context, operations <-chan BulkOperation, o *BulkOptions) <-chan BulkOperationResult {
+	options := BulkOptions{}
+	if o != nil {
+		options = *o
+	}
+	options.setDefaults()
+
+	e := &bulkExecutor{
+		container:   c,
+		options:     options,
+		results:     make(chan BulkOperationResult, options.MaxOperationsPerBatch),
+		completions: make(chan bulkBatchCompletion),
+		ranges:      map[string]*bulkRangeState{},
+	}
+	go e.run(ctx, operations)
+	return e.results
+}
+
+// bulkItem is an operation waiting to be executed.
+type bulkItem struct {
+	index            int
+	operation        BulkOperation
+	body             json.RawMessage
+	enqueued         time.Time
+	throttledRetries int
+	partitionRetries int
+}
+
+// bulkRangeState contains the operations and requests of a partition key range.
+type bulkRangeState struct {
+	pending  []*bulkItem
+	inflight int
+	// concurrency is the current limit of concurrent requests.
+	concurrency int
+}
+
+// bulkBatchCompletion reports the outcome of a batch request to the executor.
+type bulkBatchCompletion struct {
+	rangeID string
+	// finished is the number of operations whose result was sent.
+	finished int
+	// throttled contains operations to retry after retryAfter.
+	throttled  []*bulkItem
+	retryAfter time.Duration
+	// gone contains operations to send again to the partition key ranges in routingMap.
+	gone       []*bulkItem
+	routingMap *collectionRoutingMap
+}
+
+type bulkDelayedItems struct {
+	at    time.Time
+	items []*bulkItem
+}
+
+// bulkExecutor dispatches the operations of an ExecuteBulk call. Its state is owned by the goroutine executing run,
+// and batch requests report back through completions.
+type bulkExecutor struct {
+	container   *ContainerClient
+	options     BulkOptions
+	results     chan BulkOperationResult
+	completions chan bulkBatchCompletion
+	routingMap  *collectionRoutingMap
+	routingErr  error
+	ranges      map[string]*bulkRangeState
+	delayed     []bulkDelayedItems
+	// outstanding is the number of received operations without a result.
+	outstanding int
+	// senders tracks the goroutines sending results.
+	senders sync.WaitGroup
+}
+
+func (e *bulkExecutor) run(ctx context.Context, operations <-chan BulkOperation) {
+	defer func() {
+		e.senders.Wait()
+		close(e.results)
+	}()
+	e.routingMap, e.routingErr = e.container.getRoutingMap(ctx, nil)
+
+	ticker := time.NewTicker(e.options.FlushInterval)
+	defer ticker.Stop()
+	done := ctx.Done()
+	index := 0
+	for operations != nil || e.outstanding > 0 {
+		select {
+		case op, ok := <-operations:
+			if !ok {
+				operations = nil
+				break
+			}
+			e.outstanding++
+			e.enqueue(ctx, &bulkItem{index: index, operation: op})
+			index++
+		case completion := <-e.completions:
+			e.complete(ctx, completion)
+		case <-ticker.C:
+			e.requeueDelayed(ctx, time.Now())
+		case <-done:
+			// Fail the operations that weren't sent. Requests in flight fail with the context's error, and
+			// operations received from now on fail immediately.
+			done = nil
+			for _, state := range e.ranges {
+				for _, item := range state.pending {
+					e.finish(item, BulkOperationResult{Err: ctx.Err()})
+				}
+				state.pending = nil
+			}
+			for _, delayed := range e.delayed {
+				for _, item := range delayed.items {
+					e.finish(item, BulkOperationResult{Err: ctx.Err()})
+				}
+			}
+			e.delayed = nil
+		}
+		e.dispatch(ctx, operations == nil)
+	}
+}
+
+// enqueue adds the operation to the pending operations of its partition key range.
+func (e *bulkExecutor) enqueue(ctx context.Context, item *bulkItem) {
+	if ctx.Err() != nil {
+		e.finish(item, BulkOperationResult{Err: ctx.Err()})
+		return
+	}
+	if e.routingErr != nil {
+		e.finish(item, BulkOperationResult{Err: e.routingErr})
+		return
+	}
+	if item.body == nil {
+		body, err := item.operation.marshal()
+		if err != nil {
+			e.finish(item, BulkOperationResult{Err: err})
+			return
+		}
+		item.body = body
+	}
+	pkr, err := e.routingMap.rangeByPartitionKey(item.operation.partitionKey)
+	if err != nil {
+		e.finish(item, BulkOperationResult{Err: err})
+		return
+	}
+	state, ok := e.ranges[pkr.ID]
+	if !ok {
+		state = &bulkRangeState{concurrency: 1}
+		e.ranges[pkr.ID] = state
+	}
+	item.enqueued = time.Now()
+	state.pending = append(state.pending, item)
+}
+
+// dispatch sends full batches, and partially filled batches whose oldest operation waited for the flush interval,
+// to the partition key ranges with available concurrency.
+func (e *bulkExecutor) dispatch(ctx context.Context, flush bool) {
+	now := time.Now()
+	for rangeID, state := range e.ranges {
+		for len(state.pending) > 0 && state.inflight < state.concurrency {
+			count, size := 0, 0
+			for count < len(state.pending) && count < e.options.MaxOperationsPerBatch {
+				size += len(state.pending[count].body)
+				if count > 0 && size > maxBulkBatchBytes {
+					break
+				}
+				count++
+			}
+			full := count == e.options.MaxOperationsPerBatch || count < len(state.pending)
+			if !full && !flush && now.Sub(state.pending[0].enqueued) < e.options.FlushInterval {
+				break
+			}
+			batch := state.pending[:count:count]
+			state.pending = state.pending[count:]
+			state.inflight++
+			go e.send(ctx, e.routingMap, rangeID, batch)
+		}
+	}
+}
+
+// send executes a batch request to a partition key range of routingMap and reports its outcome. Results of completed
+// operations are sent directly, while operations to retry are returned to the executor.
+func (e *bulkExecutor) send(ctx context.Context, routingMap *collectionRoutingMap, rangeID string, items []*bulkItem) {
+	completion := bulkBatchCompletion{rangeID: rangeID}
+	responses, retryAfter, err := e.container.sendBulkBatch(ctx, rangeID, items, e.options.EnableContentResponseOnWrite)
+	switch {
+	case err == nil:
+		for i, response := range responses {
+			item := items[i]
+			switch {
+			case response.StatusCode == http.StatusTooManyRequests && item.throttledRetries < maxBulkThrottlingRetries:
+				item.throttledRetries++
+				completion.throttled = append(completion.throttled, item)
+				if d := response.retryAfter(); d > completion.retryAfter {
+					completion.retryAfter = d
+				}
+			case response.isPartitionGone() && item.partitionRetries < maxBulkPartitionRetries:
+				item.partitionRetries++
+				completion.gone = append(completion.gone, item)
+			default:
+				e.sendResult(item, BulkOperationResult{
+					StatusCode:    response.StatusCode,
+					RequestCharge: response.RequestCharge,
+					ResourceBody:  response.ResourceBody,
+					ETag:          response.ETag,
+				})
+				completion.finished++
+			}
+		}
+	case isStatusCode(err, http.StatusTooManyRequests):
+		// The request was still throttled after the retries of the pipeline.
+		completion.retryAfter = retryAfter
+		for _, item := range items {
+			if item.throttledRetries < maxBulkThrottlingRetries {
+				item.throttledRetries++
+				completion.throttled = append(completion.throttled, item)
+			} else {
+				e.sendResult(item, BulkOperationResult{StatusCode: http.StatusTooManyRequests})
+				completion.finished++
+			}
+		}
+	case isPartitionGone(err):
+		for _, item := range items {
+			if item.partitionRetries < maxBulkPartitionRetries {
+				item.partitionRetries++
+				completion.gone = append(completion.gone, item)
+			} else {
+				e.sendResult(item, BulkOperationResult{Err: err})
+				completion.finished++
+			}
+		}
+	default:
+		for _, item := range items {
+			e.sendResult(item, BulkOperationResult{Err: err})
+		}
+		completion.finished = len(items)
+	}
+
+	if len(completion.gone) > 0 {
+		// The executor replaces its routing map instead of modifying it, so routingMap can be read here.
+		refreshed, err := e.container.getRoutingMap(ctx, routingMap)
+		if err != nil {
+			for _, item := range completion.gone {
+				e.sendResult(item, BulkOperationResult{Err: err})
+			}
+			completion.finished += len(completion.gone)
+			completion.gone = nil
+		}
+		completion.routingMap = refreshed
+	}
+	if len(completion.throttled) > 0 && completion.retryAfter <= 0 {
+		completion.retryAfter = defaultBulkRetryAfter
+	}
+	e.completions <- completion
+}
+
+// complete updates the state of a partition key range after a batch request and schedules the retries.
+func (e *bulkExecutor) complete(ctx context.Context, completion bulkBatchCompletion) {
+	e.outstanding -= completion.finished
+	state := e.ranges[completion.rangeID]
+	state.inflight--
+	if len(completion.throttled) > 0 {
+		state.concurrency = max(1, state.concurrency/2)
+	} else if len(completion.gone) == 0 && state.concurrency < e.options.MaxConcurrencyPerPartitionKeyRange {
+		state.concurrency++
+	}
+	if completion.routingMap != nil {
+		e.routingMap = completion.routingMap
+	}
+
+	if ctx.Err() != nil {
+		for _, item := range completion.throttled {
+			e.finish(item, BulkOperationResult{Err: ctx.Err()})
+		}
+	} else if len(completion.throttled) > 0 {
+		e.delayed = append(e.delayed, bulkDelayedItems{at: time.Now().Add(completion.retryAfter), items: completion.throttled})
+	}
+	for _, item := range completion.gone {
+		e.enqueue(ctx, item)
+	}
+}
+
+// requeueDelayed enqueues the throttled operations whose retry delay elapsed.
+func (e *bulkExecutor) requeueDelayed(ctx context.Context, now time.Time) {
+	remaining := e.delayed[:0]
+	for _, delayed := range e.delayed {
+		if now.Before(delayed.at) {
+			remaining = append(remaining, delayed)
+			continue
+		}
+		for _, item := range delayed.items {
+			e.enqueue(ctx, item)
+		}
+	}
+	e.delayed = remaining
+}
+
+// finish sends the result of an operation from the executor goroutine.
+func (e *bulkExecutor) finish(item *bulkItem, result BulkOperationResult) {
+	e.outstanding--
+	e.senders.Add(1)
+	go func() {
+		defer e.senders.Done()
+		e.sendResult(item, result)
+	}()
+}
+
+func (e *bulkExecutor) sendResult(item *bulkItem, result BulkOperationResult) {
+	result.Index = item.index
+	result.Operation = item.operation
+	e.results <- result
+}
+
+// sendBulkBatch sends the operations to a partition key range as a non-atomic batch request.
+// It returns the result of every operation, or the delay requested by the service when the request was throttled.
+func (c *ContainerClient) sendBulkBatch(ctx context.Context, rangeID string, items []*bulkItem, enableContentResponseOnWrite bool) ([]bulkOperationResponse, time.Duration, error) {
+	var err error
+	spanName, err := c.getSpanForContainer(operationTypeBatch, resourceTypeCollection, c.id)
+	if err != nil {
+		return nil, 0, err
+	}
+	ctx, endSpan := runtime.StartSpan(ctx, spanName.name, c.database.client.internal.Tracer(), &spanName.options)
+	defer func() { endSpan(err) }()
+
+	operations := make([]batchOperation, len(items))
+	for i, item := range items {
+		operations[i] = bulkBatchOperation{operationType: item.operation.operation.getOperationType(), body: item.body}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:          resourceTypeDocument,
+		resourceAddress:       c.link,
+		isWriteOperation:      true,
+		headerOptionsOverride: &headerOptionsOverride{enableContentResponseOnWrite: &enableContentResponseOnWrite},
+	}
+
+	path, err := generatePathForNameBased(resourceTypeDocument, operationContext.resourceAddress, true)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	azResponse, err := c.database.client.sendBatchRequest(
+		ctx,
+		path,
+		operations,
+		operationContext,
+		&bulkRequestOptions{partitionKeyRangeID: rangeID},
+		nil)
+	if err != nil {
+		return nil, retryAfterFromError(err), err
+	}
+
+	responses, err := newBulkBatchResponse(azResponse, len(items))
+	return responses, 0, err
+}
+
+// retryAfterFromError returns the delay requested by the service in a throttled response.
+func retryAfterFromError(err error) time.Duration {
+	var azErr *azcore.ResponseError
+	if !errors.As(err, &azErr) || azErr.StatusCode != http.StatusTooManyRequests || azErr.RawResponse == nil {
+		return 0
+	}
+	ms, parseErr := strconv.ParseInt(azErr.RawResponse.Header.Get(cosmosHeaderRetryAfterMs), 10, 64)
+	if parseErr != nil {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
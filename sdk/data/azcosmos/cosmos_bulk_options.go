@@ -0,0 +1,68 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// maxBulkOperationsPerBatch is the maximum number of operations the service accepts in a batch request.
+const maxBulkOperationsPerBatch = 100
+
+// maxBulkBatchBytes limits the size of the body of a bulk batch request.
+const maxBulkBatchBytes = 220201
+
+// BulkOptions includes options for ExecuteBulk.
+type BulkOptions struct {
+	// MaxOperationsPerBatch limits the number of operations sent in a single request.
+	// Valid values are 1 to 100. The default is 100.
+	MaxOperationsPerBatch int
+	// MaxConcurrencyPerPartitionKeyRange limits the number of concurrent requests to a partition key range.
+	// The concurrency starts at 1, grows while requests succeed and is halved when requests are throttled.
+	// The default is 5.
+	MaxConcurrencyPerPartitionKeyRange int
+	// FlushInterval is the maximum time an operation waits for other operations of its partition key range before it
+	// is sent in a partially filled batch. The default is 100 milliseconds.
+	FlushInterval time.Duration
+	// When EnableContentResponseOnWrite is false, the results of write operations have no ResourceBody.
+	// The default is false.
+	EnableContentResponseOnWrite bool
+}
+
+func (options *BulkOptions) setDefaults() {
+	if options.MaxOperationsPerBatch <= 0 || options.MaxOperationsPerBatch > maxBulkOperationsPerBatch {
+		options.MaxOperationsPerBatch = maxBulkOperationsPerBatch
+	}
+	if options.MaxConcurrencyPerPartitionKeyRange <= 0 {
+		options.MaxConcurrencyPerPartitionKeyRange = 5
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = 100 * time.Millisecond
+	}
+}
+
+// BulkItemOptions includes options for an operation executed by ExecuteBulk.
+type BulkItemOptions struct {
+	// IfMatchETag is used to ensure optimistic concurrency control.
+	// https://docs.microsoft.com/azure/cosmos-db/sql/database-transactions-optimistic-concurrency#optimistic-concurrency-control
+	IfMatchETag *azcore.ETag
+}
+
+// bulkRequestOptions are the options of a batch request sending bulk operations to a partition key range.
+type bulkRequestOptions struct {
+	partitionKeyRangeID string
+}
+
+func (options *bulkRequestOptions) toHeaders() *map[string]string {
+	headers := map[string]string{
+		cosmosHeaderIsBatchRequest:       "True",
+		cosmosHeaderIsBatchAtomic:        "False",
+		cosmosHeaderIsBatchOrdered:       "False",
+		cosmosHeaderBatchContinueOnError: "True",
+		cosmosHeaderPartitionKeyRangeId:  options.partitionKeyRangeID,
+	}
+	return &headers
+}
@@ -0,0 +1,77 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// BulkOperationResult contains the result of an operation executed by ExecuteBulk.
+type BulkOperationResult struct {
+	// Index is the position of the operation in the stream of operations, starting at 0.
+	Index int
+	// Operation is the executed operation.
+	Operation BulkOperation
+	// StatusCode contains the status code of the operation.
+	// Operations that were still throttled after being retried have status code http.StatusTooManyRequests.
+	StatusCode int32
+	// RequestCharge contains the request charge for the operation.
+	RequestCharge float32
+	// ResourceBody contains the body response of the operation.
+	// This property is available depending on the EnableContentResponseOnWrite option.
+	ResourceBody []byte
+	// ETag contains the ETag of the operation.
+	ETag azcore.ETag
+	// Err is set when the operation could not be executed, for example because its request failed or ctx was done.
+	// StatusCode is 0 in that case.
+	Err error
+}
+
+// Succeeded reports whether the operation was executed successfully.
+func (r BulkOperationResult) Succeeded() bool {
+	return r.Err == nil && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// bulkOperationResponse is the result of an operation in the response of a bulk batch request.
+type bulkOperationResponse struct {
+	StatusCode             int32           `json:"statusCode"`
+	SubStatusCode          int32           `json:"subStatusCode"`
+	RequestCharge          float32         `json:"requestCharge"`
+	ETag                   azcore.ETag     `json:"eTag"`
+	ResourceBody           json.RawMessage `json:"resourceBody"`
+	RetryAfterMilliseconds int64           `json:"retryAfterMilliseconds"`
+}
+
+func newBulkBatchResponse(resp *http.Response, operationCount int) ([]bulkOperationResponse, error) {
+	var results []bulkOperationResponse
+	if err := runtime.UnmarshalAsJSON(resp, &results); err != nil {
+		return nil, err
+	}
+	if len(results) != operationCount {
+		return nil, fmt.Errorf("expected %d operation results but received %d", operationCount, len(results))
+	}
+	return results, nil
+}
+
+func (r bulkOperationResponse) isPartitionGone() bool {
+	if r.StatusCode != http.StatusGone {
+		return false
+	}
+	switch strconv.Itoa(int(r.SubStatusCode)) {
+	case subStatusPartitionKeyRangeGone, subStatusCompletingSplit, subStatusCompletingMigration:
+		return true
+	}
+	return false
+}
+
+func (r bulkOperationResponse) retryAfter() time.Duration {
+	return time.Duration(r.RetryAfterMilliseconds) * time.Millisecond
+}
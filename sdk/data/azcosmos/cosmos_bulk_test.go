@@ -0,0 +1,267 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+const testBulkLink = "dbs/db/colls/items"
+
+func newTestBulkContainer(t *testing.T, service *fakeCosmosService) *ContainerClient {
+	database, _ := newDatabase("db", service.newClient(t))
+	container, _ := newContainer("items", database)
+	return container
+}
+
+// executeBulk executes the operations and returns their results by index.
+func executeBulk(t *testing.T, ctx context.Context, container *ContainerClient, operations []BulkOperation, o *BulkOptions) []BulkOperationResult {
+	input := make(chan BulkOperation)
+	go func() {
+		defer close(input)
+		for _, op := range operations {
+			input <- op
+		}
+	}()
+
+	results := make([]BulkOperationResult, len(operations))
+	received := make([]bool, len(operations))
+	timeout := time.After(10 * time.Second)
+	output := container.ExecuteBulk(ctx, input, o)
+	for {
+		select {
+		case result, ok := <-output:
+			if !ok {
+				for i := range received {
+					if !received[i] {
+						t.Fatalf("Missing the result of operation %d", i)
+					}
+				}
+				return results
+			}
+			if received[result.Index] {
+				t.Fatalf("Received the result of operation %d twice", result.Index)
+			}
+			received[result.Index] = true
+			results[result.Index] = result
+		case <-timeout:
+			t.Fatal("Timed out waiting for the bulk results")
+		}
+	}
+}
+
+func testUpserts(count int) []BulkOperation {
+	operations := make([]BulkOperation, count)
+	for i := range operations {
+		id := fmt.Sprintf("item-%d", i)
+		operations[i] = NewBulkUpsertOperation(NewPartitionKeyString(id), []byte(fmt.Sprintf(`{"id":%q,"value":%d}`, id, i)), nil)
+	}
+	return operations
+}
+
+func TestExecuteBulkGroupsOperationsByPartitionKeyRange(t *testing.T) {
+	service := newFakeCosmosService()
+	service.setPartitionKeyRanges(testBulkLink,
+		partitionKeyRange{ID: "1", MinInclusive: "", MaxExclusive: "10"},
+		partitionKeyRange{ID: "2", MinInclusive: "10", MaxExclusive: "20"},
+		partitionKeyRange{ID: "3", MinInclusive: "20", MaxExclusive: "30"},
+		partitionKeyRange{ID: "4", MinInclusive: "30", MaxExclusive: "FF"})
+	container := newTestBulkContainer(t, service)
+
+	results := executeBulk(t, context.Background(), container, testUpserts(500), nil)
+	for i, result := range results {
+		if !result.Succeeded() || result.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected operation %d to create an item, got status %d and error %v", i, result.StatusCode, result.Err)
+		}
+		if result.ETag == "" || result.RequestCharge != 1 {
+			t.Errorf("Expected operation %d to have an ETag and a request charge, got %v", i, result)
+		}
+	}
+	if items := service.items(testBulkLink); len(items) != 500 {
+		t.Fatalf("Expected 500 items, got %d", len(items))
+	}
+
+	// The fake service rejects operations sent to the wrong partition key range.
+	ranges := map[string]int{}
+	for _, batch := range service.recordedBatches() {
+		if len(batch.ids) > maxBulkOperationsPerBatch {
+			t.Errorf("Expected at most %d operations per batch, got %d", maxBulkOperationsPerBatch, len(batch.ids))
+		}
+		ranges[batch.rangeID] += len(batch.ids)
+	}
+	if len(ranges) != 4 {
+		t.Errorf("Expected batches to all partition key ranges, got %v", ranges)
+	}
+}
+
+func TestExecuteBulkMaxOperationsPerBatch(t *testing.T) {
+	service := newFakeCosmosService()
+	container := newTestBulkContainer(t, service)
+
+	executeBulk(t, context.Background(), container, testUpserts(50), &BulkOptions{MaxOperationsPerBatch: 10, FlushInterval: time.Minute})
+	batches := service.recordedBatches()
+	if len(batches) != 5 {
+		t.Errorf("Expected 5 batches, got %d", len(batches))
+	}
+	for _, batch := range batches {
+		if len(batch.ids) != 10 {
+			t.Errorf("Expected batches of 10 operations, got %d", len(batch.ids))
+		}
+	}
+}
+
+func TestExecuteBulkOperationResults(t *testing.T) {
+	service := newFakeCosmosService()
+	container := newTestBulkContainer(t, service)
+	setup := executeBulk(t, context.Background(), container, testUpserts(5), nil)
+
+	patch := PatchOperations{}
+	patch.AppendSet("/value", "patched")
+	wrongETag := azcore.ETag(`"wrong"`)
+	operations := []BulkOperation{
+		NewBulkCreateOperation(NewPartitionKeyString("item-0"), []byte(`{"id":"item-0"}`)),
+		NewBulkCreateOperation(NewPartitionKeyString("new"), []byte(`{"id":"new"}`)),
+		NewBulkReplaceOperation(NewPartitionKeyString("missing"), "missing", []byte(`{"id":"missing"}`), nil),
+		NewBulkReplaceOperation(NewPartitionKeyString("item-1"), "item-1", []byte(`{"id":"item-1","value":"replaced"}`), &BulkItemOptions{IfMatchETag: &setup[1].ETag}),
+		NewBulkDeleteOperation(NewPartitionKeyString("item-2"), "item-2", nil),
+		NewBulkPatchOperation(NewPartitionKeyString("item-3"), "item-3", patch, nil),
+		NewBulkUpsertOperation(NewPartitionKeyString("item-4"), []byte(`{"id":"item-4"}`), &BulkItemOptions{IfMatchETag: &wrongETag}),
+		{},
+	}
+	results := executeBulk(t, context.Background(), container, operations, &BulkOptions{EnableContentResponseOnWrite: true})
+
+	expected := []int32{http.StatusConflict, http.StatusCreated, http.StatusNotFound, http.StatusOK, http.StatusNoContent, http.StatusOK, http.StatusPreconditionFailed}
+	for i, status := range expected {
+		if results[i].Err != nil || results[i].StatusCode != status {
+			t.Errorf("Expected operation %d to have status %d, got status %d and error %v", i, status, results[i].StatusCode, results[i].Err)
+		}
+		if results[i].Operation.PartitionKey().values[0] != operations[i].PartitionKey().values[0] {
+			t.Errorf("Expected result %d to contain its operation", i)
+		}
+	}
+	if results[7].Err == nil || results[7].Succeeded() {
+		t.Errorf("Expected an invalid operation to fail, got %v", results[7])
+	}
+
+	var patched map[string]any
+	if err := json.Unmarshal(results[5].ResourceBody, &patched); err != nil {
+		t.Fatal(err)
+	}
+	if patched["value"] != "patched" {
+		t.Errorf("Expected the patched item, got %v", patched)
+	}
+	if len(service.items(testBulkLink)) != 5 {
+		t.Errorf("Expected 5 items, got %v", service.items(testBulkLink))
+	}
+}
+
+func TestExecuteBulkRetriesThrottledOperations(t *testing.T) {
+	service := newFakeCosmosService()
+	service.throttledOperations = 150
+	container := newTestBulkContainer(t, service)
+
+	results := executeBulk(t, context.Background(), container, testUpserts(100), &BulkOptions{FlushInterval: time.Millisecond})
+	for i, result := range results {
+		if !result.Succeeded() {
+			t.Fatalf("Expected operation %d to succeed after being throttled, got status %d and error %v", i, result.StatusCode, result.Err)
+		}
+	}
+	if len(service.recordedBatches()) < 3 {
+		t.Errorf("Expected throttled operations to be retried, got %d batches", len(service.recordedBatches()))
+	}
+}
+
+func TestExecuteBulkThrottlingRetriesExhausted(t *testing.T) {
+	service := newFakeCosmosService()
+	service.throttledOperations = maxBulkThrottlingRetries + 1
+	container := newTestBulkContainer(t, service)
+
+	results := executeBulk(t, context.Background(), container, testUpserts(1), &BulkOptions{FlushInterval: time.Millisecond})
+	if results[0].Err != nil || results[0].StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected the operation to be throttled, got status %d and error %v", results[0].StatusCode, results[0].Err)
+	}
+}
+
+func TestExecuteBulkAfterSplit(t *testing.T) {
+	service := newFakeCosmosService()
+	container := newTestBulkContainer(t, service)
+	executeBulk(t, context.Background(), container, testUpserts(10), nil)
+
+	service.setPartitionKeyRanges(testBulkLink,
+		partitionKeyRange{ID: "1", MinInclusive: "", MaxExclusive: "20", Parents: []string{"0"}},
+		partitionKeyRange{ID: "2", MinInclusive: "20", MaxExclusive: "FF", Parents: []string{"0"}})
+	results := executeBulk(t, context.Background(), container, testUpserts(200), nil)
+	for i, result := range results {
+		if !result.Succeeded() {
+			t.Fatalf("Expected operation %d to succeed after the split, got status %d and error %v", i, result.StatusCode, result.Err)
+		}
+	}
+
+	ranges := map[string]bool{}
+	for _, batch := range service.recordedBatches() {
+		ranges[batch.rangeID] = true
+	}
+	if !ranges["1"] || !ranges["2"] {
+		t.Errorf("Expected batches to the child partition key ranges, got %v", ranges)
+	}
+}
+
+func TestExecuteBulkCancellation(t *testing.T) {
+	service := newFakeCosmosService()
+	service.batchGate = make(chan struct{})
+	container := newTestBulkContainer(t, service)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	results := executeBulk(t, ctx, container, testUpserts(300), &BulkOptions{FlushInterval: time.Millisecond})
+	for i, result := range results {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Fatalf("Expected operation %d to be cancelled, got status %d and error %v", i, result.StatusCode, result.Err)
+		}
+	}
+	if items := service.items(testBulkLink); len(items) != 0 {
+		t.Errorf("Expected no items, got %d", len(items))
+	}
+}
+
+func TestBulkOptionsDefaults(t *testing.T) {
+	options := BulkOptions{MaxOperationsPerBatch: 1000}
+	options.setDefaults()
+	if options.MaxOperationsPerBatch != maxBulkOperationsPerBatch {
+		t.Errorf("Expected MaxOperationsPerBatch %d, got %d", maxBulkOperationsPerBatch, options.MaxOperationsPerBatch)
+	}
+	if options.MaxConcurrencyPerPartitionKeyRange != 5 {
+		t.Errorf("Expected MaxConcurrencyPerPartitionKeyRange 5, got %d", options.MaxConcurrencyPerPartitionKeyRange)
+	}
+	if options.FlushInterval != 100*time.Millisecond {
+		t.Errorf("Expected FlushInterval 100ms, got %v", options.FlushInterval)
+	}
+}
+
+func TestBulkRequestOptionsToHeaders(t *testing.T) {
+	headers := *(&bulkRequestOptions{partitionKeyRangeID: "3"}).toHeaders()
+	expected := map[string]string{
+		cosmosHeaderIsBatchRequest:       "True",
+		cosmosHeaderIsBatchAtomic:        "False",
+		cosmosHeaderIsBatchOrdered:       "False",
+		cosmosHeaderBatchContinueOnError: "True",
+		cosmosHeaderPartitionKeyRangeId:  "3",
+	}
+	for k, v := range expected {
+		if headers[k] != v {
+			t.Errorf("Expected header %v to be %v, got %v", k, v, headers[k])
+		}
+	}
+}
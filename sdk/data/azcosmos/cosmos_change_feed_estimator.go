@@ -0,0 +1,128 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ChangeFeedEstimator estimates the number of changes the hosts of a change feed processor still have to process.
+type ChangeFeedEstimator struct {
+	container *ContainerClient
+	leases    *leaseStore
+}
+
+// ChangeFeedLeaseLag contains the estimated lag of a lease.
+type ChangeFeedLeaseLag struct {
+	// LeaseToken identifies the lease.
+	LeaseToken string
+	// Owner is the instance name of the host owning the lease, or empty if the lease isn't owned.
+	Owner string
+	// FeedRange is the feed range of the lease.
+	FeedRange FeedRange
+	// EstimatedLag is the estimated number of changes that weren't processed yet.
+	EstimatedLag int64
+}
+
+// NewChangeFeedEstimator creates an estimator for the change feed processor with the specified name.
+// name - The name of the processor.
+// leaseContainer - The container storing the leases of the processor.
+// o - Options for the estimator.
+func (c *ContainerClient) NewChangeFeedEstimator(name string, leaseContainer *ContainerClient, o *ChangeFeedEstimatorOptions) (*ChangeFeedEstimator, error) {
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if leaseContainer == nil {
+		return nil, errors.New("leaseContainer is required")
+	}
+	if o == nil {
+		o = &ChangeFeedEstimatorOptions{}
+	}
+	return &ChangeFeedEstimator{
+		container: c,
+		leases:    &leaseStore{container: leaseContainer, prefix: leaseIDPrefix(o.LeasePrefix, name)},
+	}, nil
+}
+
+// GetEstimatedLag returns the estimated lag of every lease of the processor.
+// The lag of a lease is the difference between the latest log sequence number of its feed range and the log sequence
+// number of its first unprocessed change, so it can be higher than the number of changed documents.
+func (e *ChangeFeedEstimator) GetEstimatedLag(ctx context.Context) ([]ChangeFeedLeaseLag, error) {
+	leases, err := e.leases.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pkrResp, err := e.container.getPartitionKeyRanges(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	lags := make([]ChangeFeedLeaseLag, 0, len(leases))
+	for _, lease := range leases {
+		lag := ChangeFeedLeaseLag{
+			LeaseToken: lease.LeaseToken,
+			Owner:      lease.Owner,
+			FeedRange:  lease.feedRange(),
+		}
+		// A lease that wasn't replaced yet after a split lags behind in every child range.
+		for _, pkr := range findOverlappingPartitionKeyRanges(lag.FeedRange, pkrResp.PartitionKeyRanges) {
+			feedRange := lag.FeedRange.intersect(pkr)
+			options := &ChangeFeedOptions{MaxItemCount: 1, FeedRange: &feedRange}
+			if lease.ContinuationToken != "" {
+				continuation := lease.ContinuationToken
+				options.Continuation = &continuation
+			}
+			resp, err := e.container.getChangeFeedForEPKRange(ctx, &feedRange, options, pkrResp.PartitionKeyRanges)
+			if err != nil {
+				return nil, err
+			}
+			rangeLag, err := estimateLag(resp)
+			if err != nil {
+				return nil, err
+			}
+			lag.EstimatedLag += rangeLag
+		}
+		lags = append(lags, lag)
+	}
+	return lags, nil
+}
+
+// estimateLag compares the log sequence number of the first change with the latest log sequence number in the session
+// token of the response.
+func estimateLag(resp ChangeFeedResponse) (int64, error) {
+	if len(resp.Documents) == 0 {
+		return 0, nil
+	}
+	var document struct {
+		LSN int64 `json:"_lsn"`
+	}
+	if err := json.Unmarshal(resp.Documents[0], &document); err != nil {
+		return 0, err
+	}
+	latest, err := parseSessionTokenLSN(resp.RawResponse.Header.Get(cosmosHeaderSessionToken))
+	if err != nil {
+		return 0, err
+	}
+	if lag := latest - document.LSN + 1; lag > 0 {
+		return lag, nil
+	}
+	return 0, nil
+}
+
+// parseSessionTokenLSN returns the global log sequence number of a session token, which is either
+// "<pkRangeId>:<lsn>" or "<pkRangeId>:<version>#<globalLsn>[#<regionId>=<lsn>...]".
+func parseSessionTokenLSN(sessionToken string) (int64, error) {
+	_, token, found := strings.Cut(sessionToken, ":")
+	if !found {
+		return 0, errors.New("invalid session token " + sessionToken)
+	}
+	parsed, err := parseVectorSessionToken(token)
+	if err != nil {
+		return 0, err
+	}
+	return parsed.globalLSN, nil
+}
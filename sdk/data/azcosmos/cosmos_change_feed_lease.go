@@ -0,0 +1,191 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// changeFeedLeaseVersion is the version of the lease document format.
+const changeFeedLeaseVersion = 1
+
+// errLeaseLost is returned when a lease was taken by another host or deleted.
+var errLeaseLost = errors.New("the lease is no longer owned by this host")
+
+// changeFeedLease is the document stored in the lease container for every feed range of the monitored container.
+type changeFeedLease struct {
+	// ID is the id of the lease document, which is also its partition key.
+	ID string `json:"id"`
+	// ETag is the entity tag of the lease document, used for optimistic concurrency.
+	ETag azcore.ETag `json:"_etag,omitempty"`
+	// Version is the version of the lease document format.
+	Version int `json:"version"`
+	// LeaseToken identifies the feed range of the lease.
+	LeaseToken string `json:"LeaseToken"`
+	// FeedRange is the feed range of the monitored container covered by the lease.
+	FeedRange changeFeedLeaseRange `json:"FeedRange"`
+	// ContinuationToken is the change feed continuation of the last processed batch.
+	ContinuationToken string `json:"ContinuationToken,omitempty"`
+	// Owner is the instance name of the host holding the lease.
+	Owner string `json:"Owner,omitempty"`
+	// Timestamp is the last time the lease was acquired, renewed or checkpointed.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type changeFeedLeaseRange struct {
+	EffectiveRange struct {
+		Min string `json:"min"`
+		Max string `json:"max"`
+	} `json:"Range"`
+}
+
+func newChangeFeedLease(prefix string, feedRange FeedRange, continuationToken string) *changeFeedLease {
+	lease := &changeFeedLease{
+		Version:           changeFeedLeaseVersion,
+		LeaseToken:        feedRange.MinInclusive + "-" + feedRange.MaxExclusive,
+		ContinuationToken: continuationToken,
+	}
+	lease.ID = prefix + lease.LeaseToken
+	lease.FeedRange.EffectiveRange.Min = feedRange.MinInclusive
+	lease.FeedRange.EffectiveRange.Max = feedRange.MaxExclusive
+	return lease
+}
+
+func (l *changeFeedLease) feedRange() FeedRange {
+	return NewFeedRange(l.FeedRange.EffectiveRange.Min, l.FeedRange.EffectiveRange.Max)
+}
+
+// isExpired reports whether the lease has no owner or its owner stopped renewing it.
+func (l *changeFeedLease) isExpired(expiration time.Duration, now time.Time) bool {
+	return l.Owner == "" || now.Sub(l.Timestamp) > expiration
+}
+
+// leaseStore reads and writes the leases of one processor in the lease container.
+// The lease container must be partitioned by /id.
+type leaseStore struct {
+	container *ContainerClient
+	// prefix is prepended to the id of every lease of the processor.
+	prefix string
+}
+
+func (s *leaseStore) list(ctx context.Context) ([]*changeFeedLease, error) {
+	pager := s.container.NewQueryItemsPager(
+		"SELECT * FROM c WHERE STARTSWITH(c.id, @prefix)",
+		NewPartitionKey(),
+		&QueryOptions{QueryParameters: []QueryParameter{{Name: "@prefix", Value: s.prefix}}})
+	var leases []*changeFeedLease
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			var lease changeFeedLease
+			if err := json.Unmarshal(item, &lease); err != nil {
+				return nil, err
+			}
+			leases = append(leases, &lease)
+		}
+	}
+	return leases, nil
+}
+
+// create stores a new lease. It returns false if a lease with the same id already exists.
+func (s *leaseStore) create(ctx context.Context, lease *changeFeedLease) (bool, error) {
+	marshalled, err := json.Marshal(lease)
+	if err != nil {
+		return false, err
+	}
+	resp, err := s.container.CreateItem(ctx, NewPartitionKeyString(lease.ID), marshalled, nil)
+	if err != nil {
+		if isStatusCode(err, http.StatusConflict) {
+			return false, nil
+		}
+		return false, err
+	}
+	lease.ETag = resp.ETag
+	return true, nil
+}
+
+func (s *leaseStore) read(ctx context.Context, id string) (*changeFeedLease, error) {
+	resp, err := s.container.ReadItem(ctx, NewPartitionKeyString(id), id, nil)
+	if err != nil {
+		return nil, err
+	}
+	var lease changeFeedLease
+	if err := json.Unmarshal(resp.Value, &lease); err != nil {
+		return nil, err
+	}
+	lease.ETag = resp.ETag
+	return &lease, nil
+}
+
+// replace writes the lease if it wasn't modified since it was read.
+func (s *leaseStore) replace(ctx context.Context, lease *changeFeedLease) error {
+	marshalled, err := json.Marshal(lease)
+	if err != nil {
+		return err
+	}
+	etag := lease.ETag
+	resp, err := s.container.ReplaceItem(ctx, NewPartitionKeyString(lease.ID), lease.ID, marshalled, &ItemOptions{IfMatchEtag: &etag})
+	if err != nil {
+		return err
+	}
+	lease.ETag = resp.ETag
+	return nil
+}
+
+// delete removes the lease if it wasn't modified since it was read. It returns errLeaseLost otherwise.
+func (s *leaseStore) delete(ctx context.Context, lease *changeFeedLease) error {
+	etag := lease.ETag
+	_, err := s.container.DeleteItem(ctx, NewPartitionKeyString(lease.ID), lease.ID, &ItemOptions{IfMatchEtag: &etag})
+	if isStatusCode(err, http.StatusPreconditionFailed) {
+		return errLeaseLost
+	}
+	if err != nil && !isStatusCode(err, http.StatusNotFound) {
+		return err
+	}
+	return nil
+}
+
+// update applies mutate to the lease and replaces it. When the lease was modified concurrently, it is read again and
+// mutate is applied to the latest version, which can reject the update by returning errLeaseLost.
+func (s *leaseStore) update(ctx context.Context, lease *changeFeedLease, mutate func(*changeFeedLease) error) (*changeFeedLease, error) {
+	current := *lease
+	for {
+		if err := mutate(&current); err != nil {
+			return nil, err
+		}
+		err := s.replace(ctx, &current)
+		if err == nil {
+			return &current, nil
+		}
+		if isStatusCode(err, http.StatusNotFound) {
+			return nil, errLeaseLost
+		}
+		if !isStatusCode(err, http.StatusPreconditionFailed) {
+			return nil, err
+		}
+		latest, err := s.read(ctx, lease.ID)
+		if err != nil {
+			if isStatusCode(err, http.StatusNotFound) {
+				return nil, errLeaseLost
+			}
+			return nil, err
+		}
+		current = *latest
+	}
+}
+
+// isStatusCode reports whether err is a *azcore.ResponseError with the given status code.
+func isStatusCode(err error, statusCode int) bool {
+	var azErr *azcore.ResponseError
+	return errors.As(err, &azErr) && azErr.StatusCode == statusCode
+}
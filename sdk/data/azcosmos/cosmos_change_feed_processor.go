@@ -0,0 +1,493 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+// cSpell:ignore Writef
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
+)
+
+// EventChangeFeedProcessor contains logs related to the change feed processor.
+const EventChangeFeedProcessor log.Event = "ChangeFeedProcessor"
+
+// releaseTimeout bounds the time spent releasing leases when a processor stops.
+const releaseTimeout = 10 * time.Second
+
+// ChangeFeedChanges contains a batch of changes read from the feed range of a lease.
+type ChangeFeedChanges struct {
+	// LeaseToken identifies the lease the changes were read for.
+	LeaseToken string
+	// FeedRange is the feed range of the lease.
+	FeedRange FeedRange
+	// Documents contains the changed documents, in the order they were changed.
+	Documents []json.RawMessage
+	// RequestCharge is the request charge of reading the changes.
+	RequestCharge float32
+}
+
+// ChangeFeedHandler processes a batch of changes. When it returns nil, the progress is checkpointed in the lease
+// container. When it returns an error, the error is reported to ChangeFeedProcessorOptions.OnError and the same batch
+// is passed to the handler again.
+type ChangeFeedHandler func(ctx context.Context, changes ChangeFeedChanges) error
+
+// ChangeFeedProcessor distributes the feed ranges of a container across hosts and passes their changes to a handler.
+// Progress is stored in lease documents in a lease container, with one lease per feed range. When a feed range is
+// split, its lease is replaced with one lease per child range.
+type ChangeFeedProcessor struct {
+	container *ContainerClient
+	leases    *leaseStore
+	handler   ChangeFeedHandler
+	options   ChangeFeedProcessorOptions
+	started   atomic.Bool
+	// workers contains the running workers by lease id. It is only accessed by the goroutine executing Run.
+	workers map[string]*leaseWorker
+}
+
+// NewChangeFeedProcessor creates a processor for the changes of the container.
+// name - The name of the processor. Hosts sharing the work for a container use the same name.
+// leaseContainer - The container storing the leases. It must be partitioned by /id.
+// handler - The function processing the changes.
+// o - Options for the processor.
+func (c *ContainerClient) NewChangeFeedProcessor(name string, leaseContainer *ContainerClient, handler ChangeFeedHandler, o *ChangeFeedProcessorOptions) (*ChangeFeedProcessor, error) {
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if leaseContainer == nil {
+		return nil, errors.New("leaseContainer is required")
+	}
+	if handler == nil {
+		return nil, errors.New("handler is required")
+	}
+	options := ChangeFeedProcessorOptions{}
+	if o != nil {
+		options = *o
+	}
+	if err := options.setDefaults(); err != nil {
+		return nil, err
+	}
+	return &ChangeFeedProcessor{
+		container: c,
+		leases:    &leaseStore{container: leaseContainer, prefix: leaseIDPrefix(options.LeasePrefix, name)},
+		handler:   handler,
+		options:   options,
+		workers:   map[string]*leaseWorker{},
+	}, nil
+}
+
+// InstanceName returns the name identifying this host in the lease container.
+func (p *ChangeFeedProcessor) InstanceName() string {
+	return p.options.InstanceName
+}
+
+// Run processes changes until ctx is done, then releases the owned leases so other hosts can take them over.
+// It returns nil when ctx is done, or an error if the leases could not be initialized.
+// A processor can only be run once.
+func (p *ChangeFeedProcessor) Run(ctx context.Context) error {
+	if !p.started.CompareAndSwap(false, true) {
+		return errors.New("the change feed processor was already started")
+	}
+	if err := p.initializeLeases(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(p.options.LeaseAcquireInterval)
+	defer ticker.Stop()
+	for {
+		if err := p.balance(ctx); err != nil && ctx.Err() == nil {
+			p.reportError("", err)
+		}
+		select {
+		case <-ctx.Done():
+			p.stop(ctx)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// initializeLeases creates one lease per feed range when the processor runs for the first time.
+func (p *ChangeFeedProcessor) initializeLeases(ctx context.Context) error {
+	leases, err := p.leases.list(ctx)
+	if err != nil {
+		return err
+	}
+	if len(leases) > 0 {
+		return nil
+	}
+	feedRanges, err := p.container.GetFeedRanges(ctx)
+	if err != nil {
+		return err
+	}
+	for _, feedRange := range feedRanges {
+		// Lease ids are derived from the feed range, so hosts starting at the same time create the same leases.
+		if _, err := p.leases.create(ctx, newChangeFeedLease(p.leases.prefix, feedRange, "")); err != nil {
+			return err
+		}
+	}
+	log.Writef(EventChangeFeedProcessor, "Created %d leases for processor %s", len(feedRanges), p.leases.prefix)
+	return nil
+}
+
+// balance acquires leases until this host owns its share of them, and starts a worker for every owned lease.
+func (p *ChangeFeedProcessor) balance(ctx context.Context) error {
+	for id, w := range p.workers {
+		select {
+		case <-w.done:
+			delete(p.workers, id)
+		default:
+		}
+	}
+
+	leases, err := p.leases.list(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	self := p.options.InstanceName
+	ownedByHost := map[string]int{self: 0}
+	var expired []*changeFeedLease
+	for _, lease := range leases {
+		if lease.isExpired(p.options.LeaseExpirationInterval, now) {
+			expired = append(expired, lease)
+			continue
+		}
+		ownedByHost[lease.Owner]++
+	}
+
+	target := (len(leases) + len(ownedByHost) - 1) / len(ownedByHost)
+	var toAcquire []*changeFeedLease
+	if missing := target - ownedByHost[self]; missing > 0 {
+		rand.Shuffle(len(expired), func(i, j int) { expired[i], expired[j] = expired[j], expired[i] })
+		if len(expired) > missing {
+			expired = expired[:missing]
+		}
+		toAcquire = expired
+		if len(toAcquire) == 0 {
+			if lease := p.leaseToSteal(leases, ownedByHost, target); lease != nil {
+				toAcquire = append(toAcquire, lease)
+			}
+		}
+	}
+
+	for _, lease := range toAcquire {
+		acquired := *lease
+		acquired.Owner = self
+		acquired.Timestamp = now
+		if err := p.leases.replace(ctx, &acquired); err != nil {
+			if isStatusCode(err, http.StatusPreconditionFailed) || isStatusCode(err, http.StatusNotFound) {
+				// Another host acquired or split the lease first.
+				continue
+			}
+			return err
+		}
+		log.Writef(EventChangeFeedProcessor, "Host %s acquired lease %s from %q", self, lease.LeaseToken, lease.Owner)
+		*lease = acquired
+	}
+
+	for _, lease := range leases {
+		if lease.Owner != self || lease.isExpired(p.options.LeaseExpirationInterval, now) {
+			continue
+		}
+		if _, ok := p.workers[lease.ID]; !ok {
+			p.workers[lease.ID] = p.startWorker(ctx, lease)
+		}
+	}
+	return nil
+}
+
+// leaseToSteal returns a lease of the host owning the most leases, when that host owns more than its share.
+func (p *ChangeFeedProcessor) leaseToSteal(leases []*changeFeedLease, ownedByHost map[string]int, target int) *changeFeedLease {
+	busiest := ""
+	for host, count := range ownedByHost {
+		if host != p.options.InstanceName && count > target && (busiest == "" || count > ownedByHost[busiest]) {
+			busiest = host
+		}
+	}
+	if busiest == "" {
+		return nil
+	}
+	for _, lease := range leases {
+		if lease.Owner == busiest {
+			return lease
+		}
+	}
+	return nil
+}
+
+// stop waits for the workers to finish and releases their leases.
+func (p *ChangeFeedProcessor) stop(ctx context.Context) {
+	releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+	for _, w := range p.workers {
+		<-w.done
+		if !w.owned {
+			continue
+		}
+		err := w.updateLease(releaseCtx, func(lease *changeFeedLease) {
+			lease.Owner = ""
+		})
+		if err != nil && !errors.Is(err, errLeaseLost) {
+			p.reportError(w.lease.LeaseToken, err)
+		}
+	}
+}
+
+func (p *ChangeFeedProcessor) reportError(leaseToken string, err error) {
+	log.Writef(EventChangeFeedProcessor, "Error for lease %q: %v", leaseToken, err)
+	if p.options.OnError != nil {
+		p.options.OnError(leaseToken, err)
+	}
+}
+
+// leaseWorker processes the changes of a lease owned by the host.
+type leaseWorker struct {
+	p *ChangeFeedProcessor
+	// mu serializes updates of the lease by the renew and processing loops.
+	mu    sync.Mutex
+	lease *changeFeedLease
+	// owned is false once the lease was lost or replaced by its children. It is only read after done is closed.
+	owned  bool
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (p *ChangeFeedProcessor) startWorker(ctx context.Context, lease *changeFeedLease) *leaseWorker {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &leaseWorker{
+		p:      p,
+		lease:  lease,
+		owned:  true,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(w.done)
+		defer cancel()
+		renewed := make(chan struct{})
+		go func() {
+			defer close(renewed)
+			w.renew(ctx)
+		}()
+		w.process(ctx)
+		cancel()
+		<-renewed
+	}()
+	return w
+}
+
+// renew keeps the lease from expiring until ctx is done or the lease is lost.
+func (w *leaseWorker) renew(ctx context.Context) {
+	ticker := time.NewTicker(w.p.options.LeaseRenewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := w.updateLease(ctx, func(*changeFeedLease) {}); err != nil {
+			if errors.Is(err, errLeaseLost) {
+				w.lost()
+				return
+			}
+			if ctx.Err() == nil {
+				w.p.reportError(w.leaseToken(), err)
+			}
+		}
+	}
+}
+
+// process reads the changes of the lease and passes them to the handler until ctx is done, the lease is lost or the
+// lease was split.
+func (w *leaseWorker) process(ctx context.Context) {
+	for ctx.Err() == nil {
+		w.mu.Lock()
+		feedRange := w.lease.feedRange()
+		options := w.p.options.changeFeedOptions(feedRange, w.lease.ContinuationToken)
+		leaseToken := w.lease.LeaseToken
+		w.mu.Unlock()
+
+		pkrResp, err := w.p.container.getPartitionKeyRanges(ctx, nil)
+		if err != nil {
+			w.retryLater(ctx, err)
+			continue
+		}
+		overlapping := findOverlappingPartitionKeyRanges(feedRange, pkrResp.PartitionKeyRanges)
+		if len(overlapping) > 1 {
+			if err := w.split(ctx, overlapping); err != nil {
+				if errors.Is(err, errLeaseLost) {
+					w.lost()
+					return
+				}
+				w.retryLater(ctx, err)
+				continue
+			}
+			return
+		}
+
+		resp, err := w.p.container.getChangeFeedForEPKRange(ctx, &feedRange, options, pkrResp.PartitionKeyRanges)
+		if err != nil {
+			if isPartitionGone(err) {
+				// Read the partition key ranges again to find the children of the range.
+				log.Writef(EventChangeFeedProcessor, "Feed range of lease %s is gone", leaseToken)
+				w.sleep(ctx)
+				continue
+			}
+			w.retryLater(ctx, err)
+			continue
+		}
+
+		if len(resp.Documents) == 0 {
+			// Checkpoint the current position of the feed, so that restarts don't skip or repeat changes.
+			if resp.ETag != "" && string(resp.ETag) != options.continuationToken() {
+				if !w.checkpoint(ctx, resp.ETag) {
+					return
+				}
+			}
+			w.sleep(ctx)
+			continue
+		}
+
+		err = w.p.handler(ctx, ChangeFeedChanges{
+			LeaseToken:    leaseToken,
+			FeedRange:     feedRange,
+			Documents:     resp.Documents,
+			RequestCharge: resp.RequestCharge,
+		})
+		if err != nil {
+			w.retryLater(ctx, err)
+			continue
+		}
+		if !w.checkpoint(ctx, resp.ETag) {
+			return
+		}
+	}
+}
+
+// checkpoint stores the continuation of the last processed changes. It returns false if the lease was lost.
+func (w *leaseWorker) checkpoint(ctx context.Context, continuation azcore.ETag) bool {
+	err := w.updateLease(ctx, func(lease *changeFeedLease) {
+		lease.ContinuationToken = string(continuation)
+	})
+	if errors.Is(err, errLeaseLost) {
+		w.lost()
+		return false
+	}
+	if err != nil && ctx.Err() == nil {
+		w.p.reportError(w.leaseToken(), err)
+	}
+	return true
+}
+
+// split replaces the lease with a lease for every child range, starting from the lease's continuation. The child
+// leases are acquired by any host during the next balancing.
+func (w *leaseWorker) split(ctx context.Context, children []partitionKeyRange) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, child := range children {
+		childLease := newChangeFeedLease(w.p.leases.prefix, w.lease.feedRange().intersect(child), w.lease.ContinuationToken)
+		if _, err := w.p.leases.create(ctx, childLease); err != nil {
+			return err
+		}
+	}
+	if err := w.p.leases.delete(ctx, w.lease); err != nil {
+		return err
+	}
+	log.Writef(EventChangeFeedProcessor, "Replaced lease %s with %d child leases", w.lease.LeaseToken, len(children))
+	w.owned = false
+	return nil
+}
+
+// updateLease applies mutate to the lease, renewing it. It returns errLeaseLost if another host owns the lease.
+func (w *leaseWorker) updateLease(ctx context.Context, mutate func(*changeFeedLease)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.owned {
+		return errLeaseLost
+	}
+	owner := w.p.options.InstanceName
+	updated, err := w.p.leases.update(ctx, w.lease, func(lease *changeFeedLease) error {
+		if lease.Owner != owner {
+			return errLeaseLost
+		}
+		mutate(lease)
+		lease.Timestamp = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	w.lease = updated
+	return nil
+}
+
+// lost stops the worker after the lease was taken by another host.
+func (w *leaseWorker) lost() {
+	w.mu.Lock()
+	w.owned = false
+	w.mu.Unlock()
+	log.Writef(EventChangeFeedProcessor, "Host %s lost lease %s", w.p.options.InstanceName, w.leaseToken())
+	w.cancel()
+}
+
+func (w *leaseWorker) leaseToken() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lease.LeaseToken
+}
+
+func (w *leaseWorker) retryLater(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	w.p.reportError(w.leaseToken(), err)
+	w.sleep(ctx)
+}
+
+func (w *leaseWorker) sleep(ctx context.Context) {
+	timer := time.NewTimer(w.p.options.PollInterval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// continuationToken returns the continuation the options read from, or an empty string.
+func (options *ChangeFeedOptions) continuationToken() string {
+	if options.Continuation == nil {
+		return ""
+	}
+	return *options.Continuation
+}
+
+// isPartitionGone reports whether the request failed because the partition key range was split or merged.
+func isPartitionGone(err error) bool {
+	var azErr *azcore.ResponseError
+	if !errors.As(err, &azErr) || azErr.StatusCode != http.StatusGone || azErr.RawResponse == nil {
+		return false
+	}
+	switch azErr.RawResponse.Header.Get(cosmosHeaderSubstatus) {
+	case subStatusPartitionKeyRangeGone, subStatusCompletingSplit, subStatusCompletingMigration:
+		return true
+	}
+	return false
+}
+
+// leaseIDPrefix returns the prefix of the lease ids of a processor.
+func leaseIDPrefix(leasePrefix string, name string) string {
+	return leasePrefix + name + ".."
+}
@@ -0,0 +1,85 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/uuid"
+)
+
+// ChangeFeedProcessorOptions includes options for NewChangeFeedProcessor.
+type ChangeFeedProcessorOptions struct {
+	// InstanceName identifies the host in the lease container. Every running processor with the same name needs a
+	// unique instance name. The default is a random UUID.
+	InstanceName string
+	// LeasePrefix is prepended to the id of the lease documents, so that several containers can share a lease container.
+	LeasePrefix string
+	// StartFrom reads changes made after the specified time, when no progress was checkpointed for a lease.
+	StartFrom *time.Time
+	// StartFromBeginning reads all the changes of the container, when no progress was checkpointed for a lease.
+	// By default, only changes made after the processor started for the first time are read.
+	StartFromBeginning bool
+	// MaxItemCount limits the number of changes passed to a single handler invocation.
+	MaxItemCount int32
+	// PollInterval is the delay before reading a feed range again once all its changes were processed. The default is 5 seconds.
+	PollInterval time.Duration
+	// LeaseAcquireInterval is the interval at which leases are balanced across hosts. The default is 13 seconds.
+	LeaseAcquireInterval time.Duration
+	// LeaseRenewInterval is the interval at which owned leases are renewed. The default is 17 seconds.
+	LeaseRenewInterval time.Duration
+	// LeaseExpirationInterval is the time after which a lease that wasn't renewed can be taken by another host.
+	// The default is 60 seconds.
+	LeaseExpirationInterval time.Duration
+	// OnError is called with errors returned by the handler or encountered while reading the change feed and managing
+	// leases. The lease token is empty for errors that aren't specific to a lease.
+	OnError func(leaseToken string, err error)
+}
+
+func (options *ChangeFeedProcessorOptions) setDefaults() error {
+	if options.InstanceName == "" {
+		id, err := uuid.New()
+		if err != nil {
+			return err
+		}
+		options.InstanceName = id.String()
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = 5 * time.Second
+	}
+	if options.LeaseAcquireInterval <= 0 {
+		options.LeaseAcquireInterval = 13 * time.Second
+	}
+	if options.LeaseRenewInterval <= 0 {
+		options.LeaseRenewInterval = 17 * time.Second
+	}
+	if options.LeaseExpirationInterval <= 0 {
+		options.LeaseExpirationInterval = 60 * time.Second
+	}
+	return nil
+}
+
+// changeFeedOptions returns the options to read the change feed of a lease from its checkpoint.
+func (options *ChangeFeedProcessorOptions) changeFeedOptions(feedRange FeedRange, continuationToken string) *ChangeFeedOptions {
+	changeFeedOptions := &ChangeFeedOptions{
+		MaxItemCount: options.MaxItemCount,
+		FeedRange:    &feedRange,
+	}
+	switch {
+	case continuationToken != "":
+		changeFeedOptions.Continuation = &continuationToken
+	case options.StartFrom != nil:
+		changeFeedOptions.StartFrom = options.StartFrom
+	case !options.StartFromBeginning:
+		now := "*"
+		changeFeedOptions.Continuation = &now
+	}
+	return changeFeedOptions
+}
+
+// ChangeFeedEstimatorOptions includes options for NewChangeFeedEstimator.
+type ChangeFeedEstimatorOptions struct {
+	// LeasePrefix must match the ChangeFeedProcessorOptions.LeasePrefix of the monitored processor.
+	LeasePrefix string
+}
@@ -0,0 +1,381 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testMonitoredLink = "dbs/db/colls/items"
+	testLeaseLink     = "dbs/db/colls/leases"
+)
+
+func newTestChangeFeedContainers(t *testing.T, service *fakeCosmosService) (*ContainerClient, *ContainerClient) {
+	database, _ := newDatabase("db", service.newClient(t))
+	monitored, _ := newContainer("items", database)
+	leases, _ := newContainer("leases", database)
+	return monitored, leases
+}
+
+func testProcessorOptions(instanceName string) *ChangeFeedProcessorOptions {
+	return &ChangeFeedProcessorOptions{
+		InstanceName:            instanceName,
+		StartFromBeginning:      true,
+		PollInterval:            5 * time.Millisecond,
+		LeaseAcquireInterval:    20 * time.Millisecond,
+		LeaseRenewInterval:      50 * time.Millisecond,
+		LeaseExpirationInterval: time.Second,
+	}
+}
+
+// changeRecorder is a ChangeFeedHandler recording the ids of the processed documents. Changes are delivered at least
+// once, so a change can be processed again by the new owner of a lease that was taken over.
+type changeRecorder struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (r *changeRecorder) handle(ctx context.Context, changes ChangeFeedChanges) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, document := range changes.Documents {
+		var doc struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(document, &doc); err != nil {
+			return err
+		}
+		r.ids = append(r.ids, doc.ID)
+	}
+	return nil
+}
+
+func (r *changeRecorder) processed() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := map[string]bool{}
+	var ids []string
+	for _, id := range r.ids {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func runProcessor(t *testing.T, p *ChangeFeedProcessor) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.Run(ctx) }()
+	return func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run returned error: %v", err)
+		}
+	}
+}
+
+func waitFor(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", description)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func leasesByOwner(service *fakeCosmosService) map[string]int {
+	owners := map[string]int{}
+	for _, lease := range service.items(testLeaseLink) {
+		owner, _ := lease["Owner"].(string)
+		owners[owner]++
+	}
+	return owners
+}
+
+func equalIDs(actual []string, expected ...string) bool {
+	if len(actual) != len(expected) {
+		return false
+	}
+	for i := range actual {
+		if actual[i] != expected[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestChangeFeedProcessorProcessesAndCheckpoints(t *testing.T) {
+	service := newFakeCosmosService()
+	service.setPartitionKeyRanges(testMonitoredLink,
+		partitionKeyRange{ID: "0", MinInclusive: "", MaxExclusive: "80"},
+		partitionKeyRange{ID: "1", MinInclusive: "80", MaxExclusive: "FF"})
+	service.addChange(testMonitoredLink, "10", map[string]any{"id": "a"})
+	service.addChange(testMonitoredLink, "90", map[string]any{"id": "b"})
+	monitored, leases := newTestChangeFeedContainers(t, service)
+
+	recorder := &changeRecorder{}
+	failures := 1
+	var reported []error
+	var reportedMu sync.Mutex
+	options := testProcessorOptions("host1")
+	options.MaxItemCount = 1
+	options.OnError = func(leaseToken string, err error) {
+		reportedMu.Lock()
+		defer reportedMu.Unlock()
+		reported = append(reported, err)
+	}
+	handlerErr := errors.New("handler failed")
+	var handlerMu sync.Mutex
+	p, err := monitored.NewChangeFeedProcessor("processor", leases, func(ctx context.Context, changes ChangeFeedChanges) error {
+		handlerMu.Lock()
+		defer handlerMu.Unlock()
+		if failures > 0 {
+			failures--
+			return handlerErr
+		}
+		return recorder.handle(ctx, changes)
+	}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := runProcessor(t, p)
+
+	waitFor(t, "initial changes", func() bool { return equalIDs(recorder.processed(), "a", "b") })
+	service.addChange(testMonitoredLink, "20", map[string]any{"id": "c"})
+	waitFor(t, "new changes", func() bool { return equalIDs(recorder.processed(), "a", "b", "c") })
+	stop()
+
+	reportedMu.Lock()
+	if len(reported) != 1 || !errors.Is(reported[0], handlerErr) {
+		t.Errorf("Expected the handler error to be reported once, got %v", reported)
+	}
+	reportedMu.Unlock()
+
+	stored := service.items(testLeaseLink)
+	if len(stored) != 2 {
+		t.Fatalf("Expected 2 leases, got %d", len(stored))
+	}
+	expected := map[string]string{"processor..-80": `"3"`, "processor..80-FF": `"2"`}
+	for _, lease := range stored {
+		if lease["ContinuationToken"] != expected[lease["id"].(string)] {
+			t.Errorf("Unexpected continuation %v for lease %v", lease["ContinuationToken"], lease["id"])
+		}
+		if _, owned := lease["Owner"]; owned {
+			t.Errorf("Expected lease %v to be released, owner is %v", lease["id"], lease["Owner"])
+		}
+	}
+
+	if err := p.Run(context.Background()); err == nil {
+		t.Error("Expected an error when running a processor twice")
+	}
+}
+
+func TestChangeFeedProcessorStartsFromNow(t *testing.T) {
+	service := newFakeCosmosService()
+	service.addChange(testMonitoredLink, "10", map[string]any{"id": "old"})
+	monitored, leases := newTestChangeFeedContainers(t, service)
+
+	recorder := &changeRecorder{}
+	options := testProcessorOptions("host1")
+	options.StartFromBeginning = false
+	p, err := monitored.NewChangeFeedProcessor("processor", leases, recorder.handle, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := runProcessor(t, p)
+	defer stop()
+
+	waitFor(t, "checkpoint of the current position", func() bool {
+		stored := service.items(testLeaseLink)
+		return len(stored) == 1 && stored[0]["ContinuationToken"] == `"1"`
+	})
+	service.addChange(testMonitoredLink, "10", map[string]any{"id": "new"})
+	waitFor(t, "new changes", func() bool { return equalIDs(recorder.processed(), "new") })
+}
+
+func TestChangeFeedProcessorLoadBalancing(t *testing.T) {
+	service := newFakeCosmosService()
+	service.setPartitionKeyRanges(testMonitoredLink,
+		partitionKeyRange{ID: "0", MinInclusive: "", MaxExclusive: "40"},
+		partitionKeyRange{ID: "1", MinInclusive: "40", MaxExclusive: "80"},
+		partitionKeyRange{ID: "2", MinInclusive: "80", MaxExclusive: "C0"},
+		partitionKeyRange{ID: "3", MinInclusive: "C0", MaxExclusive: "FF"})
+	monitored, leases := newTestChangeFeedContainers(t, service)
+
+	recorder := &changeRecorder{}
+	p1, err := monitored.NewChangeFeedProcessor("processor", leases, recorder.handle, testProcessorOptions("host1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop1 := runProcessor(t, p1)
+	waitFor(t, "host1 to own all leases", func() bool { return leasesByOwner(service)["host1"] == 4 })
+
+	p2, err := monitored.NewChangeFeedProcessor("processor", leases, recorder.handle, testProcessorOptions("host2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop2 := runProcessor(t, p2)
+	defer stop2()
+	waitFor(t, "leases to be balanced", func() bool {
+		owners := leasesByOwner(service)
+		return owners["host1"] == 2 && owners["host2"] == 2
+	})
+
+	for i, epk := range []string{"10", "50", "90", "D0"} {
+		service.addChange(testMonitoredLink, epk, map[string]any{"id": string(rune('a' + i))})
+	}
+	waitFor(t, "changes of all ranges", func() bool { return equalIDs(recorder.processed(), "a", "b", "c", "d") })
+
+	stop1()
+	waitFor(t, "host2 to take over the released leases", func() bool { return leasesByOwner(service)["host2"] == 4 })
+}
+
+func TestChangeFeedProcessorSplit(t *testing.T) {
+	service := newFakeCosmosService()
+	service.addChange(testMonitoredLink, "10", map[string]any{"id": "a"})
+	monitored, leases := newTestChangeFeedContainers(t, service)
+
+	recorder := &changeRecorder{}
+	p, err := monitored.NewChangeFeedProcessor("processor", leases, recorder.handle, testProcessorOptions("host1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := runProcessor(t, p)
+	defer stop()
+	waitFor(t, "changes before the split", func() bool { return equalIDs(recorder.processed(), "a") })
+
+	service.setPartitionKeyRanges(testMonitoredLink,
+		partitionKeyRange{ID: "1", MinInclusive: "", MaxExclusive: "80", Parents: []string{"0"}},
+		partitionKeyRange{ID: "2", MinInclusive: "80", MaxExclusive: "FF", Parents: []string{"0"}})
+	service.addChange(testMonitoredLink, "20", map[string]any{"id": "b"})
+	service.addChange(testMonitoredLink, "90", map[string]any{"id": "c"})
+	waitFor(t, "changes after the split", func() bool { return equalIDs(recorder.processed(), "a", "b", "c") })
+
+	var ids []string
+	for _, lease := range service.items(testLeaseLink) {
+		ids = append(ids, lease["id"].(string))
+	}
+	if !equalIDs(ids, "processor..-80", "processor..80-FF") {
+		t.Errorf("Expected the lease to be replaced by child leases, got %v", ids)
+	}
+}
+
+func TestChangeFeedProcessorMerge(t *testing.T) {
+	service := newFakeCosmosService()
+	service.setPartitionKeyRanges(testMonitoredLink,
+		partitionKeyRange{ID: "0", MinInclusive: "", MaxExclusive: "80"},
+		partitionKeyRange{ID: "1", MinInclusive: "80", MaxExclusive: "FF"})
+	monitored, leases := newTestChangeFeedContainers(t, service)
+
+	recorder := &changeRecorder{}
+	p, err := monitored.NewChangeFeedProcessor("processor", leases, recorder.handle, testProcessorOptions("host1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := runProcessor(t, p)
+	defer stop()
+	waitFor(t, "leases to be acquired", func() bool { return leasesByOwner(service)["host1"] == 2 })
+
+	service.setPartitionKeyRanges(testMonitoredLink,
+		partitionKeyRange{ID: "2", MinInclusive: "", MaxExclusive: "FF", Parents: []string{"0", "1"}})
+	service.addChange(testMonitoredLink, "10", map[string]any{"id": "a"})
+	service.addChange(testMonitoredLink, "90", map[string]any{"id": "b"})
+	waitFor(t, "changes after the merge", func() bool { return equalIDs(recorder.processed(), "a", "b") })
+}
+
+func TestChangeFeedProcessorValidation(t *testing.T) {
+	service := newFakeCosmosService()
+	monitored, leases := newTestChangeFeedContainers(t, service)
+	recorder := &changeRecorder{}
+	if _, err := monitored.NewChangeFeedProcessor("", leases, recorder.handle, nil); err == nil {
+		t.Error("Expected an error for an empty name")
+	}
+	if _, err := monitored.NewChangeFeedProcessor("processor", nil, recorder.handle, nil); err == nil {
+		t.Error("Expected an error for a missing lease container")
+	}
+	if _, err := monitored.NewChangeFeedProcessor("processor", leases, nil, nil); err == nil {
+		t.Error("Expected an error for a missing handler")
+	}
+	p, err := monitored.NewChangeFeedProcessor("processor", leases, recorder.handle, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.InstanceName() == "" {
+		t.Error("Expected a generated instance name")
+	}
+	if p.options.PollInterval != 5*time.Second || p.options.LeaseExpirationInterval != time.Minute {
+		t.Errorf("Unexpected default options %+v", p.options)
+	}
+}
+
+func TestChangeFeedEstimator(t *testing.T) {
+	service := newFakeCosmosService()
+	service.setPartitionKeyRanges(testMonitoredLink,
+		partitionKeyRange{ID: "1", MinInclusive: "", MaxExclusive: "80"},
+		partitionKeyRange{ID: "2", MinInclusive: "80", MaxExclusive: "C0", Parents: []string{"0"}},
+		partitionKeyRange{ID: "3", MinInclusive: "C0", MaxExclusive: "FF", Parents: []string{"0"}})
+	monitored, leases := newTestChangeFeedContainers(t, service)
+	for _, epk := range []string{"10", "90", "20", "30", "A0", "D0"} {
+		service.addChange(testMonitoredLink, epk, map[string]any{"id": epk})
+	}
+
+	ctx := context.Background()
+	store := &leaseStore{container: leases, prefix: leaseIDPrefix("", "processor")}
+	// The first lease processed the change with LSN 1. The second lease didn't process anything and wasn't replaced by
+	// the leases of its child ranges yet.
+	if _, err := store.create(ctx, newChangeFeedLease(store.prefix, NewFeedRange("", "80"), `"1"`)); err != nil {
+		t.Fatal(err)
+	}
+	parent := newChangeFeedLease(store.prefix, NewFeedRange("80", "FF"), "")
+	parent.Owner = "host1"
+	if _, err := store.create(ctx, parent); err != nil {
+		t.Fatal(err)
+	}
+
+	estimator, err := monitored.NewChangeFeedEstimator("processor", leases, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lags, err := estimator.GetEstimatedLag(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(lags, func(i, j int) bool { return lags[i].LeaseToken < lags[j].LeaseToken })
+	expected := []ChangeFeedLeaseLag{
+		{LeaseToken: "-80", FeedRange: NewFeedRange("", "80"), EstimatedLag: 2},
+		{LeaseToken: "80-FF", Owner: "host1", FeedRange: NewFeedRange("80", "FF"), EstimatedLag: 5},
+	}
+	if len(lags) != len(expected) {
+		t.Fatalf("Expected %d lags, got %+v", len(expected), lags)
+	}
+	for i := range expected {
+		if lags[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], lags[i])
+		}
+	}
+}
+
+func TestParseSessionTokenLSN(t *testing.T) {
+	for token, expected := range map[string]int64{"0:42": 42, "1:-1#123": 123, "2:1#456#1=20#2=30": 456} {
+		lsn, err := parseSessionTokenLSN(token)
+		if err != nil || lsn != expected {
+			t.Errorf("Expected %d for %s, got %d (%v)", expected, token, lsn, err)
+		}
+	}
+	if _, err := parseSessionTokenLSN("invalid"); err == nil {
+		t.Error("Expected an error for an invalid session token")
+	}
+}
@@ -72,6 +72,11 @@ func (options *ChangeFeedOptions) toHeaders(partitionKeyRanges []partitionKeyRan
 	if options.FeedRange != nil && len(partitionKeyRanges) > 0 {
 		if id, err := findPartitionKeyRangeID(*options.FeedRange, partitionKeyRanges); err == nil {
 			headers[headerXmsDocumentDbPartitionKeyRangeId] = id
+		} else if pkr, ok := findContainingPartitionKeyRange(*options.FeedRange, partitionKeyRanges); ok {
+			// The feed range is only part of a physical partition, so scope the request to its effective partition keys.
+			headers[headerXmsDocumentDbPartitionKeyRangeId] = pkr.ID
+			headers[cosmosHeaderStartEpk] = options.FeedRange.MinInclusive
+			headers[cosmosHeaderEndEpk] = options.FeedRange.MaxExclusive
 		} else {
 			return nil
 		}
@@ -266,3 +266,34 @@ func TestChangeFeedOptionsCompositeContinuationTokenWithExistingFeedRange(t *tes
 		t.Errorf("Expected FeedRange.MaxExclusive to remain BB, got %v", options.FeedRange.MaxExclusive)
 	}
 }
+
+func TestChangeFeedOptionsToHeadersWithSubRange(t *testing.T) {
+	partitionKeyRanges := []partitionKeyRange{
+		{ID: "0", MinInclusive: "", MaxExclusive: "80"},
+		{ID: "1", MinInclusive: "80", MaxExclusive: "FF"},
+	}
+
+	options := &ChangeFeedOptions{FeedRange: &FeedRange{MinInclusive: "80", MaxExclusive: "C0"}}
+	headers := options.toHeaders(partitionKeyRanges)
+	if headers == nil {
+		t.Fatal("toHeaders should return non-nil")
+	}
+	h := *headers
+	if h[headerXmsDocumentDbPartitionKeyRangeId] != "1" {
+		t.Errorf("Expected partition key range id 1, got %v", h[headerXmsDocumentDbPartitionKeyRangeId])
+	}
+	if h[cosmosHeaderStartEpk] != "80" || h[cosmosHeaderEndEpk] != "C0" {
+		t.Errorf("Expected effective partition key range [80, C0), got [%v, %v)", h[cosmosHeaderStartEpk], h[cosmosHeaderEndEpk])
+	}
+
+	options = &ChangeFeedOptions{FeedRange: &FeedRange{MinInclusive: "", MaxExclusive: "80"}}
+	h = *options.toHeaders(partitionKeyRanges)
+	if _, ok := h[cosmosHeaderStartEpk]; ok {
+		t.Error("Start effective partition key header should not be set for an exact match")
+	}
+
+	options = &ChangeFeedOptions{FeedRange: &FeedRange{MinInclusive: "40", MaxExclusive: "C0"}}
+	if headers := options.toHeaders(partitionKeyRanges); headers != nil {
+		t.Errorf("Expected nil headers for a feed range spanning several partition key ranges, got %v", *headers)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -28,10 +29,24 @@ const (
 
 // Client is used to interact with the Azure Cosmos DB database service.
 type Client struct {
-	endpoint    string
-	internal    *azcore.Client
-	gem         *globalEndpointManager
-	endpointUrl *url.URL
+	endpoint         string
+	internal         *azcore.Client
+	gem              *globalEndpointManager
+	endpointUrl      *url.URL
+	pkRangeCacheOnce sync.Once
+	pkRangeCache     *partitionKeyRangeCache
+	// sessionContainerOnce guards the lazy creation of sessionContainer, the session tokens tracked by the client.
+	sessionContainerOnce sync.Once
+	sessionContainer     *sessionContainer
+	// diagnosticsThresholds reports the diagnostics of slow or expensive operations, when set.
+	diagnosticsThresholds *DiagnosticsThresholds
+	// availabilityStrategy sends reads and queries on items to several regions, when set.
+	availabilityStrategy *AvailabilityStrategy
+	// throughputControlOnce guards the lazy creation of throughputControlGroups, the throughput control groups enabled on containers.
+	throughputControlOnce   sync.Once
+	throughputControlGroups *throughputControl
+	// serializer converts items to and from JSON in the generic item functions, when set.
+	serializer Serializer
 }
 
 // Endpoint used to create the client.
@@ -63,7 +78,39 @@ func NewClientWithKey(endpoint string, cred KeyCredential, o *ClientOptions) (*C
 	if err != nil {
 		return nil, err
 	}
-	return &Client{endpoint: endpoint, endpointUrl: endpointUrl, internal: internalClient, gem: gem}, nil
+	client := &Client{endpoint: endpoint, endpointUrl: endpointUrl, internal: internalClient, gem: gem}
+	client.applyOptions(o)
+	return client, nil
+}
+
+// NewClientWithResourceTokens creates a new instance of Cosmos client with resource token authentication. It uses the default pipeline configuration.
+// Each request uses the resource token of the permission granting access to the requested container, item or partition key.
+// endpoint - The cosmos service endpoint to use.
+// cred - The resource tokens used to authenticate with the cosmos service.
+// options - Optional Cosmos client options.  Pass nil to accept default values.
+func NewClientWithResourceTokens(endpoint string, cred ResourceTokenCredential, o *ClientOptions) (*Client, error) {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	preferredRegions := []string{}
+	enableCrossRegionRetries := true
+	if o != nil {
+		preferredRegions = o.PreferredRegions
+	}
+
+	gem, err := newGlobalEndpointManager(endpoint, newInternalPipeline(newResourceTokenCredPolicy(cred), o), preferredRegions, 0, enableCrossRegionRetries)
+	if err != nil {
+		return nil, err
+	}
+
+	internalClient, err := newClient(newResourceTokenCredPolicy(cred), gem, o)
+	if err != nil {
+		return nil, err
+	}
+	client := &Client{endpoint: endpoint, endpointUrl: endpointUrl, internal: internalClient, gem: gem}
+	client.applyOptions(o)
+	return client, nil
 }
 
 // NewClient creates a new instance of Cosmos client with Azure AD access token authentication. It uses the default pipeline configuration.
@@ -109,7 +156,9 @@ func NewClient(endpoint string, cred azcore.TokenCredential, o *ClientOptions) (
 	if err != nil {
 		return nil, err
 	}
-	return &Client{endpoint: endpoint, endpointUrl: endpointUrl, internal: internalClient, gem: gem}, nil
+	client := &Client{endpoint: endpoint, endpointUrl: endpointUrl, internal: internalClient, gem: gem}
+	client.applyOptions(o)
+	return client, nil
 }
 
 // NewClientFromConnectionString creates a new instance of Cosmos client from connection string. It uses the default pipeline configuration.
@@ -148,6 +197,17 @@ func NewClientFromConnectionString(connectionString string, o *ClientOptions) (*
 	return NewClientWithKey(endpoint, cred, o)
 }
 
+// applyOptions applies the client options handled by the client rather than its pipeline.
+func (c *Client) applyOptions(options *ClientOptions) {
+	if options == nil {
+		return
+	}
+	c.diagnosticsThresholds = options.DiagnosticsThresholds
+	c.availabilityStrategy = options.AvailabilityStrategy
+	c.serializer = options.Serializer
+	c.gem.partitionCircuitBreaker = newPartitionCircuitBreaker(options.PartitionCircuitBreaker)
+}
+
 func newClient(authPolicy policy.Policy, gem *globalEndpointManager, options *ClientOptions) (*azcore.Client, error) {
 	if options == nil {
 		options = &ClientOptions{}
@@ -164,6 +224,7 @@ func newClient(authPolicy policy.Policy, gem *globalEndpointManager, options *Cl
 			PerRetry: []policy.Policy{
 				authPolicy,
 				&clientRetryPolicy{gem: gem},
+				&throughputControlPolicy{},
 			},
 			Tracing: azruntime.TracingOptions{
 				Namespace: "Microsoft.DocumentDB",
@@ -477,6 +538,11 @@ func (c *Client) createRequest(
 		finalURL = azruntime.JoinPaths(c.endpoint, path)
 	}
 
+	// The diagnostics travel with the request context, so the pipeline policies and the response can reach them.
+	if diagnosticsFromContext(ctx) == nil {
+		ctx = withDiagnostics(ctx, newDiagnostics(operationContext))
+	}
+
 	req, err := azruntime.NewRequest(ctx, method, finalURL)
 	if err != nil {
 		return nil, err
@@ -499,6 +565,15 @@ func (c *Client) createRequest(
 		requestEnricher(req)
 	}
 
+	rangeID, parents := c.resolvePartitionKeyRange(req, operationContext)
+	operationContext.partitionKeyRangeID = rangeID
+	if err := c.applyThroughputControl(req, &operationContext, requestOptions); err != nil {
+		return nil, err
+	}
+	req.SetOperationValue(operationContext)
+
+	c.applySessionToken(req, operationContext, parents)
+
 	return req, nil
 }
 
@@ -522,11 +597,16 @@ func (c *Client) attachContent(content interface{}, req *policy.Request) error {
 
 func (c *Client) executeAndEnsureSuccessResponse(ctx context.Context, request *policy.Request) (*http.Response, error) {
 	log.Write(azlog.EventResponse, fmt.Sprintf("\n===== Client preferred regions:\n%v\n=====\n", c.gem.preferredLocations))
-	response, err := c.internal.Pipeline().Do(request)
+	response, err := c.doWithAvailabilityStrategy(request)
+	diagnostics := diagnosticsFromContext(request.Raw().Context())
+	diagnostics.complete()
+	c.diagnosticsThresholds.report(diagnostics)
 	if err != nil {
 		return nil, err
 	}
 
+	c.captureSessionToken(request, response)
+
 	c.addResponseValuesToSpan(ctx, response)
 
 	successResponse := (response.StatusCode >= 200 && response.StatusCode < 300) || response.StatusCode == 304
@@ -555,6 +635,13 @@ type pipelineRequestOptions struct {
 	resourceAddress       string
 	isRidBased            bool
 	isWriteOperation      bool
+	// partitionKeyRangeID is the partition key range targeted by a request on items, when known.
+	partitionKeyRangeID string
+	// locationIndex is the index of the preferred region the request is first sent to, set for the requests
+	// sent to other regions by the availability strategy.
+	locationIndex int
+	// throughputLimiter paces the request when it belongs to a throughput control group.
+	throughputLimiter *throughputLimiter
 }
 
 func addDefaultHeaders(req *policy.Request) {
@@ -594,6 +681,7 @@ func getAllowedHeaders() []string {
 		cosmosHeaderIsBatchRequest,
 		cosmosHeaderIsBatchAtomic,
 		cosmosHeaderIsBatchOrdered,
+		cosmosHeaderBatchContinueOnError,
 		cosmosHeaderSDKSupportedCapabilities,
 		headerXmsDate,
 		headerContentType,
@@ -636,5 +724,8 @@ func getAllowedHeaders() []string {
 		cosmosHeaderIsPartitionKeyDeletePending,
 		cosmosHeaderQueryExecutionInfo,
 		headerXmsItemCount,
+		cosmosHeaderStartEpk,
+		cosmosHeaderEndEpk,
+		cosmosHeaderRetryAfterMs,
 	}
 }
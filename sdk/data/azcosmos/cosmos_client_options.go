@@ -15,4 +15,13 @@ type ClientOptions struct {
 	EnableContentResponseOnWrite bool
 	// PreferredRegions is a list of regions to be used when initializing the client in case the default region fails.
 	PreferredRegions []string
+	// DiagnosticsThresholds, when set, reports the diagnostics of the operations exceeding a latency or request charge budget.
+	DiagnosticsThresholds *DiagnosticsThresholds
+	// AvailabilityStrategy, when set, sends reads and queries on items to the next preferred region when the first one is slow.
+	AvailabilityStrategy *AvailabilityStrategy
+	// PartitionCircuitBreaker, when set, routes the requests on a partition failing in a region to the next preferred region.
+	PartitionCircuitBreaker *PartitionCircuitBreakerOptions
+	// Serializer converts items to and from JSON in the generic item functions, such as ReadItem and NewQueryItemsPager.
+	// The default is JSONSerializer.
+	Serializer Serializer
 }
@@ -36,14 +36,21 @@ func (p *clientRetryPolicy) Do(req *policy.Request) (*http.Response, error) {
 		return nil, fmt.Errorf("failed to obtain request options, please check request being sent: %s", req.Body())
 	}
 
+	diagnostics := diagnosticsFromContext(req.Raw().Context())
 	retryContext := retryContext{}
 	for {
 		// Update the retry context with the latest retry values
 		req.SetOperationValue(retryContext)
-		resolvedEndpoint := p.gem.ResolveServiceEndpoint(retryContext.retryCount, o.resourceType, o.isWriteOperation, retryContext.useWriteEndpoint)
+		// Requests hedged by the availability strategy start at a following preferred region.
+		locationIndex := p.partitionBreakerRoute(diagnostics, o, retryContext.retryCount+o.locationIndex, retryContext.useWriteEndpoint)
+		resolvedEndpoint := p.gem.ResolveServiceEndpoint(locationIndex, o.resourceType, o.isWriteOperation, retryContext.useWriteEndpoint)
 		req.Raw().Host = resolvedEndpoint.Host
 		req.Raw().URL.Host = resolvedEndpoint.Host
+		start := time.Now()
 		response, err := req.Next() // err can happen in weird scenarios (connectivity, etc)
+		region := p.gem.GetEndpointLocation(resolvedEndpoint)
+		diagnostics.addRequest(req.Raw(), start, resolvedEndpoint, region, response, err)
+		p.recordPartitionOutcome(diagnostics, o, resolvedEndpoint, region, response, err)
 		if err != nil {
 			if p.isNetworkConnectionError(err) {
 				shouldRetry, errRetry := p.attemptRetryOnNetworkError(req, &retryContext)
@@ -53,6 +60,7 @@ func (p *clientRetryPolicy) Do(req *policy.Request) (*http.Response, error) {
 				if !shouldRetry {
 					return nil, err
 				}
+				diagnostics.addRetry("NetworkConnectionError")
 				err = req.RewindBody()
 				if err != nil {
 					return nil, err
@@ -73,14 +81,17 @@ func (p *clientRetryPolicy) Do(req *policy.Request) (*http.Response, error) {
 				if !shouldRetry {
 					return nil, errorinfo.NonRetriableError(azruntime.NewResponseErrorWithErrorCode(response, response.Status))
 				}
+				diagnostics.addRetry("EndpointFailure")
 			} else if response.StatusCode == http.StatusNotFound {
 				if !p.attemptRetryOnSessionUnavailable(o.isWriteOperation, &retryContext) {
 					return nil, errorinfo.NonRetriableError(azruntime.NewResponseErrorWithErrorCode(response, response.Status))
 				}
+				diagnostics.addRetry("ReadSessionNotAvailable")
 			} else if response.StatusCode == http.StatusServiceUnavailable {
 				if !p.attemptRetryOnServiceUnavailable(o.isWriteOperation, &retryContext) {
 					return nil, errorinfo.NonRetriableError(azruntime.NewResponseErrorWithErrorCode(response, response.Status))
 				}
+				diagnostics.addRetry("ServiceUnavailable")
 			}
 			err = req.RewindBody()
 			if err != nil {
@@ -117,6 +128,7 @@ func (p *clientRetryPolicy) attemptRetryOnNetworkError(req *policy.Request, retr
 	if err != nil {
 		return false, err
 	}
+	p.addEndpointUnavailable(req, "NetworkConnectionError")
 	err = p.gem.Update(req.Raw().Context(), false)
 	if err != nil {
 		return false, err
@@ -135,11 +147,13 @@ func (p *clientRetryPolicy) attemptRetryOnEndpointFailure(req *policy.Request, i
 		if err != nil {
 			return false, err
 		}
+		p.addEndpointUnavailable(req, "WriteForbidden")
 	} else {
 		err := p.gem.MarkEndpointUnavailableForRead(*req.Raw().URL)
 		if err != nil {
 			return false, err
 		}
+		p.addEndpointUnavailable(req, "ReadForbidden")
 	}
 
 	err := p.gem.Update(req.Raw().Context(), isWriteOperation)
@@ -181,6 +195,19 @@ func (p *clientRetryPolicy) attemptRetryOnServiceUnavailable(isWriteOperation bo
 	return true
 }
 
+// addEndpointUnavailable records in the diagnostics of the request that its endpoint was marked unavailable.
+func (p *clientRetryPolicy) addEndpointUnavailable(req *policy.Request, reason string) {
+	endpoint := *req.Raw().URL
+	endpoint.Path, endpoint.RawPath, endpoint.RawQuery = "", "", ""
+	diagnosticsFromContext(req.Raw().Context()).addEvent(DiagnosticsEvent{
+		Kind:      DiagnosticsEventEndpointUnavailable,
+		StartTime: time.Now(),
+		Region:    p.gem.GetEndpointLocation(endpoint),
+		Endpoint:  endpoint.String(),
+		Reason:    reason,
+	})
+}
+
 // isNetworkConnectionError checks if the error is related to failure to connect / resolve DNS
 func (p *clientRetryPolicy) isNetworkConnectionError(err error) bool {
 	var dnserror *net.DNSError
@@ -20,6 +20,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewClientFromConnStrReturnErrorOnWrongDelimiter(t *testing.T) {
@@ -835,6 +836,25 @@ func (p *pipelineVerifier) Do(req *policy.Request) (*http.Response, error) {
 	return req.Next()
 }
 
+// newTestContainer returns a container of a client that sends its requests to srv through a pipeline with the
+// policies of pipelineOptions. The global endpoint manager of the client has no regions when gem is nil.
+func newTestContainer(t *testing.T, srv *mock.Server, gem *globalEndpointManager, pipelineOptions azruntime.PipelineOptions, retryOptions policy.RetryOptions) *ContainerClient {
+	defaultEndpoint, err := url.Parse(srv.URL())
+	require.NoError(t, err)
+	internalClient, err := azcore.NewClient("azcosmostest", "v1.0.0", pipelineOptions, &policy.ClientOptions{Transport: srv, Retry: retryOptions})
+	require.NoError(t, err)
+	if gem == nil {
+		gem = &globalEndpointManager{preferredLocations: []string{}}
+	}
+	client := &Client{endpoint: srv.URL(), endpointUrl: defaultEndpoint, internal: internalClient, gem: gem}
+
+	database, err := newDatabase("databaseId", client)
+	require.NoError(t, err)
+	container, err := newContainer("containerId", database)
+	require.NoError(t, err)
+	return container
+}
+
 type stubCred struct {
 	t     *testing.T
 	calls []string
@@ -783,16 +783,19 @@ func (c *ContainerClient) GetChangeFeed(
 	}
 
 	if options.FeedRange != nil {
-		return c.getChangeFeedForEPKRange(ctx, options.FeedRange, options)
+		return c.getChangeFeedForEPKRange(ctx, options.FeedRange, options, nil)
 	} else {
 		return ChangeFeedResponse{}, fmt.Errorf("GetChangeFeed requires a FeedRange to be set in the options, or a continuation token that contains a composite continuation token")
 	}
 }
 
+// getChangeFeedForEPKRange reads a page of the change feed for the feed range.
+// partitionKeyRanges are read from the service when nil.
 func (c *ContainerClient) getChangeFeedForEPKRange(
 	ctx context.Context,
 	feedRange *FeedRange,
 	options *ChangeFeedOptions,
+	partitionKeyRanges []partitionKeyRange,
 ) (ChangeFeedResponse, error) {
 	var err error
 	spanName, err := c.getSpanForItems(operationTypeRead)
@@ -806,11 +809,14 @@ func (c *ContainerClient) getChangeFeedForEPKRange(
 		options = &ChangeFeedOptions{}
 	}
 
-	pkrResp, err := c.getPartitionKeyRanges(ctx, nil)
-	if err != nil {
-		return ChangeFeedResponse{}, err
+	if partitionKeyRanges == nil {
+		var pkrResp partitionKeyRangeResponse
+		pkrResp, err = c.getPartitionKeyRanges(ctx, nil)
+		if err != nil {
+			return ChangeFeedResponse{}, err
+		}
+		partitionKeyRanges = pkrResp.PartitionKeyRanges
 	}
-	partitionKeyRanges := pkrResp.PartitionKeyRanges
 
 	var addHeaders func(*policy.Request)
 	headersPtr := options.toHeaders(partitionKeyRanges)
@@ -900,6 +906,9 @@ func (c *ContainerClient) getPartitionKeyRanges(ctx context.Context, o *partitio
 		operationContext,
 		o,
 		nil)
+	if err != nil {
+		return partitionKeyRangeResponse{}, err
+	}
 
 	response, err := newPartitionKeyRangeResponse(azResponse)
 	if err != nil {
@@ -0,0 +1,235 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// TypedItemResponse is the response of a generic item operation.
+type TypedItemResponse[T any] struct {
+	ItemResponse
+	// Item is the item of the response, deserialized by the serializer of the client.
+	// It is the zero value when the response has no content, for example for writes
+	// without ItemOptions.EnableContentResponseOnWrite.
+	Item T
+}
+
+// SystemProperties returns the properties the service maintains on the item of the response.
+func (r TypedItemResponse[T]) SystemProperties() (SystemProperties, error) {
+	return systemPropertiesOf(r.Value)
+}
+
+// TypedQueryItemsResponse is a page of the results of a generic query.
+type TypedQueryItemsResponse[T any] struct {
+	QueryItemsResponse
+	// Items of the page, deserialized by the serializer of the client.
+	Items []T
+}
+
+// TypedChangeFeedResponse is a page of a generic change feed.
+type TypedChangeFeedResponse[T any] struct {
+	ChangeFeedResponse
+	// Documents changed, deserialized by the serializer of the client.
+	Documents []T
+}
+
+// TypedTransactionalBatchResponse is the response of a generic transactional batch.
+type TypedTransactionalBatchResponse[T any] struct {
+	TransactionalBatchResponse
+	// Items contains the item of each operation result, deserialized by the serializer of the client.
+	// The order of the items is the same as the order of the operations in the batch. The item is nil
+	// for operations without a resource body, such as deletes, failed operations, or writes without
+	// TransactionalBatchOptions.EnableContentResponseOnWrite.
+	Items []*T
+}
+
+func newTypedItemResponse[T any](serializer Serializer, response ItemResponse, err error) (TypedItemResponse[T], error) {
+	typed := TypedItemResponse[T]{ItemResponse: response}
+	if err != nil || len(response.Value) == 0 {
+		return typed, err
+	}
+	err = serializer.Unmarshal(response.Value, &typed.Item)
+	return typed, err
+}
+
+// ReadItem reads an item in a container and deserializes it with the serializer of the client.
+// ctx - The context for the request.
+// container - The container of the item.
+// partitionKey - The partition key for the item.
+// itemId - The id of the item.
+// o - Options for the operation.
+func ReadItem[T any](
+	ctx context.Context,
+	container *ContainerClient,
+	partitionKey PartitionKey,
+	itemId string,
+	o *ItemOptions) (TypedItemResponse[T], error) {
+	response, err := container.ReadItem(ctx, partitionKey, itemId, o)
+	return newTypedItemResponse[T](container.database.client.itemSerializer(), response, err)
+}
+
+// CreateItem serializes an item with the serializer of the client and creates it in a container.
+// ctx - The context for the request.
+// container - The container to create the item in.
+// partitionKey - The partition key for the item.
+// item - The item to create.
+// o - Options for the operation.
+func CreateItem[T any](
+	ctx context.Context,
+	container *ContainerClient,
+	partitionKey PartitionKey,
+	item T,
+	o *ItemOptions) (TypedItemResponse[T], error) {
+	serializer := container.database.client.itemSerializer()
+	body, err := serializer.Marshal(item)
+	if err != nil {
+		return TypedItemResponse[T]{}, err
+	}
+	response, err := container.CreateItem(ctx, partitionKey, body, o)
+	return newTypedItemResponse[T](serializer, response, err)
+}
+
+// UpsertItem serializes an item with the serializer of the client and creates or replaces it in a container.
+// ctx - The context for the request.
+// container - The container of the item.
+// partitionKey - The partition key for the item.
+// item - The item to upsert.
+// o - Options for the operation.
+func UpsertItem[T any](
+	ctx context.Context,
+	container *ContainerClient,
+	partitionKey PartitionKey,
+	item T,
+	o *ItemOptions) (TypedItemResponse[T], error) {
+	serializer := container.database.client.itemSerializer()
+	body, err := serializer.Marshal(item)
+	if err != nil {
+		return TypedItemResponse[T]{}, err
+	}
+	response, err := container.UpsertItem(ctx, partitionKey, body, o)
+	return newTypedItemResponse[T](serializer, response, err)
+}
+
+// ReplaceItem serializes an item with the serializer of the client and replaces an item of a container with it.
+// ctx - The context for the request.
+// container - The container of the item.
+// partitionKey - The partition key of the item to replace.
+// itemId - The id of the item to replace.
+// item - The item to replace with.
+// o - Options for the operation.
+func ReplaceItem[T any](
+	ctx context.Context,
+	container *ContainerClient,
+	partitionKey PartitionKey,
+	itemId string,
+	item T,
+	o *ItemOptions) (TypedItemResponse[T], error) {
+	serializer := container.database.client.itemSerializer()
+	body, err := serializer.Marshal(item)
+	if err != nil {
+		return TypedItemResponse[T]{}, err
+	}
+	response, err := container.ReplaceItem(ctx, partitionKey, itemId, body, o)
+	return newTypedItemResponse[T](serializer, response, err)
+}
+
+// PatchItem applies patch operations to an item of a container, and deserializes the patched item, when returned,
+// with the serializer of the client. TypedPatchOperations builds the operations from the fields of T.
+// ctx - The context for the request.
+// container - The container of the item.
+// partitionKey - The partition key of the item to patch.
+// itemId - The id of the item to patch.
+// ops - Operations to perform on the patch.
+// o - Options for the operation.
+func PatchItem[T any](
+	ctx context.Context,
+	container *ContainerClient,
+	partitionKey PartitionKey,
+	itemId string,
+	ops PatchOperations,
+	o *ItemOptions) (TypedItemResponse[T], error) {
+	response, err := container.PatchItem(ctx, partitionKey, itemId, ops, o)
+	return newTypedItemResponse[T](container.database.client.itemSerializer(), response, err)
+}
+
+// NewQueryItemsPager executes a query in a container, and deserializes the results with the serializer of the client.
+// container - The container to query.
+// query - The SQL query to execute.
+// partitionKey - The partition key to scope the query on.
+// o - Options for the operation.
+//
+// See ContainerClient.NewQueryItemsPager for the scope of the query and the queries supported across partitions.
+func NewQueryItemsPager[T any](container *ContainerClient, query string, partitionKey PartitionKey, o *QueryOptions) *runtime.Pager[TypedQueryItemsResponse[T]] {
+	pager := container.NewQueryItemsPager(query, partitionKey, o)
+	serializer := container.database.client.itemSerializer()
+	return runtime.NewPager(runtime.PagingHandler[TypedQueryItemsResponse[T]]{
+		More: func(page TypedQueryItemsResponse[T]) bool {
+			return pager.More()
+		},
+		Fetcher: func(ctx context.Context, page *TypedQueryItemsResponse[T]) (TypedQueryItemsResponse[T], error) {
+			response, err := pager.NextPage(ctx)
+			if err != nil {
+				return TypedQueryItemsResponse[T]{}, err
+			}
+			typed := TypedQueryItemsResponse[T]{QueryItemsResponse: response, Items: make([]T, len(response.Items))}
+			for i, item := range response.Items {
+				if err := serializer.Unmarshal(item, &typed.Items[i]); err != nil {
+					return TypedQueryItemsResponse[T]{}, err
+				}
+			}
+			return typed, nil
+		},
+	})
+}
+
+// GetChangeFeed reads a page of the change feed of a container, and deserializes the changed items with the
+// serializer of the client.
+// ctx - The context for the request.
+// container - The container to read the change feed of.
+// options - Options for the operation. See ContainerClient.GetChangeFeed.
+func GetChangeFeed[T any](ctx context.Context, container *ContainerClient, options *ChangeFeedOptions) (TypedChangeFeedResponse[T], error) {
+	response, err := container.GetChangeFeed(ctx, options)
+	typed := TypedChangeFeedResponse[T]{ChangeFeedResponse: response}
+	if err != nil {
+		return typed, err
+	}
+	serializer := container.database.client.itemSerializer()
+	typed.Documents = make([]T, len(response.Documents))
+	for i, document := range response.Documents {
+		if err := serializer.Unmarshal(document, &typed.Documents[i]); err != nil {
+			return typed, err
+		}
+	}
+	return typed, nil
+}
+
+// ExecuteTransactionalBatch executes a transactional batch in a container, and deserializes the resource body of
+// each operation result with the serializer of the client.
+// ctx - The context for the request.
+// container - The container to execute the batch in.
+// b - The transactional batch to execute.
+// o - Options for the operation.
+func ExecuteTransactionalBatch[T any](ctx context.Context, container *ContainerClient, b TransactionalBatch, o *TransactionalBatchOptions) (TypedTransactionalBatchResponse[T], error) {
+	response, err := container.ExecuteTransactionalBatch(ctx, b, o)
+	typed := TypedTransactionalBatchResponse[T]{TransactionalBatchResponse: response}
+	if err != nil {
+		return typed, err
+	}
+	serializer := container.database.client.itemSerializer()
+	typed.Items = make([]*T, len(response.OperationResults))
+	for i, result := range response.OperationResults {
+		if len(result.ResourceBody) == 0 {
+			continue
+		}
+		var item T
+		if err := serializer.Unmarshal(result.ResourceBody, &item); err != nil {
+			return typed, err
+		}
+		typed.Items[i] = &item
+	}
+	return typed, nil
+}
@@ -0,0 +1,216 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type genericsTestItem struct {
+	SystemProperties
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count,omitempty"`
+}
+
+// recordingSerializer wraps encoding/json and records the number of calls.
+type recordingSerializer struct {
+	marshals   int
+	unmarshals int
+}
+
+func (s *recordingSerializer) Marshal(v any) ([]byte, error) {
+	s.marshals++
+	return json.Marshal(v)
+}
+
+func (s *recordingSerializer) Unmarshal(data []byte, v any) error {
+	s.unmarshals++
+	return json.Unmarshal(data, v)
+}
+
+func newGenericsTestContainer(t *testing.T, srv *mock.Server, verifier *pipelineVerifier) *ContainerClient {
+	return newTestContainer(t, srv, nil, azruntime.PipelineOptions{PerCall: []policy.Policy{&headerPolicies{}, verifier}}, policy.RetryOptions{})
+}
+
+func TestGenericReadItem(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(
+		mock.WithBody([]byte(`{"id":"doc1","name":"first","_rid":"rid1","_etag":"\"etag1\"","_ts":1700000000}`)),
+		mock.WithHeader(cosmosHeaderRequestCharge, "1"),
+		mock.WithStatusCode(200))
+
+	container := newGenericsTestContainer(t, srv, &pipelineVerifier{})
+
+	resp, err := ReadItem[genericsTestItem](context.Background(), container, NewPartitionKeyString("1"), "doc1", nil)
+	require.NoError(t, err)
+	require.Equal(t, "doc1", resp.Item.ID)
+	require.Equal(t, "first", resp.Item.Name)
+	require.Equal(t, azcore.ETag(`"etag1"`), resp.Item.ETag)
+	require.Equal(t, time.Unix(1700000000, 0), resp.Item.LastModified())
+	require.Equal(t, float32(1), resp.RequestCharge)
+
+	properties, err := resp.SystemProperties()
+	require.NoError(t, err)
+	require.Equal(t, SystemProperties{ID: "doc1", ResourceID: "rid1", ETag: `"etag1"`, Timestamp: 1700000000}, properties)
+
+	// Items can be read as maps, without a struct type.
+	untyped, err := ReadItem[map[string]any](context.Background(), container, NewPartitionKeyString("1"), "doc1", nil)
+	require.NoError(t, err)
+	require.Equal(t, "first", untyped.Item["name"])
+}
+
+func TestGenericReadItemError(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusNotFound))
+
+	container := newGenericsTestContainer(t, srv, &pipelineVerifier{})
+
+	_, err := ReadItem[genericsTestItem](context.Background(), container, NewPartitionKeyString("1"), "doc1", nil)
+	var responseErr *azcore.ResponseError
+	require.True(t, errors.As(err, &responseErr))
+	require.Equal(t, http.StatusNotFound, responseErr.StatusCode)
+}
+
+func TestGenericWriteItems(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"doc1","name":"first","_etag":"\"etag1\""}`)), mock.WithStatusCode(201))
+	srv.AppendResponse(mock.WithStatusCode(200))
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"doc1","name":"second"}`)), mock.WithStatusCode(200))
+
+	verifier := pipelineVerifier{}
+	container := newGenericsTestContainer(t, srv, &verifier)
+	serializer := &recordingSerializer{}
+	container.database.client.serializer = serializer
+
+	item := genericsTestItem{ID: "doc1", Name: "first"}
+	created, err := CreateItem(context.Background(), container, NewPartitionKeyString("1"), item, &ItemOptions{EnableContentResponseOnWrite: true})
+	require.NoError(t, err)
+	require.Equal(t, "first", created.Item.Name)
+	require.Equal(t, azcore.ETag(`"etag1"`), created.Item.ETag)
+	require.JSONEq(t, `{"id":"doc1","name":"first"}`, verifier.requests[0].body)
+
+	// Writes without content return the zero value.
+	upserted, err := UpsertItem(context.Background(), container, NewPartitionKeyString("1"), item, nil)
+	require.NoError(t, err)
+	require.Equal(t, genericsTestItem{}, upserted.Item)
+	require.Equal(t, "true", verifier.requests[1].headers.Get(cosmosHeaderIsUpsert))
+
+	item.Name = "second"
+	replaced, err := ReplaceItem(context.Background(), container, NewPartitionKeyString("1"), "doc1", item, &ItemOptions{EnableContentResponseOnWrite: true})
+	require.NoError(t, err)
+	require.Equal(t, "second", replaced.Item.Name)
+	require.Equal(t, http.MethodPut, verifier.requests[2].method)
+
+	require.Equal(t, 3, serializer.marshals)
+	require.Equal(t, 2, serializer.unmarshals)
+}
+
+func TestGenericQueryItemsPager(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"Documents":[{"id":"doc1","name":"first"},{"id":"doc2","name":"second"}],"_count":2}`)),
+		mock.WithHeader(cosmosHeaderContinuationToken, "next"),
+		mock.WithStatusCode(200))
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"Documents":[{"id":"doc3","name":"third"}],"_count":1}`)),
+		mock.WithStatusCode(200))
+
+	container := newGenericsTestContainer(t, srv, &pipelineVerifier{})
+
+	pager := NewQueryItemsPager[genericsTestItem](container, "SELECT * FROM c", NewPartitionKeyString("1"), nil)
+	var names []string
+	for pager.More() {
+		page, err := pager.NextPage(context.Background())
+		require.NoError(t, err)
+		require.Len(t, page.QueryItemsResponse.Items, len(page.Items))
+		for _, item := range page.Items {
+			names = append(names, item.Name)
+		}
+	}
+	require.Equal(t, []string{"first", "second", "third"}, names)
+}
+
+func TestGenericQueryItemsPagerScalars(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(mock.WithBody([]byte(`{"Documents":[42],"_count":1}`)), mock.WithStatusCode(200))
+
+	container := newGenericsTestContainer(t, srv, &pipelineVerifier{})
+
+	pager := NewQueryItemsPager[int](container, "SELECT VALUE COUNT(1) FROM c", NewPartitionKeyString("1"), nil)
+	page, err := pager.NextPage(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []int{42}, page.Items)
+}
+
+func TestGenericGetChangeFeed(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"PartitionKeyRanges":[{"id":"0","minInclusive":"","maxExclusive":"FF"}],"_count":1}`)),
+		mock.WithStatusCode(200))
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"_rid":"rid","Documents":[{"id":"doc1","name":"first"},{"id":"doc2","name":"second"}],"_count":2}`)),
+		mock.WithHeader(cosmosHeaderEtag, `"12"`),
+		mock.WithStatusCode(200))
+
+	container := newGenericsTestContainer(t, srv, &pipelineVerifier{})
+
+	resp, err := GetChangeFeed[genericsTestItem](context.Background(), container, &ChangeFeedOptions{FeedRange: &FeedRange{MinInclusive: "", MaxExclusive: "FF"}})
+	require.NoError(t, err)
+	require.Equal(t, 2, resp.Count)
+	require.Len(t, resp.Documents, 2)
+	require.Equal(t, "second", resp.Documents[1].Name)
+	require.NotEmpty(t, resp.ContinuationToken)
+}
+
+func TestGenericExecuteTransactionalBatch(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(
+		mock.WithBody([]byte(`[{"statusCode":200,"requestCharge":1.0,"eTag":"etag1","resourceBody":{"id":"doc1","name":"first"}},{"statusCode":204,"requestCharge":2.0}]`)),
+		mock.WithStatusCode(200))
+
+	container := newGenericsTestContainer(t, srv, &pipelineVerifier{})
+
+	batch := container.NewTransactionalBatch(NewPartitionKeyString("1"))
+	batch.ReadItem("doc1", nil)
+	batch.DeleteItem("doc2", nil)
+	resp, err := ExecuteTransactionalBatch[genericsTestItem](context.Background(), container, batch, nil)
+	require.NoError(t, err)
+	require.True(t, resp.Success)
+	require.Len(t, resp.Items, 2)
+	require.Equal(t, "first", resp.Items[0].Name)
+	require.Nil(t, resp.Items[1])
+	require.Equal(t, int32(204), resp.OperationResults[1].StatusCode)
+}
+
+func TestJSONSerializer(t *testing.T) {
+	var serializer Serializer = JSONSerializer{}
+	data, err := serializer.Marshal(genericsTestItem{ID: "doc1", Name: "first", SystemProperties: SystemProperties{ETag: "etag"}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":"doc1","name":"first","_etag":"etag"}`, string(data))
+
+	var item genericsTestItem
+	require.NoError(t, serializer.Unmarshal(data, &item))
+	require.Equal(t, azcore.ETag("etag"), item.ETag)
+
+	require.Equal(t, JSONSerializer{}, (&Client{}).itemSerializer())
+}
@@ -157,8 +157,9 @@ func (c *ContainerClient) executeQueryWithEngine(queryEngine queryengine.QueryEn
 					// Make the single-partition query request
 					qryRequest := queryRequest(request) // Cast to our type, which has toHeaders defined on it.
 					// if the query request has an override query, use it
+					requestQuery := query
 					if qryRequest.Query != "" {
-						query = qryRequest.Query
+						requestQuery = qryRequest.Query
 					}
 
 					var queryParameters []QueryParameter
@@ -173,7 +174,7 @@ func (c *ContainerClient) executeQueryWithEngine(queryEngine queryengine.QueryEn
 						azResponse, err := c.database.client.sendQueryRequest(
 							path,
 							ctx,
-							query,
+							requestQuery,
 							queryParameters,
 							operationContext,
 							&qryRequest,
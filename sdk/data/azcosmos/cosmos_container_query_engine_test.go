@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	azcosmosinternal "github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos/internal"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos/queryengine"
 )
 
 const (
@@ -271,3 +272,47 @@ func TestNoQueryOverrideUsesOriginal(t *testing.T) {
 		t.Fatalf("expected at least one matching item for target merge order %d", target)
 	}
 }
+
+func TestQueryViaNativeQueryEngine(t *testing.T) {
+	service := newFakeCosmosService()
+	service.setPartitionKeyRanges("dbs/db/colls/items",
+		partitionKeyRange{ID: "1", MinInclusive: "", MaxExclusive: "15"},
+		partitionKeyRange{ID: "2", MinInclusive: "15", MaxExclusive: "2A"},
+		partitionKeyRange{ID: "3", MinInclusive: "2A", MaxExclusive: "FF"})
+	database, _ := newDatabase("db", service.newClient(t))
+	container, _ := newContainer("items", database)
+
+	var expected []string
+	for i := 0; i < 20; i++ {
+		id := strconv.Itoa(100 + i)
+		expected = append(expected, id)
+		if _, err := container.UpsertItem(context.TODO(), NewPartitionKeyString(id), []byte(`{"id":"`+id+`"}`), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pager := container.NewQueryItemsPager(fakeOrderByQuery, NewPartitionKey(), &QueryOptions{QueryEngine: queryengine.NewNativeQueryEngine()})
+	var ids []string
+	for pager.More() {
+		response, err := pager.NextPage(context.TODO())
+		if err != nil {
+			t.Fatalf("Failed to get next page: %v", err)
+		}
+		for _, item := range response.Items {
+			var doc map[string]any
+			if err := json.Unmarshal(item, &doc); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, doc["id"].(string))
+		}
+	}
+
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected %d items, got %v", len(expected), ids)
+	}
+	for i := range ids {
+		if ids[i] != expected[i] {
+			t.Fatalf("Expected items sorted by id %v, got %v", expected, ids)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// DiagnosticsEventKind is the kind of an event of the diagnostics of an operation.
+type DiagnosticsEventKind string
+
+const (
+	// DiagnosticsEventRequest is a request sent to a region of the service.
+	DiagnosticsEventRequest DiagnosticsEventKind = "Request"
+	// DiagnosticsEventRetry is the decision of the client to retry the operation, with the reason in the event.
+	DiagnosticsEventRetry DiagnosticsEventKind = "Retry"
+	// DiagnosticsEventEndpointUnavailable is a regional endpoint marked unavailable after a failure.
+	DiagnosticsEventEndpointUnavailable DiagnosticsEventKind = "EndpointUnavailable"
+	// DiagnosticsEventAccountRefresh is a refresh of the regions of the account by the client.
+	DiagnosticsEventAccountRefresh DiagnosticsEventKind = "AccountRefresh"
+	// DiagnosticsEventHedgedRequest is a request sent to another region by the availability strategy, after the
+	// previous regions didn't respond within the threshold.
+	DiagnosticsEventHedgedRequest DiagnosticsEventKind = "HedgedRequest"
+	// DiagnosticsEventPartitionUnavailable is a partition marked unavailable in a region by the partition circuit breaker,
+	// after consecutive failures.
+	DiagnosticsEventPartitionUnavailable DiagnosticsEventKind = "PartitionUnavailable"
+	// DiagnosticsEventPartitionFailover is a request routed away from a region where its partition is unavailable.
+	DiagnosticsEventPartitionFailover DiagnosticsEventKind = "PartitionFailover"
+	// DiagnosticsEventThroughputControl is the time a request waited for the budget of its throughput control group.
+	DiagnosticsEventThroughputControl DiagnosticsEventKind = "ThroughputControl"
+)
+
+// Returns a list of available diagnostics event kinds
+func DiagnosticsEventKindValues() []DiagnosticsEventKind {
+	return []DiagnosticsEventKind{DiagnosticsEventRequest, DiagnosticsEventRetry, DiagnosticsEventEndpointUnavailable, DiagnosticsEventAccountRefresh,
+		DiagnosticsEventHedgedRequest, DiagnosticsEventPartitionUnavailable, DiagnosticsEventPartitionFailover, DiagnosticsEventThroughputControl}
+}
+
+// ToPtr returns a *DiagnosticsEventKind
+func (k DiagnosticsEventKind) ToPtr() *DiagnosticsEventKind {
+	return &k
+}
+
+// DiagnosticsEvent is an event of the timeline of an operation.
+type DiagnosticsEvent struct {
+	// Kind of the event.
+	Kind DiagnosticsEventKind
+	// StartTime is the time the event started.
+	StartTime time.Time
+	// Duration of the request, for request events, or of the wait, for throughput control events.
+	Duration time.Duration
+	// Region contacted, or marked unavailable.
+	Region string
+	// Endpoint contacted, or marked unavailable.
+	Endpoint string
+	// StatusCode of the response, for request events.
+	StatusCode int
+	// SubStatusCode of the response, for request events.
+	SubStatusCode string
+	// RequestCharge of the request, for request events.
+	RequestCharge float32
+	// ActivityID of the request, for request events.
+	ActivityID string
+	// PartitionKeyRangeID is the partition key range that served the request, when known.
+	PartitionKeyRangeID string
+	// RetryAfter is the backoff requested by the service, for throttled requests.
+	RetryAfter time.Duration
+	// Reason of a retry, of an endpoint or partition marked unavailable, or of a request sent to another region.
+	// For throughput control events, the name of the throughput control group.
+	Reason string
+	// Error is the error of a request without response, or of a failed account refresh.
+	Error string
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (e DiagnosticsEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind                DiagnosticsEventKind `json:"kind"`
+		StartTime           time.Time            `json:"startTime"`
+		DurationMs          float64              `json:"durationMs,omitempty"`
+		Region              string               `json:"region,omitempty"`
+		Endpoint            string               `json:"endpoint,omitempty"`
+		StatusCode          int                  `json:"statusCode,omitempty"`
+		SubStatusCode       string               `json:"subStatusCode,omitempty"`
+		RequestCharge       float32              `json:"requestCharge,omitempty"`
+		ActivityID          string               `json:"activityId,omitempty"`
+		PartitionKeyRangeID string               `json:"partitionKeyRangeId,omitempty"`
+		RetryAfterMs        float64              `json:"retryAfterMs,omitempty"`
+		Reason              string               `json:"reason,omitempty"`
+		Error               string               `json:"error,omitempty"`
+	}{
+		Kind:                e.Kind,
+		StartTime:           e.StartTime,
+		DurationMs:          durationMs(e.Duration),
+		Region:              e.Region,
+		Endpoint:            e.Endpoint,
+		StatusCode:          e.StatusCode,
+		SubStatusCode:       e.SubStatusCode,
+		RequestCharge:       e.RequestCharge,
+		ActivityID:          e.ActivityID,
+		PartitionKeyRangeID: e.PartitionKeyRangeID,
+		RetryAfterMs:        durationMs(e.RetryAfter),
+		Reason:              e.Reason,
+		Error:               e.Error,
+	})
+}
+
+// Diagnostics contains the timeline of an operation: the requests sent to the regions of the service, including
+// retries and their reasons, and the changes to the regions known by the client.
+// Diagnostics are obtained from the Diagnostics field of responses, or with DiagnosticsFromError for failed operations.
+type Diagnostics struct {
+	mu              sync.Mutex
+	resourceType    resourceType
+	resourceAddress string
+	startTime       time.Time
+	duration        time.Duration
+	completed       bool
+	events          []DiagnosticsEvent
+}
+
+func newDiagnostics(operationContext pipelineRequestOptions) *Diagnostics {
+	return &Diagnostics{
+		resourceType:    operationContext.resourceType,
+		resourceAddress: operationContext.resourceAddress,
+		startTime:       time.Now(),
+	}
+}
+
+// StartTime returns the time the operation started.
+func (d *Diagnostics) StartTime() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.startTime
+}
+
+// Duration returns the duration of the operation, or the time elapsed since it started when it didn't complete.
+func (d *Diagnostics) Duration() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.durationLocked()
+}
+
+func (d *Diagnostics) durationLocked() time.Duration {
+	if d.completed {
+		return d.duration
+	}
+	return time.Since(d.startTime)
+}
+
+// RequestCharge returns the request units consumed by all the requests of the operation, including retries.
+func (d *Diagnostics) RequestCharge() float32 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.requestChargeLocked()
+}
+
+func (d *Diagnostics) requestChargeLocked() float32 {
+	var charge float32
+	for _, e := range d.events {
+		charge += e.RequestCharge
+	}
+	return charge
+}
+
+// RetryCount returns the number of requests of the operation after the first one.
+func (d *Diagnostics) RetryCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	requests := 0
+	for _, e := range d.events {
+		if e.Kind == DiagnosticsEventRequest {
+			requests++
+		}
+	}
+	return max(requests-1, 0)
+}
+
+// ThrottlingBackoff returns the total backoff requested by the service for the throttled requests of the operation.
+func (d *Diagnostics) ThrottlingBackoff() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.throttlingBackoffLocked()
+}
+
+func (d *Diagnostics) throttlingBackoffLocked() time.Duration {
+	var backoff time.Duration
+	for _, e := range d.events {
+		backoff += e.RetryAfter
+	}
+	return backoff
+}
+
+// RegionsContacted returns the regions the requests of the operation were sent to, in the order they were contacted.
+func (d *Diagnostics) RegionsContacted() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.regionsContactedLocked()
+}
+
+func (d *Diagnostics) regionsContactedLocked() []string {
+	regions := []string{}
+	seen := map[string]bool{}
+	for _, e := range d.events {
+		if e.Kind == DiagnosticsEventRequest && e.Region != "" && !seen[e.Region] {
+			seen[e.Region] = true
+			regions = append(regions, e.Region)
+		}
+	}
+	return regions
+}
+
+// Events returns the timeline of the operation.
+func (d *Diagnostics) Events() []DiagnosticsEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DiagnosticsEvent{}, d.events...)
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (d *Diagnostics) MarshalJSON() ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return json.Marshal(struct {
+		ResourceType        string             `json:"resourceType"`
+		ResourceAddress     string             `json:"resourceAddress"`
+		StartTime           time.Time          `json:"startTime"`
+		DurationMs          float64            `json:"durationMs"`
+		RequestCharge       float32            `json:"requestCharge"`
+		ThrottlingBackoffMs float64            `json:"throttlingBackoffMs,omitempty"`
+		RegionsContacted    []string           `json:"regionsContacted"`
+		Events              []DiagnosticsEvent `json:"events"`
+	}{
+		ResourceType:        d.resourceType.String(),
+		ResourceAddress:     d.resourceAddress,
+		StartTime:           d.startTime,
+		DurationMs:          durationMs(d.durationLocked()),
+		RequestCharge:       d.requestChargeLocked(),
+		ThrottlingBackoffMs: durationMs(d.throttlingBackoffLocked()),
+		RegionsContacted:    d.regionsContactedLocked(),
+		Events:              append([]DiagnosticsEvent{}, d.events...),
+	})
+}
+
+// String returns the diagnostics as JSON.
+func (d *Diagnostics) String() string {
+	if d == nil {
+		return "{}"
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+func (d *Diagnostics) addEvent(e DiagnosticsEvent) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append(d.events, e)
+}
+
+// addRequest records a request sent to an endpoint, with its response or the error of a request without response.
+func (d *Diagnostics) addRequest(req *http.Request, start time.Time, endpoint url.URL, region string, resp *http.Response, err error) {
+	if d == nil {
+		return
+	}
+	e := DiagnosticsEvent{
+		Kind:                DiagnosticsEventRequest,
+		StartTime:           start,
+		Duration:            time.Since(start),
+		Region:              region,
+		Endpoint:            endpoint.String(),
+		PartitionKeyRangeID: req.Header.Get(cosmosHeaderPartitionKeyRangeId),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if resp != nil {
+		e.StatusCode = resp.StatusCode
+		e.SubStatusCode = resp.Header.Get(cosmosHeaderSubstatus)
+		e.RequestCharge = requestChargeOf(resp)
+		e.ActivityID = resp.Header.Get(cosmosHeaderActivityId)
+		if pkRangeID := resp.Header.Get(cosmosHeaderPartitionKeyRangeId); pkRangeID != "" {
+			e.PartitionKeyRangeID = pkRangeID
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			if ms, err := strconv.ParseFloat(resp.Header.Get(cosmosHeaderRetryAfterMs), 64); err == nil {
+				e.RetryAfter = time.Duration(ms * float64(time.Millisecond))
+			}
+		}
+	}
+	d.addEvent(e)
+}
+
+// addRetry records the decision to retry the operation.
+func (d *Diagnostics) addRetry(reason string) {
+	d.addEvent(DiagnosticsEvent{Kind: DiagnosticsEventRetry, StartTime: time.Now(), Reason: reason})
+}
+
+// complete records the end of the operation.
+func (d *Diagnostics) complete() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.completed {
+		d.completed = true
+		d.duration = time.Since(d.startTime)
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+type diagnosticsContextKey struct{}
+
+// withDiagnostics returns a context carrying the diagnostics of an operation, which the pipeline policies fill.
+func withDiagnostics(ctx context.Context, d *Diagnostics) context.Context {
+	return context.WithValue(ctx, diagnosticsContextKey{}, d)
+}
+
+// diagnosticsFromContext returns the diagnostics of the operation of a context, or nil.
+func diagnosticsFromContext(ctx context.Context) *Diagnostics {
+	d, _ := ctx.Value(diagnosticsContextKey{}).(*Diagnostics)
+	return d
+}
+
+// diagnosticsFromResponse returns the diagnostics of the operation of a response, or nil.
+func diagnosticsFromResponse(resp *http.Response) *Diagnostics {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+	return diagnosticsFromContext(resp.Request.Context())
+}
+
+// DiagnosticsFromError returns the diagnostics of a failed operation, when err is or wraps the *azcore.ResponseError
+// returned by the operation, or nil.
+func DiagnosticsFromError(err error) *Diagnostics {
+	var azErr *azcore.ResponseError
+	if !errors.As(err, &azErr) {
+		return nil
+	}
+	return diagnosticsFromResponse(azErr.RawResponse)
+}
@@ -0,0 +1,163 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDiagnosticsTestContainer(t *testing.T, srv *mock.Server, thresholds *DiagnosticsThresholds) *ContainerClient {
+	defaultEndpoint, err := url.Parse(srv.URL())
+	require.NoError(t, err)
+	gem := &globalEndpointManager{
+		clientEndpoint:      srv.URL(),
+		preferredLocations:  []string{},
+		locationCache:       CreateMockLC(*defaultEndpoint, false),
+		refreshTimeInterval: defaultExpirationTime,
+		lastUpdateTime:      time.Time{},
+	}
+	container := newTestContainer(t, srv, gem,
+		azruntime.PipelineOptions{PerRetry: []policy.Policy{&clientRetryPolicy{gem: gem}}},
+		policy.RetryOptions{RetryDelay: time.Millisecond})
+	container.database.client.diagnosticsThresholds = thresholds
+	return container
+}
+
+func TestDiagnosticsRetries(t *testing.T) {
+	srv, closeFunc := mock.NewTLSServer()
+	defer closeFunc()
+
+	srv.AppendResponse(
+		mock.WithHeader(cosmosHeaderSubstatus, subStatusReadSessionNotAvailable),
+		mock.WithHeader(cosmosHeaderRequestCharge, "1"),
+		mock.WithStatusCode(404))
+	srv.AppendResponse(
+		mock.WithHeader(cosmosHeaderRetryAfterMs, "5"),
+		mock.WithHeader(cosmosHeaderRequestCharge, "0.5"),
+		mock.WithStatusCode(429))
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"id":"doc1"}`)),
+		mock.WithHeader(cosmosHeaderActivityId, "someActivityId"),
+		mock.WithHeader(cosmosHeaderRequestCharge, "2"),
+		mock.WithHeader(cosmosHeaderPartitionKeyRangeId, "3"),
+		mock.WithStatusCode(200))
+
+	container := newDiagnosticsTestContainer(t, srv, nil)
+	resp, err := container.ReadItem(context.TODO(), NewPartitionKeyString("1"), "doc1", nil)
+	require.NoError(t, err)
+	require.NotNil(t, resp.Diagnostics)
+
+	diagnostics := resp.Diagnostics
+	assert.Equal(t, float32(3.5), diagnostics.RequestCharge())
+	assert.Equal(t, 2, diagnostics.RetryCount())
+	assert.Equal(t, 5*time.Millisecond, diagnostics.ThrottlingBackoff())
+	assert.Len(t, diagnostics.RegionsContacted(), 1)
+	assert.Greater(t, diagnostics.Duration(), time.Duration(0))
+
+	events := diagnostics.Events()
+	kinds := []DiagnosticsEventKind{}
+	for _, e := range events {
+		kinds = append(kinds, e.Kind)
+	}
+	assert.Equal(t, []DiagnosticsEventKind{DiagnosticsEventRequest, DiagnosticsEventRetry, DiagnosticsEventRequest, DiagnosticsEventRequest}, kinds)
+	assert.Equal(t, 404, events[0].StatusCode)
+	assert.Equal(t, subStatusReadSessionNotAvailable, events[0].SubStatusCode)
+	assert.Equal(t, "ReadSessionNotAvailable", events[1].Reason)
+	assert.Equal(t, 429, events[2].StatusCode)
+	assert.Equal(t, 5*time.Millisecond, events[2].RetryAfter)
+	assert.Equal(t, "someActivityId", events[3].ActivityID)
+	assert.Equal(t, "3", events[3].PartitionKeyRangeID)
+	assert.NotEmpty(t, events[3].Region)
+
+	var parsed map[string]any
+	require.NoError(t, json.Unmarshal([]byte(diagnostics.String()), &parsed))
+	assert.Equal(t, "Document", parsed["resourceType"])
+	assert.Equal(t, "dbs/databaseId/colls/containerId/docs/doc1", parsed["resourceAddress"])
+	assert.Equal(t, 3.5, parsed["requestCharge"])
+	assert.Equal(t, 5.0, parsed["throttlingBackoffMs"])
+	assert.Len(t, parsed["events"], 4)
+}
+
+func TestDiagnosticsFromError(t *testing.T) {
+	srv, closeFunc := mock.NewTLSServer()
+	defer closeFunc()
+
+	srv.AppendResponse(
+		mock.WithHeader(cosmosHeaderSubstatus, subStatusReadSessionNotAvailable),
+		mock.WithStatusCode(404))
+	srv.AppendResponse(
+		mock.WithHeader(cosmosHeaderSubstatus, subStatusReadSessionNotAvailable),
+		mock.WithStatusCode(404))
+
+	container := newDiagnosticsTestContainer(t, srv, nil)
+	_, err := container.ReadItem(context.TODO(), NewPartitionKeyString("1"), "doc1", nil)
+	require.Error(t, err)
+
+	diagnostics := DiagnosticsFromError(err)
+	require.NotNil(t, diagnostics)
+	assert.Equal(t, 1, diagnostics.RetryCount())
+
+	assert.Nil(t, DiagnosticsFromError(errors.New("not a response error")))
+	assert.Nil(t, DiagnosticsFromError(nil))
+}
+
+func TestDiagnosticsThresholds(t *testing.T) {
+	srv, closeFunc := mock.NewTLSServer()
+	defer closeFunc()
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"doc1"}`)), mock.WithHeader(cosmosHeaderRequestCharge, "1"), mock.WithStatusCode(200))
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"doc1"}`)), mock.WithHeader(cosmosHeaderRequestCharge, "10"), mock.WithStatusCode(200))
+
+	var reported []*Diagnostics
+	container := newDiagnosticsTestContainer(t, srv, &DiagnosticsThresholds{
+		Latency:       time.Hour,
+		RequestCharge: 5,
+		OnThresholdExceeded: func(diagnostics *Diagnostics) {
+			reported = append(reported, diagnostics)
+		},
+	})
+
+	_, err := container.ReadItem(context.TODO(), NewPartitionKeyString("1"), "doc1", nil)
+	require.NoError(t, err)
+	assert.Empty(t, reported)
+
+	resp, err := container.ReadItem(context.TODO(), NewPartitionKeyString("1"), "doc1", nil)
+	require.NoError(t, err)
+	require.Len(t, reported, 1)
+	assert.Same(t, resp.Diagnostics, reported[0])
+}
+
+func TestDiagnosticsAccountRefresh(t *testing.T) {
+	srv, closeFunc := mock.NewTLSServer()
+	defer closeFunc()
+	srv.SetResponse(
+		mock.WithBody([]byte(`{"writableLocations":[{"name":"West US","databaseAccountEndpoint":"https://account-westus.documents.azure.com:443/"}],"readableLocations":[{"name":"West US","databaseAccountEndpoint":"https://account-westus.documents.azure.com:443/"}],"userConsistencyPolicy":{"defaultConsistencyLevel":"Session"}}`)),
+		mock.WithStatusCode(200))
+
+	pipeline := azruntime.NewPipeline("azcosmostest", "v1.0.0", azruntime.PipelineOptions{}, &policy.ClientOptions{Transport: srv})
+	gem, err := newGlobalEndpointManager(srv.URL(), pipeline, []string{}, 0, true)
+	require.NoError(t, err)
+
+	diagnostics := newDiagnostics(pipelineRequestOptions{resourceType: resourceTypeDocument})
+	require.NoError(t, gem.Update(withDiagnostics(context.TODO(), diagnostics), true))
+	assert.Equal(t, ConsistencyLevelSession, gem.DefaultConsistencyLevel())
+
+	events := diagnostics.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, DiagnosticsEventAccountRefresh, events[0].Kind)
+	assert.Equal(t, srv.URL(), events[0].Endpoint)
+	assert.Contains(t, events[0].Reason, "West US")
+	assert.Empty(t, events[0].Error)
+}
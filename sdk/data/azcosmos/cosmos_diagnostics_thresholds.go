@@ -0,0 +1,44 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"time"
+
+	azlog "github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
+)
+
+// DiagnosticsThresholds reports the diagnostics of the operations exceeding a latency or request charge budget.
+type DiagnosticsThresholds struct {
+	// Latency is the duration above which the diagnostics of an operation are reported.
+	// Zero disables the latency threshold.
+	Latency time.Duration
+	// RequestCharge is the number of request units, including retries, above which the diagnostics of an operation are reported.
+	// Zero disables the request charge threshold.
+	RequestCharge float32
+	// OnThresholdExceeded is called with the diagnostics of each operation exceeding a threshold, once the operation completed.
+	// It must be safe to call concurrently.
+	// When nil, the diagnostics are written to the azcore log.
+	OnThresholdExceeded func(diagnostics *Diagnostics)
+}
+
+// report reports the diagnostics of a completed operation when they exceed a threshold.
+func (t *DiagnosticsThresholds) report(d *Diagnostics) {
+	if t == nil || d == nil {
+		return
+	}
+
+	exceeded := (t.Latency > 0 && d.Duration() > t.Latency) ||
+		(t.RequestCharge > 0 && d.RequestCharge() > t.RequestCharge)
+	if !exceeded {
+		return
+	}
+
+	if t.OnThresholdExceeded != nil {
+		t.OnThresholdExceeded(d)
+		return
+	}
+	log.Write(azlog.EventResponse, "\n===== Operation exceeded diagnostics thresholds:\n"+d.String()+"\n=====\n")
+}
@@ -0,0 +1,478 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// fakeCosmosService is an in-memory transport emulating the subset of the Cosmos DB REST API used by tests that need
+// state across requests. Items are partitioned by id, queries only support filtering ids by the @prefix parameter, and
+// change feeds are read per partition key range with log sequence numbers as continuations.
+type fakeCosmosService struct {
+	mu         sync.Mutex
+	containers map[string]*fakeContainer
+	// throttledOperations is the number of upcoming batch operations that are throttled.
+	throttledOperations int
+	// batchGate blocks batch requests until it is closed or their context is done, when set.
+	batchGate chan struct{}
+	// batches records the batch requests.
+	batches []fakeBatch
+}
+
+type fakeBatch struct {
+	rangeID string
+	ids     []string
+}
+
+type fakeContainer struct {
+	items  map[string]map[string]any
+	ranges []partitionKeyRange
+	// changes contains the change feed of the container, ordered by log sequence number.
+	changes []fakeChange
+	lsn     int64
+}
+
+type fakeChange struct {
+	lsn int64
+	epk string
+	doc map[string]any
+}
+
+func newFakeCosmosService() *fakeCosmosService {
+	return &fakeCosmosService{containers: map[string]*fakeContainer{}}
+}
+
+// newClient returns a client sending its requests to the fake service.
+func (s *fakeCosmosService) newClient(t *testing.T) *Client {
+	internalClient, err := azcore.NewClient("azcosmostest", "v1.0.0", azruntime.PipelineOptions{}, &policy.ClientOptions{Transport: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint, _ := url.Parse("https://fake.documents.azure.com:443/")
+	return &Client{endpoint: endpoint.String(), endpointUrl: endpoint, internal: internalClient, gem: &globalEndpointManager{preferredLocations: []string{}}}
+}
+
+func (s *fakeCosmosService) container(link string) *fakeContainer {
+	c, ok := s.containers[link]
+	if !ok {
+		c = &fakeContainer{
+			items:  map[string]map[string]any{},
+			ranges: []partitionKeyRange{{ID: "0", MinInclusive: "", MaxExclusive: "FF"}},
+		}
+		s.containers[link] = c
+	}
+	return c
+}
+
+// setPartitionKeyRanges replaces the partition key ranges of a container, for example to simulate a split.
+func (s *fakeCosmosService) setPartitionKeyRanges(link string, ranges ...partitionKeyRange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.container(link).ranges = ranges
+}
+
+// addChange writes a document with the effective partition key epk to the change feed of a container.
+func (s *fakeCosmosService) addChange(link string, epk string, doc map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.container(link)
+	c.lsn++
+	stored := map[string]any{"_lsn": c.lsn}
+	for k, v := range doc {
+		stored[k] = v
+	}
+	c.changes = append(c.changes, fakeChange{lsn: c.lsn, epk: epk, doc: stored})
+}
+
+// items returns the items of a container sorted by id.
+func (s *fakeCosmosService) items(link string) []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.container(link)
+	var items []map[string]any
+	for _, item := range c.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i]["id"].(string) < items[j]["id"].(string) })
+	return items
+}
+
+func (s *fakeCosmosService) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	gate := s.batchGate
+	s.mu.Unlock()
+	if gate != nil && req.Header.Get(cosmosHeaderIsBatchRequest) != "" {
+		select {
+		case <-gate:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) < 4 || segments[0] != "dbs" || segments[2] != "colls" {
+		return s.respond(req, http.StatusNotFound, nil, nil)
+	}
+	c := s.container(strings.Join(segments[:4], "/"))
+	switch {
+	case len(segments) == 4 && req.Method == http.MethodGet:
+		return s.respond(req, http.StatusOK, map[string]any{
+			"id":           segments[3],
+			"partitionKey": PartitionKeyDefinition{Kind: PartitionKeyKindHash, Paths: []string{"/id"}, Version: 2},
+		}, nil)
+	case len(segments) == 5 && segments[4] == "pkranges":
+		return s.respond(req, http.StatusOK, map[string]any{"_rid": "rid", "PartitionKeyRanges": c.ranges, "_count": len(c.ranges)}, nil)
+	case len(segments) == 5 && req.Method == http.MethodGet && req.Header.Get(cosmosHeaderChangeFeed) != "":
+		return s.readChangeFeed(req, c)
+	case len(segments) == 5 && req.Method == http.MethodPost && req.Header.Get(cosmosHeaderQuery) != "":
+		return s.query(req, c, body)
+	case len(segments) == 5 && req.Method == http.MethodPost && req.Header.Get(cosmosHeaderIsBatchRequest) != "":
+		return s.batch(req, c, body)
+	case len(segments) == 5 && req.Method == http.MethodPost:
+		var item map[string]any
+		if err := json.Unmarshal(body, &item); err != nil {
+			return s.respond(req, http.StatusBadRequest, nil, nil)
+		}
+		id, _ := item["id"].(string)
+		if _, exists := c.items[id]; exists && req.Header.Get(cosmosHeaderIsUpsert) == "" {
+			return s.respond(req, http.StatusConflict, nil, nil)
+		}
+		return s.write(req, c, http.StatusCreated, item)
+	case len(segments) == 6 && segments[4] == "docs":
+		item, exists := c.items[segments[5]]
+		if !exists {
+			return s.respond(req, http.StatusNotFound, nil, nil)
+		}
+		if ifMatch := req.Header.Get(headerIfMatch); ifMatch != "" && ifMatch != item["_etag"] {
+			return s.respond(req, http.StatusPreconditionFailed, nil, nil)
+		}
+		switch req.Method {
+		case http.MethodGet:
+			return s.respond(req, http.StatusOK, item, http.Header{cosmosHeaderEtag: {item["_etag"].(string)}})
+		case http.MethodPut:
+			var replaced map[string]any
+			if err := json.Unmarshal(body, &replaced); err != nil {
+				return s.respond(req, http.StatusBadRequest, nil, nil)
+			}
+			return s.write(req, c, http.StatusOK, replaced)
+		case http.MethodDelete:
+			delete(c.items, segments[5])
+			return s.respond(req, http.StatusNoContent, nil, nil)
+		}
+	}
+	return s.respond(req, http.StatusBadRequest, nil, nil)
+}
+
+func (s *fakeCosmosService) write(req *http.Request, c *fakeContainer, status int, item map[string]any) (*http.Response, error) {
+	c.lsn++
+	etag := `"` + strconv.FormatInt(c.lsn, 10) + `"`
+	item["_etag"] = etag
+	c.items[item["id"].(string)] = item
+	return s.respond(req, status, item, http.Header{cosmosHeaderEtag: {etag}})
+}
+
+// batch executes the operations of a non-atomic batch request sent to a partition key range. Every operation must
+// belong to the range.
+func (s *fakeCosmosService) batch(req *http.Request, c *fakeContainer, body []byte) (*http.Response, error) {
+	var pkr *partitionKeyRange
+	for i := range c.ranges {
+		if c.ranges[i].ID == req.Header.Get(cosmosHeaderPartitionKeyRangeId) {
+			pkr = &c.ranges[i]
+		}
+	}
+	if pkr == nil {
+		return s.respond(req, http.StatusGone, nil, http.Header{cosmosHeaderSubstatus: {subStatusPartitionKeyRangeGone}})
+	}
+
+	var operations []struct {
+		PartitionKey  string          `json:"partitionKey"`
+		OperationType string          `json:"operationType"`
+		ID            string          `json:"id"`
+		IfMatch       string          `json:"ifMatch"`
+		ResourceBody  json.RawMessage `json:"resourceBody"`
+	}
+	if err := json.Unmarshal(body, &operations); err != nil {
+		return s.respond(req, http.StatusBadRequest, nil, nil)
+	}
+	definition := PartitionKeyDefinition{Kind: PartitionKeyKindHash, Paths: []string{"/id"}, Version: 2}
+	batch := fakeBatch{rangeID: pkr.ID}
+	results := make([]map[string]any, len(operations))
+	status := http.StatusOK
+	for i, op := range operations {
+		var pkValues []string
+		if err := json.Unmarshal([]byte(op.PartitionKey), &pkValues); err != nil || len(pkValues) != 1 {
+			return s.respond(req, http.StatusBadRequest, nil, nil)
+		}
+		epk, err := NewPartitionKeyString(pkValues[0]).effectivePartitionKey(definition)
+		if err != nil || epk < pkr.MinInclusive || epk >= pkr.MaxExclusive {
+			return s.respond(req, http.StatusBadRequest, nil, nil)
+		}
+		batch.ids = append(batch.ids, pkValues[0])
+
+		result := s.batchOperation(c, pkValues[0], op.OperationType, op.IfMatch, op.ResourceBody)
+		if s.throttledOperations > 0 {
+			s.throttledOperations--
+			result = map[string]any{"statusCode": http.StatusTooManyRequests, "retryAfterMilliseconds": 1}
+		}
+		if result["statusCode"].(int) >= 300 {
+			status = http.StatusMultiStatus
+		}
+		result["requestCharge"] = 1
+		results[i] = result
+	}
+	s.batches = append(s.batches, batch)
+	return s.respond(req, status, results, nil)
+}
+
+func (s *fakeCosmosService) batchOperation(c *fakeContainer, pk string, operationType string, ifMatch string, resourceBody json.RawMessage) map[string]any {
+	id := pk
+	existing, exists := c.items[id]
+	if ifMatch != "" && (!exists || ifMatch != existing["_etag"]) {
+		return map[string]any{"statusCode": http.StatusPreconditionFailed}
+	}
+	var item map[string]any
+	switch operationType {
+	case "Create", "Upsert", "Replace":
+		if err := json.Unmarshal(resourceBody, &item); err != nil || item["id"] != id {
+			return map[string]any{"statusCode": http.StatusBadRequest}
+		}
+		switch {
+		case operationType == "Create" && exists:
+			return map[string]any{"statusCode": http.StatusConflict}
+		case operationType == "Replace" && !exists:
+			return map[string]any{"statusCode": http.StatusNotFound}
+		}
+	case "Patch":
+		if !exists {
+			return map[string]any{"statusCode": http.StatusNotFound}
+		}
+		var patch struct {
+			Operations []patchOperation `json:"operations"`
+		}
+		if err := json.Unmarshal(resourceBody, &patch); err != nil {
+			return map[string]any{"statusCode": http.StatusBadRequest}
+		}
+		item = map[string]any{}
+		for k, v := range existing {
+			item[k] = v
+		}
+		for _, op := range patch.Operations {
+			item[strings.TrimPrefix(op.Path, "/")] = op.Value
+		}
+	case "Delete":
+		if !exists {
+			return map[string]any{"statusCode": http.StatusNotFound}
+		}
+		delete(c.items, id)
+		return map[string]any{"statusCode": http.StatusNoContent}
+	case "Read":
+		if !exists {
+			return map[string]any{"statusCode": http.StatusNotFound}
+		}
+		return map[string]any{"statusCode": http.StatusOK, "eTag": existing["_etag"], "resourceBody": existing}
+	default:
+		return map[string]any{"statusCode": http.StatusBadRequest}
+	}
+
+	c.lsn++
+	etag := `"` + strconv.FormatInt(c.lsn, 10) + `"`
+	item["_etag"] = etag
+	c.items[id] = item
+	status := http.StatusOK
+	if !exists {
+		status = http.StatusCreated
+	}
+	return map[string]any{"statusCode": status, "eTag": etag, "resourceBody": item}
+}
+
+// recordedBatches returns the batch requests received so far.
+func (s *fakeCosmosService) recordedBatches() []fakeBatch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeBatch{}, s.batches...)
+}
+
+// The cross-partition ORDER BY query supported by the fake service, and its query plan.
+const (
+	fakeOrderByQuery          = "SELECT * FROM c ORDER BY c.id"
+	fakeOrderByRewrittenQuery = `SELECT c._rid, [{"item": c.id}] AS orderByItems, c AS payload FROM c WHERE ({documentdb-formattableorderbyquery-filter}) ORDER BY c.id`
+	fakeOrderByQueryPlan      = `{"partitionedQueryExecutionInfoVersion":2,"queryInfo":{"distinctType":"None","orderBy":["Ascending"],"orderByExpressions":["c.id"],"rewrittenQuery":"SELECT c._rid, [{\"item\": c.id}] AS orderByItems, c AS payload FROM c WHERE ({documentdb-formattableorderbyquery-filter}) ORDER BY c.id"},"queryRanges":[{"min":"","max":"FF","isMinInclusive":true,"isMaxInclusive":false}]}`
+)
+
+func (s *fakeCosmosService) query(req *http.Request, c *fakeContainer, body []byte) (*http.Response, error) {
+	var q queryBody
+	if err := json.Unmarshal(body, &q); err != nil {
+		return s.respond(req, http.StatusBadRequest, nil, nil)
+	}
+	if req.Header.Get(cosmosHeaderIsQueryPlanRequest) != "" {
+		if q.Query != fakeOrderByQuery {
+			return s.respond(req, http.StatusBadRequest, nil, nil)
+		}
+		return s.respond(req, http.StatusOK, json.RawMessage(fakeOrderByQueryPlan), nil)
+	}
+	if q.Query == strings.ReplaceAll(fakeOrderByRewrittenQuery, "{documentdb-formattableorderbyquery-filter}", "true") {
+		return s.queryOrderByPartition(req, c)
+	}
+	prefix := ""
+	for _, p := range q.Parameters {
+		if p.Name == "@prefix" {
+			prefix, _ = p.Value.(string)
+		}
+	}
+	documents := []map[string]any{}
+	for id, item := range c.items {
+		if strings.HasPrefix(id, prefix) {
+			documents = append(documents, item)
+		}
+	}
+	return s.respond(req, http.StatusOK, map[string]any{"_rid": "rid", "Documents": documents, "_count": len(documents)}, nil)
+}
+
+// queryOrderByPartition returns a page of two items of a partition key range sorted by id, as rewritten for the
+// cross-partition ORDER BY query.
+func (s *fakeCosmosService) queryOrderByPartition(req *http.Request, c *fakeContainer) (*http.Response, error) {
+	var pkr *partitionKeyRange
+	for i := range c.ranges {
+		if c.ranges[i].ID == req.Header.Get(cosmosHeaderPartitionKeyRangeId) {
+			pkr = &c.ranges[i]
+		}
+	}
+	if pkr == nil {
+		return s.respond(req, http.StatusGone, nil, http.Header{cosmosHeaderSubstatus: {subStatusPartitionKeyRangeGone}})
+	}
+	definition := PartitionKeyDefinition{Kind: PartitionKeyKindHash, Paths: []string{"/id"}, Version: 2}
+	var ids []string
+	for id := range c.items {
+		epk, err := NewPartitionKeyString(id).effectivePartitionKey(definition)
+		if err != nil {
+			return nil, err
+		}
+		if epk >= pkr.MinInclusive && epk < pkr.MaxExclusive {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	offset := 0
+	if continuation := req.Header.Get(cosmosHeaderContinuationToken); continuation != "" {
+		var err error
+		if offset, err = strconv.Atoi(continuation); err != nil {
+			return s.respond(req, http.StatusBadRequest, nil, nil)
+		}
+	}
+	documents := []map[string]any{}
+	for _, id := range ids[offset:min(offset+2, len(ids))] {
+		documents = append(documents, map[string]any{"_rid": id, "orderByItems": []map[string]any{{"item": id}}, "payload": c.items[id]})
+	}
+	headers := http.Header{}
+	if offset+2 < len(ids) {
+		headers.Set(cosmosHeaderContinuationToken, strconv.Itoa(offset+2))
+	}
+	return s.respond(req, http.StatusOK, map[string]any{"_rid": "rid", "Documents": documents, "_count": len(documents)}, headers)
+}
+
+func (s *fakeCosmosService) readChangeFeed(req *http.Request, c *fakeContainer) (*http.Response, error) {
+	var pkr *partitionKeyRange
+	for i := range c.ranges {
+		if c.ranges[i].ID == req.Header.Get(headerXmsDocumentDbPartitionKeyRangeId) {
+			pkr = &c.ranges[i]
+		}
+	}
+	if pkr == nil {
+		return s.respond(req, http.StatusGone, nil, http.Header{cosmosHeaderSubstatus: {subStatusPartitionKeyRangeGone}})
+	}
+	minEpk, maxEpk := pkr.MinInclusive, pkr.MaxExclusive
+	if v := req.Header.Get(cosmosHeaderStartEpk); v != "" {
+		minEpk = v
+	}
+	if v := req.Header.Get(cosmosHeaderEndEpk); v != "" {
+		maxEpk = v
+	}
+
+	var latest int64
+	for _, change := range c.changes {
+		if change.epk >= pkr.MinInclusive && change.epk < pkr.MaxExclusive {
+			latest = change.lsn
+		}
+	}
+	headers := http.Header{cosmosHeaderSessionToken: {pkr.ID + ":-1#" + strconv.FormatInt(latest, 10)}}
+
+	from := int64(0)
+	switch continuation := req.Header.Get(headerIfNoneMatch); continuation {
+	case "":
+	case "*":
+		from = latest
+	default:
+		var err error
+		if from, err = strconv.ParseInt(strings.Trim(continuation, `"`), 10, 64); err != nil {
+			return s.respond(req, http.StatusBadRequest, nil, nil)
+		}
+	}
+	maxItemCount := len(c.changes)
+	if v, err := strconv.Atoi(req.Header.Get(cosmosHeaderMaxItemCount)); err == nil && v > 0 {
+		maxItemCount = v
+	}
+
+	documents := []map[string]any{}
+	next := from
+	for _, change := range c.changes {
+		if change.lsn > from && change.epk >= minEpk && change.epk < maxEpk && len(documents) < maxItemCount {
+			documents = append(documents, change.doc)
+			next = change.lsn
+		}
+	}
+	if len(documents) == 0 {
+		headers.Set(cosmosHeaderEtag, `"`+strconv.FormatInt(latest, 10)+`"`)
+		return s.respond(req, http.StatusNotModified, nil, headers)
+	}
+	headers.Set(cosmosHeaderEtag, `"`+strconv.FormatInt(next, 10)+`"`)
+	return s.respond(req, http.StatusOK, map[string]any{"_rid": "rid", "Documents": documents, "_count": len(documents)}, headers)
+}
+
+func (s *fakeCosmosService) respond(req *http.Request, status int, body any, headers http.Header) (*http.Response, error) {
+	resp := &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{},
+		Request:    req,
+		Body:       http.NoBody,
+	}
+	for k, v := range headers {
+		resp.Header.Set(k, v[0])
+	}
+	resp.Header.Set(cosmosHeaderRequestCharge, "1")
+	if body != nil {
+		marshalled, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(marshalled))
+	}
+	return resp, nil
+}
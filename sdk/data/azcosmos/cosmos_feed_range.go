@@ -32,3 +32,39 @@ func findPartitionKeyRangeID(feedRange FeedRange, partitionKeyRanges []partition
 	}
 	return "", fmt.Errorf("no matching partition key range found for feed range [%s, %s)", feedRange.MinInclusive, feedRange.MaxExclusive)
 }
+
+// findContainingPartitionKeyRange finds the partition key range that fully contains the given FeedRange.
+// This is the case when the feed range was created before a merge, or is a sub-range of a physical partition.
+// Effective partition key boundaries are upper-case hex strings between "" and "FF", so they compare lexicographically.
+func findContainingPartitionKeyRange(feedRange FeedRange, partitionKeyRanges []partitionKeyRange) (partitionKeyRange, bool) {
+	for _, pkr := range partitionKeyRanges {
+		if pkr.MinInclusive <= feedRange.MinInclusive && feedRange.MaxExclusive <= pkr.MaxExclusive {
+			return pkr, true
+		}
+	}
+	return partitionKeyRange{}, false
+}
+
+// findOverlappingPartitionKeyRanges returns the partition key ranges that overlap with the given FeedRange.
+// More than one overlapping range means the feed range was split.
+func findOverlappingPartitionKeyRanges(feedRange FeedRange, partitionKeyRanges []partitionKeyRange) []partitionKeyRange {
+	var overlapping []partitionKeyRange
+	for _, pkr := range partitionKeyRanges {
+		if pkr.MinInclusive < feedRange.MaxExclusive && feedRange.MinInclusive < pkr.MaxExclusive {
+			overlapping = append(overlapping, pkr)
+		}
+	}
+	return overlapping
+}
+
+// intersect returns the part of the FeedRange that is covered by the partition key range.
+func (f FeedRange) intersect(pkr partitionKeyRange) FeedRange {
+	result := f
+	if pkr.MinInclusive > result.MinInclusive {
+		result.MinInclusive = pkr.MinInclusive
+	}
+	if pkr.MaxExclusive < result.MaxExclusive {
+		result.MaxExclusive = pkr.MaxExclusive
+	}
+	return result
+}
@@ -142,3 +142,33 @@ func TestContainerGetFeedRangesEmpty(t *testing.T) {
 		t.Fatalf("Expected 0 feed ranges, got %d", len(feedRanges))
 	}
 }
+
+func TestFindOverlappingPartitionKeyRanges(t *testing.T) {
+	partitionKeyRanges := []partitionKeyRange{
+		{ID: "0", MinInclusive: "", MaxExclusive: "40"},
+		{ID: "1", MinInclusive: "40", MaxExclusive: "80"},
+		{ID: "2", MinInclusive: "80", MaxExclusive: "FF"},
+	}
+
+	overlapping := findOverlappingPartitionKeyRanges(NewFeedRange("20", "80"), partitionKeyRanges)
+	if len(overlapping) != 2 || overlapping[0].ID != "0" || overlapping[1].ID != "1" {
+		t.Fatalf("Expected ranges 0 and 1, got %v", overlapping)
+	}
+	if r := NewFeedRange("20", "80").intersect(overlapping[0]); r != NewFeedRange("20", "40") {
+		t.Errorf("Expected intersection [20, 40), got %v", r)
+	}
+	if r := NewFeedRange("20", "80").intersect(overlapping[1]); r != NewFeedRange("40", "80") {
+		t.Errorf("Expected intersection [40, 80), got %v", r)
+	}
+
+	if overlapping := findOverlappingPartitionKeyRanges(NewFeedRange("", "FF"), partitionKeyRanges); len(overlapping) != 3 {
+		t.Errorf("Expected all ranges to overlap the full range, got %v", overlapping)
+	}
+
+	if pkr, ok := findContainingPartitionKeyRange(NewFeedRange("90", "A0"), partitionKeyRanges); !ok || pkr.ID != "2" {
+		t.Errorf("Expected range 2 to contain [90, A0), got %v", pkr)
+	}
+	if _, ok := findContainingPartitionKeyRange(NewFeedRange("30", "50"), partitionKeyRanges); ok {
+		t.Error("Expected no range to contain [30, 50)")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	azlog "github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
@@ -26,6 +27,10 @@ type globalEndpointManager struct {
 	refreshTimeInterval time.Duration
 	gemMutex            sync.RWMutex
 	lastUpdateTime      time.Time
+	// defaultConsistencyLevel is the default consistency level of the account, once its properties were read.
+	defaultConsistencyLevel atomic.Value // ConsistencyLevel
+	// partitionCircuitBreaker tracks the partitions unavailable in each region, when enabled.
+	partitionCircuitBreaker *partitionCircuitBreaker
 }
 
 func newGlobalEndpointManager(clientEndpoint string, pipeline azruntime.Pipeline, preferredLocations []string, refreshTimeInterval time.Duration, enableCrossRegionRetries bool) (*globalEndpointManager, error) {
@@ -82,6 +87,13 @@ func (gem *globalEndpointManager) RefreshStaleEndpoints() {
 	gem.locationCache.refreshStaleEndpoints()
 }
 
+// DefaultConsistencyLevel returns the default consistency level of the account, or an empty string
+// until the account properties were read.
+func (gem *globalEndpointManager) DefaultConsistencyLevel() ConsistencyLevel {
+	level, _ := gem.defaultConsistencyLevel.Load().(ConsistencyLevel)
+	return level
+}
+
 func (gem *globalEndpointManager) ShouldRefresh() bool {
 	gem.gemMutex.RLock()
 	defer gem.gemMutex.RUnlock()
@@ -102,8 +114,14 @@ func (gem *globalEndpointManager) Update(ctx context.Context, forceRefresh bool)
 	if !gem.shouldRefresh() && !forceRefresh {
 		return nil
 	}
+	refresh := DiagnosticsEvent{Kind: DiagnosticsEventAccountRefresh, StartTime: time.Now(), Endpoint: gem.clientEndpoint}
+	defer func() {
+		refresh.Duration = time.Since(refresh.StartTime)
+		diagnosticsFromContext(ctx).addEvent(refresh)
+	}()
 	accountProperties, err := gem.GetAccountProperties(ctx)
 	if err != nil {
+		refresh.Error = err.Error()
 		return fmt.Errorf("failed to retrieve account properties: %v", err)
 	}
 	err = gem.locationCache.update(
@@ -112,8 +130,11 @@ func (gem *globalEndpointManager) Update(ctx context.Context, forceRefresh bool)
 		gem.preferredLocations,
 		&accountProperties.EnableMultipleWriteLocations)
 	if err != nil {
+		refresh.Error = err.Error()
 		return fmt.Errorf("failed to update location cache: %v", err)
 	}
+	refresh.Reason = accountProperties.String()
+	gem.defaultConsistencyLevel.Store(ConsistencyLevel(accountProperties.AccountConsistency.DefaultConsistencyLevel))
 	gem.lastUpdateTime = time.Now()
 	return nil
 }
@@ -27,7 +27,9 @@ func (p *globalEndpointManagerPolicy) Do(req *policy.Request) (*http.Response, e
 		go func() {
 			// Use the same context, but without the cancellation signal.
 			// We DO want to preserve things like context values, but the GEM update needs to complete fully, even if the user cancels the triggering request.
-			_ = p.gem.Update(context.WithoutCancel(req.Raw().Context()), false)
+			// The refresh completes after the request, outside of the timeline of its operation.
+			ctx := withDiagnostics(context.WithoutCancel(req.Raw().Context()), nil)
+			_ = p.gem.Update(ctx, false)
 		}()
 	}
 	if p.gem.CanUseMultipleWriteLocations() {
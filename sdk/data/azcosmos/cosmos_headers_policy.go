@@ -20,6 +20,14 @@ type headerOptionsOverride struct {
 	correlatedActivityId         *uuid.UUID
 }
 
+// partitionKeyOrNil returns the partition key of the request, or nil when it doesn't target a partition key.
+func (h *headerOptionsOverride) partitionKeyOrNil() *PartitionKey {
+	if h == nil || h.partitionKey == nil || len(h.partitionKey.values) == 0 {
+		return nil
+	}
+	return h.partitionKey
+}
+
 func (p *headerPolicies) Do(req *policy.Request) (*http.Response, error) {
 	o := pipelineRequestOptions{}
 	if req.OperationValue(&o) {
@@ -37,12 +37,17 @@ const (
 	cosmosHeaderIsBatchRequest                     string = "x-ms-cosmos-is-batch-request"
 	cosmosHeaderIsBatchAtomic                      string = "x-ms-cosmos-batch-atomic"
 	cosmosHeaderIsBatchOrdered                     string = "x-ms-cosmos-batch-ordered"
+	cosmosHeaderBatchContinueOnError               string = "x-ms-cosmos-batch-continue-on-error"
 	cosmosHeaderSDKSupportedCapabilities           string = "x-ms-cosmos-sdk-supportedcapabilities"
 	cosmosHeaderEnableCrossPartitionQuery          string = "x-ms-documentdb-query-enablecrosspartition"
 	cosmosHeaderIsQueryPlanRequest                 string = "x-ms-cosmos-is-query-plan-request"
 	cosmosHeaderSupportedQueryFeatures             string = "x-ms-cosmos-supported-query-features"
 	cosmosHeaderAllowTentativeWrites               string = "x-ms-cosmos-allow-tentative-writes"
 	cosmosHeaderPartitionKeyRangeId                string = "x-ms-documentdb-partitionkeyrangeid"
+	cosmosHeaderScriptEnableLogging                string = "x-ms-documentdb-script-enable-logging"
+	cosmosHeaderScriptLogResults                   string = "x-ms-documentdb-script-log-results"
+	cosmosHeaderResourceTokenExpiry                string = "x-ms-documentdb-expiry-seconds"
+	cosmosHeaderPriorityLevel                      string = "x-ms-cosmos-priority-level"
 	headerXmsDate                                  string = "x-ms-date"
 	headerAuthorization                            string = "Authorization"
 	headerContentType                              string = "Content-Type"
@@ -87,6 +92,9 @@ const (
 	headerXmsItemCount                             string = "x-ms-item-count"
 	headerDedicatedGatewayMaxAge                   string = "x-ms-dedicatedgateway-max-age"
 	headerDedicatedGatewayBypassCache              string = "x-ms-dedicatedgateway-bypass-cache"
+	cosmosHeaderStartEpk                           string = "x-ms-start-epk"
+	cosmosHeaderEndEpk                             string = "x-ms-end-epk"
+	cosmosHeaderRetryAfterMs                       string = "x-ms-retry-after-ms"
 )
 
 const (
@@ -100,4 +108,7 @@ const (
 	subStatusWriteForbidden          string = "3"
 	subStatusDatabaseAccountNotFound string = "1008"
 	subStatusReadSessionNotAvailable string = "1002"
+	subStatusPartitionKeyRangeGone   string = "1002"
+	subStatusCompletingSplit         string = "1007"
+	subStatusCompletingMigration     string = "1008"
 )
@@ -12,9 +12,11 @@ import (
 
 // ItemOptions includes options for operations on items.
 type ItemOptions struct {
-	// Triggers to be invoked before the operation.
+	// Ids of the triggers to be invoked before the operation.
+	// The triggers must be of TriggerTypePre and are created with ContainerClient.CreateTrigger.
 	PreTriggers []string
-	// Triggers to be invoked after the operation.
+	// Ids of the triggers to be invoked after the operation.
+	// The triggers must be of TriggerTypePost and are created with ContainerClient.CreateTrigger.
 	PostTriggers []string
 	// SessionToken to be used when using Session consistency on the account.
 	// When working with Session consistency, each new write request to Azure Cosmos DB is assigned a new SessionToken.
@@ -23,6 +25,7 @@ type ItemOptions struct {
 	// If you wanted these nodes to participate in the same session (to be able read your own writes consistently across web tiers),
 	// you would have to send the SessionToken from the response of the write action on one node to the client tier, using a cookie or some other mechanism, and have that token flow back to the web tier for subsequent reads.
 	// If you are using a round-robin load balancer which does not maintain session affinity between requests, such as the Azure Load Balancer,the read could potentially land on a different node to the write request, where the session was created.
+	// When set, it replaces the session token tracked by the client. Client.ExportSessionState and Client.ImportSessionState share the session tokens of all the containers instead.
 	SessionToken *string
 	// ConsistencyLevel overrides the account defined consistency level for this operation.
 	// Consistency can only be relaxed.
@@ -37,6 +40,12 @@ type ItemOptions struct {
 	IfMatchEtag *azcore.ETag
 	// Options for operations in the dedicated gateway.
 	DedicatedGatewayRequestOptions *DedicatedGatewayRequestOptions
+	// PriorityLevel of the operation for priority-based execution.
+	// When the account has priority-based execution enabled, low priority operations are throttled before high priority operations.
+	PriorityLevel *PriorityLevel
+	// ThroughputControlGroup is the name of the throughput control group whose budget the operation consumes.
+	// The group must be enabled with ContainerClient.EnableThroughputControlGroup. When empty, the default group of the container is used, if any.
+	ThroughputControlGroup string
 }
 
 func (options *ItemOptions) toHeaders() *map[string]string {
@@ -66,6 +75,10 @@ func (options *ItemOptions) toHeaders() *map[string]string {
 		headers[headerIfMatch] = string(*options.IfMatchEtag)
 	}
 
+	if options.PriorityLevel != nil {
+		headers[cosmosHeaderPriorityLevel] = string(*options.PriorityLevel)
+	}
+
 	if options.DedicatedGatewayRequestOptions != nil {
 		dedicatedGatewayRequestOptions := options.DedicatedGatewayRequestOptions
 
@@ -81,3 +94,10 @@ func (options *ItemOptions) toHeaders() *map[string]string {
 
 	return &headers
 }
+
+func (options *ItemOptions) throughputControlGroupName() string {
+	if options == nil {
+		return ""
+	}
+	return options.ThroughputControlGroup
+}
@@ -57,12 +57,15 @@ func (accountProps accountProperties) String() string {
 		accountProps.ReadRegions, accountProps.WriteRegions, accountProps.EnableMultipleWriteLocations, accountProps.AccountConsistency.DefaultConsistencyLevel)
 }
 
+// locationCache tracks the regional endpoints of the account. mapMutex guards locationUnavailabilityInfoMap, and the
+// fields written by update, which are serialized by updateMutex.
 type locationCache struct {
 	locationInfo                      databaseAccountLocationsInfo
 	defaultEndpoint                   url.URL
 	enableCrossRegionRetries          bool
 	locationUnavailabilityInfoMap     map[url.URL]locationUnavailabilityInfo
 	mapMutex                          sync.RWMutex
+	updateMutex                       sync.Mutex
 	lastUpdateTime                    time.Time
 	enableMultipleWriteLocations      bool
 	unavailableLocationExpirationTime time.Duration
@@ -79,12 +82,16 @@ func newLocationCache(prefLocations []string, defaultEndpoint url.URL, enableCro
 }
 
 func (lc *locationCache) update(writeLocations []accountRegion, readLocations []accountRegion, prefList []string, enableMultipleWriteLocations *bool) error {
+	lc.updateMutex.Lock()
+	defer lc.updateMutex.Unlock()
 	nextLoc := copyDatabaseAccountLocationsInfo(lc.locationInfo)
 	if prefList != nil {
 		nextLoc.prefLocations = prefList
 	}
 	if enableMultipleWriteLocations != nil {
+		lc.mapMutex.Lock()
 		lc.enableMultipleWriteLocations = *enableMultipleWriteLocations
+		lc.mapMutex.Unlock()
 	}
 	lc.refreshStaleEndpoints()
 	if readLocations != nil {
@@ -107,14 +114,19 @@ func (lc *locationCache) update(writeLocations []accountRegion, readLocations []
 
 	nextLoc.writeEndpoints = lc.getPrefAvailableEndpoints(nextLoc.availWriteEndpointsByLocation, nextLoc.availWriteLocations, write, lc.defaultEndpoint)
 	nextLoc.readEndpoints = lc.getPrefAvailableEndpoints(nextLoc.availReadEndpointsByLocation, nextLoc.availReadLocations, read, nextLoc.writeEndpoints[0])
+	lc.mapMutex.Lock()
 	lc.lastUpdateTime = time.Now()
 	lc.locationInfo = nextLoc
+	lc.mapMutex.Unlock()
 	// TODO: log
 	return nil
 }
 
 func (lc *locationCache) resolveServiceEndpoint(locationIndex int, resourceType resourceType, isWriteOperation, useWriteEndpoint bool) url.URL {
-	if (isWriteOperation || useWriteEndpoint) && !lc.canUseMultipleWriteLocsToRoute(resourceType) {
+	lc.mapMutex.RLock()
+	defer lc.mapMutex.RUnlock()
+	canUseMultipleWriteLocsToRoute := lc.enableMultipleWriteLocations && resourceType == resourceTypeDocument
+	if (isWriteOperation || useWriteEndpoint) && !canUseMultipleWriteLocsToRoute {
 		if lc.enableCrossRegionRetries && len(lc.locationInfo.availWriteLocations) > 0 {
 			locationIndex = min(locationIndex%2, len(lc.locationInfo.availWriteLocations)-1)
 			writeLocation := lc.locationInfo.availWriteLocations[locationIndex]
@@ -130,35 +142,40 @@ func (lc *locationCache) resolveServiceEndpoint(locationIndex int, resourceType
 	return endpoints[locationIndex%len(endpoints)]
 }
 
-func (lc *locationCache) canUseMultipleWriteLocsToRoute(resourceType resourceType) bool {
-	return lc.canUseMultipleWriteLocs() && resourceType == resourceTypeDocument
-}
-
 func (lc *locationCache) readEndpoints() ([]url.URL, error) {
-	lc.mapMutex.RLock()
-	defer lc.mapMutex.RUnlock()
-	if time.Since(lc.lastUpdateTime) > lc.unavailableLocationExpirationTime && len(lc.locationUnavailabilityInfoMap) > 0 {
+	if lc.isStale() {
 		err := lc.update(nil, nil, nil, nil)
 		if err != nil {
 			return nil, err
 		}
 	}
+	lc.mapMutex.RLock()
+	defer lc.mapMutex.RUnlock()
 	return lc.locationInfo.readEndpoints, nil
 }
 
 func (lc *locationCache) writeEndpoints() ([]url.URL, error) {
-	lc.mapMutex.RLock()
-	defer lc.mapMutex.RUnlock()
-	if time.Since(lc.lastUpdateTime) > lc.unavailableLocationExpirationTime && len(lc.locationUnavailabilityInfoMap) > 0 {
+	if lc.isStale() {
 		err := lc.update(nil, nil, nil, nil)
 		if err != nil {
 			return nil, err
 		}
 	}
+	lc.mapMutex.RLock()
+	defer lc.mapMutex.RUnlock()
 	return lc.locationInfo.writeEndpoints, nil
 }
 
+// isStale reports whether endpoints were marked unavailable since the last update, longer ago than their expiration.
+func (lc *locationCache) isStale() bool {
+	lc.mapMutex.RLock()
+	defer lc.mapMutex.RUnlock()
+	return time.Since(lc.lastUpdateTime) > lc.unavailableLocationExpirationTime && len(lc.locationUnavailabilityInfoMap) > 0
+}
+
 func (lc *locationCache) getLocation(endpoint url.URL) string {
+	lc.mapMutex.RLock()
+	defer lc.mapMutex.RUnlock()
 	firstLoc := ""
 	for location, uri := range lc.locationInfo.availWriteEndpointsByLocation {
 		if uri == endpoint {
@@ -175,7 +192,7 @@ func (lc *locationCache) getLocation(endpoint url.URL) string {
 		}
 	}
 
-	if endpoint == lc.defaultEndpoint && !lc.canUseMultipleWriteLocs() {
+	if endpoint == lc.defaultEndpoint && !lc.enableMultipleWriteLocations {
 		if len(lc.locationInfo.availWriteEndpointsByLocation) > 0 {
 			return firstLoc
 		}
@@ -184,6 +201,8 @@ func (lc *locationCache) getLocation(endpoint url.URL) string {
 }
 
 func (lc *locationCache) canUseMultipleWriteLocs() bool {
+	lc.mapMutex.RLock()
+	defer lc.mapMutex.RUnlock()
 	return lc.enableMultipleWriteLocations
 }
 
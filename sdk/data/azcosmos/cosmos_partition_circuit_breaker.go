@@ -0,0 +1,216 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PartitionCircuitBreakerOptions configures the partition circuit breaker of the client.
+// After consecutive failures of the requests on items of a partition in a region, the requests for that partition are
+// routed to the next preferred region for UnavailableDuration, while the other partitions keep using the region.
+// Reads are routed in all accounts, writes only in accounts with multiple write regions.
+// A partition is identified by its partition key range when the client knows the partition key ranges of the container,
+// and by its partition key otherwise.
+type PartitionCircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures marking a partition unavailable in a region.
+	// The default is 5.
+	FailureThreshold int
+	// UnavailableDuration is the time a partition stays unavailable in a region. Once it elapses, a single failure
+	// marks the partition unavailable again, while a success resets its failures.
+	// The default is 1 minute.
+	UnavailableDuration time.Duration
+}
+
+const (
+	defaultPartitionFailureThreshold    = 5
+	defaultPartitionUnavailableDuration = time.Minute
+)
+
+// partitionCircuitBreaker tracks the consecutive failures of the partitions of containers in each region.
+type partitionCircuitBreaker struct {
+	failureThreshold    int
+	unavailableDuration time.Duration
+
+	mu         sync.Mutex
+	partitions map[partitionRegionKey]*partitionHealth
+}
+
+// partitionRegionKey identifies a partition of a container in a regional endpoint.
+type partitionRegionKey struct {
+	containerLink string
+	partition     string
+	endpoint      string
+}
+
+type partitionHealth struct {
+	consecutiveFailures int
+	unavailableUntil    time.Time
+}
+
+func newPartitionCircuitBreaker(o *PartitionCircuitBreakerOptions) *partitionCircuitBreaker {
+	if o == nil {
+		return nil
+	}
+	b := &partitionCircuitBreaker{
+		failureThreshold:    o.FailureThreshold,
+		unavailableDuration: o.UnavailableDuration,
+		partitions:          map[partitionRegionKey]*partitionHealth{},
+	}
+	if b.failureThreshold <= 0 {
+		b.failureThreshold = defaultPartitionFailureThreshold
+	}
+	if b.unavailableDuration <= 0 {
+		b.unavailableDuration = defaultPartitionUnavailableDuration
+	}
+	return b
+}
+
+// isUnavailable reports whether the partition is unavailable in the endpoint.
+func (b *partitionCircuitBreaker) isUnavailable(key partitionRegionKey) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	health, ok := b.partitions[key]
+	return ok && time.Now().Before(health.unavailableUntil)
+}
+
+// recordSuccess resets the failures of the partition in the endpoint.
+func (b *partitionCircuitBreaker) recordSuccess(key partitionRegionKey) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.partitions, key)
+}
+
+// recordFailure records a failure of the partition in the endpoint, and reports whether it marked the partition unavailable.
+func (b *partitionCircuitBreaker) recordFailure(key partitionRegionKey) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	health, ok := b.partitions[key]
+	if !ok {
+		health = &partitionHealth{}
+		b.partitions[key] = health
+	}
+	if time.Now().Before(health.unavailableUntil) {
+		return false
+	}
+	health.consecutiveFailures++
+	if health.consecutiveFailures < b.failureThreshold {
+		return false
+	}
+	// Once the partition becomes available again, a single failure marks it unavailable.
+	health.consecutiveFailures = b.failureThreshold - 1
+	health.unavailableUntil = time.Now().Add(b.unavailableDuration)
+	return true
+}
+
+// partitionOf returns the partition targeted by a request on items, or an empty string when it doesn't target a single partition.
+func partitionOf(operationContext pipelineRequestOptions) string {
+	if operationContext.partitionKeyRangeID != "" {
+		return "pkrange:" + operationContext.partitionKeyRangeID
+	}
+	if pk := operationContext.headerOptionsOverride.partitionKeyOrNil(); pk != nil {
+		if pkJSON, err := pk.toJsonString(); err == nil {
+			return "pk:" + pkJSON
+		}
+	}
+	return ""
+}
+
+// isPartitionFailure reports whether the outcome of a request counts as a failure of its partition in the region.
+func isPartitionFailure(response *http.Response, err error) bool {
+	if err != nil {
+		// Canceling the request, for example after another region responded, isn't a failure of the region.
+		return !errors.Is(err, context.Canceled)
+	}
+	switch response.StatusCode {
+	case http.StatusRequestTimeout, http.StatusGone, http.StatusInternalServerError, http.StatusServiceUnavailable:
+		return true
+	case http.StatusNotFound:
+		return response.Header.Get(cosmosHeaderSubstatus) == subStatusReadSessionNotAvailable
+	}
+	return false
+}
+
+// partitionBreakerRoute returns the index of the first location, starting at locationIndex, where the partition of the
+// request is available, and records the failover in the diagnostics of the request.
+func (p *clientRetryPolicy) partitionBreakerRoute(diagnostics *Diagnostics, o pipelineRequestOptions, locationIndex int, useWriteEndpoint bool) int {
+	breaker := p.gem.partitionCircuitBreaker
+	partition := partitionOf(o)
+	if breaker == nil || partition == "" || o.resourceType != resourceTypeDocument || (o.isWriteOperation && !p.gem.CanUseMultipleWriteLocations()) {
+		return locationIndex
+	}
+
+	endpoints, err := p.gem.GetReadEndpoints()
+	if o.isWriteOperation {
+		endpoints, err = p.gem.GetWriteEndpoints()
+	}
+	if err != nil {
+		// Without the regions of the account, the request is routed as if the breaker was disabled.
+		return locationIndex
+	}
+
+	containerLink := containerLinkOf(o.resourceAddress)
+	for i := 0; i < len(endpoints); i++ {
+		endpoint := p.gem.ResolveServiceEndpoint(locationIndex+i, o.resourceType, o.isWriteOperation, useWriteEndpoint)
+		if breaker.isUnavailable(partitionRegionKey{containerLink: containerLink, partition: partition, endpoint: endpoint.Host}) {
+			continue
+		}
+		if i > 0 {
+			skipped := p.gem.ResolveServiceEndpoint(locationIndex, o.resourceType, o.isWriteOperation, useWriteEndpoint)
+			diagnostics.addEvent(DiagnosticsEvent{
+				Kind:                DiagnosticsEventPartitionFailover,
+				StartTime:           time.Now(),
+				Region:              p.gem.GetEndpointLocation(endpoint),
+				Endpoint:            endpoint.String(),
+				PartitionKeyRangeID: o.partitionKeyRangeID,
+				Reason:              "PartitionUnavailableIn " + p.gem.GetEndpointLocation(skipped),
+			})
+		}
+		return locationIndex + i
+	}
+	// The partition is unavailable in all the regions, the preferred one is used.
+	return locationIndex
+}
+
+// recordPartitionOutcome records the outcome of a request in the partition circuit breaker.
+func (p *clientRetryPolicy) recordPartitionOutcome(diagnostics *Diagnostics, o pipelineRequestOptions, endpoint url.URL, region string, response *http.Response, err error) {
+	breaker := p.gem.partitionCircuitBreaker
+	partition := partitionOf(o)
+	if breaker == nil || partition == "" || o.resourceType != resourceTypeDocument {
+		return
+	}
+
+	key := partitionRegionKey{containerLink: containerLinkOf(o.resourceAddress), partition: partition, endpoint: endpoint.Host}
+	if !isPartitionFailure(response, err) {
+		if err == nil {
+			breaker.recordSuccess(key)
+		}
+		return
+	}
+	if breaker.recordFailure(key) {
+		diagnostics.addEvent(DiagnosticsEvent{
+			Kind:                DiagnosticsEventPartitionUnavailable,
+			StartTime:           time.Now(),
+			Region:              region,
+			Endpoint:            endpoint.String(),
+			PartitionKeyRangeID: o.partitionKeyRangeID,
+			Reason:              strconv.Itoa(breaker.failureThreshold) + " consecutive failures",
+		})
+	}
+}
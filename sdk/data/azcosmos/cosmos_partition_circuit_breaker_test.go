@@ -0,0 +1,159 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionCircuitBreaker(t *testing.T) {
+	breaker := newPartitionCircuitBreaker(&PartitionCircuitBreakerOptions{FailureThreshold: 2, UnavailableDuration: 50 * time.Millisecond})
+	key := partitionRegionKey{containerLink: "dbs/db/colls/c", partition: "pkrange:0", endpoint: "eastus"}
+
+	require.False(t, breaker.recordFailure(key))
+	breaker.recordSuccess(key)
+	require.False(t, breaker.recordFailure(key))
+	require.True(t, breaker.recordFailure(key))
+	require.True(t, breaker.isUnavailable(key))
+	require.False(t, breaker.isUnavailable(partitionRegionKey{containerLink: "dbs/db/colls/c", partition: "pkrange:1", endpoint: "eastus"}))
+	require.False(t, breaker.isUnavailable(partitionRegionKey{containerLink: "dbs/db/colls/c", partition: "pkrange:0", endpoint: "westus"}))
+
+	// Failures while the partition is unavailable don't extend its unavailability.
+	require.False(t, breaker.recordFailure(key))
+
+	time.Sleep(60 * time.Millisecond)
+	require.False(t, breaker.isUnavailable(key))
+	// A single failure marks the partition unavailable again.
+	require.True(t, breaker.recordFailure(key))
+	time.Sleep(60 * time.Millisecond)
+	breaker.recordSuccess(key)
+	require.False(t, breaker.recordFailure(key))
+}
+
+func TestPartitionCircuitBreakerDefaults(t *testing.T) {
+	require.Nil(t, newPartitionCircuitBreaker(nil))
+
+	breaker := newPartitionCircuitBreaker(&PartitionCircuitBreakerOptions{})
+	require.Equal(t, defaultPartitionFailureThreshold, breaker.failureThreshold)
+	require.Equal(t, defaultPartitionUnavailableDuration, breaker.unavailableDuration)
+
+	// A disabled circuit breaker never marks partitions unavailable.
+	var disabled *partitionCircuitBreaker
+	require.False(t, disabled.recordFailure(partitionRegionKey{}))
+	require.False(t, disabled.isUnavailable(partitionRegionKey{}))
+}
+
+func TestIsPartitionFailure(t *testing.T) {
+	response := func(status int, subStatus string) *http.Response {
+		r := &http.Response{StatusCode: status, Header: http.Header{}}
+		if subStatus != "" {
+			r.Header.Set(cosmosHeaderSubstatus, subStatus)
+		}
+		return r
+	}
+
+	require.True(t, isPartitionFailure(response(408, ""), nil))
+	require.True(t, isPartitionFailure(response(410, ""), nil))
+	require.True(t, isPartitionFailure(response(500, ""), nil))
+	require.True(t, isPartitionFailure(response(503, ""), nil))
+	require.True(t, isPartitionFailure(response(404, subStatusReadSessionNotAvailable), nil))
+	require.True(t, isPartitionFailure(nil, context.DeadlineExceeded))
+	require.False(t, isPartitionFailure(nil, context.Canceled))
+	require.False(t, isPartitionFailure(response(404, ""), nil))
+	require.False(t, isPartitionFailure(response(429, ""), nil))
+	require.False(t, isPartitionFailure(response(200, ""), nil))
+}
+
+func TestPartitionCircuitBreakerRoutesToNextRegion(t *testing.T) {
+	primary, closePrimary := mock.NewTLSServer()
+	defer closePrimary()
+	secondary, closeSecondary := mock.NewTLSServer()
+	defer closeSecondary()
+
+	primary.AppendResponse(mock.WithStatusCode(http.StatusRequestTimeout))
+	primary.AppendResponse(mock.WithStatusCode(http.StatusRequestTimeout))
+	primary.AppendResponse(mock.WithStatusCode(200), mock.WithBody([]byte(`{"id":"primary"}`)))
+	secondary.SetResponse(mock.WithStatusCode(200), mock.WithBody([]byte(`{"id":"secondary"}`)))
+
+	container, client := newMultiRegionTestContainer(t, primary, secondary)
+	client.gem.partitionCircuitBreaker = newPartitionCircuitBreaker(&PartitionCircuitBreakerOptions{FailureThreshold: 2})
+
+	_, err := container.ReadItem(context.Background(), NewPartitionKeyString("1"), "doc1", nil)
+	require.Error(t, err)
+	diagnostics := DiagnosticsFromError(err)
+	require.NotNil(t, diagnostics)
+	require.Empty(t, diagnosticsEventsOfKind(diagnostics, DiagnosticsEventPartitionUnavailable))
+
+	_, err = container.ReadItem(context.Background(), NewPartitionKeyString("1"), "doc1", nil)
+	require.Error(t, err)
+	unavailable := diagnosticsEventsOfKind(DiagnosticsFromError(err), DiagnosticsEventPartitionUnavailable)
+	require.Len(t, unavailable, 1)
+	require.Equal(t, "East US", unavailable[0].Region)
+
+	// The partition is routed to the next region.
+	resp, err := container.ReadItem(context.Background(), NewPartitionKeyString("1"), "doc1", nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":"secondary"}`, string(resp.Value))
+	failover := diagnosticsEventsOfKind(resp.Diagnostics, DiagnosticsEventPartitionFailover)
+	require.Len(t, failover, 1)
+	require.Equal(t, "West US", failover[0].Region)
+	require.Equal(t, "PartitionUnavailableIn East US", failover[0].Reason)
+
+	// Other partitions keep using the first region.
+	resp, err = container.ReadItem(context.Background(), NewPartitionKeyString("2"), "doc2", nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":"primary"}`, string(resp.Value))
+	require.Empty(t, diagnosticsEventsOfKind(resp.Diagnostics, DiagnosticsEventPartitionFailover))
+}
+
+func TestPartitionCircuitBreakerRouteDuringAccountRefresh(t *testing.T) {
+	srv, closeFunc := mock.NewTLSServer()
+	defer closeFunc()
+	properties, err := json.Marshal(accountProperties{
+		ReadRegions:  []accountRegion{{Name: "East US", Endpoint: srv.URL()}, {Name: "West US", Endpoint: "https://westus.documents.azure.com:443/"}},
+		WriteRegions: []accountRegion{{Name: "East US", Endpoint: srv.URL()}},
+	})
+	require.NoError(t, err)
+	srv.SetResponse(mock.WithStatusCode(200), mock.WithBody(properties))
+
+	pl := azruntime.NewPipeline("azcosmostest", "v1.0.0", azruntime.PipelineOptions{}, &policy.ClientOptions{Transport: srv})
+	gem, err := newGlobalEndpointManager(srv.URL(), pl, []string{"East US", "West US"}, time.Minute, true)
+	require.NoError(t, err)
+	require.NoError(t, gem.Update(context.Background(), true))
+	gem.partitionCircuitBreaker = newPartitionCircuitBreaker(&PartitionCircuitBreakerOptions{FailureThreshold: 1})
+	primary, err := url.Parse(srv.URL())
+	require.NoError(t, err)
+	o := pipelineRequestOptions{resourceType: resourceTypeDocument, resourceAddress: "dbs/db/colls/c/docs/doc1", partitionKeyRangeID: "0"}
+	first := gem.ResolveServiceEndpoint(0, resourceTypeDocument, false, false)
+	gem.partitionCircuitBreaker.recordFailure(partitionRegionKey{containerLink: "dbs/db/colls/c", partition: partitionOf(o), endpoint: first.Host})
+
+	// Routing reads the regions of the account while they're refreshed, and while regions are marked unavailable.
+	p := &clientRetryPolicy{gem: gem}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				require.Equal(t, 1, p.partitionBreakerRoute(nil, o, 0, false))
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		require.NoError(t, gem.Update(context.Background(), true))
+		require.NoError(t, gem.MarkEndpointUnavailableForWrite(*primary))
+	}
+	wg.Wait()
+}
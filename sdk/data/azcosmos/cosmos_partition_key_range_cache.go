@@ -0,0 +1,142 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// collectionRoutingMap maps the effective partition keys of a container to its partition key ranges.
+type collectionRoutingMap struct {
+	partitionKeyDefinition PartitionKeyDefinition
+	// ranges are sorted by their minimum effective partition key and cover the whole key space.
+	ranges []partitionKeyRange
+}
+
+func newCollectionRoutingMap(definition PartitionKeyDefinition, ranges []partitionKeyRange) (*collectionRoutingMap, error) {
+	if len(ranges) == 0 {
+		return nil, errors.New("the container has no partition key ranges")
+	}
+	sorted := append([]partitionKeyRange{}, ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MinInclusive < sorted[j].MinInclusive })
+	return &collectionRoutingMap{partitionKeyDefinition: definition, ranges: sorted}, nil
+}
+
+// rangeByEffectivePartitionKey returns the partition key range containing the effective partition key.
+func (m *collectionRoutingMap) rangeByEffectivePartitionKey(epk string) partitionKeyRange {
+	i := sort.Search(len(m.ranges), func(i int) bool { return m.ranges[i].MinInclusive > epk })
+	if i == 0 {
+		return m.ranges[0]
+	}
+	return m.ranges[i-1]
+}
+
+// rangeByPartitionKey returns the partition key range storing the items with the partition key value.
+func (m *collectionRoutingMap) rangeByPartitionKey(pk PartitionKey) (partitionKeyRange, error) {
+	epk, err := pk.effectivePartitionKey(m.partitionKeyDefinition)
+	if err != nil {
+		return partitionKeyRange{}, err
+	}
+	return m.rangeByEffectivePartitionKey(epk), nil
+}
+
+// partitionKeyRangeCache caches the routing maps of the containers of a client by container link.
+type partitionKeyRangeCache struct {
+	mu          sync.Mutex
+	routingMaps map[string]*collectionRoutingMap
+}
+
+// routingMapCache returns the partition key range cache of the client.
+func (c *Client) routingMapCache() *partitionKeyRangeCache {
+	c.pkRangeCacheOnce.Do(func() {
+		c.pkRangeCache = &partitionKeyRangeCache{routingMaps: map[string]*collectionRoutingMap{}}
+	})
+	return c.pkRangeCache
+}
+
+// cachedRoutingMap returns the cached routing map of the container link, or nil when it wasn't read yet.
+func (c *Client) cachedRoutingMap(containerLink string) *collectionRoutingMap {
+	cache := c.routingMapCache()
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.routingMaps[containerLink]
+}
+
+// resolvePartitionKeyRange returns the partition key range a request on items targets, with the ranges it was split from
+// or merged from, when the request sets the partition key range header, or when it sets a partition key and the routing map
+// of the container is cached. Otherwise, it returns an empty range id.
+func (c *Client) resolvePartitionKeyRange(req *policy.Request, operationContext pipelineRequestOptions) (string, []string) {
+	rangeID := req.Raw().Header.Get(cosmosHeaderPartitionKeyRangeId)
+	containerLink := containerLinkOf(operationContext.resourceAddress)
+	if operationContext.resourceType != resourceTypeDocument || containerLink == "" {
+		return rangeID, nil
+	}
+
+	routingMap := c.cachedRoutingMap(containerLink)
+	if routingMap == nil {
+		return rangeID, nil
+	}
+
+	if rangeID != "" {
+		for _, r := range routingMap.ranges {
+			if r.ID == rangeID {
+				return rangeID, r.Parents
+			}
+		}
+		return rangeID, nil
+	}
+
+	if pk := operationContext.headerOptionsOverride.partitionKeyOrNil(); pk != nil {
+		if r, err := routingMap.rangeByPartitionKey(*pk); err == nil {
+			return r.ID, r.Parents
+		}
+	}
+	return "", nil
+}
+
+// getRoutingMap returns the cached routing map of the container, reading it from the service on first use.
+// When previous is set, the ranges it contains are known to be outdated, for example after a partition split, and
+// the routing map is read again unless another caller already refreshed it.
+func (c *ContainerClient) getRoutingMap(ctx context.Context, previous *collectionRoutingMap) (*collectionRoutingMap, error) {
+	cache := c.database.client.routingMapCache()
+	cache.mu.Lock()
+	cached := cache.routingMaps[c.link]
+	cache.mu.Unlock()
+	if cached != nil && cached != previous {
+		return cached, nil
+	}
+
+	var definition PartitionKeyDefinition
+	if cached != nil {
+		definition = cached.partitionKeyDefinition
+	} else {
+		containerResponse, err := c.Read(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		definition = containerResponse.ContainerProperties.PartitionKeyDefinition
+	}
+	pkrResp, err := c.getPartitionKeyRanges(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	routingMap, err := newCollectionRoutingMap(definition, pkrResp.PartitionKeyRanges)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if current := cache.routingMaps[c.link]; current != nil && current != previous && current != cached {
+		// Another caller refreshed the routing map concurrently.
+		return current, nil
+	}
+	cache.routingMaps[c.link] = routingMap
+	return routingMap, nil
+}
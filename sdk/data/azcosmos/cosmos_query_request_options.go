@@ -19,6 +19,7 @@ type QueryOptions struct {
 	// If you wanted these nodes to participate in the same session (to be able read your own writes consistently across web tiers),
 	// you would have to send the SessionToken from the response of the write action on one node to the client tier, using a cookie or some other mechanism, and have that token flow back to the web tier for subsequent reads.
 	// If you are using a round-robin load balancer which does not maintain session affinity between requests, such as the Azure Load Balancer,the read could potentially land on a different node to the write request, where the session was created.
+	// When set, it replaces the session token tracked by the client. Client.ExportSessionState and Client.ImportSessionState share the session tokens of all the containers instead.
 	SessionToken *string
 	// ConsistencyLevel overrides the account defined consistency level for this operation.
 	// Consistency can only be relaxed.
@@ -47,8 +48,15 @@ type QueryOptions struct {
 	// The default value, if this is not set, is true.
 	EnableCrossPartitionQuery *bool
 	// QueryEngine can be set to enable the use of an external query engine for processing cross-partition queries.
+	// queryengine.NewNativeQueryEngine returns an engine implemented in Go.
 	// This is a preview feature, which is NOT SUPPORTED in production, and is subject to breaking changes.
 	QueryEngine queryengine.QueryEngine
+	// PriorityLevel of the operation for priority-based execution.
+	// When the account has priority-based execution enabled, low priority operations are throttled before high priority operations.
+	PriorityLevel *PriorityLevel
+	// ThroughputControlGroup is the name of the throughput control group whose budget the operation consumes.
+	// The group must be enabled with ContainerClient.EnableThroughputControlGroup. When empty, the default group of the container is used, if any.
+	ThroughputControlGroup string
 }
 
 func (options *QueryOptions) toHeaders() *map[string]string {
@@ -62,6 +70,10 @@ func (options *QueryOptions) toHeaders() *map[string]string {
 		headers[cosmosHeaderSessionToken] = *options.SessionToken
 	}
 
+	if options.PriorityLevel != nil {
+		headers[cosmosHeaderPriorityLevel] = string(*options.PriorityLevel)
+	}
+
 	if options.ResponseContinuationTokenLimitInKB > 0 {
 		headers[cosmosHeaderResponseContinuationTokenLimitInKb] = fmt.Sprint(options.ResponseContinuationTokenLimitInKB)
 	}
@@ -103,3 +115,10 @@ func (options *QueryOptions) toHeaders() *map[string]string {
 
 	return &headers
 }
+
+func (options *QueryOptions) throughputControlGroupName() string {
+	if options == nil {
+		return ""
+	}
+	return options.ThroughputControlGroup
+}
@@ -21,6 +21,8 @@ type Response struct {
 	ActivityID string
 	// ETag contains the value from the ETag header.
 	ETag azcore.ETag
+	// Diagnostics contains the timeline of the requests sent to the service for the operation, including retries.
+	Diagnostics *Diagnostics
 }
 
 func newResponse(resp *http.Response) Response {
@@ -29,11 +31,17 @@ func newResponse(resp *http.Response) Response {
 	response.RequestCharge = response.readRequestCharge()
 	response.ActivityID = resp.Header.Get(cosmosHeaderActivityId)
 	response.ETag = azcore.ETag(resp.Header.Get(cosmosHeaderEtag))
+	response.Diagnostics = diagnosticsFromResponse(resp)
 	return response
 }
 
 func (c *Response) readRequestCharge() float32 {
-	requestChargeString := c.RawResponse.Header.Get(cosmosHeaderRequestCharge)
+	return requestChargeOf(c.RawResponse)
+}
+
+// requestChargeOf returns the value of the request charge header of a response.
+func requestChargeOf(resp *http.Response) float32 {
+	requestChargeString := resp.Header.Get(cosmosHeaderRequestCharge)
 	if requestChargeString == "" {
 		return 0
 	}
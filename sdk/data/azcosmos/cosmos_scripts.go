@@ -0,0 +1,484 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// CreateStoredProcedure creates a stored procedure in the Cosmos container.
+// ctx - The context for the request.
+// properties - The properties of the stored procedure.
+// o - Options for the operation.
+func (c *ContainerClient) CreateStoredProcedure(
+	ctx context.Context,
+	properties StoredProcedureProperties,
+	o *StoredProcedureOptions) (StoredProcedureResponse, error) {
+	if o == nil {
+		o = &StoredProcedureOptions{}
+	}
+
+	azResponse, err := c.sendScriptRequest(ctx, operationTypeCreate, resourceTypeStoredProcedure, pathSegmentStoredProcedure, properties.ID, properties, o)
+	if err != nil {
+		return StoredProcedureResponse{}, err
+	}
+
+	return newStoredProcedureResponse(azResponse)
+}
+
+// ReadStoredProcedure reads a stored procedure of the Cosmos container.
+// ctx - The context for the request.
+// storedProcedureID - The id of the stored procedure.
+// o - Options for the operation.
+func (c *ContainerClient) ReadStoredProcedure(
+	ctx context.Context,
+	storedProcedureID string,
+	o *StoredProcedureOptions) (StoredProcedureResponse, error) {
+	if o == nil {
+		o = &StoredProcedureOptions{}
+	}
+
+	azResponse, err := c.sendScriptRequest(ctx, operationTypeRead, resourceTypeStoredProcedure, pathSegmentStoredProcedure, storedProcedureID, nil, o)
+	if err != nil {
+		return StoredProcedureResponse{}, err
+	}
+
+	return newStoredProcedureResponse(azResponse)
+}
+
+// ReplaceStoredProcedure replaces the stored procedure with the id of the properties.
+// ctx - The context for the request.
+// properties - The new properties of the stored procedure.
+// o - Options for the operation.
+func (c *ContainerClient) ReplaceStoredProcedure(
+	ctx context.Context,
+	properties StoredProcedureProperties,
+	o *StoredProcedureOptions) (StoredProcedureResponse, error) {
+	if o == nil {
+		o = &StoredProcedureOptions{}
+	}
+
+	azResponse, err := c.sendScriptRequest(ctx, operationTypeReplace, resourceTypeStoredProcedure, pathSegmentStoredProcedure, properties.ID, properties, o)
+	if err != nil {
+		return StoredProcedureResponse{}, err
+	}
+
+	return newStoredProcedureResponse(azResponse)
+}
+
+// DeleteStoredProcedure deletes a stored procedure of the Cosmos container.
+// ctx - The context for the request.
+// storedProcedureID - The id of the stored procedure.
+// o - Options for the operation.
+func (c *ContainerClient) DeleteStoredProcedure(
+	ctx context.Context,
+	storedProcedureID string,
+	o *StoredProcedureOptions) (StoredProcedureResponse, error) {
+	if o == nil {
+		o = &StoredProcedureOptions{}
+	}
+
+	azResponse, err := c.sendScriptRequest(ctx, operationTypeDelete, resourceTypeStoredProcedure, pathSegmentStoredProcedure, storedProcedureID, nil, o)
+	if err != nil {
+		return StoredProcedureResponse{}, err
+	}
+
+	return newStoredProcedureResponse(azResponse)
+}
+
+// NewListStoredProceduresPager lists the stored procedures of the Cosmos container.
+// o - Options for the operation.
+func (c *ContainerClient) NewListStoredProceduresPager(o *QueryScriptsOptions) *runtime.Pager[QueryStoredProceduresResponse] {
+	return newScriptsPager(c, resourceTypeStoredProcedure, nil, o, newStoredProceduresQueryResponse,
+		func(page QueryStoredProceduresResponse) *string { return page.ContinuationToken })
+}
+
+// NewQueryStoredProceduresPager executes a query for stored procedures of the Cosmos container.
+// query - The SQL query to execute.
+// o - Options for the operation.
+func (c *ContainerClient) NewQueryStoredProceduresPager(query string, o *QueryScriptsOptions) *runtime.Pager[QueryStoredProceduresResponse] {
+	return newScriptsPager(c, resourceTypeStoredProcedure, &query, o, newStoredProceduresQueryResponse,
+		func(page QueryStoredProceduresResponse) *string { return page.ContinuationToken })
+}
+
+// ExecuteStoredProcedure executes a stored procedure on the items of a logical partition.
+// The parameters are marshalled to JSON and passed as the arguments of the stored procedure function.
+// The value set with getContext().getResponse().setBody() is returned in ExecuteStoredProcedureResponse.Value.
+// ctx - The context for the request.
+// storedProcedureID - The id of the stored procedure.
+// partitionKey - The partition key of the items the stored procedure operates on.
+// parameters - The arguments of the stored procedure, or nil.
+// o - Options for the operation.
+func (c *ContainerClient) ExecuteStoredProcedure(
+	ctx context.Context,
+	storedProcedureID string,
+	partitionKey PartitionKey,
+	parameters []any,
+	o *ExecuteStoredProcedureOptions) (ExecuteStoredProcedureResponse, error) {
+	if storedProcedureID == "" {
+		return ExecuteStoredProcedureResponse{}, errors.New("storedProcedureID is required")
+	}
+	var err error
+	spanName, err := c.getSpanForContainer(operationTypeExecuteJavaScript, resourceTypeStoredProcedure, c.id)
+	if err != nil {
+		return ExecuteStoredProcedureResponse{}, err
+	}
+	ctx, endSpan := runtime.StartSpan(ctx, spanName.name, c.database.client.internal.Tracer(), &spanName.options)
+	defer func() { endSpan(err) }()
+	h := headerOptionsOverride{
+		partitionKey: &partitionKey,
+	}
+
+	if o == nil {
+		o = &ExecuteStoredProcedureOptions{}
+	}
+
+	if parameters == nil {
+		parameters = []any{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:          resourceTypeStoredProcedure,
+		resourceAddress:       createLink(c.link, pathSegmentStoredProcedure, storedProcedureID),
+		isWriteOperation:      true,
+		headerOptionsOverride: &h}
+
+	path, err := generatePathForNameBased(resourceTypeStoredProcedure, operationContext.resourceAddress, false)
+	if err != nil {
+		return ExecuteStoredProcedureResponse{}, err
+	}
+
+	azResponse, err := c.database.client.sendPostRequest(
+		path,
+		ctx,
+		parameters,
+		operationContext,
+		o,
+		nil)
+	if err != nil {
+		return ExecuteStoredProcedureResponse{}, err
+	}
+
+	response, err := newExecuteStoredProcedureResponse(azResponse)
+	return response, err
+}
+
+// CreateTrigger creates a trigger in the Cosmos container.
+// Triggers are attached to item operations with ItemOptions.PreTriggers and ItemOptions.PostTriggers.
+// ctx - The context for the request.
+// properties - The properties of the trigger.
+// o - Options for the operation.
+func (c *ContainerClient) CreateTrigger(
+	ctx context.Context,
+	properties TriggerProperties,
+	o *TriggerOptions) (TriggerResponse, error) {
+	if o == nil {
+		o = &TriggerOptions{}
+	}
+
+	azResponse, err := c.sendScriptRequest(ctx, operationTypeCreate, resourceTypeTrigger, pathSegmentTrigger, properties.ID, properties, o)
+	if err != nil {
+		return TriggerResponse{}, err
+	}
+
+	return newTriggerResponse(azResponse)
+}
+
+// ReadTrigger reads a trigger of the Cosmos container.
+// ctx - The context for the request.
+// triggerID - The id of the trigger.
+// o - Options for the operation.
+func (c *ContainerClient) ReadTrigger(
+	ctx context.Context,
+	triggerID string,
+	o *TriggerOptions) (TriggerResponse, error) {
+	if o == nil {
+		o = &TriggerOptions{}
+	}
+
+	azResponse, err := c.sendScriptRequest(ctx, operationTypeRead, resourceTypeTrigger, pathSegmentTrigger, triggerID, nil, o)
+	if err != nil {
+		return TriggerResponse{}, err
+	}
+
+	return newTriggerResponse(azResponse)
+}
+
+// ReplaceTrigger replaces the trigger with the id of the properties.
+// ctx - The context for the request.
+// properties - The new properties of the trigger.
+// o - Options for the operation.
+func (c *ContainerClient) ReplaceTrigger(
+	ctx context.Context,
+	properties TriggerProperties,
+	o *TriggerOptions) (TriggerResponse, error) {
+	if o == nil {
+		o = &TriggerOptions{}
+	}
+
+	azResponse, err := c.sendScriptRequest(ctx, operationTypeReplace, resourceTypeTrigger, pathSegmentTrigger, properties.ID, properties, o)
+	if err != nil {
+		return TriggerResponse{}, err
+	}
+
+	return newTriggerResponse(azResponse)
+}
+
+// DeleteTrigger deletes a trigger of the Cosmos container.
+// ctx - The context for the request.
+// triggerID - The id of the trigger.
+// o - Options for the operation.
+func (c *ContainerClient) DeleteTrigger(
+	ctx context.Context,
+	triggerID string,
+	o *TriggerOptions) (TriggerResponse, error) {
+	if o == nil {
+		o = &TriggerOptions{}
+	}
+
+	azResponse, err := c.sendScriptRequest(ctx, operationTypeDelete, resourceTypeTrigger, pathSegmentTrigger, triggerID, nil, o)
+	if err != nil {
+		return TriggerResponse{}, err
+	}
+
+	return newTriggerResponse(azResponse)
+}
+
+// NewListTriggersPager lists the triggers of the Cosmos container.
+// o - Options for the operation.
+func (c *ContainerClient) NewListTriggersPager(o *QueryScriptsOptions) *runtime.Pager[QueryTriggersResponse] {
+	return newScriptsPager(c, resourceTypeTrigger, nil, o, newTriggersQueryResponse,
+		func(page QueryTriggersResponse) *string { return page.ContinuationToken })
+}
+
+// NewQueryTriggersPager executes a query for triggers of the Cosmos container.
+// query - The SQL query to execute.
+// o - Options for the operation.
+func (c *ContainerClient) NewQueryTriggersPager(query string, o *QueryScriptsOptions) *runtime.Pager[QueryTriggersResponse] {
+	return newScriptsPager(c, resourceTypeTrigger, &query, o, newTriggersQueryResponse,
+		func(page QueryTriggersResponse) *string { return page.ContinuationToken })
+}
+
+// CreateUserDefinedFunction creates a user-defined function in the Cosmos container.
+// ctx - The context for the request.
+// properties - The properties of the user-defined function.
+// o - Options for the operation.
+func (c *ContainerClient) CreateUserDefinedFunction(
+	ctx context.Context,
+	properties UserDefinedFunctionProperties,
+	o *UserDefinedFunctionOptions) (UserDefinedFunctionResponse, error) {
+	if o == nil {
+		o = &UserDefinedFunctionOptions{}
+	}
+
+	azResponse, err := c.sendScriptRequest(ctx, operationTypeCreate, resourceTypeUserDefinedFunction, pathSegmentUserDefinedFunction, properties.ID, properties, o)
+	if err != nil {
+		return UserDefinedFunctionResponse{}, err
+	}
+
+	return newUserDefinedFunctionResponse(azResponse)
+}
+
+// ReadUserDefinedFunction reads a user-defined function of the Cosmos container.
+// ctx - The context for the request.
+// userDefinedFunctionID - The id of the user-defined function.
+// o - Options for the operation.
+func (c *ContainerClient) ReadUserDefinedFunction(
+	ctx context.Context,
+	userDefinedFunctionID string,
+	o *UserDefinedFunctionOptions) (UserDefinedFunctionResponse, error) {
+	if o == nil {
+		o = &UserDefinedFunctionOptions{}
+	}
+
+	azResponse, err := c.sendScriptRequest(ctx, operationTypeRead, resourceTypeUserDefinedFunction, pathSegmentUserDefinedFunction, userDefinedFunctionID, nil, o)
+	if err != nil {
+		return UserDefinedFunctionResponse{}, err
+	}
+
+	return newUserDefinedFunctionResponse(azResponse)
+}
+
+// ReplaceUserDefinedFunction replaces the user-defined function with the id of the properties.
+// ctx - The context for the request.
+// properties - The new properties of the user-defined function.
+// o - Options for the operation.
+func (c *ContainerClient) ReplaceUserDefinedFunction(
+	ctx context.Context,
+	properties UserDefinedFunctionProperties,
+	o *UserDefinedFunctionOptions) (UserDefinedFunctionResponse, error) {
+	if o == nil {
+		o = &UserDefinedFunctionOptions{}
+	}
+
+	azResponse, err := c.sendScriptRequest(ctx, operationTypeReplace, resourceTypeUserDefinedFunction, pathSegmentUserDefinedFunction, properties.ID, properties, o)
+	if err != nil {
+		return UserDefinedFunctionResponse{}, err
+	}
+
+	return newUserDefinedFunctionResponse(azResponse)
+}
+
+// DeleteUserDefinedFunction deletes a user-defined function of the Cosmos container.
+// ctx - The context for the request.
+// userDefinedFunctionID - The id of the user-defined function.
+// o - Options for the operation.
+func (c *ContainerClient) DeleteUserDefinedFunction(
+	ctx context.Context,
+	userDefinedFunctionID string,
+	o *UserDefinedFunctionOptions) (UserDefinedFunctionResponse, error) {
+	if o == nil {
+		o = &UserDefinedFunctionOptions{}
+	}
+
+	azResponse, err := c.sendScriptRequest(ctx, operationTypeDelete, resourceTypeUserDefinedFunction, pathSegmentUserDefinedFunction, userDefinedFunctionID, nil, o)
+	if err != nil {
+		return UserDefinedFunctionResponse{}, err
+	}
+
+	return newUserDefinedFunctionResponse(azResponse)
+}
+
+// NewListUserDefinedFunctionsPager lists the user-defined functions of the Cosmos container.
+// o - Options for the operation.
+func (c *ContainerClient) NewListUserDefinedFunctionsPager(o *QueryScriptsOptions) *runtime.Pager[QueryUserDefinedFunctionsResponse] {
+	return newScriptsPager(c, resourceTypeUserDefinedFunction, nil, o, newUserDefinedFunctionsQueryResponse,
+		func(page QueryUserDefinedFunctionsResponse) *string { return page.ContinuationToken })
+}
+
+// NewQueryUserDefinedFunctionsPager executes a query for user-defined functions of the Cosmos container.
+// query - The SQL query to execute.
+// o - Options for the operation.
+func (c *ContainerClient) NewQueryUserDefinedFunctionsPager(query string, o *QueryScriptsOptions) *runtime.Pager[QueryUserDefinedFunctionsResponse] {
+	return newScriptsPager(c, resourceTypeUserDefinedFunction, &query, o, newUserDefinedFunctionsQueryResponse,
+		func(page QueryUserDefinedFunctionsResponse) *string { return page.ContinuationToken })
+}
+
+// sendScriptRequest sends a create, read, replace or delete request for a stored procedure, trigger or
+// user-defined function of the container.
+func (c *ContainerClient) sendScriptRequest(
+	ctx context.Context,
+	operationType operationType,
+	resourceType resourceType,
+	pathSegment string,
+	id string,
+	properties any,
+	o cosmosRequestOptions) (*http.Response, error) {
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	var err error
+	spanName, err := c.getSpanForContainer(operationType, resourceType, c.id)
+	if err != nil {
+		return nil, err
+	}
+	ctx, endSpan := runtime.StartSpan(ctx, spanName.name, c.database.client.internal.Tracer(), &spanName.options)
+	defer func() { endSpan(err) }()
+
+	isFeed := operationType == operationTypeCreate
+	operationContext := pipelineRequestOptions{
+		resourceType:     resourceType,
+		resourceAddress:  createLink(c.link, pathSegment, id),
+		isWriteOperation: operationType != operationTypeRead,
+	}
+	if isFeed {
+		operationContext.resourceAddress = c.link
+	}
+
+	path, err := generatePathForNameBased(resourceType, operationContext.resourceAddress, isFeed)
+	if err != nil {
+		return nil, err
+	}
+
+	var azResponse *http.Response
+	switch operationType {
+	case operationTypeCreate:
+		azResponse, err = c.database.client.sendPostRequest(path, ctx, properties, operationContext, o, nil)
+	case operationTypeReplace:
+		azResponse, err = c.database.client.sendPutRequest(path, ctx, properties, operationContext, o, nil)
+	case operationTypeDelete:
+		azResponse, err = c.database.client.sendDeleteRequest(path, ctx, operationContext, o, nil)
+	default:
+		azResponse, err = c.database.client.sendGetRequest(path, ctx, operationContext, o, nil)
+	}
+	return azResponse, err
+}
+
+// newScriptsPager returns a pager reading the feed of the stored procedures, triggers or user-defined functions of
+// the container, or the results of a query on it when query is set.
+func newScriptsPager[T any](
+	c *ContainerClient,
+	resourceType resourceType,
+	query *string,
+	o *QueryScriptsOptions,
+	newPage func(*http.Response) (T, error),
+	continuationToken func(T) *string) *runtime.Pager[T] {
+	queryOptions := &QueryScriptsOptions{}
+	if o != nil {
+		originalOptions := *o
+		queryOptions = &originalOptions
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceType,
+		resourceAddress: c.link,
+	}
+
+	operationType := operationTypeReadFeed
+	if query != nil {
+		operationType = operationTypeQuery
+	}
+
+	path, _ := generatePathForNameBased(resourceType, operationContext.resourceAddress, true)
+
+	return runtime.NewPager(runtime.PagingHandler[T]{
+		More: func(page T) bool {
+			return continuationToken(page) != nil
+		},
+		Fetcher: func(ctx context.Context, page *T) (T, error) {
+			var err error
+			var zero T
+			spanName, err := c.getSpanForContainer(operationType, resourceType, c.id)
+			if err != nil {
+				return zero, err
+			}
+			ctx, endSpan := runtime.StartSpan(ctx, spanName.name, c.database.client.internal.Tracer(), &spanName.options)
+			defer func() { endSpan(err) }()
+			if page != nil {
+				if token := continuationToken(*page); token != nil {
+					// Use the previous page continuation if available
+					queryOptions.ContinuationToken = token
+				}
+			}
+
+			var azResponse *http.Response
+			if query != nil {
+				azResponse, err = c.database.client.sendQueryRequest(
+					path,
+					ctx,
+					*query,
+					queryOptions.QueryParameters,
+					operationContext,
+					queryOptions,
+					nil)
+			} else {
+				azResponse, err = c.database.client.sendGetRequest(
+					path,
+					ctx,
+					operationContext,
+					queryOptions,
+					nil)
+			}
+			if err != nil {
+				return zero, err
+			}
+
+			return newPage(azResponse)
+		},
+	})
+}
@@ -0,0 +1,139 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// StoredProcedureProperties represents the properties of a stored procedure.
+type StoredProcedureProperties struct {
+	// ID contains the unique id of the stored procedure.
+	ID string
+	// Body contains the JavaScript function of the stored procedure.
+	Body string
+	// ETag contains the entity etag of the stored procedure.
+	ETag *azcore.ETag
+	// SelfLink contains the self-link of the stored procedure.
+	SelfLink string
+	// ResourceID contains the resource id of the stored procedure.
+	ResourceID string
+	// LastModified contains the last modified time of the stored procedure.
+	LastModified time.Time
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (sp StoredProcedureProperties) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newScriptProperties(sp.ID, sp.Body, sp.ETag, sp.SelfLink, sp.ResourceID, sp.LastModified))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (sp *StoredProcedureProperties) UnmarshalJSON(b []byte) error {
+	var properties scriptProperties
+	if err := json.Unmarshal(b, &properties); err != nil {
+		return err
+	}
+	sp.ID, sp.Body, sp.ETag, sp.SelfLink, sp.ResourceID = properties.ID, properties.Body, properties.ETag, properties.SelfLink, properties.ResourceID
+	sp.LastModified = timeFromUnixTimestamp(properties.Timestamp)
+	return nil
+}
+
+// TriggerProperties represents the properties of a trigger.
+type TriggerProperties struct {
+	// ID contains the unique id of the trigger.
+	ID string
+	// Body contains the JavaScript function of the trigger.
+	Body string
+	// TriggerType specifies whether the trigger runs before or after the operation.
+	TriggerType TriggerType
+	// TriggerOperation specifies the operations the trigger can be attached to.
+	TriggerOperation TriggerOperation
+	// ETag contains the entity etag of the trigger.
+	ETag *azcore.ETag
+	// SelfLink contains the self-link of the trigger.
+	SelfLink string
+	// ResourceID contains the resource id of the trigger.
+	ResourceID string
+	// LastModified contains the last modified time of the trigger.
+	LastModified time.Time
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (tp TriggerProperties) MarshalJSON() ([]byte, error) {
+	properties := newScriptProperties(tp.ID, tp.Body, tp.ETag, tp.SelfLink, tp.ResourceID, tp.LastModified)
+	properties.TriggerType = tp.TriggerType
+	properties.TriggerOperation = tp.TriggerOperation
+	return json.Marshal(properties)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (tp *TriggerProperties) UnmarshalJSON(b []byte) error {
+	var properties scriptProperties
+	if err := json.Unmarshal(b, &properties); err != nil {
+		return err
+	}
+	tp.ID, tp.Body, tp.ETag, tp.SelfLink, tp.ResourceID = properties.ID, properties.Body, properties.ETag, properties.SelfLink, properties.ResourceID
+	tp.TriggerType = properties.TriggerType
+	tp.TriggerOperation = properties.TriggerOperation
+	tp.LastModified = timeFromUnixTimestamp(properties.Timestamp)
+	return nil
+}
+
+// UserDefinedFunctionProperties represents the properties of a user-defined function.
+type UserDefinedFunctionProperties struct {
+	// ID contains the unique id of the user-defined function, used to call it in queries as udf.<ID>.
+	ID string
+	// Body contains the JavaScript function of the user-defined function.
+	Body string
+	// ETag contains the entity etag of the user-defined function.
+	ETag *azcore.ETag
+	// SelfLink contains the self-link of the user-defined function.
+	SelfLink string
+	// ResourceID contains the resource id of the user-defined function.
+	ResourceID string
+	// LastModified contains the last modified time of the user-defined function.
+	LastModified time.Time
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (up UserDefinedFunctionProperties) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newScriptProperties(up.ID, up.Body, up.ETag, up.SelfLink, up.ResourceID, up.LastModified))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (up *UserDefinedFunctionProperties) UnmarshalJSON(b []byte) error {
+	var properties scriptProperties
+	if err := json.Unmarshal(b, &properties); err != nil {
+		return err
+	}
+	up.ID, up.Body, up.ETag, up.SelfLink, up.ResourceID = properties.ID, properties.Body, properties.ETag, properties.SelfLink, properties.ResourceID
+	up.LastModified = timeFromUnixTimestamp(properties.Timestamp)
+	return nil
+}
+
+// scriptProperties is the wire format shared by stored procedures, triggers and user-defined functions.
+type scriptProperties struct {
+	ID               string           `json:"id"`
+	Body             string           `json:"body"`
+	TriggerType      TriggerType      `json:"triggerType,omitempty"`
+	TriggerOperation TriggerOperation `json:"triggerOperation,omitempty"`
+	ETag             *azcore.ETag     `json:"_etag,omitempty"`
+	SelfLink         string           `json:"_self,omitempty"`
+	ResourceID       string           `json:"_rid,omitempty"`
+	Timestamp        int64            `json:"_ts,omitempty"`
+}
+
+func newScriptProperties(id string, body string, etag *azcore.ETag, selfLink string, resourceID string, lastModified time.Time) scriptProperties {
+	return scriptProperties{
+		ID:         id,
+		Body:       body,
+		ETag:       etag,
+		SelfLink:   selfLink,
+		ResourceID: resourceID,
+		Timestamp:  unixTimestamp(lastModified),
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+func TestTriggerPropertiesSerialization(t *testing.T) {
+	nowAsUnix := time.Unix(time.Now().Unix(), 0)
+
+	etag := azcore.ETag("someETag")
+	properties := TriggerProperties{
+		ID:               "someId",
+		Body:             "function trigger() { var s = \"quoted\"; }",
+		TriggerType:      TriggerTypePre,
+		TriggerOperation: TriggerOperationCreate,
+		ETag:             &etag,
+		SelfLink:         "someSelfLink",
+		ResourceID:       "someResourceId",
+		LastModified:     nowAsUnix,
+	}
+
+	jsonString, err := json.Marshal(properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(jsonString, &raw); err != nil {
+		t.Fatal(err, string(jsonString))
+	}
+
+	if raw["triggerType"] != "Pre" || raw["triggerOperation"] != "Create" {
+		t.Errorf("Expected triggerType Pre and triggerOperation Create, but got %s", string(jsonString))
+	}
+
+	otherProperties := &TriggerProperties{}
+	err = json.Unmarshal(jsonString, otherProperties)
+	if err != nil {
+		t.Fatal(err, string(jsonString))
+	}
+
+	if properties.ID != otherProperties.ID {
+		t.Errorf("Expected otherProperties.Id to be %s, but got %s", properties.ID, otherProperties.ID)
+	}
+
+	if properties.Body != otherProperties.Body {
+		t.Errorf("Expected otherProperties.Body to be %s, but got %s", properties.Body, otherProperties.Body)
+	}
+
+	if properties.TriggerType != otherProperties.TriggerType {
+		t.Errorf("Expected otherProperties.TriggerType to be %s, but got %s", properties.TriggerType, otherProperties.TriggerType)
+	}
+
+	if properties.TriggerOperation != otherProperties.TriggerOperation {
+		t.Errorf("Expected otherProperties.TriggerOperation to be %s, but got %s", properties.TriggerOperation, otherProperties.TriggerOperation)
+	}
+
+	if *properties.ETag != *otherProperties.ETag {
+		t.Errorf("Expected otherProperties.ETag to be %s, but got %s", *properties.ETag, *otherProperties.ETag)
+	}
+
+	if properties.SelfLink != otherProperties.SelfLink {
+		t.Errorf("Expected otherProperties.SelfLink to be %s, but got %s", properties.SelfLink, otherProperties.SelfLink)
+	}
+
+	if properties.ResourceID != otherProperties.ResourceID {
+		t.Errorf("Expected otherProperties.ResourceId to be %s, but got %s", properties.ResourceID, otherProperties.ResourceID)
+	}
+
+	if properties.LastModified != otherProperties.LastModified {
+		t.Errorf("Expected otherProperties.LastModified.Time to be %v, but got %v", properties.LastModified, otherProperties.LastModified)
+	}
+}
+
+func TestStoredProcedurePropertiesSerialization(t *testing.T) {
+	properties := StoredProcedureProperties{
+		ID:   "someId",
+		Body: "function sproc() {}",
+	}
+
+	jsonString, err := json.Marshal(properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(jsonString) != `{"id":"someId","body":"function sproc() {}"}` {
+		t.Errorf("Unexpected serialization %s", string(jsonString))
+	}
+
+	otherProperties := &StoredProcedureProperties{}
+	err = json.Unmarshal(jsonString, otherProperties)
+	if err != nil {
+		t.Fatal(err, string(jsonString))
+	}
+
+	if properties.ID != otherProperties.ID || properties.Body != otherProperties.Body {
+		t.Errorf("Expected %v, but got %v", properties, *otherProperties)
+	}
+
+	if !otherProperties.LastModified.IsZero() {
+		t.Errorf("Expected otherProperties.LastModified to be zero, but got %v", otherProperties.LastModified)
+	}
+}
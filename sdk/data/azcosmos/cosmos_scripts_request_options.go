@@ -0,0 +1,110 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"strconv"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// StoredProcedureOptions are options for the create, read, replace and delete stored procedure operations.
+type StoredProcedureOptions struct {
+	// IfMatchEtag is used to ensure optimistic concurrency control.
+	IfMatchEtag *azcore.ETag
+}
+
+func (options *StoredProcedureOptions) toHeaders() *map[string]string {
+	return ifMatchEtagHeaders(options.IfMatchEtag)
+}
+
+// TriggerOptions are options for the create, read, replace and delete trigger operations.
+type TriggerOptions struct {
+	// IfMatchEtag is used to ensure optimistic concurrency control.
+	IfMatchEtag *azcore.ETag
+}
+
+func (options *TriggerOptions) toHeaders() *map[string]string {
+	return ifMatchEtagHeaders(options.IfMatchEtag)
+}
+
+// UserDefinedFunctionOptions are options for the create, read, replace and delete user-defined function operations.
+type UserDefinedFunctionOptions struct {
+	// IfMatchEtag is used to ensure optimistic concurrency control.
+	IfMatchEtag *azcore.ETag
+}
+
+func (options *UserDefinedFunctionOptions) toHeaders() *map[string]string {
+	return ifMatchEtagHeaders(options.IfMatchEtag)
+}
+
+func ifMatchEtagHeaders(ifMatchEtag *azcore.ETag) *map[string]string {
+	if ifMatchEtag == nil {
+		return nil
+	}
+
+	headers := make(map[string]string)
+	headers[headerIfMatch] = string(*ifMatchEtag)
+	return &headers
+}
+
+// ExecuteStoredProcedureOptions are options for the ExecuteStoredProcedure operation.
+type ExecuteStoredProcedureOptions struct {
+	// EnableScriptLogging enables the console.log output of the stored procedure,
+	// which is returned in ExecuteStoredProcedureResponse.ScriptLog.
+	EnableScriptLogging bool
+	// SessionToken to be used when using Session consistency on the account.
+	SessionToken *string
+	// ConsistencyLevel overrides the account defined consistency level for this operation.
+	// Consistency can only be relaxed.
+	ConsistencyLevel *ConsistencyLevel
+}
+
+func (options *ExecuteStoredProcedureOptions) toHeaders() *map[string]string {
+	headers := make(map[string]string)
+
+	if options.EnableScriptLogging {
+		headers[cosmosHeaderScriptEnableLogging] = "true"
+	}
+
+	if options.SessionToken != nil {
+		headers[cosmosHeaderSessionToken] = *options.SessionToken
+	}
+
+	if options.ConsistencyLevel != nil {
+		headers[cosmosHeaderConsistencyLevel] = string(*options.ConsistencyLevel)
+	}
+
+	return &headers
+}
+
+// QueryScriptsOptions are options to list or query the stored procedures, triggers or user-defined functions of a container.
+type QueryScriptsOptions struct {
+	// ContinuationToken to be used to continue a previous list or query execution.
+	// Obtained from the ContinuationToken of the previous page.
+	ContinuationToken *string
+
+	// MaxItemCount is the maximum number of scripts returned in a page.
+	// The default is decided by the service.
+	MaxItemCount int32
+
+	// QueryParameters allows execution of parametrized queries.
+	// It is ignored when listing scripts.
+	// See https://docs.microsoft.com/azure/cosmos-db/sql/sql-query-parameterized-queries
+	QueryParameters []QueryParameter
+}
+
+func (options *QueryScriptsOptions) toHeaders() *map[string]string {
+	headers := make(map[string]string)
+
+	if options.ContinuationToken != nil {
+		headers[cosmosHeaderContinuationToken] = *options.ContinuationToken
+	}
+
+	if options.MaxItemCount > 0 {
+		headers[cosmosHeaderMaxItemCount] = strconv.FormatInt(int64(options.MaxItemCount), 10)
+	}
+
+	return &headers
+}
@@ -0,0 +1,204 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"net/http"
+	"net/url"
+
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// StoredProcedureResponse represents the response from a stored procedure request.
+type StoredProcedureResponse struct {
+	// StoredProcedureProperties contains the unmarshalled response body in StoredProcedureProperties format.
+	StoredProcedureProperties *StoredProcedureProperties
+	Response
+}
+
+func newStoredProcedureResponse(resp *http.Response) (StoredProcedureResponse, error) {
+	response := StoredProcedureResponse{
+		Response: newResponse(resp),
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return response, nil
+	}
+	properties := &StoredProcedureProperties{}
+	err := azruntime.UnmarshalAsJSON(resp, properties)
+	if err != nil {
+		return response, err
+	}
+	response.StoredProcedureProperties = properties
+	return response, nil
+}
+
+// TriggerResponse represents the response from a trigger request.
+type TriggerResponse struct {
+	// TriggerProperties contains the unmarshalled response body in TriggerProperties format.
+	TriggerProperties *TriggerProperties
+	Response
+}
+
+func newTriggerResponse(resp *http.Response) (TriggerResponse, error) {
+	response := TriggerResponse{
+		Response: newResponse(resp),
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return response, nil
+	}
+	properties := &TriggerProperties{}
+	err := azruntime.UnmarshalAsJSON(resp, properties)
+	if err != nil {
+		return response, err
+	}
+	response.TriggerProperties = properties
+	return response, nil
+}
+
+// UserDefinedFunctionResponse represents the response from a user-defined function request.
+type UserDefinedFunctionResponse struct {
+	// UserDefinedFunctionProperties contains the unmarshalled response body in UserDefinedFunctionProperties format.
+	UserDefinedFunctionProperties *UserDefinedFunctionProperties
+	Response
+}
+
+func newUserDefinedFunctionResponse(resp *http.Response) (UserDefinedFunctionResponse, error) {
+	response := UserDefinedFunctionResponse{
+		Response: newResponse(resp),
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return response, nil
+	}
+	properties := &UserDefinedFunctionProperties{}
+	err := azruntime.UnmarshalAsJSON(resp, properties)
+	if err != nil {
+		return response, err
+	}
+	response.UserDefinedFunctionProperties = properties
+	return response, nil
+}
+
+// ExecuteStoredProcedureResponse represents the response from the execution of a stored procedure.
+type ExecuteStoredProcedureResponse struct {
+	// Value contains the value returned by the stored procedure through getContext().getResponse().setBody().
+	Value []byte
+	// ScriptLog contains the console.log output of the stored procedure when
+	// ExecuteStoredProcedureOptions.EnableScriptLogging is true.
+	ScriptLog string
+	// SessionToken contains the value from the session token header to be used on session consistency.
+	SessionToken *string
+	Response
+}
+
+func newExecuteStoredProcedureResponse(resp *http.Response) (ExecuteStoredProcedureResponse, error) {
+	response := ExecuteStoredProcedureResponse{
+		Response: newResponse(resp),
+	}
+	sessionToken := resp.Header.Get(cosmosHeaderSessionToken)
+	if sessionToken != "" {
+		response.SessionToken = &sessionToken
+	}
+	// The log is URL encoded by the service.
+	scriptLog := resp.Header.Get(cosmosHeaderScriptLogResults)
+	if unescaped, err := url.PathUnescape(scriptLog); err == nil {
+		scriptLog = unescaped
+	}
+	response.ScriptLog = scriptLog
+	defer resp.Body.Close()
+	body, err := azruntime.Payload(resp)
+	if err != nil {
+		return response, err
+	}
+	response.Value = body
+	return response, nil
+}
+
+// QueryStoredProceduresResponse contains a page of stored procedures from a list or query operation.
+type QueryStoredProceduresResponse struct {
+	Response
+	// ContinuationToken contains the value of the x-ms-continuation header in the response.
+	// It can be used to stop a query and resume it later.
+	ContinuationToken *string
+	// List of stored procedures.
+	StoredProcedures []StoredProcedureProperties
+}
+
+func newStoredProceduresQueryResponse(resp *http.Response) (QueryStoredProceduresResponse, error) {
+	response := QueryStoredProceduresResponse{
+		Response:          newResponse(resp),
+		ContinuationToken: continuationTokenFromResponse(resp),
+	}
+
+	result := struct {
+		StoredProcedures []StoredProcedureProperties `json:"StoredProcedures,omitempty"`
+	}{}
+	if err := azruntime.UnmarshalAsJSON(resp, &result); err != nil {
+		return QueryStoredProceduresResponse{}, err
+	}
+
+	response.StoredProcedures = result.StoredProcedures
+	return response, nil
+}
+
+// QueryTriggersResponse contains a page of triggers from a list or query operation.
+type QueryTriggersResponse struct {
+	Response
+	// ContinuationToken contains the value of the x-ms-continuation header in the response.
+	// It can be used to stop a query and resume it later.
+	ContinuationToken *string
+	// List of triggers.
+	Triggers []TriggerProperties
+}
+
+func newTriggersQueryResponse(resp *http.Response) (QueryTriggersResponse, error) {
+	response := QueryTriggersResponse{
+		Response:          newResponse(resp),
+		ContinuationToken: continuationTokenFromResponse(resp),
+	}
+
+	result := struct {
+		Triggers []TriggerProperties `json:"Triggers,omitempty"`
+	}{}
+	if err := azruntime.UnmarshalAsJSON(resp, &result); err != nil {
+		return QueryTriggersResponse{}, err
+	}
+
+	response.Triggers = result.Triggers
+	return response, nil
+}
+
+// QueryUserDefinedFunctionsResponse contains a page of user-defined functions from a list or query operation.
+type QueryUserDefinedFunctionsResponse struct {
+	Response
+	// ContinuationToken contains the value of the x-ms-continuation header in the response.
+	// It can be used to stop a query and resume it later.
+	ContinuationToken *string
+	// List of user-defined functions.
+	UserDefinedFunctions []UserDefinedFunctionProperties
+}
+
+func newUserDefinedFunctionsQueryResponse(resp *http.Response) (QueryUserDefinedFunctionsResponse, error) {
+	response := QueryUserDefinedFunctionsResponse{
+		Response:          newResponse(resp),
+		ContinuationToken: continuationTokenFromResponse(resp),
+	}
+
+	result := struct {
+		UserDefinedFunctions []UserDefinedFunctionProperties `json:"UserDefinedFunctions,omitempty"`
+	}{}
+	if err := azruntime.UnmarshalAsJSON(resp, &result); err != nil {
+		return QueryUserDefinedFunctionsResponse{}, err
+	}
+
+	response.UserDefinedFunctions = result.UserDefinedFunctions
+	return response, nil
+}
+
+func continuationTokenFromResponse(resp *http.Response) *string {
+	continuationToken := resp.Header.Get(cosmosHeaderContinuationToken)
+	if continuationToken == "" {
+		return nil
+	}
+	return &continuationToken
+}
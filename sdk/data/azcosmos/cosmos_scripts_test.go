@@ -0,0 +1,343 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func newScriptsTestContainer(t *testing.T, srv *mock.Server, verifier *pipelineVerifier) *ContainerClient {
+	return newTestContainer(t, srv, nil, azruntime.PipelineOptions{PerCall: []policy.Policy{&headerPolicies{}, verifier}}, policy.RetryOptions{})
+}
+
+func TestContainerCreateStoredProcedure(t *testing.T) {
+	properties := StoredProcedureProperties{
+		ID:   "sproc1",
+		Body: "function sproc() {}",
+	}
+	jsonString, err := json.Marshal(properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(
+		mock.WithBody(jsonString),
+		mock.WithHeader(cosmosHeaderEtag, "someEtag"),
+		mock.WithHeader(cosmosHeaderActivityId, "someActivityId"),
+		mock.WithHeader(cosmosHeaderRequestCharge, "13.42"),
+		mock.WithStatusCode(201))
+
+	verifier := pipelineVerifier{}
+	container := newScriptsTestContainer(t, srv, &verifier)
+
+	etag := azcore.ETag("matchEtag")
+	resp, err := container.CreateStoredProcedure(context.TODO(), properties, &StoredProcedureOptions{IfMatchEtag: &etag})
+	if err != nil {
+		t.Fatalf("Failed to create stored procedure: %v", err)
+	}
+
+	if resp.StoredProcedureProperties == nil || resp.StoredProcedureProperties.ID != "sproc1" || resp.StoredProcedureProperties.Body != properties.Body {
+		t.Errorf("Unexpected stored procedure properties %v", resp.StoredProcedureProperties)
+	}
+
+	if resp.RequestCharge != 13.42 {
+		t.Errorf("Expected RequestCharge to be %f, but got %f", 13.42, resp.RequestCharge)
+	}
+
+	if verifier.requests[0].method != http.MethodPost {
+		t.Errorf("Expected method to be %s, but got %s", http.MethodPost, verifier.requests[0].method)
+	}
+
+	if verifier.requests[0].url.RequestURI() != "/dbs/databaseId/colls/containerId/sprocs" {
+		t.Errorf("Expected url to be %s, but got %s", "/dbs/databaseId/colls/containerId/sprocs", verifier.requests[0].url.RequestURI())
+	}
+
+	if verifier.requests[0].body != string(jsonString) {
+		t.Errorf("Expected body to be %s, but got %s", string(jsonString), verifier.requests[0].body)
+	}
+
+	if verifier.requests[0].headers.Get(headerIfMatch) != "matchEtag" {
+		t.Errorf("Expected If-Match to be %s, but got %s", "matchEtag", verifier.requests[0].headers.Get(headerIfMatch))
+	}
+}
+
+func TestContainerScriptCRUDRequests(t *testing.T) {
+	triggerJSON := []byte(`{"id":"trigger1","body":"function t() {}","triggerType":"Post","triggerOperation":"All"}`)
+	udfJSON := []byte(`{"id":"udf1","body":"function u() {}"}`)
+
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody(triggerJSON), mock.WithStatusCode(200))
+	srv.AppendResponse(mock.WithBody(triggerJSON), mock.WithStatusCode(200))
+	srv.AppendResponse(mock.WithStatusCode(204))
+	srv.AppendResponse(mock.WithBody(udfJSON), mock.WithStatusCode(200))
+	srv.AppendResponse(mock.WithStatusCode(204))
+
+	verifier := pipelineVerifier{}
+	container := newScriptsTestContainer(t, srv, &verifier)
+
+	triggerResponse, err := container.ReadTrigger(context.TODO(), "trigger1", nil)
+	if err != nil {
+		t.Fatalf("Failed to read trigger: %v", err)
+	}
+	if triggerResponse.TriggerProperties.TriggerType != TriggerTypePost || triggerResponse.TriggerProperties.TriggerOperation != TriggerOperationAll {
+		t.Errorf("Unexpected trigger properties %v", triggerResponse.TriggerProperties)
+	}
+
+	_, err = container.ReplaceTrigger(context.TODO(), TriggerProperties{ID: "trigger1", Body: "function t() {}", TriggerType: TriggerTypePost, TriggerOperation: TriggerOperationAll}, nil)
+	if err != nil {
+		t.Fatalf("Failed to replace trigger: %v", err)
+	}
+
+	deleteResponse, err := container.DeleteTrigger(context.TODO(), "trigger1", nil)
+	if err != nil {
+		t.Fatalf("Failed to delete trigger: %v", err)
+	}
+	if deleteResponse.TriggerProperties != nil {
+		t.Errorf("Expected no trigger properties, but got %v", deleteResponse.TriggerProperties)
+	}
+
+	udfResponse, err := container.ReadUserDefinedFunction(context.TODO(), "udf1", nil)
+	if err != nil {
+		t.Fatalf("Failed to read user-defined function: %v", err)
+	}
+	if udfResponse.UserDefinedFunctionProperties.ID != "udf1" {
+		t.Errorf("Unexpected user-defined function properties %v", udfResponse.UserDefinedFunctionProperties)
+	}
+
+	_, err = container.DeleteUserDefinedFunction(context.TODO(), "udf1", nil)
+	if err != nil {
+		t.Fatalf("Failed to delete user-defined function: %v", err)
+	}
+
+	expected := []struct {
+		method string
+		uri    string
+	}{
+		{http.MethodGet, "/dbs/databaseId/colls/containerId/triggers/trigger1"},
+		{http.MethodPut, "/dbs/databaseId/colls/containerId/triggers/trigger1"},
+		{http.MethodDelete, "/dbs/databaseId/colls/containerId/triggers/trigger1"},
+		{http.MethodGet, "/dbs/databaseId/colls/containerId/udfs/udf1"},
+		{http.MethodDelete, "/dbs/databaseId/colls/containerId/udfs/udf1"},
+	}
+
+	if len(verifier.requests) != len(expected) {
+		t.Fatalf("Expected %d requests, got %d", len(expected), len(verifier.requests))
+	}
+
+	for i, e := range expected {
+		if verifier.requests[i].method != e.method {
+			t.Errorf("Expected method of request %d to be %s, but got %s", i, e.method, verifier.requests[i].method)
+		}
+		if verifier.requests[i].url.RequestURI() != e.uri {
+			t.Errorf("Expected url of request %d to be %s, but got %s", i, e.uri, verifier.requests[i].url.RequestURI())
+		}
+	}
+
+	if _, err := container.ReadStoredProcedure(context.TODO(), "", nil); err == nil {
+		t.Error("Expected an error for an empty id")
+	}
+}
+
+func TestContainerExecuteStoredProcedure(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(
+		mock.WithBody([]byte(`{"count":2}`)),
+		mock.WithHeader(cosmosHeaderActivityId, "someActivityId"),
+		mock.WithHeader(cosmosHeaderRequestCharge, "4.5"),
+		mock.WithHeader(cosmosHeaderSessionToken, "0:1#2"),
+		mock.WithHeader(cosmosHeaderScriptLogResults, "processed%202%20items%3A%20a%2Bb"),
+		mock.WithStatusCode(200))
+
+	verifier := pipelineVerifier{}
+	container := newScriptsTestContainer(t, srv, &verifier)
+
+	params := []any{"a", 2, map[string]any{"nested": true}}
+	resp, err := container.ExecuteStoredProcedure(context.TODO(), "sproc1", NewPartitionKeyString("pk"), params, &ExecuteStoredProcedureOptions{EnableScriptLogging: true})
+	if err != nil {
+		t.Fatalf("Failed to execute stored procedure: %v", err)
+	}
+
+	if string(resp.Value) != `{"count":2}` {
+		t.Errorf("Expected value to be %s, but got %s", `{"count":2}`, string(resp.Value))
+	}
+
+	if resp.ScriptLog != "processed 2 items: a+b" {
+		t.Errorf("Expected script log to be %s, but got %s", "processed 2 items: a+b", resp.ScriptLog)
+	}
+
+	if resp.RequestCharge != 4.5 {
+		t.Errorf("Expected RequestCharge to be %f, but got %f", 4.5, resp.RequestCharge)
+	}
+
+	if resp.SessionToken == nil || *resp.SessionToken != "0:1#2" {
+		t.Errorf("Expected SessionToken to be %s, but got %v", "0:1#2", resp.SessionToken)
+	}
+
+	request := verifier.requests[0]
+	if request.method != http.MethodPost {
+		t.Errorf("Expected method to be %s, but got %s", http.MethodPost, request.method)
+	}
+
+	if request.url.RequestURI() != "/dbs/databaseId/colls/containerId/sprocs/sproc1" {
+		t.Errorf("Expected url to be %s, but got %s", "/dbs/databaseId/colls/containerId/sprocs/sproc1", request.url.RequestURI())
+	}
+
+	if request.body != `["a",2,{"nested":true}]` {
+		t.Errorf("Expected body to be %s, but got %s", `["a",2,{"nested":true}]`, request.body)
+	}
+
+	if request.headers.Get(cosmosHeaderScriptEnableLogging) != "true" {
+		t.Errorf("Expected script logging header to be true, but got %s", request.headers.Get(cosmosHeaderScriptEnableLogging))
+	}
+
+	if request.headers.Get(cosmosHeaderPartitionKey) != `["pk"]` {
+		t.Errorf("Expected partition key header to be %s, but got %s", `["pk"]`, request.headers.Get(cosmosHeaderPartitionKey))
+	}
+
+	_, err = container.ExecuteStoredProcedure(context.TODO(), "sproc1", NewPartitionKeyString("pk"), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to execute stored procedure: %v", err)
+	}
+
+	if verifier.requests[1].body != "[]" {
+		t.Errorf("Expected body to be %s, but got %s", "[]", verifier.requests[1].body)
+	}
+
+	if verifier.requests[1].headers.Get(cosmosHeaderScriptEnableLogging) != "" {
+		t.Errorf("Expected no script logging header, but got %s", verifier.requests[1].headers.Get(cosmosHeaderScriptEnableLogging))
+	}
+}
+
+func TestContainerListStoredProcedures(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"StoredProcedures":[{"id":"sproc1","body":"function a() {}"},{"id":"sproc2","body":"function b() {}"}]}`)),
+		mock.WithHeader(cosmosHeaderContinuationToken, "someContinuationToken"),
+		mock.WithStatusCode(200))
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"StoredProcedures":[{"id":"sproc3","body":"function c() {}"}]}`)),
+		mock.WithStatusCode(200))
+
+	verifier := pipelineVerifier{}
+	container := newScriptsTestContainer(t, srv, &verifier)
+
+	var ids []string
+	pager := container.NewListStoredProceduresPager(&QueryScriptsOptions{MaxItemCount: 2})
+	for pager.More() {
+		page, err := pager.NextPage(context.TODO())
+		if err != nil {
+			t.Fatalf("Failed to list stored procedures: %v", err)
+		}
+		for _, sproc := range page.StoredProcedures {
+			ids = append(ids, sproc.ID)
+		}
+	}
+
+	if len(ids) != 3 || ids[0] != "sproc1" || ids[2] != "sproc3" {
+		t.Fatalf("Unexpected stored procedures %v", ids)
+	}
+
+	for index, request := range verifier.requests {
+		if request.method != http.MethodGet {
+			t.Errorf("Expected method to be %s, but got %s", http.MethodGet, request.method)
+		}
+
+		if request.url.RequestURI() != "/dbs/databaseId/colls/containerId/sprocs" {
+			t.Errorf("Expected url to be %s, but got %s", "/dbs/databaseId/colls/containerId/sprocs", request.url.RequestURI())
+		}
+
+		if request.headers.Get(cosmosHeaderMaxItemCount) != "2" {
+			t.Errorf("Expected max item count to be %s, but got %s", "2", request.headers.Get(cosmosHeaderMaxItemCount))
+		}
+
+		if index == 1 && request.headers.Get(cosmosHeaderContinuationToken) != "someContinuationToken" {
+			t.Errorf("Expected ContinuationToken to be %s, but got %s", "someContinuationToken", request.headers.Get(cosmosHeaderContinuationToken))
+		}
+	}
+}
+
+func TestContainerQueryTriggersAndUserDefinedFunctions(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"Triggers":[{"id":"trigger1","body":"function t() {}","triggerType":"Pre","triggerOperation":"Create"}]}`)),
+		mock.WithStatusCode(200))
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"UserDefinedFunctions":[{"id":"udf1","body":"function u() {}"}]}`)),
+		mock.WithStatusCode(200))
+
+	verifier := pipelineVerifier{}
+	container := newScriptsTestContainer(t, srv, &verifier)
+
+	triggerPager := container.NewQueryTriggersPager("SELECT * FROM t WHERE t.id = @id", &QueryScriptsOptions{QueryParameters: []QueryParameter{{Name: "@id", Value: "trigger1"}}})
+	triggers, err := triggerPager.NextPage(context.TODO())
+	if err != nil {
+		t.Fatalf("Failed to query triggers: %v", err)
+	}
+	if triggerPager.More() {
+		t.Error("Expected a single page of triggers")
+	}
+	if len(triggers.Triggers) != 1 || triggers.Triggers[0].TriggerType != TriggerTypePre {
+		t.Errorf("Unexpected triggers %v", triggers.Triggers)
+	}
+
+	udfPager := container.NewQueryUserDefinedFunctionsPager("SELECT * FROM u", nil)
+	udfs, err := udfPager.NextPage(context.TODO())
+	if err != nil {
+		t.Fatalf("Failed to query user-defined functions: %v", err)
+	}
+	if len(udfs.UserDefinedFunctions) != 1 || udfs.UserDefinedFunctions[0].ID != "udf1" {
+		t.Errorf("Unexpected user-defined functions %v", udfs.UserDefinedFunctions)
+	}
+
+	if !verifier.requests[0].isQuery || verifier.requests[0].url.RequestURI() != "/dbs/databaseId/colls/containerId/triggers" {
+		t.Errorf("Expected a query on triggers, but got %s", verifier.requests[0].url.RequestURI())
+	}
+
+	if verifier.requests[0].body != `{"query":"SELECT * FROM t WHERE t.id = @id","parameters":[{"name":"@id","value":"trigger1"}]}` {
+		t.Errorf("Unexpected query body %s", verifier.requests[0].body)
+	}
+
+	if !verifier.requests[1].isQuery || verifier.requests[1].url.RequestURI() != "/dbs/databaseId/colls/containerId/udfs" {
+		t.Errorf("Expected a query on user-defined functions, but got %s", verifier.requests[1].url.RequestURI())
+	}
+}
+
+func TestItemOptionsTriggers(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(201))
+
+	verifier := pipelineVerifier{}
+	container := newScriptsTestContainer(t, srv, &verifier)
+
+	_, err := container.CreateItem(context.TODO(), NewPartitionKeyString("1"), []byte(`{"id":"doc1"}`), &ItemOptions{
+		PreTriggers:  []string{"validate", "stamp"},
+		PostTriggers: []string{"audit"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	if verifier.requests[0].headers.Get(cosmosHeaderPreTriggerInclude) != "validate,stamp" {
+		t.Errorf("Expected pre-triggers to be %s, but got %s", "validate,stamp", verifier.requests[0].headers.Get(cosmosHeaderPreTriggerInclude))
+	}
+
+	if verifier.requests[0].headers.Get(cosmosHeaderPostTriggerInclude) != "audit" {
+		t.Errorf("Expected post-triggers to be %s, but got %s", "audit", verifier.requests[0].headers.Get(cosmosHeaderPostTriggerInclude))
+	}
+}
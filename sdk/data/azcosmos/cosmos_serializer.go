@@ -0,0 +1,73 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// Serializer converts items to and from JSON in the generic item functions, such as ReadItem, UpsertItem,
+// NewQueryItemsPager and GetChangeFeed.
+// The Marshal and Unmarshal functions of encoding/json compatible libraries satisfy it, for example through
+// a struct with methods calling them.
+type Serializer interface {
+	// Marshal returns the JSON encoding of v.
+	Marshal(v any) ([]byte, error)
+	// Unmarshal parses the JSON encoded data and stores the result in the value pointed to by v.
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONSerializer is a Serializer using encoding/json. It is the default serializer of the client.
+type JSONSerializer struct{}
+
+// Marshal returns the JSON encoding of v.
+func (JSONSerializer) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal parses the JSON encoded data and stores the result in the value pointed to by v.
+func (JSONSerializer) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// itemSerializer returns the serializer of the items of the client.
+func (c *Client) itemSerializer() Serializer {
+	if c.serializer == nil {
+		return JSONSerializer{}
+	}
+	return c.serializer
+}
+
+// SystemProperties are the properties the service maintains on items.
+// Embedding SystemProperties in an item type reads them with serializers honoring json struct tags.
+// The responses of the generic item functions also parse them from the item, whatever the serializer.
+type SystemProperties struct {
+	// ID of the item, unique within its logical partition.
+	ID string `json:"id,omitempty"`
+	// ResourceID is the unique identifier of the item in the service.
+	ResourceID string `json:"_rid,omitempty"`
+	// SelfLink is the resource id based link of the item.
+	SelfLink string `json:"_self,omitempty"`
+	// ETag of the item, used for optimistic concurrency with ItemOptions.IfMatchEtag.
+	ETag azcore.ETag `json:"_etag,omitempty"`
+	// Timestamp is the time of the last update of the item, in seconds since the Unix epoch.
+	Timestamp int64 `json:"_ts,omitempty"`
+}
+
+// LastModified returns the time of the last update of the item.
+func (p SystemProperties) LastModified() time.Time {
+	return time.Unix(p.Timestamp, 0)
+}
+
+func systemPropertiesOf(item []byte) (SystemProperties, error) {
+	var properties SystemProperties
+	if len(item) == 0 {
+		return properties, nil
+	}
+	err := json.Unmarshal(item, &properties)
+	return properties, err
+}
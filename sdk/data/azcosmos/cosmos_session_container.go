@@ -0,0 +1,281 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// sessionContainer tracks the session tokens returned by the service for the partition key ranges of each container,
+// so reads issued by the client observe its previous writes under Session consistency.
+type sessionContainer struct {
+	mu sync.RWMutex
+	// containers maps the links of containers to their session tokens.
+	containers map[string]*containerSessionTokens
+}
+
+type containerSessionTokens struct {
+	// resourceID is the resource id of the container the tokens were returned for, used to discard the tokens of a
+	// deleted container when a new container with the same id returns its first session token.
+	resourceID string
+	// tokens maps partition key range ids to their session token.
+	tokens map[string]vectorSessionToken
+}
+
+func newSessionContainer() *sessionContainer {
+	return &sessionContainer{containers: map[string]*containerSessionTokens{}}
+}
+
+// sessions returns the session container of the client.
+func (c *Client) sessions() *sessionContainer {
+	c.sessionContainerOnce.Do(func() {
+		c.sessionContainer = newSessionContainer()
+	})
+	return c.sessionContainer
+}
+
+// setSessionToken merges the session tokens of a session token header returned for a container.
+// resourceID is the resource id of the container when known.
+func (s *sessionContainer) setSessionToken(containerLink string, resourceID string, header string) error {
+	tokens, err := parseSessionTokenHeader(header)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mergeLocked(containerLink, resourceID, tokens)
+	return nil
+}
+
+func (s *sessionContainer) mergeLocked(containerLink string, resourceID string, tokens map[string]vectorSessionToken) {
+	container, ok := s.containers[containerLink]
+	if !ok || (resourceID != "" && container.resourceID != "" && container.resourceID != resourceID) {
+		container = &containerSessionTokens{tokens: map[string]vectorSessionToken{}}
+		s.containers[containerLink] = container
+	}
+	if resourceID != "" {
+		container.resourceID = resourceID
+	}
+
+	for rangeID, token := range tokens {
+		if existing, ok := container.tokens[rangeID]; ok {
+			merged, err := existing.merge(token)
+			if err != nil {
+				// The regions of the partition key range changed without a new version; the latest token wins.
+				merged = token
+			}
+			token = merged
+		}
+		container.tokens[rangeID] = token
+	}
+}
+
+// sessionToken returns the session token header to send with a request on the partition key range of a container.
+// A partition key range created by a split or a merge, with no session token yet, uses the session tokens of its parents.
+// When rangeID is empty, the session tokens of all the partition key ranges of the container are returned.
+func (s *sessionContainer) sessionToken(containerLink string, rangeID string, parents []string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	container, ok := s.containers[containerLink]
+	if !ok || len(container.tokens) == 0 {
+		return ""
+	}
+
+	if rangeID == "" {
+		rangeIDs := make([]string, 0, len(container.tokens))
+		for id := range container.tokens {
+			rangeIDs = append(rangeIDs, id)
+		}
+		sort.Strings(rangeIDs)
+		entries := make([]string, 0, len(rangeIDs))
+		for _, id := range rangeIDs {
+			entries = append(entries, id+":"+container.tokens[id].String())
+		}
+		return strings.Join(entries, ",")
+	}
+
+	if token, ok := container.tokens[rangeID]; ok {
+		return rangeID + ":" + token.String()
+	}
+
+	var inherited *vectorSessionToken
+	for _, parent := range parents {
+		token, ok := container.tokens[parent]
+		if !ok {
+			continue
+		}
+		if inherited != nil {
+			merged, err := inherited.merge(token)
+			if err != nil {
+				continue
+			}
+			token = merged
+		}
+		inherited = &token
+	}
+	if inherited == nil {
+		return ""
+	}
+	return rangeID + ":" + inherited.String()
+}
+
+// clear removes the session tokens of a container, for example after it was deleted.
+func (s *sessionContainer) clear(containerLink string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.containers, containerLink)
+}
+
+// sessionState is the JSON representation of the session tokens of a client.
+type sessionState struct {
+	Containers map[string]containerSessionState `json:"containers"`
+}
+
+type containerSessionState struct {
+	ResourceID string            `json:"resourceId,omitempty"`
+	Tokens     map[string]string `json:"tokens"`
+}
+
+func (s *sessionContainer) export() ([]byte, error) {
+	s.mu.RLock()
+	state := sessionState{Containers: make(map[string]containerSessionState, len(s.containers))}
+	for link, container := range s.containers {
+		tokens := make(map[string]string, len(container.tokens))
+		for rangeID, token := range container.tokens {
+			tokens[rangeID] = token.String()
+		}
+		state.Containers[link] = containerSessionState{ResourceID: container.resourceID, Tokens: tokens}
+	}
+	s.mu.RUnlock()
+	return json.Marshal(state)
+}
+
+func (s *sessionContainer) importState(data []byte) error {
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	parsed := make(map[string]map[string]vectorSessionToken, len(state.Containers))
+	for link, container := range state.Containers {
+		tokens := make(map[string]vectorSessionToken, len(container.Tokens))
+		for rangeID, token := range container.Tokens {
+			t, err := parseVectorSessionToken(token)
+			if err != nil {
+				return err
+			}
+			tokens[rangeID] = t
+		}
+		parsed[link] = tokens
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for link, tokens := range parsed {
+		s.mergeLocked(link, state.Containers[link].ResourceID, tokens)
+	}
+	return nil
+}
+
+// ExportSessionState returns the session tokens the client tracks for the containers it wrote to or read from,
+// as an opaque JSON document.
+// Importing the document in another client with ImportSessionState, for example in another tier of a service
+// handling requests of the same user, lets the reads of that client observe the writes of this client under Session consistency.
+func (c *Client) ExportSessionState() ([]byte, error) {
+	return c.sessions().export()
+}
+
+// ImportSessionState merges session tokens exported by ExportSessionState into the session tokens tracked by the client.
+// state - The document returned by ExportSessionState.
+func (c *Client) ImportSessionState(state []byte) error {
+	if len(state) == 0 {
+		return errors.New("state is required")
+	}
+	return c.sessions().importState(state)
+}
+
+// containerLinkOf returns the link of the container of a resource address, or an empty string when the
+// address isn't a container or one of its children.
+func containerLinkOf(resourceAddress string) string {
+	segments := strings.SplitN(strings.Trim(resourceAddress, "/"), "/", 5)
+	if len(segments) < 4 || segments[0] != pathSegmentDatabase || segments[2] != pathSegmentCollection {
+		return ""
+	}
+	return strings.Join(segments[:4], "/")
+}
+
+// usesSessionToken reports whether the session token of a request is tracked by the client: reads, queries and
+// change feed requests on items, when the consistency of the request is Session.
+func (c *Client) usesSessionToken(req *policy.Request, operationContext pipelineRequestOptions) bool {
+	if operationContext.resourceType != resourceTypeDocument || operationContext.isWriteOperation {
+		return false
+	}
+
+	consistency := ConsistencyLevel(req.Raw().Header.Get(cosmosHeaderConsistencyLevel))
+	if consistency == "" && c.gem != nil {
+		consistency = c.gem.DefaultConsistencyLevel()
+	}
+	// The account consistency is unknown until the first request reads the account properties.
+	return consistency == "" || consistency == ConsistencyLevelSession
+}
+
+// applySessionToken sets the session token header of a read, query or change feed request on items,
+// unless the request options set a session token.
+func (c *Client) applySessionToken(req *policy.Request, operationContext pipelineRequestOptions, parents []string) {
+	if req.Raw().Header.Get(cosmosHeaderSessionToken) != "" || !c.usesSessionToken(req, operationContext) {
+		return
+	}
+
+	containerLink := containerLinkOf(operationContext.resourceAddress)
+	if containerLink == "" {
+		return
+	}
+
+	// Without a partition key range, the service picks the session token of the range serving the request.
+	if token := c.sessions().sessionToken(containerLink, operationContext.partitionKeyRangeID, parents); token != "" {
+		req.Raw().Header.Set(cosmosHeaderSessionToken, token)
+	}
+}
+
+// captureSessionToken records the session token returned for a request on items, and forgets the session tokens
+// of deleted containers.
+func (c *Client) captureSessionToken(req *policy.Request, resp *http.Response) {
+	var operationContext pipelineRequestOptions
+	if !req.OperationValue(&operationContext) {
+		return
+	}
+
+	containerLink := containerLinkOf(operationContext.resourceAddress)
+	if containerLink == "" {
+		return
+	}
+
+	switch operationContext.resourceType {
+	case resourceTypeCollection:
+		if req.Raw().Method == http.MethodDelete && resp.StatusCode < 300 {
+			c.sessions().clear(containerLink)
+		}
+	case resourceTypeDocument:
+		if resp.StatusCode == http.StatusNotFound {
+			// The session token of a missing item may belong to a container deleted and recreated with the same id.
+			return
+		}
+		if header := resp.Header.Get(cosmosHeaderSessionToken); header != "" {
+			// Session tokens of partition key ranges are opaque to the caller; invalid ones are ignored.
+			_ = c.sessions().setSessionToken(containerLink, resp.Header.Get(headerXmsContentPath), header)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func TestSessionContainerSessionToken(t *testing.T) {
+	sessions := newSessionContainer()
+	if token := sessions.sessionToken("dbs/db/colls/c", "", nil); token != "" {
+		t.Errorf("Expected no session token, but got %s", token)
+	}
+
+	if err := sessions.setSessionToken("dbs/db/colls/c", "rid1", "0:1#10#1=5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sessions.setSessionToken("dbs/db/colls/c", "", "1:1#20#1=3,0:1#8#1=7"); err != nil {
+		t.Fatal(err)
+	}
+
+	if token := sessions.sessionToken("dbs/db/colls/c", "", nil); token != "0:1#10#1=7,1:1#20#1=3" {
+		t.Errorf("Expected %s, but got %s", "0:1#10#1=7,1:1#20#1=3", token)
+	}
+
+	if token := sessions.sessionToken("dbs/db/colls/c", "1", nil); token != "1:1#20#1=3" {
+		t.Errorf("Expected %s, but got %s", "1:1#20#1=3", token)
+	}
+
+	// Range 2 was created by merging ranges 0 and 1.
+	if token := sessions.sessionToken("dbs/db/colls/c", "2", []string{"0", "1"}); token != "2:1#20#1=7" {
+		t.Errorf("Expected %s, but got %s", "2:1#20#1=7", token)
+	}
+
+	if token := sessions.sessionToken("dbs/db/colls/c", "3", []string{"4"}); token != "" {
+		t.Errorf("Expected no session token, but got %s", token)
+	}
+
+	// A container recreated with the same id has a different resource id.
+	if err := sessions.setSessionToken("dbs/db/colls/c", "rid2", "0:1#1#1=1"); err != nil {
+		t.Fatal(err)
+	}
+	if token := sessions.sessionToken("dbs/db/colls/c", "", nil); token != "0:1#1#1=1" {
+		t.Errorf("Expected %s, but got %s", "0:1#1#1=1", token)
+	}
+
+	sessions.clear("dbs/db/colls/c")
+	if token := sessions.sessionToken("dbs/db/colls/c", "", nil); token != "" {
+		t.Errorf("Expected no session token, but got %s", token)
+	}
+
+	if err := sessions.setSessionToken("dbs/db/colls/c", "", "invalid"); err == nil {
+		t.Error("Expected an error for an invalid session token")
+	}
+}
+
+func TestSessionContainerExportImport(t *testing.T) {
+	client := &Client{}
+	if err := client.sessions().setSessionToken("dbs/db/colls/c", "rid1", "0:1#10#1=5,1:42"); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := client.ExportSessionState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := &Client{}
+	if err := other.sessions().setSessionToken("dbs/db/colls/c", "rid1", "0:1#12#1=4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.ImportSessionState(state); err != nil {
+		t.Fatal(err)
+	}
+
+	if token := other.sessions().sessionToken("dbs/db/colls/c", "", nil); token != "0:1#12#1=5,1:42" {
+		t.Errorf("Expected %s, but got %s", "0:1#12#1=5,1:42", token)
+	}
+
+	if err := other.ImportSessionState(nil); err == nil {
+		t.Error("Expected an error for an empty state")
+	}
+
+	if err := other.ImportSessionState([]byte(`{"containers":{"dbs/db/colls/c":{"tokens":{"0":"a"}}}}`)); err == nil {
+		t.Error("Expected an error for an invalid session token")
+	}
+}
+
+func TestContainerLinkOf(t *testing.T) {
+	cases := map[string]string{
+		"dbs/db/colls/c":         "dbs/db/colls/c",
+		"dbs/db/colls/c/docs/id": "dbs/db/colls/c",
+		"/dbs/db/colls/c/":       "dbs/db/colls/c",
+		"dbs/db":                 "",
+		"dbs/db/users/u":         "",
+		"":                       "",
+	}
+	for address, expected := range cases {
+		if link := containerLinkOf(address); link != expected {
+			t.Errorf("Expected container link of %s to be %s, but got %s", address, expected, link)
+		}
+	}
+}
+
+func TestSessionTokensAppliedToReads(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"id":"item1"}`)),
+		mock.WithHeader(cosmosHeaderSessionToken, "0:1#10"),
+		mock.WithHeader(headerXmsContentPath, "containerRid"),
+		mock.WithStatusCode(201))
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"id":"item1"}`)),
+		mock.WithHeader(cosmosHeaderSessionToken, "1:1#20"),
+		mock.WithStatusCode(201))
+	for i := 0; i < 5; i++ {
+		srv.AppendResponse(mock.WithBody([]byte(`{"id":"item1"}`)), mock.WithStatusCode(200))
+	}
+	srv.AppendResponse(mock.WithBody([]byte(`{"Documents":[]}`)), mock.WithStatusCode(200))
+
+	verifier := pipelineVerifier{}
+	container := newScriptsTestContainer(t, srv, &verifier)
+	client := container.database.client
+	pk := NewPartitionKeyString("tenant1")
+
+	for _, id := range []string{"item1", "item2"} {
+		if _, err := container.CreateItem(context.TODO(), pk, []byte(`{"id":"`+id+`"}`), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if verifier.requests[0].headers.Get(cosmosHeaderSessionToken) != "" || verifier.requests[1].headers.Get(cosmosHeaderSessionToken) != "" {
+		t.Error("Expected no session token on writes")
+	}
+
+	// Without the partition key ranges of the container, all the session tokens are sent.
+	if _, err := container.ReadItem(context.TODO(), pk, "item1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if token := verifier.requests[2].headers.Get(cosmosHeaderSessionToken); token != "0:1#10,1:1#20" {
+		t.Errorf("Expected session token to be %s, but got %s", "0:1#10,1:1#20", token)
+	}
+
+	// The session token of the request options is used as is.
+	explicitToken := "0:1#5"
+	if _, err := container.ReadItem(context.TODO(), pk, "item1", &ItemOptions{SessionToken: &explicitToken}); err != nil {
+		t.Fatal(err)
+	}
+	if token := verifier.requests[3].headers.Get(cosmosHeaderSessionToken); token != "0:1#5" {
+		t.Errorf("Expected session token to be %s, but got %s", "0:1#5", token)
+	}
+
+	// With a cached routing map, the session token of the partition key range of the partition key is sent.
+	// Ranges 2 and 3 were created by splitting range 1 and have no session token yet.
+	definition := PartitionKeyDefinition{Paths: []string{"/pk"}, Kind: PartitionKeyKindHash, Version: 2}
+	routingMap, _ := newCollectionRoutingMap(definition, []partitionKeyRange{
+		{ID: "2", MinInclusive: "", MaxExclusive: "80", Parents: []string{"1"}},
+		{ID: "3", MinInclusive: "80", MaxExclusive: "FF", Parents: []string{"1"}},
+	})
+	client.routingMapCache().routingMaps[container.link] = routingMap
+	pkRange, _ := routingMap.rangeByPartitionKey(pk)
+
+	if _, err := container.ReadItem(context.TODO(), pk, "item1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if token := verifier.requests[4].headers.Get(cosmosHeaderSessionToken); token != pkRange.ID+":1#20" {
+		t.Errorf("Expected session token to be %s, but got %s", pkRange.ID+":1#20", token)
+	}
+
+	// Session tokens are only sent under Session consistency.
+	client.gem.defaultConsistencyLevel.Store(ConsistencyLevelStrong)
+	if _, err := container.ReadItem(context.TODO(), pk, "item1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if token := verifier.requests[5].headers.Get(cosmosHeaderSessionToken); token != "" {
+		t.Errorf("Expected no session token, but got %s", token)
+	}
+
+	if _, err := container.ReadItem(context.TODO(), pk, "item1", &ItemOptions{ConsistencyLevel: ConsistencyLevelSession.ToPtr()}); err != nil {
+		t.Fatal(err)
+	}
+	if token := verifier.requests[6].headers.Get(cosmosHeaderSessionToken); token == "" {
+		t.Error("Expected a session token when the request uses Session consistency")
+	}
+
+	client.gem.defaultConsistencyLevel.Store(ConsistencyLevelSession)
+	if _, err := container.NewQueryItemsPager("SELECT * FROM c", pk, nil).NextPage(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if token := verifier.requests[7].headers.Get(cosmosHeaderSessionToken); !strings.HasPrefix(token, pkRange.ID+":") {
+		t.Errorf("Expected the session token of range %s, but got %s", pkRange.ID, token)
+	}
+}
+
+func TestSessionTokensClearedOnContainerDelete(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(204))
+
+	verifier := pipelineVerifier{}
+	container := newScriptsTestContainer(t, srv, &verifier)
+	sessions := container.database.client.sessions()
+	if err := sessions.setSessionToken(container.link, "", "0:1#10"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := container.Delete(context.TODO(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if token := sessions.sessionToken(container.link, "", nil); token != "" {
+		t.Errorf("Expected no session token, but got %s", token)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// vectorSessionToken is the session token of a partition key range.
+// Its representation is either "<globalLsn>" for simple session tokens, or
+// "<version>#<globalLsn>[#<regionId>=<localLsn>...]" for vector session tokens, which carry the local
+// log sequence number of each write region of multi-region write accounts.
+type vectorSessionToken struct {
+	version          int64
+	globalLSN        int64
+	localLSNByRegion map[int]int64
+	// simple is set for session tokens containing only the global log sequence number.
+	simple bool
+}
+
+func parseVectorSessionToken(token string) (vectorSessionToken, error) {
+	segments := strings.Split(token, "#")
+	if len(segments) == 1 {
+		lsn, err := strconv.ParseInt(segments[0], 10, 64)
+		if err != nil {
+			return vectorSessionToken{}, fmt.Errorf("invalid session token %s", token)
+		}
+		return vectorSessionToken{globalLSN: lsn, simple: true}, nil
+	}
+
+	version, err := strconv.ParseInt(segments[0], 10, 64)
+	if err != nil {
+		return vectorSessionToken{}, fmt.Errorf("invalid session token %s", token)
+	}
+	globalLSN, err := strconv.ParseInt(segments[1], 10, 64)
+	if err != nil {
+		return vectorSessionToken{}, fmt.Errorf("invalid session token %s", token)
+	}
+	parsed := vectorSessionToken{version: version, globalLSN: globalLSN, localLSNByRegion: map[int]int64{}}
+	for _, segment := range segments[2:] {
+		region, lsn, found := strings.Cut(segment, "=")
+		if !found {
+			return vectorSessionToken{}, fmt.Errorf("invalid session token %s", token)
+		}
+		regionID, err := strconv.Atoi(region)
+		if err != nil {
+			return vectorSessionToken{}, fmt.Errorf("invalid session token %s", token)
+		}
+		localLSN, err := strconv.ParseInt(lsn, 10, 64)
+		if err != nil {
+			return vectorSessionToken{}, fmt.Errorf("invalid session token %s", token)
+		}
+		parsed.localLSNByRegion[regionID] = localLSN
+	}
+	return parsed, nil
+}
+
+// merge returns a session token at least as recent as both tokens.
+// The tokens of a partition key range with the same version must track the same regions; a newer version
+// may add or remove regions, and its regions are kept.
+func (t vectorSessionToken) merge(other vectorSessionToken) (vectorSessionToken, error) {
+	if t.simple && other.simple {
+		return vectorSessionToken{globalLSN: max(t.globalLSN, other.globalLSN), simple: true}, nil
+	}
+
+	if t.version == other.version && len(t.localLSNByRegion) != len(other.localLSNByRegion) {
+		return vectorSessionToken{}, errors.New("session tokens of the same version have different regions")
+	}
+
+	higher, lower := t, other
+	if t.version < other.version {
+		higher, lower = other, t
+	}
+
+	merged := vectorSessionToken{
+		version:          higher.version,
+		globalLSN:        max(t.globalLSN, other.globalLSN),
+		localLSNByRegion: make(map[int]int64, len(higher.localLSNByRegion)),
+	}
+	for region, lsn := range higher.localLSNByRegion {
+		otherLSN, found := lower.localLSNByRegion[region]
+		switch {
+		case found:
+			merged.localLSNByRegion[region] = max(lsn, otherLSN)
+		case t.version == other.version:
+			return vectorSessionToken{}, errors.New("session tokens of the same version have different regions")
+		default:
+			merged.localLSNByRegion[region] = lsn
+		}
+	}
+	return merged, nil
+}
+
+func (t vectorSessionToken) String() string {
+	if t.simple {
+		return strconv.FormatInt(t.globalLSN, 10)
+	}
+
+	var b strings.Builder
+	b.WriteString(strconv.FormatInt(t.version, 10))
+	b.WriteString("#")
+	b.WriteString(strconv.FormatInt(t.globalLSN, 10))
+
+	regions := make([]int, 0, len(t.localLSNByRegion))
+	for region := range t.localLSNByRegion {
+		regions = append(regions, region)
+	}
+	sort.Ints(regions)
+	for _, region := range regions {
+		b.WriteString("#")
+		b.WriteString(strconv.Itoa(region))
+		b.WriteString("=")
+		b.WriteString(strconv.FormatInt(t.localLSNByRegion[region], 10))
+	}
+	return b.String()
+}
+
+// parseSessionTokenHeader parses the value of a session token header, a comma-separated list of
+// "<pkRangeId>:<sessionToken>" entries, into the session token of each partition key range.
+func parseSessionTokenHeader(header string) (map[string]vectorSessionToken, error) {
+	tokens := map[string]vectorSessionToken{}
+	for _, entry := range strings.Split(header, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rangeID, token, found := strings.Cut(entry, ":")
+		if !found || rangeID == "" {
+			return nil, errors.New("invalid session token " + entry)
+		}
+		parsed, err := parseVectorSessionToken(token)
+		if err != nil {
+			return nil, err
+		}
+		if existing, ok := tokens[rangeID]; ok {
+			if merged, err := existing.merge(parsed); err == nil {
+				parsed = merged
+			}
+		}
+		tokens[rangeID] = parsed
+	}
+	return tokens, nil
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"testing"
+)
+
+func TestVectorSessionTokenParse(t *testing.T) {
+	for _, token := range []string{"42", "1#100", "1#100#1=20", "2#100#1=20#3=30"} {
+		parsed, err := parseVectorSessionToken(token)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", token, err)
+		}
+		if parsed.String() != token {
+			t.Errorf("Expected %s, but got %s", token, parsed.String())
+		}
+	}
+
+	for _, token := range []string{"", "a", "1#a", "1#100#1", "1#100#a=1", "1#100#1=a"} {
+		if _, err := parseVectorSessionToken(token); err == nil {
+			t.Errorf("Expected an error for %q", token)
+		}
+	}
+}
+
+func TestVectorSessionTokenMerge(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected string
+	}{
+		{"10", "12", "12"},
+		{"1#100#1=20#2=30", "1#90#1=25#2=10", "1#100#1=25#2=30"},
+		// A newer version keeps its regions.
+		{"1#100#1=20", "2#90#1=10#2=5", "2#100#1=20#2=5"},
+		{"2#90#1=10", "1#100#1=20#2=5", "2#100#1=20"},
+		{"5", "1#100#1=20", "1#100#1=20"},
+	}
+	for _, c := range cases {
+		a, _ := parseVectorSessionToken(c.a)
+		b, _ := parseVectorSessionToken(c.b)
+		merged, err := a.merge(b)
+		if err != nil {
+			t.Fatalf("Failed to merge %s and %s: %v", c.a, c.b, err)
+		}
+		if merged.String() != c.expected {
+			t.Errorf("Expected merging %s and %s to be %s, but got %s", c.a, c.b, c.expected, merged.String())
+		}
+	}
+
+	a, _ := parseVectorSessionToken("1#100#1=20")
+	b, _ := parseVectorSessionToken("1#100#2=20")
+	if _, err := a.merge(b); err == nil {
+		t.Error("Expected an error merging tokens of the same version with different regions")
+	}
+}
+
+func TestParseSessionTokenHeader(t *testing.T) {
+	tokens, err := parseSessionTokenHeader("0:1#100#1=20, 1:1#50,0:1#90#1=25")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 2 {
+		t.Fatalf("Expected 2 tokens, but got %d", len(tokens))
+	}
+
+	if tokens["0"].String() != "1#100#1=25" {
+		t.Errorf("Expected token of range 0 to be %s, but got %s", "1#100#1=25", tokens["0"].String())
+	}
+
+	if tokens["1"].String() != "1#50" {
+		t.Errorf("Expected token of range 1 to be %s, but got %s", "1#50", tokens["1"].String())
+	}
+
+	if _, err := parseSessionTokenHeader("1#50"); err == nil {
+		t.Error("Expected an error for a token without partition key range")
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// ThroughputControlGroup is a budget of request units per second shared by the operations of a client on a container.
+// The client paces the operations of the group locally, using the request charge of their responses, so operations
+// of other groups or clients keep the remaining throughput of the container.
+// The budget is either TargetThroughput, or TargetThroughputThreshold of the throughput provisioned for the container.
+type ThroughputControlGroup struct {
+	// Name of the group, used by ItemOptions.ThroughputControlGroup and QueryOptions.ThroughputControlGroup.
+	Name string
+	// TargetThroughput is the budget of the group, in request units per second.
+	TargetThroughput int32
+	// TargetThroughputThreshold is the budget of the group as a fraction, between 0 and 1, of the throughput provisioned
+	// for the container, or for its database when the container shares the throughput of its database.
+	// The maximum throughput is used for autoscale throughput.
+	TargetThroughputThreshold float64
+	// IsDefault makes the group apply to the operations on the container that don't select a group.
+	// A container has at most one default group.
+	IsDefault bool
+	// PriorityLevel, when set, is the priority level of the operations of the group that don't set one.
+	PriorityLevel *PriorityLevel
+}
+
+// throughputLimiter paces the requests of a throughput control group.
+// The budget refills continuously at the rate of the group, up to one second of throughput, and each response
+// consumes its request charge. Requests wait while the budget is exhausted.
+type throughputLimiter struct {
+	name          string
+	priorityLevel *PriorityLevel
+	ruPerSecond   float64
+
+	mu         sync.Mutex
+	available  float64
+	lastRefill time.Time
+}
+
+func newThroughputLimiter(name string, ruPerSecond float64, priorityLevel *PriorityLevel) *throughputLimiter {
+	return &throughputLimiter{
+		name:          name,
+		priorityLevel: priorityLevel,
+		ruPerSecond:   ruPerSecond,
+		available:     ruPerSecond,
+		lastRefill:    time.Now(),
+	}
+}
+
+func (l *throughputLimiter) refillLocked(now time.Time) {
+	l.available = min(l.ruPerSecond, l.available+now.Sub(l.lastRefill).Seconds()*l.ruPerSecond)
+	l.lastRefill = now
+}
+
+// wait blocks until the budget of the group is available, and returns the time waited.
+func (l *throughputLimiter) wait(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	for {
+		l.mu.Lock()
+		l.refillLocked(time.Now())
+		if l.available > 0 {
+			l.mu.Unlock()
+			return time.Since(start), nil
+		}
+		delay := time.Duration((-l.available/l.ruPerSecond)*float64(time.Second)) + time.Millisecond
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return time.Since(start), ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// charge consumes the request charge of a response from the budget of the group.
+func (l *throughputLimiter) charge(requestCharge float32) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refillLocked(time.Now())
+	l.available -= float64(requestCharge)
+}
+
+// throughputControl tracks the throughput control groups of the containers of a client.
+type throughputControl struct {
+	mu sync.RWMutex
+	// containers maps the links of containers to their groups by name.
+	containers map[string]map[string]*throughputLimiter
+	// defaults maps the links of containers to the name of their default group.
+	defaults map[string]string
+}
+
+func newThroughputControl() *throughputControl {
+	return &throughputControl{
+		containers: map[string]map[string]*throughputLimiter{},
+		defaults:   map[string]string{},
+	}
+}
+
+// throughputControl returns the throughput control groups of the client.
+func (c *Client) throughputControl() *throughputControl {
+	c.throughputControlOnce.Do(func() {
+		c.throughputControlGroups = newThroughputControl()
+	})
+	return c.throughputControlGroups
+}
+
+func (t *throughputControl) enable(containerLink string, limiter *throughputLimiter, isDefault bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if name, ok := t.defaults[containerLink]; ok && isDefault && name != limiter.name {
+		return fmt.Errorf("throughput control group %s is already the default group of the container", name)
+	}
+	groups, ok := t.containers[containerLink]
+	if !ok {
+		groups = map[string]*throughputLimiter{}
+		t.containers[containerLink] = groups
+	}
+	groups[limiter.name] = limiter
+	if isDefault {
+		t.defaults[containerLink] = limiter.name
+	} else if t.defaults[containerLink] == limiter.name {
+		delete(t.defaults, containerLink)
+	}
+	return nil
+}
+
+func (t *throughputControl) disable(containerLink string, name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.containers[containerLink], name)
+	if t.defaults[containerLink] == name {
+		delete(t.defaults, containerLink)
+	}
+}
+
+// limiter returns the group of a container with a name, or its default group when name is empty.
+func (t *throughputControl) limiter(containerLink string, name string) (*throughputLimiter, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if name == "" {
+		name = t.defaults[containerLink]
+		if name == "" {
+			return nil, nil
+		}
+	}
+	limiter, ok := t.containers[containerLink][name]
+	if !ok {
+		return nil, fmt.Errorf("throughput control group %s is not enabled for container %s", name, containerLink)
+	}
+	return limiter, nil
+}
+
+// throughputControlGroupSelector is implemented by the options of the operations that select a throughput control group.
+type throughputControlGroupSelector interface {
+	throughputControlGroupName() string
+}
+
+// applyThroughputControl selects the throughput control group of a request on items, and sets the priority level
+// of the group when the request doesn't set one.
+func (c *Client) applyThroughputControl(req *policy.Request, operationContext *pipelineRequestOptions, requestOptions cosmosRequestOptions) error {
+	if operationContext.resourceType != resourceTypeDocument {
+		return nil
+	}
+	containerLink := containerLinkOf(operationContext.resourceAddress)
+	if containerLink == "" {
+		return nil
+	}
+
+	var name string
+	if selector, ok := requestOptions.(throughputControlGroupSelector); ok {
+		name = selector.throughputControlGroupName()
+	}
+	limiter, err := c.throughputControl().limiter(containerLink, name)
+	if err != nil || limiter == nil {
+		return err
+	}
+
+	operationContext.throughputLimiter = limiter
+	if limiter.priorityLevel != nil && req.Raw().Header.Get(cosmosHeaderPriorityLevel) == "" {
+		req.Raw().Header.Set(cosmosHeaderPriorityLevel, string(*limiter.priorityLevel))
+	}
+	return nil
+}
+
+// EnableThroughputControlGroup enables a throughput control group for the operations of the client on the container.
+// Enabling a group with the name of an enabled group replaces it, for example to read the provisioned throughput again.
+// ctx - The context for the request, used to read the provisioned throughput when the group sets TargetThroughputThreshold.
+// group - The throughput control group.
+func (c *ContainerClient) EnableThroughputControlGroup(ctx context.Context, group ThroughputControlGroup) error {
+	if group.Name == "" {
+		return errors.New("throughput control group name is required")
+	}
+	if (group.TargetThroughput > 0) == (group.TargetThroughputThreshold > 0) {
+		return errors.New("throughput control group requires either TargetThroughput or TargetThroughputThreshold")
+	}
+	if group.TargetThroughput < 0 || group.TargetThroughputThreshold < 0 || group.TargetThroughputThreshold > 1 {
+		return errors.New("throughput control group target must be positive, and its threshold at most 1")
+	}
+
+	ruPerSecond := float64(group.TargetThroughput)
+	if group.TargetThroughputThreshold > 0 {
+		provisioned, err := c.provisionedThroughput(ctx)
+		if err != nil {
+			return err
+		}
+		ruPerSecond = group.TargetThroughputThreshold * float64(provisioned)
+	}
+
+	return c.database.client.throughputControl().enable(c.link, newThroughputLimiter(group.Name, ruPerSecond, group.PriorityLevel), group.IsDefault)
+}
+
+// DisableThroughputControlGroup disables a throughput control group of the container.
+// Operations selecting the group fail once it is disabled.
+// name - The name of the throughput control group.
+func (c *ContainerClient) DisableThroughputControlGroup(name string) {
+	c.database.client.throughputControl().disable(c.link, name)
+}
+
+// provisionedThroughput returns the throughput provisioned for the container, or for its database when the container
+// shares the throughput of its database.
+func (c *ContainerClient) provisionedThroughput(ctx context.Context) (int32, error) {
+	response, err := c.ReadThroughput(ctx, nil)
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+		response, err = c.database.ReadThroughput(ctx, nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if throughput, ok := response.ThroughputProperties.ManualThroughput(); ok {
+		return throughput, nil
+	}
+	if throughput, ok := response.ThroughputProperties.AutoscaleMaxThroughput(); ok {
+		return throughput, nil
+	}
+	return 0, errors.New("the provisioned throughput of the container is unknown")
+}
+
+// throughputControlPolicy paces the requests of throughput control groups.
+// It runs for each attempt, so retries consume the budget of their group too.
+type throughputControlPolicy struct{}
+
+func (p *throughputControlPolicy) Do(req *policy.Request) (*http.Response, error) {
+	var o pipelineRequestOptions
+	if !req.OperationValue(&o) || o.throughputLimiter == nil {
+		return req.Next()
+	}
+
+	limiter := o.throughputLimiter
+	start := time.Now()
+	waited, err := limiter.wait(req.Raw().Context())
+	if waited > time.Millisecond {
+		diagnosticsFromContext(req.Raw().Context()).addEvent(DiagnosticsEvent{
+			Kind:      DiagnosticsEventThroughputControl,
+			StartTime: start,
+			Duration:  waited,
+			Reason:    limiter.name,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := req.Next()
+	if err == nil {
+		limiter.charge(requestChargeOf(response))
+	}
+	return response, err
+}
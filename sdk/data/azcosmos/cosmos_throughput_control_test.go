@@ -0,0 +1,160 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newThroughputControlTestContainer(t *testing.T, srv *mock.Server, verifier *pipelineVerifier) *ContainerClient {
+	return newTestContainer(t, srv, nil,
+		azruntime.PipelineOptions{PerCall: []policy.Policy{&headerPolicies{}, verifier}, PerRetry: []policy.Policy{&throughputControlPolicy{}}},
+		policy.RetryOptions{})
+}
+
+func TestThroughputLimiter(t *testing.T) {
+	limiter := newThroughputLimiter("group", 100, nil)
+
+	waited, err := limiter.wait(context.Background())
+	require.NoError(t, err)
+	require.Less(t, waited, 10*time.Millisecond)
+
+	// 150 RU consume the budget of 1 second and half of the next one.
+	limiter.charge(150)
+	waited, err = limiter.wait(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, waited, 400*time.Millisecond)
+
+	limiter.charge(1000)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = limiter.wait(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestEnableThroughputControlGroupValidation(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	container := newThroughputControlTestContainer(t, srv, &pipelineVerifier{})
+
+	require.Error(t, container.EnableThroughputControlGroup(context.Background(), ThroughputControlGroup{TargetThroughput: 100}))
+	require.Error(t, container.EnableThroughputControlGroup(context.Background(), ThroughputControlGroup{Name: "group"}))
+	require.Error(t, container.EnableThroughputControlGroup(context.Background(), ThroughputControlGroup{Name: "group", TargetThroughput: 100, TargetThroughputThreshold: 0.5}))
+	require.Error(t, container.EnableThroughputControlGroup(context.Background(), ThroughputControlGroup{Name: "group", TargetThroughputThreshold: 1.5}))
+
+	require.NoError(t, container.EnableThroughputControlGroup(context.Background(), ThroughputControlGroup{Name: "group", TargetThroughput: 100, IsDefault: true}))
+	require.Error(t, container.EnableThroughputControlGroup(context.Background(), ThroughputControlGroup{Name: "other", TargetThroughput: 100, IsDefault: true}))
+	require.NoError(t, container.EnableThroughputControlGroup(context.Background(), ThroughputControlGroup{Name: "group", TargetThroughput: 200, IsDefault: true}))
+
+	container.DisableThroughputControlGroup("group")
+	require.NoError(t, container.EnableThroughputControlGroup(context.Background(), ThroughputControlGroup{Name: "other", TargetThroughput: 100, IsDefault: true}))
+}
+
+func TestThroughputControlGroupPacesOperations(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(
+		mock.WithBody([]byte(`{"id":"doc1"}`)),
+		mock.WithHeader(cosmosHeaderRequestCharge, "15"),
+		mock.WithStatusCode(200))
+
+	verifier := pipelineVerifier{}
+	container := newThroughputControlTestContainer(t, srv, &verifier)
+	require.NoError(t, container.EnableThroughputControlGroup(context.Background(), ThroughputControlGroup{
+		Name:             "batch",
+		TargetThroughput: 20,
+		PriorityLevel:    PriorityLevelLow.ToPtr(),
+	}))
+
+	options := &ItemOptions{ThroughputControlGroup: "batch"}
+	resp, err := container.ReadItem(context.Background(), NewPartitionKeyString("1"), "doc1", options)
+	require.NoError(t, err)
+	require.Empty(t, diagnosticsEventsOfKind(resp.Diagnostics, DiagnosticsEventThroughputControl))
+	require.Equal(t, "Low", verifier.requests[0].headers.Get(cosmosHeaderPriorityLevel))
+
+	// The first two reads consume the budget of the first second, and half of the next one.
+	_, err = container.ReadItem(context.Background(), NewPartitionKeyString("1"), "doc1", options)
+	require.NoError(t, err)
+
+	start := time.Now()
+	resp, err = container.ReadItem(context.Background(), NewPartitionKeyString("1"), "doc1", &ItemOptions{ThroughputControlGroup: "batch", PriorityLevel: PriorityLevelHigh.ToPtr()})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+	events := diagnosticsEventsOfKind(resp.Diagnostics, DiagnosticsEventThroughputControl)
+	require.Len(t, events, 1)
+	require.Equal(t, "batch", events[0].Reason)
+	require.GreaterOrEqual(t, events[0].Duration, 400*time.Millisecond)
+	require.Equal(t, "High", verifier.requests[2].headers.Get(cosmosHeaderPriorityLevel))
+
+	// Operations without a group aren't paced.
+	start = time.Now()
+	_, err = container.ReadItem(context.Background(), NewPartitionKeyString("1"), "doc1", nil)
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 400*time.Millisecond)
+	require.Empty(t, verifier.requests[3].headers.Get(cosmosHeaderPriorityLevel))
+
+	_, err = container.ReadItem(context.Background(), NewPartitionKeyString("1"), "doc1", &ItemOptions{ThroughputControlGroup: "unknown"})
+	require.ErrorContains(t, err, "throughput control group unknown is not enabled")
+}
+
+func TestThroughputControlDefaultGroupAppliesToQueries(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(
+		mock.WithBody([]byte(`{"Documents":[{"id":"doc1"}],"_count":1}`)),
+		mock.WithHeader(cosmosHeaderRequestCharge, "3"),
+		mock.WithStatusCode(200))
+
+	verifier := pipelineVerifier{}
+	container := newThroughputControlTestContainer(t, srv, &verifier)
+	require.NoError(t, container.EnableThroughputControlGroup(context.Background(), ThroughputControlGroup{
+		Name:             "background",
+		TargetThroughput: 100,
+		IsDefault:        true,
+		PriorityLevel:    PriorityLevelLow.ToPtr(),
+	}))
+
+	pager := container.NewQueryItemsPager("SELECT * FROM c", NewPartitionKeyString("1"), nil)
+	_, err := pager.NextPage(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "Low", verifier.requests[0].headers.Get(cosmosHeaderPriorityLevel))
+
+	limiter, err := container.database.client.throughputControl().limiter(container.link, "")
+	require.NoError(t, err)
+	require.InDelta(t, 97, limiter.available, 1)
+}
+
+func TestEnableThroughputControlGroupWithThreshold(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	// Read the container, query its offers, and read its offer.
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"containerId","_rid":"containerRid"}`)), mock.WithStatusCode(200))
+	srv.AppendResponse(mock.WithBody([]byte(`{"Offers":[{"id":"offerId","_self":"offers/offerId/","offerResourceId":"containerRid","content":{"offerThroughput":400}}]}`)), mock.WithStatusCode(200))
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"offerId","_self":"offers/offerId/","offerResourceId":"containerRid","content":{"offerThroughput":400}}`)), mock.WithStatusCode(200))
+
+	container := newThroughputControlTestContainer(t, srv, &pipelineVerifier{})
+	require.NoError(t, container.EnableThroughputControlGroup(context.Background(), ThroughputControlGroup{Name: "group", TargetThroughputThreshold: 0.25}))
+
+	limiter, err := container.database.client.throughputControl().limiter(container.link, "group")
+	require.NoError(t, err)
+	require.Equal(t, float64(100), limiter.ruPerSecond)
+}
+
+func TestPriorityLevelHeaders(t *testing.T) {
+	itemHeaders := *(&ItemOptions{PriorityLevel: PriorityLevelLow.ToPtr()}).toHeaders()
+	require.Equal(t, "Low", itemHeaders[cosmosHeaderPriorityLevel])
+
+	queryHeaders := *(&QueryOptions{PriorityLevel: PriorityLevelHigh.ToPtr()}).toHeaders()
+	require.Equal(t, "High", queryHeaders[cosmosHeaderPriorityLevel])
+
+	_, ok := (*(&ItemOptions{}).toHeaders())[cosmosHeaderPriorityLevel]
+	require.False(t, ok)
+}
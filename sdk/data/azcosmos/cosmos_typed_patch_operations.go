@@ -0,0 +1,186 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TypedPatchOperations builds the PatchOperations of items of type T from the fields of T.
+// Fields are named by the Go names of the struct fields of T, separated by dots for nested fields, for example
+// "Address.City". Slice and array elements are named by their index, or "-" to add an element at the end, and map
+// entries by their key. The paths of the operations use the json struct tags of the fields, so T must be serialized
+// with its json struct tags.
+// The methods return an error when a field doesn't exist in T, or when a value can't be assigned to the field.
+// Numbers of any type, such as untyped constants, can be assigned to numeric fields.
+type TypedPatchOperations[T any] struct {
+	operations PatchOperations
+}
+
+// NewTypedPatchOperations returns empty patch operations for items of type T.
+func NewTypedPatchOperations[T any]() *TypedPatchOperations[T] {
+	return &TypedPatchOperations[T]{}
+}
+
+// Operations returns the patch operations, to use with ContainerClient.PatchItem or PatchItem.
+func (p *TypedPatchOperations[T]) Operations() PatchOperations {
+	return p.operations
+}
+
+// SetCondition sets condition for the patch request.
+func (p *TypedPatchOperations[T]) SetCondition(condition string) {
+	p.operations.SetCondition(condition)
+}
+
+// AppendReplace appends a replace operation of a field to the patch request.
+func (p *TypedPatchOperations[T]) AppendReplace(field string, value any) error {
+	path, err := patchPathOf[T](field, value)
+	if err != nil {
+		return err
+	}
+	p.operations.AppendReplace(path, value)
+	return nil
+}
+
+// AppendAdd appends an add operation of a field to the patch request.
+func (p *TypedPatchOperations[T]) AppendAdd(field string, value any) error {
+	path, err := patchPathOf[T](field, value)
+	if err != nil {
+		return err
+	}
+	p.operations.AppendAdd(path, value)
+	return nil
+}
+
+// AppendSet appends a set operation of a field to the patch request.
+func (p *TypedPatchOperations[T]) AppendSet(field string, value any) error {
+	path, err := patchPathOf[T](field, value)
+	if err != nil {
+		return err
+	}
+	p.operations.AppendSet(path, value)
+	return nil
+}
+
+// AppendRemove appends a remove operation of a field to the patch request.
+func (p *TypedPatchOperations[T]) AppendRemove(field string) error {
+	path, _, err := resolvePatchPath(reflect.TypeOf((*T)(nil)).Elem(), field)
+	if err != nil {
+		return err
+	}
+	p.operations.AppendRemove(path)
+	return nil
+}
+
+// AppendIncrement appends an increment operation of a numeric field to the patch request.
+func (p *TypedPatchOperations[T]) AppendIncrement(field string, value int64) error {
+	path, fieldType, err := resolvePatchPath(reflect.TypeOf((*T)(nil)).Elem(), field)
+	if err != nil {
+		return err
+	}
+	if kind := indirectType(fieldType).Kind(); !isNumericKind(kind) && kind != reflect.Interface {
+		return fmt.Errorf("field %s of type %s can't be incremented", field, fieldType)
+	}
+	p.operations.AppendIncrement(path, value)
+	return nil
+}
+
+// patchPathOf returns the patch path of a field of T, after checking the value can be assigned to the field.
+func patchPathOf[T any](field string, value any) (string, error) {
+	path, fieldType, err := resolvePatchPath(reflect.TypeOf((*T)(nil)).Elem(), field)
+	if err != nil {
+		return "", err
+	}
+	if value == nil {
+		return path, nil
+	}
+	valueType := reflect.TypeOf(value)
+	if !valueType.AssignableTo(fieldType) && !valueType.AssignableTo(indirectType(fieldType)) &&
+		!(isNumericKind(valueType.Kind()) && isNumericKind(indirectType(fieldType).Kind())) {
+		return "", fmt.Errorf("value of type %s can't be assigned to field %s of type %s", valueType, field, fieldType)
+	}
+	return path, nil
+}
+
+// isNumericKind reports whether values of a kind are serialized as JSON numbers.
+func isNumericKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// resolvePatchPath returns the JSON pointer and the type of a field of a type.
+func resolvePatchPath(t reflect.Type, field string) (string, reflect.Type, error) {
+	if field == "" {
+		return "", nil, fmt.Errorf("field is required")
+	}
+
+	var segments []string
+	for _, name := range strings.Split(field, ".") {
+		t = indirectType(t)
+		switch t.Kind() {
+		case reflect.Struct:
+			structField, ok := t.FieldByName(name)
+			if !ok || !structField.IsExported() {
+				return "", nil, fmt.Errorf("field %s doesn't exist in %s", name, t)
+			}
+			// Promoted fields of embedded structs are serialized in the embedding struct, unless the embedded struct is named.
+			embedding := t
+			for i, index := range structField.Index {
+				f := indirectType(embedding).Field(index)
+				jsonName, named := jsonFieldName(f)
+				if jsonName == "-" {
+					return "", nil, fmt.Errorf("field %s isn't serialized", f.Name)
+				}
+				if i == len(structField.Index)-1 || named {
+					segments = append(segments, jsonName)
+				}
+				embedding = f.Type
+			}
+			t = structField.Type
+		case reflect.Slice, reflect.Array:
+			if _, err := strconv.Atoi(name); err != nil && name != "-" {
+				return "", nil, fmt.Errorf("index %s of %s must be a number or -", name, t)
+			}
+			segments = append(segments, name)
+			t = t.Elem()
+		case reflect.Map:
+			segments = append(segments, name)
+			t = t.Elem()
+		case reflect.Interface:
+			// The content of interface values is unknown, the remaining names are used as is.
+			segments = append(segments, name)
+		default:
+			return "", nil, fmt.Errorf("field %s doesn't exist in %s", name, t)
+		}
+	}
+
+	for i, segment := range segments {
+		segments[i] = strings.NewReplacer("~", "~0", "/", "~1").Replace(segment)
+	}
+	return "/" + strings.Join(segments, "/"), t, nil
+}
+
+// jsonFieldName returns the name of a struct field in its JSON representation, and whether the name is set by a json tag.
+func jsonFieldName(f reflect.StructField) (string, bool) {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name, false
+	}
+	return name, true
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
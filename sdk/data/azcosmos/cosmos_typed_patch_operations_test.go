@@ -0,0 +1,89 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type typedPatchAddress struct {
+	City    string `json:"city"`
+	ZipCode string `json:"zip,omitempty"`
+}
+
+type typedPatchAudit struct {
+	UpdatedBy string `json:"updatedBy"`
+}
+
+type typedPatchItem struct {
+	typedPatchAudit
+	ID       string             `json:"id"`
+	Count    int64              `json:"count"`
+	Score    *float64           `json:"score,omitempty"`
+	Address  *typedPatchAddress `json:"address"`
+	Tags     []string           `json:"tags"`
+	Labels   map[string]string  `json:"labels"`
+	Extra    any                `json:"extra"`
+	Untagged string
+	Ignored  string `json:"-"`
+}
+
+func TestTypedPatchOperations(t *testing.T) {
+	ops := NewTypedPatchOperations[typedPatchItem]()
+	ops.SetCondition("FROM c WHERE c.count > 0")
+	require.NoError(t, ops.AppendSet("Address.City", "Seattle"))
+	require.NoError(t, ops.AppendReplace("Address", typedPatchAddress{City: "Redmond"}))
+	require.NoError(t, ops.AppendAdd("Tags.-", "new"))
+	require.NoError(t, ops.AppendRemove("Tags.0"))
+	require.NoError(t, ops.AppendIncrement("Count", 2))
+	require.NoError(t, ops.AppendIncrement("Score", 1))
+	require.NoError(t, ops.AppendSet("Labels.a/b", "value"))
+	require.NoError(t, ops.AppendSet("Extra.any.path", 1))
+	require.NoError(t, ops.AppendSet("Untagged", "value"))
+	require.NoError(t, ops.AppendSet("UpdatedBy", "user"))
+	require.NoError(t, ops.AppendSet("Address", nil))
+	// Untyped numeric constants are ints, and can be set to fields of any numeric type.
+	require.NoError(t, ops.AppendSet("Count", 5))
+	require.NoError(t, ops.AppendSet("Score", 1))
+	require.NoError(t, ops.AppendReplace("Score", 1.5))
+	require.NoError(t, ops.AppendAdd("Count", int32(7)))
+
+	data, err := json.Marshal(ops.Operations())
+	require.NoError(t, err)
+	require.JSONEq(t, `{"condition":"FROM c WHERE c.count > 0","operations":[
+		{"op":"set","path":"/address/city","value":"Seattle"},
+		{"op":"replace","path":"/address","value":{"city":"Redmond"}},
+		{"op":"add","path":"/tags/-","value":"new"},
+		{"op":"remove","path":"/tags/0"},
+		{"op":"incr","path":"/count","value":2},
+		{"op":"incr","path":"/score","value":1},
+		{"op":"set","path":"/labels/a~1b","value":"value"},
+		{"op":"set","path":"/extra/any/path","value":1},
+		{"op":"set","path":"/Untagged","value":"value"},
+		{"op":"set","path":"/updatedBy","value":"user"},
+		{"op":"set","path":"/address"},
+		{"op":"set","path":"/count","value":5},
+		{"op":"set","path":"/score","value":1},
+		{"op":"replace","path":"/score","value":1.5},
+		{"op":"add","path":"/count","value":7}]}`, string(data))
+}
+
+func TestTypedPatchOperationsErrors(t *testing.T) {
+	ops := NewTypedPatchOperations[typedPatchItem]()
+	require.Error(t, ops.AppendSet("", "value"))
+	require.Error(t, ops.AppendSet("Missing", "value"))
+	require.Error(t, ops.AppendSet("Address.Missing", "value"))
+	require.Error(t, ops.AppendSet("Ignored", "value"))
+	require.Error(t, ops.AppendSet("Count", "not a number"))
+	require.Error(t, ops.AppendSet("ID", 1))
+	require.Error(t, ops.AppendSet("Score", true))
+	require.Error(t, ops.AppendSet("Tags.first", "value"))
+	require.Error(t, ops.AppendSet("ID.Nested", "value"))
+	require.Error(t, ops.AppendIncrement("ID", 1))
+	require.Error(t, ops.AppendRemove("Missing"))
+	require.Empty(t, ops.Operations().operations)
+}
@@ -0,0 +1,403 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// UserClient lets you perform read, replace, and delete user operations.
+// It also lets you manage the permissions of the user, which grant access to resources through resource tokens.
+type UserClient struct {
+	// The Id of the Cosmos user
+	id string
+	// The database that contains the user
+	database *DatabaseClient
+	// The resource link
+	link string
+}
+
+func newUser(id string, database *DatabaseClient) (*UserClient, error) {
+	return &UserClient{
+		id:       id,
+		database: database,
+		link:     createLink(database.link, pathSegmentUser, id)}, nil
+}
+
+// ID returns the identifier of the Cosmos user.
+func (u *UserClient) ID() string {
+	return u.id
+}
+
+// NewUser returns a struct that represents the user and allows user and permission operations.
+// id - The id of the user.
+func (db *DatabaseClient) NewUser(id string) (*UserClient, error) {
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+
+	return newUser(id, db)
+}
+
+// CreateUser creates a user in the Cosmos database.
+// ctx - The context for the request.
+// userProperties - The properties for the user.
+// o - Options for the operation.
+func (db *DatabaseClient) CreateUser(
+	ctx context.Context,
+	userProperties UserProperties,
+	o *UserOptions) (UserResponse, error) {
+	if o == nil {
+		o = &UserOptions{}
+	}
+
+	azResponse, err := db.sendUserRequest(ctx, operationTypeCreate, resourceTypeUser, db.link, userProperties.ID, userProperties, o)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	return newUserResponse(azResponse)
+}
+
+// UpsertUser creates or replaces a user in the Cosmos database.
+// ctx - The context for the request.
+// userProperties - The properties for the user.
+// o - Options for the operation.
+func (db *DatabaseClient) UpsertUser(
+	ctx context.Context,
+	userProperties UserProperties,
+	o *UserOptions) (UserResponse, error) {
+	if o == nil {
+		o = &UserOptions{}
+	}
+
+	azResponse, err := db.sendUserRequest(ctx, operationTypeUpsert, resourceTypeUser, db.link, userProperties.ID, userProperties, o)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	return newUserResponse(azResponse)
+}
+
+// NewQueryUsersPager executes query for users within a database.
+// query - The SQL query to execute.
+// o - Options for the operation.
+func (db *DatabaseClient) NewQueryUsersPager(query string, o *QueryUsersOptions) *runtime.Pager[QueryUsersResponse] {
+	queryOptions := &QueryUsersOptions{}
+	if o != nil {
+		originalOptions := *o
+		queryOptions = &originalOptions
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceTypeUser,
+		resourceAddress: db.link,
+	}
+
+	path, _ := generatePathForNameBased(resourceTypeUser, operationContext.resourceAddress, true)
+
+	return runtime.NewPager(runtime.PagingHandler[QueryUsersResponse]{
+		More: func(page QueryUsersResponse) bool {
+			return page.ContinuationToken != nil
+		},
+		Fetcher: func(ctx context.Context, page *QueryUsersResponse) (QueryUsersResponse, error) {
+			var err error
+			spanName, err := db.getSpanForDatabases(operationTypeQuery, resourceTypeUser)
+			if err != nil {
+				return QueryUsersResponse{}, err
+			}
+			ctx, endSpan := runtime.StartSpan(ctx, spanName.name, db.client.internal.Tracer(), &spanName.options)
+			defer func() { endSpan(err) }()
+			if page != nil {
+				if page.ContinuationToken != nil {
+					// Use the previous page continuation if available
+					queryOptions.ContinuationToken = page.ContinuationToken
+				}
+			}
+
+			azResponse, err := db.client.sendQueryRequest(
+				path,
+				ctx,
+				query,
+				queryOptions.QueryParameters,
+				operationContext,
+				queryOptions,
+				nil)
+
+			if err != nil {
+				return QueryUsersResponse{}, err
+			}
+
+			return newUsersQueryResponse(azResponse)
+		},
+	})
+}
+
+// Read obtains the information for a Cosmos user.
+// ctx - The context for the request.
+// o - Options for the operation.
+func (u *UserClient) Read(
+	ctx context.Context,
+	o *UserOptions) (UserResponse, error) {
+	if o == nil {
+		o = &UserOptions{}
+	}
+
+	azResponse, err := u.database.sendUserRequest(ctx, operationTypeRead, resourceTypeUser, u.link, u.id, nil, o)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	return newUserResponse(azResponse)
+}
+
+// Replace replaces the properties of a Cosmos user, which renames the user when userProperties.ID is different.
+// ctx - The context for the request.
+// userProperties - The new properties of the user.
+// o - Options for the operation.
+func (u *UserClient) Replace(
+	ctx context.Context,
+	userProperties UserProperties,
+	o *UserOptions) (UserResponse, error) {
+	if o == nil {
+		o = &UserOptions{}
+	}
+
+	azResponse, err := u.database.sendUserRequest(ctx, operationTypeReplace, resourceTypeUser, u.link, u.id, userProperties, o)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	return newUserResponse(azResponse)
+}
+
+// Delete deletes a Cosmos user and its permissions.
+// ctx - The context for the request.
+// o - Options for the operation.
+func (u *UserClient) Delete(
+	ctx context.Context,
+	o *UserOptions) (UserResponse, error) {
+	if o == nil {
+		o = &UserOptions{}
+	}
+
+	azResponse, err := u.database.sendUserRequest(ctx, operationTypeDelete, resourceTypeUser, u.link, u.id, nil, o)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	return newUserResponse(azResponse)
+}
+
+// CreatePermission creates a permission for the user.
+// The response contains the resource token of the permission in PermissionProperties.Token.
+// ctx - The context for the request.
+// permissionProperties - The properties for the permission.
+// o - Options for the operation.
+func (u *UserClient) CreatePermission(
+	ctx context.Context,
+	permissionProperties PermissionProperties,
+	o *PermissionOptions) (PermissionResponse, error) {
+	if o == nil {
+		o = &PermissionOptions{}
+	}
+
+	azResponse, err := u.database.sendUserRequest(ctx, operationTypeCreate, resourceTypePermission, u.link, permissionProperties.ID, permissionProperties, o)
+	if err != nil {
+		return PermissionResponse{}, err
+	}
+
+	return newPermissionResponse(azResponse)
+}
+
+// UpsertPermission creates or replaces a permission of the user.
+// ctx - The context for the request.
+// permissionProperties - The properties for the permission.
+// o - Options for the operation.
+func (u *UserClient) UpsertPermission(
+	ctx context.Context,
+	permissionProperties PermissionProperties,
+	o *PermissionOptions) (PermissionResponse, error) {
+	if o == nil {
+		o = &PermissionOptions{}
+	}
+
+	azResponse, err := u.database.sendUserRequest(ctx, operationTypeUpsert, resourceTypePermission, u.link, permissionProperties.ID, permissionProperties, o)
+	if err != nil {
+		return PermissionResponse{}, err
+	}
+
+	return newPermissionResponse(azResponse)
+}
+
+// ReadPermission reads a permission of the user, with a new resource token.
+// ctx - The context for the request.
+// permissionID - The id of the permission.
+// o - Options for the operation.
+func (u *UserClient) ReadPermission(
+	ctx context.Context,
+	permissionID string,
+	o *PermissionOptions) (PermissionResponse, error) {
+	if o == nil {
+		o = &PermissionOptions{}
+	}
+
+	azResponse, err := u.database.sendUserRequest(ctx, operationTypeRead, resourceTypePermission, createLink(u.link, pathSegmentPermission, permissionID), permissionID, nil, o)
+	if err != nil {
+		return PermissionResponse{}, err
+	}
+
+	return newPermissionResponse(azResponse)
+}
+
+// ReplacePermission replaces the permission of the user with the id of the properties.
+// ctx - The context for the request.
+// permissionProperties - The new properties of the permission.
+// o - Options for the operation.
+func (u *UserClient) ReplacePermission(
+	ctx context.Context,
+	permissionProperties PermissionProperties,
+	o *PermissionOptions) (PermissionResponse, error) {
+	if o == nil {
+		o = &PermissionOptions{}
+	}
+
+	azResponse, err := u.database.sendUserRequest(ctx, operationTypeReplace, resourceTypePermission, createLink(u.link, pathSegmentPermission, permissionProperties.ID), permissionProperties.ID, permissionProperties, o)
+	if err != nil {
+		return PermissionResponse{}, err
+	}
+
+	return newPermissionResponse(azResponse)
+}
+
+// DeletePermission deletes a permission of the user, which revokes its resource tokens.
+// ctx - The context for the request.
+// permissionID - The id of the permission.
+// o - Options for the operation.
+func (u *UserClient) DeletePermission(
+	ctx context.Context,
+	permissionID string,
+	o *PermissionOptions) (PermissionResponse, error) {
+	if o == nil {
+		o = &PermissionOptions{}
+	}
+
+	azResponse, err := u.database.sendUserRequest(ctx, operationTypeDelete, resourceTypePermission, createLink(u.link, pathSegmentPermission, permissionID), permissionID, nil, o)
+	if err != nil {
+		return PermissionResponse{}, err
+	}
+
+	return newPermissionResponse(azResponse)
+}
+
+// NewQueryPermissionsPager executes query for permissions of the user.
+// query - The SQL query to execute.
+// o - Options for the operation.
+func (u *UserClient) NewQueryPermissionsPager(query string, o *QueryPermissionsOptions) *runtime.Pager[QueryPermissionsResponse] {
+	queryOptions := &QueryPermissionsOptions{}
+	if o != nil {
+		originalOptions := *o
+		queryOptions = &originalOptions
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceTypePermission,
+		resourceAddress: u.link,
+	}
+
+	path, _ := generatePathForNameBased(resourceTypePermission, operationContext.resourceAddress, true)
+
+	return runtime.NewPager(runtime.PagingHandler[QueryPermissionsResponse]{
+		More: func(page QueryPermissionsResponse) bool {
+			return page.ContinuationToken != nil
+		},
+		Fetcher: func(ctx context.Context, page *QueryPermissionsResponse) (QueryPermissionsResponse, error) {
+			var err error
+			spanName, err := u.database.getSpanForDatabases(operationTypeQuery, resourceTypePermission)
+			if err != nil {
+				return QueryPermissionsResponse{}, err
+			}
+			ctx, endSpan := runtime.StartSpan(ctx, spanName.name, u.database.client.internal.Tracer(), &spanName.options)
+			defer func() { endSpan(err) }()
+			if page != nil {
+				if page.ContinuationToken != nil {
+					// Use the previous page continuation if available
+					queryOptions.ContinuationToken = page.ContinuationToken
+				}
+			}
+
+			azResponse, err := u.database.client.sendQueryRequest(
+				path,
+				ctx,
+				query,
+				queryOptions.QueryParameters,
+				operationContext,
+				queryOptions,
+				nil)
+
+			if err != nil {
+				return QueryPermissionsResponse{}, err
+			}
+
+			return newPermissionsQueryResponse(azResponse)
+		},
+	})
+}
+
+// sendUserRequest sends a request for a user or a permission of the database. Create and upsert requests are sent to
+// the feed of ownerOrResourceLink, other requests to the resource itself.
+func (db *DatabaseClient) sendUserRequest(
+	ctx context.Context,
+	operationType operationType,
+	resourceType resourceType,
+	ownerOrResourceLink string,
+	id string,
+	properties any,
+	o cosmosRequestOptions) (*http.Response, error) {
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	var err error
+	spanName, err := db.getSpanForDatabases(operationType, resourceType)
+	if err != nil {
+		return nil, err
+	}
+	ctx, endSpan := runtime.StartSpan(ctx, spanName.name, db.client.internal.Tracer(), &spanName.options)
+	defer func() { endSpan(err) }()
+
+	isFeed := operationType == operationTypeCreate || operationType == operationTypeUpsert
+	operationContext := pipelineRequestOptions{
+		resourceType:     resourceType,
+		resourceAddress:  ownerOrResourceLink,
+		isWriteOperation: operationType != operationTypeRead,
+	}
+
+	path, err := generatePathForNameBased(resourceType, operationContext.resourceAddress, isFeed)
+	if err != nil {
+		return nil, err
+	}
+
+	var azResponse *http.Response
+	switch operationType {
+	case operationTypeCreate:
+		azResponse, err = db.client.sendPostRequest(path, ctx, properties, operationContext, o, nil)
+	case operationTypeUpsert:
+		addHeader := func(r *policy.Request) {
+			r.Raw().Header.Add(cosmosHeaderIsUpsert, "true")
+		}
+		azResponse, err = db.client.sendPostRequest(path, ctx, properties, operationContext, o, addHeader)
+	case operationTypeReplace:
+		azResponse, err = db.client.sendPutRequest(path, ctx, properties, operationContext, o, nil)
+	case operationTypeDelete:
+		azResponse, err = db.client.sendDeleteRequest(path, ctx, operationContext, o, nil)
+	default:
+		azResponse, err = db.client.sendGetRequest(path, ctx, operationContext, o, nil)
+	}
+	return azResponse, err
+}
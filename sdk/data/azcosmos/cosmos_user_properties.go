@@ -0,0 +1,146 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// UserProperties represents the properties of a user.
+type UserProperties struct {
+	// ID contains the unique id of the user.
+	ID string `json:"id"`
+	// ETag contains the entity etag of the user.
+	ETag *azcore.ETag `json:"_etag,omitempty"`
+	// SelfLink contains the self-link of the user.
+	SelfLink string `json:"_self,omitempty"`
+	// ResourceID contains the resource id of the user.
+	ResourceID string `json:"_rid,omitempty"`
+	// LastModified contains the last modified time of the user.
+	LastModified time.Time `json:"_ts,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (up UserProperties) MarshalJSON() ([]byte, error) {
+	return json.Marshal(userPropertiesJSON{
+		ID:         up.ID,
+		ETag:       up.ETag,
+		SelfLink:   up.SelfLink,
+		ResourceID: up.ResourceID,
+		Timestamp:  unixTimestamp(up.LastModified),
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (up *UserProperties) UnmarshalJSON(b []byte) error {
+	var properties userPropertiesJSON
+	if err := json.Unmarshal(b, &properties); err != nil {
+		return err
+	}
+	up.ID, up.ETag, up.SelfLink, up.ResourceID = properties.ID, properties.ETag, properties.SelfLink, properties.ResourceID
+	up.LastModified = timeFromUnixTimestamp(properties.Timestamp)
+	return nil
+}
+
+type userPropertiesJSON struct {
+	ID         string       `json:"id"`
+	ETag       *azcore.ETag `json:"_etag,omitempty"`
+	SelfLink   string       `json:"_self,omitempty"`
+	ResourceID string       `json:"_rid,omitempty"`
+	Timestamp  int64        `json:"_ts,omitempty"`
+}
+
+// PermissionProperties represents the properties of a permission, which grants a user access to a resource.
+type PermissionProperties struct {
+	// ID contains the unique id of the permission.
+	ID string
+	// PermissionMode contains the access granted on the resource.
+	PermissionMode PermissionMode
+	// ResourceLink contains the link of the resource the permission applies to, such as dbs/<database>/colls/<container>.
+	ResourceLink string
+	// ResourcePartitionKey optionally restricts the permission to the items of a logical partition of the container.
+	ResourcePartitionKey *PartitionKey
+	// Token contains the resource token generated by the service for the permission.
+	// It is used to authenticate with NewResourceTokenCredential.
+	Token string
+	// ETag contains the entity etag of the permission.
+	ETag *azcore.ETag
+	// SelfLink contains the self-link of the permission.
+	SelfLink string
+	// ResourceID contains the resource id of the permission.
+	ResourceID string
+	// LastModified contains the last modified time of the permission.
+	LastModified time.Time
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (pp PermissionProperties) MarshalJSON() ([]byte, error) {
+	properties := permissionPropertiesJSON{
+		ID:             pp.ID,
+		PermissionMode: pp.PermissionMode,
+		ResourceLink:   pp.ResourceLink,
+		Token:          pp.Token,
+		ETag:           pp.ETag,
+		SelfLink:       pp.SelfLink,
+		ResourceID:     pp.ResourceID,
+		Timestamp:      unixTimestamp(pp.LastModified),
+	}
+	if pp.ResourcePartitionKey != nil {
+		pk, err := pp.ResourcePartitionKey.toJsonString()
+		if err != nil {
+			return nil, err
+		}
+		properties.ResourcePartitionKey = json.RawMessage(pk)
+	}
+	return json.Marshal(properties)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (pp *PermissionProperties) UnmarshalJSON(b []byte) error {
+	var properties permissionPropertiesJSON
+	if err := json.Unmarshal(b, &properties); err != nil {
+		return err
+	}
+	pp.ID, pp.PermissionMode, pp.ResourceLink, pp.Token = properties.ID, properties.PermissionMode, properties.ResourceLink, properties.Token
+	pp.ETag, pp.SelfLink, pp.ResourceID = properties.ETag, properties.SelfLink, properties.ResourceID
+	pp.LastModified = timeFromUnixTimestamp(properties.Timestamp)
+	pp.ResourcePartitionKey = nil
+	if len(properties.ResourcePartitionKey) > 0 && string(properties.ResourcePartitionKey) != "null" {
+		var values []interface{}
+		if err := json.Unmarshal(properties.ResourcePartitionKey, &values); err != nil {
+			return err
+		}
+		pp.ResourcePartitionKey = &PartitionKey{values: values}
+	}
+	return nil
+}
+
+type permissionPropertiesJSON struct {
+	ID                   string          `json:"id"`
+	PermissionMode       PermissionMode  `json:"permissionMode,omitempty"`
+	ResourceLink         string          `json:"resource,omitempty"`
+	ResourcePartitionKey json.RawMessage `json:"resourcePartitionKey,omitempty"`
+	Token                string          `json:"_token,omitempty"`
+	ETag                 *azcore.ETag    `json:"_etag,omitempty"`
+	SelfLink             string          `json:"_self,omitempty"`
+	ResourceID           string          `json:"_rid,omitempty"`
+	Timestamp            int64           `json:"_ts,omitempty"`
+}
+
+func unixTimestamp(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func timeFromUnixTimestamp(timestamp int64) time.Time {
+	if timestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(timestamp, 0)
+}
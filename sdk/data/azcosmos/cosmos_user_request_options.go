@@ -0,0 +1,84 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"strconv"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// UserOptions are options for the create, upsert, read, replace and delete user operations.
+type UserOptions struct {
+	// IfMatchEtag is used to ensure optimistic concurrency control.
+	IfMatchEtag *azcore.ETag
+}
+
+func (options *UserOptions) toHeaders() *map[string]string {
+	return ifMatchEtagHeaders(options.IfMatchEtag)
+}
+
+// PermissionOptions are options for the create, upsert, read, replace and delete permission operations.
+type PermissionOptions struct {
+	// IfMatchEtag is used to ensure optimistic concurrency control.
+	IfMatchEtag *azcore.ETag
+	// ResourceTokenExpirySeconds sets the validity of the resource token returned in PermissionProperties.Token.
+	// The service defaults to one hour.
+	ResourceTokenExpirySeconds int32
+}
+
+func (options *PermissionOptions) toHeaders() *map[string]string {
+	headers := ifMatchEtagHeaders(options.IfMatchEtag)
+	if options.ResourceTokenExpirySeconds <= 0 {
+		return headers
+	}
+
+	if headers == nil {
+		headers = &map[string]string{}
+	}
+	(*headers)[cosmosHeaderResourceTokenExpiry] = strconv.FormatInt(int64(options.ResourceTokenExpirySeconds), 10)
+	return headers
+}
+
+// QueryUsersOptions are options to query users
+type QueryUsersOptions struct {
+	// ContinuationToken to be used to continue a previous query execution.
+	// Obtained from QueryUsersResponse.ContinuationToken.
+	ContinuationToken *string
+
+	// QueryParameters allows execution of parametrized queries.
+	// See https://docs.microsoft.com/azure/cosmos-db/sql/sql-query-parameterized-queries
+	QueryParameters []QueryParameter
+}
+
+func (options *QueryUsersOptions) toHeaders() *map[string]string {
+	headers := make(map[string]string)
+
+	if options.ContinuationToken != nil {
+		headers[cosmosHeaderContinuationToken] = *options.ContinuationToken
+	}
+
+	return &headers
+}
+
+// QueryPermissionsOptions are options to query permissions
+type QueryPermissionsOptions struct {
+	// ContinuationToken to be used to continue a previous query execution.
+	// Obtained from QueryPermissionsResponse.ContinuationToken.
+	ContinuationToken *string
+
+	// QueryParameters allows execution of parametrized queries.
+	// See https://docs.microsoft.com/azure/cosmos-db/sql/sql-query-parameterized-queries
+	QueryParameters []QueryParameter
+}
+
+func (options *QueryPermissionsOptions) toHeaders() *map[string]string {
+	headers := make(map[string]string)
+
+	if options.ContinuationToken != nil {
+		headers[cosmosHeaderContinuationToken] = *options.ContinuationToken
+	}
+
+	return &headers
+}
@@ -0,0 +1,316 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func newUsersTestDatabase(t *testing.T, srv *mock.Server, verifier *pipelineVerifier) *DatabaseClient {
+	return newTestContainer(t, srv, nil, azruntime.PipelineOptions{PerCall: []policy.Policy{verifier}}, policy.RetryOptions{}).database
+}
+
+func TestDatabaseCreateUser(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"id":"user1","_rid":"someRid","_etag":"someEtag","_ts":1700000000}`)),
+		mock.WithHeader(cosmosHeaderActivityId, "someActivityId"),
+		mock.WithHeader(cosmosHeaderRequestCharge, "13.42"),
+		mock.WithStatusCode(201))
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"id":"user1"}`)),
+		mock.WithStatusCode(200))
+
+	verifier := pipelineVerifier{}
+	database := newUsersTestDatabase(t, srv, &verifier)
+
+	resp, err := database.CreateUser(context.TODO(), UserProperties{ID: "user1"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if resp.UserProperties == nil || resp.UserProperties.ID != "user1" || resp.UserProperties.ResourceID != "someRid" {
+		t.Errorf("Unexpected user properties %v", resp.UserProperties)
+	}
+
+	if resp.UserProperties.LastModified.Unix() != 1700000000 {
+		t.Errorf("Expected LastModified to be %d, but got %d", 1700000000, resp.UserProperties.LastModified.Unix())
+	}
+
+	if resp.RequestCharge != 13.42 {
+		t.Errorf("Expected RequestCharge to be %f, but got %f", 13.42, resp.RequestCharge)
+	}
+
+	_, err = database.UpsertUser(context.TODO(), UserProperties{ID: "user1"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to upsert user: %v", err)
+	}
+
+	for i, request := range verifier.requests {
+		if request.method != http.MethodPost {
+			t.Errorf("Expected method to be %s, but got %s", http.MethodPost, request.method)
+		}
+
+		if request.url.RequestURI() != "/dbs/databaseId/users" {
+			t.Errorf("Expected url to be %s, but got %s", "/dbs/databaseId/users", request.url.RequestURI())
+		}
+
+		if request.body != `{"id":"user1"}` {
+			t.Errorf("Expected body to be %s, but got %s", `{"id":"user1"}`, request.body)
+		}
+
+		isUpsert := request.headers.Get(cosmosHeaderIsUpsert) == "true"
+		if isUpsert != (i == 1) {
+			t.Errorf("Expected upsert header only on the upsert request, request %d has it %v", i, isUpsert)
+		}
+	}
+
+	if _, err := database.NewUser(""); err == nil {
+		t.Error("Expected an error for an empty id")
+	}
+}
+
+func TestUserReadReplaceDelete(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"user1"}`)), mock.WithStatusCode(200))
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"user2"}`)), mock.WithStatusCode(200))
+	srv.AppendResponse(mock.WithStatusCode(204))
+
+	verifier := pipelineVerifier{}
+	database := newUsersTestDatabase(t, srv, &verifier)
+	user, _ := database.NewUser("user1")
+
+	if user.ID() != "user1" {
+		t.Errorf("Expected user ID to be %s, but got %s", "user1", user.ID())
+	}
+
+	if _, err := user.Read(context.TODO(), nil); err != nil {
+		t.Fatalf("Failed to read user: %v", err)
+	}
+
+	replaced, err := user.Replace(context.TODO(), UserProperties{ID: "user2"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to replace user: %v", err)
+	}
+	if replaced.UserProperties.ID != "user2" {
+		t.Errorf("Expected replaced user ID to be %s, but got %s", "user2", replaced.UserProperties.ID)
+	}
+
+	deleted, err := user.Delete(context.TODO(), nil)
+	if err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	if deleted.UserProperties != nil {
+		t.Errorf("Expected no user properties, but got %v", deleted.UserProperties)
+	}
+
+	expectedMethods := []string{http.MethodGet, http.MethodPut, http.MethodDelete}
+	for i, request := range verifier.requests {
+		if request.method != expectedMethods[i] {
+			t.Errorf("Expected method to be %s, but got %s", expectedMethods[i], request.method)
+		}
+
+		if request.url.RequestURI() != "/dbs/databaseId/users/user1" {
+			t.Errorf("Expected url to be %s, but got %s", "/dbs/databaseId/users/user1", request.url.RequestURI())
+		}
+	}
+
+	if verifier.requests[1].body != `{"id":"user2"}` {
+		t.Errorf("Expected body to be %s, but got %s", `{"id":"user2"}`, verifier.requests[1].body)
+	}
+}
+
+func TestUserPermissions(t *testing.T) {
+	permissionJSON := []byte(`{"id":"perm1","permissionMode":"Read","resource":"dbs/databaseId/colls/containerId","resourcePartitionKey":["tenant1"],"_token":"type=resource&ver=1&sig=abc"}`)
+
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.AppendResponse(mock.WithBody(permissionJSON), mock.WithStatusCode(201))
+	srv.AppendResponse(mock.WithBody(permissionJSON), mock.WithStatusCode(200))
+	srv.AppendResponse(mock.WithBody(permissionJSON), mock.WithStatusCode(200))
+	srv.AppendResponse(mock.WithBody(permissionJSON), mock.WithStatusCode(200))
+	srv.AppendResponse(mock.WithStatusCode(204))
+
+	verifier := pipelineVerifier{}
+	database := newUsersTestDatabase(t, srv, &verifier)
+	user, _ := database.NewUser("user1")
+
+	pk := NewPartitionKeyString("tenant1")
+	properties := PermissionProperties{
+		ID:                   "perm1",
+		PermissionMode:       PermissionModeRead,
+		ResourceLink:         "dbs/databaseId/colls/containerId",
+		ResourcePartitionKey: &pk,
+	}
+
+	resp, err := user.CreatePermission(context.TODO(), properties, &PermissionOptions{ResourceTokenExpirySeconds: 600})
+	if err != nil {
+		t.Fatalf("Failed to create permission: %v", err)
+	}
+
+	if resp.PermissionProperties.Token != "type=resource&ver=1&sig=abc" {
+		t.Errorf("Expected token to be %s, but got %s", "type=resource&ver=1&sig=abc", resp.PermissionProperties.Token)
+	}
+
+	if resp.PermissionProperties.ResourcePartitionKey == nil {
+		t.Fatal("Expected a resource partition key")
+	}
+
+	if pkJSON, _ := resp.PermissionProperties.ResourcePartitionKey.toJsonString(); pkJSON != `["tenant1"]` {
+		t.Errorf("Expected resource partition key to be %s, but got %s", `["tenant1"]`, pkJSON)
+	}
+
+	if _, err := user.UpsertPermission(context.TODO(), properties, nil); err != nil {
+		t.Fatalf("Failed to upsert permission: %v", err)
+	}
+
+	if _, err := user.ReadPermission(context.TODO(), "perm1", nil); err != nil {
+		t.Fatalf("Failed to read permission: %v", err)
+	}
+
+	if _, err := user.ReplacePermission(context.TODO(), properties, nil); err != nil {
+		t.Fatalf("Failed to replace permission: %v", err)
+	}
+
+	if _, err := user.DeletePermission(context.TODO(), "perm1", nil); err != nil {
+		t.Fatalf("Failed to delete permission: %v", err)
+	}
+
+	expected := []struct {
+		method string
+		uri    string
+	}{
+		{http.MethodPost, "/dbs/databaseId/users/user1/permissions"},
+		{http.MethodPost, "/dbs/databaseId/users/user1/permissions"},
+		{http.MethodGet, "/dbs/databaseId/users/user1/permissions/perm1"},
+		{http.MethodPut, "/dbs/databaseId/users/user1/permissions/perm1"},
+		{http.MethodDelete, "/dbs/databaseId/users/user1/permissions/perm1"},
+	}
+
+	if len(verifier.requests) != len(expected) {
+		t.Fatalf("Expected %d requests, got %d", len(expected), len(verifier.requests))
+	}
+
+	for i, e := range expected {
+		if verifier.requests[i].method != e.method {
+			t.Errorf("Expected method of request %d to be %s, but got %s", i, e.method, verifier.requests[i].method)
+		}
+		if verifier.requests[i].url.RequestURI() != e.uri {
+			t.Errorf("Expected url of request %d to be %s, but got %s", i, e.uri, verifier.requests[i].url.RequestURI())
+		}
+	}
+
+	expectedBody := `{"id":"perm1","permissionMode":"Read","resource":"dbs/databaseId/colls/containerId","resourcePartitionKey":["tenant1"]}`
+	if verifier.requests[0].body != expectedBody {
+		t.Errorf("Expected body to be %s, but got %s", expectedBody, verifier.requests[0].body)
+	}
+
+	if verifier.requests[0].headers.Get(cosmosHeaderResourceTokenExpiry) != "600" {
+		t.Errorf("Expected token expiry to be %s, but got %s", "600", verifier.requests[0].headers.Get(cosmosHeaderResourceTokenExpiry))
+	}
+
+	if verifier.requests[1].headers.Get(cosmosHeaderIsUpsert) != "true" {
+		t.Error("Expected upsert header on the upsert request")
+	}
+}
+
+func TestQueryUsersAndPermissions(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"Users":[{"id":"user1"},{"id":"user2"}]}`)),
+		mock.WithHeader(cosmosHeaderContinuationToken, "someContinuationToken"),
+		mock.WithStatusCode(200))
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"Users":[{"id":"user3"}]}`)),
+		mock.WithStatusCode(200))
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"Permissions":[{"id":"perm1","permissionMode":"All","resource":"dbs/databaseId/colls/containerId","_token":"token"}]}`)),
+		mock.WithStatusCode(200))
+
+	verifier := pipelineVerifier{}
+	database := newUsersTestDatabase(t, srv, &verifier)
+
+	var users []string
+	pager := database.NewQueryUsersPager("SELECT * FROM u", nil)
+	for pager.More() {
+		page, err := pager.NextPage(context.TODO())
+		if err != nil {
+			t.Fatalf("Failed to query users: %v", err)
+		}
+		for _, user := range page.Users {
+			users = append(users, user.ID)
+		}
+	}
+
+	if len(users) != 3 || users[2] != "user3" {
+		t.Fatalf("Unexpected users %v", users)
+	}
+
+	user, _ := database.NewUser("user1")
+	permissions, err := user.NewQueryPermissionsPager("SELECT * FROM p", nil).NextPage(context.TODO())
+	if err != nil {
+		t.Fatalf("Failed to query permissions: %v", err)
+	}
+
+	if len(permissions.Permissions) != 1 || permissions.Permissions[0].PermissionMode != PermissionModeAll || permissions.Permissions[0].ResourcePartitionKey != nil {
+		t.Errorf("Unexpected permissions %v", permissions.Permissions)
+	}
+
+	expectedURIs := []string{"/dbs/databaseId/users", "/dbs/databaseId/users", "/dbs/databaseId/users/user1/permissions"}
+	for i, request := range verifier.requests {
+		if !request.isQuery {
+			t.Errorf("Expected request %d to be a query", i)
+		}
+		if request.url.RequestURI() != expectedURIs[i] {
+			t.Errorf("Expected url to be %s, but got %s", expectedURIs[i], request.url.RequestURI())
+		}
+	}
+
+	if verifier.requests[1].headers.Get(cosmosHeaderContinuationToken) != "someContinuationToken" {
+		t.Errorf("Expected ContinuationToken to be %s, but got %s", "someContinuationToken", verifier.requests[1].headers.Get(cosmosHeaderContinuationToken))
+	}
+}
+
+func TestPermissionPropertiesSerialization(t *testing.T) {
+	etag := azcore.ETag("someETag")
+	pk := NewPartitionKeyString("a").AppendNumber(1)
+	properties := PermissionProperties{
+		ID:                   "perm1",
+		PermissionMode:       PermissionModeAll,
+		ResourceLink:         "dbs/db/colls/c",
+		ResourcePartitionKey: &pk,
+		Token:                "someToken",
+		ETag:                 &etag,
+	}
+
+	jsonString, err := json.Marshal(properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherProperties := &PermissionProperties{}
+	if err := json.Unmarshal(jsonString, otherProperties); err != nil {
+		t.Fatal(err, string(jsonString))
+	}
+
+	if otherProperties.ID != "perm1" || otherProperties.PermissionMode != PermissionModeAll || otherProperties.ResourceLink != "dbs/db/colls/c" || otherProperties.Token != "someToken" || *otherProperties.ETag != etag {
+		t.Errorf("Unexpected properties %v", *otherProperties)
+	}
+
+	pkJSON, _ := otherProperties.ResourcePartitionKey.toJsonString()
+	if pkJSON != `["a",1]` {
+		t.Errorf("Expected resource partition key to be %s, but got %s", `["a",1]`, pkJSON)
+	}
+}
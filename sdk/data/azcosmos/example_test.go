@@ -925,3 +925,60 @@ func retryOptimisticConcurrency(retryAttempts int, wait time.Duration, retry fun
 	}
 	return fmt.Errorf("Cosmos DB retry attempts %d, error: %s", retryAttempts, result)
 }
+
+func ExampleReadItem() {
+	endpoint, ok := os.LookupEnv("AZURE_COSMOS_ENDPOINT")
+	if !ok {
+		panic("AZURE_COSMOS_ENDPOINT could not be found")
+	}
+
+	key, ok := os.LookupEnv("AZURE_COSMOS_KEY")
+	if !ok {
+		panic("AZURE_COSMOS_KEY could not be found")
+	}
+
+	cred, err := azcosmos.NewKeyCredential(key)
+	if err != nil {
+		panic(err)
+	}
+
+	client, err := azcosmos.NewClientWithKey(endpoint, cred, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	container, err := client.NewContainer("databaseName", "aContainer")
+	if err != nil {
+		panic(err)
+	}
+
+	type Item struct {
+		azcosmos.SystemProperties
+		ID             string `json:"id"`
+		Value          string `json:"value"`
+		MyPartitionKey string `json:"myPartitionKey"`
+	}
+
+	pk := azcosmos.NewPartitionKeyString("newPartitionKey")
+	_, err = azcosmos.UpsertItem(context.Background(), container, pk, Item{ID: "anId", Value: "2", MyPartitionKey: "newPartitionKey"}, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	itemResponse, err := azcosmos.ReadItem[Item](context.Background(), container, pk, "anId", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// Patch the item using the fields of its type.
+	ops := azcosmos.NewTypedPatchOperations[Item]()
+	if err := ops.AppendSet("Value", "3"); err != nil {
+		panic(err)
+	}
+	_, err = azcosmos.PatchItem[Item](context.Background(), container, pk, "anId", ops.Operations(), &azcosmos.ItemOptions{IfMatchEtag: &itemResponse.Item.ETag})
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Item %s read with value %s, last modified at %v", itemResponse.Item.ID, itemResponse.Item.Value, itemResponse.Item.LastModified())
+}
@@ -7,12 +7,14 @@ package azcosmos
 type operationType int
 
 const (
-	operationTypeCreate  operationType = 0
-	operationTypePatch   operationType = 1
-	operationTypeRead    operationType = 2
-	operationTypeReplace operationType = 5
-	operationTypeDelete  operationType = 4
-	operationTypeUpsert  operationType = 20
-	operationTypeQuery   operationType = 15
-	operationTypeBatch   operationType = 40
+	operationTypeCreate            operationType = 0
+	operationTypePatch             operationType = 1
+	operationTypeRead              operationType = 2
+	operationTypeReadFeed          operationType = 3
+	operationTypeReplace           operationType = 5
+	operationTypeDelete            operationType = 4
+	operationTypeExecuteJavaScript operationType = 6
+	operationTypeUpsert            operationType = 20
+	operationTypeQuery             operationType = 15
+	operationTypeBatch             operationType = 40
 )
@@ -34,6 +34,37 @@ const (
 	otelSpanNamePatchItem                   = "patch_item"
 	otelSpanNameQueryItems                  = "query_items"
 	otelSpanNamePartitionKeyRanges          = "read_partition_key_ranges"
+	otelSpanNameCreateStoredProcedure       = "create_stored_procedure"
+	otelSpanNameReadStoredProcedure         = "read_stored_procedure"
+	otelSpanNameReplaceStoredProcedure      = "replace_stored_procedure"
+	otelSpanNameDeleteStoredProcedure       = "delete_stored_procedure"
+	otelSpanNameReadAllStoredProcedures     = "read_all_stored_procedures"
+	otelSpanNameQueryStoredProcedures       = "query_stored_procedures"
+	otelSpanNameExecuteStoredProcedure      = "execute_stored_procedure"
+	otelSpanNameCreateTrigger               = "create_trigger"
+	otelSpanNameReadTrigger                 = "read_trigger"
+	otelSpanNameReplaceTrigger              = "replace_trigger"
+	otelSpanNameDeleteTrigger               = "delete_trigger"
+	otelSpanNameReadAllTriggers             = "read_all_triggers"
+	otelSpanNameQueryTriggers               = "query_triggers"
+	otelSpanNameCreateUserDefinedFunction   = "create_user_defined_function"
+	otelSpanNameReadUserDefinedFunction     = "read_user_defined_function"
+	otelSpanNameReplaceUserDefinedFunction  = "replace_user_defined_function"
+	otelSpanNameDeleteUserDefinedFunction   = "delete_user_defined_function"
+	otelSpanNameReadAllUserDefinedFunctions = "read_all_user_defined_functions"
+	otelSpanNameQueryUserDefinedFunctions   = "query_user_defined_functions"
+	otelSpanNameCreateUser                  = "create_user"
+	otelSpanNameUpsertUser                  = "upsert_user"
+	otelSpanNameReadUser                    = "read_user"
+	otelSpanNameReplaceUser                 = "replace_user"
+	otelSpanNameDeleteUser                  = "delete_user"
+	otelSpanNameQueryUsers                  = "query_users"
+	otelSpanNameCreatePermission            = "create_permission"
+	otelSpanNameUpsertPermission            = "upsert_permission"
+	otelSpanNameReadPermission              = "read_permission"
+	otelSpanNameReplacePermission           = "replace_permission"
+	otelSpanNameDeletePermission            = "delete_permission"
+	otelSpanNameQueryPermissions            = "query_permissions"
 )
 
 type span struct {
@@ -69,6 +100,36 @@ func getSpanNameForDatabases(endpoint *url.URL, operationType operationType, res
 		if operationType == operationTypeQuery {
 			spanName = otelSpanNameQueryContainers
 		}
+	case resourceTypeUser:
+		switch operationType {
+		case operationTypeCreate:
+			spanName = otelSpanNameCreateUser
+		case operationTypeUpsert:
+			spanName = otelSpanNameUpsertUser
+		case operationTypeRead:
+			spanName = otelSpanNameReadUser
+		case operationTypeReplace:
+			spanName = otelSpanNameReplaceUser
+		case operationTypeDelete:
+			spanName = otelSpanNameDeleteUser
+		case operationTypeQuery:
+			spanName = otelSpanNameQueryUsers
+		}
+	case resourceTypePermission:
+		switch operationType {
+		case operationTypeCreate:
+			spanName = otelSpanNameCreatePermission
+		case operationTypeUpsert:
+			spanName = otelSpanNameUpsertPermission
+		case operationTypeRead:
+			spanName = otelSpanNameReadPermission
+		case operationTypeReplace:
+			spanName = otelSpanNameReplacePermission
+		case operationTypeDelete:
+			spanName = otelSpanNameDeletePermission
+		case operationTypeQuery:
+			spanName = otelSpanNameQueryPermissions
+		}
 	case resourceTypeOffer:
 		switch operationType {
 		case operationTypeRead:
@@ -105,6 +166,53 @@ func getSpanNameForContainers(endpoint *url.URL, operationType operationType, re
 		if operationType == operationTypeRead {
 			spanName = otelSpanNamePartitionKeyRanges
 		}
+	case resourceTypeStoredProcedure:
+		switch operationType {
+		case operationTypeCreate:
+			spanName = otelSpanNameCreateStoredProcedure
+		case operationTypeRead:
+			spanName = otelSpanNameReadStoredProcedure
+		case operationTypeReplace:
+			spanName = otelSpanNameReplaceStoredProcedure
+		case operationTypeDelete:
+			spanName = otelSpanNameDeleteStoredProcedure
+		case operationTypeReadFeed:
+			spanName = otelSpanNameReadAllStoredProcedures
+		case operationTypeQuery:
+			spanName = otelSpanNameQueryStoredProcedures
+		case operationTypeExecuteJavaScript:
+			spanName = otelSpanNameExecuteStoredProcedure
+		}
+	case resourceTypeTrigger:
+		switch operationType {
+		case operationTypeCreate:
+			spanName = otelSpanNameCreateTrigger
+		case operationTypeRead:
+			spanName = otelSpanNameReadTrigger
+		case operationTypeReplace:
+			spanName = otelSpanNameReplaceTrigger
+		case operationTypeDelete:
+			spanName = otelSpanNameDeleteTrigger
+		case operationTypeReadFeed:
+			spanName = otelSpanNameReadAllTriggers
+		case operationTypeQuery:
+			spanName = otelSpanNameQueryTriggers
+		}
+	case resourceTypeUserDefinedFunction:
+		switch operationType {
+		case operationTypeCreate:
+			spanName = otelSpanNameCreateUserDefinedFunction
+		case operationTypeRead:
+			spanName = otelSpanNameReadUserDefinedFunction
+		case operationTypeReplace:
+			spanName = otelSpanNameReplaceUserDefinedFunction
+		case operationTypeDelete:
+			spanName = otelSpanNameDeleteUserDefinedFunction
+		case operationTypeReadFeed:
+			spanName = otelSpanNameReadAllUserDefinedFunctions
+		case operationTypeQuery:
+			spanName = otelSpanNameQueryUserDefinedFunctions
+		}
 	case resourceTypeOffer:
 		switch operationType {
 		case operationTypeRead:
@@ -0,0 +1,172 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Component type markers of the binary encoding of partition key values.
+const (
+	partitionKeyComponentUndefined byte = 0x00
+	partitionKeyComponentNull      byte = 0x01
+	partitionKeyComponentFalse     byte = 0x02
+	partitionKeyComponentTrue      byte = 0x03
+	partitionKeyComponentNumber    byte = 0x05
+	partitionKeyComponentString    byte = 0x08
+)
+
+// maxPartitionKeyStringLength is the number of characters of string values used by version 1 hash partitioning.
+const maxPartitionKeyStringLength = 100
+
+// effectivePartitionKey returns the effective partition key of the partition key value, which determines the
+// partition key range that stores it. An empty partition key maps to the start of the key space.
+func (pk PartitionKey) effectivePartitionKey(definition PartitionKeyDefinition) (string, error) {
+	if len(pk.values) == 0 {
+		return "", nil
+	}
+	if definition.Kind == PartitionKeyKindMultiHash || len(definition.Paths) > 1 {
+		// Hierarchical partition keys concatenate the hash of every level, so that prefixes map to contiguous ranges.
+		var epk strings.Builder
+		for _, value := range pk.values {
+			hash, err := hashPartitionKeyV2([]interface{}{value})
+			if err != nil {
+				return "", err
+			}
+			epk.WriteString(hash)
+		}
+		return epk.String(), nil
+	}
+	if definition.Version >= 2 {
+		return hashPartitionKeyV2(pk.values)
+	}
+	return hashPartitionKeyV1(pk.values)
+}
+
+func hashPartitionKeyV2(values []interface{}) (string, error) {
+	var buffer bytes.Buffer
+	for _, value := range values {
+		if err := writePartitionKeyComponentForHashing(&buffer, value, 0xFF); err != nil {
+			return "", err
+		}
+	}
+	low, high := murmurHash3x64_128(buffer.Bytes(), 0)
+	hash := make([]byte, 16)
+	binary.BigEndian.PutUint64(hash, high)
+	binary.BigEndian.PutUint64(hash[8:], low)
+	// The two most significant bits are reserved, so effective partition keys are always lower than "FF".
+	hash[0] &= 0x3F
+	return strings.ToUpper(hex.EncodeToString(hash)), nil
+}
+
+func hashPartitionKeyV1(values []interface{}) (string, error) {
+	truncated := make([]interface{}, len(values))
+	for i, value := range values {
+		if s, ok := value.(string); ok {
+			if runes := []rune(s); len(runes) > maxPartitionKeyStringLength {
+				value = string(runes[:maxPartitionKeyStringLength])
+			}
+		}
+		truncated[i] = value
+	}
+
+	var buffer bytes.Buffer
+	for _, value := range truncated {
+		if err := writePartitionKeyComponentForHashing(&buffer, value, 0x00); err != nil {
+			return "", err
+		}
+	}
+	hash := murmurHash3x86_32(buffer.Bytes(), 0)
+
+	buffer.Reset()
+	for _, value := range append([]interface{}{float64(hash)}, truncated...) {
+		if err := writePartitionKeyComponentForBinaryEncoding(&buffer, value); err != nil {
+			return "", err
+		}
+	}
+	return strings.ToUpper(hex.EncodeToString(buffer.Bytes())), nil
+}
+
+// writePartitionKeyComponentForHashing writes the value of a partition key component as hashed by the service.
+// Strings are terminated by 0x00 for version 1 hash partitioning and 0xFF for version 2.
+func writePartitionKeyComponentForHashing(buffer *bytes.Buffer, value interface{}, stringTerminator byte) error {
+	switch v := value.(type) {
+	case nil:
+		buffer.WriteByte(partitionKeyComponentNull)
+	case bool:
+		if v {
+			buffer.WriteByte(partitionKeyComponentTrue)
+		} else {
+			buffer.WriteByte(partitionKeyComponentFalse)
+		}
+	case float64:
+		buffer.WriteByte(partitionKeyComponentNumber)
+		_ = binary.Write(buffer, binary.LittleEndian, math.Float64bits(v))
+	case string:
+		buffer.WriteByte(partitionKeyComponentString)
+		buffer.WriteString(v)
+		buffer.WriteByte(stringTerminator)
+	default:
+		return fmt.Errorf("unsupported partition key value type %T", value)
+	}
+	return nil
+}
+
+// writePartitionKeyComponentForBinaryEncoding writes the order preserving binary encoding of a partition key component.
+func writePartitionKeyComponentForBinaryEncoding(buffer *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case float64:
+		buffer.WriteByte(partitionKeyComponentNumber)
+		payload := math.Float64bits(v)
+		// Flip the sign bit of positive numbers and all bits of negative numbers, so that the encoding sorts like the values.
+		if payload < 1<<63 {
+			payload ^= 1 << 63
+		} else {
+			payload = ^payload + 1
+		}
+		// The first byte contains 8 bits of the payload, the following bytes 7 bits followed by a 1 bit, except for the
+		// last byte which ends with a 0 bit.
+		buffer.WriteByte(byte(payload >> 56))
+		payload <<= 8
+		var chunk byte
+		first := true
+		for {
+			if !first {
+				buffer.WriteByte(chunk)
+			}
+			first = false
+			chunk = byte(payload>>56) | 0x01
+			payload <<= 7
+			if payload == 0 {
+				break
+			}
+		}
+		buffer.WriteByte(chunk & 0xFE)
+	case string:
+		buffer.WriteByte(partitionKeyComponentString)
+		utf8Value := []byte(v)
+		short := len(utf8Value) <= maxPartitionKeyStringLength
+		length := len(utf8Value)
+		if !short {
+			length = maxPartitionKeyStringLength + 1
+		}
+		for _, b := range utf8Value[:length] {
+			if b < 0xFF {
+				b++
+			}
+			buffer.WriteByte(b)
+		}
+		if short {
+			buffer.WriteByte(0x00)
+		}
+	default:
+		return writePartitionKeyComponentForHashing(buffer, value, 0)
+	}
+	return nil
+}
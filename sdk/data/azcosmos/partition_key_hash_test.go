@@ -0,0 +1,81 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"testing"
+)
+
+func TestEffectivePartitionKey(t *testing.T) {
+	cases := []struct {
+		pk PartitionKey
+		v1 string
+		v2 string
+	}{
+		{NewPartitionKeyString(""), "05C1CF33970FF80800", "32E9366E637A71B4E710384B2F4970A0"},
+		{NewPartitionKeyString("partitionKey"), "05C1E1B3D9CD2608716273756A756A706F4C667A00", "013AEFCF77FA271571CF665A58C933F1"},
+		{NewPartitionKeyBool(true), "05C1D7C5A903D803", "0E711127C5B5A8E4726AC6DD306A3E59"},
+		{NewPartitionKeyBool(false), "05C1DB857D857C02", "2FE1BE91E90A3439635E0E9E37361EF2"},
+		{NullPartitionKey, "05C1ED45D7475601", "378867E4430E67857ACE5C908374FE16"},
+		{NewPartitionKeyNumber(5), "05C1D9C1C5517C05C014", "19C08621B135968252FB34B4CF66F811"},
+	}
+
+	for _, c := range cases {
+		serialized, _ := c.pk.toJsonString()
+		v1, err := c.pk.effectivePartitionKey(PartitionKeyDefinition{Kind: PartitionKeyKindHash, Paths: []string{"/pk"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v1 != c.v1 {
+			t.Errorf("Expected version 1 effective partition key %v for %v, but got %v", c.v1, serialized, v1)
+		}
+		v2, err := c.pk.effectivePartitionKey(PartitionKeyDefinition{Kind: PartitionKeyKindHash, Paths: []string{"/pk"}, Version: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v2 != c.v2 {
+			t.Errorf("Expected version 2 effective partition key %v for %v, but got %v", c.v2, serialized, v2)
+		}
+	}
+}
+
+func TestEffectivePartitionKeyHierarchical(t *testing.T) {
+	definition := PartitionKeyDefinition{Kind: PartitionKeyKindMultiHash, Paths: []string{"/a", "/b"}, Version: 2}
+	epk, err := NewPartitionKeyString("partitionKey").AppendBool(true).effectivePartitionKey(definition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "013AEFCF77FA271571CF665A58C933F1" + "0E711127C5B5A8E4726AC6DD306A3E59"
+	if epk != expected {
+		t.Errorf("Expected effective partition key %v, but got %v", expected, epk)
+	}
+
+	prefix, err := NewPartitionKeyString("partitionKey").effectivePartitionKey(definition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix != expected[:32] {
+		t.Errorf("Expected effective partition key %v, but got %v", expected[:32], prefix)
+	}
+}
+
+func TestEffectivePartitionKeyEmpty(t *testing.T) {
+	epk, err := NewPartitionKey().effectivePartitionKey(PartitionKeyDefinition{Kind: PartitionKeyKindHash, Paths: []string{"/pk"}, Version: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epk != "" {
+		t.Errorf("Expected an empty effective partition key, but got %v", epk)
+	}
+}
+
+func TestMurmurHash3(t *testing.T) {
+	if h := murmurHash3x86_32([]byte("hello"), 0); h != 613153351 {
+		t.Errorf("Expected 613153351, but got %v", h)
+	}
+	low, high := murmurHash3x64_128([]byte("hello"), 0)
+	if low != 0xcbd8a7b341bd9b02 || high != 0x5b1e906a48ae1d19 {
+		t.Errorf("Expected cbd8a7b341bd9b02 5b1e906a48ae1d19, but got %x %x", low, high)
+	}
+}
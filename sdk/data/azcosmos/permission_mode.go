@@ -0,0 +1,24 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+// PermissionMode specifies the access a permission grants on a resource.
+type PermissionMode string
+
+const (
+	// Read, write and delete access to the resource.
+	PermissionModeAll PermissionMode = "All"
+	// Read access to the resource.
+	PermissionModeRead PermissionMode = "Read"
+)
+
+// Returns a list of available permission modes
+func PermissionModeValues() []PermissionMode {
+	return []PermissionMode{PermissionModeAll, PermissionModeRead}
+}
+
+// ToPtr returns a *PermissionMode
+func (c PermissionMode) ToPtr() *PermissionMode {
+	return &c
+}
@@ -0,0 +1,24 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+// PriorityLevel of a request for priority-based execution.
+// When the account has priority-based execution enabled and the partition runs out of throughput,
+// the service throttles low priority requests before high priority requests.
+type PriorityLevel string
+
+const (
+	PriorityLevelHigh PriorityLevel = "High"
+	PriorityLevelLow  PriorityLevel = "Low"
+)
+
+// Returns a list of available priority levels
+func PriorityLevelValues() []PriorityLevel {
+	return []PriorityLevel{PriorityLevelHigh, PriorityLevelLow}
+}
+
+// ToPtr returns a *PriorityLevel
+func (p PriorityLevel) ToPtr() *PriorityLevel {
+	return &p
+}
@@ -0,0 +1,207 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package queryengine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reciprocalRankFusionConstant dampens the impact of the highest ranks in reciprocal rank fusion.
+const reciprocalRankFusionConstant = 60
+
+// globalStatistics are the full text search statistics of all partitions, used to score the component queries.
+type globalStatistics struct {
+	DocumentCount      int64 `json:"documentCount"`
+	FullTextStatistics []struct {
+		TotalWordCount int64   `json:"totalWordCount"`
+		HitCounts      []int64 `json:"hitCounts"`
+	} `json:"fullTextStatistics"`
+}
+
+// hybridSearchResult is a result of a component query of a hybrid search query.
+type hybridSearchResult struct {
+	Rid     string `json:"_rid"`
+	Payload struct {
+		Payload         json.RawMessage `json:"payload"`
+		ComponentScores []float64       `json:"componentScores"`
+	} `json:"payload"`
+
+	score float64
+}
+
+// hybridSearchSource executes hybrid search queries ordered by RANK RRF(...). The global full text statistics are
+// read first when the component queries need them, then the results of all component queries are ranked by
+// reciprocal rank fusion of their scores.
+type hybridSearchSource struct {
+	pipeline *nativeQueryPipeline
+	info     hybridSearchQueryInfo
+	// statistics reads the global statistics, and is nil once they were read.
+	statistics []*partitionProducer
+	components [][]*partitionProducer
+}
+
+func newHybridSearchSource(p *nativeQueryPipeline, info hybridSearchQueryInfo) (*hybridSearchSource, error) {
+	if len(info.ComponentQueryInfos) == 0 {
+		return nil, errors.New("hybrid search query plan has no component queries")
+	}
+	if len(info.ComponentWeights) != 0 && len(info.ComponentWeights) != len(info.ComponentQueryInfos) {
+		return nil, errors.New("hybrid search query plan has a different number of component weights and component queries")
+	}
+	s := &hybridSearchSource{pipeline: p, info: info}
+	if info.RequiresGlobalStatistics {
+		s.statistics = p.newProducers(info.GlobalStatisticsQuery, true)
+		p.producers = s.statistics
+		return s, nil
+	}
+	s.startComponents(globalStatistics{})
+	return s, nil
+}
+
+// startComponents creates the producers of the component queries, formatted with the global statistics.
+func (s *hybridSearchSource) startComponents(stats globalStatistics) {
+	s.components = make([][]*partitionProducer, len(s.info.ComponentQueryInfos))
+	s.pipeline.producers = nil
+	for i, component := range s.info.ComponentQueryInfos {
+		s.components[i] = s.pipeline.newProducers(formatComponentQuery(component.RewrittenQuery, stats), true)
+		s.pipeline.producers = append(s.pipeline.producers, s.components[i]...)
+	}
+}
+
+func formatComponentQuery(query string, stats globalStatistics) string {
+	query = strings.ReplaceAll(query, orderByFilterPlaceholder, "true")
+	query = strings.ReplaceAll(query, totalDocumentCountPlaceholder, strconv.FormatInt(stats.DocumentCount, 10))
+	for i, fullText := range stats.FullTextStatistics {
+		query = strings.ReplaceAll(query, fmt.Sprintf(totalWordCountPlaceholderFormat, i), strconv.FormatInt(fullText.TotalWordCount, 10))
+		hitCounts := make([]string, len(fullText.HitCounts))
+		for j, hitCount := range fullText.HitCounts {
+			hitCounts[j] = strconv.FormatInt(hitCount, 10)
+		}
+		query = strings.ReplaceAll(query, fmt.Sprintf(hitCountsArrayPlaceholderFormat, i), "["+strings.Join(hitCounts, ",")+"]")
+	}
+	return query
+}
+
+func (s *hybridSearchSource) next() ([]json.RawMessage, bool, error) {
+	for _, producer := range s.pipeline.producers {
+		if !producer.exhausted() {
+			return nil, false, nil
+		}
+	}
+	if s.statistics != nil {
+		stats, err := aggregateGlobalStatistics(s.statistics)
+		if err != nil {
+			return nil, false, err
+		}
+		s.statistics = nil
+		s.startComponents(stats)
+		return nil, false, nil
+	}
+	results, err := s.rank()
+	if err != nil {
+		return nil, false, err
+	}
+	return results, true, nil
+}
+
+func aggregateGlobalStatistics(producers []*partitionProducer) (globalStatistics, error) {
+	var total globalStatistics
+	for _, producer := range producers {
+		for _, raw := range producer.queue {
+			var stats globalStatistics
+			if err := json.Unmarshal(raw, &stats); err != nil {
+				return globalStatistics{}, fmt.Errorf("failed to unmarshal hybrid search statistics: %w", err)
+			}
+			total.DocumentCount += stats.DocumentCount
+			for i, fullText := range stats.FullTextStatistics {
+				if i == len(total.FullTextStatistics) {
+					total.FullTextStatistics = append(total.FullTextStatistics, fullText)
+					total.FullTextStatistics[i].HitCounts = append([]int64{}, fullText.HitCounts...)
+					continue
+				}
+				total.FullTextStatistics[i].TotalWordCount += fullText.TotalWordCount
+				for j, hitCount := range fullText.HitCounts {
+					if j == len(total.FullTextStatistics[i].HitCounts) {
+						total.FullTextStatistics[i].HitCounts = append(total.FullTextStatistics[i].HitCounts, hitCount)
+					} else {
+						total.FullTextStatistics[i].HitCounts[j] += hitCount
+					}
+				}
+			}
+		}
+		producer.queue = nil
+	}
+	return total, nil
+}
+
+// rank orders the results of the component queries by the weighted sum of the reciprocal of their rank in every
+// component, and applies the skip and take of the query.
+func (s *hybridSearchSource) rank() ([]json.RawMessage, error) {
+	// A document is returned by every component query that matches it.
+	var results []*hybridSearchResult
+	seen := map[string]bool{}
+	for _, producers := range s.components {
+		for _, producer := range producers {
+			for _, raw := range producer.queue {
+				var result hybridSearchResult
+				if err := json.Unmarshal(raw, &result); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal hybrid search result: %w", err)
+				}
+				if seen[result.Rid] {
+					continue
+				}
+				if len(result.Payload.ComponentScores) != len(s.info.ComponentQueryInfos) {
+					return nil, fmt.Errorf("expected %d component scores, got %d", len(s.info.ComponentQueryInfos), len(result.Payload.ComponentScores))
+				}
+				seen[result.Rid] = true
+				results = append(results, &result)
+			}
+			producer.queue = nil
+		}
+	}
+
+	order := make([]int, len(results))
+	for component, info := range s.info.ComponentQueryInfos {
+		for i := range order {
+			order[i] = i
+		}
+		ascending := len(info.OrderBy) > 0 && info.OrderBy[0] == sortOrderAscending
+		sort.SliceStable(order, func(i, j int) bool {
+			a, b := results[order[i]].Payload.ComponentScores[component], results[order[j]].Payload.ComponentScores[component]
+			if ascending {
+				return a < b
+			}
+			return a > b
+		})
+		weight := 1.0
+		if len(s.info.ComponentWeights) > 0 {
+			weight = s.info.ComponentWeights[component]
+		}
+		// Equal scores share a rank.
+		rank := 1
+		for i, index := range order {
+			if i > 0 && results[index].Payload.ComponentScores[component] != results[order[i-1]].Payload.ComponentScores[component] {
+				rank++
+			}
+			results[index].score += weight / float64(reciprocalRankFusionConstant+rank)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].score > results[j].score })
+
+	if s.info.Skip != nil {
+		results = results[min(int(*s.info.Skip), len(results)):]
+	}
+	if s.info.Take != nil {
+		results = results[:min(int(*s.info.Take), len(results))]
+	}
+	payloads := make([]json.RawMessage, len(results))
+	for i, result := range results {
+		payloads[i] = result.Payload.Payload
+	}
+	return payloads, nil
+}
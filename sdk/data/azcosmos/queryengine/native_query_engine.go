@@ -0,0 +1,294 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package queryengine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// NewNativeQueryEngine creates a QueryEngine implemented in Go, which doesn't require cgo or a native library.
+// It executes cross-partition queries using the query plan returned by the gateway: the query is sent to every
+// partition key range it targets, and the results are merged client-side. It supports ORDER BY, GROUP BY,
+// aggregates, DISTINCT, OFFSET/LIMIT, TOP, and vector and hybrid search queries ordered by RANK.
+func NewNativeQueryEngine() QueryEngine {
+	return nativeQueryEngine{}
+}
+
+type nativeQueryEngine struct{}
+
+// CreateQueryPipeline creates a pipeline executing the query described by the query plan on the partition key ranges.
+func (nativeQueryEngine) CreateQueryPipeline(query string, plan string, pkranges string) (QueryPipeline, error) {
+	return newNativeQueryPipeline(query, plan, pkranges)
+}
+
+// SupportedFeatures returns the query features supported by the engine, which are sent with query plan requests.
+func (nativeQueryEngine) SupportedFeatures() string {
+	return nativeQueryEngineSupportedFeatures
+}
+
+// partitionProducer buffers the results of a query sent to a partition key range.
+type partitionProducer struct {
+	pkRange partitionKeyRange
+	// query overrides the query of the pipeline when set.
+	query string
+	// drain requests all the results of the partition key range at once.
+	drain        bool
+	requestID    uint64
+	started      bool
+	continuation string
+	queue        []json.RawMessage
+}
+
+// exhausted reports whether all the results of the partition key range were received.
+func (p *partitionProducer) exhausted() bool {
+	return p.started && p.continuation == ""
+}
+
+// done reports whether all the results of the partition key range were received and consumed.
+func (p *partitionProducer) done() bool {
+	return p.exhausted() && len(p.queue) == 0
+}
+
+func (p *partitionProducer) pop() json.RawMessage {
+	item := p.queue[0]
+	p.queue[0] = nil
+	p.queue = p.queue[1:]
+	return item
+}
+
+// querySource produces the results of the pipeline from the results of its partition producers.
+type querySource interface {
+	// next returns the results that can be produced from the data received so far, and whether all results were produced.
+	next() ([]json.RawMessage, bool, error)
+}
+
+// nativeQueryPipeline is the QueryPipeline of the native query engine.
+type nativeQueryPipeline struct {
+	query         string
+	ranges        []partitionKeyRange
+	producers     []*partitionProducer
+	nextRequestID uint64
+	source        querySource
+	distinct      *distinctFilter
+	// skip is the number of results to skip, and take the number of results to return, or -1 when unlimited.
+	skip      int64
+	take      int64
+	completed bool
+	closed    bool
+}
+
+func newNativeQueryPipeline(query string, plan string, pkranges string) (*nativeQueryPipeline, error) {
+	parsedPlan, err := parseQueryPlan(plan)
+	if err != nil {
+		return nil, err
+	}
+	ranges, err := targetPartitionKeyRanges(pkranges, parsedPlan.QueryRanges)
+	if err != nil {
+		return nil, err
+	}
+	p := &nativeQueryPipeline{query: query, ranges: ranges, take: -1}
+
+	if parsedPlan.HybridSearchQueryInfo != nil {
+		p.source, err = newHybridSearchSource(p, *parsedPlan.HybridSearchQueryInfo)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+
+	info := parsedPlan.QueryInfo
+	if info.RewrittenQuery != "" {
+		p.query = strings.ReplaceAll(info.RewrittenQuery, orderByFilterPlaceholder, "true")
+	}
+	p.source, err = newQuerySource(p, info)
+	if err != nil {
+		return nil, err
+	}
+	if info.DCountInfo == nil {
+		p.distinct = newDistinctFilter(info.DistinctType)
+	}
+	if info.Offset != nil {
+		p.skip = *info.Offset
+	}
+	if info.Limit != nil {
+		p.take = *info.Limit
+	} else if info.Top != nil {
+		p.take = *info.Top
+	}
+	return p, nil
+}
+
+// newQuerySource returns the source combining the results of the partitions as described by the query info.
+func newQuerySource(p *nativeQueryPipeline, info *queryInfo) (querySource, error) {
+	switch {
+	case info.DCountInfo != nil:
+		return newDrainingSource(p, func(producers []*partitionProducer) ([]json.RawMessage, error) {
+			return distinctCount(producers, info.DCountInfo.DCountAlias)
+		}), nil
+	case len(info.GroupByExpressions) > 0 || len(info.GroupByAliasToAggregateType) > 0:
+		if err := validateAggregates(info.GroupByAliasToAggregateType); err != nil {
+			return nil, err
+		}
+		return newDrainingSource(p, func(producers []*partitionProducer) ([]json.RawMessage, error) {
+			return groupBy(producers, info)
+		}), nil
+	case len(info.Aggregates) > 0:
+		if len(info.Aggregates) > 1 || !info.HasSelectValue {
+			return nil, errors.New("multiple aggregates are only supported in queries without SELECT VALUE")
+		}
+		if _, err := newAggregator(info.Aggregates[0]); err != nil {
+			return nil, err
+		}
+		return newDrainingSource(p, func(producers []*partitionProducer) ([]json.RawMessage, error) {
+			return aggregateValue(producers, info.Aggregates[0])
+		}), nil
+	case len(info.OrderBy) > 0 && info.HasNonStreamingOrderBy:
+		return newDrainingSource(p, func(producers []*partitionProducer) ([]json.RawMessage, error) {
+			return sortResults(producers, info.OrderBy)
+		}), nil
+	case len(info.OrderBy) > 0:
+		p.producers = p.newProducers("", false)
+		return &orderBySource{producers: p.producers, orderBy: info.OrderBy, heads: map[*partitionProducer]*orderByResult{}}, nil
+	default:
+		p.producers = p.newProducers("", false)
+		return &unorderedSource{producers: p.producers}, nil
+	}
+}
+
+// newProducers creates a producer per partition key range targeted by the query.
+func (p *nativeQueryPipeline) newProducers(query string, drain bool) []*partitionProducer {
+	producers := make([]*partitionProducer, len(p.ranges))
+	for i, pkRange := range p.ranges {
+		producers[i] = &partitionProducer{pkRange: pkRange, query: query, drain: drain, requestID: p.nextRequestID}
+		p.nextRequestID++
+	}
+	return producers
+}
+
+// Query returns the query sent to the partition key ranges, as rewritten by the gateway.
+func (p *nativeQueryPipeline) Query() string {
+	return p.query
+}
+
+// IsComplete returns true once all results were returned.
+func (p *nativeQueryPipeline) IsComplete() bool {
+	return p.completed
+}
+
+// Run returns the results that can be produced from the data received so far, and the requests for more data.
+func (p *nativeQueryPipeline) Run() (*PipelineResult, error) {
+	if p.closed {
+		return nil, errors.New("pipeline is closed")
+	}
+	if p.completed {
+		return &PipelineResult{IsCompleted: true}, nil
+	}
+
+	results, done, err := p.source.next()
+	if err != nil {
+		return nil, err
+	}
+	items := make([][]byte, 0, len(results))
+	for _, result := range results {
+		if p.take == 0 {
+			done = true
+			break
+		}
+		if p.distinct != nil {
+			duplicate, err := p.distinct.isDuplicate(result)
+			if err != nil {
+				return nil, err
+			}
+			if duplicate {
+				continue
+			}
+		}
+		if p.skip > 0 {
+			p.skip--
+			continue
+		}
+		items = append(items, result)
+		if p.take > 0 {
+			p.take--
+		}
+	}
+	if p.take == 0 {
+		done = true
+	}
+
+	var requests []QueryRequest
+	if !done {
+		requests = p.requests()
+		// Sources always make progress while data is missing, so the pipeline would stall without requests.
+		done = len(requests) == 0 && len(items) == 0
+	}
+	p.completed = done
+	return &PipelineResult{IsCompleted: done, Items: items, Requests: requests}, nil
+}
+
+// requests returns a request for the next page of every partition whose results are needed.
+func (p *nativeQueryPipeline) requests() []QueryRequest {
+	var requests []QueryRequest
+	for _, producer := range p.producers {
+		if producer.exhausted() || (!producer.drain && len(producer.queue) > 0) {
+			continue
+		}
+		requests = append(requests, QueryRequest{
+			PartitionKeyRangeID: producer.pkRange.ID,
+			Id:                  producer.requestID,
+			Continuation:        producer.continuation,
+			Query:               producer.query,
+			IncludeParameters:   producer.query != "",
+			Drain:               producer.drain,
+		})
+	}
+	return requests
+}
+
+// ProvideData adds the results of partition requests to the buffers of their producers.
+func (p *nativeQueryPipeline) ProvideData(data []QueryResult) error {
+	if p.closed {
+		return errors.New("pipeline is closed")
+	}
+	for _, result := range data {
+		var producer *partitionProducer
+		for _, candidate := range p.producers {
+			if candidate.pkRange.ID == result.PartitionKeyRangeID && candidate.requestID == result.RequestId && !candidate.exhausted() {
+				producer = candidate
+				break
+			}
+		}
+		if producer == nil {
+			return fmt.Errorf("unexpected data for request %d of partition key range %s", result.RequestId, result.PartitionKeyRangeID)
+		}
+
+		var page struct {
+			Documents []json.RawMessage `json:"Documents"`
+		}
+		if err := json.Unmarshal(result.Data, &page); err != nil {
+			return fmt.Errorf("failed to unmarshal results of partition key range %s: %w", result.PartitionKeyRangeID, err)
+		}
+		producer.started = true
+		producer.continuation = result.NextContinuation
+		producer.queue = append(producer.queue, page.Documents...)
+		if !producer.drain || result.NextContinuation == "" {
+			// Pages of draining requests share the id of the request.
+			producer.requestID = p.nextRequestID
+			p.nextRequestID++
+		}
+	}
+	return nil
+}
+
+// Close releases the buffered results.
+func (p *nativeQueryPipeline) Close() {
+	p.closed = true
+	p.producers = nil
+	p.source = nil
+}
+
+var _ QueryPipeline = (*nativeQueryPipeline)(nil)
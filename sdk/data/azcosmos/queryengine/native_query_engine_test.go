@@ -0,0 +1,433 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package queryengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testPartitionKeyRanges = `{"PartitionKeyRanges":[
+	{"id":"2","minInclusive":"80","maxExclusive":"FF"},
+	{"id":"0","minInclusive":"","maxExclusive":"40"},
+	{"id":"1","minInclusive":"40","maxExclusive":"80"}]}`
+
+// fakePartitions serves the pages of results of queries per partition key range. The continuation of a page is the
+// index of the next page.
+type fakePartitions struct {
+	// pages contains the pages of results by query and partition key range id.
+	pages    map[string]map[string][]string
+	requests []QueryRequest
+	queries  []string
+}
+
+func (f *fakePartitions) page(t *testing.T, query string, request QueryRequest) QueryResult {
+	f.requests = append(f.requests, request)
+	f.queries = append(f.queries, query)
+	pages, ok := f.pages[query][request.PartitionKeyRangeID]
+	if !ok {
+		pages = []string{"[]"}
+	}
+	index := 0
+	if request.Continuation != "" {
+		var err error
+		if index, err = strconv.Atoi(request.Continuation); err != nil {
+			t.Fatal(err)
+		}
+	}
+	continuation := ""
+	if index+1 < len(pages) {
+		continuation = strconv.Itoa(index + 1)
+	}
+	return QueryResult{
+		PartitionKeyRangeID: request.PartitionKeyRangeID,
+		RequestId:           request.Id,
+		NextContinuation:    continuation,
+		Data:                []byte(`{"_rid":"rid","Documents":` + pages[index] + `}`),
+	}
+}
+
+// runQuery executes the pipeline like the SDK does and returns the items it produced.
+func runQuery(t *testing.T, query string, plan string, partitions *fakePartitions) []string {
+	pipeline, err := NewNativeQueryEngine().CreateQueryPipeline(query, plan, testPartitionKeyRanges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pipeline.Close()
+
+	var items []string
+	for turn := 0; !pipeline.IsComplete(); turn++ {
+		if turn > 1000 {
+			t.Fatal("The pipeline doesn't make progress")
+		}
+		result, err := pipeline.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range result.Items {
+			items = append(items, string(item))
+		}
+		if len(result.Items) > 0 {
+			continue
+		}
+		for _, request := range result.Requests {
+			requestQuery := pipeline.Query()
+			if request.Query != "" {
+				requestQuery = request.Query
+			}
+			for {
+				page := partitions.page(t, requestQuery, request)
+				if err := pipeline.ProvideData([]QueryResult{page}); err != nil {
+					t.Fatal(err)
+				}
+				if !request.Drain || page.NextContinuation == "" {
+					break
+				}
+				request.Continuation = page.NextContinuation
+			}
+		}
+	}
+	return items
+}
+
+func expectItems(t *testing.T, items []string, expected ...string) {
+	t.Helper()
+	if strings.Join(items, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected items %v, got %v", expected, items)
+	}
+}
+
+func orderByDocument(rid string, payload string, orderByItems ...string) string {
+	items := make([]string, len(orderByItems))
+	for i, item := range orderByItems {
+		if item == "" {
+			items[i] = "{}"
+		} else {
+			items[i] = `{"item":` + item + `}`
+		}
+	}
+	return fmt.Sprintf(`{"_rid":%q,"orderByItems":[%s],"payload":%s}`, rid, strings.Join(items, ","), payload)
+}
+
+func TestNativeQueryEngineUnordered(t *testing.T) {
+	query := "SELECT VALUE c.id FROM c OFFSET 1 LIMIT 4"
+	partitions := &fakePartitions{pages: map[string]map[string][]string{
+		"SELECT VALUE c.id FROM c OFFSET 0 LIMIT 5": {
+			"0": {`["a","b"]`, `["c"]`},
+			"1": {`[]`},
+			"2": {`["d","e","f"]`},
+		},
+	}}
+	plan := `{"queryInfo":{"distinctType":"None","offset":1,"limit":4,"rewrittenQuery":"SELECT VALUE c.id FROM c OFFSET 0 LIMIT 5","hasSelectValue":true},"queryRanges":[{"min":"","max":"FF","isMinInclusive":true,"isMaxInclusive":false}]}`
+
+	expectItems(t, runQuery(t, query, plan, partitions), `"b"`, `"c"`, `"d"`, `"e"`)
+}
+
+func TestNativeQueryEngineOrderBy(t *testing.T) {
+	rewritten := `SELECT c._rid, [{"item": c.name}, {"item": c.age}] AS orderByItems, c AS payload FROM c WHERE ({documentdb-formattableorderbyquery-filter}) ORDER BY c.name, c.age DESC`
+	sent := strings.ReplaceAll(rewritten, "{documentdb-formattableorderbyquery-filter}", "true")
+	partitions := &fakePartitions{pages: map[string]map[string][]string{
+		sent: {
+			"0": {
+				"[" + orderByDocument("1", `{"id":"1"}`, "", "") + "," + orderByDocument("2", `{"id":"2"}`, "null", "1") + "]",
+				"[" + orderByDocument("3", `{"id":"3"}`, `"a"`, "5") + "]",
+			},
+			"1": {
+				"[" + orderByDocument("4", `{"id":"4"}`, "false", "1") + "," + orderByDocument("5", `{"id":"5"}`, `"a"`, "7") + "]",
+				"[" + orderByDocument("6", `{"id":"6"}`, `"b"`, "1") + "]",
+			},
+			"2": {
+				"[" + orderByDocument("7", `{"id":"7"}`, "10", "1") + "," + orderByDocument("8", `{"id":"8"}`, `"a"`, "6") + "]",
+			},
+		},
+	}}
+	plan, _ := json.Marshal(map[string]any{
+		"queryInfo": map[string]any{
+			"distinctType":       "None",
+			"orderBy":            []string{"Ascending", "Descending"},
+			"orderByExpressions": []string{"c.name", "c.age"},
+			"rewrittenQuery":     rewritten,
+		},
+		"queryRanges": []map[string]any{{"min": "", "max": "FF", "isMinInclusive": true, "isMaxInclusive": false}},
+	})
+
+	items := runQuery(t, "SELECT * FROM c ORDER BY c.name, c.age DESC", string(plan), partitions)
+	expectItems(t, items, `{"id":"1"}`, `{"id":"2"}`, `{"id":"4"}`, `{"id":"7"}`, `{"id":"5"}`, `{"id":"8"}`, `{"id":"3"}`, `{"id":"6"}`)
+
+	// Pages are only requested when the results of a partition key range are needed.
+	for _, request := range partitions.requests {
+		if request.Drain {
+			t.Errorf("Expected streaming requests, got %v", request)
+		}
+	}
+	if len(partitions.requests) != 5 {
+		t.Errorf("Expected 5 requests, got %d", len(partitions.requests))
+	}
+}
+
+func TestNativeQueryEngineOrderByTopDistinct(t *testing.T) {
+	rewritten := `SELECT DISTINCT c._rid, [{"item": c.name}] AS orderByItems, c.name AS payload FROM c WHERE ({documentdb-formattableorderbyquery-filter}) ORDER BY c.name`
+	sent := strings.ReplaceAll(rewritten, "{documentdb-formattableorderbyquery-filter}", "true")
+	partitions := &fakePartitions{pages: map[string]map[string][]string{
+		sent: {
+			"0": {"[" + orderByDocument("1", `"a"`, `"a"`) + "," + orderByDocument("2", `"c"`, `"c"`) + "]"},
+			"1": {"[" + orderByDocument("3", `"a"`, `"a"`) + "," + orderByDocument("4", `"b"`, `"b"`) + "]"},
+			"2": {"[" + orderByDocument("5", `"b"`, `"b"`) + "," + orderByDocument("6", `"d"`, `"d"`) + "]"},
+		},
+	}}
+	plan, _ := json.Marshal(map[string]any{
+		"queryInfo": map[string]any{
+			"distinctType":   "Ordered",
+			"top":            3,
+			"orderBy":        []string{"Ascending"},
+			"rewrittenQuery": rewritten,
+			"hasSelectValue": true,
+		},
+	})
+
+	expectItems(t, runQuery(t, "SELECT DISTINCT TOP 3 VALUE c.name FROM c ORDER BY c.name", string(plan), partitions), `"a"`, `"b"`, `"c"`)
+}
+
+func TestNativeQueryEngineValueAggregates(t *testing.T) {
+	cases := []struct {
+		aggregate string
+		pages     map[string][]string
+		expected  []string
+	}{
+		{"Count", map[string][]string{"0": {`[[{"item":2}]]`}, "1": {`[[{"item":0}]]`}, "2": {`[[{"item":3}]]`}}, []string{"5"}},
+		{"Sum", map[string][]string{"0": {`[[{"item":2.5}]]`}, "2": {`[[{"item":3}]]`}}, []string{"5.5"}},
+		{"Sum", map[string][]string{"0": {`[[{"item":2.5}]]`}, "2": {`[[{}]]`}}, nil},
+		{"Average", map[string][]string{"0": {`[[{"item":{"sum":6,"count":2}}]]`}, "1": {`[[{"item":{"sum":0,"count":0}}]]`}, "2": {`[[{"item":{"sum":3,"count":2}}]]`}}, []string{"2.25"}},
+		{"Min", map[string][]string{"0": {`[[{"item":{"min":"b","count":1}}]]`}, "1": {`[[{"item":{"count":0}}]]`}, "2": {`[[{"item":{"min":4,"count":2}}]]`}}, []string{"4"}},
+		{"Max", map[string][]string{"0": {`[[{"item":"b"}]]`}, "1": {`[[{}]]`}, "2": {`[[{"item":4}]]`}}, []string{`"b"`}},
+		{"Max", map[string][]string{"0": {`[[{}]]`}}, nil},
+	}
+
+	for _, c := range cases {
+		rewritten := fmt.Sprintf(`SELECT VALUE [{"item": %s(c.x)}] FROM c`, strings.ToUpper(c.aggregate))
+		plan, _ := json.Marshal(map[string]any{
+			"queryInfo": map[string]any{
+				"distinctType":   "None",
+				"aggregates":     []string{c.aggregate},
+				"rewrittenQuery": rewritten,
+				"hasSelectValue": true,
+			},
+		})
+		partitions := &fakePartitions{pages: map[string]map[string][]string{rewritten: c.pages}}
+		items := runQuery(t, "SELECT VALUE "+c.aggregate+"(c.x) FROM c", string(plan), partitions)
+		expectItems(t, items, c.expected...)
+		for _, request := range partitions.requests {
+			if !request.Drain {
+				t.Errorf("Expected draining requests for aggregates, got %v", request)
+			}
+		}
+	}
+}
+
+func TestNativeQueryEngineGroupBy(t *testing.T) {
+	rewritten := `SELECT [{"item": c.team}] AS groupByItems, {"team": c.team, "count": {"item": COUNT(1)}, "oldest": {"item": MAX(c.age)}} AS payload FROM c GROUP BY c.team`
+	partitions := &fakePartitions{pages: map[string]map[string][]string{
+		rewritten: {
+			"0": {
+				`[{"groupByItems":[{"item":"red"}],"payload":{"team":"red","count":{"item":2},"oldest":{"item":30}}}]`,
+				`[{"groupByItems":[{"item":"blue"}],"payload":{"team":"blue","count":{"item":1},"oldest":{"item":20}}}]`,
+			},
+			"2": {
+				`[{"groupByItems":[{"item":"red"}],"payload":{"team":"red","count":{"item":3},"oldest":{"item":40}}},` +
+					`{"groupByItems":[{}],"payload":{"count":{"item":1},"oldest":{"item":50}}}]`,
+			},
+		},
+	}}
+	plan, _ := json.Marshal(map[string]any{
+		"queryInfo": map[string]any{
+			"distinctType":                "None",
+			"groupByExpressions":          []string{"c.team"},
+			"groupByAliases":              []string{"team", "count", "oldest"},
+			"aggregates":                  []string{"Count", "Max"},
+			"groupByAliasToAggregateType": map[string]any{"team": nil, "count": "Count", "oldest": "Max"},
+			"rewrittenQuery":              rewritten,
+		},
+	})
+
+	items := runQuery(t, "SELECT c.team, COUNT(1) AS count, MAX(c.age) AS oldest FROM c GROUP BY c.team", string(plan), partitions)
+	expectItems(t, items, `{"team":"red","count":5,"oldest":40}`, `{"team":"blue","count":1,"oldest":20}`, `{"count":1,"oldest":50}`)
+}
+
+func TestNativeQueryEngineNonValueAggregate(t *testing.T) {
+	rewritten := `SELECT [] AS groupByItems, {"$1": {"item": COUNT(1)}} AS payload FROM c`
+	partitions := &fakePartitions{pages: map[string]map[string][]string{
+		rewritten: {
+			"0": {`[{"groupByItems":[],"payload":{"$1":{"item":4}}}]`},
+			"1": {`[{"groupByItems":[],"payload":{"$1":{"item":0}}}]`},
+			"2": {`[{"groupByItems":[],"payload":{"$1":{"item":6}}}]`},
+		},
+	}}
+	plan, _ := json.Marshal(map[string]any{
+		"queryInfo": map[string]any{
+			"aggregates":                  []string{"Count"},
+			"groupByAliasToAggregateType": map[string]any{"$1": "Count"},
+			"rewrittenQuery":              rewritten,
+		},
+	})
+
+	expectItems(t, runQuery(t, "SELECT COUNT(1) FROM c", string(plan), partitions), `{"$1":10}`)
+}
+
+func TestNativeQueryEngineDistinct(t *testing.T) {
+	partitions := &fakePartitions{pages: map[string]map[string][]string{
+		"SELECT DISTINCT VALUE c.x FROM c": {
+			"0": {`[1,{"a":1,"b":2}]`, `["x"]`},
+			"1": {`[1.0,"x"]`},
+			"2": {`[{"b":2,"a":1},null]`},
+		},
+	}}
+	plan := `{"queryInfo":{"distinctType":"Unordered","rewrittenQuery":"SELECT DISTINCT VALUE c.x FROM c","hasSelectValue":true}}`
+
+	expectItems(t, runQuery(t, "SELECT DISTINCT VALUE c.x FROM c", plan, partitions), `1`, `{"a":1,"b":2}`, `"x"`, `null`)
+}
+
+func TestNativeQueryEngineDCount(t *testing.T) {
+	partitions := &fakePartitions{pages: map[string]map[string][]string{
+		"SELECT DISTINCT VALUE c.x FROM c": {
+			"0": {`[1,2]`},
+			"1": {`[2,3]`},
+			"2": {`[3,4]`},
+		},
+	}}
+	plan := `{"queryInfo":{"distinctType":"Unordered","aggregates":["Count"],"dCountInfo":{"dCountAlias":"n"},"rewrittenQuery":"SELECT DISTINCT VALUE c.x FROM c"}}`
+
+	expectItems(t, runQuery(t, "SELECT COUNT(1) AS n FROM (SELECT DISTINCT VALUE c.x FROM c)", plan, partitions), `{"n":4}`)
+}
+
+func TestNativeQueryEngineNonStreamingOrderBy(t *testing.T) {
+	rewritten := `SELECT TOP 3 c._rid, [{"item": VectorDistance(c.v, [1,2])}] AS orderByItems, c AS payload FROM c ORDER BY VectorDistance(c.v, [1,2])`
+	partitions := &fakePartitions{pages: map[string]map[string][]string{
+		rewritten: {
+			"0": {"[" + orderByDocument("1", `{"id":"1"}`, "0.9") + "]", "[" + orderByDocument("2", `{"id":"2"}`, "0.5") + "]"},
+			"1": {"[" + orderByDocument("3", `{"id":"3"}`, "0.7") + "," + orderByDocument("4", `{"id":"4"}`, "0.1") + "]"},
+			"2": {"[" + orderByDocument("5", `{"id":"5"}`, "0.8") + "]"},
+		},
+	}}
+	plan, _ := json.Marshal(map[string]any{
+		"queryInfo": map[string]any{
+			"top":                    3,
+			"orderBy":                []string{"Descending"},
+			"orderByExpressions":     []string{"VectorDistance(c.v, [1,2])"},
+			"rewrittenQuery":         rewritten,
+			"hasNonStreamingOrderBy": true,
+		},
+	})
+
+	items := runQuery(t, "SELECT TOP 3 * FROM c ORDER BY VectorDistance(c.v, [1,2])", string(plan), partitions)
+	expectItems(t, items, `{"id":"1"}`, `{"id":"5"}`, `{"id":"3"}`)
+}
+
+func TestNativeQueryEngineHybridSearch(t *testing.T) {
+	statisticsQuery := "SELECT COUNT(1) AS documentCount, [{totalWordCount: SUM(_FullTextWordCount(c.text)), hitCounts: [COUNTIF(FullTextContains(c.text, 'cosmos'))]}] AS fullTextStatistics FROM c"
+	fullTextQuery := "SELECT TOP 10 c._rid, {payload: c, componentScores: [_FullTextScore(c.text, ['cosmos'], {documentdb-formattablehybridsearchquery-totaldocumentcount}, {documentdb-formattablehybridsearchquery-totalwordcount-0}, {documentdb-formattablehybridsearchquery-hitcountsarray-0}), VectorDistance(c.v, [1,2])]} AS payload FROM c WHERE {documentdb-formattableorderbyquery-filter}"
+	vectorQuery := "SELECT TOP 10 c._rid, {payload: c, componentScores: [_FullTextScore(c.text, ['cosmos'], {documentdb-formattablehybridsearchquery-totaldocumentcount}, {documentdb-formattablehybridsearchquery-totalwordcount-0}, {documentdb-formattablehybridsearchquery-hitcountsarray-0}), VectorDistance(c.v, [1,2])]} AS payload FROM c ORDER BY VectorDistance(c.v, [1,2])"
+	formattedFullTextQuery := "SELECT TOP 10 c._rid, {payload: c, componentScores: [_FullTextScore(c.text, ['cosmos'], 6, 60, [3]), VectorDistance(c.v, [1,2])]} AS payload FROM c WHERE true"
+	formattedVectorQuery := "SELECT TOP 10 c._rid, {payload: c, componentScores: [_FullTextScore(c.text, ['cosmos'], 6, 60, [3]), VectorDistance(c.v, [1,2])]} AS payload FROM c ORDER BY VectorDistance(c.v, [1,2])"
+
+	result := func(rid string, fullTextScore float64, vectorScore float64) string {
+		return fmt.Sprintf(`{"_rid":%q,"payload":{"payload":{"id":%q},"componentScores":[%v,%v]}}`, rid, rid, fullTextScore, vectorScore)
+	}
+	partitions := &fakePartitions{pages: map[string]map[string][]string{
+		statisticsQuery: {
+			"0": {`[{"documentCount":2,"fullTextStatistics":[{"totalWordCount":20,"hitCounts":[1]}]}]`},
+			"1": {`[{"documentCount":1,"fullTextStatistics":[{"totalWordCount":10,"hitCounts":[0]}]}]`},
+			"2": {`[{"documentCount":3,"fullTextStatistics":[{"totalWordCount":30,"hitCounts":[2]}]}]`},
+		},
+		formattedFullTextQuery: {
+			"0": {"[" + result("a", 3, 0.1) + "]"},
+			"2": {"[" + result("b", 2, 0.9) + "," + result("c", 1, 0.5) + "]"},
+		},
+		formattedVectorQuery: {
+			"0": {"[" + result("a", 3, 0.1) + "]"},
+			"1": {"[" + result("d", 0, 0.95) + "]"},
+			"2": {"[" + result("b", 2, 0.9) + "]"},
+		},
+	}}
+	plan, _ := json.Marshal(map[string]any{
+		"hybridSearchQueryInfo": map[string]any{
+			"globalStatisticsQuery": statisticsQuery,
+			"componentQueryInfos": []map[string]any{
+				{"orderBy": []string{"Descending"}, "rewrittenQuery": fullTextQuery, "hasNonStreamingOrderBy": true},
+				{"orderBy": []string{"Descending"}, "rewrittenQuery": vectorQuery, "hasNonStreamingOrderBy": true},
+			},
+			"componentWeights":         []float64{1, 2},
+			"skip":                     1,
+			"take":                     2,
+			"requiresGlobalStatistics": true,
+		},
+		"queryRanges": []map[string]any{{"min": "", "max": "FF", "isMinInclusive": true, "isMaxInclusive": false}},
+	})
+
+	items := runQuery(t, "SELECT TOP 2 * FROM c ORDER BY RANK RRF(FullTextScore(c.text, 'cosmos'), VectorDistance(c.v, [1,2]), [1, 2]) OFFSET 1", string(plan), partitions)
+	// Full text ranks: a 1, b 2, c 3, d 4. Vector ranks: d 1, b 2, c 3, a 4.
+	// Scores: d 1/64+2/61, b 1/62+2/62, a 1/61+2/64, c 1/63+2/63.
+	expectItems(t, items, `{"id":"b"}`, `{"id":"a"}`)
+
+	for i, query := range partitions.queries {
+		if i < 3 && query != statisticsQuery {
+			t.Errorf("Expected the statistics to be read first, got %v", query)
+		}
+		if partitions.requests[i].Query == "" || !partitions.requests[i].IncludeParameters {
+			t.Errorf("Expected hybrid search requests to override the query with parameters, got %v", partitions.requests[i])
+		}
+	}
+}
+
+func TestNativeQueryEngineQueryRanges(t *testing.T) {
+	partitions := &fakePartitions{pages: map[string]map[string][]string{
+		"SELECT * FROM c WHERE c.pk = 'a'": {"1": {`[{"id":"1"}]`}},
+	}}
+	plan := `{"queryInfo":{},"queryRanges":[{"min":"50","max":"50","isMinInclusive":true,"isMaxInclusive":true}]}`
+
+	expectItems(t, runQuery(t, "SELECT * FROM c WHERE c.pk = 'a'", plan, partitions), `{"id":"1"}`)
+	if len(partitions.requests) != 1 || partitions.requests[0].PartitionKeyRangeID != "1" {
+		t.Errorf("Expected a single request to partition key range 1, got %v", partitions.requests)
+	}
+}
+
+func TestNativeQueryEngineErrors(t *testing.T) {
+	engine := NewNativeQueryEngine()
+	if _, err := engine.CreateQueryPipeline("SELECT * FROM c", "not json", testPartitionKeyRanges); err == nil {
+		t.Error("Expected an invalid query plan to fail")
+	}
+	if _, err := engine.CreateQueryPipeline("SELECT VALUE MAKELIST(c.x) FROM c", `{"queryInfo":{"aggregates":["MakeList"],"hasSelectValue":true}}`, testPartitionKeyRanges); err == nil {
+		t.Error("Expected an unsupported aggregate to fail")
+	}
+
+	pipeline, err := engine.CreateQueryPipeline("SELECT * FROM c", `{"queryInfo":{}}`, testPartitionKeyRanges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := pipeline.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Requests) != 3 {
+		t.Fatalf("Expected a request per partition key range, got %v", result.Requests)
+	}
+	if err := pipeline.ProvideData([]QueryResult{{PartitionKeyRangeID: "0", RequestId: 42, Data: []byte(`{"Documents":[]}`)}}); err == nil {
+		t.Error("Expected data for an unknown request to fail")
+	}
+	pipeline.Close()
+	if _, err := pipeline.Run(); err == nil {
+		t.Error("Expected running a closed pipeline to fail")
+	}
+}
+
+func TestNativeQueryEngineSupportedFeatures(t *testing.T) {
+	features := NewNativeQueryEngine().SupportedFeatures()
+	for _, feature := range []string{"OrderBy", "GroupBy", "Aggregate", "Distinct", "OffsetAndLimit", "NonStreamingOrderBy", "HybridSearch"} {
+		if !strings.Contains(features, feature) {
+			t.Errorf("Expected feature %s in %s", feature, features)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package queryengine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// nativeQueryEngineSupportedFeatures are the query features the native query engine sends with query plan requests.
+const nativeQueryEngineSupportedFeatures = "Aggregate, CountIf, DCount, Distinct, GroupBy, HybridSearch, MultipleAggregates, MultipleOrderBy, NonStreamingOrderBy, NonValueAggregate, OffsetAndLimit, OrderBy, Top, WeightedRankFusion"
+
+// Placeholders the gateway leaves in rewritten queries, to be replaced before the queries are sent to partitions.
+const (
+	orderByFilterPlaceholder        = "{documentdb-formattableorderbyquery-filter}"
+	totalDocumentCountPlaceholder   = "{documentdb-formattablehybridsearchquery-totaldocumentcount}"
+	totalWordCountPlaceholderFormat = "{documentdb-formattablehybridsearchquery-totalwordcount-%d}"
+	hitCountsArrayPlaceholderFormat = "{documentdb-formattablehybridsearchquery-hitcountsarray-%d}"
+)
+
+// Values of queryInfo fields.
+const (
+	distinctTypeOrdered   = "Ordered"
+	distinctTypeUnordered = "Unordered"
+	sortOrderAscending    = "Ascending"
+)
+
+// queryPlan is the query plan returned by the gateway for a query plan request.
+type queryPlan struct {
+	QueryInfo             *queryInfo             `json:"queryInfo"`
+	HybridSearchQueryInfo *hybridSearchQueryInfo `json:"hybridSearchQueryInfo"`
+	QueryRanges           []queryRange           `json:"queryRanges"`
+}
+
+// queryInfo describes how the results of the partitions are combined.
+type queryInfo struct {
+	DistinctType                string             `json:"distinctType"`
+	Top                         *int64             `json:"top"`
+	Offset                      *int64             `json:"offset"`
+	Limit                       *int64             `json:"limit"`
+	OrderBy                     []string           `json:"orderBy"`
+	OrderByExpressions          []string           `json:"orderByExpressions"`
+	GroupByExpressions          []string           `json:"groupByExpressions"`
+	GroupByAliases              []string           `json:"groupByAliases"`
+	Aggregates                  []string           `json:"aggregates"`
+	GroupByAliasToAggregateType map[string]*string `json:"groupByAliasToAggregateType"`
+	RewrittenQuery              string             `json:"rewrittenQuery"`
+	HasSelectValue              bool               `json:"hasSelectValue"`
+	DCountInfo                  *dCountInfo        `json:"dCountInfo"`
+	HasNonStreamingOrderBy      bool               `json:"hasNonStreamingOrderBy"`
+}
+
+type dCountInfo struct {
+	DCountAlias string `json:"dCountAlias"`
+}
+
+// hybridSearchQueryInfo describes a query ordered by the rank fusion of full text and vector search scores.
+type hybridSearchQueryInfo struct {
+	GlobalStatisticsQuery    string      `json:"globalStatisticsQuery"`
+	ComponentQueryInfos      []queryInfo `json:"componentQueryInfos"`
+	ComponentWeights         []float64   `json:"componentWeights"`
+	Skip                     *int64      `json:"skip"`
+	Take                     *int64      `json:"take"`
+	RequiresGlobalStatistics bool        `json:"requiresGlobalStatistics"`
+}
+
+// queryRange is a range of effective partition keys targeted by the query.
+type queryRange struct {
+	Min            string `json:"min"`
+	Max            string `json:"max"`
+	IsMinInclusive bool   `json:"isMinInclusive"`
+	IsMaxInclusive bool   `json:"isMaxInclusive"`
+}
+
+type partitionKeyRange struct {
+	ID           string `json:"id"`
+	MinInclusive string `json:"minInclusive"`
+	MaxExclusive string `json:"maxExclusive"`
+}
+
+type partitionKeyRanges struct {
+	PartitionKeyRanges []partitionKeyRange `json:"PartitionKeyRanges"`
+}
+
+// overlaps reports whether the query range contains effective partition keys of the partition key range.
+func (r queryRange) overlaps(pkRange partitionKeyRange) bool {
+	if r.Min >= pkRange.MaxExclusive {
+		return false
+	}
+	if r.Max < pkRange.MinInclusive || (r.Max == pkRange.MinInclusive && !r.IsMaxInclusive) {
+		return false
+	}
+	return true
+}
+
+func parseQueryPlan(plan string) (queryPlan, error) {
+	var parsed queryPlan
+	if err := json.Unmarshal([]byte(plan), &parsed); err != nil {
+		return queryPlan{}, fmt.Errorf("failed to unmarshal query plan: %w", err)
+	}
+	if parsed.QueryInfo == nil && parsed.HybridSearchQueryInfo == nil {
+		parsed.QueryInfo = &queryInfo{}
+	}
+	return parsed, nil
+}
+
+// targetPartitionKeyRanges returns the partition key ranges overlapping the query ranges, sorted by effective
+// partition key.
+func targetPartitionKeyRanges(pkranges string, queryRanges []queryRange) ([]partitionKeyRange, error) {
+	var parsed partitionKeyRanges
+	if err := json.Unmarshal([]byte(pkranges), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal partition key ranges: %w", err)
+	}
+	if len(parsed.PartitionKeyRanges) == 0 {
+		return nil, errors.New("no partition key ranges")
+	}
+	sort.Slice(parsed.PartitionKeyRanges, func(i, j int) bool {
+		return parsed.PartitionKeyRanges[i].MinInclusive < parsed.PartitionKeyRanges[j].MinInclusive
+	})
+	if len(queryRanges) == 0 {
+		return parsed.PartitionKeyRanges, nil
+	}
+
+	var targets []partitionKeyRange
+	for _, pkRange := range parsed.PartitionKeyRanges {
+		for _, r := range queryRanges {
+			if r.overlaps(pkRange) {
+				targets = append(targets, pkRange)
+				break
+			}
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,373 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package queryengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// unorderedSource returns the results of the partition key ranges in the order of the ranges.
+type unorderedSource struct {
+	producers []*partitionProducer
+}
+
+func (s *unorderedSource) next() ([]json.RawMessage, bool, error) {
+	var results []json.RawMessage
+	for _, producer := range s.producers {
+		for len(producer.queue) > 0 {
+			results = append(results, producer.pop())
+		}
+		if !producer.done() {
+			// Results of the following ranges are returned once this range is done.
+			return results, false, nil
+		}
+	}
+	return results, true, nil
+}
+
+// orderByResult is a result of a query with ORDER BY, as rewritten by the gateway.
+type orderByResult struct {
+	Rid          string `json:"_rid"`
+	OrderByItems []struct {
+		Item json.RawMessage `json:"item"`
+	} `json:"orderByItems"`
+	Payload json.RawMessage `json:"payload"`
+
+	values []value
+}
+
+func decodeOrderByResult(raw json.RawMessage) (*orderByResult, error) {
+	var result orderByResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ORDER BY result: %w", err)
+	}
+	result.values = make([]value, len(result.OrderByItems))
+	for i, item := range result.OrderByItems {
+		v, err := decodeValue(item.Item)
+		if err != nil {
+			return nil, err
+		}
+		result.values[i] = v
+	}
+	return &result, nil
+}
+
+// compareOrderByResults compares results by their ORDER BY items and sort orders.
+func compareOrderByResults(a, b *orderByResult, orderBy []string) int {
+	for i, order := range orderBy {
+		if i >= len(a.values) || i >= len(b.values) {
+			break
+		}
+		c := compareValues(a.values[i], b.values[i])
+		if order != sortOrderAscending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// orderBySource merges the sorted results of the partition key ranges. A result is only returned once every
+// partition key range that isn't done buffers a result to compare it with.
+type orderBySource struct {
+	producers []*partitionProducer
+	orderBy   []string
+	// heads caches the decoded first result of the producers.
+	heads map[*partitionProducer]*orderByResult
+}
+
+func (s *orderBySource) next() ([]json.RawMessage, bool, error) {
+	var results []json.RawMessage
+	for {
+		var minProducer *partitionProducer
+		var minHead *orderByResult
+		for _, producer := range s.producers {
+			if producer.done() {
+				continue
+			}
+			if len(producer.queue) == 0 {
+				return results, false, nil
+			}
+			head, err := s.head(producer)
+			if err != nil {
+				return nil, false, err
+			}
+			// Ties are broken by the order of the partition key ranges.
+			if minHead == nil || compareOrderByResults(head, minHead, s.orderBy) < 0 {
+				minProducer, minHead = producer, head
+			}
+		}
+		if minProducer == nil {
+			return results, true, nil
+		}
+		minProducer.pop()
+		delete(s.heads, minProducer)
+		if minHead.Payload != nil {
+			results = append(results, minHead.Payload)
+		}
+	}
+}
+
+func (s *orderBySource) head(producer *partitionProducer) (*orderByResult, error) {
+	if head, ok := s.heads[producer]; ok {
+		return head, nil
+	}
+	head, err := decodeOrderByResult(producer.queue[0])
+	if err != nil {
+		return nil, err
+	}
+	s.heads[producer] = head
+	return head, nil
+}
+
+// drainingSource reads all the results of the partition key ranges before combining them.
+type drainingSource struct {
+	producers []*partitionProducer
+	combine   func([]*partitionProducer) ([]json.RawMessage, error)
+}
+
+func newDrainingSource(p *nativeQueryPipeline, combine func([]*partitionProducer) ([]json.RawMessage, error)) *drainingSource {
+	p.producers = p.newProducers("", true)
+	return &drainingSource{producers: p.producers, combine: combine}
+}
+
+func (s *drainingSource) next() ([]json.RawMessage, bool, error) {
+	for _, producer := range s.producers {
+		if !producer.exhausted() {
+			return nil, false, nil
+		}
+	}
+	results, err := s.combine(s.producers)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, producer := range s.producers {
+		producer.queue = nil
+	}
+	return results, true, nil
+}
+
+// sortResults sorts the results of queries with ORDER BY that the partitions can't return sorted incrementally, such
+// as vector search queries.
+func sortResults(producers []*partitionProducer, orderBy []string) ([]json.RawMessage, error) {
+	var all []*orderByResult
+	for _, producer := range producers {
+		for _, raw := range producer.queue {
+			result, err := decodeOrderByResult(raw)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, result)
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return compareOrderByResults(all[i], all[j], orderBy) < 0 })
+	results := make([]json.RawMessage, 0, len(all))
+	for _, result := range all {
+		if result.Payload != nil {
+			results = append(results, result.Payload)
+		}
+	}
+	return results, nil
+}
+
+// partialAggregates is a result of a query with SELECT VALUE and an aggregate, as rewritten by the gateway.
+type partialAggregates []struct {
+	Item json.RawMessage `json:"item"`
+}
+
+// aggregateValue combines the partial aggregates of a query with SELECT VALUE and an aggregate.
+func aggregateValue(producers []*partitionProducer, aggregateType string) ([]json.RawMessage, error) {
+	agg, err := newAggregator(aggregateType)
+	if err != nil {
+		return nil, err
+	}
+	for _, producer := range producers {
+		for _, raw := range producer.queue {
+			var partials partialAggregates
+			if err := json.Unmarshal(raw, &partials); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal partial aggregate: %w", err)
+			}
+			if len(partials) == 0 {
+				continue
+			}
+			if err := agg.add(partials[0].Item); err != nil {
+				return nil, err
+			}
+		}
+	}
+	result, err := agg.result()
+	if err != nil || result == nil {
+		return nil, err
+	}
+	return []json.RawMessage{result}, nil
+}
+
+func validateAggregates(aliasToAggregateType map[string]*string) error {
+	for _, aggregateType := range aliasToAggregateType {
+		if aggregateType == nil {
+			continue
+		}
+		if _, err := newAggregator(*aggregateType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupByResult is a result of a query with GROUP BY or aggregates without SELECT VALUE, as rewritten by the gateway.
+type groupByResult struct {
+	GroupByItems json.RawMessage            `json:"groupByItems"`
+	Payload      map[string]json.RawMessage `json:"payload"`
+}
+
+// group accumulates the results of a group.
+type group struct {
+	values      map[string]json.RawMessage
+	aggregators map[string]aggregator
+}
+
+// groupBy combines the partial groups of the partitions.
+func groupBy(producers []*partitionProducer, info *queryInfo) ([]json.RawMessage, error) {
+	aliases := info.GroupByAliases
+	if len(aliases) == 0 {
+		for alias := range info.GroupByAliasToAggregateType {
+			aliases = append(aliases, alias)
+		}
+		sort.Strings(aliases)
+	}
+
+	groups := map[string]*group{}
+	var keys []string
+	for _, producer := range producers {
+		for _, raw := range producer.queue {
+			var result groupByResult
+			if err := json.Unmarshal(raw, &result); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal GROUP BY result: %w", err)
+			}
+			key, err := canonicalRawJSON(result.GroupByItems)
+			if err != nil {
+				return nil, err
+			}
+			g, ok := groups[key]
+			if !ok {
+				g = &group{values: map[string]json.RawMessage{}, aggregators: map[string]aggregator{}}
+				groups[key] = g
+				keys = append(keys, key)
+			}
+			for alias, aggregateType := range info.GroupByAliasToAggregateType {
+				if aggregateType == nil {
+					if _, ok := g.values[alias]; !ok && result.Payload[alias] != nil {
+						g.values[alias] = result.Payload[alias]
+					}
+					continue
+				}
+				agg, ok := g.aggregators[alias]
+				if !ok {
+					if agg, err = newAggregator(*aggregateType); err != nil {
+						return nil, err
+					}
+					g.aggregators[alias] = agg
+				}
+				var partial struct {
+					Item json.RawMessage `json:"item"`
+				}
+				if raw := result.Payload[alias]; raw != nil {
+					if err := json.Unmarshal(raw, &partial); err != nil {
+						return nil, fmt.Errorf("failed to unmarshal partial aggregate: %w", err)
+					}
+				}
+				if err := agg.add(partial.Item); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	results := make([]json.RawMessage, 0, len(keys))
+	for _, key := range keys {
+		g := groups[key]
+		for alias, agg := range g.aggregators {
+			v, err := agg.result()
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				g.values[alias] = v
+			}
+		}
+		if info.HasSelectValue {
+			// SELECT VALUE queries have a single alias.
+			for _, alias := range aliases {
+				if v, ok := g.values[alias]; ok {
+					results = append(results, v)
+				}
+			}
+			continue
+		}
+		result, err := marshalOrderedObject(aliases, g.values)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// marshalOrderedObject marshals the defined values as a JSON object with properties in the order of keys.
+func marshalOrderedObject(keys []string, values map[string]json.RawMessage) (json.RawMessage, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	first := true
+	for _, key := range keys {
+		v, ok := values[key]
+		if !ok {
+			continue
+		}
+		if !first {
+			buffer.WriteByte(',')
+		}
+		first = false
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(name)
+		buffer.WriteByte(':')
+		buffer.Write(v)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// distinctCount counts the distinct results of the partitions, for queries like SELECT VALUE COUNT(1) FROM
+// (SELECT DISTINCT VALUE c.x FROM c).
+func distinctCount(producers []*partitionProducer, alias string) ([]json.RawMessage, error) {
+	seen := map[string]struct{}{}
+	for _, producer := range producers {
+		for _, raw := range producer.queue {
+			key, err := canonicalRawJSON(raw)
+			if err != nil {
+				return nil, err
+			}
+			seen[key] = struct{}{}
+		}
+	}
+	count, err := json.Marshal(len(seen))
+	if err != nil {
+		return nil, err
+	}
+	if alias == "" {
+		return []json.RawMessage{count}, nil
+	}
+	result, err := marshalOrderedObject([]string{alias}, map[string]json.RawMessage{alias: count})
+	if err != nil {
+		return nil, err
+	}
+	return []json.RawMessage{result}, nil
+}
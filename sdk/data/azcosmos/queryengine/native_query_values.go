@@ -0,0 +1,278 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package queryengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// value is a decoded JSON value. Undefined values, such as missing properties, have defined set to false.
+type value struct {
+	defined bool
+	v       any
+}
+
+func decodeValue(raw json.RawMessage) (value, error) {
+	if raw == nil {
+		return value{}, nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return value{}, err
+	}
+	return value{defined: true, v: v}, nil
+}
+
+// typeOrder returns the position of the type of the value in the order of types used by ORDER BY.
+func (v value) typeOrder() int {
+	if !v.defined {
+		return 0
+	}
+	switch v.v.(type) {
+	case nil:
+		return 1
+	case bool:
+		return 2
+	case float64:
+		return 3
+	case string:
+		return 4
+	case []any:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// compareValues compares values like ORDER BY: undefined, null, booleans, numbers and strings are ordered in that
+// sequence, and values of the same type by their natural order.
+func compareValues(a, b value) int {
+	if ta, tb := a.typeOrder(), b.typeOrder(); ta != tb {
+		return ta - tb
+	}
+	switch av := a.v.(type) {
+	case bool:
+		bv := b.v.(bool)
+		switch {
+		case av == bv:
+			return 0
+		case !av:
+			return -1
+		default:
+			return 1
+		}
+	case float64:
+		bv := b.v.(float64)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		default:
+			return 0
+		}
+	case string:
+		return strings.Compare(av, b.v.(string))
+	case []any, map[string]any:
+		return strings.Compare(canonicalJSON(a.v), canonicalJSON(b.v))
+	}
+	return 0
+}
+
+// canonicalJSON returns a JSON representation of a decoded value in which equal values are equal strings.
+func canonicalJSON(v any) string {
+	// Maps are marshalled with sorted keys, and numbers were normalized by decoding them as float64.
+	marshalled, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(marshalled)
+}
+
+// canonicalRawJSON returns the canonical JSON representation of a raw value.
+func canonicalRawJSON(raw json.RawMessage) (string, error) {
+	v, err := decodeValue(raw)
+	if err != nil {
+		return "", err
+	}
+	if !v.defined {
+		return "", nil
+	}
+	return canonicalJSON(v.v), nil
+}
+
+// aggregator combines the partial aggregates computed by partitions.
+type aggregator interface {
+	add(partial json.RawMessage) error
+	// result returns the aggregate, or nil when it is undefined.
+	result() (json.RawMessage, error)
+}
+
+func newAggregator(aggregateType string) (aggregator, error) {
+	switch aggregateType {
+	case "Count", "CountIf", "Sum":
+		return &sumAggregator{defined: true}, nil
+	case "Average":
+		return &averageAggregator{}, nil
+	case "Min":
+		return &minMaxAggregator{field: "min"}, nil
+	case "Max":
+		return &minMaxAggregator{field: "max", max: true}, nil
+	default:
+		return nil, fmt.Errorf("unsupported aggregate %s", aggregateType)
+	}
+}
+
+// sumAggregator adds partial counts or sums. A sum is undefined when a partition had values that aren't numbers.
+type sumAggregator struct {
+	sum     float64
+	defined bool
+}
+
+func (a *sumAggregator) add(partial json.RawMessage) error {
+	v, err := decodeValue(partial)
+	if err != nil {
+		return err
+	}
+	n, ok := v.v.(float64)
+	if !ok {
+		a.defined = false
+		return nil
+	}
+	a.sum += n
+	return nil
+}
+
+func (a *sumAggregator) result() (json.RawMessage, error) {
+	if !a.defined {
+		return nil, nil
+	}
+	return json.Marshal(a.sum)
+}
+
+// averageAggregator combines partial sums and counts.
+type averageAggregator struct {
+	sum     float64
+	count   float64
+	invalid bool
+}
+
+func (a *averageAggregator) add(partial json.RawMessage) error {
+	var p struct {
+		Sum   *float64 `json:"sum"`
+		Count float64  `json:"count"`
+	}
+	if partial == nil {
+		return nil
+	}
+	if err := json.Unmarshal(partial, &p); err != nil {
+		return err
+	}
+	if p.Count == 0 {
+		return nil
+	}
+	if p.Sum == nil {
+		a.invalid = true
+		return nil
+	}
+	a.sum += *p.Sum
+	a.count += p.Count
+	return nil
+}
+
+func (a *averageAggregator) result() (json.RawMessage, error) {
+	if a.invalid || a.count == 0 {
+		return nil, nil
+	}
+	return json.Marshal(a.sum / a.count)
+}
+
+// minMaxAggregator keeps the minimum or maximum of the partial results. Partitions return either the value, or an
+// object with the value and the number of values it was computed from.
+type minMaxAggregator struct {
+	field   string
+	max     bool
+	current value
+	invalid bool
+}
+
+func (a *minMaxAggregator) add(partial json.RawMessage) error {
+	v, err := decodeValue(partial)
+	if err != nil {
+		return err
+	}
+	if obj, ok := v.v.(map[string]any); ok {
+		if count, hasCount := obj["count"].(float64); hasCount {
+			if count == 0 {
+				return nil
+			}
+			inner, hasValue := obj[a.field]
+			if !hasValue {
+				// The partition had values of types that can't be compared.
+				a.invalid = true
+				return nil
+			}
+			v = value{defined: true, v: inner}
+		}
+	}
+	if !v.defined {
+		return nil
+	}
+	if !a.current.defined {
+		a.current = v
+		return nil
+	}
+	if c := compareValues(v, a.current); (a.max && c > 0) || (!a.max && c < 0) {
+		a.current = v
+	}
+	return nil
+}
+
+func (a *minMaxAggregator) result() (json.RawMessage, error) {
+	if a.invalid || !a.current.defined {
+		return nil, nil
+	}
+	return json.Marshal(a.current.v)
+}
+
+// distinctFilter removes duplicates from the results. Ordered queries only need to compare an item with the previous
+// one, while unordered queries remember every item.
+type distinctFilter struct {
+	ordered bool
+	last    *string
+	seen    map[string]struct{}
+}
+
+func newDistinctFilter(distinctType string) *distinctFilter {
+	switch distinctType {
+	case distinctTypeOrdered:
+		return &distinctFilter{ordered: true}
+	case distinctTypeUnordered:
+		return &distinctFilter{seen: map[string]struct{}{}}
+	default:
+		return nil
+	}
+}
+
+// isDuplicate reports whether the item was already returned, and records it otherwise.
+func (f *distinctFilter) isDuplicate(item json.RawMessage) (bool, error) {
+	key, err := canonicalRawJSON(item)
+	if err != nil {
+		return false, err
+	}
+	if f.ordered {
+		if f.last != nil && *f.last == key {
+			return true, nil
+		}
+		f.last = &key
+		return false, nil
+	}
+	if _, ok := f.seen[key]; ok {
+		return true, nil
+	}
+	f.seen[key] = struct{}{}
+	return false, nil
+}
@@ -0,0 +1,187 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	azlog "github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/errorinfo"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
+)
+
+// NewResourceTokenCredential creates a ResourceTokenCredential containing the
+// resource tokens of a set of permissions.
+// permissions - The permissions, as returned by UserClient.CreatePermission or UserClient.ReadPermission.
+// Only ResourceLink, ResourcePartitionKey and Token are used.
+func NewResourceTokenCredential(permissions []PermissionProperties) (ResourceTokenCredential, error) {
+	c := ResourceTokenCredential{}
+	if err := c.Update(permissions); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// ResourceTokenCredential contains the resource tokens of a set of permissions.
+// Each request is authenticated with the token of the permission on the most specific resource
+// that contains the requested resource, and whose partition key matches the partition key of the request.
+// It is goroutine-safe, and copies share the tokens set with Update.
+type ResourceTokenCredential struct {
+	// Only the ResourceTokenCredential methods should set this; all other methods should treat it as read-only
+	tokens *atomic.Pointer[[]resourceToken]
+}
+
+// resourceToken is the resource token of a permission on a resource, optionally restricted to a partition key.
+type resourceToken struct {
+	// link is the unescaped link of the resource, without leading or trailing slashes.
+	link string
+	// partitionKey is the JSON representation of the partition key, or empty when the permission applies to the whole resource.
+	partitionKey string
+	token        string
+}
+
+// Update replaces the existing resource tokens with the tokens of the specified permissions,
+// such as permissions read again after their tokens expired.
+func (c *ResourceTokenCredential) Update(permissions []PermissionProperties) error {
+	if len(permissions) == 0 {
+		return errors.New("at least one permission is required")
+	}
+
+	tokens := make([]resourceToken, 0, len(permissions))
+	for _, permission := range permissions {
+		if permission.Token == "" {
+			return fmt.Errorf("permission %s has no resource token", permission.ID)
+		}
+		if permission.ResourceLink == "" {
+			return fmt.Errorf("permission %s has no resource link", permission.ID)
+		}
+		token := resourceToken{
+			link:  normalizeResourceLink(permission.ResourceLink),
+			token: permission.Token,
+		}
+		if permission.ResourcePartitionKey != nil && len(permission.ResourcePartitionKey.values) > 0 {
+			pk, err := permission.ResourcePartitionKey.toJsonString()
+			if err != nil {
+				return err
+			}
+			token.partitionKey = pk
+		}
+		tokens = append(tokens, token)
+	}
+	if c.tokens == nil {
+		c.tokens = &atomic.Pointer[[]resourceToken]{}
+	}
+	c.tokens.Store(&tokens)
+	return nil
+}
+
+// tokenFor returns the resource token to authenticate a request on the resource, with the partition key of the request,
+// or an empty partitionKey when the request doesn't target a partition key.
+//
+// Tokens of the resource or its closest parent are preferred. Requests on a parent of the resources of all the
+// permissions, such as reading the container of a permission on a partition key, use the token of a child resource.
+func (c *ResourceTokenCredential) tokenFor(resourceAddress string, partitionKey string) (string, error) {
+	if c.tokens == nil || c.tokens.Load() == nil {
+		return "", errors.New("no resource tokens")
+	}
+	tokens := c.tokens.Load()
+	address := normalizeResourceLink(resourceAddress)
+
+	var best *resourceToken
+	bestScore := -1
+	var child *resourceToken
+	for i := range *tokens {
+		token := &(*tokens)[i]
+		matchesPartitionKey := token.partitionKey == "" || partitionKey == "" || token.partitionKey == partitionKey
+		switch {
+		case isResourceLinkParent(token.link, address):
+			if !matchesPartitionKey {
+				continue
+			}
+			// Prefer the most specific resource, then the partition key of the request.
+			score := 2 * len(token.link)
+			if token.partitionKey != "" && token.partitionKey == partitionKey {
+				score++
+			}
+			if score > bestScore {
+				best, bestScore = token, score
+			}
+		case child == nil && isResourceLinkParent(address, token.link):
+			if matchesPartitionKey {
+				child = token
+			}
+		}
+	}
+
+	if best != nil {
+		return best.token, nil
+	}
+	if child != nil {
+		return child.token, nil
+	}
+	return "", fmt.Errorf("no resource token grants access to %s", resourceAddress)
+}
+
+// isResourceLinkParent reports whether the resource link is the resource address or one of its parents.
+func isResourceLinkParent(link string, address string) bool {
+	return link == "" || link == address || strings.HasPrefix(address, link+"/")
+}
+
+func normalizeResourceLink(link string) string {
+	link = strings.Trim(link, "/")
+	if unescaped, err := url.PathUnescape(link); err == nil {
+		link = unescaped
+	}
+	return link
+}
+
+type resourceTokenCredPolicy struct {
+	cred ResourceTokenCredential
+}
+
+func newResourceTokenCredPolicy(cred ResourceTokenCredential) *resourceTokenCredPolicy {
+	return &resourceTokenCredPolicy{
+		cred: cred,
+	}
+}
+
+func (s *resourceTokenCredPolicy) Do(req *policy.Request) (*http.Response, error) {
+	// Add a x-ms-date header if it doesn't already exist
+	if d := req.Raw().Header.Get(headerXmsDate); d == "" {
+		req.Raw().Header.Set(headerXmsDate, time.Now().UTC().Format(http.TimeFormat))
+	}
+
+	var opValues pipelineRequestOptions
+	if req.OperationValue(&opValues) {
+		partitionKey := ""
+		if opValues.headerOptionsOverride != nil && opValues.headerOptionsOverride.partitionKey != nil && len(opValues.headerOptionsOverride.partitionKey.values) > 0 {
+			pk, err := opValues.headerOptionsOverride.partitionKey.toJsonString()
+			if err != nil {
+				return nil, err
+			}
+			partitionKey = pk
+		}
+
+		token, err := s.cred.tokenFor(opValues.resourceAddress, partitionKey)
+		if err != nil {
+			// Retrying can't find a token either.
+			return nil, errorinfo.NonRetriableError(err)
+		}
+		req.Raw().Header.Set(headerAuthorization, url.QueryEscape(token))
+	}
+
+	response, err := req.Next()
+	if err == nil && response != nil && response.StatusCode == http.StatusForbidden {
+		// Service rejected the resource token, log the resource it was used for
+		log.Write(azlog.EventResponse, "===== HTTP Forbidden status with resource token for:\n"+opValues.resourceAddress+"\n=====\n")
+	}
+	return response, err
+}
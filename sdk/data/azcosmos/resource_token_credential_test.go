@@ -0,0 +1,122 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestResourceTokenCredential(t *testing.T) ResourceTokenCredential {
+	pk1 := NewPartitionKeyString("tenant1")
+	pk2 := NewPartitionKeyString("tenant2")
+	cred, err := NewResourceTokenCredential([]PermissionProperties{
+		{ID: "orders", ResourceLink: "dbs/db/colls/orders", Token: "orders-token"},
+		{ID: "tenant1", ResourceLink: "/dbs/db/colls/users/", ResourcePartitionKey: &pk1, Token: "tenant1-token"},
+		{ID: "tenant2", ResourceLink: "dbs/db/colls/users", ResourcePartitionKey: &pk2, Token: "tenant2-token"},
+		{ID: "profile", ResourceLink: "dbs/db/colls/profiles/docs/my profile", Token: "profile-token"},
+	})
+	require.NoError(t, err)
+	return cred
+}
+
+func TestResourceTokenCredentialTokenFor(t *testing.T) {
+	cred := newTestResourceTokenCredential(t)
+
+	tests := []struct {
+		name         string
+		address      string
+		partitionKey string
+		expected     string
+	}{
+		{"container", "dbs/db/colls/orders", "", "orders-token"},
+		{"item of container", "dbs/db/colls/orders/docs/order1", `["any"]`, "orders-token"},
+		{"partition", "dbs/db/colls/users/docs/u1", `["tenant2"]`, "tenant2-token"},
+		{"partition feed", "dbs/db/colls/users", `["tenant1"]`, "tenant1-token"},
+		{"escaped item", "dbs/db/colls/profiles/docs/my%20profile", "", "profile-token"},
+		{"parent of item", "dbs/db/colls/profiles", "", "profile-token"},
+		{"account", "", "", "orders-token"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token, err := cred.tokenFor(test.address, test.partitionKey)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, token)
+		})
+	}
+
+	_, err := cred.tokenFor("dbs/db/colls/users/docs/u3", `["tenant3"]`)
+	require.Error(t, err)
+
+	_, err = cred.tokenFor("dbs/db/colls/other", "")
+	require.Error(t, err)
+}
+
+func TestResourceTokenCredentialUpdate(t *testing.T) {
+	cred := newTestResourceTokenCredential(t)
+	clientCopy := cred
+
+	err := cred.Update([]PermissionProperties{{ID: "orders", ResourceLink: "dbs/db/colls/orders", Token: "renewed-token"}})
+	require.NoError(t, err)
+
+	token, err := clientCopy.tokenFor("dbs/db/colls/orders/docs/order1", "")
+	require.NoError(t, err)
+	require.Equal(t, "renewed-token", token)
+
+	require.Error(t, cred.Update(nil))
+	require.Error(t, cred.Update([]PermissionProperties{{ID: "noToken", ResourceLink: "dbs/db"}}))
+	require.Error(t, cred.Update([]PermissionProperties{{ID: "noLink", Token: "token"}}))
+
+	_, err = NewResourceTokenCredential(nil)
+	require.Error(t, err)
+}
+
+func TestResourceTokenCredPolicy(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+
+	verifier := pipelineVerifier{}
+	pl := azruntime.NewPipeline("azcosmostest", "v1.0.0", azruntime.PipelineOptions{PerRetry: []policy.Policy{newResourceTokenCredPolicy(newTestResourceTokenCredential(t)), &verifier}}, &policy.ClientOptions{Transport: srv})
+
+	pk := NewPartitionKeyString("tenant1")
+	req, err := azruntime.NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	req.SetOperationValue(pipelineRequestOptions{
+		resourceType:          resourceTypeDocument,
+		resourceAddress:       "dbs/db/colls/users/docs/u1",
+		headerOptionsOverride: &headerOptionsOverride{partitionKey: &pk},
+	})
+
+	_, err = pl.Do(req)
+	require.NoError(t, err)
+
+	require.Equal(t, url.QueryEscape("tenant1-token"), verifier.requests[0].headers.Get(headerAuthorization))
+	require.NotEmpty(t, verifier.requests[0].headers.Get(headerXmsDate))
+
+	req, err = azruntime.NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	req.SetOperationValue(pipelineRequestOptions{
+		resourceType:    resourceTypeDocument,
+		resourceAddress: "dbs/db/colls/unknown/docs/u1",
+	})
+
+	_, err = pl.Do(req)
+	require.Error(t, err)
+	require.Len(t, verifier.requests, 1)
+}
+
+func TestNewClientWithResourceTokens(t *testing.T) {
+	client, err := NewClientWithResourceTokens("https://localhost:8081/", newTestResourceTokenCredential(t), nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://localhost:8081/", client.Endpoint())
+}
@@ -3,6 +3,8 @@
 
 package azcosmos
 
+import "strconv"
+
 // resourceType defines supported values for resources.
 type resourceType int
 
@@ -21,3 +23,37 @@ const (
 	resourceTypePartitionKeyRange   resourceType = 125
 	resourceTypeClientEncryptionKey resourceType = 141
 )
+
+// String returns the name of the resource type.
+func (r resourceType) String() string {
+	switch r {
+	case resourceTypeDatabase:
+		return "Database"
+	case resourceTypeCollection:
+		return "Collection"
+	case resourceTypeDocument:
+		return "Document"
+	case resourceTypeUser:
+		return "User"
+	case resourceTypePermission:
+		return "Permission"
+	case resourceTypeConflict:
+		return "Conflict"
+	case resourceTypeStoredProcedure:
+		return "StoredProcedure"
+	case resourceTypeTrigger:
+		return "Trigger"
+	case resourceTypeUserDefinedFunction:
+		return "UserDefinedFunction"
+	case resourceTypeOffer:
+		return "Offer"
+	case resourceTypeDatabaseAccount:
+		return "DatabaseAccount"
+	case resourceTypePartitionKeyRange:
+		return "PartitionKeyRange"
+	case resourceTypeClientEncryptionKey:
+		return "ClientEncryptionKey"
+	default:
+		return strconv.Itoa(int(r))
+	}
+}
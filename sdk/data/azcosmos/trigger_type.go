@@ -0,0 +1,50 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+// TriggerType specifies when a trigger is executed relative to the operation it is attached to.
+type TriggerType string
+
+const (
+	// The trigger is executed before the operation.
+	TriggerTypePre TriggerType = "Pre"
+	// The trigger is executed after the operation.
+	TriggerTypePost TriggerType = "Post"
+)
+
+// Returns a list of available trigger types
+func TriggerTypeValues() []TriggerType {
+	return []TriggerType{TriggerTypePre, TriggerTypePost}
+}
+
+// ToPtr returns a *TriggerType
+func (c TriggerType) ToPtr() *TriggerType {
+	return &c
+}
+
+// TriggerOperation specifies the item operations a trigger can be attached to.
+type TriggerOperation string
+
+const (
+	// The trigger can be attached to any operation.
+	TriggerOperationAll TriggerOperation = "All"
+	// The trigger can be attached to create operations.
+	TriggerOperationCreate TriggerOperation = "Create"
+	// The trigger can be attached to update operations.
+	TriggerOperationUpdate TriggerOperation = "Update"
+	// The trigger can be attached to delete operations.
+	TriggerOperationDelete TriggerOperation = "Delete"
+	// The trigger can be attached to replace operations.
+	TriggerOperationReplace TriggerOperation = "Replace"
+)
+
+// Returns a list of available trigger operations
+func TriggerOperationValues() []TriggerOperation {
+	return []TriggerOperation{TriggerOperationAll, TriggerOperationCreate, TriggerOperationUpdate, TriggerOperationDelete, TriggerOperationReplace}
+}
+
+// ToPtr returns a *TriggerOperation
+func (c TriggerOperation) ToPtr() *TriggerOperation {
+	return &c
+}
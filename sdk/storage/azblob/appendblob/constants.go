@@ -0,0 +1,20 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package appendblob
+
+const (
+	_1MiB = 1024 * 1024
+
+	// MaxAppendBlockBytes indicates the maximum number of bytes that can be sent in a call to AppendBlock.
+	MaxAppendBlockBytes = 100 * _1MiB // 100MiB
+
+	// MaxBlocks indicates the maximum number of blocks allowed in an append blob.
+	MaxBlocks = 50000
+
+	// DefaultWriterBlockSize is the default size of the blocks appended by a Writer.
+	DefaultWriterBlockSize = 4 * _1MiB // 4MiB
+)
@@ -7,6 +7,8 @@
 package appendblob
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
@@ -181,3 +183,58 @@ type ExpiryTypeNever = exported.ExpiryTypeNever
 
 // SetExpiryOptions contains the optional parameters for the Client.SetExpiry method.
 type SetExpiryOptions = exported.SetExpiryOptions
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// WriterOptions contains the optional parameters for the Client.NewWriter method.
+type WriterOptions struct {
+	// BlockSize is the size of the blocks appended to the blob; writes are buffered until a block is full.
+	// A Write no larger than BlockSize is never split across blocks or blobs.
+	// The default is DefaultWriterBlockSize and the maximum is MaxAppendBlockBytes.
+	BlockSize int64
+
+	// FlushInterval, when greater than zero, flushes buffered data at least this often.
+	FlushInterval time.Duration
+
+	// MaxBlobSize, when greater than zero, limits the size of each blob in bytes. It's enforced by the service
+	// with the max-size append condition; a block that would grow the blob beyond MaxBlobSize is appended to the next blob.
+	// It must not be smaller than BlockSize.
+	MaxBlobSize int64
+
+	// RolloverName returns the name of the blob to write once the blob named blobName is full or sealed.
+	// index starts at 1 and increments with each rollover. The default appends "."+index to blobName.
+	// A blob that already exists is appended to if it's neither sealed nor full, so that a restarted
+	// writer resumes where it left off.
+	RolloverName func(blobName string, index int) string
+
+	// SealOnClose seals each blob once the Writer is done with it, i.e. on rollover and on Close.
+	SealOnClose bool
+
+	// CreateOptions is used when the Writer creates a blob.
+	CreateOptions *CreateOptions
+
+	// TransactionalValidation specifies the transfer validation type to use for each block.
+	TransactionalValidation blob.TransferValidationType
+
+	CPKInfo *blob.CPKInfo
+
+	CPKScopeInfo *blob.CPKScopeInfo
+}
+
+func (o *WriterOptions) format() error {
+	if o.BlockSize == 0 {
+		o.BlockSize = DefaultWriterBlockSize
+	}
+	if o.BlockSize < 0 || o.BlockSize > MaxAppendBlockBytes {
+		return fmt.Errorf("BlockSize must be between 1 and %d", MaxAppendBlockBytes)
+	}
+	if o.MaxBlobSize < 0 || (o.MaxBlobSize > 0 && o.MaxBlobSize < o.BlockSize) {
+		return errors.New("MaxBlobSize must not be smaller than BlockSize")
+	}
+	if o.RolloverName == nil {
+		o.RolloverName = func(blobName string, index int) string {
+			return fmt.Sprintf("%s.%d", blobName, index)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,343 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package appendblob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/base"
+)
+
+// Writer is an io.WriteCloser that appends to append blobs.
+// Writes are buffered into blocks of WriterOptions.BlockSize bytes, which are appended when full,
+// every WriterOptions.FlushInterval, on Flush and on Close.
+// Every block is appended with an append-position condition so that a retried request can't append
+// the same data twice, and with a max-size condition when WriterOptions.MaxBlobSize is set.
+// When a blob reaches MaxBlocks blocks or MaxBlobSize bytes, the Writer rolls over to a new blob,
+// named by WriterOptions.RolloverName.
+// The Writer assumes it's the only writer appending to its blobs. Once an append fails, the Writer
+// returns the error from all subsequent calls. Its methods are safe for concurrent use.
+type Writer struct {
+	ctx      context.Context
+	options  WriterOptions
+	urlParts blob.URLParts
+	blobName string
+
+	mu        sync.Mutex
+	client    *Client
+	offset    int64
+	blocks    int32
+	rollovers int
+	buf       []byte
+	err       error
+	closed    bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewWriter creates a Writer that appends to this blob, creating it if it doesn't exist.
+// If the blob is sealed or full, the Writer rolls over to the next blob right away.
+// The provided context is used for all requests made by the Writer, including periodic flushes.
+func (ab *Client) NewWriter(ctx context.Context, o *WriterOptions) (*Writer, error) {
+	options := WriterOptions{}
+	if o != nil {
+		options = *o
+	}
+	if err := options.format(); err != nil {
+		return nil, err
+	}
+
+	urlParts, err := blob.ParseURL(ab.URL())
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		ctx:      ctx,
+		options:  options,
+		urlParts: urlParts,
+		blobName: urlParts.BlobName,
+		buf:      make([]byte, 0, options.BlockSize),
+	}
+	if err := w.open(ab); err != nil {
+		return nil, err
+	}
+
+	if options.FlushInterval > 0 {
+		w.stop = make(chan struct{})
+		w.done = make(chan struct{})
+		go w.flushPeriodically()
+	}
+	return w, nil
+}
+
+// Client returns a client for the blob currently being appended to.
+func (w *Writer) Client() *Client {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.client
+}
+
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.checkLocked(); err != nil {
+		return 0, err
+	}
+
+	blockSize := int(w.options.BlockSize)
+	if len(w.buf) > 0 && len(w.buf)+len(p) > blockSize {
+		// append what's buffered so that p isn't split across blocks
+		if err := w.flushLocked(); err != nil {
+			return 0, err
+		}
+	}
+
+	written := 0
+	for len(p)-written >= blockSize {
+		if err := w.appendLocked(p[written : written+blockSize]); err != nil {
+			w.err = err
+			return written, err
+		}
+		written += blockSize
+	}
+
+	w.buf = append(w.buf, p[written:]...)
+	if len(w.buf) == blockSize {
+		if err := w.flushLocked(); err != nil {
+			return written, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush appends any buffered data to the blob.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.checkLocked(); err != nil {
+		return err
+	}
+	return w.flushLocked()
+}
+
+// Close flushes any buffered data and, if WriterOptions.SealOnClose is set, seals the current blob.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return errors.New("appendblob.Writer is closed")
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	if w.stop != nil {
+		close(w.stop)
+		<-w.done
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.flushLocked(); err != nil {
+		return err
+	}
+	if w.options.SealOnClose {
+		if _, err := w.client.Seal(w.ctx, nil); err != nil {
+			w.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) checkLocked() error {
+	if w.closed {
+		return errors.New("appendblob.Writer is closed")
+	}
+	return w.err
+}
+
+func (w *Writer) flushPeriodically() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.options.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.err == nil {
+				// a failure is reported by the next call to Write, Flush or Close
+				_ = w.flushLocked()
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// flushLocked appends the buffered data. w.mu must be held.
+func (w *Writer) flushLocked() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	if err := w.appendLocked(w.buf); err != nil {
+		w.err = err
+		return err
+	}
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// appendLocked appends data as a single block, rolling over to the next blob as required. w.mu must be held.
+func (w *Writer) appendLocked(data []byte) error {
+	size := int64(len(data))
+	for {
+		if w.full(size) {
+			if err := w.rollover(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		conditions := &AppendPositionAccessConditions{AppendPosition: to.Ptr(w.offset)}
+		if w.options.MaxBlobSize > 0 {
+			conditions.MaxSize = to.Ptr(w.options.MaxBlobSize)
+		}
+		resp, err := w.client.AppendBlock(w.ctx, streaming.NopCloser(bytes.NewReader(data)), &AppendBlockOptions{
+			TransactionalValidation:        w.options.TransactionalValidation,
+			AppendPositionAccessConditions: conditions,
+			CPKInfo:                        w.options.CPKInfo,
+			CPKScopeInfo:                   w.options.CPKScopeInfo,
+		})
+		switch {
+		case err == nil:
+			w.offset += size
+			w.blocks++
+			if resp.BlobCommittedBlockCount != nil {
+				w.blocks = *resp.BlobCommittedBlockCount
+			}
+			return nil
+		case bloberror.HasCode(err, bloberror.AppendPositionConditionNotMet):
+			// the response to a successful append may have been lost, in which case the retry
+			// fails the append-position condition. don't append the block again if it's there.
+			if appended, propsErr := w.appended(size); propsErr != nil || !appended {
+				return err
+			}
+			return nil
+		case bloberror.HasCode(err, bloberror.MaxBlobSizeConditionNotMet, bloberror.BlockCountExceedsLimit):
+			if err := w.rollover(); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+}
+
+// full returns true if a block of the specified size can't be appended to the current blob.
+func (w *Writer) full(size int64) bool {
+	return w.blocks >= MaxBlocks || (w.options.MaxBlobSize > 0 && w.offset+size > w.options.MaxBlobSize)
+}
+
+// appended returns true if the blob has grown by size bytes since the last successful append.
+func (w *Writer) appended(size int64) (bool, error) {
+	props, err := w.client.GetProperties(w.ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	if props.ContentLength == nil || *props.ContentLength != w.offset+size {
+		return false, nil
+	}
+	w.offset += size
+	if props.BlobCommittedBlockCount != nil {
+		w.blocks = *props.BlobCommittedBlockCount
+	}
+	return true, nil
+}
+
+// rollover optionally seals the current blob and opens the next one.
+func (w *Writer) rollover() error {
+	if w.options.SealOnClose {
+		if _, err := w.client.Seal(w.ctx, nil); err != nil {
+			return err
+		}
+	}
+	return w.open(w.nextClient())
+}
+
+// nextClient returns a client for the blob named by the next call to RolloverName.
+func (w *Writer) nextClient() *Client {
+	w.rollovers++
+	w.urlParts.BlobName = w.options.RolloverName(w.blobName, w.rollovers)
+	return (*Client)(base.NewAppendBlobClient(w.urlParts.String(), w.client.generated().InternalClient(), w.client.sharedKey()))
+}
+
+// open starts appending to the specified blob, creating it if it doesn't exist.
+// It rolls over to the next blob if the specified one is sealed or full.
+func (w *Writer) open(client *Client) error {
+	w.client = client
+	for {
+		props, err := client.GetProperties(w.ctx, &blob.GetPropertiesOptions{CPKInfo: w.options.CPKInfo})
+		if err == nil {
+			if props.BlobType == nil || *props.BlobType != blob.BlobTypeAppendBlob {
+				return errors.New("blob " + w.urlParts.BlobName + " is not an append blob")
+			}
+			w.offset, w.blocks = 0, 0
+			if props.ContentLength != nil {
+				w.offset = *props.ContentLength
+			}
+			if props.BlobCommittedBlockCount != nil {
+				w.blocks = *props.BlobCommittedBlockCount
+			}
+			if (props.IsSealed != nil && *props.IsSealed) || w.full(1) {
+				client = w.nextClient()
+				w.client = client
+				continue
+			}
+			return nil
+		}
+		if !bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return err
+		}
+
+		createOptions := CreateOptions{}
+		if w.options.CreateOptions != nil {
+			createOptions = *w.options.CreateOptions
+		}
+		createOptions.CPKInfo, createOptions.CPKScopeInfo = w.options.CPKInfo, w.options.CPKScopeInfo
+		createOptions.AccessConditions = &blob.AccessConditions{ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: to.Ptr(azcore.ETagAny)}}
+		_, err = client.Create(w.ctx, &createOptions)
+		if err == nil {
+			w.offset, w.blocks = 0, 0
+			return nil
+		}
+		if !bloberror.HasCode(err, bloberror.BlobAlreadyExists, bloberror.ConditionNotMet) {
+			return err
+		}
+		// another writer created the blob first; look at it again
+	}
+}
+
+var _ io.WriteCloser = (*Writer)(nil)
@@ -0,0 +1,219 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package appendblob
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAppendBlob struct {
+	data   []byte
+	blocks int
+	sealed bool
+}
+
+// appendTransport is an in-memory container of append blobs that enforces the append conditions.
+type appendTransport struct {
+	mu    sync.Mutex
+	blobs map[string]*fakeAppendBlob
+
+	// loseResponses is the number of successful appends whose responses are replaced with a 500
+	loseResponses int
+}
+
+func (f *appendTransport) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	header := http.Header{}
+	respond := func(status int, code string) (*http.Response, error) {
+		if code != "" {
+			header.Set("x-ms-error-code", code)
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: http.NoBody, Request: req}, nil
+	}
+	name := strings.TrimPrefix(req.URL.Path, "/c/")
+	b := f.blobs[name]
+
+	switch comp := req.URL.Query().Get("comp"); {
+	case req.Method == http.MethodHead:
+		if b == nil {
+			return respond(http.StatusNotFound, "BlobNotFound")
+		}
+		header.Set("Content-Length", strconv.Itoa(len(b.data)))
+		header.Set("x-ms-blob-type", "AppendBlob")
+		header.Set("x-ms-blob-committed-block-count", strconv.Itoa(b.blocks))
+		header.Set("x-ms-blob-sealed", strconv.FormatBool(b.sealed))
+		return respond(http.StatusOK, "")
+	case comp == "":
+		if b != nil && req.Header["If-None-Match"][0] == "*" {
+			return respond(http.StatusConflict, "BlobAlreadyExists")
+		}
+		f.blobs[name] = &fakeAppendBlob{}
+		return respond(http.StatusCreated, "")
+	case comp == "seal":
+		b.sealed = true
+		return respond(http.StatusOK, "")
+	case comp == "appendblock":
+		if b == nil {
+			return respond(http.StatusNotFound, "BlobNotFound")
+		}
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if pos := req.Header["x-ms-blob-condition-appendpos"]; len(pos) > 0 && pos[0] != strconv.Itoa(len(b.data)) {
+			return respond(http.StatusPreconditionFailed, "AppendPositionConditionNotMet")
+		}
+		if maxSize := req.Header["x-ms-blob-condition-maxsize"]; len(maxSize) > 0 {
+			if n, _ := strconv.Atoi(maxSize[0]); len(b.data)+len(data) > n {
+				return respond(http.StatusPreconditionFailed, "MaxBlobSizeConditionNotMet")
+			}
+		}
+		if b.sealed {
+			return respond(http.StatusConflict, "BlobIsSealed")
+		}
+		b.data = append(b.data, data...)
+		b.blocks++
+		if f.loseResponses > 0 {
+			f.loseResponses--
+			return respond(http.StatusInternalServerError, "InternalError")
+		}
+		header.Set("x-ms-blob-committed-block-count", strconv.Itoa(b.blocks))
+		return respond(http.StatusCreated, "")
+	}
+	return respond(http.StatusBadRequest, "UnsupportedHttpVerb")
+}
+
+func (f *appendTransport) blob(name string) *fakeAppendBlob {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.blobs[name]
+}
+
+func (f *appendTransport) content(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if b := f.blobs[name]; b != nil {
+		return string(b.data)
+	}
+	return ""
+}
+
+func newWriterTestClient(t *testing.T, transport *appendTransport) *Client {
+	client, err := NewClientWithNoCredential("https://account.blob.core.windows.net/c/log", &ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: transport, Retry: policy.RetryOptions{MaxRetries: 1, RetryDelay: time.Millisecond}},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestWriterBlocksAndRollover(t *testing.T) {
+	transport := &appendTransport{blobs: map[string]*fakeAppendBlob{}}
+	w, err := newWriterTestClient(t, transport).NewWriter(context.Background(), &WriterOptions{
+		BlockSize:   10,
+		MaxBlobSize: 25,
+		SealOnClose: true,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, transport.blob("log"))
+
+	write := func(s string) {
+		n, err := w.Write([]byte(s))
+		require.NoError(t, err)
+		require.Equal(t, len(s), n)
+	}
+	write("aaaa")
+	write("bbbb")
+	require.Empty(t, transport.content("log"))
+	write("cccc")
+	require.Equal(t, "aaaabbbb", transport.content("log"))
+
+	// writes aren't split across blocks or blobs unless they're larger than a block
+	write("0123456789dddddd")
+	write("eeeeeeeee")
+	require.Equal(t, "aaaabbbbcccc0123456789", transport.content("log"))
+	require.Equal(t, "dddddd", transport.content("log.1"))
+	write("ffffffffff")
+	require.NoError(t, w.Flush())
+	write("gggggggggg")
+	require.NoError(t, w.Close())
+
+	require.Equal(t, "aaaabbbbcccc0123456789", transport.content("log"))
+	require.Equal(t, "ddddddeeeeeeeeeffffffffff", transport.content("log.1"))
+	require.Equal(t, "gggggggggg", transport.content("log.2"))
+	require.Equal(t, 3, transport.blob("log").blocks)
+	for _, name := range []string{"log", "log.1", "log.2"} {
+		require.True(t, transport.blob(name).sealed, name)
+	}
+	require.True(t, strings.HasSuffix(w.Client().URL(), "/c/log.2"))
+
+	_, err = w.Write([]byte("x"))
+	require.Error(t, err)
+}
+
+func TestWriterResumesExistingBlob(t *testing.T) {
+	transport := &appendTransport{blobs: map[string]*fakeAppendBlob{
+		"log":   {data: []byte("sealed"), blocks: 1, sealed: true},
+		"log.1": {data: []byte("existing"), blocks: 1},
+	}}
+	w, err := newWriterTestClient(t, transport).NewWriter(context.Background(), &WriterOptions{
+		RolloverName: func(blobName string, index int) string { return blobName + "." + strconv.Itoa(index) },
+	})
+	require.NoError(t, err)
+	_, err = w.Write([]byte("-more"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	require.Equal(t, "sealed", transport.content("log"))
+	require.Equal(t, "existing-more", transport.content("log.1"))
+	require.False(t, transport.blob("log.1").sealed)
+}
+
+func TestWriterLostResponseIsNotDuplicated(t *testing.T) {
+	transport := &appendTransport{blobs: map[string]*fakeAppendBlob{}, loseResponses: 1}
+	w, err := newWriterTestClient(t, transport).NewWriter(context.Background(), &WriterOptions{BlockSize: 4})
+	require.NoError(t, err)
+
+	_, err = io.Copy(w, bytes.NewReader([]byte("0123456789")))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.Equal(t, "0123456789", transport.content("log"))
+}
+
+func TestWriterFlushInterval(t *testing.T) {
+	transport := &appendTransport{blobs: map[string]*fakeAppendBlob{}}
+	w, err := newWriterTestClient(t, transport).NewWriter(context.Background(), &WriterOptions{FlushInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return transport.content("log") == "hello"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWriterOptionsValidation(t *testing.T) {
+	client := newWriterTestClient(t, &appendTransport{blobs: map[string]*fakeAppendBlob{}})
+	_, err := client.NewWriter(context.Background(), &WriterOptions{BlockSize: MaxAppendBlockBytes + 1})
+	require.Error(t, err)
+	_, err = client.NewWriter(context.Background(), &WriterOptions{BlockSize: 10, MaxBlobSize: 5})
+	require.Error(t, err)
+}
@@ -0,0 +1,195 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package blob
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/shared"
+)
+
+// downloadedBlock is a block downloaded into the first n bytes of a buffer, or the error that occurred downloading it.
+type downloadedBlock[T ~[]byte] struct {
+	buffer T
+	n      int
+	err    error
+}
+
+// downloadToWriter downloads a blob to an io.Writer. Blocks are downloaded in parallel into buffers from a pool of
+// o.Concurrency buffers and written to w in order, so at most o.Concurrency blocks are held in memory.
+func downloadToWriter[T ~[]byte](ctx context.Context, b *Client, w io.Writer, o downloadOptions, getBufferManager func(maxBuffers int, bufferSize int64) shared.BufferManager[T]) (int64, error) {
+	if o.BlockSize == 0 {
+		o.BlockSize = DefaultDownloadBlockSize
+	}
+	if o.Concurrency == 0 {
+		o.Concurrency = DefaultConcurrency
+	}
+
+	// the blocks are downloaded on the condition that the blob doesn't change, so that a blob that's
+	// modified during the download fails it instead of producing a mix of versions
+	props, err := b.GetProperties(ctx, o.getBlobPropertiesOptions())
+	if err != nil {
+		return 0, err
+	}
+	size := *props.ContentLength
+	var dec *exported.ClientSideDecryptor
+	if o.ClientSideEncryption != nil {
+		if dec, err = exported.NewClientSideDecryptor(ctx, o.ClientSideEncryption, props.Metadata); err != nil {
+			return 0, err
+		}
+		size = dec.PlaintextSize(size)
+	}
+	count := o.Range.Count
+	if count == CountToEnd || o.Range.Offset+count > size {
+		count = size - o.Range.Offset
+	}
+	if count <= 0 {
+		return 0, nil
+	}
+	o.AccessConditions = withIfMatch(o.AccessConditions, props.ETag)
+
+	buffers := getBufferManager(int(o.Concurrency), o.BlockSize)
+	defer buffers.Free()
+
+	// NOTE: cancel MUST execute before the buffers are freed, and every buffer must be released
+	// back to the pool by then, see below.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	progress := int64(0)
+	progressLock := &sync.Mutex{}
+
+	download := func(offset int64, buffer T) (int, error) {
+		downloadBlobOptions := o.getDownloadBlobOptions(HTTPRange{Offset: o.Range.Offset + offset, Count: int64(len(buffer))}, nil)
+		var dr DownloadStreamResponse
+		var err error
+		if dec != nil {
+			dr, err = b.downloadStreamDecrypted(ctx, downloadBlobOptions, dec)
+		} else {
+			dr, err = b.DownloadStream(ctx, downloadBlobOptions)
+		}
+		if err != nil {
+			return 0, err
+		}
+		var body io.ReadCloser = dr.NewRetryReader(ctx, &o.RetryReaderOptionsPerBlock)
+		defer body.Close()
+		if o.Progress != nil {
+			rangeProgress := int64(0)
+			body = streaming.NewResponseProgress(
+				body,
+				func(bytesTransferred int64) {
+					diff := bytesTransferred - rangeProgress
+					rangeProgress = bytesTransferred
+					progressLock.Lock()
+					progress += diff
+					o.Progress(progress)
+					progressLock.Unlock()
+				})
+		}
+		return io.ReadFull(body, buffer)
+	}
+
+	// this goroutine starts the download of each block as soon as a buffer is available, and queues the
+	// channels on which the blocks are delivered in order. It stops when ctx is canceled.
+	pending := make(chan chan downloadedBlock[T], o.Concurrency)
+	go func() {
+		defer close(pending)
+		for offset := int64(0); offset < count; offset += o.BlockSize {
+			var buffer T
+			select {
+			case buffer = <-buffers.Acquire():
+				// got a buffer
+			default:
+				// no buffer available; allocate a new buffer if possible
+				if _, err := buffers.Grow(); err != nil {
+					block := make(chan downloadedBlock[T], 1)
+					block <- downloadedBlock[T]{err: err}
+					pending <- block
+					return
+				}
+				select {
+				case buffer = <-buffers.Acquire():
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			block := make(chan downloadedBlock[T], 1)
+			go func(offset int64) {
+				n, err := download(offset, buffer[:min(o.BlockSize, count-offset)])
+				block <- downloadedBlock[T]{buffer: buffer, n: n, err: err}
+			}(offset)
+
+			select {
+			case pending <- block:
+			case <-ctx.Done():
+				// the block isn't queued, so release its buffer here
+				buffers.Release((<-block).buffer)
+				return
+			}
+		}
+	}()
+
+	written := int64(0)
+	for block := range pending {
+		result := <-block
+		if result.err == nil {
+			var n int
+			n, result.err = w.Write(result.buffer[:result.n])
+			written += int64(n)
+		}
+		if result.buffer != nil {
+			buffers.Release(result.buffer)
+		}
+		if result.err != nil {
+			err = result.err
+			break
+		}
+	}
+
+	if err != nil {
+		// stop the downloads, and release the buffers of the blocks that are queued
+		cancel()
+		for block := range pending {
+			if result := <-block; result.buffer != nil {
+				buffers.Release(result.buffer)
+			}
+		}
+		return written, err
+	}
+	if written < count {
+		// the goroutine stopped queueing blocks because ctx was canceled
+		if err = ctx.Err(); err == nil {
+			err = io.ErrShortWrite
+		}
+		return written, err
+	}
+	return written, nil
+}
+
+// withIfMatch returns access conditions that include an IfMatch condition on etag, unless one is already specified.
+func withIfMatch(accessConditions *AccessConditions, etag *azcore.ETag) *AccessConditions {
+	if etag == nil || (accessConditions != nil && accessConditions.ModifiedAccessConditions != nil && accessConditions.ModifiedAccessConditions.IfMatch != nil) {
+		return accessConditions
+	}
+	conditions := AccessConditions{}
+	modified := ModifiedAccessConditions{}
+	if accessConditions != nil {
+		conditions = *accessConditions
+		if accessConditions.ModifiedAccessConditions != nil {
+			modified = *accessConditions.ModifiedAccessConditions
+		}
+	}
+	modified.IfMatch = etag
+	conditions.ModifiedAccessConditions = &modified
+	return &conditions
+}
@@ -0,0 +1,198 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/shared"
+	"github.com/stretchr/testify/require"
+)
+
+// slowTransport delays the responses to Get Blob requests by a random amount so that blocks complete out of
+// order, and fails them with ConditionNotMet once the blob is modified.
+type slowTransport struct {
+	*rangeTransport
+	modifyAfter int32
+	gets        int32
+}
+
+func (f *slowTransport) Do(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet {
+		time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+		if n := atomic.AddInt32(&f.gets, 1); f.modifyAfter > 0 && n > f.modifyAfter {
+			header := http.Header{}
+			header.Set("x-ms-error-code", string(bloberror.ConditionNotMet))
+			return &http.Response{StatusCode: http.StatusPreconditionFailed, Header: header, Body: http.NoBody, Request: req}, nil
+		}
+	}
+	return f.rangeTransport.Do(req)
+}
+
+// countingBufferManager is a pool of byte slices that records the number of buffers allocated.
+type countingBufferManager struct {
+	buffers chan []byte
+	count   int
+	max     int
+	size    int64
+}
+
+func (pool *countingBufferManager) Acquire() <-chan []byte {
+	return pool.buffers
+}
+
+func (pool *countingBufferManager) Release(buffer []byte) {
+	pool.buffers <- buffer
+}
+
+func (pool *countingBufferManager) Grow() (int, error) {
+	if pool.count < pool.max {
+		pool.buffers <- make([]byte, pool.size)
+		pool.count++
+	}
+	return pool.count, nil
+}
+
+func (pool *countingBufferManager) Free() {
+	// blocks if a buffer wasn't released
+	for i := 0; i < pool.count; i++ {
+		<-pool.buffers
+	}
+}
+
+func newSlowTestClient(t *testing.T, size int, modifyAfter int32) (*Client, *rangeTransport) {
+	_, transport := newReaderTestClient(t, size)
+	client, err := NewClientWithNoCredential("https://account.blob.core.windows.net/c/b", &ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: &slowTransport{rangeTransport: transport, modifyAfter: modifyAfter}, Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	require.NoError(t, err)
+	return client, transport
+}
+
+func downloadToWriterForTest(ctx context.Context, client *Client, w *bytes.Buffer, o DownloadToWriterOptions) (int64, *countingBufferManager, error) {
+	var pool *countingBufferManager
+	n, err := downloadToWriter(ctx, client, w, (downloadOptions)(o), func(maxBuffers int, bufferSize int64) shared.BufferManager[[]byte] {
+		pool = &countingBufferManager{buffers: make(chan []byte, maxBuffers), max: maxBuffers, size: bufferSize}
+		return pool
+	})
+	return n, pool, err
+}
+
+func TestDownloadToWriterInOrder(t *testing.T) {
+	client, transport := newSlowTestClient(t, 10500, 0)
+
+	var progress int64
+	var progressLock sync.Mutex
+	buf := &bytes.Buffer{}
+	n, pool, err := downloadToWriterForTest(context.Background(), client, buf, DownloadToWriterOptions{
+		BlockSize:   1000,
+		Concurrency: 4,
+		Progress: func(bytesTransferred int64) {
+			progressLock.Lock()
+			progress = bytesTransferred
+			progressLock.Unlock()
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(10500), n)
+	require.Equal(t, transport.data, buf.Bytes())
+	require.Equal(t, int64(10500), progress)
+	require.Equal(t, 11, transport.requests())
+	require.LessOrEqual(t, pool.count, 4)
+
+	// a range of the blob
+	buf.Reset()
+	n, err = client.DownloadToWriter(context.Background(), buf, &DownloadToWriterOptions{
+		Range:     HTTPRange{Offset: 2500, Count: 3000},
+		BlockSize: 1024,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3000), n)
+	require.Equal(t, transport.data[2500:5500], buf.Bytes())
+}
+
+func TestDownloadToWriterBlobModified(t *testing.T) {
+	client, _ := newSlowTestClient(t, 10000, 3)
+
+	buf := &bytes.Buffer{}
+	n, _, err := downloadToWriterForTest(context.Background(), client, buf, DownloadToWriterOptions{BlockSize: 1000, Concurrency: 3})
+	require.True(t, bloberror.HasCode(err, bloberror.ConditionNotMet))
+	require.LessOrEqual(t, n, int64(3000))
+	require.Equal(t, n, int64(buf.Len()))
+}
+
+type failingWriter struct {
+	written int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.written >= 2000 {
+		return 0, errors.New("broken pipe")
+	}
+	w.written += len(p)
+	return len(p), nil
+}
+
+func TestDownloadToWriterWriteError(t *testing.T) {
+	client, _ := newSlowTestClient(t, 20000, 0)
+
+	var pool *countingBufferManager
+	w := &failingWriter{}
+	n, err := downloadToWriter(context.Background(), client, w, downloadOptions{BlockSize: 1000, Concurrency: 5}, func(maxBuffers int, bufferSize int64) shared.BufferManager[[]byte] {
+		pool = &countingBufferManager{buffers: make(chan []byte, maxBuffers), max: maxBuffers, size: bufferSize}
+		return pool
+	})
+	require.EqualError(t, err, "broken pipe")
+	// returning at all means that every buffer was released to the pool before it was freed
+	require.Equal(t, int64(2000), n)
+	require.LessOrEqual(t, pool.count, 5)
+}
+
+// cancelingWriter cancels the download once it's written the first block.
+type cancelingWriter struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *cancelingWriter) Write(p []byte) (int, error) {
+	w.cancel()
+	return w.Buffer.Write(p)
+}
+
+func TestDownloadToWriterCanceled(t *testing.T) {
+	client, _ := newSlowTestClient(t, 10000, 0)
+
+	for i := 0; i < 10; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		w := &cancelingWriter{cancel: cancel}
+		n, err := downloadToWriter(ctx, client, w, downloadOptions{BlockSize: 1000, Concurrency: 1}, func(maxBuffers int, bufferSize int64) shared.BufferManager[[]byte] {
+			return &countingBufferManager{buffers: make(chan []byte, maxBuffers), max: maxBuffers, size: bufferSize}
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		require.Less(t, n, int64(10000))
+		require.Equal(t, n, int64(w.Len()))
+	}
+}
+
+func TestDownloadToWriterEmptyBlob(t *testing.T) {
+	client, _ := newSlowTestClient(t, 0, 0)
+	buf := &bytes.Buffer{}
+	n, err := client.DownloadToWriter(context.Background(), buf, nil)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
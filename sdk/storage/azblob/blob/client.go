@@ -8,8 +8,11 @@ package blob
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -331,14 +334,27 @@ func (b *Client) GetSASURL(permissions sas.BlobPermissions, expiry time.Time, o
 // Concurrent Download Functions -----------------------------------------------------------------------------------------
 
 // downloadBuffer downloads an Azure blob to a WriterAt in parallel.
-func (b *Client) downloadBuffer(ctx context.Context, writer io.WriterAt, o downloadOptions) (int64, error) {
+// When client-side encryption is enabled, dec may carry an already resolved decryptor.
+func (b *Client) downloadBuffer(ctx context.Context, writer io.WriterAt, o downloadOptions, dec *exported.ClientSideDecryptor) (int64, error) {
 	if o.BlockSize == 0 {
 		o.BlockSize = DefaultDownloadBlockSize
 	}
 	dataDownloaded := int64(0)
 	computeReadLength := true
 	count := o.Range.Count
-	if count == CountToEnd { // If size not specified, calculate it
+	if o.ClientSideEncryption != nil && dec == nil {
+		var size int64
+		var err error
+		dec, size, err = b.newClientSideDecryptor(ctx, &o)
+		if err != nil {
+			return 0, err
+		}
+		if count == CountToEnd {
+			count = size - o.Range.Offset
+			dataDownloaded = count
+			computeReadLength = false
+		}
+	} else if count == CountToEnd { // If size not specified, calculate it
 		// If we don't have the length at all, get it
 		gr, err := b.GetProperties(ctx, o.getBlobPropertiesOptions())
 		if err != nil {
@@ -369,7 +385,13 @@ func (b *Client) downloadBuffer(ctx context.Context, writer io.WriterAt, o downl
 				Offset: chunkStart + o.Range.Offset,
 				Count:  count,
 			}, nil)
-			dr, err := b.DownloadStream(ctx, downloadBlobOptions)
+			var dr DownloadStreamResponse
+			var err error
+			if dec != nil {
+				dr, err = b.downloadStreamDecrypted(ctx, downloadBlobOptions, dec)
+			} else {
+				dr, err = b.DownloadStream(ctx, downloadBlobOptions)
+			}
 			if err != nil {
 				return err
 			}
@@ -412,6 +434,10 @@ func (b *Client) DownloadStream(ctx context.Context, o *DownloadStreamOptions) (
 		o = &DownloadStreamOptions{}
 	}
 
+	if o.ClientSideEncryption != nil {
+		return b.downloadStreamDecrypted(ctx, o, nil)
+	}
+
 	dr, err := b.generated().Download(ctx, downloadOptions, leaseAccessConditions, cpkInfo, modifiedAccessConditions)
 	if err != nil {
 		return DownloadStreamResponse{}, err
@@ -432,7 +458,7 @@ func (b *Client) DownloadBuffer(ctx context.Context, buffer []byte, o *DownloadB
 	if o == nil {
 		o = &DownloadBufferOptions{}
 	}
-	return b.downloadBuffer(ctx, shared.NewBytesWriter(buffer), (downloadOptions)(*o))
+	return b.downloadBuffer(ctx, shared.NewBytesWriter(buffer), (downloadOptions)(*o), nil)
 }
 
 // DownloadFile downloads an Azure blob to a local file.
@@ -445,9 +471,18 @@ func (b *Client) DownloadFile(ctx context.Context, file *os.File, o *DownloadFil
 
 	// 1. Calculate the size of the destination file
 	var size int64
+	var dec *exported.ClientSideDecryptor
 
 	count := do.Range.Count
-	if count == CountToEnd {
+	if count == CountToEnd && do.ClientSideEncryption != nil {
+		var err error
+		dec, size, err = b.newClientSideDecryptor(ctx, do)
+		if err != nil {
+			return 0, err
+		}
+		size -= do.Range.Offset
+		do.Range.Count = size
+	} else if count == CountToEnd {
 		// Try to get Azure blob's size
 		getBlobPropertiesOptions := do.getBlobPropertiesOptions()
 		props, err := b.GetProperties(ctx, getBlobPropertiesOptions)
@@ -472,8 +507,115 @@ func (b *Client) DownloadFile(ctx context.Context, file *os.File, o *DownloadFil
 	}
 
 	if size > 0 {
-		return b.downloadBuffer(ctx, file, *do)
+		return b.downloadBuffer(ctx, file, *do, dec)
 	} else { // if the blob's size is 0, there is no need in downloading it
 		return 0, nil
 	}
 }
+
+// DownloadToWriter downloads an Azure blob to an io.Writer, e.g. a pipe, an HTTP response or a hash, with parallel.
+// Blocks are downloaded concurrently into a bounded pool of buffers and written to the writer in order, so at most
+// Concurrency blocks are held in memory. The blocks are downloaded on the condition that the blob doesn't change
+// during the download. It returns the number of bytes written.
+func (b *Client) DownloadToWriter(ctx context.Context, writer io.Writer, o *DownloadToWriterOptions) (int64, error) {
+	if o == nil {
+		o = &DownloadToWriterOptions{}
+	}
+	return downloadToWriter(ctx, b, writer, (downloadOptions)(*o), shared.NewMMBPool)
+}
+
+// Client-Side Encryption -------------------------------------------------------------------------------------------------
+
+// newClientSideDecryptor reads the blob's client-side encryption metadata and unwraps its content key.
+// It also returns the size of the blob's plaintext.
+func (b *Client) newClientSideDecryptor(ctx context.Context, o *downloadOptions) (*exported.ClientSideDecryptor, int64, error) {
+	props, err := b.GetProperties(ctx, o.getBlobPropertiesOptions())
+	if err != nil {
+		return nil, 0, err
+	}
+	dec, err := exported.NewClientSideDecryptor(ctx, o.ClientSideEncryption, props.Metadata)
+	if err != nil {
+		return nil, 0, err
+	}
+	return dec, dec.PlaintextSize(*props.ContentLength), nil
+}
+
+// downloadStreamDecrypted downloads the encrypted regions covering o.Range and returns a response whose body
+// yields the decrypted plaintext. If dec is nil, it is created from the blob's metadata.
+func (b *Client) downloadStreamDecrypted(ctx context.Context, o *DownloadStreamOptions, dec *exported.ClientSideDecryptor) (DownloadStreamResponse, error) {
+	rawOptions := *o
+	rawOptions.ClientSideEncryption = nil
+	rawOptions.RangeGetContentMD5 = nil
+
+	var dr DownloadResponse
+	if dec == nil && o.Range.Offset == 0 && o.Range.Count == CountToEnd {
+		// the whole blob is requested, so the encryption metadata can be taken from the download itself
+		downloadOptions, leaseAccessConditions, cpkInfo, modifiedAccessConditions := rawOptions.format()
+		resp, err := b.generated().Download(ctx, downloadOptions, leaseAccessConditions, cpkInfo, modifiedAccessConditions)
+		if err != nil {
+			return DownloadStreamResponse{}, err
+		}
+		dec, err = exported.NewClientSideDecryptor(ctx, o.ClientSideEncryption, resp.Metadata)
+		if err != nil {
+			_ = resp.Body.Close()
+			return DownloadStreamResponse{}, err
+		}
+		dr = resp
+	} else {
+		if dec == nil {
+			props, err := b.GetProperties(ctx, &GetPropertiesOptions{AccessConditions: o.AccessConditions, CPKInfo: o.CPKInfo})
+			if err != nil {
+				return DownloadStreamResponse{}, err
+			}
+			dec, err = exported.NewClientSideDecryptor(ctx, o.ClientSideEncryption, props.Metadata)
+			if err != nil {
+				return DownloadStreamResponse{}, err
+			}
+		}
+		rawOptions.Range, _ = dec.EncryptedRange(o.Range)
+		downloadOptions, leaseAccessConditions, cpkInfo, modifiedAccessConditions := rawOptions.format()
+		resp, err := b.generated().Download(ctx, downloadOptions, leaseAccessConditions, cpkInfo, modifiedAccessConditions)
+		if err != nil {
+			return DownloadStreamResponse{}, err
+		}
+		dr = resp
+	}
+
+	_, skip := dec.EncryptedRange(o.Range)
+	var encLength int64
+	if dr.ContentLength != nil {
+		encLength = *dr.ContentLength
+	}
+	plainLength := dec.PlaintextSize(encLength) - skip
+	if o.Range.Count != CountToEnd && o.Range.Count < plainLength {
+		plainLength = o.Range.Count
+	}
+	if plainLength < 0 {
+		plainLength = 0
+	}
+
+	dr.Body = dec.NewReader(dr.Body, encLength, skip, o.Range.Count)
+	dr.ContentLength = &plainLength
+	// the service-computed hashes describe the ciphertext, not the returned plaintext
+	dr.ContentMD5 = nil
+	dr.ContentCRC64 = nil
+	dr.BlobContentMD5 = nil
+	if dr.ContentRange != nil {
+		if i := strings.LastIndex(*dr.ContentRange, "/"); i >= 0 {
+			if total, err := strconv.ParseInt((*dr.ContentRange)[i+1:], 10, 64); err == nil {
+				contentRange := fmt.Sprintf("bytes %d-%d/%d", o.Range.Offset, o.Range.Offset+plainLength-1, dec.PlaintextSize(total))
+				dr.ContentRange = &contentRange
+			}
+		}
+	}
+
+	return DownloadStreamResponse{
+		client:                 b,
+		DownloadResponse:       dr,
+		getInfo:                httpGetterInfo{Range: o.Range, ETag: dr.ETag},
+		ObjectReplicationRules: deserializeORSPolicies(dr.ObjectReplicationRules),
+		cpkInfo:                o.CPKInfo,
+		cpkScope:               o.CPKScopeInfo,
+		decryptor:              dec,
+	}, nil
+}
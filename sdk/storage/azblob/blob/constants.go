@@ -22,6 +22,9 @@ const (
 
 	// DefaultConcurrency is the default number of blocks downloaded or uploaded in parallel
 	DefaultConcurrency = shared.DefaultConcurrency
+
+	// DefaultReaderCacheSize is the default number of blocks cached by a Reader
+	DefaultReaderCacheSize = 4
 )
 
 // BlobType defines values for BlobType
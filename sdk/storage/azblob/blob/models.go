@@ -54,6 +54,21 @@ type Tags = generated.BlobTag
 // which has an offset and zero value count indicates from the offset to the resource's end.
 type HTTPRange = exported.HTTPRange
 
+// ClientSideEncryptionOptions configures client-side encryption (protocol version 2.0, AES-GCM) of blob content.
+type ClientSideEncryptionOptions = exported.ClientSideEncryptionOptions
+
+// KeyEncryptionKey wraps and unwraps the content encryption key used for client-side encryption.
+type KeyEncryptionKey = exported.KeyEncryptionKey
+
+// KeyResolver returns the KeyEncryptionKey with the specified ID.
+type KeyResolver = exported.KeyResolver
+
+// NewLocalKeyEncryptionKey creates a KeyEncryptionKey that wraps content keys with the AES key wrap
+// algorithm (RFC 3394) using a 128, 192 or 256-bit key held in memory.
+func NewLocalKeyEncryptionKey(keyID string, key []byte) (KeyEncryptionKey, error) {
+	return exported.NewLocalKeyEncryptionKey(keyID, key)
+}
+
 // Request Model Declaration -------------------------------------------------------------------------------------------
 
 // DownloadStreamOptions contains the optional parameters for the Client.Download method.
@@ -68,6 +83,10 @@ type DownloadStreamOptions struct {
 	AccessConditions *AccessConditions
 	CPKInfo          *CPKInfo
 	CPKScopeInfo     *CPKScopeInfo
+
+	// ClientSideEncryption, when set, decrypts content that was uploaded with client-side encryption.
+	// Range refers to the plaintext; the whole encrypted regions covering it are downloaded and decrypted.
+	ClientSideEncryption *ClientSideEncryptionOptions
 }
 
 func (o *DownloadStreamOptions) format() (*generated.BlobClientDownloadOptions, *generated.LeaseAccessConditions, *generated.CPKInfo, *generated.ModifiedAccessConditions) {
@@ -109,6 +128,9 @@ type downloadOptions struct {
 
 	// RetryReaderOptionsPerBlock is used when downloading each block.
 	RetryReaderOptionsPerBlock RetryReaderOptions
+
+	// ClientSideEncryption, when set, decrypts content that was uploaded with client-side encryption.
+	ClientSideEncryption *ClientSideEncryptionOptions
 }
 
 func (o *downloadOptions) getBlobPropertiesOptions() *GetPropertiesOptions {
@@ -131,6 +153,8 @@ func (o *downloadOptions) getDownloadBlobOptions(rnge HTTPRange, rangeGetContent
 		CPKScopeInfo:       o.CPKScopeInfo,
 		Range:              rnge,
 		RangeGetContentMD5: rangeGetContentMD5,
+
+		ClientSideEncryption: o.ClientSideEncryption,
 	}
 }
 
@@ -159,6 +183,9 @@ type DownloadBufferOptions struct {
 
 	// RetryReaderOptionsPerBlock is used when downloading each block.
 	RetryReaderOptionsPerBlock RetryReaderOptions
+
+	// ClientSideEncryption, when set, decrypts content that was uploaded with client-side encryption.
+	ClientSideEncryption *ClientSideEncryptionOptions
 }
 
 // DownloadFileOptions contains the optional parameters for the DownloadFile method.
@@ -184,6 +211,84 @@ type DownloadFileOptions struct {
 
 	// RetryReaderOptionsPerBlock is used when downloading each block.
 	RetryReaderOptionsPerBlock RetryReaderOptions
+
+	// ClientSideEncryption, when set, decrypts content that was uploaded with client-side encryption.
+	ClientSideEncryption *ClientSideEncryptionOptions
+}
+
+// DownloadToWriterOptions contains the optional parameters for the DownloadToWriter method.
+type DownloadToWriterOptions struct {
+	// Range specifies a range of bytes.  The default value is all bytes.
+	Range HTTPRange
+
+	// BlockSize specifies the block size to use for each parallel download; the default size is DefaultDownloadBlockSize.
+	BlockSize int64
+
+	// Progress is a function that is invoked periodically as bytes are received.
+	Progress func(bytesTransferred int64)
+
+	// BlobAccessConditions indicates the access conditions used when making HTTP GET requests against the blob.
+	AccessConditions *AccessConditions
+
+	// CPKInfo contains a group of parameters for client provided encryption key.
+	CPKInfo *CPKInfo
+
+	// CPKScopeInfo contains a group of parameters for client provided encryption scope.
+	CPKScopeInfo *CPKScopeInfo
+
+	// Concurrency indicates the maximum number of blocks to download in parallel, which is also the maximum
+	// number of blocks buffered in memory. The default value is DefaultConcurrency.
+	Concurrency uint16
+
+	// RetryReaderOptionsPerBlock is used when downloading each block.
+	RetryReaderOptionsPerBlock RetryReaderOptions
+
+	// ClientSideEncryption, when set, decrypts content that was uploaded with client-side encryption.
+	ClientSideEncryption *ClientSideEncryptionOptions
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// ReaderOptions contains the optional parameters for the Client.NewReader method.
+type ReaderOptions struct {
+	// BlockSize specifies the size of each ranged download; the default size is DefaultDownloadBlockSize.
+	BlockSize int64
+
+	// CacheSize specifies the maximum number of blocks kept in memory. The default value is DefaultReaderCacheSize.
+	// It is raised if needed to hold ReadAhead blocks in addition to the block being read.
+	CacheSize int
+
+	// ReadAhead specifies the number of blocks following the current one to download in the background
+	// when the blob is read sequentially. The default value is 0 (no read-ahead).
+	ReadAhead int
+
+	// AccessConditions are checked when the Reader is created. Subsequent reads are conditioned on the ETag
+	// observed at that time and on any lease in LeaseAccessConditions.
+	AccessConditions *AccessConditions
+
+	// CPKInfo contains a group of parameters for client provided encryption key.
+	CPKInfo *CPKInfo
+
+	// CPKScopeInfo contains a group of parameters for client provided encryption scope.
+	CPKScopeInfo *CPKScopeInfo
+
+	// RetryReaderOptionsPerBlock is used when downloading each block.
+	RetryReaderOptionsPerBlock RetryReaderOptions
+}
+
+func (o *ReaderOptions) setDefaults() {
+	if o.BlockSize <= 0 {
+		o.BlockSize = DefaultDownloadBlockSize
+	}
+	if o.ReadAhead < 0 {
+		o.ReadAhead = 0
+	}
+	if o.CacheSize <= 0 {
+		o.CacheSize = DefaultReaderCacheSize
+	}
+	if o.CacheSize < o.ReadAhead+1 {
+		o.CacheSize = o.ReadAhead + 1
+	}
 }
 
 // ---------------------------------------------------------------------------------------------------------------------
@@ -0,0 +1,235 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package blob
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Reader provides random access to the content of a blob.
+// It implements io.ReaderAt, io.ReadSeeker and io.Closer. Data is fetched in blocks with ranged
+// DownloadStream calls and kept in a bounded cache, optionally reading ahead when access is sequential.
+// All ranged reads are conditioned on the ETag observed when the Reader was created, so a Reader never
+// mixes data from different versions of the blob. ReadAt may be called concurrently; Read and Seek may not.
+type Reader struct {
+	client  *Client
+	ctx     context.Context
+	cancel  context.CancelFunc
+	props   GetPropertiesResponse
+	size    int64
+	options ReaderOptions
+
+	mu        sync.Mutex
+	blocks    map[int64]*list.Element
+	lru       *list.List
+	lastBlock int64
+
+	pos int64
+}
+
+// readerBlock is a cached block; done is closed once data or err is set.
+type readerBlock struct {
+	index int64
+	done  chan struct{}
+	data  []byte
+	err   error
+}
+
+// NewReader creates a Reader over the blob's current content.
+// The provided context is used for all requests made by the Reader, including read-ahead;
+// read-ahead stops when the Reader is closed.
+func (b *Client) NewReader(ctx context.Context, o *ReaderOptions) (*Reader, error) {
+	options := ReaderOptions{}
+	if o != nil {
+		options = *o
+	}
+	options.setDefaults()
+
+	props, err := b.GetProperties(ctx, &GetPropertiesOptions{AccessConditions: options.AccessConditions, CPKInfo: options.CPKInfo})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r := &Reader{
+		client:    b,
+		ctx:       ctx,
+		cancel:    cancel,
+		props:     props,
+		size:      *props.ContentLength,
+		options:   options,
+		blocks:    map[int64]*list.Element{},
+		lru:       list.New(),
+		lastBlock: -1,
+	}
+	return r, nil
+}
+
+// Size returns the size of the blob in bytes.
+func (r *Reader) Size() int64 {
+	return r.size
+}
+
+// Properties returns the blob properties observed when the Reader was created.
+func (r *Reader) Properties() GetPropertiesResponse {
+	return r.props
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("blob.Reader.ReadAt: negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	read := 0
+	for read < len(p) && off < r.size {
+		block := off / r.options.BlockSize
+		data, err := r.getBlock(block)
+		if err != nil {
+			return read, err
+		}
+		n := copy(p[read:], data[off-block*r.options.BlockSize:])
+		read += n
+		off += int64(n)
+	}
+	if read < len(p) {
+		return read, io.EOF
+	}
+	return read, nil
+}
+
+// Read implements io.Reader.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	n, err := r.ReadAt(p, r.pos)
+	r.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("blob.Reader.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("blob.Reader.Seek: negative position")
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+// Close stops any read-ahead in progress and releases the cached blocks.
+func (r *Reader) Close() error {
+	r.cancel()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.blocks = map[int64]*list.Element{}
+	r.lru.Init()
+	return nil
+}
+
+// getBlock returns the data of the specified block, downloading it if it's not cached.
+func (r *Reader) getBlock(index int64) ([]byte, error) {
+	r.mu.Lock()
+	blk := r.fetchLocked(index)
+	if r.options.ReadAhead > 0 && (index == r.lastBlock || index == r.lastBlock+1) {
+		for i := index + 1; i <= index+int64(r.options.ReadAhead) && i*r.options.BlockSize < r.size; i++ {
+			r.fetchLocked(i)
+		}
+	}
+	r.lastBlock = index
+	r.mu.Unlock()
+
+	select {
+	case <-blk.done:
+	case <-r.ctx.Done():
+		return nil, r.ctx.Err()
+	}
+	if blk.err != nil {
+		// don't cache failures so that a later read can try again
+		r.mu.Lock()
+		if e, ok := r.blocks[index]; ok && e.Value.(*readerBlock) == blk {
+			r.lru.Remove(e)
+			delete(r.blocks, index)
+		}
+		r.mu.Unlock()
+		return nil, blk.err
+	}
+	return blk.data, nil
+}
+
+// fetchLocked returns the cached block or starts downloading it. r.mu must be held.
+func (r *Reader) fetchLocked(index int64) *readerBlock {
+	if e, ok := r.blocks[index]; ok {
+		r.lru.MoveToFront(e)
+		return e.Value.(*readerBlock)
+	}
+
+	blk := &readerBlock{index: index, done: make(chan struct{})}
+	r.blocks[index] = r.lru.PushFront(blk)
+	for r.lru.Len() > r.options.CacheSize {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.blocks, oldest.Value.(*readerBlock).index)
+	}
+
+	go func() {
+		defer close(blk.done)
+		blk.data, blk.err = r.download(index)
+	}()
+	return blk
+}
+
+func (r *Reader) download(index int64) ([]byte, error) {
+	offset := index * r.options.BlockSize
+	count := min(r.options.BlockSize, r.size-offset)
+
+	accessConditions := &AccessConditions{ModifiedAccessConditions: &ModifiedAccessConditions{IfMatch: r.props.ETag}}
+	if r.options.AccessConditions != nil {
+		accessConditions.LeaseAccessConditions = r.options.AccessConditions.LeaseAccessConditions
+	}
+	resp, err := r.client.DownloadStream(r.ctx, &DownloadStreamOptions{
+		Range:            HTTPRange{Offset: offset, Count: count},
+		AccessConditions: accessConditions,
+		CPKInfo:          r.options.CPKInfo,
+		CPKScopeInfo:     r.options.CPKScopeInfo,
+	})
+	if err != nil {
+		return nil, err
+	}
+	body := resp.NewRetryReader(r.ctx, &r.options.RetryReaderOptionsPerBlock)
+	defer body.Close()
+
+	data := make([]byte, count)
+	if _, err := io.ReadFull(body, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+var _ interface {
+	io.ReaderAt
+	io.ReadSeekCloser
+} = (*Reader)(nil)
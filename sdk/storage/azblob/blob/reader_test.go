@@ -0,0 +1,152 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+// rangeTransport serves Get Blob and Get Blob Properties requests for a single blob and records the ranges requested.
+type rangeTransport struct {
+	data []byte
+
+	mu     sync.Mutex
+	ranges []string
+}
+
+func (f *rangeTransport) Do(req *http.Request) (*http.Response, error) {
+	header := http.Header{}
+	header.Set("ETag", `"0x1"`)
+	if req.Method == http.MethodHead {
+		header.Set("Content-Length", strconv.Itoa(len(f.data)))
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody, Request: req}, nil
+	}
+	if m := req.Header["If-Match"]; len(m) == 0 || m[0] != `"0x1"` {
+		return nil, fmt.Errorf("missing If-Match header")
+	}
+
+	var start, end int
+	_, _ = fmt.Sscanf(req.Header["x-ms-range"][0], "bytes=%d-%d", &start, &end)
+	end = min(end, len(f.data)-1)
+	f.mu.Lock()
+	f.ranges = append(f.ranges, fmt.Sprintf("%d-%d", start, end))
+	f.mu.Unlock()
+
+	header.Set("Content-Length", strconv.Itoa(end-start+1))
+	header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(f.data)))
+	return &http.Response{StatusCode: http.StatusPartialContent, Header: header, Body: io.NopCloser(bytes.NewReader(f.data[start : end+1])), Request: req}, nil
+}
+
+func (f *rangeTransport) requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.ranges)
+}
+
+func newReaderTestClient(t *testing.T, size int) (*Client, *rangeTransport) {
+	data := make([]byte, size)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+	transport := &rangeTransport{data: data}
+	client, err := NewClientWithNoCredential("https://account.blob.core.windows.net/c/b", &ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: transport, Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	require.NoError(t, err)
+	return client, transport
+}
+
+func TestReaderReadAtAndSeek(t *testing.T) {
+	client, transport := newReaderTestClient(t, 1000)
+	r, err := client.NewReader(context.Background(), &ReaderOptions{BlockSize: 100, CacheSize: 2})
+	require.NoError(t, err)
+	defer r.Close()
+	require.Equal(t, int64(1000), r.Size())
+
+	p := make([]byte, 150)
+	n, err := r.ReadAt(p, 50)
+	require.NoError(t, err)
+	require.Equal(t, 150, n)
+	require.Equal(t, transport.data[50:200], p)
+	require.Equal(t, []string{"0-99", "100-199"}, transport.ranges)
+
+	// cached blocks aren't downloaded again
+	_, err = r.ReadAt(p[:20], 120)
+	require.NoError(t, err)
+	require.Equal(t, 2, transport.requests())
+
+	n, err = r.ReadAt(p, 950)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, 50, n)
+	require.Equal(t, transport.data[950:], p[:n])
+
+	pos, err := r.Seek(-10, io.SeekEnd)
+	require.NoError(t, err)
+	require.Equal(t, int64(990), pos)
+	rest, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, transport.data[990:], rest)
+
+	_, err = r.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	all, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, transport.data, all)
+}
+
+func TestReaderReadAhead(t *testing.T) {
+	client, transport := newReaderTestClient(t, 1000)
+	r, err := client.NewReader(context.Background(), &ReaderOptions{BlockSize: 100, ReadAhead: 3})
+	require.NoError(t, err)
+	defer r.Close()
+
+	p := make([]byte, 10)
+	_, err = r.ReadAt(p, 0)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return transport.requests() == 4 }, time.Second, 10*time.Millisecond)
+
+	// blocks 1-3 were prefetched; reading them moves the read-ahead window to blocks 4-6
+	for off := int64(100); off < 400; off += 100 {
+		_, err = r.ReadAt(p, off)
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool { return transport.requests() == 7 }, time.Second, 10*time.Millisecond)
+}
+
+func TestReaderConcurrentReadAt(t *testing.T) {
+	client, transport := newReaderTestClient(t, 4096)
+	r, err := client.NewReader(context.Background(), &ReaderOptions{BlockSize: 256, ReadAhead: 1})
+	require.NoError(t, err)
+	defer r.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(off int64) {
+			defer wg.Done()
+			p := make([]byte, 300)
+			n, err := r.ReadAt(p, off)
+			if err != io.EOF {
+				require.NoError(t, err)
+			}
+			require.Equal(t, transport.data[off:off+int64(n)], p[:n])
+		}(int64(i * 250))
+	}
+	wg.Wait()
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"io"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/exported"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/generated"
 )
 
@@ -22,10 +23,11 @@ type DownloadStreamResponse struct {
 	DownloadResponse
 	ObjectReplicationRules []ObjectReplicationPolicy
 
-	client   *Client
-	getInfo  httpGetterInfo
-	cpkInfo  *CPKInfo
-	cpkScope *CPKScopeInfo
+	client    *Client
+	getInfo   httpGetterInfo
+	cpkInfo   *CPKInfo
+	cpkScope  *CPKScopeInfo
+	decryptor *exported.ClientSideDecryptor
 }
 
 // NewRetryReader constructs new RetryReader stream for reading data. If a connection fails while
@@ -47,7 +49,13 @@ func (r *DownloadStreamResponse) NewRetryReader(ctx context.Context, options *Re
 			CPKInfo:          r.cpkInfo,
 			CPKScopeInfo:     r.cpkScope,
 		}
-		resp, err := r.client.DownloadStream(ctx, &options)
+		var resp DownloadStreamResponse
+		var err error
+		if r.decryptor != nil {
+			resp, err = r.client.downloadStreamDecrypted(ctx, &options, r.decryptor)
+		} else {
+			resp, err = r.client.DownloadStream(ctx, &options)
+		}
 		if err != nil {
 			return nil, err
 		}
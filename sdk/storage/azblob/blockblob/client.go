@@ -176,6 +176,25 @@ func (bb *Client) Upload(ctx context.Context, body io.ReadSeekCloser, options *U
 
 	opts, httpHeaders, leaseInfo, cpkV, cpkN, accessConditions := options.format()
 
+	if options != nil && options.ClientSideEncryption != nil {
+		enc, err := exported.NewClientSideEncryptor(ctx, options.ClientSideEncryption)
+		if err != nil {
+			return UploadResponse{}, err
+		}
+		plaintext, err := io.ReadAll(body)
+		_ = body.Close()
+		if err != nil {
+			return UploadResponse{}, err
+		}
+		encrypted, err := enc.Encrypt(plaintext)
+		if err != nil {
+			return UploadResponse{}, err
+		}
+		body = streaming.NopCloser(bytes.NewReader(encrypted))
+		count = int64(len(encrypted))
+		opts.Metadata = enc.Metadata(opts.Metadata)
+	}
+
 	if options != nil && options.TransactionalValidation != nil {
 		body, err = options.TransactionalValidation.Apply(body, opts)
 		if err != nil {
@@ -422,6 +441,19 @@ func (bb *Client) GetSASURL(permissions sas.BlobPermissions, expiry time.Time, o
 
 // uploadFromReader uploads a buffer in blocks to a block blob.
 func (bb *Client) uploadFromReader(ctx context.Context, reader io.ReaderAt, actualSize int64, o *uploadFromReaderOptions) (uploadFromReaderResponse, error) {
+	if o.ClientSideEncryption != nil {
+		enc, err := exported.NewClientSideEncryptor(ctx, o.ClientSideEncryption)
+		if err != nil {
+			return uploadFromReaderResponse{}, err
+		}
+		reader, actualSize, err = enc.NewReaderAt(reader, actualSize)
+		if err != nil {
+			return uploadFromReaderResponse{}, err
+		}
+		o.Metadata = enc.Metadata(o.Metadata)
+		o.ClientSideEncryption = nil
+	}
+
 	if o.BlockSize == 0 {
 		// If bufferSize > (MaxStageBlockBytes * MaxBlocks), then error
 		if actualSize > MaxStageBlockBytes*MaxBlocks {
@@ -569,7 +601,18 @@ func (bb *Client) UploadStream(ctx context.Context, body io.Reader, o *UploadStr
 		return UploadStreamResponse{}, bloberror.UnsupportedChecksum
 	}
 
-	result, err := copyFromReader(ctx, body, bb, *o, shared.NewMMBPool)
+	uploadOptions := *o
+	if uploadOptions.ClientSideEncryption != nil {
+		enc, err := exported.NewClientSideEncryptor(ctx, uploadOptions.ClientSideEncryption)
+		if err != nil {
+			return UploadStreamResponse{}, err
+		}
+		body = enc.NewReader(body)
+		uploadOptions.Metadata = enc.Metadata(uploadOptions.Metadata)
+		uploadOptions.ClientSideEncryption = nil
+	}
+
+	result, err := copyFromReader(ctx, body, bb, uploadOptions, shared.NewMMBPool)
 	if err != nil {
 		return CommitBlockListResponse{}, err
 	}
@@ -0,0 +1,268 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package blockblob
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/uuid"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/shared"
+)
+
+// CopyFromURLInBlocks copies a source blob of any size to this block blob without moving its content through the client.
+// The source is split into ranges that the service reads from the source URL with StageBlockFromURL, in parallel,
+// and the blocks are then committed with the HTTP headers, metadata and tags of the source blob.
+// Every block is staged with an IfMatch condition on the ETag of the source blob, so a source that changes during
+// the copy fails it instead of producing a mix of versions.
+//   - source - a client for the source blob, which may be in another account. It's used to read the properties and
+//     tags of the source blob, and its URL is the copy source, so the URL must authorize reads (e.g. with a SAS)
+//     unless CopySourceAuthorization is specified.
+func (bb *Client) CopyFromURLInBlocks(ctx context.Context, source *blob.Client, o *CopyFromURLInBlocksOptions) (CopyFromURLInBlocksResponse, error) {
+	options := CopyFromURLInBlocksOptions{}
+	if o != nil {
+		options = *o
+	}
+
+	props, err := source.GetProperties(ctx, &blob.GetPropertiesOptions{AccessConditions: options.SourceAccessConditions})
+	if err != nil {
+		return CopyFromURLInBlocksResponse{}, err
+	}
+	if props.ContentLength == nil || props.ETag == nil {
+		return CopyFromURLInBlocksResponse{}, errors.New("the source blob properties are missing the content length or ETag")
+	}
+
+	commitOptions := &CommitBlockListOptions{
+		Metadata:         options.Metadata,
+		Tags:             options.Tags,
+		Tier:             options.AccessTier,
+		HTTPHeaders:      options.HTTPHeaders,
+		CPKInfo:          options.CPKInfo,
+		CPKScopeInfo:     options.CPKScopeInfo,
+		AccessConditions: options.AccessConditions,
+	}
+	if commitOptions.Metadata == nil {
+		commitOptions.Metadata = props.Metadata
+	}
+	if commitOptions.HTTPHeaders == nil {
+		commitOptions.HTTPHeaders = &blob.HTTPHeaders{
+			BlobCacheControl:       props.CacheControl,
+			BlobContentDisposition: props.ContentDisposition,
+			BlobContentEncoding:    props.ContentEncoding,
+			BlobContentLanguage:    props.ContentLanguage,
+			BlobContentMD5:         props.ContentMD5,
+			BlobContentType:        props.ContentType,
+		}
+	}
+	if commitOptions.Tags == nil && props.TagCount != nil && *props.TagCount > 0 {
+		tags, err := source.GetTags(ctx, nil)
+		if err != nil {
+			return CopyFromURLInBlocksResponse{}, err
+		}
+		commitOptions.Tags = map[string]string{}
+		for _, tag := range tags.BlobTagSet {
+			if tag != nil && tag.Key != nil && tag.Value != nil {
+				commitOptions.Tags[*tag.Key] = *tag.Value
+			}
+		}
+	}
+
+	checkpoint, resumed, err := bb.copyCheckpoint(ctx, source.URL(), *props.ETag, *props.ContentLength, &options)
+	if err != nil {
+		return CopyFromURLInBlocksResponse{}, err
+	}
+
+	size, blockSize := checkpoint.SourceSize, checkpoint.BlockSize
+	numBlocks := (size + blockSize - 1) / blockSize
+	blockIDs := make([]string, numBlocks)
+	for i := range blockIDs {
+		blockIDs[i] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s-%05d", checkpoint.CopyID, i)))
+	}
+
+	staged := map[string]int64{}
+	if resumed {
+		if staged, err = bb.uncommittedBlocks(ctx, options.AccessConditions); err != nil {
+			return CopyFromURLInBlocksResponse{}, err
+		}
+	}
+
+	progress := int64(0)
+	progressLock := &sync.Mutex{}
+	reportProgress := func(n int64) {
+		if options.Progress == nil {
+			return
+		}
+		progressLock.Lock()
+		progress += n
+		options.Progress(progress)
+		progressLock.Unlock()
+	}
+
+	if numBlocks > 0 {
+		leaseAccessConditions, _ := exported.FormatBlobAccessConditions(options.AccessConditions)
+		err = shared.DoBatchTransfer(ctx, &shared.BatchTransferOptions{
+			OperationName: "CopyFromURLInBlocks",
+			TransferSize:  size,
+			ChunkSize:     blockSize,
+			NumChunks:     uint64(numBlocks),
+			Concurrency:   options.Concurrency,
+			Operation: func(ctx context.Context, offset int64, count int64) error {
+				blockID := blockIDs[offset/blockSize]
+				if n, ok := staged[blockID]; ok && n == count {
+					reportProgress(count)
+					return nil
+				}
+				_, err := bb.StageBlockFromURL(ctx, blockID, source.URL(), &StageBlockFromURLOptions{
+					CopySourceAuthorization:        options.CopySourceAuthorization,
+					LeaseAccessConditions:          leaseAccessConditions,
+					SourceModifiedAccessConditions: &blob.SourceModifiedAccessConditions{SourceIfMatch: &checkpoint.SourceETag},
+					Range:                          blob.HTTPRange{Offset: offset, Count: count},
+					CPKInfo:                        options.CPKInfo,
+					CPKScopeInfo:                   options.CPKScopeInfo,
+				})
+				if err != nil {
+					return err
+				}
+				reportProgress(count)
+				return nil
+			},
+		})
+		if err != nil {
+			return CopyFromURLInBlocksResponse{}, err
+		}
+	}
+
+	resp, err := bb.CommitBlockList(ctx, blockIDs, commitOptions)
+	if err != nil {
+		return CopyFromURLInBlocksResponse{}, err
+	}
+	if options.Journal != nil {
+		if err := options.Journal.Delete(ctx); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
+// copyCheckpoint returns the checkpoint saved in the journal if it describes a copy of the same version of
+// the source, or starts a new copy. It returns true if the copy is resumed.
+func (bb *Client) copyCheckpoint(ctx context.Context, sourceURL string, etag azcore.ETag, size int64, o *CopyFromURLInBlocksOptions) (CopyCheckpoint, bool, error) {
+	sourceURL, _, _ = strings.Cut(sourceURL, "?")
+
+	if o.Journal != nil {
+		saved, err := o.Journal.Load(ctx)
+		if err != nil {
+			return CopyCheckpoint{}, false, err
+		}
+		if saved != nil && saved.SourceURL == sourceURL && saved.SourceETag == etag && saved.SourceSize == size && saved.BlockSize > 0 {
+			return *saved, true, nil
+		}
+	}
+
+	blockSize := o.BlockSize
+	if blockSize == 0 {
+		blockSize = max(int64(math.Ceil(float64(size)/MaxBlocks)), blob.DefaultDownloadBlockSize)
+	}
+	if blockSize < 0 || blockSize > MaxStageBlockBytes {
+		return CopyCheckpoint{}, false, fmt.Errorf("BlockSize must be between 1 and %d", MaxStageBlockBytes)
+	}
+	if (size+blockSize-1)/blockSize > MaxBlocks {
+		return CopyCheckpoint{}, false, errors.New("block limit exceeded")
+	}
+
+	copyID, err := uuid.New()
+	if err != nil {
+		return CopyCheckpoint{}, false, err
+	}
+	checkpoint := CopyCheckpoint{
+		CopyID:     copyID.String(),
+		SourceURL:  sourceURL,
+		SourceETag: etag,
+		SourceSize: size,
+		BlockSize:  blockSize,
+	}
+	if o.Journal != nil {
+		if err := o.Journal.Save(ctx, checkpoint); err != nil {
+			return CopyCheckpoint{}, false, err
+		}
+	}
+	return checkpoint, false, nil
+}
+
+// uncommittedBlocks returns the sizes of the uncommitted blocks of the blob by block ID.
+func (bb *Client) uncommittedBlocks(ctx context.Context, accessConditions *blob.AccessConditions) (map[string]int64, error) {
+	var leaseOnly *blob.AccessConditions
+	if accessConditions != nil && accessConditions.LeaseAccessConditions != nil {
+		leaseOnly = &blob.AccessConditions{LeaseAccessConditions: accessConditions.LeaseAccessConditions}
+	}
+	resp, err := bb.GetBlockList(ctx, BlockListTypeUncommitted, &GetBlockListOptions{AccessConditions: leaseOnly})
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return map[string]int64{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	blocks := map[string]int64{}
+	for _, block := range resp.UncommittedBlocks {
+		if block != nil && block.Name != nil && block.Size != nil {
+			blocks[*block.Name] = *block.Size
+		}
+	}
+	return blocks, nil
+}
+
+// NewFileCopyJournal returns a CopyJournal that saves the checkpoint as JSON to the file at path.
+func NewFileCopyJournal(path string) CopyJournal {
+	return fileCopyJournal(path)
+}
+
+type fileCopyJournal string
+
+func (j fileCopyJournal) Load(ctx context.Context) (*CopyCheckpoint, error) {
+	data, err := os.ReadFile(string(j))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var checkpoint CopyCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+func (j fileCopyJournal) Save(ctx context.Context, checkpoint CopyCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	// write a temporary file first so that a crash can't leave a partial checkpoint
+	tmp := string(j) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, string(j))
+}
+
+func (j fileCopyJournal) Delete(ctx context.Context) error {
+	if err := os.Remove(string(j)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
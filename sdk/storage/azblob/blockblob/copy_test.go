@@ -0,0 +1,206 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package blockblob
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/stretchr/testify/require"
+)
+
+// copyTransport serves a source blob and a destination block blob that stages blocks from the source by URL.
+type copyTransport struct {
+	source []byte
+
+	mu          sync.Mutex
+	uncommitted map[string][]byte
+	committed   []byte
+	commitReq   *http.Request
+	staged      []string
+
+	// failBlock is the index of a block whose staging fails
+	failBlock int
+}
+
+func (f *copyTransport) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	header := http.Header{}
+	respond := func(status int, code string, body []byte) (*http.Response, error) {
+		if code != "" {
+			header.Set("x-ms-error-code", code)
+		}
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(bytes.NewReader(body)), Request: req}, nil
+	}
+	q := req.URL.Query()
+
+	if req.URL.Host == "src.blob.core.windows.net" {
+		header.Set("ETag", `"0xsrc"`)
+		if q.Get("comp") == "tags" {
+			return respond(http.StatusOK, "", []byte(`<?xml version="1.0" encoding="utf-8"?><Tags><TagSet><Tag><Key>team</Key><Value>storage</Value></Tag></TagSet></Tags>`))
+		}
+		header.Set("Content-Length", strconv.Itoa(len(f.source)))
+		header.Set("Content-Type", "application/x-tar")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("x-ms-meta-owner", "someone")
+		header.Set("x-ms-tag-count", "1")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody, Request: req}, nil
+	}
+
+	switch {
+	case q.Get("comp") == "block":
+		if req.Header["x-ms-copy-source"][0] != "https://src.blob.core.windows.net/c/source?sig=secret" {
+			return respond(http.StatusBadRequest, "InvalidHeaderValue", nil)
+		}
+		if req.Header["x-ms-source-if-match"][0] != `"0xsrc"` {
+			return respond(http.StatusPreconditionFailed, "SourceConditionNotMet", nil)
+		}
+		var start, end int
+		_, _ = fmt.Sscanf(req.Header["x-ms-source-range"][0], "bytes=%d-%d", &start, &end)
+		id := q.Get("blockid")
+		if f.failBlock >= 0 && start/(end-start+1) == f.failBlock {
+			return respond(http.StatusInternalServerError, "InternalError", nil)
+		}
+		f.staged = append(f.staged, id)
+		f.uncommitted[id] = f.source[start : end+1]
+		return respond(http.StatusCreated, "", nil)
+	case q.Get("comp") == "blocklist" && req.Method == http.MethodGet:
+		var list strings.Builder
+		for id, data := range f.uncommitted {
+			fmt.Fprintf(&list, "<Block><Name>%s</Name><Size>%d</Size></Block>", id, len(data))
+		}
+		return respond(http.StatusOK, "", []byte(`<?xml version="1.0" encoding="utf-8"?><BlockList><CommittedBlocks /><UncommittedBlocks>`+list.String()+`</UncommittedBlocks></BlockList>`))
+	case q.Get("comp") == "blocklist":
+		var body struct {
+			Latest []string `xml:"Latest"`
+		}
+		if err := xml.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		f.committed = []byte{}
+		for _, id := range body.Latest {
+			f.committed = append(f.committed, f.uncommitted[id]...)
+		}
+		f.uncommitted = map[string][]byte{}
+		f.commitReq = req
+		return respond(http.StatusCreated, "", nil)
+	}
+	return respond(http.StatusBadRequest, "UnsupportedHttpVerb", nil)
+}
+
+func newCopyTestClients(t *testing.T, transport *copyTransport) (*Client, *blob.Client) {
+	options := azcore.ClientOptions{Transport: transport, Retry: policy.RetryOptions{MaxRetries: -1}}
+	dst, err := NewClientWithNoCredential("https://dst.blob.core.windows.net/c/copy", &ClientOptions{ClientOptions: options})
+	require.NoError(t, err)
+	src, err := blob.NewClientWithNoCredential("https://src.blob.core.windows.net/c/source?sig=secret", &blob.ClientOptions{ClientOptions: options})
+	require.NoError(t, err)
+	return dst, src
+}
+
+func newCopyTestTransport(t *testing.T, size int) *copyTransport {
+	source := make([]byte, size)
+	_, err := rand.Read(source)
+	require.NoError(t, err)
+	return &copyTransport{source: source, uncommitted: map[string][]byte{}, failBlock: -1}
+}
+
+func TestCopyFromURLInBlocks(t *testing.T) {
+	transport := newCopyTestTransport(t, 10000)
+	dst, src := newCopyTestClients(t, transport)
+
+	var progress int64
+	_, err := dst.CopyFromURLInBlocks(context.Background(), src, &CopyFromURLInBlocksOptions{
+		BlockSize:   1024,
+		Concurrency: 4,
+		Progress:    func(n int64) { progress = n },
+		AccessTier:  to.Ptr(blob.AccessTierCool),
+	})
+	require.NoError(t, err)
+	require.Equal(t, transport.source, transport.committed)
+	require.Len(t, transport.staged, 10)
+	require.Equal(t, int64(10000), progress)
+
+	commit := transport.commitReq
+	require.Equal(t, "application/x-tar", commit.Header["x-ms-blob-content-type"][0])
+	require.Equal(t, "no-cache", commit.Header["x-ms-blob-cache-control"][0])
+	require.Equal(t, "team=storage", commit.Header["x-ms-tags"][0])
+	require.Equal(t, "Cool", commit.Header["x-ms-access-tier"][0])
+	var metadata string
+	for k, v := range commit.Header {
+		if strings.EqualFold(k, "x-ms-meta-owner") {
+			metadata = v[0]
+		}
+	}
+	require.Equal(t, "someone", metadata)
+}
+
+func TestCopyFromURLInBlocksResume(t *testing.T) {
+	transport := newCopyTestTransport(t, 5000)
+	transport.failBlock = 4
+	dst, src := newCopyTestClients(t, transport)
+	journalPath := filepath.Join(t.TempDir(), "copy.json")
+	options := &CopyFromURLInBlocksOptions{
+		BlockSize:   1000,
+		Concurrency: 1,
+		Journal:     NewFileCopyJournal(journalPath),
+	}
+
+	_, err := dst.CopyFromURLInBlocks(context.Background(), src, options)
+	require.Error(t, err)
+	require.Len(t, transport.staged, 4)
+	checkpoint, err := options.Journal.Load(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "https://src.blob.core.windows.net/c/source", checkpoint.SourceURL)
+	require.Equal(t, int64(1000), checkpoint.BlockSize)
+	id, err := base64.StdEncoding.DecodeString(transport.staged[0])
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(id), checkpoint.CopyID+"-"))
+
+	// the resumed copy only stages the remaining blocks
+	transport.failBlock = -1
+	transport.staged = nil
+	var progress []int64
+	options.Progress = func(n int64) { progress = append(progress, n) }
+	_, err = dst.CopyFromURLInBlocks(context.Background(), src, options)
+	require.NoError(t, err)
+	require.Len(t, transport.staged, 1)
+	require.Equal(t, []int64{1000, 2000, 3000, 4000, 5000}, progress)
+	require.Equal(t, transport.source, transport.committed)
+
+	_, err = os.Stat(journalPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestCopyFromURLInBlocksEmptySource(t *testing.T) {
+	transport := newCopyTestTransport(t, 0)
+	dst, src := newCopyTestClients(t, transport)
+
+	_, err := dst.CopyFromURLInBlocks(context.Background(), src, &CopyFromURLInBlocksOptions{Tags: map[string]string{}})
+	require.NoError(t, err)
+	require.Empty(t, transport.staged)
+	require.NotNil(t, transport.commitReq)
+	require.Empty(t, transport.commitReq.Header["x-ms-tags"])
+}
@@ -7,8 +7,10 @@
 package blockblob
 
 import (
+	"context"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/exported"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/generated"
@@ -50,6 +52,10 @@ type UploadOptions struct {
 
 	// Deprecated: TransactionalContentMD5 can be set by using TransactionalValidation instead
 	TransactionalContentMD5 []byte
+
+	// ClientSideEncryption, when set, encrypts the content before it is sent and records the encryption
+	// envelope in the blob's metadata. The whole body is read into memory to be encrypted.
+	ClientSideEncryption *blob.ClientSideEncryptionOptions
 }
 
 func (o *UploadOptions) format() (*generated.BlockBlobClientUploadOptions, *generated.BlobHTTPHeaders, *generated.LeaseAccessConditions,
@@ -278,6 +284,10 @@ type uploadFromReaderOptions struct {
 
 	// Deprecated: TransactionalContentMD5 cannot be generated at block level
 	TransactionalContentMD5 []byte
+
+	// ClientSideEncryption, when set, encrypts the content before it is sent and records the encryption
+	// envelope in the blob's metadata.
+	ClientSideEncryption *blob.ClientSideEncryptionOptions
 }
 
 // UploadBufferOptions provides set of configurations for UploadBuffer operation.
@@ -340,6 +350,10 @@ type UploadStreamOptions struct {
 	Tags             map[string]string
 	CPKInfo          *blob.CPKInfo
 	CPKScopeInfo     *blob.CPKScopeInfo
+
+	// ClientSideEncryption, when set, encrypts the content before it is sent and records the encryption
+	// envelope in the blob's metadata.
+	ClientSideEncryption *blob.ClientSideEncryptionOptions
 }
 
 func (u *UploadStreamOptions) setDefaults() {
@@ -417,3 +431,82 @@ type ExpiryTypeNever = exported.ExpiryTypeNever
 
 // SetExpiryOptions contains the optional parameters for the Client.SetExpiry method.
 type SetExpiryOptions = exported.SetExpiryOptions
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// CopyFromURLInBlocksOptions contains the optional parameters for the Client.CopyFromURLInBlocks method.
+type CopyFromURLInBlocksOptions struct {
+	// BlockSize specifies the size of the source ranges staged in parallel. The default is the larger of
+	// blob.DefaultDownloadBlockSize and the size required to stay within MaxBlocks blocks.
+	BlockSize int64
+
+	// Concurrency indicates the maximum number of blocks to stage in parallel (0=default).
+	Concurrency uint16
+
+	// Progress is a function that is invoked as blocks are staged, with the number of bytes of the source
+	// that have been staged so far, including blocks staged before the copy was resumed.
+	Progress func(bytesTransferred int64)
+
+	// Journal, when set, records the state of the copy so that a copy that was interrupted, even by the
+	// process exiting, resumes with the blocks that were already staged. The journal is deleted once the
+	// block list is committed.
+	Journal CopyJournal
+
+	// Only Bearer type is supported. Credentials should be a valid OAuth access token to copy source.
+	CopySourceAuthorization *string
+
+	// HTTPHeaders overrides the HTTP headers of the source blob.
+	HTTPHeaders *blob.HTTPHeaders
+
+	// Metadata overrides the metadata of the source blob. Set it to an empty map to copy no metadata.
+	Metadata map[string]*string
+
+	// Tags overrides the tags of the source blob. Set it to an empty map to copy no tags.
+	Tags map[string]string
+
+	// AccessTier indicates the tier of the destination blob.
+	AccessTier *blob.AccessTier
+
+	// AccessConditions indicates the access conditions for the destination blob.
+	AccessConditions *blob.AccessConditions
+
+	// SourceAccessConditions indicates the access conditions used when reading the properties of the source blob.
+	// Every block is staged with an IfMatch condition on the ETag of the source blob.
+	SourceAccessConditions *blob.AccessConditions
+
+	// CPKInfo and CPKScopeInfo are used for the destination blob.
+	CPKInfo      *blob.CPKInfo
+	CPKScopeInfo *blob.CPKScopeInfo
+}
+
+// CopyCheckpoint is the state of a Client.CopyFromURLInBlocks operation that's recorded by a CopyJournal.
+type CopyCheckpoint struct {
+	// CopyID identifies the copy; it's the prefix of the IDs of the staged blocks.
+	CopyID string `json:"copyId"`
+
+	// SourceURL is the URL of the source blob, without the query string.
+	SourceURL string `json:"sourceUrl"`
+
+	// SourceETag is the ETag of the source blob when the copy started. A copy isn't resumed if the source has changed.
+	SourceETag azcore.ETag `json:"sourceEtag"`
+
+	// SourceSize is the size of the source blob in bytes.
+	SourceSize int64 `json:"sourceSize"`
+
+	// BlockSize is the size of the staged blocks.
+	BlockSize int64 `json:"blockSize"`
+}
+
+// CopyJournal persists a CopyCheckpoint so that Client.CopyFromURLInBlocks can resume an interrupted copy.
+// The blocks that were staged are read from the destination's uncommitted block list, so the journal only
+// needs to be saved once, before staging starts. Uncommitted blocks are discarded by the service after a week.
+type CopyJournal interface {
+	// Load returns the saved checkpoint, or nil if there's none.
+	Load(ctx context.Context) (*CopyCheckpoint, error)
+
+	// Save saves the checkpoint.
+	Save(ctx context.Context, checkpoint CopyCheckpoint) error
+
+	// Delete deletes the saved checkpoint, if any.
+	Delete(ctx context.Context) error
+}
@@ -31,6 +31,9 @@ type StageBlockFromURLResponse = generated.BlockBlobClientStageBlockFromURLRespo
 // GetBlockListResponse contains the response from method Client.GetBlockList.
 type GetBlockListResponse = generated.BlockBlobClientGetBlockListResponse
 
+// CopyFromURLInBlocksResponse contains the response from method Client.CopyFromURLInBlocks.
+type CopyFromURLInBlocksResponse = CommitBlockListResponse
+
 // uploadFromReaderResponse contains the response from method Client.UploadBuffer/Client.UploadFile.
 type uploadFromReaderResponse struct {
 	// ClientRequestID contains the information returned from the x-ms-client-request-id header response.
@@ -0,0 +1,153 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package container
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/exported"
+)
+
+// BulkDelete deletes the blobs in the container selected by source. The blobs are deleted in batches of up to
+// 256 blobs, which are submitted concurrently, and the blobs whose deletion failed with a transient error are
+// retried. The returned BulkResult contains the blobs whose deletion failed; an error is returned only if listing
+// the blobs failed or ctx was canceled.
+func (c *Client) BulkDelete(ctx context.Context, source BulkSource, o *BulkDeleteOptions) (BulkResult, error) {
+	options := BulkDeleteOptions{}
+	if o != nil {
+		options = *o
+	}
+	deleteOptions := &BatchDeleteOptions{DeleteOptions: blob.DeleteOptions{DeleteSnapshots: options.DeleteSnapshots}}
+	return c.bulk(ctx, source, options.BulkOptions, func(bb *BatchBuilder, blobName string) error {
+		return bb.Delete(blobName, deleteOptions)
+	})
+}
+
+// BulkSetTier sets the access tier of the blobs in the container selected by source. The blobs are updated in
+// batches of up to 256 blobs, which are submitted concurrently, and the blobs whose update failed with a transient
+// error are retried. The returned BulkResult contains the blobs whose update failed; an error is returned only if
+// listing the blobs failed or ctx was canceled.
+func (c *Client) BulkSetTier(ctx context.Context, source BulkSource, tier blob.AccessTier, o *BulkSetTierOptions) (BulkResult, error) {
+	options := BulkSetTierOptions{}
+	if o != nil {
+		options = *o
+	}
+	setTierOptions := &BatchSetTierOptions{SetTierOptions: blob.SetTierOptions{RehydratePriority: options.RehydratePriority}}
+	return c.bulk(ctx, source, options.BulkOptions, func(bb *BatchBuilder, blobName string) error {
+		return bb.SetTier(blobName, tier, setTierOptions)
+	})
+}
+
+// BulkSetTags replaces the tags of the blobs in the container selected by source. Batches don't support setting
+// tags, so the tags of every blob are set with a separate request and BulkOptions.BatchSize is ignored; up to
+// BulkOptions.Concurrency requests are in flight at the same time. The blobs whose update failed with a transient
+// error are retried. The returned BulkResult contains the blobs whose update failed; an error is returned only if
+// listing the blobs failed or ctx was canceled.
+func (c *Client) BulkSetTags(ctx context.Context, source BulkSource, tags map[string]string, o *BulkSetTagsOptions) (BulkResult, error) {
+	options := BulkSetTagsOptions{}
+	if o != nil {
+		options = *o
+	}
+	list, err := c.listBulkTargets(source)
+	if err != nil {
+		return BulkResult{}, err
+	}
+	options.BatchSize = 1
+	return exported.DoBulk(ctx, list, func(ctx context.Context, targets []exported.BulkTarget) ([]error, error) {
+		_, err := c.NewBlobClient(targets[0].BlobName).SetTags(ctx, tags, nil)
+		return []error{err}, nil
+	}, options.BulkOptions)
+}
+
+// bulk adds a sub-request to a batch with add for every blob selected by source and submits the batches.
+func (c *Client) bulk(ctx context.Context, source BulkSource, o BulkOptions, add func(bb *BatchBuilder, blobName string) error) (BulkResult, error) {
+	list, err := c.listBulkTargets(source)
+	if err != nil {
+		return BulkResult{}, err
+	}
+	return exported.DoBulk(ctx, list, func(ctx context.Context, targets []exported.BulkTarget) ([]error, error) {
+		bb, err := c.NewBatchBuilder()
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range targets {
+			if err := add(bb, target.BlobName); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := c.SubmitBatch(ctx, bb, nil)
+		if err != nil {
+			return nil, err
+		}
+		return exported.BatchResponseErrors(resp.Responses, len(targets)), nil
+	}, o)
+}
+
+// listBulkTargets returns a function listing the blobs selected by source.
+func (c *Client) listBulkTargets(source BulkSource) (exported.BulkList, error) {
+	if err := source.Validate(); err != nil {
+		return nil, err
+	}
+	containerName, err := c.containerName()
+	if err != nil {
+		return nil, err
+	}
+	target := func(blobName string) exported.BulkTarget {
+		return exported.BulkTarget{ContainerName: containerName, BlobName: blobName}
+	}
+
+	switch {
+	case source.Prefix != nil:
+		pager := c.NewListBlobsFlatPager(&ListBlobsFlatOptions{Prefix: source.Prefix})
+		return func(ctx context.Context) ([]exported.BulkTarget, bool, error) {
+			resp, err := pager.NextPage(ctx)
+			if err != nil {
+				return nil, false, err
+			}
+			var targets []exported.BulkTarget
+			if resp.Segment != nil {
+				for _, item := range resp.Segment.BlobItems {
+					if item != nil && item.Name != nil {
+						targets = append(targets, target(*item.Name))
+					}
+				}
+			}
+			return targets, pager.More(), nil
+		}, nil
+	case source.Where != nil:
+		var marker *string
+		return func(ctx context.Context) ([]exported.BulkTarget, bool, error) {
+			resp, err := c.FilterBlobs(ctx, *source.Where, &FilterBlobsOptions{Marker: marker})
+			if err != nil {
+				return nil, false, err
+			}
+			var targets []exported.BulkTarget
+			for _, item := range resp.Blobs {
+				if item != nil && item.Name != nil {
+					targets = append(targets, target(*item.Name))
+				}
+			}
+			marker = resp.NextMarker
+			return targets, marker != nil && *marker != "", nil
+		}, nil
+	default:
+		targets := make([]exported.BulkTarget, len(source.BlobNames))
+		for i, name := range source.BlobNames {
+			targets[i] = target(name)
+		}
+		return exported.ListBulkTargets(targets), nil
+	}
+}
+
+func (c *Client) containerName() (string, error) {
+	urlParts, err := blob.ParseURL(c.URL())
+	if err != nil {
+		return "", err
+	}
+	return urlParts.ContainerName, nil
+}
@@ -0,0 +1,284 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBulkBlob struct {
+	tier string
+	tags map[string]string
+}
+
+// bulkTransport is an in-memory container that supports listing and filtering blobs, batches of
+// deletes and set tier operations, and setting tags.
+type bulkTransport struct {
+	mu    sync.Mutex
+	blobs map[string]*fakeBulkBlob
+
+	// busy is the number of times the operation on a blob fails with ServerBusy before it succeeds
+	busy map[string]int
+
+	batches  []int
+	setTags  int
+	pageSize int
+}
+
+func (f *bulkTransport) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	header := http.Header{}
+	respond := func(status int, body string) (*http.Response, error) {
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	}
+	q := req.URL.Query()
+
+	switch q.Get("comp") {
+	case "list":
+		names := f.names(func(name string, _ *fakeBulkBlob) bool { return strings.HasPrefix(name, q.Get("prefix")) })
+		start, _ := strconv.Atoi(q.Get("marker"))
+		end := min(start+f.pageSize, len(names))
+		var items strings.Builder
+		for _, name := range names[start:end] {
+			fmt.Fprintf(&items, "<Blob><Name>%s</Name><Properties /></Blob>", name)
+		}
+		next := ""
+		if end < len(names) {
+			next = strconv.Itoa(end)
+		}
+		return respond(http.StatusOK, `<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs>`+items.String()+`</Blobs><NextMarker>`+next+`</NextMarker></EnumerationResults>`)
+	case "blobs":
+		key, value, _ := strings.Cut(q.Get("where"), "=")
+		value = strings.Trim(value, "'")
+		names := f.names(func(_ string, b *fakeBulkBlob) bool { return b.tags[key] == value })
+		var items strings.Builder
+		for _, name := range names {
+			fmt.Fprintf(&items, "<Blob><Name>%s</Name><ContainerName>c</ContainerName></Blob>", name)
+		}
+		return respond(http.StatusOK, `<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs>`+items.String()+`</Blobs><NextMarker /></EnumerationResults>`)
+	case "tags":
+		name := strings.TrimPrefix(req.URL.Path, "/c/")
+		f.setTags++
+		if f.busy[name] > 0 {
+			f.busy[name]--
+			header.Set("x-ms-error-code", string(bloberror.ServerBusy))
+			return respond(http.StatusServiceUnavailable, "")
+		}
+		var body struct {
+			Tags []struct {
+				Key   string `xml:"Key"`
+				Value string `xml:"Value"`
+			} `xml:"TagSet>Tag"`
+		}
+		if err := xml.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		b := f.blobs[name]
+		b.tags = map[string]string{}
+		for _, tag := range body.Tags {
+			b.tags[tag.Key] = tag.Value
+		}
+		return respond(http.StatusNoContent, "")
+	case "batch":
+		return f.batch(req)
+	}
+	return respond(http.StatusBadRequest, "")
+}
+
+func (f *bulkTransport) batch(req *http.Request) (*http.Response, error) {
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	reader := multipart.NewReader(req.Body, params["boundary"])
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	count := 0
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		subRequest, err := http.ReadRequest(bufio.NewReader(part))
+		if err != nil {
+			return nil, err
+		}
+		count++
+
+		status, code := http.StatusAccepted, ""
+		name, _ := url.PathUnescape(strings.TrimPrefix(subRequest.URL.EscapedPath(), "/c/"))
+		b := f.blobs[name]
+		switch {
+		case b == nil:
+			status, code = http.StatusNotFound, string(bloberror.BlobNotFound)
+		case f.busy[name] > 0:
+			f.busy[name]--
+			status, code = http.StatusServiceUnavailable, string(bloberror.ServerBusy)
+		case subRequest.Method == http.MethodDelete:
+			delete(f.blobs, name)
+		default:
+			b.tier = subRequest.Header.Get("x-ms-access-tier")
+			status = http.StatusOK
+		}
+
+		partWriter, err := writer.CreatePart(map[string][]string{
+			"Content-Type": {"application/http"},
+			"Content-ID":   {part.Header.Get("Content-ID")},
+		})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(partWriter, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+		if code != "" {
+			fmt.Fprintf(partWriter, "x-ms-error-code: %s\r\n", code)
+		}
+		fmt.Fprint(partWriter, "Content-Length: 0\r\n\r\n")
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	f.batches = append(f.batches, count)
+
+	header := http.Header{}
+	header.Set("Content-Type", "multipart/mixed; boundary="+writer.Boundary())
+	return &http.Response{StatusCode: http.StatusAccepted, Header: header, Body: io.NopCloser(body), Request: req}, nil
+}
+
+// names returns the sorted names of the blobs that match.
+func (f *bulkTransport) names(match func(string, *fakeBulkBlob) bool) []string {
+	var names []string
+	for name, b := range f.blobs {
+		if match(name, b) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newBulkTestClient(t *testing.T, transport *bulkTransport) *Client {
+	client, err := NewClientWithNoCredential("https://account.blob.core.windows.net/c", &ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: transport, Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func newBulkTestTransport(count int) *bulkTransport {
+	transport := &bulkTransport{blobs: map[string]*fakeBulkBlob{}, busy: map[string]int{}, pageSize: 100}
+	for i := 0; i < count; i++ {
+		transport.blobs[fmt.Sprintf("logs/%04d", i)] = &fakeBulkBlob{tier: "Hot"}
+	}
+	transport.blobs["keep"] = &fakeBulkBlob{tier: "Hot"}
+	return transport
+}
+
+func TestBulkDeleteByPrefix(t *testing.T) {
+	transport := newBulkTestTransport(600)
+	transport.busy["logs/0007"] = 2
+	transport.busy["logs/0300"] = 10
+
+	var results int
+	result, err := newBulkTestClient(t, transport).BulkDelete(context.Background(), BulkSource{Prefix: to.Ptr("logs/")}, &BulkDeleteOptions{
+		BulkOptions: BulkOptions{
+			Concurrency: 3,
+			RetryDelay:  time.Millisecond,
+			OnResult:    func(BulkItemResult) { results++ },
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 600, results)
+	require.Equal(t, int64(599), result.Succeeded)
+
+	// logs/0300 is still busy after the retries
+	require.Len(t, result.Failed, 1)
+	require.Equal(t, "c", result.Failed[0].ContainerName)
+	require.Equal(t, "logs/0300", result.Failed[0].BlobName)
+	require.True(t, bloberror.HasCode(result.Failed[0].Error, bloberror.ServerBusy))
+	require.Len(t, transport.blobs, 2)
+	require.Contains(t, transport.blobs, "keep")
+
+	// the listing is split into batches of 256, and the busy blobs are retried in batches of their own
+	sort.Ints(transport.batches)
+	require.Equal(t, []int{1, 1, 1, 1, 1, 88, 256, 256}, transport.batches)
+}
+
+func TestBulkSetTierByList(t *testing.T) {
+	transport := newBulkTestTransport(10)
+	source := BulkSource{BlobNames: []string{"logs/0001", "logs/0002", "missing"}}
+
+	result, err := newBulkTestClient(t, transport).BulkSetTier(context.Background(), source, blob.AccessTierCool, &BulkSetTierOptions{
+		BulkOptions: BulkOptions{BatchSize: 2, MaxRetries: -1},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), result.Succeeded)
+	require.Len(t, result.Failed, 1)
+	require.Equal(t, "missing", result.Failed[0].BlobName)
+	require.True(t, bloberror.HasCode(result.Failed[0].Error, bloberror.BlobNotFound))
+	require.Equal(t, "Cool", transport.blobs["logs/0001"].tier)
+	require.Equal(t, "Cool", transport.blobs["logs/0002"].tier)
+	require.Equal(t, "Hot", transport.blobs["logs/0003"].tier)
+	require.ElementsMatch(t, []int{2, 1}, transport.batches)
+}
+
+func TestBulkSetTagsAndDeleteByTags(t *testing.T) {
+	transport := newBulkTestTransport(20)
+	transport.busy["logs/0005"] = 1
+	client := newBulkTestClient(t, transport)
+
+	result, err := client.BulkSetTags(context.Background(), BulkSource{Prefix: to.Ptr("logs/001")}, map[string]string{"status": "expired"}, &BulkSetTagsOptions{
+		BulkOptions: BulkOptions{RetryDelay: time.Millisecond},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(10), result.Succeeded)
+	require.Empty(t, result.Failed)
+	require.Equal(t, 10, transport.setTags)
+
+	result, err = client.BulkDelete(context.Background(), BulkSource{Where: to.Ptr("status='expired'")}, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), result.Succeeded)
+	require.Len(t, transport.blobs, 11)
+	require.NotContains(t, transport.blobs, "logs/0010")
+}
+
+func TestBulkValidation(t *testing.T) {
+	client := newBulkTestClient(t, newBulkTestTransport(1))
+	_, err := client.BulkDelete(context.Background(), BulkSource{}, nil)
+	require.Error(t, err)
+	_, err = client.BulkDelete(context.Background(), BulkSource{Prefix: to.Ptr(""), BlobNames: []string{"a"}}, nil)
+	require.Error(t, err)
+	_, err = client.BulkDelete(context.Background(), BulkSource{BlobNames: []string{"a"}}, &BulkDeleteOptions{BulkOptions: BulkOptions{BatchSize: 257}})
+	require.Error(t, err)
+}
@@ -0,0 +1,357 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package container
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+// fsDelimiter separates the path elements of blob names exposed through FS.
+const fsDelimiter = "/"
+
+// fsFolderMetadataKey marks the zero-length blobs that hierarchical namespace accounts use to represent directories.
+const fsFolderMetadataKey = "hdi_isfolder"
+
+// FS is a read-only file system view of a container, implementing fs.FS, fs.ReadDirFS and fs.StatFS.
+// Blob names are treated as slash-separated paths and virtual directories are discovered by listing
+// with a "/" delimiter, so fs.WalkDir, fs.Glob, template.ParseFS and similar functions work against a container.
+// Files returned by Open are backed by a blob.Reader and also implement io.ReaderAt and io.Seeker,
+// e.g. for use with archive/zip.NewReader.
+type FS struct {
+	client  *Client
+	ctx     context.Context
+	options FSOptions
+}
+
+// NewFS returns a read-only fs.FS view of the container.
+// The provided context is used for all requests made through the FS and the files it opens.
+func (c *Client) NewFS(ctx context.Context, o *FSOptions) *FS {
+	options := FSOptions{}
+	if o != nil {
+		options = *o
+	}
+	return &FS{client: c, ctx: ctx, options: options}
+}
+
+// Open implements fs.FS.
+func (f *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &fsDir{fs: f, name: name, info: fsDirInfo(name, nil)}, nil
+	}
+
+	reader, err := f.client.NewBlobClient(name).NewReader(f.ctx, f.options.ReaderOptions)
+	if err == nil {
+		props := reader.Properties()
+		if !isFolderBlob(props.Metadata) {
+			return &fsFile{Reader: reader, info: fsFileInfoFromProperties(name, props)}, nil
+		}
+		_ = reader.Close()
+		return &fsDir{fs: f, name: name, info: fsDirInfo(name, props.LastModified)}, nil
+	}
+	if !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if err := f.checkDir("open", name); err != nil {
+		return nil, err
+	}
+	return &fsDir{fs: f, name: name, info: fsDirInfo(name, nil)}, nil
+}
+
+// Stat implements fs.StatFS.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return fsDirInfo(name, nil), nil
+	}
+
+	props, err := f.client.NewBlobClient(name).GetProperties(f.ctx, nil)
+	if err == nil {
+		if isFolderBlob(props.Metadata) {
+			return fsDirInfo(name, props.LastModified), nil
+		}
+		return fsFileInfoFromProperties(name, props), nil
+	}
+	if !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+
+	if err := f.checkDir("stat", name); err != nil {
+		return nil, err
+	}
+	return fsDirInfo(name, nil), nil
+}
+
+// ReadDir implements fs.ReadDirFS. The entries are sorted by name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entries, err := f.list(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	if len(entries) == 0 && name != "." {
+		if _, err := f.Stat(name); err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+		}
+	}
+	return entries, nil
+}
+
+// checkDir reports fs.ErrNotExist unless at least one blob exists under the virtual directory name.
+func (f *FS) checkDir(op, name string) error {
+	pager := f.client.NewListBlobsHierarchyPager(fsDelimiter, &ListBlobsHierarchyOptions{
+		Prefix:     to.Ptr(name + fsDelimiter),
+		MaxResults: to.Ptr(int32(1)),
+	})
+	page, err := pager.NextPage(f.ctx)
+	if err != nil {
+		return &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if page.Segment == nil || (len(page.Segment.BlobItems) == 0 && len(page.Segment.BlobPrefixes) == 0) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return nil
+}
+
+// list returns the sorted entries of the virtual directory name.
+func (f *FS) list(name string) ([]fs.DirEntry, error) {
+	prefix := ""
+	if name != "." {
+		prefix = name + fsDelimiter
+	}
+
+	byName := map[string]fs.DirEntry{}
+	pager := f.client.NewListBlobsHierarchyPager(fsDelimiter, &ListBlobsHierarchyOptions{
+		Include: ListBlobsInclude{Metadata: true},
+		Prefix:  &prefix,
+	})
+	for pager.More() {
+		page, err := pager.NextPage(f.ctx)
+		if err != nil {
+			return nil, err
+		}
+		if page.Segment == nil {
+			continue
+		}
+		for _, p := range page.Segment.BlobPrefixes {
+			if p.Name == nil {
+				continue
+			}
+			base := strings.TrimSuffix(strings.TrimPrefix(*p.Name, prefix), fsDelimiter)
+			if _, ok := byName[base]; ok || base == "" {
+				// a blob with the same name shadows the virtual directory, as in Open
+				continue
+			}
+			byName[base] = fsDirEntry{info: fsDirInfo(base, nil)}
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil {
+				continue
+			}
+			base := strings.TrimPrefix(*item.Name, prefix)
+			if base == "" || strings.Contains(base, fsDelimiter) {
+				continue
+			}
+			if isFolderBlob(item.Metadata) {
+				var modTime *time.Time
+				if item.Properties != nil {
+					modTime = item.Properties.LastModified
+				}
+				byName[base] = fsDirEntry{info: fsDirInfo(base, modTime)}
+			} else {
+				byName[base] = fsDirEntry{info: fsFileInfoFromItem(base, item)}
+			}
+		}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(byName))
+	for _, e := range byName {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func isFolderBlob(metadata map[string]*string) bool {
+	for k, v := range metadata {
+		if strings.EqualFold(k, fsFolderMetadataKey) && v != nil && strings.EqualFold(*v, "true") {
+			return true
+		}
+	}
+	return false
+}
+
+// fsFile is an open blob; it implements fs.File, io.ReaderAt and io.Seeker.
+type fsFile struct {
+	*blob.Reader
+	info fsFileInfo
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// fsDir is an open virtual directory; it implements fs.ReadDirFile.
+type fsDir struct {
+	fs      *FS
+	name    string
+	info    fsFileInfo
+	entries []fs.DirEntry
+	loaded  bool
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *fsDir) Close() error {
+	return nil
+}
+
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.loaded {
+		entries, err := d.fs.list(d.name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: err}
+		}
+		d.entries = entries
+		d.loaded = true
+	}
+
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+// fsFileInfo describes a blob or virtual directory; it implements fs.FileInfo.
+type fsFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+	sys     any
+}
+
+func fsDirInfo(name string, modTime *time.Time) fsFileInfo {
+	info := fsFileInfo{name: path.Base(name), dir: true}
+	if modTime != nil {
+		info.modTime = *modTime
+	}
+	return info
+}
+
+func fsFileInfoFromProperties(name string, props blob.GetPropertiesResponse) fsFileInfo {
+	info := fsFileInfo{name: path.Base(name), sys: props}
+	if props.ContentLength != nil {
+		info.size = *props.ContentLength
+	}
+	if props.LastModified != nil {
+		info.modTime = *props.LastModified
+	}
+	return info
+}
+
+func fsFileInfoFromItem(name string, item *BlobItem) fsFileInfo {
+	info := fsFileInfo{name: path.Base(name), sys: item}
+	if item.Properties != nil {
+		if item.Properties.ContentLength != nil {
+			info.size = *item.Properties.ContentLength
+		}
+		if item.Properties.LastModified != nil {
+			info.modTime = *item.Properties.LastModified
+		}
+	}
+	return info
+}
+
+func (i fsFileInfo) Name() string {
+	return i.name
+}
+
+func (i fsFileInfo) Size() int64 {
+	return i.size
+}
+
+func (i fsFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+func (i fsFileInfo) ModTime() time.Time {
+	return i.modTime
+}
+
+func (i fsFileInfo) IsDir() bool {
+	return i.dir
+}
+
+// Sys returns the blob.GetPropertiesResponse or *BlobItem describing a blob, or nil for a virtual directory.
+func (i fsFileInfo) Sys() any {
+	return i.sys
+}
+
+// fsDirEntry implements fs.DirEntry.
+type fsDirEntry struct {
+	info fsFileInfo
+}
+
+func (e fsDirEntry) Name() string {
+	return e.info.Name()
+}
+
+func (e fsDirEntry) IsDir() bool {
+	return e.info.IsDir()
+}
+
+func (e fsDirEntry) Type() fs.FileMode {
+	return e.info.Mode().Type()
+}
+
+func (e fsDirEntry) Info() (fs.FileInfo, error) {
+	return e.info, nil
+}
+
+var (
+	_ fs.ReadDirFS   = (*FS)(nil)
+	_ fs.StatFS      = (*FS)(nil)
+	_ fs.ReadDirFile = (*fsDir)(nil)
+	_ io.ReaderAt    = (*fsFile)(nil)
+	_ io.Seeker      = (*fsFile)(nil)
+)
@@ -0,0 +1,175 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package container_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/stretchr/testify/require"
+)
+
+const fsTestLastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// fsTestTransport serves Get Blob, Get Blob Properties and List Blobs requests from an in-memory container.
+type fsTestTransport map[string][]byte
+
+func (f fsTestTransport) Do(req *http.Request) (*http.Response, error) {
+	q := req.URL.Query()
+	header := http.Header{}
+	respond := func(status int, body []byte) (*http.Response, error) {
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(bytes.NewReader(body)), Request: req}, nil
+	}
+
+	if q.Get("comp") == "list" {
+		return respond(http.StatusOK, f.list(q.Get("prefix"), q.Get("delimiter")))
+	}
+
+	name := strings.TrimPrefix(req.URL.Path, "/c/")
+	data, ok := f[name]
+	if !ok {
+		header.Set("x-ms-error-code", "BlobNotFound")
+		return respond(http.StatusNotFound, nil)
+	}
+	header.Set("ETag", `"0x1"`)
+	header.Set("Last-Modified", fsTestLastModified)
+	if req.Method == http.MethodHead {
+		resp, err := respond(http.StatusOK, nil)
+		resp.Header.Set("Content-Length", strconv.Itoa(len(data)))
+		return resp, err
+	}
+	status := http.StatusOK
+	if r := req.Header["x-ms-range"]; len(r) > 0 {
+		var start, end int
+		_, _ = fmt.Sscanf(r[0], "bytes=%d-%d", &start, &end)
+		end = min(end, len(data)-1)
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		data = data[start : end+1]
+		status = http.StatusPartialContent
+	}
+	return respond(status, data)
+}
+
+func (f fsTestTransport) list(prefix, delimiter string) []byte {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var blobs strings.Builder
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if i := strings.Index(name[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			p := name[:len(prefix)+i+1]
+			if !seen[p] {
+				seen[p] = true
+				fmt.Fprintf(&blobs, "<BlobPrefix><Name>%s</Name></BlobPrefix>", p)
+			}
+			continue
+		}
+		fmt.Fprintf(&blobs, "<Blob><Name>%s</Name><Properties><Last-Modified>%s</Last-Modified><Etag>0x1</Etag><Content-Length>%d</Content-Length></Properties></Blob>",
+			name, fsTestLastModified, len(f[name]))
+	}
+	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults ServiceEndpoint="https://account.blob.core.windows.net/" ContainerName="c"><Prefix>%s</Prefix><Delimiter>%s</Delimiter><Blobs>%s</Blobs><NextMarker /></EnumerationResults>`,
+		prefix, delimiter, blobs.String()))
+}
+
+func newFSTestClient(t *testing.T, blobs fsTestTransport) *container.Client {
+	client, err := container.NewClientWithNoCredential("https://account.blob.core.windows.net/c", &container.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: blobs, Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestFS(t *testing.T) {
+	blobs := fsTestTransport{
+		"a.txt":             []byte("hello"),
+		"dir/b.txt":         []byte("world"),
+		"dir/sub/c.txt":     bytes.Repeat([]byte("c"), 100),
+		"dir/sub/deep/d.md": []byte("# d"),
+		"empty":             {},
+	}
+	fsys := newFSTestClient(t, blobs).NewFS(context.Background(), &container.FSOptions{
+		ReaderOptions: &blob.ReaderOptions{BlockSize: 7},
+	})
+
+	require.NoError(t, fstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c.txt", "dir/sub/deep/d.md", "empty"))
+
+	data, err := fs.ReadFile(fsys, "dir/sub/c.txt")
+	require.NoError(t, err)
+	require.Equal(t, blobs["dir/sub/c.txt"], data)
+
+	var walked []string
+	require.NoError(t, fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		require.NoError(t, err)
+		walked = append(walked, path)
+		return nil
+	}))
+	require.Equal(t, []string{".", "a.txt", "dir", "dir/b.txt", "dir/sub", "dir/sub/c.txt", "dir/sub/deep", "dir/sub/deep/d.md", "empty"}, walked)
+
+	info, err := fs.Stat(fsys, "dir/sub")
+	require.NoError(t, err)
+	require.True(t, info.IsDir())
+
+	_, err = fsys.Open("missing")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = fsys.Open("/a.txt")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func TestFSZipReaderAt(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i < 3; i++ {
+		w, err := zw.Create(fmt.Sprintf("file%d.txt", i))
+		require.NoError(t, err)
+		_, err = w.Write(bytes.Repeat([]byte{byte('a' + i)}, 1000*(i+1)))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	fsys := newFSTestClient(t, fsTestTransport{"archive.zip": buf.Bytes()}).NewFS(context.Background(), &container.FSOptions{
+		ReaderOptions: &blob.ReaderOptions{BlockSize: 512, ReadAhead: 2},
+	})
+	f, err := fsys.Open("archive.zip")
+	require.NoError(t, err)
+	defer f.Close()
+	info, err := f.Stat()
+	require.NoError(t, err)
+
+	zr, err := zip.NewReader(f.(io.ReaderAt), info.Size())
+	require.NoError(t, err)
+	require.Len(t, zr.File, 3)
+	for i, zf := range zr.File {
+		rc, err := zf.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		require.Equal(t, bytes.Repeat([]byte{byte('a' + i)}, 1000*(i+1)), data)
+	}
+}
@@ -428,3 +428,41 @@ func (o *FilterBlobsOptions) format() *generated.ContainerClientFilterBlobsOptio
 		Maxresults: o.MaxResults,
 	}
 }
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// FSOptions contains the optional parameters for the Client.NewFS method.
+type FSOptions struct {
+	// ReaderOptions configures the blob.Reader backing each file opened through the FS.
+	ReaderOptions *blob.ReaderOptions
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// BulkSource selects the blobs that a bulk operation applies to. Exactly one of the fields must be specified.
+type BulkSource = exported.BulkSource
+
+// BulkOptions contains the optional parameters common to the bulk operations.
+type BulkOptions = exported.BulkOptions
+
+// BulkDeleteOptions contains the optional parameters for the Client.BulkDelete method.
+type BulkDeleteOptions struct {
+	BulkOptions
+
+	// Required if the blobs have snapshots. Specify one of the following two options: include: Delete the base blob
+	// and all of its snapshots. only: Delete only the blob's snapshots and not the blob itself.
+	DeleteSnapshots *blob.DeleteSnapshotsOptionType
+}
+
+// BulkSetTierOptions contains the optional parameters for the Client.BulkSetTier method.
+type BulkSetTierOptions struct {
+	BulkOptions
+
+	// Optional: Indicates the priority with which to rehydrate archived blobs.
+	RehydratePriority *blob.RehydratePriority
+}
+
+// BulkSetTagsOptions contains the optional parameters for the Client.BulkSetTags method.
+type BulkSetTagsOptions struct {
+	BulkOptions
+}
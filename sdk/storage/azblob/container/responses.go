@@ -67,3 +67,9 @@ type BatchResponseItem = exported.BatchResponseItem
 
 // FilterBlobsResponse contains the response from method Client.FilterBlobs.
 type FilterBlobsResponse = generated.ContainerClientFilterBlobsResponse
+
+// BulkResult summarizes the results of the Client.BulkDelete, Client.BulkSetTier and Client.BulkSetTags methods.
+type BulkResult = exported.BulkResult
+
+// BulkItemResult contains the result of a bulk operation on a single blob.
+type BulkItemResult = exported.BulkItemResult
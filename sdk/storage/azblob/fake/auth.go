@@ -0,0 +1,178 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package fake
+
+import (
+	"crypto/hmac"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+)
+
+// signedHeaders are the standard headers in the string to sign of a shared key signature, spelled the way the
+// string to sign is built with.
+var signedHeaders = []string{
+	"Content-Encoding", "Content-Language", "Content-Length", "Content-MD5", "Content-Type",
+	"If-Modified-Since", "If-Match", "If-None-Match", "If-Unmodified-Since", "Range",
+}
+
+// authorize checks that the request is authorized to perform the operation with a shared key signature, a SAS, or
+// anonymously.
+func (s *Server) authorize(req *request, op *operation) *response {
+	if auth := req.header.Get("Authorization"); strings.HasPrefix(auth, "SharedKey ") {
+		return s.authorizeSharedKey(req, strings.TrimPrefix(auth, "SharedKey "))
+	}
+	if req.query.Get("sig") != "" {
+		return s.authorizeSAS(req, op)
+	}
+	if s.anonymous {
+		return nil
+	}
+	if op.public != "" {
+		if c := s.containers[req.container]; c != nil && (c.publicAccess == op.public || c.publicAccess == publicAccessContainer) {
+			return nil
+		}
+		// the service doesn't reveal the existence of private resources to anonymous requests
+		if op.resourceType == 'o' {
+			return errorResponse(http.StatusNotFound, bloberror.ResourceNotFound, "The specified resource does not exist.")
+		}
+	}
+	return errorResponse(http.StatusUnauthorized, bloberror.NoAuthenticationInformation,
+		"Server failed to authenticate the request. Please refer to the information in the www-authenticate header.")
+}
+
+func (s *Server) authorizeSharedKey(req *request, credential string) *response {
+	account, signature, _ := strings.Cut(credential, ":")
+	if account != s.accountName {
+		return authenticationFailed("The account of the signature doesn't match the account of the request.")
+	}
+
+	// the signature covers the headers as they were sent, whose spelling may have been changed since
+	signing := req.raw.Clone(req.raw.Context())
+	signing.Header = http.Header{}
+	for k, v := range req.raw.Header {
+		signing.Header[k] = v
+	}
+	for _, name := range signedHeaders {
+		if v := req.header.Values(name); len(v) > 0 {
+			for k := range signing.Header {
+				if strings.EqualFold(k, name) {
+					delete(signing.Header, k)
+				}
+			}
+			signing.Header[name] = v
+		}
+	}
+	stringToSign, err := exported.BuildStringToSign(s.cred, signing)
+	if err != nil {
+		return authenticationFailed(err.Error())
+	}
+	expected, err := exported.ComputeHMACSHA256(s.cred, stringToSign)
+	if err != nil || !hmac.Equal([]byte(expected), []byte(signature)) {
+		return authenticationFailed("The MAC signature found in the HTTP request is not the same as any computed signature.")
+	}
+	return nil
+}
+
+func (s *Server) authorizeSAS(req *request, op *operation) *response {
+	params := sas.NewQueryParameters(req.query, false)
+	start, expiry, permissions := params.StartTime(), params.ExpiryTime(), params.Permissions()
+	var signed sas.QueryParameters
+	var err error
+	if params.Services() != "" {
+		// account SAS
+		if !strings.Contains(params.Services(), "b") {
+			return errorResponse(http.StatusForbidden, bloberror.AuthorizationServiceMismatch,
+				"This request is not authorized to perform this operation using this service.")
+		}
+		if !strings.ContainsRune(params.ResourceTypes(), rune(op.resourceType)) {
+			return errorResponse(http.StatusForbidden, bloberror.AuthorizationResourceTypeMismatch,
+				"This request is not authorized to perform this operation using this resource type.")
+		}
+		signed, err = sas.AccountSignatureValues{
+			Version:         params.Version(),
+			Protocol:        params.Protocol(),
+			StartTime:       params.StartTime(),
+			ExpiryTime:      params.ExpiryTime(),
+			Permissions:     params.Permissions(),
+			IPRange:         params.IPRange(),
+			ResourceTypes:   params.ResourceTypes(),
+			EncryptionScope: params.EncryptionScope(),
+		}.SignWithSharedKey(s.SharedKeyCredential())
+	} else {
+		// service SAS
+		values := sas.BlobSignatureValues{
+			Version:            params.Version(),
+			Protocol:           params.Protocol(),
+			StartTime:          params.StartTime(),
+			ExpiryTime:         params.ExpiryTime(),
+			SnapshotTime:       params.SnapshotTime(),
+			Permissions:        params.Permissions(),
+			IPRange:            params.IPRange(),
+			Identifier:         params.Identifier(),
+			ContainerName:      req.container,
+			CacheControl:       params.CacheControl(),
+			ContentDisposition: params.ContentDisposition(),
+			ContentEncoding:    params.ContentEncoding(),
+			ContentLanguage:    params.ContentLanguage(),
+			ContentType:        params.ContentType(),
+			EncryptionScope:    params.EncryptionScope(),
+		}
+		switch params.Resource() {
+		case "c":
+			if op.resourceType == 's' {
+				return errorResponse(http.StatusForbidden, bloberror.AuthorizationResourceTypeMismatch,
+					"This request is not authorized to perform this operation using this resource type.")
+			}
+		case "b", "bs":
+			values.BlobName = req.blob
+		default:
+			return authenticationFailed("The signed resource of the SAS isn't supported by the fake Blob service.")
+		}
+		signed, err = values.SignWithSharedKey(s.SharedKeyCredential())
+
+		// the fields that the SAS doesn't specify are specified by its stored access policy
+		if values.Identifier != "" {
+			policy := s.accessPolicy(req.container, values.Identifier)
+			if policy == nil {
+				return authenticationFailed("The stored access policy of the SAS doesn't exist.")
+			}
+			if start.IsZero() && policy.Start != nil {
+				start = *policy.Start
+			}
+			if expiry.IsZero() && policy.Expiry != nil {
+				expiry = *policy.Expiry
+			}
+			if permissions == "" && policy.Permission != nil {
+				permissions = *policy.Permission
+			}
+		}
+	}
+	if err != nil || !hmac.Equal([]byte(signed.Signature()), []byte(req.query.Get("sig"))) {
+		return authenticationFailed("Signature did not match.")
+	}
+
+	if !start.IsZero() && req.now.Before(start) {
+		return authenticationFailed("Signed start time is in the future.")
+	}
+	if expiry.IsZero() || !req.now.Before(expiry) {
+		return authenticationFailed("Signed expiry time must be in the future.")
+	}
+	if !strings.ContainsAny(permissions, op.permissions) {
+		return errorResponse(http.StatusForbidden, bloberror.AuthorizationPermissionMismatch,
+			"This request is not authorized to perform this operation using this permission.")
+	}
+	return nil
+}
+
+func authenticationFailed(detail string) *response {
+	return errorResponse(http.StatusForbidden, bloberror.AuthenticationFailed,
+		"Server failed to authenticate the request. Make sure the value of Authorization header is formed correctly including the signature. "+detail)
+}
@@ -0,0 +1,680 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package fake
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+const (
+	blockBlob  = "BlockBlob"
+	appendBlob = "AppendBlob"
+	pageBlob   = "PageBlob"
+
+	pageSize = 512
+
+	maxTags = 10
+)
+
+// blob is a blob of a container.
+type blob struct {
+	blobType     string
+	data         []byte
+	headers      blobHeaders
+	metadata     map[string]string
+	tags         map[string]string
+	tier         string
+	etag         string
+	created      time.Time
+	lastModified time.Time
+	lease        lease
+
+	// blocks are the committed blocks of a block blob.
+	blocks []block
+
+	// appendBlocks is the number of committed blocks of an append blob.
+	appendBlocks int
+
+	// pages are the indexes of the written pages of a page blob.
+	pages          map[int64]bool
+	sequenceNumber int64
+}
+
+// block is a block of a block blob.
+type block struct {
+	id   string
+	data []byte
+}
+
+// blobHeaders are the standard HTTP properties of a blob.
+type blobHeaders struct {
+	contentType        string
+	contentEncoding    string
+	contentLanguage    string
+	contentDisposition string
+	cacheControl       string
+	contentMD5         []byte
+}
+
+// readBlobHeaders reads the standard HTTP properties of a blob from the x-ms-blob-* headers of a request, and reports
+// whether any of them is specified.
+func readBlobHeaders(req *request) (blobHeaders, bool, *response) {
+	headers := blobHeaders{
+		contentType:        req.header.Get("x-ms-blob-content-type"),
+		contentEncoding:    req.header.Get("x-ms-blob-content-encoding"),
+		contentLanguage:    req.header.Get("x-ms-blob-content-language"),
+		contentDisposition: req.header.Get("x-ms-blob-content-disposition"),
+		cacheControl:       req.header.Get("x-ms-blob-cache-control"),
+	}
+	if v := req.header.Get("x-ms-blob-content-md5"); v != "" {
+		md5, err := base64.StdEncoding.DecodeString(v)
+		if err != nil || len(md5) != 16 {
+			return headers, false, errorResponse(http.StatusBadRequest, bloberror.InvalidMD5, "The MD5 value specified in the request is invalid.")
+		}
+		headers.contentMD5 = md5
+	}
+	specified := headers.contentType != "" || headers.contentEncoding != "" || headers.contentLanguage != "" ||
+		headers.contentDisposition != "" || headers.cacheControl != "" || headers.contentMD5 != nil
+	return headers, specified, nil
+}
+
+func (b *blob) state() resourceState {
+	return resourceState{exists: true, etag: b.etag, lastModified: b.lastModified, tags: b.tags}
+}
+
+// modified updates the ETag and the last modified time of the blob.
+func (b *blob) modified(s *Server, req *request) {
+	b.etag = s.newETag()
+	b.lastModified = req.now.Truncate(time.Second)
+}
+
+func (b *blob) setHeaders(h http.Header) {
+	h.Set("ETag", b.etag)
+	h.Set("Last-Modified", b.lastModified.Format(http.TimeFormat))
+}
+
+// setPropertyHeaders sets the headers of the properties of the blob, except its content length.
+func (b *blob) setPropertyHeaders(h http.Header) {
+	b.setHeaders(h)
+	h.Set("x-ms-creation-time", b.created.Format(http.TimeFormat))
+	h.Set("x-ms-blob-type", b.blobType)
+	h.Set("Content-Type", b.contentType())
+	if b.headers.contentEncoding != "" {
+		h.Set("Content-Encoding", b.headers.contentEncoding)
+	}
+	if b.headers.contentLanguage != "" {
+		h.Set("Content-Language", b.headers.contentLanguage)
+	}
+	if b.headers.contentDisposition != "" {
+		h.Set("Content-Disposition", b.headers.contentDisposition)
+	}
+	if b.headers.cacheControl != "" {
+		h.Set("Cache-Control", b.headers.cacheControl)
+	}
+	if b.headers.contentMD5 != nil {
+		h.Set("Content-MD5", base64.StdEncoding.EncodeToString(b.headers.contentMD5))
+	}
+	setMetadataHeaders(h, b.metadata)
+	b.lease.setHeaders(h)
+	if len(b.tags) > 0 {
+		h.Set("x-ms-tag-count", strconv.Itoa(len(b.tags)))
+	}
+	switch b.blobType {
+	case appendBlob:
+		h.Set("x-ms-blob-committed-block-count", strconv.Itoa(b.appendBlocks))
+	case pageBlob:
+		h.Set("x-ms-blob-sequence-number", strconv.FormatInt(b.sequenceNumber, 10))
+	}
+	h.Set("x-ms-server-encrypted", "true")
+	h.Set("Accept-Ranges", "bytes")
+}
+
+func (b *blob) contentType() string {
+	if b.headers.contentType == "" {
+		return "application/octet-stream"
+	}
+	return b.headers.contentType
+}
+
+// properties returns the properties of the blob in a listing.
+func (b *blob) properties() blobProperties {
+	props := blobProperties{
+		CreationTime:       b.created.Format(http.TimeFormat),
+		LastModified:       b.lastModified.Format(http.TimeFormat),
+		ETag:               strings.Trim(b.etag, `"`),
+		ContentLength:      int64(len(b.data)),
+		ContentType:        b.contentType(),
+		ContentEncoding:    b.headers.contentEncoding,
+		ContentLanguage:    b.headers.contentLanguage,
+		ContentDisposition: b.headers.contentDisposition,
+		CacheControl:       b.headers.cacheControl,
+		BlobType:           b.blobType,
+		LeaseStatus:        b.lease.status(),
+		LeaseState:         b.lease.state,
+		LeaseDuration:      b.lease.durationType(),
+		ServerEncrypted:    true,
+		TagCount:           len(b.tags),
+	}
+	if b.headers.contentMD5 != nil {
+		props.ContentMD5 = base64.StdEncoding.EncodeToString(b.headers.contentMD5)
+	}
+	if b.blobType == pageBlob {
+		props.SequenceNumber = &b.sequenceNumber
+	}
+	if b.blobType == blockBlob {
+		props.AccessTier = b.accessTier()
+		inferred := b.tier == ""
+		props.AccessTierInferred = &inferred
+	}
+	return props
+}
+
+func (b *blob) accessTier() string {
+	if b.tier == "" {
+		return "Hot"
+	}
+	return b.tier
+}
+
+// getBlob returns the blob of the request and its container, or a response if either doesn't exist.
+func (s *Server) getBlob(req *request) (*container, *blob, *response) {
+	c, resp := s.getContainer(req)
+	if resp != nil {
+		return nil, nil, resp
+	}
+	b := c.blobs[req.blob]
+	if b == nil {
+		return c, nil, errorResponse(http.StatusNotFound, bloberror.BlobNotFound, "The specified blob does not exist.")
+	}
+	b.lease.update(req.now)
+	return c, b, nil
+}
+
+// prepareWrite checks the conditions and the lease of a request that creates or overwrites a blob, which may not
+// exist yet.
+func prepareWrite(req *request, existing *blob) *response {
+	if existing == nil {
+		if resp := checkConditions(req, resourceState{}, false); resp != nil {
+			return resp
+		}
+		return (&lease{}).check(req, true, blobLeaseErrors)
+	}
+	if req.header.Get("If-None-Match") == "*" {
+		return errorResponse(http.StatusConflict, bloberror.BlobAlreadyExists, "The specified blob already exists.")
+	}
+	if resp := checkConditions(req, existing.state(), false); resp != nil {
+		return resp
+	}
+	return existing.lease.check(req, true, blobLeaseErrors)
+}
+
+// prepareUpdate checks the conditions and the lease of a request that updates a blob.
+func prepareUpdate(req *request, b *blob) *response {
+	if resp := checkConditions(req, b.state(), false); resp != nil {
+		return resp
+	}
+	return b.lease.check(req, true, blobLeaseErrors)
+}
+
+// validateContentMD5 validates the transactional MD5 of the body of a request.
+func validateContentMD5(req *request) *response {
+	v := req.header.Get("Content-MD5")
+	if v == "" {
+		return nil
+	}
+	expected, err := base64.StdEncoding.DecodeString(v)
+	if err != nil || len(expected) != md5.Size {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidMD5, "The MD5 value specified in the request is invalid.")
+	}
+	if actual := md5.Sum(req.body); !bytes.Equal(actual[:], expected) {
+		return errorResponse(http.StatusBadRequest, bloberror.MD5Mismatch,
+			"The MD5 value specified in the request did not match with the MD5 value calculated by the server.")
+	}
+	return nil
+}
+
+func setContentMD5(h http.Header, data []byte) {
+	sum := md5.Sum(data)
+	h.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+}
+
+// newBlob creates a blob from the headers of a request that creates or overwrites it.
+func (s *Server) newBlob(req *request, blobType string, existing *blob) (*blob, *response) {
+	headers, _, resp := readBlobHeaders(req)
+	if resp != nil {
+		return nil, resp
+	}
+	tags, resp := parseTagsHeader(req)
+	if resp != nil {
+		return nil, resp
+	}
+	tier := req.header.Get("x-ms-access-tier")
+	if tier != "" {
+		if blobType != blockBlob || !validTier(tier) {
+			return nil, errorResponse(http.StatusBadRequest, bloberror.InvalidBlobTier, "The specified blob tier is invalid.")
+		}
+	}
+	b := &blob{
+		blobType: blobType,
+		headers:  headers,
+		metadata: req.metadata,
+		tags:     tags,
+		tier:     tier,
+		created:  req.now.Truncate(time.Second),
+	}
+	if existing != nil {
+		// the lease of a blob is kept when it's overwritten
+		b.lease = existing.lease
+		b.created = existing.created
+	}
+	b.modified(s, req)
+	return b, nil
+}
+
+// putBlob handles https://learn.microsoft.com/rest/api/storageservices/put-blob.
+func (s *Server) putBlob(req *request) *response {
+	c, resp := s.getContainer(req)
+	if resp != nil {
+		return resp
+	}
+	existing := c.blobs[req.blob]
+	if existing != nil {
+		existing.lease.update(req.now)
+	}
+	if resp := prepareWrite(req, existing); resp != nil {
+		return resp
+	}
+	blobType := req.header.Get("x-ms-blob-type")
+	if blobType != blockBlob && len(req.body) > 0 {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue, "The request body must be empty for append and page blobs.")
+	}
+	if resp := validateContentMD5(req); resp != nil {
+		return resp
+	}
+	b, resp := s.newBlob(req, blobType, existing)
+	if resp != nil {
+		return resp
+	}
+
+	resp = newResponse(http.StatusCreated)
+	switch blobType {
+	case blockBlob:
+		b.data = req.body
+		if b.headers.contentMD5 == nil {
+			sum := md5.Sum(req.body)
+			b.headers.contentMD5 = sum[:]
+		}
+		setContentMD5(resp.header, req.body)
+		delete(c.uncommitted, req.blob)
+	case appendBlob:
+		b.data = []byte{}
+	case pageBlob:
+		size, err := strconv.ParseInt(req.header.Get("x-ms-blob-content-length"), 10, 64)
+		if err != nil || size < 0 || size%pageSize != 0 {
+			return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue,
+				"The size of a page blob must be a multiple of 512 bytes.")
+		}
+		if v := req.header.Get("x-ms-blob-sequence-number"); v != "" {
+			if b.sequenceNumber, err = strconv.ParseInt(v, 10, 64); err != nil || b.sequenceNumber < 0 {
+				return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue, "The sequence number is invalid.")
+			}
+		}
+		b.data = make([]byte, size)
+		b.pages = map[int64]bool{}
+	default:
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue, "The value of the x-ms-blob-type header is invalid.")
+	}
+	c.blobs[req.blob] = b
+	b.setHeaders(resp.header)
+	resp.header.Set("x-ms-request-server-encrypted", "true")
+	return resp
+}
+
+// getBlobProperties handles https://learn.microsoft.com/rest/api/storageservices/get-blob-properties.
+func (s *Server) getBlobProperties(req *request) *response {
+	_, b, resp := s.getBlob(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := checkConditions(req, b.state(), true); resp != nil {
+		return resp
+	}
+	if resp := b.lease.check(req, false, blobLeaseErrors); resp != nil {
+		return resp
+	}
+	resp = newResponse(http.StatusOK)
+	b.setPropertyHeaders(resp.header)
+	resp.header.Set("Content-Length", strconv.Itoa(len(b.data)))
+	if b.blobType == blockBlob {
+		resp.header.Set("x-ms-access-tier", b.accessTier())
+		if b.tier == "" {
+			resp.header.Set("x-ms-access-tier-inferred", "true")
+		}
+	}
+	return resp
+}
+
+// getBlobContent handles https://learn.microsoft.com/rest/api/storageservices/get-blob.
+func (s *Server) getBlobContent(req *request) *response {
+	_, b, resp := s.getBlob(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := checkConditions(req, b.state(), true); resp != nil {
+		return resp
+	}
+	if resp := b.lease.check(req, false, blobLeaseErrors); resp != nil {
+		return resp
+	}
+	if b.tier == "Archive" {
+		return errorResponse(http.StatusConflict, bloberror.BlobArchived, "This operation is not permitted on an archived blob.")
+	}
+	start, end, ranged, resp := parseRange(req, int64(len(b.data)))
+	if resp != nil {
+		return resp
+	}
+
+	resp = newResponse(http.StatusOK)
+	b.setPropertyHeaders(resp.header)
+	if ranged {
+		resp.status = http.StatusPartialContent
+		resp.header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(b.data)))
+		resp.header.Del("Content-MD5")
+		if b.headers.contentMD5 != nil {
+			resp.header.Set("x-ms-blob-content-md5", base64.StdEncoding.EncodeToString(b.headers.contentMD5))
+		}
+		resp.body = b.data[start : end+1]
+		if req.header.Get("x-ms-range-get-content-md5") == "true" {
+			setContentMD5(resp.header, resp.body)
+		}
+	} else {
+		resp.body = b.data
+	}
+	resp.header.Set("Content-Length", strconv.Itoa(len(resp.body)))
+	return resp
+}
+
+// parseRange parses the range of a request, which can be specified with the x-ms-range or the Range header, and
+// returns its inclusive bounds within a blob of the given size.
+func parseRange(req *request, size int64) (int64, int64, bool, *response) {
+	v := req.header.Get("x-ms-range")
+	if v == "" {
+		v = req.header.Get("Range")
+	}
+	if v == "" {
+		return 0, size - 1, false, nil
+	}
+	invalid := errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue, "The range specified is invalid.")
+	bounds, ok := strings.CutPrefix(v, "bytes=")
+	if !ok {
+		return 0, 0, false, invalid
+	}
+	first, last, ok := strings.Cut(bounds, "-")
+	start, err := strconv.ParseInt(first, 10, 64)
+	if !ok || err != nil || start < 0 {
+		return 0, 0, false, invalid
+	}
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, 0, false, invalid
+		}
+		end = min(end, size-1)
+	}
+	if start >= size {
+		return 0, 0, false, errorResponse(http.StatusRequestedRangeNotSatisfiable, bloberror.InvalidRange,
+			"The range specified is invalid for the current size of the resource.")
+	}
+	return start, end, true, nil
+}
+
+// deleteBlob handles https://learn.microsoft.com/rest/api/storageservices/delete-blob.
+func (s *Server) deleteBlob(req *request) *response {
+	c, b, resp := s.getBlob(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := prepareUpdate(req, b); resp != nil {
+		return resp
+	}
+	delete(c.blobs, req.blob)
+	delete(c.uncommitted, req.blob)
+	return newResponse(http.StatusAccepted)
+}
+
+// setBlobMetadata handles https://learn.microsoft.com/rest/api/storageservices/set-blob-metadata.
+func (s *Server) setBlobMetadata(req *request) *response {
+	_, b, resp := s.getBlob(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := prepareUpdate(req, b); resp != nil {
+		return resp
+	}
+	b.metadata = req.metadata
+	b.modified(s, req)
+	resp = newResponse(http.StatusOK)
+	b.setHeaders(resp.header)
+	resp.header.Set("x-ms-request-server-encrypted", "true")
+	return resp
+}
+
+// setBlobProperties handles https://learn.microsoft.com/rest/api/storageservices/set-blob-properties.
+func (s *Server) setBlobProperties(req *request) *response {
+	_, b, resp := s.getBlob(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := prepareUpdate(req, b); resp != nil {
+		return resp
+	}
+	headers, specified, resp := readBlobHeaders(req)
+	if resp != nil {
+		return resp
+	}
+
+	// the size and the sequence number are only updated if they're specified
+	size, resize := int64(len(b.data)), req.header.Get("x-ms-blob-content-length") != ""
+	if resize {
+		var err error
+		size, err = strconv.ParseInt(req.header.Get("x-ms-blob-content-length"), 10, 64)
+		if b.blobType != pageBlob || err != nil || size < 0 || size%pageSize != 0 {
+			return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue,
+				"The size of a page blob must be a multiple of 512 bytes.")
+		}
+	}
+	sequenceNumber := b.sequenceNumber
+	if action := req.header.Get("x-ms-sequence-number-action"); action != "" {
+		if b.blobType != pageBlob {
+			return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue, "Only page blobs have sequence numbers.")
+		}
+		n, err := strconv.ParseInt(req.header.Get("x-ms-blob-sequence-number"), 10, 64)
+		if action != "increment" && (err != nil || n < 0) {
+			return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue, "The sequence number is invalid.")
+		}
+		switch action {
+		case "max":
+			sequenceNumber = max(sequenceNumber, n)
+		case "update":
+			sequenceNumber = n
+		case "increment":
+			sequenceNumber++
+		default:
+			return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue, "The sequence number action is invalid.")
+		}
+	}
+
+	if specified {
+		b.headers = headers
+	}
+	if resize {
+		data := make([]byte, size)
+		copy(data, b.data)
+		b.data = data
+		for page := range b.pages {
+			if page*pageSize >= size {
+				delete(b.pages, page)
+			}
+		}
+	}
+	b.sequenceNumber = sequenceNumber
+	b.modified(s, req)
+	resp = newResponse(http.StatusOK)
+	b.setHeaders(resp.header)
+	if b.blobType == pageBlob {
+		resp.header.Set("x-ms-blob-sequence-number", strconv.FormatInt(b.sequenceNumber, 10))
+	}
+	return resp
+}
+
+// blobTags is the XML representation of the tags of a blob.
+type blobTags struct {
+	XMLName xml.Name `xml:"Tags"`
+	Tags    []tag    `xml:"TagSet>Tag"`
+}
+
+type tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+func newBlobTags(tags map[string]string) *blobTags {
+	t := &blobTags{Tags: []tag{}}
+	for k, v := range tags {
+		t.Tags = append(t.Tags, tag{Key: k, Value: v})
+	}
+	sort.Slice(t.Tags, func(i, j int) bool { return t.Tags[i].Key < t.Tags[j].Key })
+	return t
+}
+
+// parseTagsHeader parses the tags of the x-ms-tags header, which are URL query encoded.
+func parseTagsHeader(req *request) (map[string]string, *response) {
+	v := req.header.Get("x-ms-tags")
+	if v == "" {
+		return nil, nil
+	}
+	query, err := url.ParseQuery(v)
+	if err != nil {
+		return nil, errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue, "The tags are not URL query encoded.")
+	}
+	tags := map[string]string{}
+	for k, values := range query {
+		tags[k] = values[0]
+	}
+	return tags, validateTags(tags)
+}
+
+func validateTags(tags map[string]string) *response {
+	if len(tags) > maxTags {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidInput, "A blob can have up to 10 tags.")
+	}
+	for k, v := range tags {
+		if len(k) == 0 || len(k) > 128 || len(v) > 256 {
+			return errorResponse(http.StatusBadRequest, bloberror.InvalidInput,
+				"Tag keys must have between 1 and 128 characters and tag values up to 256 characters.")
+		}
+	}
+	return nil
+}
+
+// getBlobTags handles https://learn.microsoft.com/rest/api/storageservices/get-blob-tags.
+func (s *Server) getBlobTags(req *request) *response {
+	_, b, resp := s.getBlob(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := checkConditions(req, resourceState{exists: true, tags: b.tags}, false); resp != nil {
+		return resp
+	}
+	if resp := b.lease.check(req, false, blobLeaseErrors); resp != nil {
+		return resp
+	}
+	return xmlResponse(http.StatusOK, newBlobTags(b.tags))
+}
+
+// setBlobTags handles https://learn.microsoft.com/rest/api/storageservices/set-blob-tags.
+func (s *Server) setBlobTags(req *request) *response {
+	_, b, resp := s.getBlob(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := checkConditions(req, resourceState{exists: true, tags: b.tags}, false); resp != nil {
+		return resp
+	}
+	if resp := b.lease.check(req, false, blobLeaseErrors); resp != nil {
+		return resp
+	}
+	var body blobTags
+	if err := xml.Unmarshal(req.body, &body); err != nil {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidXMLDocument, "XML specified is not syntactically valid.")
+	}
+	tags := map[string]string{}
+	for _, t := range body.Tags {
+		tags[t.Key] = t.Value
+	}
+	if resp := validateTags(tags); resp != nil {
+		return resp
+	}
+	// setting tags doesn't modify the blob
+	b.tags = tags
+	return newResponse(http.StatusNoContent)
+}
+
+func validTier(tier string) bool {
+	return tier == "Hot" || tier == "Cool" || tier == "Cold" || tier == "Archive"
+}
+
+// setBlobTier handles https://learn.microsoft.com/rest/api/storageservices/set-blob-tier. Archived blobs are
+// rehydrated immediately.
+func (s *Server) setBlobTier(req *request) *response {
+	_, b, resp := s.getBlob(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := checkConditions(req, resourceState{exists: true, tags: b.tags}, false); resp != nil {
+		return resp
+	}
+	if resp := b.lease.check(req, false, blobLeaseErrors); resp != nil {
+		return resp
+	}
+	tier := req.header.Get("x-ms-access-tier")
+	if b.blobType != blockBlob || !validTier(tier) {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidBlobTier, "The specified blob tier is invalid.")
+	}
+	status := http.StatusOK
+	if b.tier == "Archive" && tier != "Archive" {
+		status = http.StatusAccepted
+	}
+	b.tier = tier
+	return newResponse(status)
+}
+
+// blobLease handles https://learn.microsoft.com/rest/api/storageservices/lease-blob.
+func (s *Server) blobLease(req *request) *response {
+	_, b, resp := s.getBlob(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := checkConditions(req, b.state(), false); resp != nil {
+		return resp
+	}
+	resp = b.lease.handle(req)
+	b.setHeaders(resp.header)
+	return resp
+}
@@ -0,0 +1,396 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package fake
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/generated"
+)
+
+const (
+	maxBlockIDSize     = 64
+	maxCommittedBlocks = 50000
+)
+
+// stageBlock handles https://learn.microsoft.com/rest/api/storageservices/put-block.
+func (s *Server) stageBlock(req *request) *response {
+	c, resp := s.getContainer(req)
+	if resp != nil {
+		return resp
+	}
+	id := req.query.Get("blockid")
+	if decoded, err := base64.StdEncoding.DecodeString(id); err != nil || len(decoded) == 0 || len(decoded) > maxBlockIDSize {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidQueryParameterValue,
+			"Value for one of the query parameters specified in the request URI is invalid.")
+	}
+	if b := c.blobs[req.blob]; b != nil {
+		b.lease.update(req.now)
+		if b.blobType != blockBlob {
+			return errorResponse(http.StatusConflict, bloberror.InvalidBlobType, "The blob type is invalid for this operation.")
+		}
+		if resp := b.lease.check(req, true, blobLeaseErrors); resp != nil {
+			return resp
+		}
+	}
+	if resp := validateContentMD5(req); resp != nil {
+		return resp
+	}
+
+	// staging a block again replaces it
+	staged := block{id: id, data: req.body}
+	blocks := c.uncommitted[req.blob]
+	replaced := false
+	for i := range blocks {
+		if blocks[i].id == id {
+			blocks[i], replaced = staged, true
+		}
+	}
+	if !replaced {
+		c.uncommitted[req.blob] = append(blocks, staged)
+	}
+	resp = newResponse(http.StatusCreated)
+	setContentMD5(resp.header, req.body)
+	resp.header.Set("x-ms-request-server-encrypted", "true")
+	return resp
+}
+
+// blockLookup is an entry of the block list of a Put Block List request.
+type blockLookup struct {
+	list string
+	id   string
+}
+
+// parseBlockLookupList parses the block list of a Put Block List request, preserving the order of the entries of
+// different lists.
+func parseBlockLookupList(body []byte) ([]blockLookup, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	var lookups []blockLookup
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return lookups, nil
+		} else if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local == "BlockList" {
+			continue
+		}
+		switch start.Name.Local {
+		case "Committed", "Uncommitted", "Latest":
+		default:
+			return nil, errors.New("unexpected element " + start.Name.Local)
+		}
+		var id string
+		if err := dec.DecodeElement(&id, &start); err != nil {
+			return nil, err
+		}
+		lookups = append(lookups, blockLookup{list: start.Name.Local, id: id})
+	}
+}
+
+// commitBlockList handles https://learn.microsoft.com/rest/api/storageservices/put-block-list.
+func (s *Server) commitBlockList(req *request) *response {
+	c, resp := s.getContainer(req)
+	if resp != nil {
+		return resp
+	}
+	existing := c.blobs[req.blob]
+	if existing != nil {
+		existing.lease.update(req.now)
+		if existing.blobType != blockBlob {
+			return errorResponse(http.StatusConflict, bloberror.InvalidBlobType, "The blob type is invalid for this operation.")
+		}
+	}
+	if resp := prepareWrite(req, existing); resp != nil {
+		return resp
+	}
+	lookups, err := parseBlockLookupList(req.body)
+	if err != nil {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidXMLDocument, "XML specified is not syntactically valid.")
+	}
+	if len(lookups) > maxCommittedBlocks {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidBlockList, "The block list may not contain more than 50,000 blocks.")
+	}
+
+	committed, uncommitted := map[string]block{}, map[string]block{}
+	if existing != nil {
+		for _, b := range existing.blocks {
+			committed[b.id] = b
+		}
+	}
+	for _, b := range c.uncommitted[req.blob] {
+		uncommitted[b.id] = b
+	}
+	blocks := make([]block, 0, len(lookups))
+	var data []byte
+	for _, lookup := range lookups {
+		var b block
+		var ok bool
+		switch lookup.list {
+		case "Committed":
+			b, ok = committed[lookup.id]
+		case "Uncommitted":
+			b, ok = uncommitted[lookup.id]
+		default:
+			if b, ok = uncommitted[lookup.id]; !ok {
+				b, ok = committed[lookup.id]
+			}
+		}
+		if !ok {
+			return errorResponse(http.StatusBadRequest, bloberror.InvalidBlockList, "The specified block list is invalid.")
+		}
+		blocks = append(blocks, b)
+		data = append(data, b.data...)
+	}
+
+	b, resp := s.newBlob(req, blockBlob, existing)
+	if resp != nil {
+		return resp
+	}
+	b.blocks, b.data = blocks, data
+	if b.data == nil {
+		b.data = []byte{}
+	}
+	c.blobs[req.blob] = b
+	delete(c.uncommitted, req.blob)
+	resp = newResponse(http.StatusCreated)
+	b.setHeaders(resp.header)
+	resp.header.Set("x-ms-request-server-encrypted", "true")
+	return resp
+}
+
+// getBlockList handles https://learn.microsoft.com/rest/api/storageservices/get-block-list.
+func (s *Server) getBlockList(req *request) *response {
+	c, resp := s.getContainer(req)
+	if resp != nil {
+		return resp
+	}
+	listType := req.query.Get("blocklisttype")
+	if listType == "" {
+		listType = "committed"
+	}
+	if listType != "committed" && listType != "uncommitted" && listType != "all" {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidQueryParameterValue,
+			"Value for one of the query parameters specified in the request URI is invalid.")
+	}
+	b := c.blobs[req.blob]
+	staged := c.uncommitted[req.blob]
+	if b == nil && len(staged) == 0 {
+		return errorResponse(http.StatusNotFound, bloberror.BlobNotFound, "The specified blob does not exist.")
+	}
+
+	list := generated.BlockList{}
+	if listType != "uncommitted" {
+		list.CommittedBlocks = []*generated.Block{}
+		if b != nil {
+			b.lease.update(req.now)
+			if resp := b.lease.check(req, false, blobLeaseErrors); resp != nil {
+				return resp
+			}
+			for _, committed := range b.blocks {
+				list.CommittedBlocks = append(list.CommittedBlocks, &generated.Block{
+					Name: to.Ptr(committed.id),
+					Size: to.Ptr(int64(len(committed.data))),
+				})
+			}
+		}
+	}
+	if listType != "committed" {
+		list.UncommittedBlocks = []*generated.Block{}
+		for _, uncommitted := range staged {
+			list.UncommittedBlocks = append(list.UncommittedBlocks, &generated.Block{
+				Name: to.Ptr(uncommitted.id),
+				Size: to.Ptr(int64(len(uncommitted.data))),
+			})
+		}
+	}
+	resp = xmlResponse(http.StatusOK, list)
+	if b != nil {
+		b.setHeaders(resp.header)
+		resp.header.Set("x-ms-blob-content-length", strconv.Itoa(len(b.data)))
+	}
+	return resp
+}
+
+// appendBlock handles https://learn.microsoft.com/rest/api/storageservices/append-block.
+func (s *Server) appendBlock(req *request) *response {
+	_, b, resp := s.getBlob(req)
+	if resp != nil {
+		return resp
+	}
+	if b.blobType != appendBlob {
+		return errorResponse(http.StatusConflict, bloberror.InvalidBlobType, "The blob type is invalid for this operation.")
+	}
+	if resp := prepareUpdate(req, b); resp != nil {
+		return resp
+	}
+	offset := int64(len(b.data))
+	if v := req.header.Get("x-ms-blob-condition-maxsize"); v != "" {
+		if maxSize, err := strconv.ParseInt(v, 10, 64); err == nil && offset+int64(len(req.body)) > maxSize {
+			return errorResponse(http.StatusPreconditionFailed, bloberror.MaxBlobSizeConditionNotMet,
+				"The max blob size condition specified was not met.")
+		}
+	}
+	if v := req.header.Get("x-ms-blob-condition-appendpos"); v != "" {
+		if position, err := strconv.ParseInt(v, 10, 64); err == nil && position != offset {
+			return errorResponse(http.StatusPreconditionFailed, bloberror.AppendPositionConditionNotMet,
+				"The append position condition specified was not met.")
+		}
+	}
+	if b.appendBlocks == maxCommittedBlocks {
+		return errorResponse(http.StatusConflict, bloberror.BlockCountExceedsLimit,
+			"The committed block count cannot exceed the maximum limit of 50,000 blocks.")
+	}
+	if resp := validateContentMD5(req); resp != nil {
+		return resp
+	}
+
+	b.data = append(b.data, req.body...)
+	b.appendBlocks++
+	b.modified(s, req)
+	resp = newResponse(http.StatusCreated)
+	b.setHeaders(resp.header)
+	setContentMD5(resp.header, req.body)
+	resp.header.Set("x-ms-blob-append-offset", strconv.FormatInt(offset, 10))
+	resp.header.Set("x-ms-blob-committed-block-count", strconv.Itoa(b.appendBlocks))
+	resp.header.Set("x-ms-request-server-encrypted", "true")
+	return resp
+}
+
+// checkSequenceNumber evaluates the sequence number conditions of a request to write pages.
+func checkSequenceNumber(req *request, sequenceNumber int64) *response {
+	for _, condition := range []struct {
+		header string
+		met    func(int64) bool
+	}{
+		{"x-ms-if-sequence-number-le", func(n int64) bool { return sequenceNumber <= n }},
+		{"x-ms-if-sequence-number-lt", func(n int64) bool { return sequenceNumber < n }},
+		{"x-ms-if-sequence-number-eq", func(n int64) bool { return sequenceNumber == n }},
+	} {
+		v := req.header.Get(condition.header)
+		if v == "" {
+			continue
+		}
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && !condition.met(n) {
+			return errorResponse(http.StatusPreconditionFailed, bloberror.SequenceNumberConditionNotMet,
+				"The sequence number condition specified was not met.")
+		}
+	}
+	return nil
+}
+
+// putPages handles https://learn.microsoft.com/rest/api/storageservices/put-page.
+func (s *Server) putPages(req *request) *response {
+	_, b, resp := s.getBlob(req)
+	if resp != nil {
+		return resp
+	}
+	if b.blobType != pageBlob {
+		return errorResponse(http.StatusConflict, bloberror.InvalidBlobType, "The blob type is invalid for this operation.")
+	}
+	if resp := prepareUpdate(req, b); resp != nil {
+		return resp
+	}
+	if resp := checkSequenceNumber(req, b.sequenceNumber); resp != nil {
+		return resp
+	}
+	size := int64(len(b.data))
+	start, end, ranged, resp := parseRange(req, size)
+	switch {
+	case resp != nil && resp.status != http.StatusRequestedRangeNotSatisfiable:
+		return resp
+	case resp != nil || !ranged || start%pageSize != 0 || (end+1)%pageSize != 0 || end >= size:
+		return errorResponse(http.StatusRequestedRangeNotSatisfiable, bloberror.InvalidPageRange,
+			"The page range specified is invalid.")
+	}
+
+	resp = newResponse(http.StatusCreated)
+	switch req.header.Get("x-ms-page-write") {
+	case "update":
+		if int64(len(req.body)) != end-start+1 {
+			return errorResponse(http.StatusBadRequest, bloberror.InvalidInput,
+				"The length of the body doesn't match the length of the page range.")
+		}
+		if resp := validateContentMD5(req); resp != nil {
+			return resp
+		}
+		copy(b.data[start:], req.body)
+		for page := start / pageSize; page <= end/pageSize; page++ {
+			b.pages[page] = true
+		}
+		setContentMD5(resp.header, req.body)
+	case "clear":
+		clear(b.data[start : end+1])
+		for page := start / pageSize; page <= end/pageSize; page++ {
+			delete(b.pages, page)
+		}
+	default:
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue,
+			"The value of the x-ms-page-write header is invalid.")
+	}
+	b.modified(s, req)
+	b.setHeaders(resp.header)
+	resp.header.Set("x-ms-blob-sequence-number", strconv.FormatInt(b.sequenceNumber, 10))
+	resp.header.Set("x-ms-request-server-encrypted", "true")
+	return resp
+}
+
+// getPageRanges handles https://learn.microsoft.com/rest/api/storageservices/get-page-ranges.
+func (s *Server) getPageRanges(req *request) *response {
+	_, b, resp := s.getBlob(req)
+	if resp != nil {
+		return resp
+	}
+	if b.blobType != pageBlob {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidBlobType, "The blob type is invalid for this operation.")
+	}
+	if resp := checkConditions(req, b.state(), true); resp != nil {
+		return resp
+	}
+	if resp := b.lease.check(req, false, blobLeaseErrors); resp != nil {
+		return resp
+	}
+	size := int64(len(b.data))
+	first, last := int64(0), size/pageSize-1
+	if size > 0 {
+		start, end, _, resp := parseRange(req, size)
+		if resp != nil {
+			return resp
+		}
+		first, last = start/pageSize, end/pageSize
+	}
+
+	// consecutive written pages are merged into a range
+	list := generated.PageList{PageRange: []*generated.PageRange{}}
+	for page := first; page <= last; page++ {
+		if !b.pages[page] {
+			continue
+		}
+		if n := len(list.PageRange); n > 0 && *list.PageRange[n-1].End == page*pageSize-1 {
+			list.PageRange[n-1].End = to.Ptr((page+1)*pageSize - 1)
+			continue
+		}
+		list.PageRange = append(list.PageRange, &generated.PageRange{
+			Start: to.Ptr(page * pageSize),
+			End:   to.Ptr((page+1)*pageSize - 1),
+		})
+	}
+	resp = xmlResponse(http.StatusOK, list)
+	b.setHeaders(resp.header)
+	resp.header.Set("x-ms-blob-content-length", strconv.FormatInt(size, 10))
+	return resp
+}
@@ -0,0 +1,191 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package fake
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+// resourceState is the state of a container or a blob that conditional headers are evaluated against.
+type resourceState struct {
+	exists       bool
+	etag         string
+	lastModified time.Time
+	tags         map[string]string
+}
+
+// checkConditions evaluates the conditional headers of a request. Read requests whose conditions aren't met fail with
+// status code 304 (Not Modified) where the service does so, and write requests fail with status code 412
+// (Precondition Failed).
+func checkConditions(req *request, state resourceState, read bool) *response {
+	notMet := func(notModified bool) *response {
+		if read && notModified {
+			return newResponse(http.StatusNotModified)
+		}
+		return errorResponse(http.StatusPreconditionFailed, bloberror.ConditionNotMet,
+			"The condition specified using HTTP conditional header(s) is not met.")
+	}
+	if v := req.header.Get("If-Match"); v != "" && (!state.exists || (v != "*" && !etagMatches(v, state.etag))) {
+		return notMet(false)
+	}
+	if v := req.header.Get("If-None-Match"); v != "" && state.exists && (v == "*" || etagMatches(v, state.etag)) {
+		return notMet(true)
+	}
+	if v := req.header.Get("If-Modified-Since"); v != "" && state.exists {
+		if t, err := http.ParseTime(v); err == nil && !state.lastModified.After(t) {
+			return notMet(true)
+		}
+	}
+	if v := req.header.Get("If-Unmodified-Since"); v != "" && state.exists {
+		if t, err := http.ParseTime(v); err == nil && state.lastModified.After(t) {
+			return notMet(false)
+		}
+	}
+	if v := req.header.Get("x-ms-if-tags"); v != "" {
+		met, err := evaluateTagConditions(v, state.tags)
+		if err != nil {
+			return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue,
+				"The value for one of the HTTP headers is not in the correct format. "+err.Error())
+		}
+		if !met {
+			return errorResponse(http.StatusPreconditionFailed, bloberror.ConditionNotMet,
+				"The condition specified using HTTP conditional header(s) is not met.")
+		}
+	}
+	return nil
+}
+
+// etagMatches reports whether a comma-separated list of ETags contains an ETag, with or without quotes.
+func etagMatches(list string, etag string) bool {
+	for _, v := range strings.Split(list, ",") {
+		if strings.Trim(strings.TrimSpace(v), `"`) == strings.Trim(etag, `"`) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateTagConditions evaluates a SQL-like tag expression such as "tag1" = 'a' AND ("tag2" > 'b' OR "tag3" <> 'c').
+// AND takes precedence over OR, and missing tags compare as empty strings.
+// See https://learn.microsoft.com/rest/api/storageservices/specifying-conditional-headers-for-blob-service-operations.
+func evaluateTagConditions(expression string, tags map[string]string) (bool, error) {
+	p := tagParser{input: expression, tags: tags}
+	met, err := p.or()
+	if err != nil {
+		return false, err
+	}
+	if p.skipSpace(); p.pos != len(p.input) {
+		return false, errors.New("unexpected characters at the end of the tag conditions")
+	}
+	return met, nil
+}
+
+type tagParser struct {
+	input string
+	pos   int
+	tags  map[string]string
+}
+
+var errInvalidTagConditions = errors.New("invalid tag conditions")
+
+func (p *tagParser) skipSpace() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// keyword consumes a case-insensitive keyword if it's next.
+func (p *tagParser) keyword(kw string) bool {
+	p.skipSpace()
+	if len(p.input)-p.pos >= len(kw) && strings.EqualFold(p.input[p.pos:p.pos+len(kw)], kw) {
+		p.pos += len(kw)
+		return true
+	}
+	return false
+}
+
+func (p *tagParser) or() (bool, error) {
+	met, err := p.and()
+	for err == nil && p.keyword("OR") {
+		var right bool
+		right, err = p.and()
+		met = met || right
+	}
+	return met, err
+}
+
+func (p *tagParser) and() (bool, error) {
+	met, err := p.comparison()
+	for err == nil && p.keyword("AND") {
+		var right bool
+		right, err = p.comparison()
+		met = met && right
+	}
+	return met, err
+}
+
+func (p *tagParser) comparison() (bool, error) {
+	if p.keyword("(") {
+		met, err := p.or()
+		if err == nil && !p.keyword(")") {
+			err = errInvalidTagConditions
+		}
+		return met, err
+	}
+	key, err := p.quoted('"')
+	if err != nil {
+		return false, err
+	}
+	var op string
+	for _, candidate := range []string{"<>", "<=", ">=", "=", "<", ">"} {
+		if p.keyword(candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return false, errInvalidTagConditions
+	}
+	value, err := p.quoted('\'')
+	if err != nil {
+		return false, err
+	}
+	actual := p.tags[key]
+	switch op {
+	case "=":
+		return actual == value, nil
+	case "<>":
+		return actual != value, nil
+	case "<":
+		return actual < value, nil
+	case "<=":
+		return actual <= value, nil
+	case ">":
+		return actual > value, nil
+	default:
+		return actual >= value, nil
+	}
+}
+
+func (p *tagParser) quoted(quote byte) (string, error) {
+	p.skipSpace()
+	if p.pos == len(p.input) || p.input[p.pos] != quote {
+		return "", errInvalidTagConditions
+	}
+	end := strings.IndexByte(p.input[p.pos+1:], quote)
+	if end < 0 {
+		return "", errInvalidTagConditions
+	}
+	s := p.input[p.pos+1 : p.pos+1+end]
+	p.pos += end + 2
+	return s, nil
+}
@@ -0,0 +1,482 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package fake
+
+import (
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/generated"
+)
+
+const (
+	publicAccessBlob      = "blob"
+	publicAccessContainer = "container"
+
+	maxListResults = 5000
+)
+
+// container is a container of a Server.
+type container struct {
+	name         string
+	metadata     map[string]string
+	publicAccess string
+	identifiers  []*generated.SignedIdentifier
+	etag         string
+	lastModified time.Time
+	lease        lease
+	blobs        map[string]*blob
+
+	// uncommitted are the uncommitted blocks of the blobs, including the blobs that don't exist yet.
+	uncommitted map[string][]block
+}
+
+func (c *container) setHeaders(h http.Header) {
+	h.Set("ETag", c.etag)
+	h.Set("Last-Modified", c.lastModified.Format(http.TimeFormat))
+}
+
+// getContainer returns the container of the request, or a response if it doesn't exist.
+func (s *Server) getContainer(req *request) (*container, *response) {
+	c := s.containers[req.container]
+	if c == nil {
+		return nil, errorResponse(http.StatusNotFound, bloberror.ContainerNotFound, "The specified container does not exist.")
+	}
+	c.lease.update(req.now)
+	return c, nil
+}
+
+func (s *Server) accessPolicy(containerName string, id string) *generated.AccessPolicy {
+	c := s.containers[containerName]
+	if c == nil {
+		return nil
+	}
+	for _, identifier := range c.identifiers {
+		if identifier.ID != nil && *identifier.ID == id && identifier.AccessPolicy != nil {
+			return identifier.AccessPolicy
+		}
+	}
+	return nil
+}
+
+func validContainerName(name string) bool {
+	if name == "$root" || name == "$logs" || name == "$web" {
+		return true
+	}
+	if len(name) < 3 || len(name) > 63 || name[0] == '-' || name[len(name)-1] == '-' || strings.Contains(name, "--") {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// createContainer handles https://learn.microsoft.com/rest/api/storageservices/create-container.
+func (s *Server) createContainer(req *request) *response {
+	if !validContainerName(req.container) {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidResourceName, "The specified resource name contains invalid characters.")
+	}
+	if s.containers[req.container] != nil {
+		return errorResponse(http.StatusConflict, bloberror.ContainerAlreadyExists, "The specified container already exists.")
+	}
+	publicAccess := req.header.Get("x-ms-blob-public-access")
+	if publicAccess != "" && publicAccess != publicAccessBlob && publicAccess != publicAccessContainer {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue, "The value for one of the HTTP headers is not in the correct format.")
+	}
+	c := &container{
+		name:         req.container,
+		metadata:     req.metadata,
+		publicAccess: publicAccess,
+		etag:         s.newETag(),
+		lastModified: req.now.Truncate(time.Second),
+		blobs:        map[string]*blob{},
+		uncommitted:  map[string][]block{},
+	}
+	s.containers[req.container] = c
+	resp := newResponse(http.StatusCreated)
+	c.setHeaders(resp.header)
+	return resp
+}
+
+// deleteContainer handles https://learn.microsoft.com/rest/api/storageservices/delete-container.
+func (s *Server) deleteContainer(req *request) *response {
+	c, resp := s.getContainer(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := checkConditions(req, c.state(), false); resp != nil {
+		return resp
+	}
+	if resp := c.lease.check(req, true, containerLeaseErrors); resp != nil {
+		return resp
+	}
+	delete(s.containers, req.container)
+	return newResponse(http.StatusAccepted)
+}
+
+// getContainerProperties handles https://learn.microsoft.com/rest/api/storageservices/get-container-properties.
+func (s *Server) getContainerProperties(req *request) *response {
+	c, resp := s.getContainer(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := c.lease.check(req, false, containerLeaseErrors); resp != nil {
+		return resp
+	}
+	resp = newResponse(http.StatusOK)
+	c.setHeaders(resp.header)
+	setMetadataHeaders(resp.header, c.metadata)
+	c.lease.setHeaders(resp.header)
+	if c.publicAccess != "" {
+		resp.header.Set("x-ms-blob-public-access", c.publicAccess)
+	}
+	resp.header.Set("x-ms-has-immutability-policy", "false")
+	resp.header.Set("x-ms-has-legal-hold", "false")
+	return resp
+}
+
+// setContainerMetadata handles https://learn.microsoft.com/rest/api/storageservices/set-container-metadata.
+func (s *Server) setContainerMetadata(req *request) *response {
+	c, resp := s.getContainer(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := checkConditions(req, c.state(), false); resp != nil {
+		return resp
+	}
+	if resp := c.lease.check(req, false, containerLeaseErrors); resp != nil {
+		return resp
+	}
+	c.metadata = req.metadata
+	c.etag = s.newETag()
+	c.lastModified = req.now.Truncate(time.Second)
+	resp = newResponse(http.StatusOK)
+	c.setHeaders(resp.header)
+	return resp
+}
+
+type signedIdentifiers struct {
+	XMLName     xml.Name                      `xml:"SignedIdentifiers"`
+	Identifiers []*generated.SignedIdentifier `xml:"SignedIdentifier"`
+}
+
+// getContainerAccessPolicy handles https://learn.microsoft.com/rest/api/storageservices/get-container-acl.
+func (s *Server) getContainerAccessPolicy(req *request) *response {
+	c, resp := s.getContainer(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := c.lease.check(req, false, containerLeaseErrors); resp != nil {
+		return resp
+	}
+	resp = xmlResponse(http.StatusOK, signedIdentifiers{Identifiers: c.identifiers})
+	c.setHeaders(resp.header)
+	if c.publicAccess != "" {
+		resp.header.Set("x-ms-blob-public-access", c.publicAccess)
+	}
+	return resp
+}
+
+// setContainerAccessPolicy handles https://learn.microsoft.com/rest/api/storageservices/set-container-acl.
+func (s *Server) setContainerAccessPolicy(req *request) *response {
+	c, resp := s.getContainer(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := checkConditions(req, c.state(), false); resp != nil {
+		return resp
+	}
+	if resp := c.lease.check(req, false, containerLeaseErrors); resp != nil {
+		return resp
+	}
+	publicAccess := req.header.Get("x-ms-blob-public-access")
+	if publicAccess != "" && publicAccess != publicAccessBlob && publicAccess != publicAccessContainer {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue, "The value for one of the HTTP headers is not in the correct format.")
+	}
+	var identifiers signedIdentifiers
+	if len(req.body) > 0 {
+		if err := xml.Unmarshal(req.body, &identifiers); err != nil {
+			return errorResponse(http.StatusBadRequest, bloberror.InvalidXMLDocument, "XML specified is not syntactically valid.")
+		}
+	}
+	if len(identifiers.Identifiers) > 5 {
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidXMLDocument, "A container can have up to five stored access policies.")
+	}
+	c.publicAccess = publicAccess
+	c.identifiers = identifiers.Identifiers
+	c.etag = s.newETag()
+	c.lastModified = req.now.Truncate(time.Second)
+	resp = newResponse(http.StatusOK)
+	c.setHeaders(resp.header)
+	return resp
+}
+
+// containerLease handles https://learn.microsoft.com/rest/api/storageservices/lease-container.
+func (s *Server) containerLease(req *request) *response {
+	c, resp := s.getContainer(req)
+	if resp != nil {
+		return resp
+	}
+	if resp := checkConditions(req, c.state(), false); resp != nil {
+		return resp
+	}
+	resp = c.lease.handle(req)
+	c.setHeaders(resp.header)
+	return resp
+}
+
+func (c *container) state() resourceState {
+	return resourceState{exists: true, etag: c.etag, lastModified: c.lastModified}
+}
+
+// listParameters are the query parameters of a listing.
+type listParameters struct {
+	prefix     string
+	marker     string
+	maxResults int
+	include    map[string]bool
+}
+
+func parseListParameters(req *request) (listParameters, *response) {
+	params := listParameters{
+		prefix:     req.query.Get("prefix"),
+		marker:     req.query.Get("marker"),
+		maxResults: maxListResults,
+		include:    map[string]bool{},
+	}
+	if v := req.query.Get("maxresults"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return params, errorResponse(http.StatusBadRequest, bloberror.OutOfRangeQueryParameterValue,
+				"One of the query parameters specified in the request URI is outside the permissible range.")
+		}
+		params.maxResults = min(n, maxListResults)
+	}
+	for _, include := range strings.Split(req.query.Get("include"), ",") {
+		params.include[strings.ToLower(strings.TrimSpace(include))] = true
+	}
+	return params, nil
+}
+
+type containerList struct {
+	XMLName         xml.Name        `xml:"EnumerationResults"`
+	ServiceEndpoint string          `xml:"ServiceEndpoint,attr"`
+	Prefix          string          `xml:"Prefix,omitempty"`
+	Marker          string          `xml:"Marker,omitempty"`
+	MaxResults      int             `xml:"MaxResults,omitempty"`
+	Containers      []containerItem `xml:"Containers>Container"`
+	NextMarker      string          `xml:"NextMarker"`
+}
+
+type containerItem struct {
+	Name       string              `xml:"Name"`
+	Properties containerProperties `xml:"Properties"`
+	Metadata   metadataElements    `xml:"Metadata,omitempty"`
+}
+
+type containerProperties struct {
+	LastModified          string `xml:"Last-Modified"`
+	ETag                  string `xml:"Etag"`
+	LeaseStatus           string `xml:"LeaseStatus"`
+	LeaseState            string `xml:"LeaseState"`
+	LeaseDuration         string `xml:"LeaseDuration,omitempty"`
+	PublicAccess          string `xml:"PublicAccess,omitempty"`
+	HasImmutabilityPolicy bool   `xml:"HasImmutabilityPolicy"`
+	HasLegalHold          bool   `xml:"HasLegalHold"`
+}
+
+// listContainers handles https://learn.microsoft.com/rest/api/storageservices/list-containers2.
+func (s *Server) listContainers(req *request) *response {
+	params, resp := parseListParameters(req)
+	if resp != nil {
+		return resp
+	}
+	names := make([]string, 0, len(s.containers))
+	for name := range s.containers {
+		if strings.HasPrefix(name, params.prefix) && name >= params.marker {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	list := containerList{
+		ServiceEndpoint: s.URL(),
+		Prefix:          params.prefix,
+		Marker:          params.marker,
+		MaxResults:      params.maxResults,
+	}
+	if len(names) > params.maxResults {
+		list.NextMarker = names[params.maxResults]
+		names = names[:params.maxResults]
+	}
+	for _, name := range names {
+		c := s.containers[name]
+		c.lease.update(req.now)
+		item := containerItem{
+			Name: name,
+			Properties: containerProperties{
+				LastModified:  c.lastModified.Format(http.TimeFormat),
+				ETag:          c.etag,
+				LeaseStatus:   c.lease.status(),
+				LeaseState:    c.lease.state,
+				LeaseDuration: c.lease.durationType(),
+				PublicAccess:  c.publicAccess,
+			},
+		}
+		if params.include["metadata"] {
+			item.Metadata = c.metadata
+		}
+		list.Containers = append(list.Containers, item)
+	}
+	return xmlResponse(http.StatusOK, list)
+}
+
+type blobList struct {
+	XMLName         xml.Name     `xml:"EnumerationResults"`
+	ServiceEndpoint string       `xml:"ServiceEndpoint,attr"`
+	ContainerName   string       `xml:"ContainerName,attr"`
+	Prefix          string       `xml:"Prefix,omitempty"`
+	Marker          string       `xml:"Marker,omitempty"`
+	MaxResults      int          `xml:"MaxResults,omitempty"`
+	Delimiter       string       `xml:"Delimiter,omitempty"`
+	Blobs           []blobItem   `xml:"Blobs>Blob"`
+	Prefixes        []blobPrefix `xml:"Blobs>BlobPrefix"`
+	NextMarker      string       `xml:"NextMarker"`
+}
+
+type blobItem struct {
+	Name       string           `xml:"Name"`
+	Properties blobProperties   `xml:"Properties"`
+	Metadata   metadataElements `xml:"Metadata,omitempty"`
+	Tags       *blobTags        `xml:"Tags,omitempty"`
+}
+
+type blobPrefix struct {
+	Name string `xml:"Name"`
+}
+
+type blobProperties struct {
+	CreationTime       string `xml:"Creation-Time"`
+	LastModified       string `xml:"Last-Modified"`
+	ETag               string `xml:"Etag"`
+	ContentLength      int64  `xml:"Content-Length"`
+	ContentType        string `xml:"Content-Type"`
+	ContentEncoding    string `xml:"Content-Encoding,omitempty"`
+	ContentLanguage    string `xml:"Content-Language,omitempty"`
+	ContentMD5         string `xml:"Content-MD5,omitempty"`
+	ContentDisposition string `xml:"Content-Disposition,omitempty"`
+	CacheControl       string `xml:"Cache-Control,omitempty"`
+	SequenceNumber     *int64 `xml:"x-ms-blob-sequence-number,omitempty"`
+	BlobType           string `xml:"BlobType"`
+	AccessTier         string `xml:"AccessTier,omitempty"`
+	AccessTierInferred *bool  `xml:"AccessTierInferred,omitempty"`
+	LeaseStatus        string `xml:"LeaseStatus"`
+	LeaseState         string `xml:"LeaseState"`
+	LeaseDuration      string `xml:"LeaseDuration,omitempty"`
+	ServerEncrypted    bool   `xml:"ServerEncrypted"`
+	TagCount           int    `xml:"TagCount,omitempty"`
+}
+
+// listBlobs handles https://learn.microsoft.com/rest/api/storageservices/list-blobs.
+func (s *Server) listBlobs(req *request) *response {
+	c, resp := s.getContainer(req)
+	if resp != nil {
+		return resp
+	}
+	params, resp := parseListParameters(req)
+	if resp != nil {
+		return resp
+	}
+	delimiter := req.query.Get("delimiter")
+
+	names := make([]string, 0, len(c.blobs))
+	for name := range c.blobs {
+		if strings.HasPrefix(name, params.prefix) && name >= params.marker {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	list := blobList{
+		ServiceEndpoint: s.URL(),
+		ContainerName:   c.name,
+		Prefix:          params.prefix,
+		Marker:          params.marker,
+		MaxResults:      params.maxResults,
+		Delimiter:       delimiter,
+	}
+	count, lastPrefix := 0, ""
+	for _, name := range names {
+		// the names under a prefix are listed as the prefix
+		entry := name
+		if delimiter != "" {
+			if i := strings.Index(name[len(params.prefix):], delimiter); i >= 0 {
+				entry = name[:len(params.prefix)+i+len(delimiter)]
+			}
+		}
+		if entry == lastPrefix {
+			continue
+		}
+		if count == params.maxResults {
+			list.NextMarker = entry
+			break
+		}
+		count++
+		if entry != name {
+			lastPrefix = entry
+			list.Prefixes = append(list.Prefixes, blobPrefix{Name: entry})
+			continue
+		}
+
+		b := c.blobs[name]
+		b.lease.update(req.now)
+		item := blobItem{Name: name, Properties: b.properties()}
+		if params.include["metadata"] {
+			item.Metadata = b.metadata
+		}
+		if params.include["tags"] && len(b.tags) > 0 {
+			item.Tags = newBlobTags(b.tags)
+		}
+		list.Blobs = append(list.Blobs, item)
+	}
+	return xmlResponse(http.StatusOK, list)
+}
+
+// metadataElements is metadata that's marshalled as an element per key.
+type metadataElements map[string]string
+
+// MarshalXML implements the xml.Marshaler interface for type metadataElements.
+func (m metadataElements) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := enc.EncodeElement(m[k], xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+func setMetadataHeaders(h http.Header, metadata map[string]string) {
+	for k, v := range metadata {
+		h.Set("x-ms-meta-"+k, v)
+	}
+}
@@ -0,0 +1,233 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/uuid"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+const (
+	leaseStateAvailable = "available"
+	leaseStateLeased    = "leased"
+	leaseStateExpired   = "expired"
+	leaseStateBreaking  = "breaking"
+	leaseStateBroken    = "broken"
+)
+
+// lease is the lease of a container or a blob.
+type lease struct {
+	id       string
+	state    string
+	duration int
+
+	// expiry is the time that a lease with a fixed duration expires.
+	expiry time.Time
+
+	// broken is the time that a breaking lease is broken.
+	broken time.Time
+}
+
+// leaseErrors are the error codes of the operations that a lease prevents.
+type leaseErrors struct {
+	missing    bloberror.Code
+	mismatch   bloberror.Code
+	notPresent bloberror.Code
+}
+
+var (
+	containerLeaseErrors = leaseErrors{
+		missing:    bloberror.LeaseIDMissing,
+		mismatch:   bloberror.LeaseIDMismatchWithContainerOperation,
+		notPresent: bloberror.LeaseNotPresentWithContainerOperation,
+	}
+	blobLeaseErrors = leaseErrors{
+		missing:    bloberror.LeaseIDMissing,
+		mismatch:   bloberror.LeaseIDMismatchWithBlobOperation,
+		notPresent: bloberror.LeaseNotPresentWithBlobOperation,
+	}
+)
+
+// update expires and breaks the lease according to the current time.
+func (l *lease) update(now time.Time) {
+	switch {
+	case l.state == "":
+		l.state = leaseStateAvailable
+	case l.state == leaseStateLeased && l.duration > 0 && !now.Before(l.expiry):
+		l.state = leaseStateExpired
+	case l.state == leaseStateBreaking && !now.Before(l.broken):
+		l.state = leaseStateBroken
+	}
+}
+
+// active reports whether the lease locks its container or blob.
+func (l *lease) active() bool {
+	return l.state == leaseStateLeased || l.state == leaseStateBreaking
+}
+
+func (l *lease) status() string {
+	if l.active() {
+		return "locked"
+	}
+	return "unlocked"
+}
+
+func (l *lease) durationType() string {
+	switch {
+	case l.state != leaseStateLeased:
+		return ""
+	case l.duration < 0:
+		return "infinite"
+	default:
+		return "fixed"
+	}
+}
+
+func (l *lease) setHeaders(h http.Header) {
+	h.Set("x-ms-lease-state", l.state)
+	h.Set("x-ms-lease-status", l.status())
+	if d := l.durationType(); d != "" {
+		h.Set("x-ms-lease-duration", d)
+	}
+}
+
+// check checks the lease ID of a request against the lease. Write requests must specify the ID of an active lease.
+func (l *lease) check(req *request, write bool, errs leaseErrors) *response {
+	id := req.header.Get("x-ms-lease-id")
+	switch {
+	case id != "" && !l.active():
+		return errorResponse(http.StatusPreconditionFailed, errs.notPresent,
+			"There is currently no lease on the resource.")
+	case id != "" && id != l.id:
+		return errorResponse(http.StatusPreconditionFailed, errs.mismatch,
+			"The lease ID specified did not match the lease ID for the resource.")
+	case id == "" && write && l.active():
+		return errorResponse(http.StatusPreconditionFailed, errs.missing,
+			"There is currently a lease on the resource and no lease ID was specified in the request.")
+	}
+	return nil
+}
+
+// handle handles a lease request for a container or a blob.
+func (l *lease) handle(req *request) *response {
+	id := req.header.Get("x-ms-lease-id")
+	proposed := req.header.Get("x-ms-proposed-lease-id")
+	switch action := req.header.Get("x-ms-lease-action"); action {
+	case "acquire":
+		duration, err := strconv.Atoi(req.header.Get("x-ms-lease-duration"))
+		if err != nil || (duration != -1 && (duration < 15 || duration > 60)) {
+			return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue,
+				"The value for one of the HTTP headers is not in the correct format.")
+		}
+		switch {
+		case l.state == leaseStateBreaking:
+			return errorResponse(http.StatusConflict, bloberror.LeaseIsBreakingAndCannotBeAcquired,
+				"There is already a lease present and it is being broken.")
+		case l.state == leaseStateLeased && (proposed == "" || proposed != l.id):
+			return errorResponse(http.StatusConflict, bloberror.LeaseAlreadyPresent, "There is already a lease present.")
+		}
+		if proposed == "" {
+			u, err := uuid.New()
+			if err != nil {
+				return errorResponse(http.StatusInternalServerError, bloberror.InternalError, err.Error())
+			}
+			proposed = u.String()
+		}
+		l.id, l.state, l.duration = proposed, leaseStateLeased, duration
+		l.expiry = req.now.Add(time.Duration(duration) * time.Second)
+		resp := newResponse(http.StatusCreated)
+		resp.header.Set("x-ms-lease-id", l.id)
+		return resp
+
+	case "renew":
+		switch {
+		case id != l.id || l.state == leaseStateAvailable:
+			return errorResponse(http.StatusConflict, bloberror.LeaseIDMismatchWithLeaseOperation,
+				"The lease ID specified did not match the lease ID for the resource.")
+		case l.state == leaseStateBreaking || l.state == leaseStateBroken:
+			return errorResponse(http.StatusConflict, bloberror.LeaseIsBrokenAndCannotBeRenewed,
+				"The lease ID matched, but the lease has been broken explicitly and cannot be renewed.")
+		}
+		l.state = leaseStateLeased
+		l.expiry = req.now.Add(time.Duration(l.duration) * time.Second)
+		resp := newResponse(http.StatusOK)
+		resp.header.Set("x-ms-lease-id", l.id)
+		return resp
+
+	case "change":
+		switch {
+		case l.state == leaseStateBreaking:
+			return errorResponse(http.StatusConflict, bloberror.LeaseIsBreakingAndCannotBeChanged,
+				"The lease ID matched, but the lease is currently in breaking state and cannot be changed.")
+		case l.state != leaseStateLeased:
+			return errorResponse(http.StatusConflict, bloberror.LeaseNotPresentWithLeaseOperation,
+				"There is currently no lease on the resource.")
+		case id != l.id && proposed != l.id:
+			return errorResponse(http.StatusConflict, bloberror.LeaseIDMismatchWithLeaseOperation,
+				"The lease ID specified did not match the lease ID for the resource.")
+		case proposed == "":
+			return errorResponse(http.StatusBadRequest, bloberror.MissingRequiredHeader,
+				"An HTTP header that's mandatory for this request is not specified.")
+		}
+		l.id = proposed
+		resp := newResponse(http.StatusOK)
+		resp.header.Set("x-ms-lease-id", l.id)
+		return resp
+
+	case "release":
+		if id != l.id || l.state == leaseStateAvailable {
+			return errorResponse(http.StatusConflict, bloberror.LeaseIDMismatchWithLeaseOperation,
+				"The lease ID specified did not match the lease ID for the resource.")
+		}
+		l.state = leaseStateAvailable
+		return newResponse(http.StatusOK)
+
+	case "break":
+		if !l.active() {
+			return errorResponse(http.StatusConflict, bloberror.LeaseNotPresentWithLeaseOperation,
+				"There is currently no lease on the resource.")
+		}
+		// by default, fixed leases break when they expire and infinite leases break immediately
+		remaining := 0
+		if l.duration > 0 {
+			remaining = int(l.expiry.Sub(req.now).Seconds())
+		}
+		if l.state == leaseStateBreaking {
+			remaining = int(l.broken.Sub(req.now).Seconds())
+		}
+		period := remaining
+		if v := req.header.Get("x-ms-lease-break-period"); v != "" {
+			p, err := strconv.Atoi(v)
+			if err != nil || p < 0 || p > 60 {
+				return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue,
+					"The value for one of the HTTP headers is not in the correct format.")
+			}
+			period = min(p, max(remaining, 0))
+			if l.state == leaseStateLeased || l.duration < 0 {
+				period = p
+			}
+		}
+		if period <= 0 {
+			l.state = leaseStateBroken
+		} else {
+			l.state = leaseStateBreaking
+			l.broken = req.now.Add(time.Duration(period) * time.Second)
+		}
+		resp := newResponse(http.StatusAccepted)
+		resp.header.Set("x-ms-lease-time", strconv.Itoa(max(period, 0)))
+		return resp
+
+	default:
+		return errorResponse(http.StatusBadRequest, bloberror.InvalidHeaderValue,
+			fmt.Sprintf("The lease action %q is invalid.", action))
+	}
+}
@@ -0,0 +1,384 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+// Package fake provides an in-memory Blob service for testing code that uses the azblob clients without a storage
+// account or an emulator.
+//
+// A Server implements policy.Transporter, so that clients send their requests to it when it's set as the Transport
+// of their azcore.ClientOptions, and http.Handler, so that it can be served with net/http/httptest.
+package fake
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/exported"
+)
+
+const (
+	// DefaultAccountName is the default name of the account of a Server.
+	DefaultAccountName = "fakeaccount"
+
+	// DefaultAccountKey is the default key of the account of a Server.
+	DefaultAccountKey = "ZmFrZWFjY291bnRrZXlmYWtlYWNjb3VudGtleWZha2VhY2NvdW50a2V5ZmFrZWFjY291bnRrZXk="
+
+	// serviceVersion is the service version that a Server responds with.
+	serviceVersion = "2025-11-05"
+)
+
+// ServerOptions contains the optional parameters for the NewServer method.
+type ServerOptions struct {
+	// AccountName is the name of the account.
+	// The default value is DefaultAccountName.
+	AccountName string
+
+	// AccountKey is the base64-encoded key of the account, which shared key signatures and SAS are validated with.
+	// The default value is DefaultAccountKey.
+	AccountKey string
+
+	// AllowAnonymousAccess authorizes the requests without a shared key signature or SAS, including the requests
+	// with bearer tokens, as if they were made by the account owner. By default, such requests can only read the
+	// containers and blobs with public access.
+	AllowAnonymousAccess bool
+}
+
+// Server is an in-memory Blob service. It supports containers, block, append and page blobs, block lists, metadata,
+// tags, leases, conditional headers, listing with prefix and delimiter, and shared key and SAS authorization.
+// Operations that it doesn't support fail with status code 501 (Not Implemented).
+// A Server is safe for concurrent use.
+type Server struct {
+	accountName string
+	accountKey  string
+	cred        *exported.SharedKeyCredential
+	anonymous   bool
+
+	mu         sync.Mutex
+	containers map[string]*container
+	etags      int64
+	requests   int64
+}
+
+// NewServer creates a Server with an empty account.
+//   - options - Server options; pass nil to accept the default values
+func NewServer(options *ServerOptions) (*Server, error) {
+	o := ServerOptions{}
+	if options != nil {
+		o = *options
+	}
+	if o.AccountName == "" {
+		o.AccountName = DefaultAccountName
+	}
+	if o.AccountKey == "" {
+		o.AccountKey = DefaultAccountKey
+	}
+	cred, err := exported.NewSharedKeyCredential(o.AccountName, o.AccountKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		accountName: o.AccountName,
+		accountKey:  o.AccountKey,
+		cred:        cred,
+		anonymous:   o.AllowAnonymousAccess,
+		containers:  map[string]*container{},
+	}, nil
+}
+
+// URL returns the URL of the account, e.g. https://fakeaccount.blob.core.windows.net/, for clients that use the
+// Server as their Transport. Clients of a Server served with httptest use the URL of the httptest.Server followed by
+// the name of the account instead.
+func (s *Server) URL() string {
+	return "https://" + s.accountName + ".blob.core.windows.net/"
+}
+
+// SharedKeyCredential returns a credential with the name and key of the account.
+func (s *Server) SharedKeyCredential() *azblob.SharedKeyCredential {
+	cred, _ := azblob.NewSharedKeyCredential(s.accountName, s.accountKey)
+	return cred
+}
+
+// Do implements the policy.Transporter interface for type Server.
+func (s *Server) Do(req *http.Request) (*http.Response, error) {
+	resp, err := s.handle(req)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.status, http.StatusText(resp.status)),
+		StatusCode:    resp.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        resp.header,
+		Body:          io.NopCloser(bytes.NewReader(resp.body)),
+		ContentLength: int64(len(resp.body)),
+		Request:       req,
+	}, nil
+}
+
+// ServeHTTP implements the http.Handler interface for type Server.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	resp, err := s.handle(req)
+	if err != nil {
+		resp = errorResponse(http.StatusBadRequest, bloberror.InvalidInput, err.Error())
+	}
+	for k, v := range resp.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.status)
+	if req.Method != http.MethodHead {
+		_, _ = w.Write(resp.body)
+	}
+}
+
+// request is a request to a Server.
+type request struct {
+	raw       *http.Request
+	header    http.Header
+	metadata  map[string]string
+	query     url.Values
+	body      []byte
+	container string
+	blob      string
+	now       time.Time
+}
+
+// response is a response from a Server.
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func newResponse(status int) *response {
+	return &response{status: status, header: http.Header{}}
+}
+
+func errorResponse(status int, code bloberror.Code, message string) *response {
+	resp := newResponse(status)
+	resp.header.Set("x-ms-error-code", string(code))
+	resp.header.Set("Content-Type", "application/xml")
+	resp.body = []byte(fmt.Sprintf("%s<Error><Code>%s</Code><Message>%s</Message></Error>", xml.Header, code, xmlEscape(message)))
+	return resp
+}
+
+func notImplemented(req *request) *response {
+	return errorResponse(http.StatusNotImplemented, "NotImplemented",
+		fmt.Sprintf("The fake Blob service doesn't support %s requests with query %q.", req.raw.Method, req.raw.URL.RawQuery))
+}
+
+func xmlResponse(status int, v any) *response {
+	resp := newResponse(status)
+	body, err := xml.Marshal(v)
+	if err != nil {
+		return errorResponse(http.StatusInternalServerError, bloberror.InternalError, err.Error())
+	}
+	resp.header.Set("Content-Type", "application/xml")
+	resp.body = append([]byte(xml.Header), body...)
+	return resp
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (s *Server) handle(raw *http.Request) (*response, error) {
+	var body []byte
+	if raw.Body != nil {
+		var err error
+		if body, err = io.ReadAll(raw.Body); err != nil {
+			return nil, err
+		}
+		_ = raw.Body.Close()
+	}
+	req := &request{
+		raw:      raw,
+		header:   http.Header{},
+		metadata: map[string]string{},
+		query:    raw.URL.Query(),
+		body:     body,
+		now:      time.Now().UTC(),
+	}
+	for k, v := range raw.Header {
+		if len(k) > len("x-ms-meta-") && strings.EqualFold(k[:len("x-ms-meta-")], "x-ms-meta-") && len(v) > 0 {
+			req.metadata[k[len("x-ms-meta-"):]] = v[0]
+		}
+		key := http.CanonicalHeaderKey(k)
+		req.header[key] = append(req.header[key], v...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	var resp *response
+	if container, blob, ok := s.parsePath(raw); !ok {
+		resp = errorResponse(http.StatusBadRequest, bloberror.InvalidURI, "The URL doesn't refer to the account of the fake Blob service.")
+	} else {
+		req.container, req.blob = container, blob
+		resp = s.serve(req)
+	}
+	resp.header.Set("x-ms-request-id", fmt.Sprintf("00000000-0000-0000-0000-%012d", s.requests))
+	resp.header.Set("x-ms-version", serviceVersion)
+	resp.header.Set("Date", req.now.Format(http.TimeFormat))
+	if resp.header.Get("Content-Length") == "" {
+		resp.header.Set("Content-Length", fmt.Sprint(len(resp.body)))
+	}
+	return resp, nil
+}
+
+// parsePath returns the container and blob that the request refers to. The account is the first subdomain of the
+// host, or the first segment of the path for IP-style URLs such as the ones of a Server served with httptest.
+func (s *Server) parsePath(req *http.Request) (string, string, bool) {
+	p := strings.TrimPrefix(req.URL.Path, "/")
+	if !strings.HasPrefix(req.URL.Host, s.accountName+".") {
+		account, rest, _ := strings.Cut(p, "/")
+		if account != s.accountName {
+			return "", "", false
+		}
+		p = rest
+	}
+	container, blob, _ := strings.Cut(p, "/")
+	return container, blob, true
+}
+
+// operation is a supported operation of the Blob service.
+type operation struct {
+	// permissions are the SAS permissions that allow the operation, any of which is sufficient.
+	permissions string
+
+	// resourceType is the account SAS resource type of the operation: 's' for service, 'c' for container or 'o' for
+	// object.
+	resourceType byte
+
+	// public is the public access level of a container that allows anonymous requests to perform the operation.
+	public string
+
+	handle func(*request) *response
+}
+
+func (s *Server) serve(req *request) *response {
+	op := s.route(req)
+	if op == nil {
+		return notImplemented(req)
+	}
+	if resp := s.authorize(req, op); resp != nil {
+		return resp
+	}
+	return op.handle(req)
+}
+
+func (s *Server) route(req *request) *operation {
+	method, restype, comp := req.raw.Method, req.query.Get("restype"), req.query.Get("comp")
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	switch {
+	case restype == "account" && comp == "properties" && method == http.MethodGet:
+		return &operation{permissions: "rwdlacuptfx", resourceType: 's', handle: s.getAccountInfo}
+	case req.container == "":
+		if comp == "list" && method == http.MethodGet {
+			return &operation{permissions: "l", resourceType: 's', handle: s.listContainers}
+		}
+		return nil
+	case req.blob == "":
+		if restype != "container" {
+			return nil
+		}
+		return s.routeContainer(req, method, comp)
+	default:
+		return s.routeBlob(req, method, comp)
+	}
+}
+
+func (s *Server) routeContainer(req *request, method string, comp string) *operation {
+	switch {
+	case comp == "" && method == http.MethodPut:
+		return &operation{permissions: "cw", resourceType: 'c', handle: s.createContainer}
+	case comp == "" && method == http.MethodDelete:
+		return &operation{permissions: "d", resourceType: 'c', handle: s.deleteContainer}
+	case comp == "" && method == http.MethodGet:
+		return &operation{permissions: "r", resourceType: 'c', public: publicAccessContainer, handle: s.getContainerProperties}
+	case comp == "metadata" && method == http.MethodPut:
+		return &operation{permissions: "w", resourceType: 'c', handle: s.setContainerMetadata}
+	case comp == "acl" && method == http.MethodGet:
+		return &operation{permissions: "r", resourceType: 'c', handle: s.getContainerAccessPolicy}
+	case comp == "acl" && method == http.MethodPut:
+		return &operation{permissions: "w", resourceType: 'c', handle: s.setContainerAccessPolicy}
+	case comp == "lease" && method == http.MethodPut:
+		return &operation{permissions: "w", resourceType: 'c', handle: s.containerLease}
+	case comp == "list" && method == http.MethodGet:
+		return &operation{permissions: "l", resourceType: 'c', public: publicAccessContainer, handle: s.listBlobs}
+	}
+	return nil
+}
+
+func (s *Server) routeBlob(req *request, method string, comp string) *operation {
+	switch {
+	case comp == "" && method == http.MethodPut:
+		if req.header.Get("x-ms-copy-source") != "" {
+			return nil
+		}
+		return &operation{permissions: "cw", resourceType: 'o', handle: s.putBlob}
+	case comp == "" && method == http.MethodGet:
+		if req.raw.Method == http.MethodHead {
+			return &operation{permissions: "r", resourceType: 'o', public: publicAccessBlob, handle: s.getBlobProperties}
+		}
+		return &operation{permissions: "r", resourceType: 'o', public: publicAccessBlob, handle: s.getBlobContent}
+	case comp == "" && method == http.MethodDelete:
+		return &operation{permissions: "d", resourceType: 'o', handle: s.deleteBlob}
+	case comp == "metadata" && method == http.MethodPut:
+		return &operation{permissions: "w", resourceType: 'o', handle: s.setBlobMetadata}
+	case comp == "properties" && method == http.MethodPut:
+		return &operation{permissions: "w", resourceType: 'o', handle: s.setBlobProperties}
+	case comp == "tags" && method == http.MethodGet:
+		return &operation{permissions: "t", resourceType: 'o', handle: s.getBlobTags}
+	case comp == "tags" && method == http.MethodPut:
+		return &operation{permissions: "t", resourceType: 'o', handle: s.setBlobTags}
+	case comp == "tier" && method == http.MethodPut:
+		return &operation{permissions: "w", resourceType: 'o', handle: s.setBlobTier}
+	case comp == "lease" && method == http.MethodPut:
+		return &operation{permissions: "w", resourceType: 'o', handle: s.blobLease}
+	case comp == "block" && method == http.MethodPut && req.header.Get("x-ms-copy-source") == "":
+		return &operation{permissions: "w", resourceType: 'o', handle: s.stageBlock}
+	case comp == "blocklist" && method == http.MethodPut:
+		return &operation{permissions: "cw", resourceType: 'o', handle: s.commitBlockList}
+	case comp == "blocklist" && method == http.MethodGet:
+		return &operation{permissions: "r", resourceType: 'o', public: publicAccessBlob, handle: s.getBlockList}
+	case comp == "appendblock" && method == http.MethodPut && req.header.Get("x-ms-copy-source") == "":
+		return &operation{permissions: "aw", resourceType: 'o', handle: s.appendBlock}
+	case comp == "page" && method == http.MethodPut && req.header.Get("x-ms-copy-source") == "":
+		return &operation{permissions: "w", resourceType: 'o', handle: s.putPages}
+	case comp == "pagelist" && method == http.MethodGet && req.query.Get("prevsnapshot") == "":
+		return &operation{permissions: "r", resourceType: 'o', public: publicAccessBlob, handle: s.getPageRanges}
+	}
+	return nil
+}
+
+// getAccountInfo handles https://learn.microsoft.com/rest/api/storageservices/get-account-information.
+func (s *Server) getAccountInfo(req *request) *response {
+	resp := newResponse(http.StatusOK)
+	resp.header.Set("x-ms-sku-name", "Standard_LRS")
+	resp.header.Set("x-ms-account-kind", "StorageV2")
+	resp.header.Set("x-ms-is-hns-enabled", "false")
+	return resp
+}
+
+// newETag returns a new unique ETag.
+func (s *Server) newETag() string {
+	s.etags++
+	return fmt.Sprintf("\"0x8DC%013X\"", s.etags)
+}
@@ -0,0 +1,372 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package fake_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/lease"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+	"github.com/stretchr/testify/require"
+)
+
+func clientOptions(server *fake.Server) azcore.ClientOptions {
+	return azcore.ClientOptions{Transport: server, Retry: policy.RetryOptions{MaxRetries: -1}}
+}
+
+func newServer(t *testing.T) (*fake.Server, *container.Client) {
+	server, err := fake.NewServer(nil)
+	require.NoError(t, err)
+	client, err := container.NewClientWithSharedKeyCredential(server.URL()+"data", server.SharedKeyCredential(),
+		&container.ClientOptions{ClientOptions: clientOptions(server)})
+	require.NoError(t, err)
+	_, err = client.Create(context.Background(), &container.CreateOptions{Metadata: map[string]*string{"Owner": to.Ptr("tests")}})
+	require.NoError(t, err)
+	return server, client
+}
+
+func body(s string) io.ReadSeekCloser {
+	return streaming.NopCloser(strings.NewReader(s))
+}
+
+func TestServerBlockBlobs(t *testing.T) {
+	_, containerClient := newServer(t)
+	ctx := context.Background()
+	client := containerClient.NewBlockBlobClient("dir/hello.txt")
+
+	_, err := client.UploadBuffer(ctx, []byte("hello, world"), &blockblob.UploadBufferOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: to.Ptr("text/plain")},
+		Metadata:    map[string]*string{"Color": to.Ptr("blue")},
+		Tags:        map[string]string{"project": "fake"},
+	})
+	require.NoError(t, err)
+
+	buffer := make([]byte, 12)
+	n, err := client.DownloadBuffer(ctx, buffer, nil)
+	require.NoError(t, err)
+	require.Equal(t, "hello, world", string(buffer[:n]))
+
+	resp, err := client.DownloadStream(ctx, &blob.DownloadStreamOptions{Range: blob.HTTPRange{Offset: 7, Count: 5}})
+	require.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "world", string(data))
+	require.Equal(t, "bytes 7-11/12", *resp.ContentRange)
+
+	props, err := client.GetProperties(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(12), *props.ContentLength)
+	require.Equal(t, "text/plain", *props.ContentType)
+	require.Equal(t, "blue", *props.Metadata["Color"])
+	require.Equal(t, int64(1), *props.TagCount)
+	require.Equal(t, blob.BlobTypeBlockBlob, *props.BlobType)
+
+	tags, err := client.GetTags(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, tags.BlobTagSet, 1)
+	require.Equal(t, "fake", *tags.BlobTagSet[0].Value)
+
+	// blocks are committed in the order of the block list
+	ids := []string{base64.StdEncoding.EncodeToString([]byte("1")), base64.StdEncoding.EncodeToString([]byte("2"))}
+	_, err = client.StageBlock(ctx, ids[0], body("first "), nil)
+	require.NoError(t, err)
+	_, err = client.StageBlock(ctx, ids[1], body("second"), nil)
+	require.NoError(t, err)
+	blocks, err := client.GetBlockList(ctx, blockblob.BlockListTypeAll, nil)
+	require.NoError(t, err)
+	require.Empty(t, blocks.BlockList.CommittedBlocks)
+	require.Len(t, blocks.BlockList.UncommittedBlocks, 2)
+
+	_, err = client.CommitBlockList(ctx, []string{ids[1], ids[0]}, nil)
+	require.NoError(t, err)
+	n, err = client.DownloadBuffer(ctx, buffer, nil)
+	require.NoError(t, err)
+	require.Equal(t, "secondfirst ", string(buffer[:n]))
+	_, err = client.CommitBlockList(ctx, []string{ids[0], base64.StdEncoding.EncodeToString([]byte("3"))}, nil)
+	require.True(t, bloberror.HasCode(err, bloberror.InvalidBlockList))
+
+	// the transactional MD5 is validated
+	_, err = client.Upload(ctx, body("content"), &blockblob.UploadOptions{TransactionalValidation: blob.TransferValidationTypeMD5([]byte("0123456789abcdef"))})
+	require.True(t, bloberror.HasCode(err, bloberror.MD5Mismatch))
+
+	_, err = client.Delete(ctx, nil)
+	require.NoError(t, err)
+	_, err = client.GetProperties(ctx, nil)
+	require.True(t, bloberror.HasCode(err, bloberror.BlobNotFound))
+}
+
+func TestServerAppendAndPageBlobs(t *testing.T) {
+	_, containerClient := newServer(t)
+	ctx := context.Background()
+
+	appendClient := containerClient.NewAppendBlobClient("log")
+	_, err := appendClient.Create(ctx, nil)
+	require.NoError(t, err)
+	for _, line := range []string{"one\n", "two\n"} {
+		_, err = appendClient.AppendBlock(ctx, body(line), nil)
+		require.NoError(t, err)
+	}
+	_, err = appendClient.AppendBlock(ctx, body("three\n"), &appendblob.AppendBlockOptions{
+		AppendPositionAccessConditions: &appendblob.AppendPositionAccessConditions{AppendPosition: to.Ptr(int64(4))},
+	})
+	require.True(t, bloberror.HasCode(err, bloberror.AppendPositionConditionNotMet))
+	resp, err := appendClient.DownloadStream(ctx, nil)
+	require.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "one\ntwo\n", string(data))
+	require.Equal(t, int32(2), *resp.BlobCommittedBlockCount)
+
+	pageClient := containerClient.NewPageBlobClient("disk")
+	_, err = pageClient.Create(ctx, 4*512, nil)
+	require.NoError(t, err)
+	page := bytes.Repeat([]byte("p"), 512)
+	for _, offset := range []int64{0, 512, 3 * 512} {
+		_, err = pageClient.UploadPages(ctx, streaming.NopCloser(bytes.NewReader(page)), blob.HTTPRange{Offset: offset, Count: 512}, nil)
+		require.NoError(t, err)
+	}
+	_, err = pageClient.UploadPages(ctx, streaming.NopCloser(bytes.NewReader(page)), blob.HTTPRange{Offset: 100, Count: 512}, nil)
+	require.True(t, bloberror.HasCode(err, bloberror.InvalidPageRange))
+
+	pager := pageClient.NewGetPageRangesPager(nil)
+	require.True(t, pager.More())
+	ranges, err := pager.NextPage(ctx)
+	require.NoError(t, err)
+	require.Len(t, ranges.PageRange, 2)
+	require.Equal(t, int64(1023), *ranges.PageRange[0].End)
+	require.Equal(t, int64(3*512), *ranges.PageRange[1].Start)
+
+	buffer := make([]byte, 4*512)
+	n, err := pageClient.DownloadBuffer(ctx, buffer, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(4*512), n)
+	require.Equal(t, make([]byte, 512), buffer[2*512:3*512])
+}
+
+func TestServerListBlobs(t *testing.T) {
+	_, containerClient := newServer(t)
+	ctx := context.Background()
+	for _, name := range []string{"a/1", "a/2", "b/1", "c", "d"} {
+		_, err := containerClient.NewBlockBlobClient(name).UploadBuffer(ctx, []byte(name), nil)
+		require.NoError(t, err)
+	}
+
+	var names []string
+	pager := containerClient.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: to.Ptr("a/"), MaxResults: to.Ptr(int32(1))})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		require.NoError(t, err)
+		for _, item := range page.Segment.BlobItems {
+			names = append(names, *item.Name)
+		}
+	}
+	require.Equal(t, []string{"a/1", "a/2"}, names)
+
+	names = nil
+	hierarchy := containerClient.NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{MaxResults: to.Ptr(int32(3))})
+	for hierarchy.More() {
+		page, err := hierarchy.NextPage(ctx)
+		require.NoError(t, err)
+		for _, prefix := range page.Segment.BlobPrefixes {
+			names = append(names, *prefix.Name)
+		}
+		for _, item := range page.Segment.BlobItems {
+			names = append(names, *item.Name)
+			require.Equal(t, int64(1), *item.Properties.ContentLength)
+		}
+	}
+	require.ElementsMatch(t, []string{"a/", "b/", "c", "d"}, names)
+}
+
+func TestServerLeasesAndConditions(t *testing.T) {
+	_, containerClient := newServer(t)
+	ctx := context.Background()
+	client := containerClient.NewBlockBlobClient("locked")
+	uploaded, err := client.UploadBuffer(ctx, []byte("v1"), nil)
+	require.NoError(t, err)
+
+	// conditional requests
+	_, err = client.UploadBuffer(ctx, []byte("v2"), &blockblob.UploadBufferOptions{AccessConditions: &blob.AccessConditions{
+		ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: to.Ptr(azcore.ETagAny)},
+	}})
+	require.True(t, bloberror.HasCode(err, bloberror.BlobAlreadyExists))
+	_, err = client.SetMetadata(ctx, nil, &blob.SetMetadataOptions{AccessConditions: &blob.AccessConditions{
+		ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: to.Ptr(azcore.ETag(`"other"`))},
+	}})
+	require.True(t, bloberror.HasCode(err, bloberror.ConditionNotMet))
+	_, err = client.SetMetadata(ctx, nil, &blob.SetMetadataOptions{AccessConditions: &blob.AccessConditions{
+		ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: uploaded.ETag},
+	}})
+	require.NoError(t, err)
+	_, err = client.SetTags(ctx, map[string]string{"state": "done"}, nil)
+	require.NoError(t, err)
+	_, err = client.Delete(ctx, &blob.DeleteOptions{AccessConditions: &blob.AccessConditions{
+		ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfTags: to.Ptr(`"state" = 'pending'`)},
+	}})
+	require.True(t, bloberror.HasCode(err, bloberror.ConditionNotMet))
+
+	// writes require the ID of the lease
+	leaseClient, err := lease.NewBlobClient(client, nil)
+	require.NoError(t, err)
+	_, err = leaseClient.AcquireLease(ctx, -1, nil)
+	require.NoError(t, err)
+	_, err = client.Delete(ctx, nil)
+	require.True(t, bloberror.HasCode(err, bloberror.LeaseIDMissing))
+	props, err := client.GetProperties(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, lease.StateTypeLeased, *props.LeaseState)
+	require.Equal(t, lease.DurationTypeInfinite, *props.LeaseDuration)
+
+	broken, err := leaseClient.BreakLease(ctx, &lease.BlobBreakOptions{BreakPeriod: to.Ptr(int32(0))})
+	require.NoError(t, err)
+	require.Equal(t, int32(0), *broken.LeaseTime)
+	_, err = client.Delete(ctx, &blob.DeleteOptions{AccessConditions: &blob.AccessConditions{
+		ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfTags: to.Ptr(`"state" = 'done' AND "missing" < 'a'`)},
+	}})
+	require.NoError(t, err)
+
+	containerLease, err := lease.NewContainerClient(containerClient, nil)
+	require.NoError(t, err)
+	_, err = containerLease.AcquireLease(ctx, 15, nil)
+	require.NoError(t, err)
+	_, err = containerClient.Delete(ctx, nil)
+	require.True(t, bloberror.HasCode(err, bloberror.LeaseIDMissing))
+	_, err = containerLease.ReleaseLease(ctx, nil)
+	require.NoError(t, err)
+	_, err = containerClient.Delete(ctx, nil)
+	require.NoError(t, err)
+}
+
+func TestServerAuthorization(t *testing.T) {
+	server, containerClient := newServer(t)
+	ctx := context.Background()
+	_, err := containerClient.NewBlockBlobClient("secret").UploadBuffer(ctx, []byte("secret"), nil)
+	require.NoError(t, err)
+
+	// a shared key signature with another key fails
+	cred, err := azblob.NewSharedKeyCredential(fake.DefaultAccountName, base64.StdEncoding.EncodeToString([]byte("wrong")))
+	require.NoError(t, err)
+	wrong, err := blob.NewClientWithSharedKeyCredential(server.URL()+"data/secret", cred, &blob.ClientOptions{ClientOptions: clientOptions(server)})
+	require.NoError(t, err)
+	_, err = wrong.GetProperties(ctx, nil)
+	require.True(t, bloberror.HasCode(err, bloberror.AuthenticationFailed))
+
+	// private blobs don't exist for anonymous requests
+	anonymous, err := blob.NewClientWithNoCredential(server.URL()+"data/secret", &blob.ClientOptions{ClientOptions: clientOptions(server)})
+	require.NoError(t, err)
+	_, err = anonymous.DownloadStream(ctx, nil)
+	require.True(t, bloberror.HasCode(err, bloberror.ResourceNotFound))
+
+	// service SAS
+	sasURL, err := containerClient.NewBlobClient("secret").GetSASURL(sas.BlobPermissions{Read: true}, time.Now().Add(time.Hour), nil)
+	require.NoError(t, err)
+	sasClient, err := blob.NewClientWithNoCredential(sasURL, &blob.ClientOptions{ClientOptions: clientOptions(server)})
+	require.NoError(t, err)
+	resp, err := sasClient.DownloadStream(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	_, err = sasClient.Delete(ctx, nil)
+	require.True(t, bloberror.HasCode(err, bloberror.AuthorizationPermissionMismatch))
+
+	// service SAS with a stored access policy
+	_, err = containerClient.SetAccessPolicy(ctx, &container.SetAccessPolicyOptions{ContainerACL: []*container.SignedIdentifier{{
+		ID: to.Ptr("deleters"),
+		AccessPolicy: &container.AccessPolicy{
+			Expiry:     to.Ptr(time.Now().Add(time.Hour).UTC().Truncate(time.Second)),
+			Permission: to.Ptr("d"),
+		},
+	}}})
+	require.NoError(t, err)
+	signed, err := sas.BlobSignatureValues{
+		Protocol:      sas.ProtocolHTTPS,
+		ContainerName: "data",
+		BlobName:      "secret",
+		Identifier:    "deleters",
+	}.SignWithSharedKey(server.SharedKeyCredential())
+	require.NoError(t, err)
+	policyClient, err := blob.NewClientWithNoCredential(server.URL()+"data/secret?"+signed.Encode(), &blob.ClientOptions{ClientOptions: clientOptions(server)})
+	require.NoError(t, err)
+	_, err = policyClient.GetProperties(ctx, nil)
+	require.True(t, bloberror.HasCode(err, bloberror.AuthorizationPermissionMismatch))
+	_, err = policyClient.Delete(ctx, nil)
+	require.NoError(t, err)
+
+	// account SAS
+	serviceClient, err := service.NewClientWithSharedKeyCredential(server.URL(), server.SharedKeyCredential(), &service.ClientOptions{ClientOptions: clientOptions(server)})
+	require.NoError(t, err)
+	accountURL, err := serviceClient.GetSASURL(sas.AccountResourceTypes{Service: true}, sas.AccountPermissions{List: true}, time.Now().Add(time.Hour), nil)
+	require.NoError(t, err)
+	accountClient, err := service.NewClientWithNoCredential(accountURL, &service.ClientOptions{ClientOptions: clientOptions(server)})
+	require.NoError(t, err)
+	pager := accountClient.NewListContainersPager(&service.ListContainersOptions{Include: service.ListContainersInclude{Metadata: true}})
+	page, err := pager.NextPage(ctx)
+	require.NoError(t, err)
+	require.Len(t, page.ContainerItems, 1)
+	require.Equal(t, "tests", *page.ContainerItems[0].Metadata["owner"])
+	_, err = accountClient.NewContainerClient("data").GetProperties(ctx, nil)
+	require.True(t, bloberror.HasCode(err, bloberror.AuthorizationResourceTypeMismatch))
+}
+
+func TestServerHTTPTest(t *testing.T) {
+	server, err := fake.NewServer(&fake.ServerOptions{AccountName: "httptest"})
+	require.NoError(t, err)
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+	ctx := context.Background()
+
+	client, err := azblob.NewClientWithSharedKeyCredential(srv.URL+"/httptest/", server.SharedKeyCredential(),
+		&azblob.ClientOptions{ClientOptions: azcore.ClientOptions{Retry: policy.RetryOptions{MaxRetries: -1}}})
+	require.NoError(t, err)
+	_, err = client.CreateContainer(ctx, "data", nil)
+	require.NoError(t, err)
+	_, err = client.UploadBuffer(ctx, "data", "blob", []byte("served"), &azblob.UploadBufferOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentMD5: []byte("0123456789abcdef")},
+	})
+	require.NoError(t, err)
+	buffer := make([]byte, 6)
+	n, err := client.DownloadBuffer(ctx, "data", "blob", buffer, nil)
+	require.NoError(t, err)
+	require.Equal(t, "served", string(buffer[:n]))
+	props, err := client.ServiceClient().NewContainerClient("data").NewBlobClient("blob").GetProperties(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("0123456789abcdef"), props.ContentMD5)
+
+	_, err = client.UploadBuffer(ctx, "missing", "blob", nil, nil)
+	require.True(t, bloberror.HasCode(err, bloberror.ContainerNotFound))
+}
+
+func TestServerNotImplemented(t *testing.T) {
+	server, containerClient := newServer(t)
+	serviceClient, err := service.NewClientWithSharedKeyCredential(server.URL(), server.SharedKeyCredential(), &service.ClientOptions{ClientOptions: clientOptions(server)})
+	require.NoError(t, err)
+	_, err = serviceClient.GetProperties(context.Background(), nil)
+	var respErr *azcore.ResponseError
+	require.ErrorAs(t, err, &respErr)
+	require.Equal(t, 501, respErr.StatusCode)
+	_, err = containerClient.NewBlobClient("b").CreateSnapshot(context.Background(), nil)
+	require.ErrorAs(t, err, &respErr)
+	require.Equal(t, "NotImplemented", respErr.ErrorCode)
+}
@@ -0,0 +1,272 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package exported
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+const (
+	// MaxBatchSubRequests is the maximum number of sub-requests in a batch.
+	MaxBatchSubRequests = 256
+
+	defaultBulkConcurrency = 5
+	defaultBulkMaxRetries  = 3
+	defaultBulkRetryDelay  = time.Second
+	maxBulkRetryDelay      = time.Minute
+)
+
+// BulkSource selects the blobs that a bulk operation applies to. Exactly one of the fields must be specified.
+type BulkSource struct {
+	// Prefix selects the blobs whose names start with the prefix. When the bulk operation is performed on the
+	// account, the prefix starts with the name of a container followed by a slash, e.g. "logs/2024/".
+	Prefix *string
+
+	// Where selects the blobs whose tags match the expression, e.g. "status='expired'". See FilterBlobs.
+	Where *string
+
+	// BlobNames lists the blobs. When the bulk operation is performed on the account, each name is the name
+	// of a container followed by a slash and the name of the blob.
+	BlobNames []string
+}
+
+// Validate returns an error unless exactly one of the fields is specified.
+func (s BulkSource) Validate() error {
+	specified := 0
+	for _, ok := range []bool{s.Prefix != nil, s.Where != nil, s.BlobNames != nil} {
+		if ok {
+			specified++
+		}
+	}
+	if specified != 1 {
+		return errors.New("exactly one of Prefix, Where and BlobNames must be specified")
+	}
+	return nil
+}
+
+// BulkOptions contains the optional parameters common to the bulk operations.
+type BulkOptions struct {
+	// BatchSize is the number of blobs in each batch request, up to 256. The default is 256.
+	BatchSize int
+
+	// Concurrency is the number of requests in flight at the same time. The default is 5.
+	Concurrency int
+
+	// MaxRetries is the number of times a blob whose operation failed with a transient error, e.g. because
+	// the service is busy, is retried in a later request. Pass -1 to disable these retries. The default is 3.
+	// Batch requests as a whole are retried by the client's retry policy.
+	MaxRetries int32
+
+	// RetryDelay is the delay before the first retry of the failed blobs, doubled for every further retry.
+	// The default is 1 second.
+	RetryDelay time.Duration
+
+	// OnResult, if specified, is called with the result of the operation on every blob, once the blob has
+	// succeeded or failed for good. Calls are serialized.
+	OnResult func(BulkItemResult)
+}
+
+// BulkItemResult contains the result of a bulk operation on a single blob.
+type BulkItemResult struct {
+	ContainerName string
+	BlobName      string
+
+	// Error is nil if the operation on the blob succeeded.
+	Error error
+}
+
+// BulkResult summarizes the results of a bulk operation.
+type BulkResult struct {
+	// Succeeded is the number of blobs on which the operation succeeded.
+	Succeeded int64
+
+	// Failed contains the results of the blobs on which the operation failed.
+	Failed []BulkItemResult
+}
+
+// BulkTarget is a blob that a bulk operation applies to.
+type BulkTarget struct {
+	ContainerName string
+	BlobName      string
+}
+
+// BulkList returns the next page of the blobs that a bulk operation applies to, and whether there are more.
+type BulkList func(ctx context.Context) ([]BulkTarget, bool, error)
+
+// BulkSubmit performs the operation on a batch of blobs. It returns the error of each blob, or an error if the
+// request for the whole batch failed.
+type BulkSubmit func(ctx context.Context, targets []BulkTarget) ([]error, error)
+
+// ListBulkTargets returns a BulkList that returns the targets once.
+func ListBulkTargets(targets []BulkTarget) BulkList {
+	return func(context.Context) ([]BulkTarget, bool, error) {
+		return targets, false, nil
+	}
+}
+
+// BatchResponseErrors returns the error of each sub-request of a batch with n sub-requests.
+func BatchResponseErrors(responses []*BatchResponseItem, n int) []error {
+	errs := make([]error, n)
+	for _, item := range responses {
+		if item.ContentID != nil && *item.ContentID >= 0 && *item.ContentID < n {
+			errs[*item.ContentID] = item.Error
+		}
+	}
+	return errs
+}
+
+// DoBulk lists the blobs with list, splits them into batches and performs submit on the batches concurrently.
+// The blobs that fail with a transient error are retried. It returns an error if listing the blobs fails or ctx is
+// canceled, along with the results so far.
+func DoBulk(ctx context.Context, list BulkList, submit BulkSubmit, o BulkOptions) (BulkResult, error) {
+	if o.BatchSize == 0 {
+		o.BatchSize = MaxBatchSubRequests
+	}
+	if o.BatchSize < 0 || o.BatchSize > MaxBatchSubRequests {
+		return BulkResult{}, fmt.Errorf("BatchSize must be between 1 and %d", MaxBatchSubRequests)
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultBulkConcurrency
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = defaultBulkMaxRetries
+	} else if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+	if o.RetryDelay <= 0 {
+		o.RetryDelay = defaultBulkRetryDelay
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := BulkResult{}
+	resultLock := &sync.Mutex{}
+	report := func(target BulkTarget, err error) {
+		resultLock.Lock()
+		defer resultLock.Unlock()
+		item := BulkItemResult{ContainerName: target.ContainerName, BlobName: target.BlobName, Error: err}
+		if err == nil {
+			result.Succeeded++
+		} else {
+			result.Failed = append(result.Failed, item)
+		}
+		if o.OnResult != nil {
+			o.OnResult(item)
+		}
+	}
+
+	batches := make(chan []BulkTarget)
+	wg := sync.WaitGroup{}
+	for i := 0; i < o.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				doBulkBatch(ctx, batch, submit, o, report)
+			}
+		}()
+	}
+
+	listErr := func() error {
+		defer close(batches)
+		var pending []BulkTarget
+		send := func(batch []BulkTarget) error {
+			select {
+			case batches <- batch:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		for more := true; more; {
+			page, m, err := list(ctx)
+			if err != nil {
+				return err
+			}
+			more = m
+			pending = append(pending, page...)
+			for len(pending) >= o.BatchSize || (!more && len(pending) > 0) {
+				n := min(len(pending), o.BatchSize)
+				if err := send(pending[:n:n]); err != nil {
+					return err
+				}
+				pending = pending[n:]
+			}
+		}
+		return nil
+	}()
+	if listErr != nil {
+		cancel()
+	}
+	wg.Wait()
+
+	if listErr == nil {
+		listErr = ctx.Err()
+	}
+	return result, listErr
+}
+
+// doBulkBatch submits a batch, resubmitting the blobs that fail with a transient error.
+func doBulkBatch(ctx context.Context, batch []BulkTarget, submit BulkSubmit, o BulkOptions, report func(BulkTarget, error)) {
+	delay := o.RetryDelay
+	for attempt := int32(0); ; attempt++ {
+		errs, err := submit(ctx, batch)
+		if err != nil {
+			errs = make([]error, len(batch))
+			for i := range errs {
+				errs[i] = err
+			}
+		}
+
+		var retry []BulkTarget
+		for i, target := range batch {
+			if errs[i] != nil && attempt < o.MaxRetries && ctx.Err() == nil && isTransientBulkError(errs[i]) {
+				retry = append(retry, target)
+				continue
+			}
+			report(target, errs[i])
+		}
+		if len(retry) == 0 {
+			return
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			for _, target := range retry {
+				report(target, ctx.Err())
+			}
+			return
+		case <-timer.C:
+		}
+		batch = retry
+		delay = min(2*delay, maxBulkRetryDelay)
+	}
+}
+
+// isTransientBulkError returns true if the operation on a blob failed with an error that may go away on retry.
+func isTransientBulkError(err error) bool {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	switch respErr.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
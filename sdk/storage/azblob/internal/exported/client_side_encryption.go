@@ -0,0 +1,574 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package exported
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	// ClientSideEncryptionVersion2 is the only client-side encryption protocol version written by this module.
+	// It is the AES-GCM envelope format shared with the .NET, Java and Python storage SDKs.
+	ClientSideEncryptionVersion2 = "2.0"
+
+	// EncryptionDataMetadataKey is the name of the blob metadata entry holding the encryption envelope.
+	EncryptionDataMetadataKey = "encryptiondata"
+
+	// KeyWrapAlgorithmA256KW is the AES key wrap algorithm (RFC 3394) used by local key-encryption keys.
+	KeyWrapAlgorithmA256KW = "A256KW"
+
+	encryptionAlgorithmAESGCM256 = "AES_GCM_256"
+	encryptionRegionDataLength   = 4 * 1024 * 1024
+	encryptionNonceLength        = 12
+	encryptionTagLength          = 16
+	contentKeyLength             = 32
+	protocolPrefixLength         = 8
+)
+
+// KeyEncryptionKey wraps and unwraps the per-blob content encryption key.
+// It can be implemented on top of a key management service such as Azure Key Vault (azkeys.Client WrapKey and
+// UnwrapKey) or with a locally held key, see NewLocalKeyEncryptionKey.
+type KeyEncryptionKey interface {
+	// KeyID returns the identifier recorded in the blob's encryption metadata.
+	KeyID() string
+
+	// WrapKey encrypts the content encryption key using the specified algorithm.
+	WrapKey(ctx context.Context, algorithm string, key []byte) ([]byte, error)
+
+	// UnwrapKey decrypts a content encryption key previously returned by WrapKey.
+	UnwrapKey(ctx context.Context, algorithm string, encryptedKey []byte) ([]byte, error)
+}
+
+// KeyResolver returns the KeyEncryptionKey with the specified ID.
+type KeyResolver func(ctx context.Context, keyID string) (KeyEncryptionKey, error)
+
+// ClientSideEncryptionOptions configures client-side encryption of blob content.
+// Uploaded data is encrypted with the version 2.0 protocol and the envelope is stored in the blob's
+// "encryptiondata" metadata entry, so it remains readable by the other Azure Storage SDKs.
+type ClientSideEncryptionOptions struct {
+	// KeyEncryptionKey wraps the content encryption key on upload. On download it is used to unwrap the key
+	// when KeyResolver is nil. It is required for uploads.
+	KeyEncryptionKey KeyEncryptionKey
+
+	// KeyWrapAlgorithm is passed to KeyEncryptionKey.WrapKey. The default value is KeyWrapAlgorithmA256KW.
+	KeyWrapAlgorithm string
+
+	// KeyResolver, when set, is used on download to look up the key-encryption key named in the blob's metadata.
+	KeyResolver KeyResolver
+}
+
+func (o *ClientSideEncryptionOptions) keyWrapAlgorithm() string {
+	if o.KeyWrapAlgorithm == "" {
+		return KeyWrapAlgorithmA256KW
+	}
+	return o.KeyWrapAlgorithm
+}
+
+func (o *ClientSideEncryptionOptions) resolveKey(ctx context.Context, keyID string) (KeyEncryptionKey, error) {
+	if o.KeyResolver != nil {
+		kek, err := o.KeyResolver(ctx, keyID)
+		if err != nil {
+			return nil, err
+		}
+		if kek == nil {
+			return nil, fmt.Errorf("key resolver returned no key for key ID %q", keyID)
+		}
+		return kek, nil
+	}
+	if o.KeyEncryptionKey == nil {
+		return nil, errors.New("client-side encryption requires a KeyEncryptionKey or a KeyResolver")
+	}
+	if id := o.KeyEncryptionKey.KeyID(); id != keyID {
+		return nil, fmt.Errorf("blob was encrypted with key %q but the configured key is %q", keyID, id)
+	}
+	return o.KeyEncryptionKey, nil
+}
+
+// encryptionData is the JSON envelope stored in the blob's metadata.
+type encryptionData struct {
+	WrappedContentKey   wrappedContentKey    `json:"WrappedContentKey"`
+	EncryptionAgent     encryptionAgent      `json:"EncryptionAgent"`
+	EncryptedRegionInfo *encryptedRegionInfo `json:"EncryptedRegionInfo,omitempty"`
+	KeyWrappingMetadata map[string]string    `json:"KeyWrappingMetadata,omitempty"`
+}
+
+type wrappedContentKey struct {
+	KeyID        string `json:"KeyId"`
+	EncryptedKey []byte `json:"EncryptedKey"`
+	Algorithm    string `json:"Algorithm"`
+}
+
+type encryptionAgent struct {
+	Protocol            string `json:"Protocol"`
+	EncryptionAlgorithm string `json:"EncryptionAlgorithm"`
+}
+
+type encryptedRegionInfo struct {
+	DataLength  int64 `json:"DataLength"`
+	NonceLength int64 `json:"NonceLength"`
+}
+
+// protocolPrefix returns the protocol version, zero-padded to 8 bytes, which v2 prepends to the
+// content encryption key before wrapping it.
+func protocolPrefix() []byte {
+	p := make([]byte, protocolPrefixLength)
+	copy(p, ClientSideEncryptionVersion2)
+	return p
+}
+
+// ClientSideEncryptor encrypts blob content for upload.
+type ClientSideEncryptor struct {
+	aead cipher.AEAD
+	data string
+}
+
+// NewClientSideEncryptor generates a new content encryption key and wraps it with the configured key-encryption key.
+func NewClientSideEncryptor(ctx context.Context, o *ClientSideEncryptionOptions) (*ClientSideEncryptor, error) {
+	if o == nil || o.KeyEncryptionKey == nil {
+		return nil, errors.New("client-side encryption requires a KeyEncryptionKey")
+	}
+
+	cek := make([]byte, contentKeyLength)
+	if _, err := rand.Read(cek); err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm := o.keyWrapAlgorithm()
+	wrapped, err := o.KeyEncryptionKey.WrapKey(ctx, algorithm, append(protocolPrefix(), cek...))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(encryptionData{
+		WrappedContentKey: wrappedContentKey{
+			KeyID:        o.KeyEncryptionKey.KeyID(),
+			EncryptedKey: wrapped,
+			Algorithm:    algorithm,
+		},
+		EncryptionAgent: encryptionAgent{
+			Protocol:            ClientSideEncryptionVersion2,
+			EncryptionAlgorithm: encryptionAlgorithmAESGCM256,
+		},
+		EncryptedRegionInfo: &encryptedRegionInfo{
+			DataLength:  encryptionRegionDataLength,
+			NonceLength: encryptionNonceLength,
+		},
+		KeyWrappingMetadata: map[string]string{"EncryptionLibrary": "Go " + ModuleVersion},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClientSideEncryptor{aead: aead, data: string(data)}, nil
+}
+
+// Metadata returns a copy of metadata with the encryption envelope added.
+func (e *ClientSideEncryptor) Metadata(metadata map[string]*string) map[string]*string {
+	m := make(map[string]*string, len(metadata)+1)
+	for k, v := range metadata {
+		if !strings.EqualFold(k, EncryptionDataMetadataKey) {
+			m[k] = v
+		}
+	}
+	data := e.data
+	m[EncryptionDataMetadataKey] = &data
+	return m
+}
+
+// EncryptedSize returns the size of the encrypted form of size bytes of content.
+func (e *ClientSideEncryptor) EncryptedSize(size int64) int64 {
+	regions := (size + encryptionRegionDataLength - 1) / encryptionRegionDataLength
+	return size + regions*(encryptionNonceLength+encryptionTagLength)
+}
+
+// Encrypt returns the encrypted form of p.
+func (e *ClientSideEncryptor) Encrypt(p []byte) ([]byte, error) {
+	out := make([]byte, 0, e.EncryptedSize(int64(len(p))))
+	for len(p) > 0 {
+		n := min(len(p), encryptionRegionDataLength)
+		nonce := make([]byte, encryptionNonceLength)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		out = append(out, nonce...)
+		out = e.aead.Seal(out, nonce, p[:n], nil)
+		p = p[n:]
+	}
+	return out, nil
+}
+
+// NewReader returns a reader producing the encrypted form of r.
+func (e *ClientSideEncryptor) NewReader(r io.Reader) io.Reader {
+	return &encryptingReader{e: e, src: r, plain: make([]byte, encryptionRegionDataLength)}
+}
+
+// NewReaderAt returns a ReaderAt over the encrypted form of the size bytes of content in r, along with its size.
+// A nonce is chosen per region up front so that overlapping or repeated reads (e.g. retries) return identical data.
+func (e *ClientSideEncryptor) NewReaderAt(r io.ReaderAt, size int64) (io.ReaderAt, int64, error) {
+	regions := (size + encryptionRegionDataLength - 1) / encryptionRegionDataLength
+	nonces := make([]byte, regions*encryptionNonceLength)
+	if _, err := rand.Read(nonces); err != nil {
+		return nil, 0, err
+	}
+	return &encryptingReaderAt{e: e, src: r, size: size, nonces: nonces}, e.EncryptedSize(size), nil
+}
+
+type encryptingReader struct {
+	e     *ClientSideEncryptor
+	src   io.Reader
+	plain []byte
+	out   []byte
+	err   error
+}
+
+func (r *encryptingReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		n, err := io.ReadFull(r.src, r.plain)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		if n > 0 {
+			if r.out, r.err = r.e.Encrypt(r.plain[:n]); r.err != nil {
+				return 0, r.err
+			}
+		}
+		if err != nil {
+			r.err = err
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// encryptingReaderAtCacheSize bounds the number of sealed regions kept by encryptingReaderAt.
+// Callers typically read a region in many small chunks, so recently sealed regions are reused.
+const encryptingReaderAtCacheSize = 16
+
+type encryptingReaderAt struct {
+	e      *ClientSideEncryptor
+	src    io.ReaderAt
+	size   int64
+	nonces []byte
+
+	mu    sync.Mutex
+	cache map[int64][]byte
+	order []int64
+}
+
+func (r *encryptingReaderAt) sealRegion(region int64) ([]byte, error) {
+	r.mu.Lock()
+	sealed, ok := r.cache[region]
+	r.mu.Unlock()
+	if ok {
+		return sealed, nil
+	}
+
+	plainOff := region * encryptionRegionDataLength
+	plain := make([]byte, min(encryptionRegionDataLength, r.size-plainOff))
+	if _, err := r.src.ReadAt(plain, plainOff); err != nil && err != io.EOF {
+		return nil, err
+	}
+	nonce := r.nonces[region*encryptionNonceLength : (region+1)*encryptionNonceLength]
+	sealed = make([]byte, 0, len(plain)+encryptionNonceLength+encryptionTagLength)
+	sealed = r.e.aead.Seal(append(sealed, nonce...), nonce, plain, nil)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cache == nil {
+		r.cache = map[int64][]byte{}
+	}
+	if _, ok := r.cache[region]; !ok {
+		if len(r.order) == encryptingReaderAtCacheSize {
+			delete(r.cache, r.order[0])
+			r.order = r.order[1:]
+		}
+		r.cache[region] = sealed
+		r.order = append(r.order, region)
+	}
+	return sealed, nil
+}
+
+func (r *encryptingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	const regionLength = encryptionRegionDataLength + encryptionNonceLength + encryptionTagLength
+	encSize := r.e.EncryptedSize(r.size)
+	if off >= encSize {
+		return 0, io.EOF
+	}
+
+	read := 0
+	for read < len(p) && off < encSize {
+		region := off / regionLength
+		sealed, err := r.sealRegion(region)
+		if err != nil {
+			return read, err
+		}
+		n := copy(p[read:], sealed[off-region*regionLength:])
+		read += n
+		off += int64(n)
+	}
+	if read < len(p) {
+		return read, io.EOF
+	}
+	return read, nil
+}
+
+// ClientSideDecryptor decrypts blob content written with client-side encryption.
+type ClientSideDecryptor struct {
+	aead       cipher.AEAD
+	dataLength int64
+}
+
+// HasEncryptionData reports whether the blob metadata contains a client-side encryption envelope.
+func HasEncryptionData(metadata map[string]*string) bool {
+	return getEncryptionData(metadata) != nil
+}
+
+func getEncryptionData(metadata map[string]*string) *string {
+	for k, v := range metadata {
+		if strings.EqualFold(k, EncryptionDataMetadataKey) && v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// NewClientSideDecryptor parses the encryption envelope in metadata and unwraps the content encryption key.
+func NewClientSideDecryptor(ctx context.Context, o *ClientSideEncryptionOptions, metadata map[string]*string) (*ClientSideDecryptor, error) {
+	raw := getEncryptionData(metadata)
+	if raw == nil {
+		return nil, errors.New("blob does not contain client-side encryption metadata")
+	}
+
+	var data encryptionData
+	if err := json.Unmarshal([]byte(*raw), &data); err != nil {
+		return nil, fmt.Errorf("invalid client-side encryption metadata: %w", err)
+	}
+	if data.EncryptionAgent.Protocol != ClientSideEncryptionVersion2 {
+		return nil, fmt.Errorf("unsupported client-side encryption protocol %q", data.EncryptionAgent.Protocol)
+	}
+	if data.EncryptionAgent.EncryptionAlgorithm != encryptionAlgorithmAESGCM256 {
+		return nil, fmt.Errorf("unsupported client-side encryption algorithm %q", data.EncryptionAgent.EncryptionAlgorithm)
+	}
+	if data.EncryptedRegionInfo == nil || data.EncryptedRegionInfo.DataLength <= 0 ||
+		data.EncryptedRegionInfo.NonceLength != encryptionNonceLength {
+		return nil, errors.New("invalid client-side encryption region info")
+	}
+
+	kek, err := o.resolveKey(ctx, data.WrappedContentKey.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	unwrapped, err := kek.UnwrapKey(ctx, data.WrappedContentKey.Algorithm, data.WrappedContentKey.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(unwrapped) != protocolPrefixLength+contentKeyLength ||
+		subtle.ConstantTimeCompare(unwrapped[:protocolPrefixLength], protocolPrefix()) != 1 {
+		return nil, errors.New("unwrapped content encryption key does not match the encryption protocol version")
+	}
+	aead, err := newGCM(unwrapped[protocolPrefixLength:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClientSideDecryptor{aead: aead, dataLength: data.EncryptedRegionInfo.DataLength}, nil
+}
+
+func (d *ClientSideDecryptor) regionLength() int64 {
+	return d.dataLength + encryptionNonceLength + encryptionTagLength
+}
+
+// PlaintextSize returns the size of the plaintext stored in an encrypted blob of encryptedSize bytes.
+func (d *ClientSideDecryptor) PlaintextSize(encryptedSize int64) int64 {
+	regions := (encryptedSize + d.regionLength() - 1) / d.regionLength()
+	return encryptedSize - regions*(encryptionNonceLength+encryptionTagLength)
+}
+
+// EncryptedRange maps a plaintext range onto the range of whole encrypted regions containing it.
+// It also returns the number of plaintext bytes to discard from the start of the first region.
+func (d *ClientSideDecryptor) EncryptedRange(r HTTPRange) (HTTPRange, int64) {
+	first := r.Offset / d.dataLength
+	encRange := HTTPRange{Offset: first * d.regionLength()}
+	if r.Count > 0 {
+		last := (r.Offset + r.Count + d.dataLength - 1) / d.dataLength
+		encRange.Count = (last - first) * d.regionLength()
+	}
+	return encRange, r.Offset - first*d.dataLength
+}
+
+// NewReader returns a reader that decrypts the encryptedSize bytes of whole regions read from body,
+// skipping the first skip plaintext bytes and returning at most count bytes (0 means to the end).
+func (d *ClientSideDecryptor) NewReader(body io.ReadCloser, encryptedSize, skip, count int64) io.ReadCloser {
+	return &decryptingReader{d: d, body: body, remaining: encryptedSize, skip: skip, count: count, limited: count > 0}
+}
+
+type decryptingReader struct {
+	d         *ClientSideDecryptor
+	body      io.ReadCloser
+	remaining int64
+	skip      int64
+	count     int64
+	limited   bool
+	region    []byte
+	out       []byte
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.remaining <= 0 || (r.limited && r.count <= 0) {
+			return 0, io.EOF
+		}
+		n := min(r.remaining, r.d.regionLength())
+		if int64(cap(r.region)) < n {
+			r.region = make([]byte, r.d.regionLength())
+		}
+		region := r.region[:n]
+		if _, err := io.ReadFull(r.body, region); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		r.remaining -= n
+		if n <= encryptionNonceLength+encryptionTagLength {
+			return 0, errors.New("encrypted region is too short")
+		}
+		plain, err := r.d.aead.Open(region[encryptionNonceLength:encryptionNonceLength], region[:encryptionNonceLength], region[encryptionNonceLength:], nil)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt blob content: %w", err)
+		}
+		if r.skip > 0 {
+			s := min(r.skip, int64(len(plain)))
+			plain = plain[s:]
+			r.skip -= s
+		}
+		if r.limited {
+			plain = plain[:min(int64(len(plain)), r.count)]
+			r.count -= int64(len(plain))
+		}
+		r.out = plain
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+func (r *decryptingReader) Close() error {
+	return r.body.Close()
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, encryptionNonceLength)
+}
+
+// localKeyEncryptionKey is a KeyEncryptionKey backed by a locally held AES key.
+type localKeyEncryptionKey struct {
+	id    string
+	block cipher.Block
+}
+
+// NewLocalKeyEncryptionKey creates a KeyEncryptionKey that wraps content keys with the AES key wrap
+// algorithm (RFC 3394) using a 128, 192 or 256-bit key held in memory.
+func NewLocalKeyEncryptionKey(keyID string, key []byte) (KeyEncryptionKey, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &localKeyEncryptionKey{id: keyID, block: block}, nil
+}
+
+func (k *localKeyEncryptionKey) KeyID() string {
+	return k.id
+}
+
+func (k *localKeyEncryptionKey) checkAlgorithm(algorithm string) error {
+	switch algorithm {
+	case "A128KW", "A192KW", "A256KW":
+		return nil
+	default:
+		return fmt.Errorf("unsupported key wrap algorithm %q for a local key", algorithm)
+	}
+}
+
+var keyWrapDefaultIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+func (k *localKeyEncryptionKey) WrapKey(_ context.Context, algorithm string, key []byte) ([]byte, error) {
+	if err := k.checkAlgorithm(algorithm); err != nil {
+		return nil, err
+	}
+	if len(key)%8 != 0 || len(key) < 16 {
+		return nil, errors.New("key to wrap must be a multiple of 8 bytes and at least 16 bytes long")
+	}
+
+	n := len(key) / 8
+	out := make([]byte, len(key)+8)
+	copy(out, keyWrapDefaultIV)
+	copy(out[8:], key)
+	b := make([]byte, aes.BlockSize)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(b, out[:8])
+			copy(b[8:], out[i*8:i*8+8])
+			k.block.Encrypt(b, b)
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(b[:8])^t)
+			copy(out[i*8:], b[8:])
+		}
+	}
+	return out, nil
+}
+
+func (k *localKeyEncryptionKey) UnwrapKey(_ context.Context, algorithm string, encryptedKey []byte) ([]byte, error) {
+	if err := k.checkAlgorithm(algorithm); err != nil {
+		return nil, err
+	}
+	if len(encryptedKey)%8 != 0 || len(encryptedKey) < 24 {
+		return nil, errors.New("wrapped key must be a multiple of 8 bytes and at least 24 bytes long")
+	}
+
+	n := len(encryptedKey)/8 - 1
+	out := make([]byte, len(encryptedKey))
+	copy(out, encryptedKey)
+	b := make([]byte, aes.BlockSize)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b[:8], binary.BigEndian.Uint64(out[:8])^t)
+			copy(b[8:], out[i*8:i*8+8])
+			k.block.Decrypt(b, b)
+			copy(out[:8], b[:8])
+			copy(out[i*8:], b[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(out[:8], keyWrapDefaultIV) != 1 {
+		return nil, errors.New("key unwrap failed integrity check")
+	}
+	return out[8:], nil
+}
@@ -0,0 +1,185 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package exported
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEncryptionOptions(t *testing.T) *ClientSideEncryptionOptions {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	kek, err := NewLocalKeyEncryptionKey("local-key", key)
+	require.NoError(t, err)
+	return &ClientSideEncryptionOptions{KeyEncryptionKey: kek}
+}
+
+func newTestPlaintext(t *testing.T, size int) []byte {
+	p := make([]byte, size)
+	_, err := rand.Read(p)
+	require.NoError(t, err)
+	return p
+}
+
+func TestLocalKeyWrapRFC3394(t *testing.T) {
+	kekBytes, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F")
+	keyData, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F")
+	expected, _ := hex.DecodeString("28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21")
+
+	kek, err := NewLocalKeyEncryptionKey("id", kekBytes)
+	require.NoError(t, err)
+
+	wrapped, err := kek.WrapKey(context.Background(), KeyWrapAlgorithmA256KW, keyData)
+	require.NoError(t, err)
+	require.Equal(t, expected, wrapped)
+
+	unwrapped, err := kek.UnwrapKey(context.Background(), KeyWrapAlgorithmA256KW, wrapped)
+	require.NoError(t, err)
+	require.Equal(t, keyData, unwrapped)
+
+	wrapped[3] ^= 0xff
+	_, err = kek.UnwrapKey(context.Background(), KeyWrapAlgorithmA256KW, wrapped)
+	require.Error(t, err)
+}
+
+func TestClientSideEncryptionMetadataFormat(t *testing.T) {
+	o := newTestEncryptionOptions(t)
+	enc, err := NewClientSideEncryptor(context.Background(), o)
+	require.NoError(t, err)
+
+	metadata := enc.Metadata(map[string]*string{"foo": nil})
+	require.Contains(t, metadata, "foo")
+	require.NotNil(t, metadata[EncryptionDataMetadataKey])
+
+	var data map[string]any
+	require.NoError(t, json.Unmarshal([]byte(*metadata[EncryptionDataMetadataKey]), &data))
+	require.Equal(t, map[string]any{"Protocol": "2.0", "EncryptionAlgorithm": "AES_GCM_256"}, data["EncryptionAgent"])
+	require.Equal(t, map[string]any{"DataLength": float64(4194304), "NonceLength": float64(12)}, data["EncryptedRegionInfo"])
+	wrapped := data["WrappedContentKey"].(map[string]any)
+	require.Equal(t, "local-key", wrapped["KeyId"])
+	require.Equal(t, "A256KW", wrapped["Algorithm"])
+}
+
+func TestClientSideEncryptionRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	o := newTestEncryptionOptions(t)
+	plaintext := newTestPlaintext(t, 2*encryptionRegionDataLength+1000)
+
+	enc, err := NewClientSideEncryptor(ctx, o)
+	require.NoError(t, err)
+	metadata := enc.Metadata(nil)
+
+	encrypted, err := enc.Encrypt(plaintext)
+	require.NoError(t, err)
+	require.Equal(t, enc.EncryptedSize(int64(len(plaintext))), int64(len(encrypted)))
+
+	streamed, err := io.ReadAll(enc.NewReader(bytes.NewReader(plaintext)))
+	require.NoError(t, err)
+	require.Len(t, streamed, len(encrypted))
+
+	readerAt, size, err := enc.NewReaderAt(bytes.NewReader(plaintext), int64(len(plaintext)))
+	require.NoError(t, err)
+	fromReaderAt, err := io.ReadAll(io.NewSectionReader(readerAt, 0, size))
+	require.NoError(t, err)
+	again := make([]byte, 100)
+	_, err = readerAt.ReadAt(again, encryptionRegionDataLength)
+	require.NoError(t, err)
+	require.Equal(t, fromReaderAt[encryptionRegionDataLength:encryptionRegionDataLength+100], again)
+
+	dec, err := NewClientSideDecryptor(ctx, o, metadata)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(plaintext)), dec.PlaintextSize(int64(len(encrypted))))
+
+	for _, ciphertext := range [][]byte{encrypted, streamed, fromReaderAt} {
+		r := dec.NewReader(io.NopCloser(bytes.NewReader(ciphertext)), int64(len(ciphertext)), 0, 0)
+		decrypted, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, plaintext, decrypted)
+	}
+}
+
+func TestClientSideEncryptionRangedDecrypt(t *testing.T) {
+	ctx := context.Background()
+	o := newTestEncryptionOptions(t)
+	plaintext := newTestPlaintext(t, 3*encryptionRegionDataLength-7)
+
+	enc, err := NewClientSideEncryptor(ctx, o)
+	require.NoError(t, err)
+	encrypted, err := enc.Encrypt(plaintext)
+	require.NoError(t, err)
+	dec, err := NewClientSideDecryptor(ctx, o, enc.Metadata(nil))
+	require.NoError(t, err)
+
+	for _, rnge := range []HTTPRange{
+		{Offset: 10, Count: 20},
+		{Offset: encryptionRegionDataLength - 5, Count: 10},
+		{Offset: encryptionRegionDataLength, Count: encryptionRegionDataLength},
+		{Offset: 2*encryptionRegionDataLength + 3, Count: 0},
+		{Offset: 5, Count: 3 * encryptionRegionDataLength},
+	} {
+		encRange, skip := dec.EncryptedRange(rnge)
+		end := int64(len(encrypted))
+		if encRange.Count > 0 && encRange.Offset+encRange.Count < end {
+			end = encRange.Offset + encRange.Count
+		}
+		body := encrypted[encRange.Offset:end]
+		decrypted, err := io.ReadAll(dec.NewReader(io.NopCloser(bytes.NewReader(body)), int64(len(body)), skip, rnge.Count))
+		require.NoError(t, err)
+
+		expectedEnd := int64(len(plaintext))
+		if rnge.Count > 0 && rnge.Offset+rnge.Count < expectedEnd {
+			expectedEnd = rnge.Offset + rnge.Count
+		}
+		require.Equal(t, plaintext[rnge.Offset:expectedEnd], decrypted, "range %v", rnge)
+	}
+}
+
+func TestClientSideEncryptionKeyMismatch(t *testing.T) {
+	ctx := context.Background()
+	o := newTestEncryptionOptions(t)
+	enc, err := NewClientSideEncryptor(ctx, o)
+	require.NoError(t, err)
+	metadata := enc.Metadata(nil)
+
+	_, err = NewClientSideDecryptor(ctx, newTestEncryptionOptions(t), metadata)
+	require.Error(t, err)
+
+	_, err = NewClientSideDecryptor(ctx, &ClientSideEncryptionOptions{
+		KeyResolver: func(ctx context.Context, keyID string) (KeyEncryptionKey, error) {
+			require.Equal(t, "local-key", keyID)
+			return o.KeyEncryptionKey, nil
+		},
+	}, metadata)
+	require.NoError(t, err)
+
+	_, err = NewClientSideDecryptor(ctx, o, map[string]*string{})
+	require.Error(t, err)
+}
+
+func TestClientSideEncryptionTamperedContent(t *testing.T) {
+	ctx := context.Background()
+	o := newTestEncryptionOptions(t)
+	enc, err := NewClientSideEncryptor(ctx, o)
+	require.NoError(t, err)
+	encrypted, err := enc.Encrypt([]byte("some secret content"))
+	require.NoError(t, err)
+	dec, err := NewClientSideDecryptor(ctx, o, enc.Metadata(nil))
+	require.NoError(t, err)
+
+	encrypted[encryptionNonceLength+2] ^= 0x01
+	_, err = io.ReadAll(dec.NewReader(io.NopCloser(bytes.NewReader(encrypted)), int64(len(encrypted)), 0, 0))
+	require.Error(t, err)
+}
@@ -272,6 +272,11 @@ func ComputeHMACSHA256(cred *SharedKeyCredential, message string) (string, error
 	return cred.computeHMACSHA256(message)
 }
 
+// BuildStringToSign is a helper for computing the string to sign for an HTTP request outside of this package.
+func BuildStringToSign(cred *SharedKeyCredential, req *http.Request) (string, error) {
+	return cred.buildStringToSign(req)
+}
+
 // the following content isn't actually exported but must live
 // next to SharedKeyCredential as it uses its unexported methods
 
@@ -0,0 +1,249 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package lease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+// lockClient is the lease client of the blob or container that a Lock leases.
+type lockClient interface {
+	acquire(ctx context.Context, duration int32) error
+	renew(ctx context.Context) error
+	release(ctx context.Context) error
+	leaseID() *string
+}
+
+// Lock is a distributed lock backed by the lease of a blob or a container, e.g. to elect a single leader
+// among several processes. The lease is renewed in the background while the lock is held, and the context
+// returned by Acquire and TryAcquire is canceled if the lease is lost.
+//
+// The lease ID identifies the holder of the lock. To fence writes made by a holder that has lost the lock without
+// noticing yet, e.g. because it was paused, pass AccessConditions to the operations it performs on the leased blob.
+// The service then rejects them once the lease is held by someone else.
+//
+// A Lock must be created with BlobClient.NewLock or ContainerClient.NewLock. The blob or container must exist.
+// Don't use the underlying lease client for other lease operations while the lock is held.
+type Lock struct {
+	client  lockClient
+	options LockOptions
+
+	mu sync.Mutex
+	// held is the context returned to the holder of the lock, and cancel cancels it
+	held   context.Context
+	cancel context.CancelCauseFunc
+	// stopRenewals stops the goroutine renewing the lease, which closes done when it exits
+	stopRenewals context.CancelFunc
+	done         chan struct{}
+}
+
+// NewLock creates a Lock backed by the lease of the blob. The lease uses the lease ID of the client.
+func (c *BlobClient) NewLock(o *LockOptions) (*Lock, error) {
+	options, err := o.format()
+	if err != nil {
+		return nil, err
+	}
+	return newLock(blobLockClient{c}, options), nil
+}
+
+// NewLock creates a Lock backed by the lease of the container. The lease uses the lease ID of the client.
+func (c *ContainerClient) NewLock(o *LockOptions) (*Lock, error) {
+	options, err := o.format()
+	if err != nil {
+		return nil, err
+	}
+	return newLock(containerLockClient{c}, options), nil
+}
+
+func newLock(client lockClient, options LockOptions) *Lock {
+	return &Lock{client: client, options: options}
+}
+
+// LeaseID returns the lease ID used by the lock, which can be used as a fencing token.
+func (l *Lock) LeaseID() string {
+	if id := l.client.leaseID(); id != nil {
+		return *id
+	}
+	return ""
+}
+
+// AccessConditions returns lease access conditions with the lease ID of the lock. Operations on the leased
+// blob or container that specify them fail once the lock is lost.
+func (l *Lock) AccessConditions() *AccessConditions {
+	id := l.LeaseID()
+	return &AccessConditions{LeaseID: &id}
+}
+
+// Acquire acquires the lock, waiting until the lease is released or expires if it's held by another client.
+// It returns a context that is canceled when the lock is lost or closed, with the cause of a loss available from
+// context.Cause. The returned context carries the values of ctx but isn't canceled with it.
+// Acquire returns an error if ctx is canceled before the lock is acquired.
+func (l *Lock) Acquire(ctx context.Context) (context.Context, error) {
+	for {
+		held, err := l.TryAcquire(ctx)
+		if !bloberror.HasCode(err, bloberror.LeaseAlreadyPresent, bloberror.LeaseIsBreakingAndCannotBeAcquired) {
+			return held, err
+		}
+		timer := time.NewTimer(l.options.RetryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// TryAcquire makes a single attempt to acquire the lock. If the lease is held by another client, it returns an
+// error with the bloberror.LeaseAlreadyPresent code. Otherwise it behaves like Acquire.
+func (l *Lock) TryAcquire(ctx context.Context) (context.Context, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.done != nil {
+		select {
+		case <-l.done:
+			// the lock was lost
+			l.stopRenewals()
+		default:
+			return nil, errors.New("the lock is already held")
+		}
+	}
+
+	acquired := time.Now()
+	if err := l.client.acquire(ctx, int32(l.options.Duration/time.Second)); err != nil {
+		return nil, err
+	}
+
+	held, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	renewals, stopRenewals := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	l.held, l.cancel, l.stopRenewals, l.done = held, cancel, stopRenewals, done
+	go l.renew(renewals, cancel, done, acquired)
+	return held, nil
+}
+
+// renew renews the lease until renewals is canceled or the lease is lost, in which case it cancels the context
+// of the holder with the cause of the loss.
+func (l *Lock) renew(renewals context.Context, cancel context.CancelCauseFunc, done chan struct{}, renewed time.Time) {
+	defer close(done)
+	ticker := time.NewTicker(l.options.RenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-renewals.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// the lease is valid for Duration from the time the request renewing it was sent
+		expires := renewed.Add(l.options.Duration)
+		ctx, cancelRenew := context.WithDeadline(renewals, expires)
+		start := time.Now()
+		err := l.client.renew(ctx)
+		cancelRenew()
+		switch {
+		case err == nil:
+			renewed = start
+		case renewals.Err() != nil:
+			return
+		case bloberror.HasCode(err,
+			bloberror.LeaseLost,
+			bloberror.LeaseIDMismatchWithLeaseOperation,
+			bloberror.LeaseNotPresentWithLeaseOperation,
+			bloberror.LeaseIsBrokenAndCannotBeRenewed,
+			bloberror.BlobNotFound,
+			bloberror.ContainerNotFound,
+			bloberror.ContainerBeingDeleted):
+			cancel(err)
+			return
+		case !time.Now().Before(expires):
+			cancel(fmt.Errorf("the lease expired before it could be renewed: %w", err))
+			return
+		}
+		// other errors are retried at the next interval while the lease is valid
+	}
+}
+
+// Close releases the lock if it's held. The context returned by Acquire is canceled before the lease is released,
+// so the holder should stop using the leased resource when it's done. The lock can be acquired again after Close.
+func (l *Lock) Close(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.done == nil {
+		return nil
+	}
+	l.stopRenewals()
+	<-l.done
+	lost := l.held.Err() != nil
+	l.cancel(nil)
+	l.held, l.cancel, l.stopRenewals, l.done = nil, nil, nil, nil
+	if lost {
+		return nil
+	}
+
+	err := l.client.release(ctx)
+	if bloberror.HasCode(err, bloberror.LeaseIDMismatchWithLeaseOperation, bloberror.LeaseNotPresentWithLeaseOperation) {
+		// the lease has expired or was taken over, so there's nothing to release
+		return nil
+	}
+	return err
+}
+
+type blobLockClient struct {
+	*BlobClient
+}
+
+func (c blobLockClient) acquire(ctx context.Context, duration int32) error {
+	_, err := c.AcquireLease(ctx, duration, nil)
+	return err
+}
+
+func (c blobLockClient) renew(ctx context.Context) error {
+	_, err := c.RenewLease(ctx, nil)
+	return err
+}
+
+func (c blobLockClient) release(ctx context.Context) error {
+	_, err := c.ReleaseLease(ctx, nil)
+	return err
+}
+
+func (c blobLockClient) leaseID() *string {
+	return c.LeaseID()
+}
+
+type containerLockClient struct {
+	*ContainerClient
+}
+
+func (c containerLockClient) acquire(ctx context.Context, duration int32) error {
+	_, err := c.AcquireLease(ctx, duration, nil)
+	return err
+}
+
+func (c containerLockClient) renew(ctx context.Context) error {
+	_, err := c.RenewLease(ctx, nil)
+	return err
+}
+
+func (c containerLockClient) release(ctx context.Context) error {
+	_, err := c.ReleaseLease(ctx, nil)
+	return err
+}
+
+func (c containerLockClient) leaseID() *string {
+	return c.LeaseID()
+}
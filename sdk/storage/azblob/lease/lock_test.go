@@ -0,0 +1,246 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package lease
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLeaseDuration is the duration of every lease of leaseTransport, so that tests don't have to wait for
+// the real lease durations.
+const fakeLeaseDuration = 100 * time.Millisecond
+
+// leaseTransport is an in-memory lease of a single blob or container.
+type leaseTransport struct {
+	mu      sync.Mutex
+	holder  string
+	expires time.Time
+
+	renewals int
+	releases int
+	// failRenewals makes renewals fail with a transient error
+	failRenewals bool
+}
+
+func (f *leaseTransport) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	header := http.Header{}
+	respond := func(status int, code string) (*http.Response, error) {
+		if code != "" {
+			header.Set("x-ms-error-code", code)
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: http.NoBody, Request: req}, nil
+	}
+	if time.Now().After(f.expires) {
+		f.holder = ""
+	}
+
+	switch req.Header["x-ms-lease-action"][0] {
+	case "acquire":
+		id := req.Header["x-ms-proposed-lease-id"][0]
+		if f.holder != "" && f.holder != id {
+			return respond(http.StatusConflict, "LeaseAlreadyPresent")
+		}
+		f.holder, f.expires = id, time.Now().Add(fakeLeaseDuration)
+		header.Set("x-ms-lease-id", id)
+		return respond(http.StatusCreated, "")
+	case "renew":
+		if f.failRenewals {
+			return respond(http.StatusInternalServerError, "InternalError")
+		}
+		if f.holder != req.Header["x-ms-lease-id"][0] {
+			return respond(http.StatusConflict, "LeaseIdMismatchWithLeaseOperation")
+		}
+		f.renewals++
+		f.expires = time.Now().Add(fakeLeaseDuration)
+		return respond(http.StatusOK, "")
+	case "release":
+		if f.holder != req.Header["x-ms-lease-id"][0] {
+			return respond(http.StatusConflict, "LeaseIdMismatchWithLeaseOperation")
+		}
+		f.releases++
+		f.holder = ""
+		return respond(http.StatusOK, "")
+	}
+	return respond(http.StatusBadRequest, "UnsupportedHttpVerb")
+}
+
+func (f *leaseTransport) counts() (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.renewals, f.releases
+}
+
+func (f *leaseTransport) takeOver(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.holder, f.expires = id, time.Now().Add(time.Minute)
+}
+
+func (f *leaseTransport) setFailRenewals(fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failRenewals = fail
+}
+
+var testClientOptions = azcore.ClientOptions{Retry: policy.RetryOptions{MaxRetries: -1}}
+
+// testLockOptions matches the lease duration of leaseTransport, bypassing the validation of LockOptions
+// which only allows real lease durations.
+var testLockOptions = LockOptions{Duration: fakeLeaseDuration, RenewInterval: 20 * time.Millisecond, RetryInterval: 10 * time.Millisecond}
+
+func newTestBlobLock(t *testing.T, transport *leaseTransport, leaseID string) *Lock {
+	options := testClientOptions
+	options.Transport = transport
+	blobClient, err := blob.NewClientWithNoCredential("https://account.blob.core.windows.net/c/leader", &blob.ClientOptions{ClientOptions: options})
+	require.NoError(t, err)
+	leaseClient, err := NewBlobClient(blobClient, &BlobClientOptions{LeaseID: to.Ptr(leaseID)})
+	require.NoError(t, err)
+	return newLock(blobLockClient{leaseClient}, testLockOptions)
+}
+
+func TestLockAcquireAndClose(t *testing.T) {
+	transport := &leaseTransport{}
+	first := newTestBlobLock(t, transport, "00000000-0000-0000-0000-000000000001")
+	second := newTestBlobLock(t, transport, "00000000-0000-0000-0000-000000000002")
+
+	held, err := first.TryAcquire(context.Background())
+	require.NoError(t, err)
+	_, err = first.TryAcquire(context.Background())
+	require.Error(t, err)
+	_, err = second.TryAcquire(context.Background())
+	require.True(t, bloberror.HasCode(err, bloberror.LeaseAlreadyPresent))
+	require.Equal(t, "00000000-0000-0000-0000-000000000001", *first.AccessConditions().LeaseID)
+
+	acquired := make(chan context.Context)
+	go func() {
+		held, err := second.Acquire(context.Background())
+		require.NoError(t, err)
+		acquired <- held
+	}()
+
+	// the lease is renewed past its duration, so the second lock keeps waiting
+	require.Eventually(t, func() bool {
+		renewals, _ := transport.counts()
+		return renewals >= 10
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, held.Err())
+	select {
+	case <-acquired:
+		require.Fail(t, "the lock was acquired twice")
+	default:
+	}
+
+	require.NoError(t, first.Close(context.Background()))
+	require.ErrorIs(t, context.Cause(held), context.Canceled)
+	secondHeld := <-acquired
+	require.NoError(t, secondHeld.Err())
+	_, releases := transport.counts()
+	require.Equal(t, 1, releases)
+
+	require.NoError(t, second.Close(context.Background()))
+	require.NoError(t, second.Close(context.Background()))
+	require.Error(t, secondHeld.Err())
+
+	// a lock can be acquired again after it's closed
+	held, err = first.TryAcquire(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, first.Close(context.Background()))
+	require.Error(t, held.Err())
+}
+
+func TestLockAcquireCanceled(t *testing.T) {
+	transport := &leaseTransport{}
+	transport.takeOver("someone else")
+	lock := newTestBlobLock(t, transport, "00000000-0000-0000-0000-000000000001")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := lock.Acquire(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLockLost(t *testing.T) {
+	transport := &leaseTransport{}
+	options := testClientOptions
+	options.Transport = transport
+	containerClient, err := container.NewClientWithNoCredential("https://account.blob.core.windows.net/c", &container.ClientOptions{ClientOptions: options})
+	require.NoError(t, err)
+	leaseClient, err := NewContainerClient(containerClient, nil)
+	require.NoError(t, err)
+	lock := newLock(containerLockClient{leaseClient}, testLockOptions)
+
+	held, err := lock.TryAcquire(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, *leaseClient.LeaseID(), lock.LeaseID())
+
+	// the lease is broken and acquired by someone else
+	transport.takeOver("someone else")
+	select {
+	case <-held.Done():
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the loss of the lease wasn't detected")
+	}
+	require.True(t, bloberror.HasCode(context.Cause(held), bloberror.LeaseIDMismatchWithLeaseOperation))
+
+	// there's nothing to release
+	require.NoError(t, lock.Close(context.Background()))
+	_, releases := transport.counts()
+	require.Zero(t, releases)
+}
+
+func TestLockExpiresWhenRenewalsFail(t *testing.T) {
+	transport := &leaseTransport{}
+	lock := newTestBlobLock(t, transport, "00000000-0000-0000-0000-000000000001")
+
+	held, err := lock.TryAcquire(context.Background())
+	require.NoError(t, err)
+	transport.setFailRenewals(true)
+	select {
+	case <-held.Done():
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the expiry of the lease wasn't detected")
+	}
+	require.ErrorContains(t, context.Cause(held), "expired")
+
+	// the lock can be acquired again once the lease can be renewed
+	transport.setFailRenewals(false)
+	held, err = lock.Acquire(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, lock.Close(context.Background()))
+	require.Error(t, held.Err())
+}
+
+func TestLockOptionsValidation(t *testing.T) {
+	options, err := (*LockOptions)(nil).format()
+	require.NoError(t, err)
+	require.Equal(t, LockOptions{Duration: time.Minute, RenewInterval: 20 * time.Second, RetryInterval: 20 * time.Second}, options)
+
+	for _, o := range []LockOptions{
+		{Duration: 10 * time.Second},
+		{Duration: 2 * time.Minute},
+		{Duration: 30 * time.Second, RenewInterval: 30 * time.Second},
+		{RetryInterval: -time.Second},
+	} {
+		_, err := o.format()
+		require.Error(t, err)
+	}
+}
@@ -7,6 +7,9 @@
 package lease
 
 import (
+	"errors"
+	"time"
+
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/exported"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/generated"
 )
@@ -180,3 +183,45 @@ func leasePeriodPointer(period int32) *int32 {
 		return nil
 	}
 }
+
+// LockOptions contains the optional parameters for the BlobClient.NewLock and ContainerClient.NewLock methods.
+type LockOptions struct {
+	// Duration is the duration of the lease, between 15 and 60 seconds. It bounds how long the lock remains held
+	// after its holder stops renewing it, e.g. because the process crashed. The default is 60 seconds.
+	Duration time.Duration
+
+	// RenewInterval is how often the lease is renewed. A renewal that fails with a transient error is retried
+	// at the next interval until the lease expires. The default is a third of Duration.
+	RenewInterval time.Duration
+
+	// RetryInterval is how often Lock.Acquire tries to acquire a lease that's held by another client.
+	// The default is RenewInterval.
+	RetryInterval time.Duration
+}
+
+func (o *LockOptions) format() (LockOptions, error) {
+	options := LockOptions{}
+	if o != nil {
+		options = *o
+	}
+	if options.Duration == 0 {
+		options.Duration = 60 * time.Second
+	}
+	if options.Duration < 15*time.Second || options.Duration > 60*time.Second {
+		return LockOptions{}, errors.New("Duration must be between 15 and 60 seconds")
+	}
+	options.Duration = options.Duration.Truncate(time.Second)
+	if options.RenewInterval == 0 {
+		options.RenewInterval = options.Duration / 3
+	}
+	if options.RenewInterval < 0 || options.RenewInterval >= options.Duration {
+		return LockOptions{}, errors.New("RenewInterval must be shorter than Duration")
+	}
+	if options.RetryInterval == 0 {
+		options.RetryInterval = options.RenewInterval
+	}
+	if options.RetryInterval < 0 {
+		return LockOptions{}, errors.New("RetryInterval must not be negative")
+	}
+	return options, nil
+}
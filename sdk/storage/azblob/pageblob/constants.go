@@ -11,6 +11,9 @@ import "github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/generated"
 const (
 	// PageBytes indicates the number of bytes in a page (512).
 	PageBytes = 512
+
+	// MaxUploadPagesBytes indicates the maximum number of bytes that can be sent in a call to UploadPages.
+	MaxUploadPagesBytes = 4 * 1024 * 1024 // 4MB
 )
 
 // CopyStatusType defines values for CopyStatusType
@@ -9,6 +9,7 @@ package pageblob
 import (
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/shared"
 
@@ -331,3 +332,95 @@ func (o *CopyIncrementalOptions) format() (*generated.PageBlobClientCopyIncremen
 }
 
 // ---------------------------------------------------------------------------------------------------------------------
+
+// DownloadSparseFileOptions contains the optional parameters for the Client.DownloadSparseFile method.
+type DownloadSparseFileOptions struct {
+	// BlockSize specifies the maximum size of each parallel download; the default size is blob.DefaultDownloadBlockSize.
+	BlockSize int64
+
+	// Concurrency indicates the maximum number of ranges to download in parallel (0=default).
+	Concurrency uint16
+
+	// Progress is a function that is invoked periodically as bytes are received.
+	Progress func(bytesTransferred int64)
+
+	// AccessConditions indicates the access conditions used when listing and downloading the page ranges.
+	// Unless an IfMatch condition is specified, downloads are conditioned on the ETag returned when listing the page ranges.
+	AccessConditions *blob.AccessConditions
+
+	CPKInfo *blob.CPKInfo
+
+	CPKScopeInfo *blob.CPKScopeInfo
+
+	// RetryReaderOptionsPerBlock is used when downloading each range.
+	RetryReaderOptionsPerBlock blob.RetryReaderOptions
+}
+
+func (o *DownloadSparseFileOptions) format() ApplySnapshotDiffOptions {
+	if o == nil {
+		return ApplySnapshotDiffOptions{}
+	}
+	return ApplySnapshotDiffOptions(*o)
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// SnapshotDiff describes the pages that differ between a page blob, or one of its snapshots, and a previous snapshot.
+// It's returned by Client.GetSnapshotDiff and applied to a local copy of the previous snapshot with Client.ApplySnapshotDiff.
+type SnapshotDiff struct {
+	// Size is the size of the page blob in bytes.
+	Size int64
+
+	// ETag is the ETag of the page blob when the diff was computed.
+	ETag *azcore.ETag
+
+	// Changed contains the ranges of pages that were written since the previous snapshot.
+	Changed []blob.HTTPRange
+
+	// Cleared contains the ranges of pages that were cleared since the previous snapshot.
+	Cleared []blob.HTTPRange
+}
+
+// ApplySnapshotDiffOptions contains the optional parameters for the Client.ApplySnapshotDiff method.
+type ApplySnapshotDiffOptions struct {
+	// BlockSize specifies the maximum size of each parallel download; the default size is blob.DefaultDownloadBlockSize.
+	BlockSize int64
+
+	// Concurrency indicates the maximum number of ranges to download in parallel (0=default).
+	Concurrency uint16
+
+	// Progress is a function that is invoked periodically as bytes are received.
+	Progress func(bytesTransferred int64)
+
+	// AccessConditions indicates the access conditions used when downloading the changed pages.
+	// Unless an IfMatch condition is specified, downloads are conditioned on SnapshotDiff.ETag.
+	AccessConditions *blob.AccessConditions
+
+	CPKInfo *blob.CPKInfo
+
+	CPKScopeInfo *blob.CPKScopeInfo
+
+	// RetryReaderOptionsPerBlock is used when downloading each range.
+	RetryReaderOptionsPerBlock blob.RetryReaderOptions
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// UploadSparseFileOptions contains the optional parameters for the Client.UploadSparseFile method.
+type UploadSparseFileOptions struct {
+	// BlockSize specifies the size of the chunks read from the file and scanned for zero pages.
+	// It must be a multiple of PageBytes; the default and maximum is MaxUploadPagesBytes.
+	BlockSize int64
+
+	// Concurrency indicates the maximum number of chunks to upload in parallel (0=default).
+	Concurrency uint16
+
+	// Progress is a function that is invoked periodically as bytes are sent, including skipped zero pages.
+	Progress func(bytesTransferred int64)
+
+	// CreateOptions is used to create the page blob; CPKInfo and CPKScopeInfo are also used for each UploadPages call.
+	CreateOptions *CreateOptions
+
+	// TransactionalValidation specifies the transfer validation type to use for each UploadPages call.
+	TransactionalValidation blob.TransferValidationType
+}
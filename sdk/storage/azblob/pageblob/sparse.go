@@ -0,0 +1,303 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package pageblob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/shared"
+)
+
+// DownloadSparseFile downloads the page blob to a local file, transferring only its valid page ranges in parallel.
+// The file is truncated and resized to the size of the blob, so pages that were never written or were cleared
+// are left as holes on file systems that support sparse files. It returns the number of bytes downloaded.
+// To back up a page blob incrementally, download a snapshot with DownloadSparseFile and then bring the local copy
+// up to date with GetSnapshotDiff and ApplySnapshotDiff.
+func (pb *Client) DownloadSparseFile(ctx context.Context, file *os.File, o *DownloadSparseFileOptions) (int64, error) {
+	options := o.format()
+	if options.BlockSize < 0 {
+		return 0, errors.New("BlockSize must not be negative")
+	}
+
+	diff := SnapshotDiff{}
+	pager := pb.NewGetPageRangesPager(&GetPageRangesOptions{AccessConditions: options.AccessConditions})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if diff.ETag == nil {
+			diff.ETag = page.ETag
+		}
+		if page.BlobContentLength != nil {
+			diff.Size = *page.BlobContentLength
+		}
+		for _, r := range page.PageRange {
+			diff.Changed = append(diff.Changed, toHTTPRange(r.Start, r.End))
+		}
+	}
+
+	// discard the existing content so that the pages which aren't downloaded read as zeros
+	if err := file.Truncate(0); err != nil {
+		return 0, err
+	}
+	return pb.ApplySnapshotDiff(ctx, file, diff, &options)
+}
+
+// GetSnapshotDiff returns the page ranges that changed and were cleared between the previous snapshot specified
+// with GetPageRangesDiffOptions.PrevSnapshot or GetPageRangesDiffOptions.PrevSnapshotURL and this page blob.
+// To diff two snapshots, call GetSnapshotDiff on a client created with WithSnapshot for the newer one.
+func (pb *Client) GetSnapshotDiff(ctx context.Context, o *GetPageRangesDiffOptions) (SnapshotDiff, error) {
+	if o == nil || (o.PrevSnapshot == nil && o.PrevSnapshotURL == nil) {
+		return SnapshotDiff{}, errors.New("PrevSnapshot or PrevSnapshotURL must be specified")
+	}
+	options := *o
+	options.Marker = nil
+
+	diff := SnapshotDiff{}
+	pager := pb.NewGetPageRangesDiffPager(&options)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return SnapshotDiff{}, err
+		}
+		if diff.ETag == nil {
+			diff.ETag = page.ETag
+		}
+		if page.BlobContentLength != nil {
+			diff.Size = *page.BlobContentLength
+		}
+		for _, r := range page.PageRange {
+			diff.Changed = append(diff.Changed, toHTTPRange(r.Start, r.End))
+		}
+		for _, r := range page.ClearRange {
+			diff.Cleared = append(diff.Cleared, toHTTPRange(r.Start, r.End))
+		}
+	}
+	return diff, nil
+}
+
+// ApplySnapshotDiff brings a local copy of the previous snapshot up to date with the page blob.
+// The file is resized to SnapshotDiff.Size, cleared pages are zeroed and changed pages are downloaded in parallel.
+// It returns the number of bytes downloaded.
+func (pb *Client) ApplySnapshotDiff(ctx context.Context, file *os.File, diff SnapshotDiff, o *ApplySnapshotDiffOptions) (int64, error) {
+	options := ApplySnapshotDiffOptions{}
+	if o != nil {
+		options = *o
+	}
+	if options.BlockSize == 0 {
+		options.BlockSize = blob.DefaultDownloadBlockSize
+	}
+	if options.BlockSize < 0 {
+		return 0, errors.New("BlockSize must not be negative")
+	}
+
+	if err := file.Truncate(diff.Size); err != nil {
+		return 0, err
+	}
+
+	accessConditions := withIfMatch(options.AccessConditions, diff.ETag)
+	changed := splitRanges(diff.Changed, diff.Size, options.BlockSize)
+	cleared := splitRanges(diff.Cleared, diff.Size, options.BlockSize)
+	var zeros []byte
+	if len(cleared) > 0 {
+		zeros = make([]byte, options.BlockSize)
+	}
+
+	downloaded := int64(0)
+	progress := int64(0)
+	progressLock := &sync.Mutex{}
+
+	err := shared.DoBatchTransfer(ctx, &shared.BatchTransferOptions{
+		OperationName: "ApplySnapshotDiff",
+		TransferSize:  int64(len(changed) + len(cleared)),
+		ChunkSize:     1,
+		NumChunks:     uint64(len(changed) + len(cleared)),
+		Concurrency:   options.Concurrency,
+		Operation: func(ctx context.Context, i int64, _ int64) error {
+			if i >= int64(len(changed)) {
+				r := cleared[i-int64(len(changed))]
+				_, err := file.WriteAt(zeros[:r.Count], r.Offset)
+				return err
+			}
+
+			r := changed[i]
+			dr, err := pb.DownloadStream(ctx, &blob.DownloadStreamOptions{
+				Range:            r,
+				AccessConditions: accessConditions,
+				CPKInfo:          options.CPKInfo,
+				CPKScopeInfo:     options.CPKScopeInfo,
+			})
+			if err != nil {
+				return err
+			}
+			var body io.ReadCloser = dr.NewRetryReader(ctx, &options.RetryReaderOptionsPerBlock)
+			if options.Progress != nil {
+				rangeProgress := int64(0)
+				body = streaming.NewResponseProgress(
+					body,
+					func(bytesTransferred int64) {
+						delta := bytesTransferred - rangeProgress
+						rangeProgress = bytesTransferred
+						progressLock.Lock()
+						progress += delta
+						options.Progress(progress)
+						progressLock.Unlock()
+					})
+			}
+			n, err := io.Copy(shared.NewSectionWriter(file, r.Offset, r.Count), body)
+			if err != nil {
+				return err
+			}
+			atomic.AddInt64(&downloaded, n)
+			return body.Close()
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return downloaded, nil
+}
+
+// UploadSparseFile creates a page blob with the content of a local disk image, e.g. a fixed-size VHD,
+// uploading chunks of the file in parallel. Pages that contain only zeros aren't uploaded, since the pages of
+// a newly created page blob read as zeros. The size of the file must be a multiple of PageBytes.
+// An existing blob is overwritten. It returns the number of bytes uploaded.
+func (pb *Client) UploadSparseFile(ctx context.Context, file *os.File, o *UploadSparseFileOptions) (int64, error) {
+	options := UploadSparseFileOptions{}
+	if o != nil {
+		options = *o
+	}
+	if options.BlockSize == 0 {
+		options.BlockSize = MaxUploadPagesBytes
+	}
+	if options.BlockSize < 0 || options.BlockSize > MaxUploadPagesBytes || options.BlockSize%PageBytes != 0 {
+		return 0, errors.New("BlockSize must be a multiple of PageBytes no larger than MaxUploadPagesBytes")
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := stat.Size()
+	if size%PageBytes != 0 {
+		return 0, errors.New("the size of the file must be a multiple of PageBytes")
+	}
+
+	if _, err := pb.Create(ctx, size, options.CreateOptions); err != nil {
+		return 0, err
+	}
+	if size == 0 {
+		return 0, nil
+	}
+
+	uploadOptions := &UploadPagesOptions{TransactionalValidation: options.TransactionalValidation}
+	if options.CreateOptions != nil {
+		uploadOptions.CPKInfo = options.CreateOptions.CPKInfo
+		uploadOptions.CPKScopeInfo = options.CreateOptions.CPKScopeInfo
+	}
+
+	uploaded := int64(0)
+	progress := int64(0)
+	progressLock := &sync.Mutex{}
+
+	err = shared.DoBatchTransfer(ctx, &shared.BatchTransferOptions{
+		OperationName: "UploadSparseFile",
+		TransferSize:  size,
+		ChunkSize:     options.BlockSize,
+		NumChunks:     uint64(((size - 1) / options.BlockSize) + 1),
+		Concurrency:   options.Concurrency,
+		Operation: func(ctx context.Context, offset int64, count int64) error {
+			buf := make([]byte, count)
+			if _, err := file.ReadAt(buf, offset); err != nil && err != io.EOF {
+				return err
+			}
+			for _, r := range nonZeroPages(buf) {
+				body := streaming.NopCloser(bytes.NewReader(buf[r.Offset : r.Offset+r.Count]))
+				if _, err := pb.UploadPages(ctx, body, blob.HTTPRange{Offset: offset + r.Offset, Count: r.Count}, uploadOptions); err != nil {
+					return err
+				}
+				atomic.AddInt64(&uploaded, r.Count)
+			}
+			if options.Progress != nil {
+				progressLock.Lock()
+				progress += count
+				options.Progress(progress)
+				progressLock.Unlock()
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return uploaded, nil
+}
+
+// toHTTPRange converts an inclusive page range returned by the service.
+func toHTTPRange(start, end *int64) blob.HTTPRange {
+	if start == nil || end == nil {
+		return blob.HTTPRange{}
+	}
+	return blob.HTTPRange{Offset: *start, Count: *end - *start + 1}
+}
+
+// splitRanges splits ranges into ranges no larger than blockSize, dropping anything beyond size.
+func splitRanges(ranges []blob.HTTPRange, size, blockSize int64) []blob.HTTPRange {
+	var split []blob.HTTPRange
+	for _, r := range ranges {
+		end := min(r.Offset+r.Count, size)
+		for offset := r.Offset; offset < end; offset += blockSize {
+			split = append(split, blob.HTTPRange{Offset: offset, Count: min(blockSize, end-offset)})
+		}
+	}
+	return split
+}
+
+// nonZeroPages returns the runs of pages in buf that contain a non-zero byte.
+func nonZeroPages(buf []byte) []blob.HTTPRange {
+	var runs []blob.HTTPRange
+	zero := make([]byte, PageBytes)
+	for offset := int64(0); offset < int64(len(buf)); offset += PageBytes {
+		if bytes.Equal(buf[offset:offset+PageBytes], zero) {
+			continue
+		}
+		if n := len(runs); n > 0 && runs[n-1].Offset+runs[n-1].Count == offset {
+			runs[n-1].Count += PageBytes
+		} else {
+			runs = append(runs, blob.HTTPRange{Offset: offset, Count: PageBytes})
+		}
+	}
+	return runs
+}
+
+// withIfMatch returns access conditions that include an IfMatch condition on etag, unless one is already specified.
+func withIfMatch(accessConditions *blob.AccessConditions, etag *azcore.ETag) *blob.AccessConditions {
+	if etag == nil || (accessConditions != nil && accessConditions.ModifiedAccessConditions != nil && accessConditions.ModifiedAccessConditions.IfMatch != nil) {
+		return accessConditions
+	}
+	conditions := blob.AccessConditions{}
+	modified := blob.ModifiedAccessConditions{}
+	if accessConditions != nil {
+		conditions = *accessConditions
+		if accessConditions.ModifiedAccessConditions != nil {
+			modified = *accessConditions.ModifiedAccessConditions
+		}
+	}
+	modified.IfMatch = etag
+	conditions.ModifiedAccessConditions = &modified
+	return &conditions
+}
@@ -0,0 +1,270 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package pageblob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/stretchr/testify/require"
+)
+
+// pageTransport is an in-memory page blob with snapshots. Pages are tracked individually so that
+// page ranges and diffs can be computed; a nil page is one that was never written or was cleared.
+type pageTransport struct {
+	mu        sync.Mutex
+	pages     [][]byte
+	snapshots map[string][][]byte
+	etag      int
+
+	uploads []string
+}
+
+func (f *pageTransport) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q := req.URL.Query()
+	header := http.Header{}
+	header.Set("ETag", fmt.Sprintf(`"0x%d"`, f.etag))
+	respond := func(status int, body []byte) (*http.Response, error) {
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(bytes.NewReader(body)), Request: req}, nil
+	}
+	parseRange := func() (int, int) {
+		var start, end int
+		_, _ = fmt.Sscanf(req.Header["x-ms-range"][0], "bytes=%d-%d", &start, &end)
+		return start, end
+	}
+
+	pages := f.pages
+	if s := q.Get("snapshot"); s != "" {
+		pages = f.snapshots[s]
+	}
+
+	switch {
+	case req.Method == http.MethodPut && q.Get("comp") == "":
+		size, _ := strconv.Atoi(req.Header["x-ms-blob-content-length"][0])
+		f.pages = make([][]byte, size/PageBytes)
+		f.etag++
+		return respond(http.StatusCreated, nil)
+	case req.Method == http.MethodPut && q.Get("comp") == "page":
+		start, end := parseRange()
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		f.uploads = append(f.uploads, fmt.Sprintf("%d-%d", start, end))
+		for i := start / PageBytes; i <= end/PageBytes; i++ {
+			off := i*PageBytes - start
+			f.pages[i] = data[off : off+PageBytes]
+		}
+		f.etag++
+		return respond(http.StatusCreated, nil)
+	case req.Method == http.MethodGet && q.Get("comp") == "pagelist":
+		var prev [][]byte
+		if p := q.Get("prevsnapshot"); p != "" {
+			prev = f.snapshots[p]
+		}
+		var list strings.Builder
+		for i := 0; i < len(pages); {
+			changed := func(i int) bool {
+				if prev == nil {
+					return pages[i] != nil
+				}
+				return i >= len(prev) || !bytes.Equal(pages[i], prev[i])
+			}
+			if !changed(i) {
+				i++
+				continue
+			}
+			j := i
+			for j < len(pages) && changed(j) && (pages[j] == nil) == (pages[i] == nil) {
+				j++
+			}
+			tag := "PageRange"
+			if pages[i] == nil {
+				tag = "ClearRange"
+			}
+			fmt.Fprintf(&list, "<%s><Start>%d</Start><End>%d</End></%s>", tag, i*PageBytes, j*PageBytes-1, tag)
+			i = j
+		}
+		header.Set("x-ms-blob-content-length", strconv.Itoa(len(pages)*PageBytes))
+		return respond(http.StatusOK, []byte(`<?xml version="1.0" encoding="utf-8"?><PageList>`+list.String()+`</PageList>`))
+	case req.Method == http.MethodGet:
+		if m := req.Header["If-Match"]; len(m) == 0 || m[0] != header.Get("ETag") {
+			header.Set("x-ms-error-code", "ConditionNotMet")
+			return respond(http.StatusPreconditionFailed, nil)
+		}
+		start, end := parseRange()
+		var data []byte
+		for i := start / PageBytes; i <= end/PageBytes; i++ {
+			if pages[i] == nil {
+				data = append(data, make([]byte, PageBytes)...)
+			} else {
+				data = append(data, pages[i]...)
+			}
+		}
+		data = data[start%PageBytes : start%PageBytes+end-start+1]
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(pages)*PageBytes))
+		return respond(http.StatusPartialContent, data)
+	}
+	return respond(http.StatusBadRequest, nil)
+}
+
+func (f *pageTransport) snapshot(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.snapshots[name] = append([][]byte(nil), f.pages...)
+}
+
+func (f *pageTransport) write(offset int, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := 0; i < len(data); i += PageBytes {
+		f.pages[(offset+i)/PageBytes] = data[i : i+PageBytes]
+	}
+	f.etag++
+}
+
+func (f *pageTransport) clear(offset, count int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := offset / PageBytes; i < (offset+count)/PageBytes; i++ {
+		f.pages[i] = nil
+	}
+	f.etag++
+}
+
+func (f *pageTransport) content() []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var data []byte
+	for _, p := range f.pages {
+		if p == nil {
+			p = make([]byte, PageBytes)
+		}
+		data = append(data, p...)
+	}
+	return data
+}
+
+func newSparseTestClient(t *testing.T, transport *pageTransport) *Client {
+	client, err := NewClientWithNoCredential("https://account.blob.core.windows.net/c/disk.vhd", &ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: transport, Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func page(b byte, n int) []byte {
+	return bytes.Repeat([]byte{b}, n*PageBytes)
+}
+
+func TestUploadSparseFileSkipsZeroPages(t *testing.T) {
+	image := bytes.Join([][]byte{page(1, 2), page(0, 3), page(2, 1), page(0, 1), page(3, 4)}, nil)
+	path := filepath.Join(t.TempDir(), "disk.vhd")
+	require.NoError(t, os.WriteFile(path, image, 0o600))
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	transport := &pageTransport{}
+	var progress int64
+	uploaded, err := newSparseTestClient(t, transport).UploadSparseFile(context.Background(), file, &UploadSparseFileOptions{
+		BlockSize:   4 * PageBytes,
+		Concurrency: 2,
+		Progress:    func(n int64) { progress = n },
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(7*PageBytes), uploaded)
+	require.Equal(t, int64(len(image)), progress)
+	require.Equal(t, image, transport.content())
+	// the chunks are [1 1 0 0] [0 2 0 3] [3 3 3]
+	require.ElementsMatch(t, []string{"0-1023", "2560-3071", "3584-4095", "4096-5631"}, transport.uploads)
+
+	_, err = newSparseTestClient(t, transport).UploadSparseFile(context.Background(), file, &UploadSparseFileOptions{BlockSize: 1000})
+	require.Error(t, err)
+}
+
+func TestDownloadSparseFileAndApplySnapshotDiff(t *testing.T) {
+	ctx := context.Background()
+	transport := &pageTransport{pages: make([][]byte, 16), snapshots: map[string][][]byte{}}
+	transport.write(0, page(1, 3))
+	transport.write(8*PageBytes, page(2, 2))
+	transport.snapshot("s1")
+	client := newSparseTestClient(t, transport)
+
+	s1, err := client.WithSnapshot("s1")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "backup.vhd")
+	require.NoError(t, os.WriteFile(path, []byte("stale content"), 0o600))
+	file, err := os.OpenFile(path, os.O_RDWR, 0o600)
+	require.NoError(t, err)
+	defer file.Close()
+
+	downloaded, err := s1.DownloadSparseFile(ctx, file, &DownloadSparseFileOptions{BlockSize: 2 * PageBytes, Concurrency: 3})
+	require.NoError(t, err)
+	require.Equal(t, int64(5*PageBytes), downloaded)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, transport.content(), data)
+
+	// change the blob and take another snapshot; the base blob then moves on again
+	transport.write(2*PageBytes, page(3, 2))
+	transport.clear(8*PageBytes, PageBytes)
+	transport.write(12*PageBytes, page(4, 1))
+	transport.snapshot("s2")
+	expected := transport.content()
+	transport.write(0, page(5, 1))
+
+	s2, err := client.WithSnapshot("s2")
+	require.NoError(t, err)
+	diff, err := s2.GetSnapshotDiff(ctx, &GetPageRangesDiffOptions{PrevSnapshot: to.Ptr("s1")})
+	require.NoError(t, err)
+	require.Equal(t, int64(16*PageBytes), diff.Size)
+	require.Len(t, diff.Changed, 2)
+	require.Len(t, diff.Cleared, 1)
+
+	downloaded, err = s2.ApplySnapshotDiff(ctx, file, diff, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(3*PageBytes), downloaded)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+
+	// the base blob has changed since the diff was computed
+	diff, err = client.GetSnapshotDiff(ctx, &GetPageRangesDiffOptions{PrevSnapshot: to.Ptr("s2")})
+	require.NoError(t, err)
+	transport.write(0, page(6, 1))
+	_, err = client.ApplySnapshotDiff(ctx, file, diff, nil)
+	require.Error(t, err)
+
+	_, err = client.GetSnapshotDiff(ctx, nil)
+	require.Error(t, err)
+
+	// a negative block size is rejected before the file is changed
+	_, err = s2.ApplySnapshotDiff(ctx, file, SnapshotDiff{Size: PageBytes}, &ApplySnapshotDiffOptions{BlockSize: -1})
+	require.Error(t, err)
+	_, err = s1.DownloadSparseFile(ctx, file, &DownloadSparseFileOptions{BlockSize: -1})
+	require.Error(t, err)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+}
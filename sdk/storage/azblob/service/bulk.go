@@ -0,0 +1,152 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/exported"
+)
+
+// BulkDelete deletes the blobs in the account selected by source. The names in source start with the name of the
+// container followed by a slash. The blobs are deleted in batches of up to 256 blobs, which are submitted
+// concurrently, and the blobs whose deletion failed with a transient error are retried. The returned BulkResult
+// contains the blobs whose deletion failed; an error is returned only if listing the blobs failed or ctx was canceled.
+func (s *Client) BulkDelete(ctx context.Context, source BulkSource, o *BulkDeleteOptions) (BulkResult, error) {
+	options := BulkDeleteOptions{}
+	if o != nil {
+		options = *o
+	}
+	deleteOptions := &BatchDeleteOptions{DeleteOptions: blob.DeleteOptions{DeleteSnapshots: options.DeleteSnapshots}}
+	return s.bulk(ctx, source, options.BulkOptions, func(bb *BatchBuilder, target exported.BulkTarget) error {
+		return bb.Delete(target.ContainerName, target.BlobName, deleteOptions)
+	})
+}
+
+// BulkSetTier sets the access tier of the blobs in the account selected by source. The names in source start with
+// the name of the container followed by a slash. The blobs are updated in batches of up to 256 blobs, which are
+// submitted concurrently, and the blobs whose update failed with a transient error are retried. The returned
+// BulkResult contains the blobs whose update failed; an error is returned only if listing the blobs failed or ctx
+// was canceled.
+func (s *Client) BulkSetTier(ctx context.Context, source BulkSource, tier blob.AccessTier, o *BulkSetTierOptions) (BulkResult, error) {
+	options := BulkSetTierOptions{}
+	if o != nil {
+		options = *o
+	}
+	setTierOptions := &BatchSetTierOptions{SetTierOptions: blob.SetTierOptions{RehydratePriority: options.RehydratePriority}}
+	return s.bulk(ctx, source, options.BulkOptions, func(bb *BatchBuilder, target exported.BulkTarget) error {
+		return bb.SetTier(target.ContainerName, target.BlobName, tier, setTierOptions)
+	})
+}
+
+// BulkSetTags replaces the tags of the blobs in the account selected by source. The names in source start with
+// the name of the container followed by a slash. Batches don't support setting tags, so the tags of every blob are
+// set with a separate request and BulkOptions.BatchSize is ignored; up to BulkOptions.Concurrency requests are in
+// flight at the same time. The blobs whose update failed with a transient error are retried. The returned
+// BulkResult contains the blobs whose update failed; an error is returned only if listing the blobs failed or ctx
+// was canceled.
+func (s *Client) BulkSetTags(ctx context.Context, source BulkSource, tags map[string]string, o *BulkSetTagsOptions) (BulkResult, error) {
+	options := BulkSetTagsOptions{}
+	if o != nil {
+		options = *o
+	}
+	list, err := s.listBulkTargets(source)
+	if err != nil {
+		return BulkResult{}, err
+	}
+	options.BatchSize = 1
+	return exported.DoBulk(ctx, list, func(ctx context.Context, targets []exported.BulkTarget) ([]error, error) {
+		blobClient := s.NewContainerClient(targets[0].ContainerName).NewBlobClient(targets[0].BlobName)
+		_, err := blobClient.SetTags(ctx, tags, nil)
+		return []error{err}, nil
+	}, options.BulkOptions)
+}
+
+// bulk adds a sub-request to a batch with add for every blob selected by source and submits the batches.
+func (s *Client) bulk(ctx context.Context, source BulkSource, o BulkOptions, add func(bb *BatchBuilder, target exported.BulkTarget) error) (BulkResult, error) {
+	list, err := s.listBulkTargets(source)
+	if err != nil {
+		return BulkResult{}, err
+	}
+	return exported.DoBulk(ctx, list, func(ctx context.Context, targets []exported.BulkTarget) ([]error, error) {
+		bb, err := s.NewBatchBuilder()
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range targets {
+			if err := add(bb, target); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := s.SubmitBatch(ctx, bb, nil)
+		if err != nil {
+			return nil, err
+		}
+		return exported.BatchResponseErrors(resp.Responses, len(targets)), nil
+	}, o)
+}
+
+// listBulkTargets returns a function listing the blobs selected by source.
+func (s *Client) listBulkTargets(source BulkSource) (exported.BulkList, error) {
+	if err := source.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case source.Prefix != nil:
+		containerName, prefix, _ := strings.Cut(*source.Prefix, "/")
+		if containerName == "" {
+			return nil, fmt.Errorf("Prefix %q must start with the name of a container", *source.Prefix)
+		}
+		pager := s.NewContainerClient(containerName).NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: &prefix})
+		return func(ctx context.Context) ([]exported.BulkTarget, bool, error) {
+			resp, err := pager.NextPage(ctx)
+			if err != nil {
+				return nil, false, err
+			}
+			var targets []exported.BulkTarget
+			if resp.Segment != nil {
+				for _, item := range resp.Segment.BlobItems {
+					if item != nil && item.Name != nil {
+						targets = append(targets, exported.BulkTarget{ContainerName: containerName, BlobName: *item.Name})
+					}
+				}
+			}
+			return targets, pager.More(), nil
+		}, nil
+	case source.Where != nil:
+		var marker *string
+		return func(ctx context.Context) ([]exported.BulkTarget, bool, error) {
+			resp, err := s.FilterBlobs(ctx, *source.Where, &FilterBlobsOptions{Marker: marker})
+			if err != nil {
+				return nil, false, err
+			}
+			var targets []exported.BulkTarget
+			for _, item := range resp.Blobs {
+				if item != nil && item.ContainerName != nil && item.Name != nil {
+					targets = append(targets, exported.BulkTarget{ContainerName: *item.ContainerName, BlobName: *item.Name})
+				}
+			}
+			marker = resp.NextMarker
+			return targets, marker != nil && *marker != "", nil
+		}, nil
+	default:
+		targets := make([]exported.BulkTarget, len(source.BlobNames))
+		for i, name := range source.BlobNames {
+			containerName, blobName, ok := strings.Cut(name, "/")
+			if !ok || containerName == "" || blobName == "" {
+				return nil, fmt.Errorf("blob name %q must be the name of a container followed by a slash and the name of the blob", name)
+			}
+			targets[i] = exported.BulkTarget{ContainerName: containerName, BlobName: blobName}
+		}
+		return exported.ListBulkTargets(targets), nil
+	}
+}
@@ -0,0 +1,41 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/internal/exported"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkTargets(t *testing.T) {
+	client, err := NewClientWithNoCredential("https://account.blob.core.windows.net", nil)
+	require.NoError(t, err)
+
+	list, err := client.listBulkTargets(BulkSource{BlobNames: []string{"logs/2024/01.log", "images/cat.png"}})
+	require.NoError(t, err)
+	targets, more, err := list(context.Background())
+	require.NoError(t, err)
+	require.False(t, more)
+	require.Equal(t, []exported.BulkTarget{
+		{ContainerName: "logs", BlobName: "2024/01.log"},
+		{ContainerName: "images", BlobName: "cat.png"},
+	}, targets)
+
+	for _, source := range []BulkSource{
+		{BlobNames: []string{"no-container"}},
+		{BlobNames: []string{"logs/"}},
+		{Prefix: to.Ptr("/2024")},
+		{Prefix: to.Ptr("logs/"), Where: to.Ptr("a='b'")},
+	} {
+		_, err := client.listBulkTargets(source)
+		require.Error(t, err)
+	}
+}
@@ -61,3 +61,9 @@ type SubmitBatchResponse struct {
 
 // BatchResponseItem contains the response for the individual sub-requests.
 type BatchResponseItem = exported.BatchResponseItem
+
+// BulkResult summarizes the results of the Client.BulkDelete, Client.BulkSetTier and Client.BulkSetTags methods.
+type BulkResult = exported.BulkResult
+
+// BulkItemResult contains the result of a bulk operation on a single blob.
+type BulkItemResult = exported.BulkItemResult
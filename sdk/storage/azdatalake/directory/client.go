@@ -273,6 +273,15 @@ func (d *Client) Delete(ctx context.Context, options *DeleteOptions) (DeleteResp
 	}
 }
 
+// Undelete restores a soft-deleted directory and the paths it contained. deletionID identifies the deleted version
+// of the directory to restore; it's returned by filesystem.Client.NewListDeletedPathsPager.
+func (d *Client) Undelete(ctx context.Context, deletionID string, options *UndeleteOptions) (UndeleteResponse, error) {
+	opts := path.FormatUndeleteOptions(options, deletionID)
+	resp, err := generated.NewPathClient(d.BlobURL(), d.generatedDirClientWithDFS().InternalClient()).Undelete(ctx, opts)
+	err = exported.ConvertToDFSError(err)
+	return resp, err
+}
+
 // GetProperties gets the properties of a directory.
 func (d *Client) GetProperties(ctx context.Context, options *GetPropertiesOptions) (GetPropertiesResponse, error) {
 	opts := path.FormatGetPropertiesOptions(options)
@@ -10,6 +10,10 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/internal/path"
 )
 
+// DefaultTransferConcurrency is the default number of paths transferred in parallel by the UploadDirectory,
+// DownloadDirectory and CopyDirectory operations.
+const DefaultTransferConcurrency = 5
+
 // EncryptionAlgorithmType defines values for EncryptionAlgorithmType.
 type EncryptionAlgorithmType = path.EncryptionAlgorithmType
 
@@ -7,6 +7,10 @@
 package directory
 
 import (
+	"errors"
+	"fmt"
+	gopath "path"
+
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/internal/exported"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/internal/generated"
@@ -120,6 +124,9 @@ type RemoveAccessControlRecursiveOptions = accessControlRecursiveOptions
 // DeleteOptions contains the optional parameters when calling the Delete operation.
 type DeleteOptions = path.DeleteOptions
 
+// UndeleteOptions contains the optional parameters when calling the Undelete operation.
+type UndeleteOptions = path.UndeleteOptions
+
 // RenameOptions contains the optional parameters when calling the Rename operation.
 type RenameOptions = path.RenameOptions
 
@@ -170,3 +177,59 @@ type CPKScopeInfo path.CPKScopeInfo
 
 // ACLFailedEntry contains the failed ACL entry (response model).
 type ACLFailedEntry = path.ACLFailedEntry
+
+// TransferOptions contains the optional parameters common to the UploadDirectory, DownloadDirectory and
+// CopyDirectory operations.
+type TransferOptions struct {
+	// Concurrency is the maximum number of paths that are transferred in parallel. The default value is 5.
+	Concurrency int
+	// ChunkSize is the size of the chunks in which each file is transferred. The default value depends on the operation.
+	ChunkSize int64
+	// Include, when specified, restricts the transferred files to the files matching one of the patterns.
+	// Patterns use the syntax of path.Match and are matched against the path of the file relative to the root of
+	// the tree, or against the name of the file if the pattern doesn't contain a slash. Include doesn't apply to
+	// directories.
+	Include []string
+	// Exclude skips the files and directories matching one of the patterns, including the contents of the skipped
+	// directories. Patterns are matched the same way as Include.
+	Exclude []string
+	// OnResult, when specified, is called with the result of every path as soon as it's available.
+	// It's called from multiple goroutines, but never concurrently.
+	OnResult func(TransferredPath)
+}
+
+func (o *TransferOptions) format() (TransferOptions, error) {
+	options := *o
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultTransferConcurrency
+	}
+	if options.ChunkSize < 0 {
+		return TransferOptions{}, errors.New("ChunkSize must be greater than or equal to zero")
+	}
+	for _, pattern := range append(append([]string{}, options.Include...), options.Exclude...) {
+		if _, err := gopath.Match(pattern, ""); err != nil {
+			return TransferOptions{}, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return options, nil
+}
+
+// UploadDirectoryOptions contains the optional parameters for the Client.UploadDirectory method.
+type UploadDirectoryOptions struct {
+	TransferOptions
+}
+
+// DownloadDirectoryOptions contains the optional parameters for the Client.DownloadDirectory method.
+type DownloadDirectoryOptions struct {
+	TransferOptions
+}
+
+// CopyDirectoryOptions contains the optional parameters for the Client.CopyDirectory method.
+type CopyDirectoryOptions struct {
+	TransferOptions
+	// PreserveACL copies the permissions and the access control list of every path to the destination.
+	PreserveACL bool
+	// PreserveOwner copies the owner and the owning group of every path to the destination.
+	// Changing the owner of a path requires the caller to be a super-user.
+	PreserveOwner bool
+}
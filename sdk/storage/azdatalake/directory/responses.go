@@ -51,3 +51,40 @@ type CreateResponse = path.CreateResponse
 
 // DeleteResponse contains the response fields for the Delete operation.
 type DeleteResponse = path.DeleteResponse
+
+// UndeleteResponse contains the response fields for the Undelete operation.
+type UndeleteResponse = path.UndeleteResponse
+
+// TransferredPath contains the result of transferring a path with the UploadDirectory, DownloadDirectory or
+// CopyDirectory operations.
+type TransferredPath struct {
+	// Path is the path relative to the root of the tree, with slashes as separators.
+	Path string
+	// IsDirectory is true if the path is a directory.
+	IsDirectory bool
+	// Bytes is the number of bytes transferred.
+	Bytes int64
+	// Err is the error transferring the path, or nil if it was transferred.
+	Err error
+}
+
+// TransferResponse contains the result of the UploadDirectory, DownloadDirectory and CopyDirectory operations.
+type TransferResponse struct {
+	// Paths contains the result of every path that was transferred, or failed to be transferred, sorted by path.
+	Paths []TransferredPath
+	// Succeeded is the number of paths that were transferred.
+	Succeeded int
+	// Failed is the number of paths that failed to be transferred.
+	Failed int
+	// BytesTransferred is the number of bytes transferred.
+	BytesTransferred int64
+}
+
+// UploadDirectoryResponse contains the response fields for the UploadDirectory operation.
+type UploadDirectoryResponse = TransferResponse
+
+// DownloadDirectoryResponse contains the response fields for the DownloadDirectory operation.
+type DownloadDirectoryResponse = TransferResponse
+
+// CopyDirectoryResponse contains the response fields for the CopyDirectory operation.
+type CopyDirectoryResponse = TransferResponse
@@ -0,0 +1,452 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package directory
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	gopath "path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/datalakeerror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/file"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/internal/generated"
+)
+
+// treePath is a file or directory of a tree that's transferred.
+type treePath struct {
+	// path is relative to the root of the tree, with slashes as separators
+	path  string
+	isDir bool
+	size  int64
+}
+
+// UploadDirectory uploads the files and directories beneath the local directory localPath into the directory,
+// which is created if it doesn't exist. Existing files are overwritten, and existing directories are kept as is.
+// Only regular files are uploaded; symbolic links and other special files are skipped.
+// The response reports the result of every path; an error is returned only if walking localPath failed or ctx was
+// canceled.
+func (d *Client) UploadDirectory(ctx context.Context, localPath string, o *UploadDirectoryOptions) (UploadDirectoryResponse, error) {
+	if o == nil {
+		o = &UploadDirectoryOptions{}
+	}
+	options, err := o.format()
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	var tree []treePath
+	err = filepath.WalkDir(localPath, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == localPath || !(entry.IsDir() || entry.Type().IsRegular()) {
+			return nil
+		}
+		rel, err := filepath.Rel(localPath, p)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		tree = append(tree, treePath{path: filepath.ToSlash(rel), isDir: entry.IsDir(), size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	if err := createDirectoryIfNotExists(ctx, d); err != nil {
+		return TransferResponse{}, err
+	}
+	return transferTree(ctx, tree, options, func(ctx context.Context, p treePath) (int64, error) {
+		if p.isDir {
+			dir, err := d.descendant(p.path)
+			if err != nil {
+				return 0, err
+			}
+			return 0, createDirectoryIfNotExists(ctx, dir)
+		}
+
+		fileClient, err := d.descendantFile(p.path)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := fileClient.Create(ctx, nil); err != nil {
+			return 0, err
+		}
+		if p.size == 0 {
+			return 0, nil
+		}
+		f, err := os.Open(filepath.Join(localPath, filepath.FromSlash(p.path)))
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		if err := fileClient.UploadFile(ctx, f, &file.UploadFileOptions{ChunkSize: options.ChunkSize}); err != nil {
+			return 0, err
+		}
+		return p.size, nil
+	})
+}
+
+// DownloadDirectory downloads the files and directories beneath the directory into the local directory localPath,
+// which is created if it doesn't exist. Existing local files are overwritten.
+// The response reports the result of every path; an error is returned only if listing the directory failed or ctx
+// was canceled.
+func (d *Client) DownloadDirectory(ctx context.Context, localPath string, o *DownloadDirectoryOptions) (DownloadDirectoryResponse, error) {
+	if o == nil {
+		o = &DownloadDirectoryOptions{}
+	}
+	options, err := o.format()
+	if err != nil {
+		return TransferResponse{}, err
+	}
+	tree, err := d.listTree(ctx)
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	if err := os.MkdirAll(localPath, 0755); err != nil {
+		return TransferResponse{}, err
+	}
+	return transferTree(ctx, tree, options, func(ctx context.Context, p treePath) (int64, error) {
+		rel := filepath.FromSlash(p.path)
+		if !filepath.IsLocal(rel) {
+			return 0, fmt.Errorf("path %q is outside of %s", p.path, localPath)
+		}
+		target := filepath.Join(localPath, rel)
+		if p.isDir {
+			return 0, os.MkdirAll(target, 0755)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return 0, err
+		}
+		f, err := os.Create(target)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		if p.size == 0 {
+			return 0, nil
+		}
+		fileClient, err := d.descendantFile(p.path)
+		if err != nil {
+			return 0, err
+		}
+		return fileClient.DownloadFile(ctx, f, &file.DownloadFileOptions{ChunkSize: options.ChunkSize})
+	})
+}
+
+// CopyDirectory copies the files and directories beneath the directory into the destination directory, which is
+// created if it doesn't exist. The destination can be in another filesystem or another account; the data of every
+// file is streamed through the client, so the source and the destination can use different credentials.
+// The content headers of the files are copied, and so are the owner, the owning group, the permissions and the
+// access control lists if requested with options. Existing files are overwritten, and existing directories are
+// kept as is.
+// The response reports the result of every path; an error is returned only if listing the directory failed or ctx
+// was canceled.
+func (d *Client) CopyDirectory(ctx context.Context, destination *Client, o *CopyDirectoryOptions) (CopyDirectoryResponse, error) {
+	if o == nil {
+		o = &CopyDirectoryOptions{}
+	}
+	options, err := o.format()
+	if err != nil {
+		return TransferResponse{}, err
+	}
+	tree, err := d.listTree(ctx)
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	if err := createDirectoryIfNotExists(ctx, destination); err != nil {
+		return TransferResponse{}, err
+	}
+	return transferTree(ctx, tree, options, func(ctx context.Context, p treePath) (int64, error) {
+		var n int64
+		if p.isDir {
+			dir, err := destination.descendant(p.path)
+			if err != nil {
+				return 0, err
+			}
+			if err := createDirectoryIfNotExists(ctx, dir); err != nil {
+				return 0, err
+			}
+		} else {
+			var err error
+			if n, err = d.copyFile(ctx, destination, p, options.ChunkSize); err != nil {
+				return 0, err
+			}
+		}
+		if o.PreserveACL || o.PreserveOwner {
+			return n, d.copyAccessControl(ctx, destination, p.path, o.PreserveACL, o.PreserveOwner)
+		}
+		return n, nil
+	})
+}
+
+// copyFile streams the content of the file p beneath the directory to the same path beneath destination.
+func (d *Client) copyFile(ctx context.Context, destination *Client, p treePath, chunkSize int64) (int64, error) {
+	source, err := d.descendantFile(p.path)
+	if err != nil {
+		return 0, err
+	}
+	target, err := destination.descendantFile(p.path)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := source.DownloadStream(ctx, &file.DownloadStreamOptions{Range: &file.HTTPRange{}})
+	if err != nil {
+		return 0, err
+	}
+	body := resp.NewRetryReader(ctx, nil)
+	defer body.Close()
+
+	_, err = target.Create(ctx, &file.CreateOptions{
+		HTTPHeaders: &file.HTTPHeaders{
+			CacheControl:       resp.CacheControl,
+			ContentDisposition: resp.ContentDisposition,
+			ContentEncoding:    resp.ContentEncoding,
+			ContentLanguage:    resp.ContentLanguage,
+			ContentType:        resp.ContentType,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	size := int64(0)
+	if resp.ContentLength != nil {
+		size = *resp.ContentLength
+	}
+	if size == 0 {
+		return 0, nil
+	}
+	if err := target.UploadStream(ctx, body, &file.UploadStreamOptions{ChunkSize: chunkSize}); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// copyAccessControl copies the access control list and/or the owner and owning group of the path p beneath the
+// directory to the same path beneath destination.
+func (d *Client) copyAccessControl(ctx context.Context, destination *Client, p string, acl bool, owner bool) error {
+	source, err := d.descendant(p)
+	if err != nil {
+		return err
+	}
+	target, err := destination.descendant(p)
+	if err != nil {
+		return err
+	}
+	resp, err := source.GetAccessControl(ctx, nil)
+	if err != nil {
+		return err
+	}
+	options := &SetAccessControlOptions{}
+	if acl {
+		options.ACL = resp.ACL
+	}
+	if owner {
+		options.Owner, options.Group = resp.Owner, resp.Group
+	}
+	_, err = target.SetAccessControl(ctx, options)
+	return err
+}
+
+// listTree returns the paths beneath the directory.
+func (d *Client) listTree(ctx context.Context) ([]treePath, error) {
+	urlParts, err := azdatalake.ParseURL(d.DFSURL())
+	if err != nil {
+		return nil, err
+	}
+	dirPath := strings.Trim(urlParts.PathName, "/")
+	urlParts.PathName = ""
+	fsClient := generated.NewFileSystemClient(urlParts.String(), d.generatedDirClientWithDFS().InternalClient())
+
+	var tree []treePath
+	listOptions := &generated.FileSystemClientListPathsOptions{Path: &dirPath}
+	for {
+		req, err := fsClient.ListPathsCreateRequest(ctx, true, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := fsClient.InternalClient().Pipeline().Do(req)
+		if err != nil {
+			return nil, exported.ConvertToDFSError(err)
+		}
+		if !runtime.HasStatusCode(resp, http.StatusOK) {
+			return nil, exported.ConvertToDFSError(runtime.NewResponseError(resp))
+		}
+		page, err := fsClient.ListPathsHandleResponse(resp)
+		if err != nil {
+			return nil, exported.ConvertToDFSError(err)
+		}
+		for _, p := range page.Paths {
+			if p == nil || p.Name == nil {
+				continue
+			}
+			item := treePath{path: strings.TrimPrefix(*p.Name, dirPath+"/")}
+			if p.IsDirectory != nil {
+				item.isDir = *p.IsDirectory
+			}
+			if p.ContentLength != nil {
+				item.size = *p.ContentLength
+			}
+			tree = append(tree, item)
+		}
+		if page.Continuation == nil || *page.Continuation == "" {
+			return tree, nil
+		}
+		listOptions.Continuation = page.Continuation
+	}
+}
+
+// descendant returns a Client for the directory at the relative path p beneath the directory.
+func (d *Client) descendant(p string) (*Client, error) {
+	dir := d
+	for _, name := range strings.Split(p, "/") {
+		var err error
+		if dir, err = dir.NewSubdirectoryClient(name); err != nil {
+			return nil, err
+		}
+	}
+	return dir, nil
+}
+
+// descendantFile returns a file.Client for the file at the relative path p beneath the directory.
+func (d *Client) descendantFile(p string) (*file.Client, error) {
+	dir := d
+	if parent, name := gopath.Split(p); parent != "" {
+		var err error
+		if dir, err = d.descendant(strings.TrimSuffix(parent, "/")); err != nil {
+			return nil, err
+		}
+		p = name
+	}
+	return dir.NewFileClient(p)
+}
+
+// createDirectoryIfNotExists creates the directory, unless it already exists.
+func createDirectoryIfNotExists(ctx context.Context, d *Client) error {
+	_, err := d.Create(ctx, &CreateOptions{
+		AccessConditions: &AccessConditions{ModifiedAccessConditions: &ModifiedAccessConditions{IfNoneMatch: to.Ptr(azcore.ETagAny)}},
+	})
+	if datalakeerror.HasCode(err, datalakeerror.PathAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+// transferTree transfers the paths of tree selected by o with transfer; the directories are transferred first, and
+// then the files. Up to o.Concurrency paths are transferred in parallel.
+func transferTree(ctx context.Context, tree []treePath, o TransferOptions, transfer func(ctx context.Context, p treePath) (int64, error)) (TransferResponse, error) {
+	tree = filterTree(tree, o)
+	resp := TransferResponse{}
+	var mu sync.Mutex
+	for _, dirs := range []bool{true, false} {
+		sem := make(chan struct{}, o.Concurrency)
+		var wg sync.WaitGroup
+		for _, p := range tree {
+			if p.isDir != dirs {
+				continue
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(p treePath) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				n, err := transfer(ctx, p)
+				result := TransferredPath{Path: p.path, IsDirectory: p.isDir, Bytes: n, Err: err}
+
+				mu.Lock()
+				defer mu.Unlock()
+				resp.Paths = append(resp.Paths, result)
+				resp.BytesTransferred += n
+				if err != nil {
+					resp.Failed++
+				} else {
+					resp.Succeeded++
+				}
+				if o.OnResult != nil {
+					o.OnResult(result)
+				}
+			}(p)
+		}
+		wg.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		return resp, err
+	}
+
+	sort.Slice(resp.Paths, func(i, j int) bool {
+		return resp.Paths[i].Path < resp.Paths[j].Path
+	})
+	return resp, nil
+}
+
+// filterTree returns the paths of tree that are selected by the Include and Exclude patterns of o.
+func filterTree(tree []treePath, o TransferOptions) []treePath {
+	matches := func(patterns []string, p string) bool {
+		for _, pattern := range patterns {
+			name := p
+			if !strings.Contains(pattern, "/") {
+				name = gopath.Base(p)
+			}
+			if ok, _ := gopath.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	excluded := map[string]bool{}
+	for _, p := range tree {
+		if p.isDir && matches(o.Exclude, p.path) {
+			excluded[p.path] = true
+		}
+	}
+	inExcludedDirectory := func(p string) bool {
+		for dir := gopath.Dir(p); dir != "."; dir = gopath.Dir(dir) {
+			if excluded[dir] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var selected []treePath
+	for _, p := range tree {
+		if excluded[p.path] || inExcludedDirectory(p.path) {
+			continue
+		}
+		if !p.isDir && (matches(o.Exclude, p.path) || (len(o.Include) > 0 && !matches(o.Include, p.path))) {
+			continue
+		}
+		selected = append(selected, p)
+	}
+	return selected
+}
@@ -0,0 +1,304 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package directory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/datalakeerror"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePath struct {
+	dir         bool
+	data        []byte
+	pending     []byte
+	contentType string
+	owner       string
+	group       string
+	acl         string
+}
+
+// treeTransport is an in-memory account with hierarchical namespace that supports creating directories and files,
+// appending and flushing data, listing paths recursively, downloading files and getting and setting access control.
+// Paths are keyed by the name of the filesystem followed by the path.
+type treeTransport struct {
+	mu    sync.Mutex
+	paths map[string]*fakePath
+	// fail is the set of paths whose creation fails
+	fail map[string]bool
+}
+
+func newTreeTransport() *treeTransport {
+	return &treeTransport{paths: map[string]*fakePath{}, fail: map[string]bool{}}
+}
+
+func (f *treeTransport) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	header := http.Header{}
+	respond := func(status int, body []byte) (*http.Response, error) {
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(bytes.NewReader(body)), Request: req}, nil
+	}
+	fail := func(status int, code datalakeerror.StorageErrorCode) (*http.Response, error) {
+		header.Set("x-ms-error-code", string(code))
+		return respond(status, nil)
+	}
+	q := req.URL.Query()
+	name := strings.TrimPrefix(req.URL.Path, "/")
+	p := f.paths[name]
+
+	switch {
+	case req.Method == http.MethodGet && q.Get("resource") == "filesystem":
+		dir := name + "/" + q.Get("directory")
+		if f.paths[dir] == nil {
+			return fail(http.StatusNotFound, datalakeerror.PathNotFound)
+		}
+		type item struct {
+			Name          string `json:"name"`
+			IsDirectory   string `json:"isDirectory,omitempty"`
+			ContentLength string `json:"contentLength"`
+		}
+		var items []item
+		for key, p := range f.paths {
+			if strings.HasPrefix(key, dir+"/") {
+				i := item{Name: strings.TrimPrefix(key, name+"/"), ContentLength: strconv.Itoa(len(p.data))}
+				if p.dir {
+					i.IsDirectory = "true"
+				}
+				items = append(items, i)
+			}
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+		body, err := json.Marshal(map[string][]item{"paths": items})
+		if err != nil {
+			return nil, err
+		}
+		return respond(http.StatusOK, body)
+
+	case req.Method == http.MethodPut && q.Get("resource") != "":
+		if f.fail[name] {
+			return fail(http.StatusInternalServerError, datalakeerror.InternalError)
+		}
+		if p != nil && len(req.Header["If-None-Match"]) > 0 {
+			return fail(http.StatusConflict, datalakeerror.PathAlreadyExists)
+		}
+		contentType := ""
+		if v := req.Header["x-ms-content-type"]; len(v) > 0 {
+			contentType = v[0]
+		}
+		f.paths[name] = &fakePath{dir: q.Get("resource") == "directory", contentType: contentType, owner: "$superuser", group: "$superuser", acl: "user::rwx,group::r-x,other::---"}
+		return respond(http.StatusCreated, nil)
+
+	case p == nil:
+		return fail(http.StatusNotFound, datalakeerror.PathNotFound)
+
+	case req.Method == http.MethodPatch && q.Get("action") == "append":
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		position, _ := strconv.Atoi(q.Get("position"))
+		if len(p.pending) < position+len(body) {
+			p.pending = append(p.pending, make([]byte, position+len(body)-len(p.pending))...)
+		}
+		copy(p.pending[position:], body)
+		return respond(http.StatusAccepted, nil)
+
+	case req.Method == http.MethodPatch && q.Get("action") == "flush":
+		position, _ := strconv.Atoi(q.Get("position"))
+		p.data, p.pending = p.pending[:position], nil
+		return respond(http.StatusOK, nil)
+
+	case req.Method == http.MethodPatch && q.Get("action") == "setAccessControl":
+		if v := req.Header["x-ms-owner"]; len(v) > 0 {
+			p.owner = v[0]
+		}
+		if v := req.Header["x-ms-group"]; len(v) > 0 {
+			p.group = v[0]
+		}
+		if v := req.Header["x-ms-acl"]; len(v) > 0 {
+			p.acl = v[0]
+		}
+		return respond(http.StatusOK, nil)
+
+	case req.Method == http.MethodHead && q.Get("action") == "getAccessControl":
+		header.Set("x-ms-owner", p.owner)
+		header.Set("x-ms-group", p.group)
+		header.Set("x-ms-acl", p.acl)
+		return respond(http.StatusOK, nil)
+
+	case req.Method == http.MethodHead || req.Method == http.MethodGet:
+		data := p.data
+		status := http.StatusOK
+		if r := req.Header["x-ms-range"]; len(r) > 0 {
+			var start, end int
+			if _, err := fmt.Sscanf(r[0], "bytes=%d-%d", &start, &end); err != nil {
+				return nil, err
+			}
+			end = min(end+1, len(data))
+			header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, len(data)))
+			data, status = data[start:end], http.StatusPartialContent
+		}
+		header.Set("ETag", `"etag"`)
+		header.Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		header.Set("x-ms-blob-type", "BlockBlob")
+		if p.contentType != "" {
+			header.Set("Content-Type", p.contentType)
+		}
+		if req.Method == http.MethodHead {
+			header.Set("Content-Length", strconv.Itoa(len(data)))
+			return &http.Response{StatusCode: status, Header: header, Body: http.NoBody, Request: req}, nil
+		}
+		return respond(status, data)
+	}
+	return fail(http.StatusBadRequest, datalakeerror.InvalidInput)
+}
+
+func (f *treeTransport) data(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p := f.paths[name]; p != nil {
+		return string(p.data)
+	}
+	return "<missing>"
+}
+
+func newTreeTestClient(t *testing.T, transport *treeTransport, directoryURL string) *Client {
+	client, err := NewClientWithNoCredential(directoryURL, &ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: transport, Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func writeTestTree(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+	return root
+}
+
+func transferredPaths(resp TransferResponse) []string {
+	paths := make([]string, len(resp.Paths))
+	for i, p := range resp.Paths {
+		paths[i] = p.Path
+	}
+	return paths
+}
+
+func TestUploadAndDownloadDirectory(t *testing.T) {
+	transport := newTreeTransport()
+	client := newTreeTestClient(t, transport, "https://account.dfs.core.windows.net/fs/data")
+	root := writeTestTree(t, map[string]string{
+		"a.csv":           "1,2,3",
+		"empty.csv":       "",
+		"2025/01/b.csv":   strings.Repeat("b", 1000),
+		"2025/01/b.tmp":   "temporary",
+		"2025/02/c.csv":   "c",
+		"scratch/d.csv":   "d",
+		"scratch/e/f.csv": "f",
+	})
+
+	var results int
+	resp, err := client.UploadDirectory(context.Background(), root, &UploadDirectoryOptions{
+		TransferOptions: TransferOptions{
+			Concurrency: 3,
+			ChunkSize:   100,
+			Exclude:     []string{"*.tmp", "scratch"},
+			OnResult:    func(TransferredPath) { results++ },
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"2025", "2025/01", "2025/01/b.csv", "2025/02", "2025/02/c.csv", "a.csv", "empty.csv"}, transferredPaths(resp))
+	require.Equal(t, 7, results)
+	require.Equal(t, 7, resp.Succeeded)
+	require.Zero(t, resp.Failed)
+	require.Equal(t, int64(1006), resp.BytesTransferred)
+	require.True(t, transport.paths["fs/data/2025/01"].dir)
+	require.Equal(t, strings.Repeat("b", 1000), transport.data("fs/data/2025/01/b.csv"))
+	require.Equal(t, "", transport.data("fs/data/empty.csv"))
+	require.NotContains(t, transport.paths, "fs/data/scratch")
+
+	local := filepath.Join(t.TempDir(), "download")
+	resp, err = client.DownloadDirectory(context.Background(), local, &DownloadDirectoryOptions{
+		TransferOptions: TransferOptions{ChunkSize: 300, Include: []string{"2025/*/*.csv"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"2025", "2025/01", "2025/01/b.csv", "2025/02", "2025/02/c.csv"}, transferredPaths(resp))
+	for _, p := range resp.Paths {
+		require.NoError(t, p.Err, p.Path)
+	}
+	require.Equal(t, int64(1001), resp.BytesTransferred)
+	content, err := os.ReadFile(filepath.Join(local, "2025", "01", "b.csv"))
+	require.NoError(t, err)
+	require.Equal(t, strings.Repeat("b", 1000), string(content))
+	require.NoFileExists(t, filepath.Join(local, "a.csv"))
+}
+
+func TestCopyDirectory(t *testing.T) {
+	transport := newTreeTransport()
+	source := newTreeTestClient(t, transport, "https://account.dfs.core.windows.net/src/data")
+	destination := newTreeTestClient(t, transport, "https://account.dfs.core.windows.net/dst/copy")
+	_, err := source.UploadDirectory(context.Background(), writeTestTree(t, map[string]string{
+		"a.json":     `{"a":1}`,
+		"sub/b.json": strings.Repeat("b", 5000),
+		"sub/c.json": "c",
+	}), nil)
+	require.NoError(t, err)
+	transport.paths["src/data/sub"].owner = "alice"
+	transport.paths["src/data/sub"].acl = "user::rwx,user:bob:r-x,group::r-x,mask::r-x,other::---"
+	transport.paths["src/data/a.json"].contentType = "application/json"
+	transport.fail["dst/copy/sub/c.json"] = true
+
+	resp, err := source.CopyDirectory(context.Background(), destination, &CopyDirectoryOptions{
+		TransferOptions: TransferOptions{ChunkSize: 1024},
+		PreserveACL:     true,
+		PreserveOwner:   true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.json", "sub", "sub/b.json", "sub/c.json"}, transferredPaths(resp))
+	require.Equal(t, 3, resp.Succeeded)
+	require.Equal(t, 1, resp.Failed)
+	require.True(t, datalakeerror.HasCode(resp.Paths[3].Err, datalakeerror.InternalError))
+
+	require.True(t, transport.paths["dst/copy"].dir)
+	require.Equal(t, `{"a":1}`, transport.data("dst/copy/a.json"))
+	require.Equal(t, "application/json", transport.paths["dst/copy/a.json"].contentType)
+	require.Equal(t, strings.Repeat("b", 5000), transport.data("dst/copy/sub/b.json"))
+	require.Equal(t, "alice", transport.paths["dst/copy/sub"].owner)
+	require.Equal(t, "user::rwx,user:bob:r-x,group::r-x,mask::r-x,other::---", transport.paths["dst/copy/sub"].acl)
+}
+
+func TestTransferOptionsValidation(t *testing.T) {
+	client := newTreeTestClient(t, newTreeTransport(), "https://account.dfs.core.windows.net/fs/data")
+	_, err := client.DownloadDirectory(context.Background(), t.TempDir(), &DownloadDirectoryOptions{TransferOptions: TransferOptions{Include: []string{"["}}})
+	require.Error(t, err)
+
+	// the directory doesn't exist
+	_, err = client.DownloadDirectory(context.Background(), t.TempDir(), nil)
+	require.True(t, datalakeerror.HasCode(err, datalakeerror.PathNotFound))
+}
@@ -588,4 +588,11 @@ func (f *Client) DownloadFile(ctx context.Context, file *os.File, o *DownloadFil
 	return val, exported.ConvertToDFSError(err)
 }
 
-// TODO: Undelete()
+// Undelete restores a soft-deleted file. deletionID identifies the deleted version of the file to restore; it's
+// returned by filesystem.Client.NewListDeletedPathsPager.
+func (f *Client) Undelete(ctx context.Context, deletionID string, options *UndeleteOptions) (UndeleteResponse, error) {
+	opts := path.FormatUndeleteOptions(options, deletionID)
+	resp, err := generated.NewPathClient(f.BlobURL(), f.generatedFileClientWithDFS().InternalClient()).Undelete(ctx, opts)
+	err = exported.ConvertToDFSError(err)
+	return resp, err
+}
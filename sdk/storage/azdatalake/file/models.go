@@ -558,6 +558,9 @@ type HTTPRange = exported.HTTPRange
 // DeleteOptions contains the optional parameters when calling the Delete operation.
 type DeleteOptions = path.DeleteOptions
 
+// UndeleteOptions contains the optional parameters when calling the Undelete operation.
+type UndeleteOptions = path.UndeleteOptions
+
 // RenameOptions contains the optional parameters when calling the Rename operation.
 type RenameOptions = path.RenameOptions
 
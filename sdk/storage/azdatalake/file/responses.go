@@ -277,6 +277,9 @@ type CreateResponse = path.CreateResponse
 // DeleteResponse contains the response fields for the Delete operation.
 type DeleteResponse = path.DeleteResponse
 
+// UndeleteResponse contains the response fields for the Undelete operation.
+type UndeleteResponse = path.UndeleteResponse
+
 // UpdateAccessControlResponse contains the response fields for the UpdateAccessControlRecursive operation.
 type UpdateAccessControlResponse = path.UpdateAccessControlResponse
 
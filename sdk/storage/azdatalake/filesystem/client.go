@@ -379,6 +379,14 @@ func (fs *Client) NewListDeletedPathsPager(options *ListDeletedPathsOptions) *ru
 	})
 }
 
+// UndeletePath restores a soft-deleted file or directory. deletedPath is the name of the path, and deletionID
+// identifies the deleted version of the path to restore; both are returned by NewListDeletedPathsPager.
+// The ResourceType of the response indicates whether the restored path is a file or a directory.
+func (fs *Client) UndeletePath(ctx context.Context, deletedPath string, deletionID string, options *UndeletePathOptions) (UndeletePathResponse, error) {
+	resp, err := fs.NewFileClient(deletedPath).Undelete(ctx, deletionID, options)
+	return resp, err
+}
+
 // GetSASURL is a convenience method for generating a SAS token for the currently pointed at filesystem.
 // It can only be used if the credential supplied during creation was a SharedKeyCredential.
 func (fs *Client) GetSASURL(permissions sas.FileSystemPermissions, expiry time.Time, o *GetSASURLOptions) (string, error) {
@@ -11,6 +11,9 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake"
 )
 
+// DefaultRestoreConcurrency is the default number of paths restored in parallel by Client.RestoreDeletedPaths.
+const DefaultRestoreConcurrency = 5
+
 // PublicAccessType defines values for AccessType - private (default) or file or filesystem.
 type PublicAccessType = azblob.PublicAccessType
 
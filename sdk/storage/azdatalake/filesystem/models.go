@@ -227,16 +227,45 @@ func (o *GetSASURLOptions) format() time.Time {
 	return st
 }
 
-// UndeletePathOptions contains the optional parameters for the FileSystem.UndeletePath operation.
-// type UndeletePathOptions struct {
-//	// placeholder
-// }
-// func (o *UndeletePathOptions) format() *UndeletePathOptions {
-//	if o == nil {
-//		return nil
-//	}
-//	return &UndeletePathOptions{}
-// }
+// UndeletePathOptions contains the optional parameters for the Client.UndeletePath method.
+type UndeletePathOptions = file.UndeleteOptions
+
+// RestoreDeletedPathsOptions contains the optional parameters for the Client.RestoreDeletedPaths method.
+type RestoreDeletedPathsOptions struct {
+	// Prefix restricts the restored paths to the deleted paths whose names begin with the specified prefix.
+	Prefix *string
+	// DeletedAfter restricts the restored paths to the paths deleted at or after this time.
+	DeletedAfter *time.Time
+	// DeletedBefore restricts the restored paths to the paths deleted before this time.
+	DeletedBefore *time.Time
+	// Concurrency is the maximum number of paths that are restored in parallel. The default value is 5.
+	Concurrency int
+	// OnResult, when specified, is called with the result of every restored path as soon as it's available.
+	// It's called from multiple goroutines, but never concurrently.
+	OnResult func(RestoredPath)
+}
+
+func (o *RestoreDeletedPathsOptions) format() RestoreDeletedPathsOptions {
+	if o == nil {
+		return RestoreDeletedPathsOptions{Concurrency: DefaultRestoreConcurrency}
+	}
+	options := *o
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultRestoreConcurrency
+	}
+	return options
+}
+
+// includes returns true if a path deleted at deletedTime is in the time window of the options.
+func (o *RestoreDeletedPathsOptions) includes(deletedTime *time.Time) bool {
+	if o.DeletedAfter == nil && o.DeletedBefore == nil {
+		return true
+	}
+	if deletedTime == nil {
+		return false
+	}
+	return (o.DeletedAfter == nil || !deletedTime.Before(*o.DeletedAfter)) && (o.DeletedBefore == nil || deletedTime.Before(*o.DeletedBefore))
+}
 
 // CPKScopeInfo contains a group of parameters for the FileSystemClient.Create method.
 type CPKScopeInfo = container.CPKScopeInfo
@@ -143,6 +143,30 @@ type ListPathsSegmentResponse = generated.FileSystemClientListPathsResponse
 // UndeletePathResponse contains the response from method FileSystemClient.UndeletePath.
 type UndeletePathResponse = generated.PathClientUndeleteResponse
 
+// RestoredPath contains the result of restoring a deleted path with Client.RestoreDeletedPaths.
+type RestoredPath struct {
+	// Name is the name of the path.
+	Name string
+	// DeletionID identifies the deleted version of the path.
+	DeletionID string
+	// DeletedTime is the time at which the path was deleted.
+	DeletedTime *time.Time
+	// ResourceType is "file" or "directory" when the path was restored.
+	ResourceType *string
+	// Err is the error restoring the path, or nil if it was restored.
+	Err error
+}
+
+// RestoreDeletedPathsResponse contains the response from method Client.RestoreDeletedPaths.
+type RestoreDeletedPathsResponse struct {
+	// Paths contains the result of every path that was restored, or failed to be restored, sorted by name.
+	Paths []RestoredPath
+	// Restored is the number of paths that were restored.
+	Restored int
+	// Failed is the number of paths that failed to be restored.
+	Failed int
+}
+
 // ListDeletedPathsSegmentResponse contains the response from method FileSystemClient.ListPathsSegment.
 type ListDeletedPathsSegmentResponse = generated.FileSystemClientListPathHierarchySegmentResponse
 
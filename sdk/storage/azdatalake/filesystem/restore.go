@@ -0,0 +1,126 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package filesystem
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RestoreDeletedPaths restores the soft-deleted paths in the filesystem whose names begin with options.Prefix and
+// that were deleted within the time window of options.DeletedAfter and options.DeletedBefore.
+// A path that was deleted more than once within the time window is restored to its most recently deleted version.
+// Directories are restored before the paths beneath them, so that restoring a path whose parent directory was
+// deleted as well doesn't fail.
+// The response reports the result of every path; an error is returned only if listing the deleted paths failed or
+// ctx was canceled.
+func (fs *Client) RestoreDeletedPaths(ctx context.Context, options *RestoreDeletedPathsOptions) (RestoreDeletedPathsResponse, error) {
+	o := options.format()
+	paths, err := fs.listRestorablePaths(ctx, &o)
+	if err != nil {
+		return RestoreDeletedPathsResponse{}, err
+	}
+
+	// paths are restored level by level, so that a parent directory is restored before its children
+	levels := map[int][]RestoredPath{}
+	for _, p := range paths {
+		depth := strings.Count(strings.Trim(p.Name, "/"), "/")
+		levels[depth] = append(levels[depth], p)
+	}
+	depths := make([]int, 0, len(levels))
+	for depth := range levels {
+		depths = append(depths, depth)
+	}
+	sort.Ints(depths)
+
+	resp := RestoreDeletedPathsResponse{}
+	var mu sync.Mutex
+	for _, depth := range depths {
+		if err := ctx.Err(); err != nil {
+			return resp, err
+		}
+		level := levels[depth]
+		sem := make(chan struct{}, o.Concurrency)
+		var wg sync.WaitGroup
+		for i := range level {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(p *RestoredPath) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				undeleteResp, err := fs.UndeletePath(ctx, p.Name, p.DeletionID, nil)
+				p.ResourceType, p.Err = undeleteResp.ResourceType, err
+
+				mu.Lock()
+				defer mu.Unlock()
+				if p.Err != nil {
+					resp.Failed++
+				} else {
+					resp.Restored++
+				}
+				if o.OnResult != nil {
+					o.OnResult(*p)
+				}
+			}(&level[i])
+		}
+		wg.Wait()
+		resp.Paths = append(resp.Paths, level...)
+	}
+	if err := ctx.Err(); err != nil {
+		return resp, err
+	}
+
+	sort.Slice(resp.Paths, func(i, j int) bool {
+		return resp.Paths[i].Name < resp.Paths[j].Name
+	})
+	return resp, nil
+}
+
+// listRestorablePaths returns the most recently deleted version of every deleted path selected by o.
+func (fs *Client) listRestorablePaths(ctx context.Context, o *RestoreDeletedPathsOptions) ([]RestoredPath, error) {
+	latest := map[string]RestoredPath{}
+	pager := fs.NewListDeletedPathsPager(&ListDeletedPathsOptions{Prefix: o.Prefix})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if page.Segment == nil {
+			continue
+		}
+		for _, item := range page.Segment.PathItems {
+			if item == nil || item.Name == nil || item.DeletionID == nil {
+				continue
+			}
+			var deletedTime *time.Time
+			if item.Properties != nil {
+				deletedTime = item.Properties.DeletedTime
+			}
+			if !o.includes(deletedTime) {
+				continue
+			}
+			if p, ok := latest[*item.Name]; ok && p.DeletedTime != nil && (deletedTime == nil || !deletedTime.After(*p.DeletedTime)) {
+				continue
+			}
+			latest[*item.Name] = RestoredPath{Name: *item.Name, DeletionID: *item.DeletionID, DeletedTime: deletedTime}
+		}
+	}
+
+	paths := make([]RestoredPath, 0, len(latest))
+	for _, p := range latest {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return paths[i].Name < paths[j].Name
+	})
+	return paths, nil
+}
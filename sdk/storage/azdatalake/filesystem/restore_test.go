@@ -0,0 +1,186 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/datalakeerror"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDeletedPath struct {
+	name        string
+	deletionID  string
+	deletedTime time.Time
+	directory   bool
+}
+
+// deletedPathsTransport is an in-memory filesystem that lists soft-deleted paths and restores them.
+type deletedPathsTransport struct {
+	mu       sync.Mutex
+	deleted  []fakeDeletedPath
+	existing map[string]bool
+	restored []string
+	hosts    map[string]bool
+}
+
+func (f *deletedPathsTransport) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	header := http.Header{}
+	respond := func(status int, body string) (*http.Response, error) {
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	}
+	f.hosts[req.URL.Host] = true
+	q := req.URL.Query()
+
+	switch {
+	case req.Method == http.MethodGet && q.Get("comp") == "list" && q.Get("showonly") == "deleted":
+		var items strings.Builder
+		for _, p := range f.deleted {
+			if strings.HasPrefix(p.name, q.Get("prefix")) {
+				fmt.Fprintf(&items, "<Blob><Name>%s</Name><Deleted>true</Deleted><DeletionId>%s</DeletionId><Properties><DeletedTime>%s</DeletedTime></Properties></Blob>",
+					p.name, p.deletionID, p.deletedTime.Format(http.TimeFormat))
+			}
+		}
+		return respond(http.StatusOK, `<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs>`+items.String()+`</Blobs><NextMarker /></EnumerationResults>`)
+	case req.Method == http.MethodPut && q.Get("comp") == "undelete":
+		name := strings.TrimPrefix(req.URL.Path, "/fs/")
+		source := req.Header["x-ms-undelete-source"]
+		if len(source) != 1 || !strings.HasPrefix(source[0], "?deletionid=") {
+			return respond(http.StatusBadRequest, "")
+		}
+		deletionID := strings.TrimPrefix(source[0], "?deletionid=")
+		if parent := path.Dir(name); parent != "." && !f.existing[parent] {
+			header.Set("x-ms-error-code", string(datalakeerror.PathNotFound))
+			return respond(http.StatusNotFound, "")
+		}
+		if f.existing[name] {
+			header.Set("x-ms-error-code", string(datalakeerror.PathAlreadyExists))
+			return respond(http.StatusConflict, "")
+		}
+		for _, p := range f.deleted {
+			if p.name == name && p.deletionID == deletionID {
+				f.existing[name] = true
+				f.restored = append(f.restored, name+"@"+deletionID)
+				if p.directory {
+					header.Set("x-ms-resource-type", "directory")
+				} else {
+					header.Set("x-ms-resource-type", "file")
+				}
+				return respond(http.StatusOK, "")
+			}
+		}
+		header.Set("x-ms-error-code", string(datalakeerror.PathNotFound))
+		return respond(http.StatusNotFound, "")
+	}
+	return respond(http.StatusBadRequest, "")
+}
+
+func newRestoreTestClient(t *testing.T, transport *deletedPathsTransport) *Client {
+	client, err := NewClientWithNoCredential("https://account.dfs.core.windows.net/fs", &ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: transport, Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestUndeletePath(t *testing.T) {
+	deletedTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	transport := &deletedPathsTransport{
+		deleted:  []fakeDeletedPath{{name: "dir", deletionID: "1", deletedTime: deletedTime, directory: true}},
+		existing: map[string]bool{},
+		hosts:    map[string]bool{},
+	}
+	client := newRestoreTestClient(t, transport)
+
+	resp, err := client.UndeletePath(context.Background(), "dir", "1", nil)
+	require.NoError(t, err)
+	require.Equal(t, "directory", *resp.ResourceType)
+	require.Equal(t, []string{"dir@1"}, transport.restored)
+
+	// undelete requests are sent to the blob endpoint
+	require.Equal(t, map[string]bool{"account.blob.core.windows.net": true}, transport.hosts)
+
+	_, err = client.UndeletePath(context.Background(), "dir", "1", nil)
+	require.True(t, datalakeerror.HasCode(err, datalakeerror.PathAlreadyExists))
+}
+
+func TestRestoreDeletedPaths(t *testing.T) {
+	base := time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+	transport := &deletedPathsTransport{
+		deleted: []fakeDeletedPath{
+			// deleted before the time window
+			{name: "logs/old.txt", deletionID: "1", deletedTime: base.Add(-time.Hour)},
+			// deleted twice within the time window; the latest version is restored
+			{name: "logs/a.txt", deletionID: "2", deletedTime: base.Add(time.Minute)},
+			{name: "logs/a.txt", deletionID: "3", deletedTime: base.Add(2 * time.Minute)},
+			// a directory and a file beneath it that was deleted before it
+			{name: "logs/2025/01/b.txt", deletionID: "4", deletedTime: base.Add(3 * time.Minute)},
+			{name: "logs/2025", deletionID: "5", deletedTime: base.Add(4 * time.Minute), directory: true},
+			{name: "logs/2025/01", deletionID: "6", deletedTime: base.Add(4 * time.Minute), directory: true},
+			// a file that was recreated after it was deleted
+			{name: "logs/c.txt", deletionID: "7", deletedTime: base.Add(5 * time.Minute)},
+			// outside of the prefix
+			{name: "data/d.txt", deletionID: "8", deletedTime: base.Add(5 * time.Minute)},
+		},
+		existing: map[string]bool{"logs": true, "logs/c.txt": true},
+		hosts:    map[string]bool{},
+	}
+	client := newRestoreTestClient(t, transport)
+
+	var results int
+	resp, err := client.RestoreDeletedPaths(context.Background(), &RestoreDeletedPathsOptions{
+		Prefix:        to.Ptr("logs/"),
+		DeletedAfter:  to.Ptr(base),
+		DeletedBefore: to.Ptr(base.Add(time.Hour)),
+		Concurrency:   2,
+		OnResult:      func(RestoredPath) { results++ },
+	})
+	require.NoError(t, err)
+	require.Equal(t, 5, results)
+	require.Equal(t, 4, resp.Restored)
+	require.Equal(t, 1, resp.Failed)
+
+	names := make([]string, len(resp.Paths))
+	for i, p := range resp.Paths {
+		names[i] = p.Name + "@" + p.DeletionID
+	}
+	require.Equal(t, []string{"logs/2025@5", "logs/2025/01@6", "logs/2025/01/b.txt@4", "logs/a.txt@3", "logs/c.txt@7"}, names)
+	require.Equal(t, "directory", *resp.Paths[0].ResourceType)
+	require.Equal(t, "file", *resp.Paths[2].ResourceType)
+	require.Equal(t, base.Add(3*time.Minute), resp.Paths[2].DeletedTime.UTC())
+	require.True(t, datalakeerror.HasCode(resp.Paths[4].Err, datalakeerror.PathAlreadyExists))
+	require.Nil(t, resp.Paths[4].ResourceType)
+
+	// the parent directories are restored before the paths beneath them
+	require.Len(t, transport.restored, 4)
+	require.Equal(t, "logs/2025/01/b.txt@4", transport.restored[3])
+}
+
+func TestRestoreDeletedPathsCanceled(t *testing.T) {
+	client := newRestoreTestClient(t, &deletedPathsTransport{existing: map[string]bool{}, hosts: map[string]bool{}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.RestoreDeletedPaths(ctx, nil)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -8,6 +8,9 @@ package path
 
 import (
 	"errors"
+	"net/url"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/datalakeerror"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/internal/exported"
@@ -34,6 +37,18 @@ func FormatDeleteOptions(o *DeleteOptions, recursive bool) (*generated.LeaseAcce
 	return leaseAccessConditions, modifiedAccessConditions, deleteOpts
 }
 
+// UndeleteOptions contains the optional parameters when calling the Undelete operation.
+type UndeleteOptions struct {
+	// placeholder for future options
+}
+
+// FormatUndeleteOptions returns the options of the Undelete request restoring the deleted path identified by deletionID.
+func FormatUndeleteOptions(o *UndeleteOptions, deletionID string) *generated.PathClientUndeleteOptions {
+	return &generated.PathClientUndeleteOptions{
+		UndeleteSource: to.Ptr("?deletionid=" + url.QueryEscape(deletionID)),
+	}
+}
+
 // RenameOptions contains the optional parameters when calling the Rename operation.
 type RenameOptions struct {
 	// SourceAccessConditions identifies the source path access conditions.
@@ -33,6 +33,9 @@ type CreateResponse = generated.PathClientCreateResponse
 // DeleteResponse contains the response fields for the Delete operation.
 type DeleteResponse = generated.PathClientDeleteResponse
 
+// UndeleteResponse contains the response fields for the Undelete operation.
+type UndeleteResponse = generated.PathClientUndeleteResponse
+
 type RenameResponse struct {
 	// ContentLength contains the information returned from the Content-Length header response.
 	ContentLength *int64
@@ -47,3 +47,7 @@ const (
 func PossibleShareTokenIntentValues() []ShareTokenIntent {
 	return generated.PossibleShareTokenIntentValues()
 }
+
+// DefaultTransferConcurrency is the default number of files and directories that are transferred in parallel by
+// Client.UploadDirectory, Client.DownloadDirectory and Client.CopyDirectory.
+const DefaultTransferConcurrency = 5
@@ -7,6 +7,8 @@
 package directory
 
 import (
+	"errors"
+
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/file"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/internal/exported"
@@ -370,3 +372,61 @@ func (o *ForceCloseHandlesOptions) format() *generated.DirectoryClientForceClose
 		Sharesnapshot: o.ShareSnapshot,
 	}
 }
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// TransferOptions contains the optional parameters common to Client.UploadDirectory, Client.DownloadDirectory and
+// Client.CopyDirectory.
+type TransferOptions struct {
+	// Concurrency is the maximum number of files and directories that are transferred in parallel.
+	// The default value is DefaultTransferConcurrency.
+	Concurrency int
+
+	// ChunkSize specifies the chunk size to use for transferring the content of each file.
+	// The default value depends on the transfer.
+	ChunkSize int64
+
+	// Sync makes the transfer incremental: a file is skipped if the destination already has a file at the same path
+	// with the same size and last write time. Files that only exist at the destination are kept.
+	Sync bool
+
+	// OnResult is called with the result of each file and directory as it completes.
+	// It is called by one goroutine at a time.
+	OnResult func(TransferredPath)
+}
+
+func (o *TransferOptions) format() (TransferOptions, error) {
+	if o == nil {
+		return TransferOptions{Concurrency: DefaultTransferConcurrency}, nil
+	}
+	if o.Concurrency < 0 {
+		return TransferOptions{}, errors.New("Concurrency cannot be negative")
+	}
+	if o.ChunkSize < 0 {
+		return TransferOptions{}, errors.New("ChunkSize cannot be negative")
+	}
+	options := *o
+	if options.Concurrency == 0 {
+		options.Concurrency = DefaultTransferConcurrency
+	}
+	return options, nil
+}
+
+// UploadDirectoryOptions contains the optional parameters for the Client.UploadDirectory method.
+type UploadDirectoryOptions struct {
+	TransferOptions
+}
+
+// DownloadDirectoryOptions contains the optional parameters for the Client.DownloadDirectory method.
+type DownloadDirectoryOptions struct {
+	TransferOptions
+}
+
+// CopyDirectoryOptions contains the optional parameters for the Client.CopyDirectory method.
+type CopyDirectoryOptions struct {
+	TransferOptions
+
+	// IgnorePermissions skips copying the security descriptors of the files and directories, which then inherit
+	// the permissions of their parent directory at the destination.
+	IgnorePermissions bool
+}
@@ -42,3 +42,49 @@ type ListHandlesSegmentResponse = generated.ListHandlesResponse
 
 // ForceCloseHandlesResponse contains the response from method Client.ForceCloseHandles.
 type ForceCloseHandlesResponse = generated.DirectoryClientForceCloseHandlesResponse
+
+// TransferredPath is the result of transferring a file or directory.
+type TransferredPath struct {
+	// Path is the path of the file or directory relative to the root of the transfer, with slashes as separators.
+	Path string
+
+	// IsDirectory is true if the path is a directory.
+	IsDirectory bool
+
+	// Bytes is the number of bytes of the file that were transferred.
+	Bytes int64
+
+	// Skipped is true if the file wasn't transferred because it's unchanged at the destination; see TransferOptions.Sync.
+	Skipped bool
+
+	// Err is the error that occurred transferring the path, if any.
+	Err error
+}
+
+// TransferResponse contains the response from methods Client.UploadDirectory, Client.DownloadDirectory and
+// Client.CopyDirectory.
+type TransferResponse struct {
+	// Paths contains the result of every file and directory, sorted by path.
+	Paths []TransferredPath
+
+	// Succeeded is the number of files and directories that were transferred.
+	Succeeded int
+
+	// Skipped is the number of files that were unchanged at the destination.
+	Skipped int
+
+	// Failed is the number of files and directories that failed.
+	Failed int
+
+	// BytesTransferred is the total number of bytes of the files that were transferred.
+	BytesTransferred int64
+}
+
+// UploadDirectoryResponse contains the response from method Client.UploadDirectory.
+type UploadDirectoryResponse = TransferResponse
+
+// DownloadDirectoryResponse contains the response from method Client.DownloadDirectory.
+type DownloadDirectoryResponse = TransferResponse
+
+// CopyDirectoryResponse contains the response from method Client.CopyDirectory.
+type CopyDirectoryResponse = TransferResponse
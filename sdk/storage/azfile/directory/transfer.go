@@ -0,0 +1,626 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package directory
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	gopath "path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/file"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/fileerror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/internal/generated"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/sas"
+)
+
+// treePath is a file or directory of a tree that's transferred, with its SMB properties.
+type treePath struct {
+	// path is relative to the root of the tree, with slashes as separators
+	path          string
+	isDir         bool
+	size          int64
+	attributes    *string
+	creationTime  *time.Time
+	lastWriteTime *time.Time
+	changeTime    *time.Time
+	permissionKey *string
+}
+
+// smbProperties returns the SMB properties of p, as they're set on the copy of p.
+func (p treePath) smbProperties() (*file.SMBProperties, error) {
+	attributes, err := file.ParseNTFSFileAttributes(p.attributes)
+	if err != nil {
+		return nil, err
+	}
+	if attributes != nil {
+		// the Directory attribute is added when the properties of a directory are formatted
+		attributes.Directory = false
+		if !p.isDir && *attributes == (file.NTFSFileAttributes{}) {
+			attributes.None = true
+		}
+	}
+	return &file.SMBProperties{
+		Attributes:    attributes,
+		CreationTime:  p.creationTime,
+		LastWriteTime: p.lastWriteTime,
+		ChangeTime:    p.changeTime,
+	}, nil
+}
+
+// unchanged returns true if dst is a file with the same size and last write time as the file p; the times are
+// compared at the 100ns precision of the service.
+func (p treePath) unchanged(dst treePath, ok bool) bool {
+	if !ok || dst.isDir || p.isDir || dst.size != p.size || p.lastWriteTime == nil || dst.lastWriteTime == nil {
+		return false
+	}
+	return p.lastWriteTime.Truncate(100 * time.Nanosecond).Equal(dst.lastWriteTime.Truncate(100 * time.Nanosecond))
+}
+
+// UploadDirectory uploads the files and directories beneath the local directory localPath into the directory,
+// which is created if it doesn't exist. The last write time of every file and directory is preserved; the other
+// SMB properties and the permissions are the defaults of the share. Existing files are overwritten, unless
+// options.Sync is set and they're unchanged. Only regular files are uploaded; symbolic links and other special
+// files are skipped.
+// The response reports the result of every path; an error is returned only if walking localPath failed or ctx was
+// canceled.
+func (d *Client) UploadDirectory(ctx context.Context, localPath string, options *UploadDirectoryOptions) (UploadDirectoryResponse, error) {
+	if options == nil {
+		options = &UploadDirectoryOptions{}
+	}
+	o, err := options.TransferOptions.format()
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	var tree []treePath
+	err = filepath.WalkDir(localPath, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == localPath || !(entry.IsDir() || entry.Type().IsRegular()) {
+			return nil
+		}
+		rel, err := filepath.Rel(localPath, p)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		tree = append(tree, localTreePath(filepath.ToSlash(rel), info))
+		return nil
+	})
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	if err := createDirectory(ctx, d, nil); err != nil {
+		return TransferResponse{}, err
+	}
+	existing, err := d.listExisting(ctx, o.Sync)
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	transfer := func(ctx context.Context, p treePath) (int64, bool, error) {
+		smbProperties := &file.SMBProperties{LastWriteTime: p.lastWriteTime}
+		if p.isDir {
+			return 0, false, createDirectory(ctx, d.descendant(p.path), &CreateOptions{FileSMBProperties: smbProperties})
+		}
+		if dst, ok := existing[p.path]; p.unchanged(dst, ok) {
+			return 0, true, nil
+		}
+
+		fileClient := d.descendantFile(p.path)
+		if _, err := fileClient.Create(ctx, p.size, nil); err != nil {
+			return 0, false, err
+		}
+		if p.size > 0 {
+			f, err := os.Open(filepath.Join(localPath, filepath.FromSlash(p.path)))
+			if err != nil {
+				return 0, false, err
+			}
+			defer f.Close()
+			if err := fileClient.UploadFile(ctx, f, &file.UploadFileOptions{ChunkSize: o.ChunkSize}); err != nil {
+				return 0, false, err
+			}
+		}
+		// the last write time is set once the content is written
+		_, err := fileClient.SetHTTPHeaders(ctx, &file.SetHTTPHeadersOptions{SMBProperties: smbProperties})
+		return p.size, false, err
+	}
+	finish := func(ctx context.Context, p treePath) error {
+		_, err := d.descendant(p.path).SetProperties(ctx, &SetPropertiesOptions{FileSMBProperties: &file.SMBProperties{LastWriteTime: p.lastWriteTime}})
+		return err
+	}
+	return transferTree(ctx, tree, o, transfer, finish)
+}
+
+// DownloadDirectory downloads the files and directories beneath the directory into the local directory localPath,
+// which is created if it doesn't exist. The last write time of every file and directory is preserved as its
+// modification time; the other SMB properties and the permissions aren't. Existing local files are overwritten,
+// unless options.Sync is set and they're unchanged.
+// The response reports the result of every path; an error is returned only if listing the directory failed or ctx
+// was canceled.
+func (d *Client) DownloadDirectory(ctx context.Context, localPath string, options *DownloadDirectoryOptions) (DownloadDirectoryResponse, error) {
+	if options == nil {
+		options = &DownloadDirectoryOptions{}
+	}
+	o, err := options.TransferOptions.format()
+	if err != nil {
+		return TransferResponse{}, err
+	}
+	tree, err := d.listTree(ctx)
+	if err != nil {
+		return TransferResponse{}, err
+	}
+	if err := os.MkdirAll(localPath, 0755); err != nil {
+		return TransferResponse{}, err
+	}
+
+	target := func(p treePath) (string, error) {
+		rel := filepath.FromSlash(p.path)
+		if !filepath.IsLocal(rel) {
+			return "", fmt.Errorf("path %q is outside of %s", p.path, localPath)
+		}
+		return filepath.Join(localPath, rel), nil
+	}
+	transfer := func(ctx context.Context, p treePath) (int64, bool, error) {
+		name, err := target(p)
+		if err != nil {
+			return 0, false, err
+		}
+		if p.isDir {
+			return 0, false, os.MkdirAll(name, 0755)
+		}
+		if o.Sync {
+			if info, err := os.Stat(name); err == nil && info.Mode().IsRegular() && p.unchanged(localTreePath(p.path, info), true) {
+				return 0, true, nil
+			}
+		}
+
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return 0, false, err
+		}
+		f, err := os.Create(name)
+		if err != nil {
+			return 0, false, err
+		}
+		n, err := int64(0), error(nil)
+		if p.size > 0 {
+			n, err = d.descendantFile(p.path).DownloadFile(ctx, f, &file.DownloadFileOptions{ChunkSize: o.ChunkSize})
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return n, false, err
+		}
+		return n, false, setLocalTimes(name, p)
+	}
+	finish := func(ctx context.Context, p treePath) error {
+		name, err := target(p)
+		if err != nil {
+			return err
+		}
+		return setLocalTimes(name, p)
+	}
+	return transferTree(ctx, tree, o, transfer, finish)
+}
+
+// CopyDirectory copies the files and directories beneath the directory into the destination directory, which is
+// created if it doesn't exist. The destination can be in another share or another account; the data of every file
+// is streamed through the client, so the source and the destination can use different credentials.
+// The SMB attributes, the creation, last write and change times, the content headers, the metadata and the
+// permissions of every file and directory are preserved. A permission is created in the destination share for each
+// permission key of the source share that's used, unless both directories are in the same share.
+// Existing files are overwritten, unless options.Sync is set and they're unchanged.
+// The response reports the result of every path; an error is returned only if listing the directory failed or ctx
+// was canceled.
+func (d *Client) CopyDirectory(ctx context.Context, destination *Client, options *CopyDirectoryOptions) (CopyDirectoryResponse, error) {
+	if options == nil {
+		options = &CopyDirectoryOptions{}
+	}
+	o, err := options.TransferOptions.format()
+	if err != nil {
+		return TransferResponse{}, err
+	}
+	tree, err := d.listTree(ctx)
+	if err != nil {
+		return TransferResponse{}, err
+	}
+	permissions, err := newPermissionMapper(d, destination)
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	if err := createDirectory(ctx, destination, nil); err != nil {
+		return TransferResponse{}, err
+	}
+	existing, err := destination.listExisting(ctx, o.Sync)
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	properties := func(ctx context.Context, p treePath) (*file.SMBProperties, *file.Permissions, error) {
+		smbProperties, err := p.smbProperties()
+		if err != nil {
+			return nil, nil, err
+		}
+		if options.IgnorePermissions {
+			return smbProperties, nil, nil
+		}
+		key, err := permissions.destinationKey(ctx, p.permissionKey)
+		if err != nil || key == nil {
+			return smbProperties, nil, err
+		}
+		return smbProperties, &file.Permissions{PermissionKey: key}, nil
+	}
+	transfer := func(ctx context.Context, p treePath) (int64, bool, error) {
+		smbProperties, filePermissions, err := properties(ctx, p)
+		if err != nil {
+			return 0, false, err
+		}
+		if p.isDir {
+			return 0, false, createDirectory(ctx, destination.descendant(p.path), &CreateOptions{FileSMBProperties: smbProperties, FilePermissions: filePermissions})
+		}
+		if dst, ok := existing[p.path]; p.unchanged(dst, ok) {
+			return 0, true, nil
+		}
+		n, err := d.copyFile(ctx, destination, p, smbProperties, filePermissions, o.ChunkSize)
+		return n, false, err
+	}
+	finish := func(ctx context.Context, p treePath) error {
+		// creating the children of the directory changed its times
+		smbProperties, err := p.smbProperties()
+		if err != nil {
+			return err
+		}
+		_, err = destination.descendant(p.path).SetProperties(ctx, &SetPropertiesOptions{FileSMBProperties: smbProperties})
+		return err
+	}
+	return transferTree(ctx, tree, o, transfer, finish)
+}
+
+// copyFile streams the content of the file p beneath the directory to the same path beneath destination, and sets
+// the SMB properties and the permissions of the copy.
+func (d *Client) copyFile(ctx context.Context, destination *Client, p treePath, smbProperties *file.SMBProperties, permissions *file.Permissions, chunkSize int64) (int64, error) {
+	resp, err := d.descendantFile(p.path).DownloadStream(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	body := resp.NewRetryReader(ctx, nil)
+	defer body.Close()
+
+	size := int64(0)
+	if resp.ContentLength != nil {
+		size = *resp.ContentLength
+	}
+	headers := &file.HTTPHeaders{
+		CacheControl:       resp.CacheControl,
+		ContentDisposition: resp.ContentDisposition,
+		ContentEncoding:    resp.ContentEncoding,
+		ContentLanguage:    resp.ContentLanguage,
+		ContentType:        resp.ContentType,
+	}
+
+	// the file is created without its attributes, which could make it read-only, and they're set with the times
+	// once the content is written
+	target := destination.descendantFile(p.path)
+	_, err = target.Create(ctx, size, &file.CreateOptions{
+		Permissions: permissions,
+		HTTPHeaders: headers,
+		Metadata:    resp.Metadata,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if size > 0 {
+		if err := target.UploadStream(ctx, body, &file.UploadStreamOptions{ChunkSize: chunkSize}); err != nil {
+			return 0, err
+		}
+	}
+	_, err = target.SetHTTPHeaders(ctx, &file.SetHTTPHeadersOptions{SMBProperties: smbProperties, HTTPHeaders: headers})
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// permissionMapper maps the permission keys of a source share to the keys of the same permissions in a
+// destination share.
+type permissionMapper struct {
+	source      *generated.ShareClient
+	destination *generated.ShareClient
+	sameShare   bool
+
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+func newPermissionMapper(source *Client, destination *Client) (*permissionMapper, error) {
+	sourceShare, sourceURL, err := source.shareClient()
+	if err != nil {
+		return nil, err
+	}
+	destinationShare, destinationURL, err := destination.shareClient()
+	if err != nil {
+		return nil, err
+	}
+	return &permissionMapper{
+		source:      sourceShare,
+		destination: destinationShare,
+		sameShare:   sourceURL == destinationURL,
+		keys:        map[string]string{},
+	}, nil
+}
+
+// destinationKey returns the key in the destination share of the permission with the source key; the permission is
+// created in the destination share the first time the key is seen.
+func (m *permissionMapper) destinationKey(ctx context.Context, key *string) (*string, error) {
+	if key == nil || m.sameShare {
+		return key, nil
+	}
+	m.mu.Lock()
+	destinationKey, ok := m.keys[*key]
+	m.mu.Unlock()
+	if ok {
+		return &destinationKey, nil
+	}
+
+	permission, err := m.source.GetPermission(ctx, *key, nil)
+	if err != nil {
+		return nil, err
+	}
+	created, err := m.destination.CreatePermission(ctx, generated.SharePermission{Permission: permission.Permission, Format: permission.Format}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if created.FilePermissionKey == nil {
+		return nil, fmt.Errorf("no permission key was returned for permission %s", *key)
+	}
+
+	m.mu.Lock()
+	m.keys[*key] = *created.FilePermissionKey
+	m.mu.Unlock()
+	return created.FilePermissionKey, nil
+}
+
+// shareClient returns a client of the share of the directory, and the URL of the share without query parameters.
+func (d *Client) shareClient() (*generated.ShareClient, string, error) {
+	urlParts, err := sas.ParseURL(d.URL())
+	if err != nil {
+		return nil, "", err
+	}
+	urlParts.DirectoryOrFilePath = ""
+	urlParts.ShareSnapshot = ""
+	shareURL := urlParts.String()
+	urlParts.SAS = sas.QueryParameters{}
+	urlParts.UnparsedParams = ""
+
+	var intent *generated.ShareTokenIntent
+	if clientOptions := d.getClientOptions(); clientOptions != nil {
+		intent = clientOptions.FileRequestIntent
+	}
+	return generated.NewShareClient(shareURL, intent, d.generated().InternalClient()), urlParts.String(), nil
+}
+
+// listTree returns the paths beneath the directory, with their SMB properties.
+func (d *Client) listTree(ctx context.Context) ([]treePath, error) {
+	var tree []treePath
+	var list func(dir *Client, prefix string) error
+	list = func(dir *Client, prefix string) error {
+		pager := dir.NewListFilesAndDirectoriesPager(&ListFilesAndDirectoriesOptions{
+			Include:             ListFilesInclude{Timestamps: true, ETag: true, Attributes: true, PermissionKey: true},
+			IncludeExtendedInfo: to.Ptr(true),
+		})
+		var subdirectories []string
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return err
+			}
+			if page.Segment == nil {
+				continue
+			}
+			for _, item := range page.Segment.Directories {
+				if item == nil || item.Name == nil {
+					continue
+				}
+				tree = append(tree, listedTreePath(prefix+*item.Name, true, item.Attributes, item.PermissionKey, item.Properties))
+				subdirectories = append(subdirectories, *item.Name)
+			}
+			for _, item := range page.Segment.Files {
+				if item == nil || item.Name == nil {
+					continue
+				}
+				tree = append(tree, listedTreePath(prefix+*item.Name, false, item.Attributes, item.PermissionKey, item.Properties))
+			}
+		}
+		for _, name := range subdirectories {
+			if err := list(dir.NewSubdirectoryClient(name), prefix+name+"/"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := list(d, ""); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// listExisting returns the files beneath the directory by path if sync is true, to compare them with the files
+// that are transferred.
+func (d *Client) listExisting(ctx context.Context, sync bool) (map[string]treePath, error) {
+	if !sync {
+		return nil, nil
+	}
+	tree, err := d.listTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]treePath, len(tree))
+	for _, p := range tree {
+		existing[p.path] = p
+	}
+	return existing, nil
+}
+
+func listedTreePath(p string, isDir bool, attributes *string, permissionKey *string, properties *FileProperty) treePath {
+	item := treePath{path: p, isDir: isDir, attributes: attributes, permissionKey: permissionKey}
+	if properties != nil {
+		if properties.ContentLength != nil && !isDir {
+			item.size = *properties.ContentLength
+		}
+		item.creationTime = properties.CreationTime
+		item.lastWriteTime = properties.LastWriteTime
+		item.changeTime = properties.ChangeTime
+	}
+	return item
+}
+
+func localTreePath(p string, info fs.FileInfo) treePath {
+	item := treePath{path: p, isDir: info.IsDir(), lastWriteTime: to.Ptr(info.ModTime())}
+	if !item.isDir {
+		item.size = info.Size()
+	}
+	return item
+}
+
+// setLocalTimes sets the modification time of the local file or directory name to the last write time of p.
+func setLocalTimes(name string, p treePath) error {
+	if p.lastWriteTime == nil {
+		return nil
+	}
+	return os.Chtimes(name, time.Time{}, *p.lastWriteTime)
+}
+
+// descendant returns a Client for the directory at the relative path p beneath the directory.
+func (d *Client) descendant(p string) *Client {
+	dir := d
+	for _, name := range strings.Split(p, "/") {
+		dir = dir.NewSubdirectoryClient(name)
+	}
+	return dir
+}
+
+// descendantFile returns a file.Client for the file at the relative path p beneath the directory.
+func (d *Client) descendantFile(p string) *file.Client {
+	parent, name := gopath.Split(p)
+	if parent == "" {
+		return d.NewFileClient(name)
+	}
+	return d.descendant(strings.TrimSuffix(parent, "/")).NewFileClient(name)
+}
+
+// createDirectory creates the directory with options, or sets its properties if it already exists.
+func createDirectory(ctx context.Context, d *Client, options *CreateOptions) error {
+	_, err := d.Create(ctx, options)
+	if !fileerror.HasCode(err, fileerror.ResourceAlreadyExists) {
+		return err
+	}
+	if options == nil {
+		return nil
+	}
+	_, err = d.SetProperties(ctx, &SetPropertiesOptions{FileSMBProperties: options.FileSMBProperties, FilePermissions: options.FilePermissions})
+	return err
+}
+
+// transferTree transfers the paths of tree with transfer, which reports whether a file was skipped. The directories
+// are transferred first, parents before children, then the files, and then finish is called for the directories, for the properties that
+// change as their children are created. Up to o.Concurrency paths are transferred in parallel.
+func transferTree(ctx context.Context, tree []treePath, o TransferOptions, transfer func(ctx context.Context, p treePath) (int64, bool, error), finish func(ctx context.Context, p treePath) error) (TransferResponse, error) {
+	resp := TransferResponse{}
+	var mu sync.Mutex
+	report := func(result TransferredPath) {
+		mu.Lock()
+		defer mu.Unlock()
+		resp.Paths = append(resp.Paths, result)
+		resp.BytesTransferred += result.Bytes
+		switch {
+		case result.Err != nil:
+			resp.Failed++
+		case result.Skipped:
+			resp.Skipped++
+		default:
+			resp.Succeeded++
+		}
+		if o.OnResult != nil {
+			o.OnResult(result)
+		}
+	}
+	forEach := func(paths []treePath, do func(p treePath)) {
+		sem := make(chan struct{}, o.Concurrency)
+		var wg sync.WaitGroup
+		for _, p := range paths {
+			if ctx.Err() != nil {
+				break
+			}
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(p treePath) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				do(p)
+			}(p)
+		}
+		wg.Wait()
+	}
+
+	// the directories are created level by level, so that the parent of a directory exists when it's created
+	var levels [][]treePath
+	var files, created []treePath
+	for _, p := range tree {
+		if !p.isDir {
+			files = append(files, p)
+			continue
+		}
+		depth := strings.Count(p.path, "/")
+		for len(levels) <= depth {
+			levels = append(levels, nil)
+		}
+		levels[depth] = append(levels[depth], p)
+	}
+	for _, dirs := range levels {
+		forEach(dirs, func(p treePath) {
+			if _, _, err := transfer(ctx, p); err != nil {
+				report(TransferredPath{Path: p.path, IsDirectory: true, Err: err})
+				return
+			}
+			mu.Lock()
+			created = append(created, p)
+			mu.Unlock()
+		})
+	}
+	forEach(files, func(p treePath) {
+		n, skipped, err := transfer(ctx, p)
+		report(TransferredPath{Path: p.path, Bytes: n, Skipped: skipped, Err: err})
+	})
+	forEach(created, func(p treePath) {
+		report(TransferredPath{Path: p.path, IsDirectory: true, Err: finish(ctx, p)})
+	})
+	if err := ctx.Err(); err != nil {
+		return resp, err
+	}
+
+	sort.Slice(resp.Paths, func(i, j int) bool {
+		return resp.Paths[i].Path < resp.Paths[j].Path
+	})
+	return resp, nil
+}
@@ -0,0 +1,403 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package directory_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/directory"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/fileerror"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeNow = "2024-05-01T00:00:00.0000000Z"
+
+type fakeSMBItem struct {
+	isDir         bool
+	data          []byte
+	contentType   string
+	metadata      map[string]string
+	attributes    string
+	creationTime  string
+	lastWriteTime string
+	changeTime    string
+	permissionKey string
+}
+
+// shareTransport is an in-memory set of shares that supports the directory, file and permission operations used
+// by the tree transfers. Items are keyed by their path including the share name.
+type shareTransport struct {
+	mu          sync.Mutex
+	items       map[string]*fakeSMBItem
+	permissions map[string]string
+
+	rangesWritten     int
+	permissionFetches int
+}
+
+func newShareTransport() *shareTransport {
+	return &shareTransport{items: map[string]*fakeSMBItem{}, permissions: map[string]string{}}
+}
+
+func (f *shareTransport) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	header := http.Header{}
+	respond := func(status int, body string) (*http.Response, error) {
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	}
+	fail := func(status int, code fileerror.Code) (*http.Response, error) {
+		header.Set("x-ms-error-code", string(code))
+		return respond(status, "")
+	}
+	name, err := url.PathUnescape(strings.Trim(req.URL.EscapedPath(), "/"))
+	if err != nil {
+		return nil, err
+	}
+	share, _, _ := strings.Cut(name, "/")
+	q := req.URL.Query()
+	item := f.items[name]
+
+	switch {
+	case q.Get("restype") == "share" && q.Get("comp") == "filepermission":
+		if req.Method == http.MethodGet {
+			f.permissionFetches++
+			permission, ok := f.permissions[req.Header["x-ms-file-permission-key"][0]]
+			if !ok {
+				return fail(http.StatusNotFound, fileerror.ResourceNotFound)
+			}
+			body, _ := json.Marshal(map[string]string{"permission": permission})
+			return respond(http.StatusOK, string(body))
+		}
+		var body struct {
+			Permission string `json:"permission"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("%s-key-%d", share, len(f.permissions))
+		f.permissions[key] = body.Permission
+		header.Set("x-ms-file-permission-key", key)
+		return respond(http.StatusCreated, "")
+
+	case q.Get("restype") == "directory" && q.Get("comp") == "list":
+		if item == nil || !item.isDir {
+			return fail(http.StatusNotFound, fileerror.ResourceNotFound)
+		}
+		return respond(http.StatusOK, f.list(name))
+
+	case q.Get("restype") == "directory" && req.Method == http.MethodPut:
+		if q.Get("comp") == "properties" {
+			if item == nil {
+				return fail(http.StatusNotFound, fileerror.ResourceNotFound)
+			}
+			f.setProperties(item, req)
+			return respond(http.StatusOK, "")
+		}
+		if item != nil {
+			return fail(http.StatusConflict, fileerror.ResourceAlreadyExists)
+		}
+		if parent := f.items[filepath.Dir(name)]; name != share && (parent == nil || !parent.isDir) {
+			return fail(http.StatusNotFound, fileerror.ParentNotFound)
+		}
+		item = &fakeSMBItem{isDir: true, attributes: "Directory", creationTime: fakeNow, lastWriteTime: fakeNow, changeTime: fakeNow, permissionKey: "inherited"}
+		f.setProperties(item, req)
+		f.items[name] = item
+		return respond(http.StatusCreated, "")
+
+	case req.Method == http.MethodPut && q.Get("comp") == "range":
+		if item == nil || item.isDir {
+			return fail(http.StatusNotFound, fileerror.ResourceNotFound)
+		}
+		if strings.Contains(item.attributes, "ReadOnly") {
+			return fail(http.StatusForbidden, fileerror.ReadOnlyAttribute)
+		}
+		var start, end int
+		if _, err := fmt.Sscanf(req.Header["x-ms-range"][0], "bytes=%d-%d", &start, &end); err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		copy(item.data[start:end+1], data)
+		f.rangesWritten++
+		return respond(http.StatusCreated, "")
+
+	case req.Method == http.MethodPut && q.Get("comp") == "properties":
+		if item == nil || item.isDir {
+			return fail(http.StatusNotFound, fileerror.ResourceNotFound)
+		}
+		f.setProperties(item, req)
+		item.contentType = strings.Join(req.Header["x-ms-content-type"], "")
+		return respond(http.StatusOK, "")
+
+	case req.Method == http.MethodPut:
+		if parent := f.items[filepath.Dir(name)]; parent == nil || !parent.isDir {
+			return fail(http.StatusNotFound, fileerror.ParentNotFound)
+		}
+		size, err := strconv.Atoi(req.Header["x-ms-content-length"][0])
+		if err != nil {
+			return nil, err
+		}
+		item = &fakeSMBItem{data: make([]byte, size), attributes: "Archive", creationTime: fakeNow, lastWriteTime: fakeNow, changeTime: fakeNow, permissionKey: "inherited", metadata: map[string]string{}}
+		f.setProperties(item, req)
+		item.contentType = strings.Join(req.Header["x-ms-content-type"], "")
+		for key, values := range req.Header {
+			if strings.HasPrefix(strings.ToLower(key), "x-ms-meta-") {
+				item.metadata[strings.TrimPrefix(strings.ToLower(key), "x-ms-meta-")] = values[0]
+			}
+		}
+		f.items[name] = item
+		return respond(http.StatusCreated, "")
+
+	case req.Method == http.MethodGet || req.Method == http.MethodHead:
+		if item == nil || item.isDir {
+			return fail(http.StatusNotFound, fileerror.ResourceNotFound)
+		}
+		header.Set("x-ms-file-attributes", item.attributes)
+		header.Set("x-ms-file-creation-time", item.creationTime)
+		header.Set("x-ms-file-last-write-time", item.lastWriteTime)
+		header.Set("x-ms-file-change-time", item.changeTime)
+		header.Set("x-ms-file-permission-key", item.permissionKey)
+		if item.contentType != "" {
+			header.Set("Content-Type", item.contentType)
+		}
+		for key, value := range item.metadata {
+			header.Set("x-ms-meta-"+key, value)
+		}
+		data := item.data
+		status := http.StatusOK
+		if r := req.Header["x-ms-range"]; len(r) > 0 {
+			var start, end int
+			if _, err := fmt.Sscanf(r[0], "bytes=%d-%d", &start, &end); err == nil {
+				end = min(end, len(data)-1)
+				header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+				data = data[start : end+1]
+				status = http.StatusPartialContent
+			}
+		}
+		if req.Method == http.MethodHead {
+			header.Set("Content-Length", strconv.Itoa(len(data)))
+			return &http.Response{StatusCode: status, Header: header, Body: http.NoBody, Request: req}, nil
+		}
+		return respond(status, string(data))
+	}
+	return respond(http.StatusBadRequest, "")
+}
+
+// setProperties applies the SMB properties and the permission key of the request to item.
+func (f *shareTransport) setProperties(item *fakeSMBItem, req *http.Request) {
+	for key, field := range map[string]*string{
+		"x-ms-file-attributes":      &item.attributes,
+		"x-ms-file-creation-time":   &item.creationTime,
+		"x-ms-file-last-write-time": &item.lastWriteTime,
+		"x-ms-file-change-time":     &item.changeTime,
+		"x-ms-file-permission-key":  &item.permissionKey,
+	} {
+		if values := req.Header[key]; len(values) > 0 && values[0] != "preserve" {
+			*field = values[0]
+			if values[0] == "now" {
+				*field = fakeNow
+			}
+		}
+	}
+}
+
+// list returns the listing of the children of the directory name.
+func (f *shareTransport) list(name string) string {
+	var names []string
+	for p := range f.items {
+		if filepath.Dir(p) == name {
+			names = append(names, p)
+		}
+	}
+	sort.Strings(names)
+	var entries strings.Builder
+	for _, p := range names {
+		item := f.items[p]
+		element, length := "File", fmt.Sprintf("<Content-Length>%d</Content-Length>", len(item.data))
+		if item.isDir {
+			element, length = "Directory", ""
+		}
+		fmt.Fprintf(&entries, "<%s><Name>%s</Name><Properties>%s<CreationTime>%s</CreationTime><LastWriteTime>%s</LastWriteTime><ChangeTime>%s</ChangeTime></Properties><Attributes>%s</Attributes><PermissionKey>%s</PermissionKey></%s>",
+			element, filepath.Base(p), length, item.creationTime, item.lastWriteTime, item.changeTime, item.attributes, item.permissionKey, element)
+	}
+	return `<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Entries>` + entries.String() + `</Entries><NextMarker /></EnumerationResults>`
+}
+
+func newTransferTestClient(t *testing.T, transport *shareTransport, path string) *directory.Client {
+	client, err := directory.NewClientWithNoCredential("https://account.file.core.windows.net/"+path, &directory.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: transport, Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func writeLocalFile(t *testing.T, name string, content string, modTime time.Time) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+	require.NoError(t, os.WriteFile(name, []byte(content), 0644))
+	require.NoError(t, os.Chtimes(name, time.Time{}, modTime))
+}
+
+func TestUploadAndDownloadDirectory(t *testing.T) {
+	modTime := time.Date(2023, 3, 4, 5, 6, 7, 123456789, time.UTC)
+	src := t.TempDir()
+	writeLocalFile(t, filepath.Join(src, "a.txt"), "hello", modTime)
+	writeLocalFile(t, filepath.Join(src, "sub", "b.txt"), "hello world", modTime.Add(time.Hour))
+	writeLocalFile(t, filepath.Join(src, "sub", "deeper", "empty.txt"), "", modTime)
+	require.NoError(t, os.Mkdir(filepath.Join(src, "emptydir"), 0755))
+	require.NoError(t, os.Chtimes(filepath.Join(src, "sub"), time.Time{}, modTime))
+
+	transport := newShareTransport()
+	transport.items["share"] = &fakeSMBItem{isDir: true}
+	client := newTransferTestClient(t, transport, "share/root")
+
+	var results int
+	resp, err := client.UploadDirectory(context.Background(), src, &directory.UploadDirectoryOptions{
+		TransferOptions: directory.TransferOptions{OnResult: func(directory.TransferredPath) { results++ }},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 6, results)
+	require.Equal(t, 6, resp.Succeeded)
+	require.Zero(t, resp.Failed)
+	require.Equal(t, int64(16), resp.BytesTransferred)
+	require.Equal(t, "a.txt", resp.Paths[0].Path)
+	require.Equal(t, []byte("hello world"), transport.items["share/root/sub/b.txt"].data)
+	require.Equal(t, "2023-03-04T06:06:07.1234567Z", transport.items["share/root/sub/b.txt"].lastWriteTime)
+	// the time of a directory is set after its children are created
+	require.Equal(t, "2023-03-04T05:06:07.1234567Z", transport.items["share/root/sub"].lastWriteTime)
+	require.True(t, transport.items["share/root/emptydir"].isDir)
+
+	// unchanged files are skipped
+	rangesWritten := transport.rangesWritten
+	writeLocalFile(t, filepath.Join(src, "a.txt"), "HELLO", modTime.Add(time.Minute))
+	resp, err = client.UploadDirectory(context.Background(), src, &directory.UploadDirectoryOptions{
+		TransferOptions: directory.TransferOptions{Sync: true, Concurrency: 1},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, resp.Skipped)
+	require.Equal(t, 4, resp.Succeeded)
+	require.Equal(t, int64(5), resp.BytesTransferred)
+	require.Equal(t, rangesWritten+1, transport.rangesWritten)
+	require.Equal(t, []byte("HELLO"), transport.items["share/root/a.txt"].data)
+
+	dst := t.TempDir()
+	resp, err = client.DownloadDirectory(context.Background(), dst, nil)
+	require.NoError(t, err)
+	require.Equal(t, 6, resp.Succeeded)
+	require.Equal(t, int64(16), resp.BytesTransferred)
+	for name, content := range map[string]string{"a.txt": "HELLO", "sub/b.txt": "hello world", "sub/deeper/empty.txt": ""} {
+		data, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		require.Equal(t, content, string(data))
+	}
+	info, err := os.Stat(filepath.Join(dst, "sub", "b.txt"))
+	require.NoError(t, err)
+	require.True(t, info.ModTime().Equal(modTime.Add(time.Hour).Truncate(100*time.Nanosecond)))
+	info, err = os.Stat(filepath.Join(dst, "sub"))
+	require.NoError(t, err)
+	require.True(t, info.ModTime().Equal(modTime.Truncate(100*time.Nanosecond)))
+
+	resp, err = client.DownloadDirectory(context.Background(), dst, &directory.DownloadDirectoryOptions{
+		TransferOptions: directory.TransferOptions{Sync: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, resp.Skipped)
+	require.Zero(t, resp.BytesTransferred)
+}
+
+func TestCopyDirectory(t *testing.T) {
+	transport := newShareTransport()
+	transport.permissions["source-key"] = "O:BAG:SYD:(A;;FA;;;BA)"
+	transport.items["share1"] = &fakeSMBItem{isDir: true}
+	transport.items["share2"] = &fakeSMBItem{isDir: true}
+	transport.items["share1/src"] = &fakeSMBItem{isDir: true, attributes: "Directory", permissionKey: "source-key"}
+	transport.items["share1/src/dir"] = &fakeSMBItem{isDir: true, attributes: "Directory | Hidden", creationTime: "2020-01-01T00:00:00.0000000Z",
+		lastWriteTime: "2020-01-02T00:00:00.0000000Z", changeTime: "2020-01-03T00:00:00.0000000Z", permissionKey: "source-key"}
+	transport.items["share1/src/dir/report.txt"] = &fakeSMBItem{data: []byte("quarterly report"), contentType: "text/plain", metadata: map[string]string{"owner": "finance"},
+		attributes: "ReadOnly | Archive", creationTime: "2021-01-01T00:00:00.1234567Z", lastWriteTime: "2021-01-02T00:00:00.1234567Z",
+		changeTime: "2021-01-03T00:00:00.1234567Z", permissionKey: "source-key"}
+	transport.items["share1/src/empty"] = &fakeSMBItem{attributes: "None", creationTime: fakeNow, lastWriteTime: fakeNow, changeTime: fakeNow, permissionKey: "source-key"}
+
+	source := newTransferTestClient(t, transport, "share1/src")
+	resp, err := source.CopyDirectory(context.Background(), newTransferTestClient(t, transport, "share2/dst"), nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, resp.Succeeded)
+	require.Zero(t, resp.Failed)
+	require.Equal(t, int64(16), resp.BytesTransferred)
+
+	dir := transport.items["share2/dst/dir"]
+	require.Equal(t, "Hidden|Directory", dir.attributes)
+	require.Equal(t, "2020-01-01T00:00:00.0000000Z", dir.creationTime)
+	require.Equal(t, "2020-01-02T00:00:00.0000000Z", dir.lastWriteTime)
+	require.Equal(t, "2020-01-03T00:00:00.0000000Z", dir.changeTime)
+
+	// the file is written before it's made read-only
+	copied := transport.items["share2/dst/dir/report.txt"]
+	require.Equal(t, []byte("quarterly report"), copied.data)
+	require.Equal(t, "ReadOnly|Archive", copied.attributes)
+	require.Equal(t, "2021-01-01T00:00:00.1234567Z", copied.creationTime)
+	require.Equal(t, "2021-01-02T00:00:00.1234567Z", copied.lastWriteTime)
+	require.Equal(t, "2021-01-03T00:00:00.1234567Z", copied.changeTime)
+	require.Equal(t, "text/plain", copied.contentType)
+	require.Equal(t, map[string]string{"owner": "finance"}, copied.metadata)
+
+	// the permission is created in the destination share once and shared by all the copies
+	key := copied.permissionKey
+	require.True(t, strings.HasPrefix(key, "share2-key-"))
+	require.Equal(t, "O:BAG:SYD:(A;;FA;;;BA)", transport.permissions[key])
+	require.Equal(t, key, dir.permissionKey)
+	require.Equal(t, key, transport.items["share2/dst/empty"].permissionKey)
+	require.Equal(t, 1, transport.permissionFetches)
+
+	// within a share, the permission keys are kept as is
+	resp, err = source.CopyDirectory(context.Background(), newTransferTestClient(t, transport, "share1/copy"), &directory.CopyDirectoryOptions{
+		TransferOptions: directory.TransferOptions{Sync: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, resp.Succeeded)
+	require.Equal(t, "source-key", transport.items["share1/copy/dir/report.txt"].permissionKey)
+	require.Equal(t, 1, transport.permissionFetches)
+
+	// a second incremental copy skips the unchanged files
+	resp, err = source.CopyDirectory(context.Background(), newTransferTestClient(t, transport, "share1/copy"), &directory.CopyDirectoryOptions{
+		TransferOptions: directory.TransferOptions{Sync: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, resp.Skipped)
+	require.Equal(t, 1, resp.Succeeded)
+}
+
+func TestTransferOptionsValidation(t *testing.T) {
+	client := newTransferTestClient(t, newShareTransport(), "share/root")
+	_, err := client.UploadDirectory(context.Background(), t.TempDir(), &directory.UploadDirectoryOptions{
+		TransferOptions: directory.TransferOptions{Concurrency: -1},
+	})
+	require.Error(t, err)
+	_, err = client.DownloadDirectory(context.Background(), t.TempDir(), &directory.DownloadDirectoryOptions{
+		TransferOptions: directory.TransferOptions{ChunkSize: -1},
+	})
+	require.Error(t, err)
+}
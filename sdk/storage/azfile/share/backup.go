@@ -0,0 +1,476 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package share
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/directory"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/file"
+)
+
+// snapshotPath is a file or directory of a share snapshot.
+type snapshotPath struct {
+	// path is relative to the root of the share, with slashes as separators
+	path          string
+	isDir         bool
+	size          int64
+	fileID        string
+	etag          azcore.ETag
+	lastWriteTime *time.Time
+}
+
+// sameFile returns true if p and other are the same file, which is identified by its file ID if the service
+// returned it, and by its path otherwise.
+func (p snapshotPath) sameFile(other snapshotPath) bool {
+	if p.isDir || other.isDir {
+		return false
+	}
+	if p.fileID != "" && other.fileID != "" {
+		return p.fileID == other.fileID
+	}
+	return p.path == other.path
+}
+
+// fileChange is a file of the snapshot that's backed up, and the same file in the previous snapshot, if any.
+type fileChange struct {
+	current  snapshotPath
+	previous *snapshotPath
+	change   BackupChange
+	// staged is the temporary local path of a renamed file, before it's moved to its new path
+	staged string
+}
+
+// backupPlan contains the changes between the previous snapshot and the snapshot that's backed up.
+type backupPlan struct {
+	files        []*fileChange
+	addedDirs    []snapshotPath
+	deletedFiles []snapshotPath
+	deletedDirs  []snapshotPath
+	unchanged    int
+}
+
+// planBackup compares the previous snapshot with the current one. A file of the current snapshot that's in the
+// previous one at another path, according to its file ID, was renamed.
+func planBackup(previous []snapshotPath, current []snapshotPath) backupPlan {
+	previousByPath := map[string]snapshotPath{}
+	previousByID := map[string]snapshotPath{}
+	for _, p := range previous {
+		previousByPath[p.path] = p
+		if !p.isDir && p.fileID != "" {
+			previousByID[p.fileID] = p
+		}
+	}
+	currentByPath := map[string]snapshotPath{}
+	currentByID := map[string]snapshotPath{}
+	for _, p := range current {
+		currentByPath[p.path] = p
+		if !p.isDir && p.fileID != "" {
+			currentByID[p.fileID] = p
+		}
+	}
+
+	plan := backupPlan{}
+	for _, c := range current {
+		p, ok := previousByPath[c.path]
+		if c.isDir {
+			if !ok || !p.isDir {
+				plan.addedDirs = append(plan.addedDirs, c)
+			}
+			continue
+		}
+		switch {
+		case ok && c.sameFile(p):
+			if c.etag == p.etag && c.size == p.size {
+				plan.unchanged++
+				continue
+			}
+			plan.files = append(plan.files, &fileChange{current: c, previous: &p, change: BackupChangeModified})
+		case c.fileID != "" && previousByID[c.fileID].path != "":
+			// the file was renamed, unless the previous file is still at its path
+			renamed := previousByID[c.fileID]
+			if other, ok := currentByPath[renamed.path]; !ok || !other.sameFile(renamed) {
+				plan.files = append(plan.files, &fileChange{current: c, previous: &renamed, change: BackupChangeRenamed})
+				continue
+			}
+			plan.files = append(plan.files, &fileChange{current: c, change: BackupChangeAdded})
+		default:
+			plan.files = append(plan.files, &fileChange{current: c, change: BackupChangeAdded})
+		}
+	}
+
+	for _, p := range previous {
+		c, ok := currentByPath[p.path]
+		if p.isDir {
+			if !ok || !c.isDir {
+				plan.deletedDirs = append(plan.deletedDirs, p)
+			}
+			continue
+		}
+		if ok && c.sameFile(p) {
+			continue
+		}
+		if renamed, ok := currentByID[p.fileID]; p.fileID != "" && ok && renamed.path != p.path {
+			continue
+		}
+		plan.deletedFiles = append(plan.deletedFiles, p)
+	}
+	// the deleted directories are removed from the deepest up
+	sort.Slice(plan.deletedDirs, func(i, j int) bool {
+		return plan.deletedDirs[i].path > plan.deletedDirs[j].path
+	})
+	return plan
+}
+
+// Backup backs up a snapshot of the share into the local directory localPath, which is created if it doesn't exist.
+// If options.PreviousSnapshot is specified, localPath must contain the backup of that snapshot, and the backup is
+// incremental: only the ranges of the files that changed since the previous snapshot are downloaded, renamed files
+// are moved and deleted files and directories are removed. Renamed files are identified by their file ID.
+// The last write time of every file that's downloaded is preserved as its modification time.
+// The response contains the snapshot that was backed up, and reports the result of every path that changed; an error
+// is returned only if the snapshot couldn't be created or listed or ctx was canceled.
+func (s *Client) Backup(ctx context.Context, localPath string, options *BackupOptions) (BackupResponse, error) {
+	o, err := options.format()
+	if err != nil {
+		return BackupResponse{}, err
+	}
+	snapshot := o.Snapshot
+	if snapshot == nil {
+		created, err := s.CreateSnapshot(ctx, nil)
+		if err != nil {
+			return BackupResponse{}, err
+		}
+		if created.Snapshot == nil {
+			return BackupResponse{}, errors.New("no snapshot was returned when the snapshot of the share was created")
+		}
+		snapshot = created.Snapshot
+	}
+
+	snapshotClient, err := s.WithSnapshot(*snapshot)
+	if err != nil {
+		return BackupResponse{}, err
+	}
+	current, err := listSnapshot(ctx, snapshotClient.NewRootDirectoryClient())
+	if err != nil {
+		return BackupResponse{}, err
+	}
+	var previous []snapshotPath
+	if o.PreviousSnapshot != nil {
+		previousClient, err := s.WithSnapshot(*o.PreviousSnapshot)
+		if err != nil {
+			return BackupResponse{}, err
+		}
+		if previous, err = listSnapshot(ctx, previousClient.NewRootDirectoryClient()); err != nil {
+			return BackupResponse{}, err
+		}
+	}
+	for _, p := range append(current, previous...) {
+		if !filepath.IsLocal(filepath.FromSlash(p.path)) {
+			return BackupResponse{}, fmt.Errorf("path %q is outside of %s", p.path, localPath)
+		}
+	}
+	if err := os.MkdirAll(localPath, 0755); err != nil {
+		return BackupResponse{}, err
+	}
+
+	b := &backup{
+		root:             snapshotClient.NewRootDirectoryClient(),
+		localPath:        localPath,
+		previousSnapshot: o.PreviousSnapshot,
+		onResult:         o.OnResult,
+		resp:             BackupResponse{Snapshot: *snapshot},
+	}
+	plan := planBackup(previous, current)
+	b.resp.Unchanged = plan.unchanged
+	if err := b.run(ctx, plan, o.Concurrency); err != nil {
+		return b.resp, err
+	}
+
+	sort.Slice(b.resp.Paths, func(i, j int) bool {
+		return b.resp.Paths[i].Path < b.resp.Paths[j].Path
+	})
+	return b.resp, nil
+}
+
+// backup is a backup of a snapshot in progress.
+type backup struct {
+	root             *directory.Client
+	localPath        string
+	previousSnapshot *string
+	onResult         func(BackedUpPath)
+
+	mu   sync.Mutex
+	resp BackupResponse
+}
+
+func (b *backup) report(result BackedUpPath) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resp.Paths = append(b.resp.Paths, result)
+	b.resp.BytesDownloaded += result.Bytes
+	if result.Err != nil {
+		b.resp.Failed++
+	}
+	if b.onResult != nil {
+		b.onResult(result)
+	}
+}
+
+func (b *backup) local(p string) string {
+	return filepath.Join(b.localPath, filepath.FromSlash(p))
+}
+
+// run applies the changes of plan to the local mirror. The renamed files are moved out of the way first, so that
+// deleting the files and directories, and the other renames, don't affect them.
+func (b *backup) run(ctx context.Context, plan backupPlan, concurrency int) error {
+	var staging string
+	for i, f := range plan.files {
+		if f.change != BackupChangeRenamed {
+			continue
+		}
+		if staging == "" {
+			var err error
+			if staging, err = os.MkdirTemp(b.localPath, ".backup-"); err != nil {
+				return err
+			}
+			defer os.RemoveAll(staging)
+		}
+		f.staged = filepath.Join(staging, strconv.Itoa(i))
+		if err := os.Rename(b.local(f.previous.path), f.staged); err != nil {
+			// the file is downloaded in full instead
+			f.staged = ""
+		}
+	}
+
+	for _, p := range plan.deletedFiles {
+		err := os.Remove(b.local(p.path))
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		b.report(BackedUpPath{Path: p.path, Change: BackupChangeDeleted, Err: err})
+	}
+	for _, p := range plan.deletedDirs {
+		b.report(BackedUpPath{Path: p.path, IsDirectory: true, Change: BackupChangeDeleted, Err: os.RemoveAll(b.local(p.path))})
+	}
+	for _, p := range plan.addedDirs {
+		b.report(BackedUpPath{Path: p.path, IsDirectory: true, Change: BackupChangeAdded, Err: os.MkdirAll(b.local(p.path), 0755)})
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, f := range plan.files {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(f *fileChange) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			n, err := b.backupFile(ctx, f)
+			result := BackedUpPath{Path: f.current.path, Change: f.change, Bytes: n, Err: err}
+			if f.change == BackupChangeRenamed {
+				result.PreviousPath = f.previous.path
+			}
+			b.report(result)
+		}(f)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// backupFile downloads the ranges of the file that changed since the previous snapshot into the local mirror, or the
+// whole file if it's new or its previous backup is missing.
+func (b *backup) backupFile(ctx context.Context, f *fileChange) (int64, error) {
+	name := b.local(f.current.path)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return 0, err
+	}
+	if f.staged != "" {
+		if err := os.Rename(f.staged, name); err != nil {
+			return 0, err
+		}
+	}
+
+	fileClient := b.fileClient(f.current.path)
+	full := f.previous == nil || (f.change == BackupChangeRenamed && f.staged == "")
+	var local *os.File
+	var err error
+	if !full {
+		local, err = os.OpenFile(name, os.O_RDWR, 0)
+		if errors.Is(err, fs.ErrNotExist) {
+			full = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	if full {
+		if local, err = os.Create(name); err != nil {
+			return 0, err
+		}
+		n, err := fileClient.DownloadFile(ctx, local, nil)
+		return n, closeAndSetTimes(local, err, f.current)
+	}
+
+	n, err := b.downloadChanges(ctx, fileClient, local, f)
+	return n, closeAndSetTimes(local, err, f.current)
+}
+
+// downloadChanges writes the ranges of the file that changed since the previous snapshot to local.
+func (b *backup) downloadChanges(ctx context.Context, fileClient *file.Client, local *os.File, f *fileChange) (int64, error) {
+	ranges, err := fileClient.GetRangeList(ctx, &file.GetRangeListOptions{
+		PrevShareSnapshot: b.previousSnapshot,
+		SupportRename:     to.Ptr(f.change == BackupChangeRenamed),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := local.Truncate(f.current.size); err != nil {
+		return 0, err
+	}
+
+	for _, r := range ranges.ClearRanges {
+		if r == nil || r.Start == nil || r.End == nil {
+			continue
+		}
+		if err := writeZeros(local, *r.Start, min(*r.End+1, f.current.size)); err != nil {
+			return 0, err
+		}
+	}
+	var downloaded int64
+	for _, r := range ranges.Ranges {
+		if r == nil || r.Start == nil || r.End == nil || *r.Start >= f.current.size {
+			continue
+		}
+		count := min(*r.End+1, f.current.size) - *r.Start
+		resp, err := fileClient.DownloadStream(ctx, &file.DownloadStreamOptions{Range: file.HTTPRange{Offset: *r.Start, Count: count}})
+		if err != nil {
+			return downloaded, err
+		}
+		body := resp.NewRetryReader(ctx, nil)
+		n, err := io.Copy(io.NewOffsetWriter(local, *r.Start), body)
+		_ = body.Close()
+		downloaded += n
+		if err != nil {
+			return downloaded, err
+		}
+	}
+	return downloaded, nil
+}
+
+// fileClient returns a client of the file at the path p of the snapshot.
+func (b *backup) fileClient(p string) *file.Client {
+	dir := b.root
+	names := strings.Split(p, "/")
+	for _, name := range names[:len(names)-1] {
+		dir = dir.NewSubdirectoryClient(name)
+	}
+	return dir.NewFileClient(names[len(names)-1])
+}
+
+// listSnapshot returns the files and directories beneath root, with their file IDs.
+func listSnapshot(ctx context.Context, root *directory.Client) ([]snapshotPath, error) {
+	var paths []snapshotPath
+	var list func(dir *directory.Client, prefix string) error
+	list = func(dir *directory.Client, prefix string) error {
+		pager := dir.NewListFilesAndDirectoriesPager(&directory.ListFilesAndDirectoriesOptions{
+			Include:             directory.ListFilesInclude{Timestamps: true, ETag: true},
+			IncludeExtendedInfo: to.Ptr(true),
+		})
+		var subdirectories []string
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return err
+			}
+			if page.Segment == nil {
+				continue
+			}
+			for _, item := range page.Segment.Directories {
+				if item == nil || item.Name == nil {
+					continue
+				}
+				paths = append(paths, listedSnapshotPath(prefix+*item.Name, true, item.ID, item.Properties))
+				subdirectories = append(subdirectories, *item.Name)
+			}
+			for _, item := range page.Segment.Files {
+				if item == nil || item.Name == nil {
+					continue
+				}
+				paths = append(paths, listedSnapshotPath(prefix+*item.Name, false, item.ID, item.Properties))
+			}
+		}
+		for _, name := range subdirectories {
+			if err := list(dir.NewSubdirectoryClient(name), prefix+name+"/"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := list(root, ""); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+func listedSnapshotPath(p string, isDir bool, fileID *string, properties *directory.FileProperty) snapshotPath {
+	item := snapshotPath{path: p, isDir: isDir}
+	if fileID != nil {
+		item.fileID = *fileID
+	}
+	if properties != nil {
+		if properties.ContentLength != nil && !isDir {
+			item.size = *properties.ContentLength
+		}
+		if properties.ETag != nil {
+			item.etag = *properties.ETag
+		}
+		item.lastWriteTime = properties.LastWriteTime
+	}
+	return item
+}
+
+// writeZeros writes zeros to f from offset start to end.
+func writeZeros(f *os.File, start int64, end int64) error {
+	if end <= start {
+		return nil
+	}
+	zeros := make([]byte, min(end-start, 64*1024))
+	for offset := start; offset < end; offset += int64(len(zeros)) {
+		if _, err := f.WriteAt(zeros[:min(int64(len(zeros)), end-offset)], offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeAndSetTimes closes the local file of p, and sets its modification time to the last write time of p, unless
+// err isn't nil.
+func closeAndSetTimes(local *os.File, err error, p snapshotPath) error {
+	if closeErr := local.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || p.lastWriteTime == nil {
+		return err
+	}
+	return os.Chtimes(local.Name(), time.Time{}, *p.lastWriteTime)
+}
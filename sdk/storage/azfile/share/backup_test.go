@@ -0,0 +1,356 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package share_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/fileerror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/share"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRangeSize is the granularity of the ranges reported by snapshotTransport.
+const fakeRangeSize = 4
+
+type fakeSnapshotItem struct {
+	isDir         bool
+	id            string
+	data          []byte
+	version       int
+	lastWriteTime time.Time
+}
+
+// snapshotTransport is an in-memory share with snapshots. The files of the share are edited with the methods of the
+// transport, and the snapshots are listed, diffed and downloaded through the client.
+type snapshotTransport struct {
+	mu        sync.Mutex
+	live      map[string]*fakeSnapshotItem
+	snapshots map[string]map[string]*fakeSnapshotItem
+	nextID    int
+
+	rangeLists      int
+	bytesDownloaded int
+}
+
+func newSnapshotTransport() *snapshotTransport {
+	return &snapshotTransport{live: map[string]*fakeSnapshotItem{}, snapshots: map[string]map[string]*fakeSnapshotItem{}}
+}
+
+func (f *snapshotTransport) writeFile(name string, data string) {
+	item := f.live[name]
+	if item == nil {
+		f.nextID++
+		item = &fakeSnapshotItem{id: strconv.Itoa(f.nextID)}
+		f.live[name] = item
+	}
+	item.data = []byte(data)
+	item.version++
+	item.lastWriteTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(f.nextID*100+item.version) * time.Minute)
+}
+
+func (f *snapshotTransport) mkdir(name string) {
+	f.nextID++
+	f.live[name] = &fakeSnapshotItem{isDir: true, id: strconv.Itoa(f.nextID)}
+}
+
+func (f *snapshotTransport) rename(from string, to string) {
+	f.live[to] = f.live[from]
+	delete(f.live, from)
+}
+
+func (f *snapshotTransport) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	header := http.Header{}
+	respond := func(status int, body string) (*http.Response, error) {
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	}
+	fail := func(status int, code fileerror.Code) (*http.Response, error) {
+		header.Set("x-ms-error-code", string(code))
+		return respond(status, "")
+	}
+	p, err := url.PathUnescape(strings.Trim(req.URL.EscapedPath(), "/"))
+	if err != nil {
+		return nil, err
+	}
+	_, name, _ := strings.Cut(p, "/")
+	q := req.URL.Query()
+
+	if q.Get("restype") == "share" && q.Get("comp") == "snapshot" {
+		snapshot := fmt.Sprintf("2024-02-%02dT00:00:00.0000000Z", len(f.snapshots)+1)
+		items := map[string]*fakeSnapshotItem{}
+		for name, item := range f.live {
+			copied := *item
+			copied.data = bytes.Clone(item.data)
+			items[name] = &copied
+		}
+		f.snapshots[snapshot] = items
+		header.Set("x-ms-snapshot", snapshot)
+		return respond(http.StatusCreated, "")
+	}
+
+	items, ok := f.snapshots[q.Get("sharesnapshot")]
+	if !ok {
+		return fail(http.StatusNotFound, fileerror.ShareNotFound)
+	}
+	item := items[name]
+
+	switch {
+	case q.Get("restype") == "directory" && q.Get("comp") == "list":
+		if name != "" && (item == nil || !item.isDir) {
+			return fail(http.StatusNotFound, fileerror.ResourceNotFound)
+		}
+		return respond(http.StatusOK, listSnapshotItems(items, name))
+
+	case item == nil || item.isDir:
+		return fail(http.StatusNotFound, fileerror.ResourceNotFound)
+
+	case q.Get("comp") == "rangelist":
+		f.rangeLists++
+		previousItems, ok := f.snapshots[q.Get("prevsharesnapshot")]
+		if !ok {
+			return fail(http.StatusNotFound, fileerror.ResourceNotFound)
+		}
+		previous := previousItems[name]
+		if strings.Join(req.Header["x-ms-file-support-rename"], "") == "true" {
+			for _, other := range previousItems {
+				if other.id == item.id {
+					previous = other
+				}
+			}
+		}
+		if previous == nil || previous.id != item.id {
+			return fail(http.StatusConflict, fileerror.ResourceNotFound)
+		}
+		header.Set("x-ms-content-length", strconv.Itoa(len(item.data)))
+		return respond(http.StatusOK, diffRanges(previous.data, item.data))
+
+	case req.Method == http.MethodGet || req.Method == http.MethodHead:
+		header.Set("x-ms-file-last-write-time", item.lastWriteTime.Format("2006-01-02T15:04:05.0000000Z"))
+		data := item.data
+		status := http.StatusOK
+		if r := req.Header["x-ms-range"]; len(r) > 0 {
+			var start, end int
+			if _, err := fmt.Sscanf(r[0], "bytes=%d-%d", &start, &end); err == nil {
+				end = min(end, len(data)-1)
+				header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+				data = data[start : end+1]
+				status = http.StatusPartialContent
+			}
+		}
+		if req.Method == http.MethodHead {
+			header.Set("Content-Length", strconv.Itoa(len(data)))
+			return &http.Response{StatusCode: status, Header: header, Body: http.NoBody, Request: req}, nil
+		}
+		f.bytesDownloaded += len(data)
+		return respond(status, string(data))
+	}
+	return respond(http.StatusBadRequest, "")
+}
+
+// listSnapshotItems returns the listing of the children of the directory dir of a snapshot.
+func listSnapshotItems(items map[string]*fakeSnapshotItem, dir string) string {
+	var names []string
+	for name := range items {
+		parent := filepath.Dir(name)
+		if parent == "." {
+			parent = ""
+		}
+		if parent == dir {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var entries strings.Builder
+	for _, name := range names {
+		item := items[name]
+		if item.isDir {
+			fmt.Fprintf(&entries, "<Directory><Name>%s</Name><FileId>%s</FileId><Properties /></Directory>", filepath.Base(name), item.id)
+			continue
+		}
+		fmt.Fprintf(&entries, "<File><Name>%s</Name><FileId>%s</FileId><Properties><Content-Length>%d</Content-Length><Etag>\"%s-%d\"</Etag><LastWriteTime>%s</LastWriteTime></Properties></File>",
+			filepath.Base(name), item.id, len(item.data), item.id, item.version, item.lastWriteTime.Format("2006-01-02T15:04:05.0000000Z"))
+	}
+	return `<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Entries>` + entries.String() + `</Entries><NextMarker /></EnumerationResults>`
+}
+
+// diffRanges returns the range list of the blocks of current that differ from previous; the blocks that were zeroed
+// are cleared ranges.
+func diffRanges(previous []byte, current []byte) string {
+	var ranges strings.Builder
+	for start := 0; start < len(current); start += fakeRangeSize {
+		end := min(start+fakeRangeSize, len(current))
+		block := current[start:end]
+		var before []byte
+		if start < len(previous) {
+			before = previous[start:min(end, len(previous))]
+		}
+		if bytes.Equal(block, before) {
+			continue
+		}
+		element := "Range"
+		if bytes.Count(block, []byte{0}) == len(block) {
+			element = "ClearRange"
+		}
+		fmt.Fprintf(&ranges, "<%s><Start>%d</Start><End>%d</End></%s>", element, start, end-1, element)
+	}
+	return `<?xml version="1.0" encoding="utf-8"?><Ranges>` + ranges.String() + `</Ranges>`
+}
+
+func newBackupTestClient(t *testing.T, transport *snapshotTransport) *share.Client {
+	client, err := share.NewClientWithNoCredential("https://account.file.core.windows.net/share", &share.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: transport, Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func requireMirror(t *testing.T, localPath string, expected map[string]string) {
+	actual := map[string]string{}
+	err := filepath.WalkDir(localPath, func(p string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(localPath, p)
+		actual[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
+func TestIncrementalBackup(t *testing.T) {
+	transport := newSnapshotTransport()
+	transport.writeFile("readme.txt", "hello world")
+	transport.mkdir("docs")
+	transport.writeFile("docs/report.txt", "0000111122223333")
+	transport.writeFile("docs/old.txt", "obsolete")
+	transport.mkdir("tmp")
+	transport.writeFile("tmp/scratch.txt", "scratch")
+	client := newBackupTestClient(t, transport)
+	localPath := t.TempDir()
+
+	var results int
+	full, err := client.Backup(context.Background(), localPath, &share.BackupOptions{OnResult: func(share.BackedUpPath) { results++ }})
+	require.NoError(t, err)
+	require.NotEmpty(t, full.Snapshot)
+	require.Zero(t, full.Failed)
+	require.Equal(t, 6, results)
+	require.Equal(t, int64(42), full.BytesDownloaded)
+	requireMirror(t, localPath, map[string]string{
+		"readme.txt":      "hello world",
+		"docs/report.txt": "0000111122223333",
+		"docs/old.txt":    "obsolete",
+		"tmp/scratch.txt": "scratch",
+	})
+	info, err := os.Stat(filepath.Join(localPath, "docs", "report.txt"))
+	require.NoError(t, err)
+	require.True(t, info.ModTime().Equal(transport.live["docs/report.txt"].lastWriteTime))
+
+	// modify a block of a file and rename it, swap two files, delete a file and a directory, and add a file
+	transport.writeFile("docs/report.txt", "0000xxxx22223333tail")
+	transport.rename("docs/report.txt", "archive.txt")
+	transport.writeFile("a.txt", "first")
+	transport.writeFile("b.txt", "second")
+	transport.rename("a.txt", "swap")
+	transport.rename("b.txt", "a.txt")
+	transport.rename("swap", "b.txt")
+	delete(transport.live, "docs/old.txt")
+	delete(transport.live, "tmp/scratch.txt")
+	delete(transport.live, "tmp")
+	transport.writeFile("docs/new.txt", "new")
+
+	// the new files are backed up in full from the snapshot
+	second, err := client.Backup(context.Background(), localPath, &share.BackupOptions{PreviousSnapshot: to.Ptr(full.Snapshot)})
+	require.NoError(t, err)
+	requireMirror(t, localPath, map[string]string{
+		"readme.txt":   "hello world",
+		"archive.txt":  "0000xxxx22223333tail",
+		"a.txt":        "second",
+		"b.txt":        "first",
+		"docs/new.txt": "new",
+	})
+	require.Equal(t, int64(22), second.BytesDownloaded)
+	require.Equal(t, 1, second.Unchanged)
+
+	snapshot3, err := client.CreateSnapshot(context.Background(), nil)
+	require.NoError(t, err)
+	transport.rename("a.txt", "c.txt")
+	transport.writeFile("b.txt", "FIRST")
+	transport.writeFile("archive.txt", "0000xxxx\x00\x00\x00\x0022223333tail")
+	transport.live["archive.txt"].data = transport.live["archive.txt"].data[:16]
+	transport.writeFile("readme.txt", "hello world")
+
+	// the renamed and modified files are updated with their changed ranges
+	backupDiffs := func(snapshot string, previous string) share.BackupResponse {
+		resp, err := client.Backup(context.Background(), localPath, &share.BackupOptions{Snapshot: to.Ptr(snapshot), PreviousSnapshot: to.Ptr(previous), Concurrency: 2})
+		require.NoError(t, err)
+		require.Zero(t, resp.Failed)
+		return resp
+	}
+	resp := backupDiffs(*snapshot3.Snapshot, second.Snapshot)
+	require.Zero(t, resp.BytesDownloaded)
+	require.Equal(t, 5, resp.Unchanged)
+
+	snapshot4, err := client.CreateSnapshot(context.Background(), nil)
+	require.NoError(t, err)
+	rangeLists := transport.rangeLists
+	resp = backupDiffs(*snapshot4.Snapshot, *snapshot3.Snapshot)
+	requireMirror(t, localPath, map[string]string{
+		"readme.txt":   "hello world",
+		"archive.txt":  "0000xxxx\x00\x00\x00\x002222",
+		"c.txt":        "second",
+		"b.txt":        "FIRST",
+		"docs/new.txt": "new",
+	})
+	changes := map[string]share.BackupChange{}
+	for _, p := range resp.Paths {
+		changes[p.Path] = p.Change
+	}
+	require.Equal(t, map[string]share.BackupChange{
+		"archive.txt": share.BackupChangeModified,
+		"b.txt":       share.BackupChangeModified,
+		"c.txt":       share.BackupChangeRenamed,
+		"readme.txt":  share.BackupChangeModified,
+	}, changes)
+	require.Equal(t, "a.txt", resp.Paths[2].PreviousPath)
+	require.Equal(t, 4, transport.rangeLists-rangeLists)
+	// only the blocks that changed are downloaded, and the cleared block is zeroed
+	require.Equal(t, int64(9), resp.BytesDownloaded)
+	require.Equal(t, 1, resp.Unchanged)
+}
+
+func TestBackupValidation(t *testing.T) {
+	client := newBackupTestClient(t, newSnapshotTransport())
+	_, err := client.Backup(context.Background(), t.TempDir(), &share.BackupOptions{Concurrency: -1})
+	require.Error(t, err)
+	_, err = client.Backup(context.Background(), t.TempDir(), &share.BackupOptions{Snapshot: to.Ptr("missing")})
+	require.True(t, fileerror.HasCode(err, fileerror.ShareNotFound))
+}
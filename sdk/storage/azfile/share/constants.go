@@ -74,3 +74,28 @@ const (
 func PossibleTokenIntentValues() []TokenIntent {
 	return generated.PossibleShareTokenIntentValues()
 }
+
+// DefaultBackupConcurrency is the default number of files that are backed up in parallel by Client.Backup.
+const DefaultBackupConcurrency = 5
+
+// BackupChange is the change to a file or directory between the previous snapshot and the snapshot that's backed up.
+type BackupChange string
+
+const (
+	BackupChangeAdded     BackupChange = "Added"
+	BackupChangeModified  BackupChange = "Modified"
+	BackupChangeRenamed   BackupChange = "Renamed"
+	BackupChangeDeleted   BackupChange = "Deleted"
+	BackupChangeUnchanged BackupChange = "Unchanged"
+)
+
+// PossibleBackupChangeValues returns the possible values for the BackupChange const type.
+func PossibleBackupChangeValues() []BackupChange {
+	return []BackupChange{
+		BackupChangeAdded,
+		BackupChangeModified,
+		BackupChangeRenamed,
+		BackupChangeDeleted,
+		BackupChangeUnchanged,
+	}
+}
@@ -7,6 +7,8 @@
 package share
 
 import (
+	"errors"
+
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/internal/exported"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/internal/generated"
 	"time"
@@ -366,3 +368,38 @@ func (o *GetSASURLOptions) format() time.Time {
 	}
 	return st
 }
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// BackupOptions contains the optional parameters for the Client.Backup method.
+type BackupOptions struct {
+	// Snapshot is the share snapshot to back up. If not specified, a snapshot of the share is created.
+	Snapshot *string
+
+	// PreviousSnapshot is the share snapshot that the local mirror is a backup of. If specified, only the ranges of
+	// the files that changed since PreviousSnapshot are downloaded, and the files that were renamed or deleted since
+	// are moved or deleted in the mirror. If not specified, every file is downloaded.
+	PreviousSnapshot *string
+
+	// Concurrency is the maximum number of files that are backed up in parallel.
+	// The default value is DefaultBackupConcurrency.
+	Concurrency int
+
+	// OnResult is called with the result of each file and directory as it completes.
+	// It is called by one goroutine at a time.
+	OnResult func(BackedUpPath)
+}
+
+func (o *BackupOptions) format() (BackupOptions, error) {
+	if o == nil {
+		return BackupOptions{Concurrency: DefaultBackupConcurrency}, nil
+	}
+	if o.Concurrency < 0 {
+		return BackupOptions{}, errors.New("Concurrency cannot be negative")
+	}
+	options := *o
+	if options.Concurrency == 0 {
+		options.Concurrency = DefaultBackupConcurrency
+	}
+	return options, nil
+}
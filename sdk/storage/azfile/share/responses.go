@@ -43,3 +43,42 @@ type SetMetadataResponse = generated.ShareClientSetMetadataResponse
 
 // GetStatisticsResponse contains the response from method Client.GetStatistics.
 type GetStatisticsResponse = generated.ShareClientGetStatisticsResponse
+
+// BackedUpPath is the result of backing up a file or directory.
+type BackedUpPath struct {
+	// Path is the path of the file or directory in the snapshot, with slashes as separators.
+	Path string
+
+	// PreviousPath is the path of a renamed file in the previous snapshot.
+	PreviousPath string
+
+	// IsDirectory is true if the path is a directory.
+	IsDirectory bool
+
+	// Change is the change to the path since the previous snapshot.
+	Change BackupChange
+
+	// Bytes is the number of bytes of the file that were downloaded.
+	Bytes int64
+
+	// Err is the error that occurred backing up the path, if any.
+	Err error
+}
+
+// BackupResponse contains the response from method Client.Backup.
+type BackupResponse struct {
+	// Snapshot is the share snapshot that was backed up; it's the PreviousSnapshot of the next incremental backup.
+	Snapshot string
+
+	// Paths contains the result of every file and directory that changed, sorted by path.
+	Paths []BackedUpPath
+
+	// Unchanged is the number of files that didn't change since the previous snapshot.
+	Unchanged int
+
+	// Failed is the number of files and directories that failed.
+	Failed int
+
+	// BytesDownloaded is the total number of bytes that were downloaded.
+	BytesDownloaded int64
+}
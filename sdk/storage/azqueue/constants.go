@@ -6,7 +6,11 @@
 
 package azqueue
 
-import "github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue/v2/internal/generated"
+import (
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue/v2/internal/generated"
+)
 
 // GeoReplicationStatus - The status of the secondary location
 type GeoReplicationStatus = generated.GeoReplicationStatus
@@ -16,3 +20,57 @@ const (
 	GeoReplicationStatusBootstrap   GeoReplicationStatus = generated.GeoReplicationStatusBootstrap
 	GeoReplicationStatusUnavailable GeoReplicationStatus = generated.GeoReplicationStatusUnavailable
 )
+
+const (
+	// DefaultProcessorConcurrency is the default maximum number of messages that a Processor handles in parallel.
+	DefaultProcessorConcurrency = 16
+
+	// DefaultProcessorVisibilityTimeout is the default time for which a message dequeued by a Processor is invisible
+	// to other consumers.
+	DefaultProcessorVisibilityTimeout = 30 * time.Second
+
+	// DefaultMaxDequeueCount is the default number of times that a message is dequeued by a Processor before it's
+	// moved to the poison queue.
+	DefaultMaxDequeueCount = 5
+
+	// DefaultMinPollInterval is the default interval at which a Processor polls an empty queue at first.
+	DefaultMinPollInterval = 100 * time.Millisecond
+
+	// DefaultMaxPollInterval is the default maximum interval at which a Processor polls an empty queue.
+	DefaultMaxPollInterval = time.Minute
+
+	// DefaultShutdownTimeout is the default time for which a stopped Processor waits for the messages that are being
+	// handled.
+	DefaultShutdownTimeout = 30 * time.Second
+
+	// PoisonQueueSuffix is appended to the name of a queue to get the name of its default poison queue.
+	PoisonQueueSuffix = "-poison"
+
+	maxDequeueBatchSize = 32
+
+	maxQueueNameLength = 63
+)
+
+// ProcessorOperation is the operation of a Processor that failed.
+type ProcessorOperation string
+
+const (
+	ProcessorOperationDequeue           ProcessorOperation = "Dequeue"
+	ProcessorOperationHandle            ProcessorOperation = "Handle"
+	ProcessorOperationExtendVisibility  ProcessorOperation = "ExtendVisibility"
+	ProcessorOperationRelease           ProcessorOperation = "Release"
+	ProcessorOperationDelete            ProcessorOperation = "Delete"
+	ProcessorOperationMoveToPoisonQueue ProcessorOperation = "MoveToPoisonQueue"
+)
+
+// PossibleProcessorOperationValues returns the possible values for the ProcessorOperation const type.
+func PossibleProcessorOperationValues() []ProcessorOperation {
+	return []ProcessorOperation{
+		ProcessorOperationDequeue,
+		ProcessorOperationHandle,
+		ProcessorOperationExtendVisibility,
+		ProcessorOperationRelease,
+		ProcessorOperationDelete,
+		ProcessorOperationMoveToPoisonQueue,
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue/v2/internal/generated"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue/v2/internal/shared"
 	"strings"
+	"sync"
 )
 
 // ClientOptions contains the optional parameters when creating a Client.
@@ -23,11 +24,22 @@ type ClientOptions struct {
 	// Only has an effect when credential is of type TokenCredential. The value could be
 	// https://storage.azure.com/ (default) or https://<account>.queue.core.windows.net.
 	Audience string
+
+	// MessageEncoding encodes the content of messages before they're enqueued, and decodes the text of messages after
+	// they're dequeued or peeked. The default value is NoneMessageEncoding, which leaves the content unchanged.
+	MessageEncoding exported.MessageEncoding
+
+	// ClaimCheck stores the content of messages that are too large to be enqueued in blobs, and enqueues references
+	// to the blobs instead. The references are resolved when the messages are dequeued or peeked, and the blobs are
+	// deleted when the messages are deleted by the client that dequeued them. The blobs of messages that expire or
+	// are cleared aren't deleted; use a lifecycle management policy on the container to delete them.
+	ClaimCheck *exported.ClaimCheckOptions
 }
 
 type Client[T any] struct {
 	inner     *T
 	sharedKey *exported.SharedKeyCredential
+	options   *ClientOptions
 }
 
 func InnerClient[T any](client *Client[T]) *T {
@@ -38,6 +50,10 @@ func SharedKey[T any](client *Client[T]) *exported.SharedKeyCredential {
 	return client.sharedKey
 }
 
+func Options[T any](client *Client[T]) *ClientOptions {
+	return client.options
+}
+
 func NewClient[T any](inner *T) *Client[T] {
 	return &Client[T]{inner: inner}
 }
@@ -49,18 +65,21 @@ func GetAudience(clOpts *ClientOptions) string {
 		return strings.TrimRight(clOpts.Audience, "/") + "/.default"
 	}
 }
-func NewServiceClient(queueURL string, pipeline runtime.Pipeline, sharedKey *exported.SharedKeyCredential) *Client[generated.ServiceClient] {
+func NewServiceClient(queueURL string, pipeline runtime.Pipeline, sharedKey *exported.SharedKeyCredential, options *ClientOptions) *Client[generated.ServiceClient] {
 	return &Client[generated.ServiceClient]{
 		inner:     generated.NewServiceClient(queueURL, pipeline),
 		sharedKey: sharedKey,
+		options:   options,
 	}
 }
 
-func NewQueueClient(queueURL string, pipeline runtime.Pipeline, sharedKey *exported.SharedKeyCredential) *CompositeClient[generated.QueueClient, generated.MessagesClient] {
+func NewQueueClient(queueURL string, pipeline runtime.Pipeline, sharedKey *exported.SharedKeyCredential, options *ClientOptions) *CompositeClient[generated.QueueClient, generated.MessagesClient] {
 	return &CompositeClient[generated.QueueClient, generated.MessagesClient]{
 		innerT:    generated.NewQueueClient(queueURL, pipeline),
 		innerU:    generated.NewMessagesClient(runtime.JoinPaths(queueURL, "messages"), pipeline),
 		sharedKey: sharedKey,
+		options:   options,
+		payloads:  &sync.Map{},
 	}
 }
 
@@ -68,6 +87,8 @@ type CompositeClient[T, U any] struct {
 	innerT    *T
 	innerU    *U
 	sharedKey *exported.SharedKeyCredential
+	options   *ClientOptions
+	payloads  *sync.Map
 }
 
 func InnerClients[T, U any](client *CompositeClient[T, U]) (*T, *U) {
@@ -77,3 +98,13 @@ func InnerClients[T, U any](client *CompositeClient[T, U]) (*T, *U) {
 func SharedKeyComposite[T, U any](client *CompositeClient[T, U]) *exported.SharedKeyCredential {
 	return client.sharedKey
 }
+
+func OptionsComposite[T, U any](client *CompositeClient[T, U]) *ClientOptions {
+	return client.options
+}
+
+// Payloads returns the claim-check payloads of the messages dequeued by the client that are still invisible, keyed by
+// message ID.
+func Payloads[T, U any](client *CompositeClient[T, U]) *sync.Map {
+	return client.payloads
+}
@@ -0,0 +1,64 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package exported
+
+import (
+	"encoding/base64"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+)
+
+// MessageEncoding encodes the content of messages before they're enqueued, and decodes the text of messages after
+// they're dequeued or peeked.
+type MessageEncoding interface {
+	// Encode returns the text of a message with the specified content.
+	Encode(content string) (string, error)
+
+	// Decode returns the content of a message with the specified text.
+	Decode(text string) (string, error)
+}
+
+// NoneMessageEncoding leaves the content of messages unchanged.
+type NoneMessageEncoding struct{}
+
+// Encode returns content.
+func (NoneMessageEncoding) Encode(content string) (string, error) {
+	return content, nil
+}
+
+// Decode returns text.
+func (NoneMessageEncoding) Decode(text string) (string, error) {
+	return text, nil
+}
+
+// Base64MessageEncoding encodes the content of messages in standard base64, which is the default encoding of
+// Azure Functions and of the Azure Storage Queues SDKs for other languages.
+type Base64MessageEncoding struct{}
+
+// Encode returns content encoded in standard base64.
+func (Base64MessageEncoding) Encode(content string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(content)), nil
+}
+
+// Decode returns text decoded from standard base64.
+func (Base64MessageEncoding) Decode(text string) (string, error) {
+	content, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// ClaimCheckOptions contains the parameters for storing the content of large messages in blobs.
+type ClaimCheckOptions struct {
+	// Container is the container that the blobs are stored in. REQUIRED.
+	Container *container.Client
+
+	// Threshold is the size in bytes of the encoded text of a message above which its content is stored in a blob.
+	// The default value is 65536, which is the maximum size of a message.
+	Threshold int
+}
@@ -0,0 +1,238 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package azqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/uuid"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue/v2/internal/exported"
+)
+
+// MessageEncoding encodes the content of messages before they're enqueued, and decodes the text of messages after
+// they're dequeued or peeked. Implement it to use a custom encoding.
+type MessageEncoding = exported.MessageEncoding
+
+// NoneMessageEncoding leaves the content of messages unchanged. It's the default encoding.
+type NoneMessageEncoding = exported.NoneMessageEncoding
+
+// Base64MessageEncoding encodes the content of messages in standard base64, which is the default encoding of
+// Azure Functions and of the Azure Storage Queues SDKs for other languages.
+type Base64MessageEncoding = exported.Base64MessageEncoding
+
+// ClaimCheckOptions contains the parameters for storing the content of large messages in blobs.
+type ClaimCheckOptions = exported.ClaimCheckOptions
+
+// MaxMessageSize is the maximum size in bytes of the text of a message.
+const MaxMessageSize = 64 * 1024
+
+// claimCheckReference is the content of a message whose content is stored in a blob.
+type claimCheckReference struct {
+	Blob string `json:"$claimCheckBlob"`
+}
+
+// claimCheckPayload is the blob that stores the content of a dequeued message, and the text of the message.
+// It's tracked until the message becomes visible again, after which another consumer may dequeue and delete it.
+type claimCheckPayload struct {
+	blob      string
+	text      string
+	visibleAt time.Time
+}
+
+func (q *QueueClient) messageEncoding() MessageEncoding {
+	if o := q.options(); o != nil && o.MessageEncoding != nil {
+		return o.MessageEncoding
+	}
+	return NoneMessageEncoding{}
+}
+
+func (q *QueueClient) claimCheck() *ClaimCheckOptions {
+	if o := q.options(); o != nil && o.ClaimCheck != nil && o.ClaimCheck.Container != nil {
+		return o.ClaimCheck
+	}
+	return nil
+}
+
+// encodeMessage returns the text of a message with the specified content. If the text is larger than the claim-check
+// threshold, the content is stored in a blob whose name is returned, and the text references the blob instead.
+func (q *QueueClient) encodeMessage(ctx context.Context, content string) (text string, blob string, err error) {
+	encoding := q.messageEncoding()
+	text, err = encoding.Encode(content)
+	if err != nil {
+		return "", "", err
+	}
+	claimCheck := q.claimCheck()
+	threshold := MaxMessageSize
+	if claimCheck != nil && claimCheck.Threshold > 0 {
+		threshold = claimCheck.Threshold
+	}
+	if claimCheck == nil || len(text) <= threshold {
+		return text, "", nil
+	}
+
+	id, err := uuid.New()
+	if err != nil {
+		return "", "", err
+	}
+	blob = id.String()
+	if _, err = claimCheck.Container.NewBlockBlobClient(blob).UploadBuffer(ctx, []byte(content), nil); err != nil {
+		return "", "", err
+	}
+	reference, err := json.Marshal(claimCheckReference{Blob: blob})
+	if err == nil {
+		text, err = encoding.Encode(string(reference))
+	}
+	if err != nil {
+		_ = q.deletePayload(ctx, blob)
+		return "", "", err
+	}
+	return text, blob, nil
+}
+
+// decodeMessage returns the content of a message with the specified text. If the text references a blob, the content
+// is read from the blob whose name is returned.
+func (q *QueueClient) decodeMessage(ctx context.Context, text string) (content string, blob string, err error) {
+	content, err = q.messageEncoding().Decode(text)
+	if err != nil {
+		return "", "", err
+	}
+	claimCheck := q.claimCheck()
+	var reference claimCheckReference
+	if claimCheck == nil || !strings.HasPrefix(content, `{"$claimCheckBlob":`) || json.Unmarshal([]byte(content), &reference) != nil || reference.Blob == "" {
+		return content, "", nil
+	}
+
+	resp, err := claimCheck.Container.NewBlobClient(reference.Blob).DownloadStream(ctx, nil)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+	return string(payload), reference.Blob, nil
+}
+
+// decodeDequeuedMessages decodes the text of the messages in place, and tracks the blobs that store the content of
+// the messages so that they can be deleted with the messages. The text of the messages that can't be decoded is
+// left unchanged.
+func (q *QueueClient) decodeDequeuedMessages(ctx context.Context, messages []*DequeuedMessage) error {
+	q.prunePayloads(time.Now())
+	var errs []error
+	for _, message := range messages {
+		if message == nil || message.MessageText == nil {
+			continue
+		}
+		content, blob, err := q.decodeMessage(ctx, *message.MessageText)
+		if err != nil {
+			errs = append(errs, decodingError(message.MessageID, err))
+			continue
+		}
+		if message.MessageID != nil {
+			if blob != "" {
+				q.payloads().Store(*message.MessageID, claimCheckPayload{blob: blob, text: *message.MessageText, visibleAt: timeOrZero(message.TimeNextVisible)})
+			} else {
+				q.payloads().Delete(*message.MessageID)
+			}
+		}
+		message.MessageText = &content
+	}
+	return errors.Join(errs...)
+}
+
+// decodePeekedMessages decodes the text of the messages in place. The text of the messages that can't be decoded is
+// left unchanged.
+func (q *QueueClient) decodePeekedMessages(ctx context.Context, messages []*PeekedMessage) error {
+	var errs []error
+	for _, message := range messages {
+		if message == nil || message.MessageText == nil {
+			continue
+		}
+		content, _, err := q.decodeMessage(ctx, *message.MessageText)
+		if err != nil {
+			errs = append(errs, decodingError(message.MessageID, err))
+			continue
+		}
+		message.MessageText = &content
+	}
+	return errors.Join(errs...)
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+func decodingError(messageID *string, err error) error {
+	if messageID == nil {
+		return fmt.Errorf("failed to decode message: %w", err)
+	}
+	return fmt.Errorf("failed to decode message %s: %w", *messageID, err)
+}
+
+// prunePayloads stops tracking the blobs of the messages that became visible again before now. Those messages were
+// abandoned by this client, or their visibility timed out, and they may be dequeued and deleted by other consumers.
+func (q *QueueClient) prunePayloads(now time.Time) {
+	q.payloads().Range(func(messageID, payload any) bool {
+		if payload.(claimCheckPayload).visibleAt.Before(now) {
+			q.payloads().CompareAndDelete(messageID, payload)
+		}
+		return true
+	})
+}
+
+// replacePayload tracks blob as the blob that stores the content of the message until visibleAt, and deletes the blob
+// that stored its previous content.
+func (q *QueueClient) replacePayload(ctx context.Context, messageID string, blob string, text string, visibleAt time.Time) error {
+	var previous any
+	var ok bool
+	if blob != "" {
+		previous, ok = q.payloads().Swap(messageID, claimCheckPayload{blob: blob, text: text, visibleAt: visibleAt})
+	} else {
+		previous, ok = q.payloads().LoadAndDelete(messageID)
+	}
+	if !ok || previous.(claimCheckPayload).blob == blob {
+		return nil
+	}
+	return q.deletePayload(ctx, previous.(claimCheckPayload).blob)
+}
+
+func (q *QueueClient) deletePayload(ctx context.Context, blob string) error {
+	claimCheck := q.claimCheck()
+	if claimCheck == nil {
+		return nil
+	}
+	_, err := claimCheck.Container.NewBlobClient(blob).Delete(ctx, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil
+	}
+	return err
+}
+
+// updateVisibility updates the visibility timeout of a dequeued message without changing its content. The text of
+// a message whose content is stored in a blob is sent unchanged, so that the content isn't stored again.
+func (q *QueueClient) updateVisibility(ctx context.Context, messageID string, popReceipt string, content string, o *UpdateMessageOptions) (UpdateMessageResponse, error) {
+	if payload, ok := q.payloads().Load(messageID); ok {
+		text := payload.(claimCheckPayload).text
+		resp, err := q.updateMessage(ctx, messageID, popReceipt, text, o)
+		if err != nil {
+			return resp, err
+		}
+		return resp, q.replacePayload(ctx, messageID, payload.(claimCheckPayload).blob, text, timeOrZero(resp.TimeNextVisible))
+	}
+	return q.UpdateMessage(ctx, messageID, popReceipt, content, o)
+}
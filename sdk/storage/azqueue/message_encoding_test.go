@@ -0,0 +1,249 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package azqueue_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// blobTransport is an in-memory blob service that serves the block blobs of a container.
+type blobTransport struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (t *blobTransport) Do(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	name := path.Base(req.URL.Path)
+	switch req.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		t.blobs[name] = body
+		return response(http.StatusCreated, "", "")
+	case http.MethodGet:
+		blob, ok := t.blobs[name]
+		if !ok {
+			return response(http.StatusNotFound, "BlobNotFound", "")
+		}
+		resp, err := response(http.StatusOK, "", string(blob))
+		resp.Header.Set("Content-Length", strconv.Itoa(len(blob)))
+		resp.ContentLength = int64(len(blob))
+		return resp, err
+	case http.MethodDelete:
+		if _, ok := t.blobs[name]; !ok {
+			return response(http.StatusNotFound, "BlobNotFound", "")
+		}
+		delete(t.blobs, name)
+		return response(http.StatusAccepted, "", "")
+	}
+	return response(http.StatusBadRequest, "UnsupportedHttpVerb", "")
+}
+
+func (t *blobTransport) names() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var names []string
+	for name := range t.blobs {
+		names = append(names, name)
+	}
+	return names
+}
+
+func newEncodingQueueClient(t *testing.T, transport *queueTransport, encoding azqueue.MessageEncoding, claimCheck *azqueue.ClaimCheckOptions) *azqueue.QueueClient {
+	client, err := azqueue.NewQueueClientWithNoCredential("https://account.queue.core.windows.net/work", &azqueue.ClientOptions{
+		ClientOptions:   azcore.ClientOptions{Transport: transport, Retry: policy.RetryOptions{MaxRetries: -1}},
+		MessageEncoding: encoding,
+		ClaimCheck:      claimCheck,
+	})
+	require.NoError(t, err)
+	return client
+}
+
+// reverseEncoding is a custom encoding that reverses the content of messages.
+type reverseEncoding struct{}
+
+func (reverseEncoding) Encode(content string) (string, error) {
+	runes := []rune(content)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes), nil
+}
+
+func (e reverseEncoding) Decode(text string) (string, error) {
+	return e.Encode(text)
+}
+
+func TestMessageEncoding(t *testing.T) {
+	for _, test := range []struct {
+		encoding azqueue.MessageEncoding
+		text     string
+		updated  string
+	}{
+		{nil, "hello, world", "updated"},
+		{azqueue.NoneMessageEncoding{}, "hello, world", "updated"},
+		{azqueue.Base64MessageEncoding{}, "aGVsbG8sIHdvcmxk", "dXBkYXRlZA=="},
+		{reverseEncoding{}, "dlrow ,olleh", "detadpu"},
+	} {
+		transport := newQueueTransport("work")
+		client := newEncodingQueueClient(t, transport, test.encoding, nil)
+		_, err := client.EnqueueMessage(context.Background(), "hello, world", nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{test.text}, transport.messages("work"))
+
+		peeked, err := client.PeekMessages(context.Background(), nil)
+		require.NoError(t, err)
+		require.Equal(t, "hello, world", *peeked.Messages[0].MessageText)
+
+		dequeued, err := client.DequeueMessage(context.Background(), nil)
+		require.NoError(t, err)
+		message := dequeued.Messages[0]
+		require.Equal(t, "hello, world", *message.MessageText)
+
+		_, err = client.UpdateMessage(context.Background(), *message.MessageID, *message.PopReceipt, "updated", nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{test.updated}, transport.messages("work"))
+	}
+}
+
+func TestMessageDecodingError(t *testing.T) {
+	transport := newQueueTransport("work")
+	transport.enqueue("work", "not base64!", "b2s=")
+	client := newEncodingQueueClient(t, transport, azqueue.Base64MessageEncoding{}, nil)
+
+	resp, err := client.DequeueMessages(context.Background(), &azqueue.DequeueMessagesOptions{NumberOfMessages: to.Ptr(int32(2))})
+	require.ErrorContains(t, err, "failed to decode message work-0")
+	require.Len(t, resp.Messages, 2)
+	require.Equal(t, "not base64!", *resp.Messages[0].MessageText)
+	require.Equal(t, "ok", *resp.Messages[1].MessageText)
+}
+
+func TestMessageClaimCheck(t *testing.T) {
+	blobs := &blobTransport{blobs: map[string][]byte{}}
+	containerClient, err := container.NewClientWithNoCredential("https://account.blob.core.windows.net/payloads", &container.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: blobs, Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	require.NoError(t, err)
+	transport := newQueueTransport("work")
+	claimCheck := &azqueue.ClaimCheckOptions{Container: containerClient, Threshold: 100}
+	producer := newEncodingQueueClient(t, transport, azqueue.Base64MessageEncoding{}, claimCheck)
+	consumer := newEncodingQueueClient(t, transport, azqueue.Base64MessageEncoding{}, claimCheck)
+	ctx := context.Background()
+
+	large := strings.Repeat("large payload ", 10)
+	_, err = producer.EnqueueMessage(ctx, "small", nil)
+	require.NoError(t, err)
+	_, err = producer.EnqueueMessage(ctx, large, nil)
+	require.NoError(t, err)
+
+	// only the large content is stored in a blob, and its message references the blob
+	names := blobs.names()
+	require.Len(t, names, 1)
+	require.Equal(t, large, string(blobs.blobs[names[0]]))
+	messages := transport.messages("work")
+	require.Equal(t, "c21hbGw=", messages[0])
+	require.LessOrEqual(t, len(messages[1]), 100)
+	require.NotContains(t, messages[1], "large")
+
+	peeked, err := consumer.PeekMessages(ctx, &azqueue.PeekMessagesOptions{NumberOfMessages: to.Ptr(int32(2))})
+	require.NoError(t, err)
+	require.Equal(t, "small", *peeked.Messages[0].MessageText)
+	require.Equal(t, large, *peeked.Messages[1].MessageText)
+
+	dequeued, err := consumer.DequeueMessages(ctx, &azqueue.DequeueMessagesOptions{NumberOfMessages: to.Ptr(int32(2))})
+	require.NoError(t, err)
+	require.Len(t, dequeued.Messages, 2)
+	message := dequeued.Messages[1]
+	require.Equal(t, large, *message.MessageText)
+
+	// updating the message with new large content replaces its blob
+	updated, err := consumer.UpdateMessage(ctx, *message.MessageID, *message.PopReceipt, large+"updated", nil)
+	require.NoError(t, err)
+	require.Len(t, blobs.names(), 1)
+	require.NotEqual(t, names[0], blobs.names()[0])
+
+	// deleting the message deletes its blob
+	_, err = consumer.DeleteMessage(ctx, *message.MessageID, *updated.PopReceipt, nil)
+	require.NoError(t, err)
+	require.Empty(t, blobs.names())
+	require.Equal(t, []string{"c21hbGw="}, transport.messages("work"))
+
+	// the blob isn't stored if the message can't be enqueued
+	failing := newEncodingQueueClient(t, newQueueTransport(), azqueue.Base64MessageEncoding{}, claimCheck)
+	_, err = failing.EnqueueMessage(ctx, large, nil)
+	require.Error(t, err)
+	require.Empty(t, blobs.names())
+
+	// the reference is returned unresolved by clients without claim-check
+	_, err = producer.EnqueueMessage(ctx, large, nil)
+	require.NoError(t, err)
+	plain := newEncodingQueueClient(t, transport, azqueue.Base64MessageEncoding{}, nil)
+	peeked, err = plain.PeekMessages(ctx, &azqueue.PeekMessagesOptions{NumberOfMessages: to.Ptr(int32(2))})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(*peeked.Messages[1].MessageText, `{"$claimCheckBlob":`))
+
+	// the content can't be resolved once the blob is gone
+	for _, name := range blobs.names() {
+		_, err := containerClient.NewBlobClient(name).Delete(ctx, nil)
+		require.NoError(t, err)
+	}
+	_, err = consumer.PeekMessages(ctx, &azqueue.PeekMessagesOptions{NumberOfMessages: to.Ptr(int32(2))})
+	require.ErrorContains(t, err, "BlobNotFound")
+}
+
+func TestMessageClaimCheckExpiresWithVisibility(t *testing.T) {
+	blobs := &blobTransport{blobs: map[string][]byte{}}
+	containerClient, err := container.NewClientWithNoCredential("https://account.blob.core.windows.net/payloads", &container.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: blobs, Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	require.NoError(t, err)
+	transport := newQueueTransport("work")
+	claimCheck := &azqueue.ClaimCheckOptions{Container: containerClient, Threshold: 100}
+	consumer := newEncodingQueueClient(t, transport, nil, claimCheck)
+	other := newEncodingQueueClient(t, transport, nil, claimCheck)
+	ctx := context.Background()
+
+	_, err = consumer.EnqueueMessage(ctx, strings.Repeat("large payload ", 10), nil)
+	require.NoError(t, err)
+	_, err = consumer.EnqueueMessage(ctx, "small", nil)
+	require.NoError(t, err)
+	dequeued, err := consumer.DequeueMessage(ctx, &azqueue.DequeueMessageOptions{VisibilityTimeout: to.Ptr(int32(1))})
+	require.NoError(t, err)
+	require.Len(t, dequeued.Messages, 1)
+
+	// the message becomes visible again, and another consumer dequeues it
+	time.Sleep(1100 * time.Millisecond)
+	taken, err := other.DequeueMessage(ctx, &azqueue.DequeueMessageOptions{VisibilityTimeout: to.Ptr(int32(30))})
+	require.NoError(t, err)
+	require.Equal(t, *dequeued.Messages[0].MessageID, *taken.Messages[0].MessageID)
+
+	// the consumer stops tracking the blob of the message on its next dequeue, so it no longer deletes it
+	_, err = consumer.DequeueMessage(ctx, nil)
+	require.NoError(t, err)
+	_, err = consumer.UpdateMessage(ctx, *taken.Messages[0].MessageID, *taken.Messages[0].PopReceipt, "replaced", nil)
+	require.NoError(t, err)
+	require.Len(t, blobs.names(), 1)
+}
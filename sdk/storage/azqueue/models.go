@@ -7,6 +7,9 @@
 package azqueue
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue/v2/internal/exported"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue/v2/internal/generated"
@@ -468,3 +471,100 @@ func (o *GetSASURLOptions) format() time.Time {
 	}
 	return st
 }
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// ProcessorOptions contains the optional parameters for the NewProcessor method.
+type ProcessorOptions struct {
+	// Concurrency is the maximum number of messages that are handled in parallel.
+	// The default value is DefaultProcessorConcurrency.
+	Concurrency int
+
+	// BatchSize is the maximum number of messages that are dequeued at once, up to 32. Messages are only dequeued
+	// when they can be handled, so fewer messages are dequeued if fewer handlers are available.
+	// The default value is 32.
+	BatchSize int
+
+	// VisibilityTimeout is the time for which a dequeued message is invisible to other consumers. It's rounded down
+	// to seconds. The visibility of a message is extended by VisibilityTimeout every half VisibilityTimeout for as
+	// long as it's being handled.
+	// The default value is DefaultProcessorVisibilityTimeout.
+	VisibilityTimeout time.Duration
+
+	// MaxDequeueCount is the number of times that a message can be dequeued before it's moved to the poison queue;
+	// a message is moved to the poison queue when its handler fails for the MaxDequeueCount time.
+	// The default value is DefaultMaxDequeueCount.
+	MaxDequeueCount int64
+
+	// PoisonQueue is the queue that poison messages are moved to. It's created if it doesn't exist.
+	// The default value is the queue with the name of the processed queue followed by PoisonQueueSuffix, in the same
+	// account. PoisonQueue must be set for queues whose names are longer than 56 characters, since queue names are
+	// limited to 63 characters.
+	PoisonQueue *QueueClient
+
+	// RetryDelay is the time for which a message whose handler failed is invisible before it's dequeued again.
+	// The default value is 0, so that the message can be dequeued again immediately.
+	RetryDelay time.Duration
+
+	// MinPollInterval is the interval at which the queue is polled when it's empty at first. The interval doubles
+	// every time the queue is empty, up to MaxPollInterval, and it's reset when messages are dequeued.
+	// The default value is DefaultMinPollInterval.
+	MinPollInterval time.Duration
+
+	// MaxPollInterval is the maximum interval at which the queue is polled when it's empty.
+	// The default value is DefaultMaxPollInterval.
+	MaxPollInterval time.Duration
+
+	// ShutdownTimeout is the time for which Processor.Run waits for the messages that are being handled once its
+	// context is canceled. The contexts of the handlers are canceled after ShutdownTimeout.
+	// The default value is DefaultShutdownTimeout.
+	ShutdownTimeout time.Duration
+
+	// OnError is called with the errors that occur while the messages are processed, including the errors returned
+	// by the handler. It's called by one goroutine at a time.
+	OnError func(*ProcessorError)
+}
+
+func (o *ProcessorOptions) format() (ProcessorOptions, error) {
+	options := ProcessorOptions{}
+	if o != nil {
+		options = *o
+	}
+	switch {
+	case options.Concurrency < 0:
+		return ProcessorOptions{}, errors.New("Concurrency cannot be negative")
+	case options.BatchSize < 0 || options.BatchSize > maxDequeueBatchSize:
+		return ProcessorOptions{}, fmt.Errorf("BatchSize must be between 0 and %d", maxDequeueBatchSize)
+	case options.VisibilityTimeout < 0 || (options.VisibilityTimeout > 0 && options.VisibilityTimeout < time.Second):
+		return ProcessorOptions{}, errors.New("VisibilityTimeout must be at least one second")
+	case options.MaxDequeueCount < 0:
+		return ProcessorOptions{}, errors.New("MaxDequeueCount cannot be negative")
+	case options.RetryDelay < 0 || options.MinPollInterval < 0 || options.MaxPollInterval < 0 || options.ShutdownTimeout < 0:
+		return ProcessorOptions{}, errors.New("RetryDelay, MinPollInterval, MaxPollInterval and ShutdownTimeout cannot be negative")
+	}
+	if options.Concurrency == 0 {
+		options.Concurrency = DefaultProcessorConcurrency
+	}
+	if options.BatchSize == 0 {
+		options.BatchSize = maxDequeueBatchSize
+	}
+	if options.VisibilityTimeout == 0 {
+		options.VisibilityTimeout = DefaultProcessorVisibilityTimeout
+	}
+	if options.MaxDequeueCount == 0 {
+		options.MaxDequeueCount = DefaultMaxDequeueCount
+	}
+	if options.MinPollInterval == 0 {
+		options.MinPollInterval = DefaultMinPollInterval
+	}
+	if options.MaxPollInterval == 0 {
+		options.MaxPollInterval = max(DefaultMaxPollInterval, options.MinPollInterval)
+	}
+	if options.MinPollInterval > options.MaxPollInterval {
+		return ProcessorOptions{}, errors.New("MinPollInterval cannot be greater than MaxPollInterval")
+	}
+	if options.ShutdownTimeout == 0 {
+		options.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	return options, nil
+}
@@ -0,0 +1,332 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package azqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue/v2/internal/base"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue/v2/queueerror"
+)
+
+// MessageHandler handles a message dequeued by a Processor. The message is deleted from the queue if the handler
+// returns nil. ctx is canceled if the visibility of the message can no longer be extended, in which case another
+// consumer may dequeue it, or when the Processor has been stopped for longer than ProcessorOptions.ShutdownTimeout.
+type MessageHandler func(ctx context.Context, message *DequeuedMessage) error
+
+// ProcessorError is an error that occurred while a Processor was processing the messages of a queue.
+type ProcessorError struct {
+	// Operation is the operation that failed.
+	Operation ProcessorOperation
+
+	// MessageID is the ID of the message that was processed; it's empty for errors dequeuing messages.
+	MessageID string
+
+	// Err is the error that occurred.
+	Err error
+}
+
+// Error implements the error interface for type ProcessorError.
+func (e *ProcessorError) Error() string {
+	if e.MessageID == "" {
+		return fmt.Sprintf("%s failed: %v", e.Operation, e.Err)
+	}
+	return fmt.Sprintf("%s of message %s failed: %v", e.Operation, e.MessageID, e.Err)
+}
+
+// Unwrap returns the error that occurred.
+func (e *ProcessorError) Unwrap() error {
+	return e.Err
+}
+
+// Processor dequeues the messages of a queue and handles them concurrently. The visibility of the messages is
+// extended while they're being handled, the messages that were handled are deleted, and the messages that can't
+// be handled are moved to a poison queue once they've been dequeued ProcessorOptions.MaxDequeueCount times.
+// Messages are handled at least once: a message can be handled again if it couldn't be deleted, or its visibility
+// couldn't be extended.
+type Processor struct {
+	client      *QueueClient
+	poisonQueue *QueueClient
+	handler     MessageHandler
+	options     ProcessorOptions
+
+	started atomic.Bool
+	errorMu sync.Mutex
+}
+
+// NewProcessor creates a Processor that handles the messages of the queue with handler.
+//   - client - the client of the queue
+//   - handler - the handler of the messages
+//   - options - Processor options; pass nil to accept the default values
+func NewProcessor(client *QueueClient, handler MessageHandler, options *ProcessorOptions) (*Processor, error) {
+	if client == nil || handler == nil {
+		return nil, errors.New("client and handler must be specified")
+	}
+	o, err := options.format()
+	if err != nil {
+		return nil, err
+	}
+	poisonQueue := o.PoisonQueue
+	if poisonQueue == nil {
+		urlParts, err := ParseURL(client.URL())
+		if err != nil {
+			return nil, err
+		}
+		if len(urlParts.QueueName)+len(PoisonQueueSuffix) > maxQueueNameLength {
+			return nil, fmt.Errorf("the name of the default poison queue of queue %s would be longer than %d characters; set PoisonQueue", urlParts.QueueName, maxQueueNameLength)
+		}
+		u, err := url.Parse(client.URL())
+		if err != nil {
+			return nil, err
+		}
+		u.Path += PoisonQueueSuffix
+		u.RawPath = ""
+		poisonQueue = (*QueueClient)(base.NewQueueClient(u.String(), client.queueClient().Pipeline(), client.sharedKey(), client.options()))
+	}
+	return &Processor{client: client, poisonQueue: poisonQueue, handler: handler, options: o}, nil
+}
+
+// Run dequeues and handles messages until ctx is canceled. It then stops dequeuing messages, waits for the messages
+// that are being handled for up to ProcessorOptions.ShutdownTimeout, and returns nil.
+// The errors that occur while the messages are processed are reported to ProcessorOptions.OnError, and don't stop
+// the Processor. A Processor can only be run once.
+func (p *Processor) Run(ctx context.Context) error {
+	if !p.started.CompareAndSwap(false, true) {
+		return errors.New("the Processor has already been run")
+	}
+
+	// the handlers keep running for up to ShutdownTimeout after ctx is canceled
+	handlerCtx, stopHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopHandlers()
+
+	var wg sync.WaitGroup
+	p.poll(ctx, handlerCtx, &wg)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(p.options.ShutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		stopHandlers()
+		<-done
+	}
+	return nil
+}
+
+// poll dequeues messages whenever handlers are available, and starts handling them with handlerCtx, until ctx is
+// canceled.
+func (p *Processor) poll(ctx context.Context, handlerCtx context.Context, wg *sync.WaitGroup) {
+	handlers := make(chan struct{}, p.options.Concurrency)
+	release := func(n int) {
+		for i := 0; i < n; i++ {
+			<-handlers
+		}
+	}
+	interval := p.options.MinPollInterval
+	for {
+		// wait for a handler, and reserve as many more handlers as are available, up to the batch size
+		select {
+		case handlers <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		reserved := 1
+	reserve:
+		for reserved < p.options.BatchSize {
+			select {
+			case handlers <- struct{}{}:
+				reserved++
+			default:
+				break reserve
+			}
+		}
+
+		resp, err := p.client.DequeueMessages(ctx, &DequeueMessagesOptions{
+			NumberOfMessages:  to.Ptr(int32(reserved)),
+			VisibilityTimeout: to.Ptr(int32(p.options.VisibilityTimeout / time.Second)),
+		})
+		if ctx.Err() != nil {
+			release(reserved)
+			return
+		}
+		var messages []*DequeuedMessage
+		for _, message := range resp.Messages {
+			if message != nil && message.MessageID != nil && message.PopReceipt != nil {
+				messages = append(messages, message)
+			}
+		}
+		release(reserved - len(messages))
+		if err != nil {
+			p.reportError(ProcessorOperationDequeue, "", err)
+		}
+
+		if len(messages) == 0 {
+			// back off while the queue is empty
+			timer := time.NewTimer(interval)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+			interval = min(2*interval, p.options.MaxPollInterval)
+			continue
+		}
+		interval = p.options.MinPollInterval
+
+		for _, message := range messages {
+			wg.Add(1)
+			go func(message *DequeuedMessage) {
+				defer func() {
+					release(1)
+					wg.Done()
+				}()
+				p.process(handlerCtx, message)
+			}(message)
+		}
+	}
+}
+
+// process handles a message while extending its visibility, and then deletes it, releases it or moves it to the
+// poison queue.
+func (p *Processor) process(ctx context.Context, message *DequeuedMessage) {
+	var dequeueCount int64
+	if message.DequeueCount != nil {
+		dequeueCount = *message.DequeueCount
+	}
+	if dequeueCount > p.options.MaxDequeueCount {
+		// the message was dequeued without its handler completing, e.g. because the process ended
+		p.moveToPoisonQueue(ctx, message, *message.PopReceipt)
+		return
+	}
+
+	handlerCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	receipt := &visibilityReceipt{popReceipt: *message.PopReceipt}
+	stopExtending := make(chan struct{})
+	extended := make(chan struct{})
+	go func() {
+		defer close(extended)
+		p.extendVisibility(ctx, message, receipt, stopExtending, cancel)
+	}()
+
+	err := p.handler(handlerCtx, message)
+	close(stopExtending)
+	<-extended
+	popReceipt := receipt.get()
+
+	if err == nil {
+		if _, err := p.client.DeleteMessage(ctx, *message.MessageID, popReceipt, nil); err != nil {
+			p.reportError(ProcessorOperationDelete, *message.MessageID, err)
+		}
+		return
+	}
+
+	p.reportError(ProcessorOperationHandle, *message.MessageID, err)
+	if dequeueCount >= p.options.MaxDequeueCount {
+		p.moveToPoisonQueue(ctx, message, popReceipt)
+		return
+	}
+	_, err = p.client.updateVisibility(ctx, *message.MessageID, popReceipt, messageText(message), &UpdateMessageOptions{
+		VisibilityTimeout: to.Ptr(int32(p.options.RetryDelay / time.Second)),
+	})
+	if err != nil {
+		p.reportError(ProcessorOperationRelease, *message.MessageID, err)
+	}
+}
+
+// visibilityReceipt is the latest pop receipt of a message, which changes every time its visibility is extended.
+type visibilityReceipt struct {
+	mu         sync.Mutex
+	popReceipt string
+}
+
+func (r *visibilityReceipt) get() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.popReceipt
+}
+
+func (r *visibilityReceipt) set(popReceipt string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.popReceipt = popReceipt
+}
+
+// extendVisibility extends the visibility of the message every half visibility timeout until stop is closed. If the
+// visibility can't be extended, the handler of the message is canceled with cancel.
+func (p *Processor) extendVisibility(ctx context.Context, message *DequeuedMessage, receipt *visibilityReceipt, stop <-chan struct{}, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(p.options.VisibilityTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		resp, err := p.client.updateVisibility(ctx, *message.MessageID, receipt.get(), messageText(message), &UpdateMessageOptions{
+			VisibilityTimeout: to.Ptr(int32(p.options.VisibilityTimeout / time.Second)),
+		})
+		if err == nil && resp.PopReceipt == nil {
+			err = errors.New("no pop receipt was returned")
+		}
+		if err != nil {
+			p.reportError(ProcessorOperationExtendVisibility, *message.MessageID, err)
+			cancel(err)
+			return
+		}
+		receipt.set(*resp.PopReceipt)
+	}
+}
+
+// moveToPoisonQueue enqueues the message to the poison queue, which is created if it doesn't exist, and deletes it
+// from the queue.
+func (p *Processor) moveToPoisonQueue(ctx context.Context, message *DequeuedMessage, popReceipt string) {
+	options := &EnqueueMessageOptions{TimeToLive: to.Ptr(int32(-1))}
+	_, err := p.poisonQueue.EnqueueMessage(ctx, messageText(message), options)
+	if queueerror.HasCode(err, queueerror.QueueNotFound) {
+		if _, err = p.poisonQueue.Create(ctx, nil); err == nil || queueerror.HasCode(err, queueerror.QueueAlreadyExists) {
+			_, err = p.poisonQueue.EnqueueMessage(ctx, messageText(message), options)
+		}
+	}
+	if err == nil {
+		_, err = p.client.DeleteMessage(ctx, *message.MessageID, popReceipt, nil)
+	}
+	if err != nil {
+		p.reportError(ProcessorOperationMoveToPoisonQueue, *message.MessageID, err)
+	}
+}
+
+func (p *Processor) reportError(operation ProcessorOperation, messageID string, err error) {
+	if p.options.OnError == nil {
+		return
+	}
+	p.errorMu.Lock()
+	defer p.errorMu.Unlock()
+	p.options.OnError(&ProcessorError{Operation: operation, MessageID: messageID, Err: err})
+}
+
+func messageText(message *DequeuedMessage) string {
+	if message.MessageText == nil {
+		return ""
+	}
+	return *message.MessageText
+}
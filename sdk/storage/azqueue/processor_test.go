@@ -0,0 +1,332 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package azqueue_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue/v2"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMessage struct {
+	id           string
+	text         string
+	popReceipt   string
+	dequeueCount int64
+	visibleAt    time.Time
+}
+
+// queueTransport is an in-memory queue service that serves the queues of an account.
+type queueTransport struct {
+	mu       sync.Mutex
+	queues   map[string][]*fakeMessage
+	receipts int
+	updates  int
+}
+
+func newQueueTransport(queues ...string) *queueTransport {
+	t := &queueTransport{queues: map[string][]*fakeMessage{}}
+	for _, queue := range queues {
+		t.queues[queue] = nil
+	}
+	return t
+}
+
+func (t *queueTransport) enqueue(queue string, texts ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, text := range texts {
+		t.queues[queue] = append(t.queues[queue], &fakeMessage{id: fmt.Sprintf("%s-%d", queue, len(t.queues[queue])), text: text})
+	}
+}
+
+func (t *queueTransport) messages(queue string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var texts []string
+	for _, m := range t.queues[queue] {
+		texts = append(texts, m.text)
+	}
+	return texts
+}
+
+func (t *queueTransport) Do(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	query := req.URL.Query()
+	messages, ok := t.queues[segments[0]]
+	switch {
+	case len(segments) == 1 && req.Method == http.MethodPut:
+		if ok {
+			return response(http.StatusNoContent, "QueueAlreadyExists", "")
+		}
+		t.queues[segments[0]] = nil
+		return response(http.StatusCreated, "", "")
+	case !ok:
+		return response(http.StatusNotFound, "QueueNotFound", "")
+	case len(segments) == 2 && req.Method == http.MethodGet:
+		n, err := strconv.Atoi(query.Get("numofmessages"))
+		if err != nil {
+			n = 1
+		}
+		visibility, _ := strconv.Atoi(query.Get("visibilitytimeout"))
+		now := time.Now()
+		var body strings.Builder
+		body.WriteString("<QueueMessagesList>")
+		for _, m := range messages {
+			if n == 0 {
+				break
+			}
+			if m.visibleAt.After(now) {
+				continue
+			}
+			n--
+			if query.Get("peekonly") == "true" {
+				fmt.Fprintf(&body, "<QueueMessage><MessageId>%s</MessageId><DequeueCount>%d</DequeueCount><MessageText>%s</MessageText></QueueMessage>",
+					m.id, m.dequeueCount, m.text)
+				continue
+			}
+			t.receipts++
+			m.popReceipt = strconv.Itoa(t.receipts)
+			m.dequeueCount++
+			m.visibleAt = now.Add(time.Duration(visibility) * time.Second)
+			fmt.Fprintf(&body, "<QueueMessage><MessageId>%s</MessageId><PopReceipt>%s</PopReceipt><TimeNextVisible>%s</TimeNextVisible><DequeueCount>%d</DequeueCount><MessageText>%s</MessageText></QueueMessage>",
+				m.id, m.popReceipt, m.visibleAt.UTC().Format(http.TimeFormat), m.dequeueCount, m.text)
+		}
+		body.WriteString("</QueueMessagesList>")
+		return response(http.StatusOK, "", body.String())
+	case len(segments) == 2 && req.Method == http.MethodPost:
+		var message struct {
+			MessageText string `xml:"MessageText"`
+		}
+		body, _ := io.ReadAll(req.Body)
+		if err := xml.Unmarshal(body, &message); err != nil {
+			return nil, err
+		}
+		t.queues[segments[0]] = append(messages, &fakeMessage{id: fmt.Sprintf("%s-%d", segments[0], len(messages)), text: message.MessageText})
+		return response(http.StatusCreated, "", "<QueueMessagesList></QueueMessagesList>")
+	case len(segments) == 3:
+		for i, m := range messages {
+			if m.id != segments[2] {
+				continue
+			}
+			if m.popReceipt != query.Get("popreceipt") {
+				return response(http.StatusBadRequest, "PopReceiptMismatch", "")
+			}
+			if req.Method == http.MethodDelete {
+				t.queues[segments[0]] = append(messages[:i:i], messages[i+1:]...)
+				return response(http.StatusNoContent, "", "")
+			}
+			var message struct {
+				MessageText string `xml:"MessageText"`
+			}
+			body, _ := io.ReadAll(req.Body)
+			if err := xml.Unmarshal(body, &message); err != nil {
+				return nil, err
+			}
+			m.text = message.MessageText
+			visibility, _ := strconv.Atoi(query.Get("visibilitytimeout"))
+			t.receipts++
+			t.updates++
+			m.popReceipt = strconv.Itoa(t.receipts)
+			m.visibleAt = time.Now().Add(time.Duration(visibility) * time.Second)
+			resp, err := response(http.StatusNoContent, "", "")
+			resp.Header.Set("x-ms-popreceipt", m.popReceipt)
+			resp.Header.Set("x-ms-time-next-visible", m.visibleAt.UTC().Format(http.TimeFormat))
+			return resp, err
+		}
+		return response(http.StatusNotFound, "MessageNotFound", "")
+	}
+	return response(http.StatusBadRequest, "UnsupportedHttpVerb", "")
+}
+
+func response(status int, code string, body string) (*http.Response, error) {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+	if code != "" {
+		resp.Header.Set("x-ms-error-code", code)
+	}
+	return resp, nil
+}
+
+func newFakeQueueClient(t *testing.T, transport *queueTransport, queue string) *azqueue.QueueClient {
+	client, err := azqueue.NewQueueClientWithNoCredential("https://account.queue.core.windows.net/"+queue, &azqueue.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: transport, Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestProcessorHandlesMessages(t *testing.T) {
+	transport := newQueueTransport("work")
+	transport.enqueue("work", "a", "b", "c", "fail", "d", "e")
+	client := newFakeQueueClient(t, transport, "work")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	handled := map[string]int{}
+	running, maxRunning := 0, 0
+	var handleErrors []*azqueue.ProcessorError
+	processor, err := azqueue.NewProcessor(client, func(ctx context.Context, message *azqueue.DequeuedMessage) error {
+		mu.Lock()
+		handled[*message.MessageText]++
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if *message.MessageText == "fail" {
+			return errors.New("handler failed")
+		}
+		return nil
+	}, &azqueue.ProcessorOptions{
+		Concurrency:     2,
+		MaxDequeueCount: 3,
+		MinPollInterval: time.Millisecond,
+		MaxPollInterval: 5 * time.Millisecond,
+		OnError: func(err *azqueue.ProcessorError) {
+			mu.Lock()
+			defer mu.Unlock()
+			handleErrors = append(handleErrors, err)
+		},
+	})
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() { done <- processor.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		return len(transport.messages("work")) == 0
+	}, 10*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	require.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1, "d": 1, "e": 1, "fail": 3}, handled)
+	require.LessOrEqual(t, maxRunning, 2)
+	require.Len(t, handleErrors, 3)
+	for _, err := range handleErrors {
+		require.Equal(t, azqueue.ProcessorOperationHandle, err.Operation)
+		require.Equal(t, "work-3", err.MessageID)
+		require.EqualError(t, errors.Unwrap(err), "handler failed")
+	}
+	require.Empty(t, transport.messages("work"))
+	require.Equal(t, []string{"fail"}, transport.messages("work"+azqueue.PoisonQueueSuffix))
+
+	require.EqualError(t, processor.Run(context.Background()), "the Processor has already been run")
+}
+
+func TestProcessorExtendsVisibility(t *testing.T) {
+	transport := newQueueTransport("work")
+	transport.enqueue("work", "slow")
+	client := newFakeQueueClient(t, transport, "work")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls atomic.Int32
+	processor, err := azqueue.NewProcessor(client, func(ctx context.Context, message *azqueue.DequeuedMessage) error {
+		calls.Add(1)
+		time.Sleep(2500 * time.Millisecond)
+		cancel()
+		return nil
+	}, &azqueue.ProcessorOptions{
+		VisibilityTimeout: time.Second,
+		MinPollInterval:   time.Millisecond,
+		MaxPollInterval:   time.Millisecond,
+		OnError: func(err *azqueue.ProcessorError) {
+			t.Errorf("unexpected error: %v", err)
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, processor.Run(ctx))
+
+	// the message would have been dequeued again after a second if its visibility wasn't extended
+	require.Equal(t, int32(1), calls.Load())
+	require.GreaterOrEqual(t, transport.updates, 4)
+	require.Empty(t, transport.messages("work"))
+}
+
+func TestProcessorShutdown(t *testing.T) {
+	transport := newQueueTransport("work")
+	transport.enqueue("work", "graceful", "stuck")
+	client := newFakeQueueClient(t, transport, "work")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{}, 2)
+	processor, err := azqueue.NewProcessor(client, func(ctx context.Context, message *azqueue.DequeuedMessage) error {
+		started <- struct{}{}
+		if *message.MessageText == "graceful" {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}, &azqueue.ProcessorOptions{ShutdownTimeout: 200 * time.Millisecond, RetryDelay: time.Hour})
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() { done <- processor.Run(ctx) }()
+	<-started
+	<-started
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the processor didn't stop")
+	}
+
+	// the graceful handler completed, and the stuck handler was canceled after the shutdown timeout
+	require.Equal(t, []string{"stuck"}, transport.messages("work"))
+}
+
+func TestProcessorOptionsValidation(t *testing.T) {
+	client := newFakeQueueClient(t, newQueueTransport("work"), "work")
+	handler := func(context.Context, *azqueue.DequeuedMessage) error { return nil }
+
+	_, err := azqueue.NewProcessor(client, nil, nil)
+	require.Error(t, err)
+	for _, options := range []azqueue.ProcessorOptions{
+		{Concurrency: -1},
+		{BatchSize: 33},
+		{VisibilityTimeout: time.Millisecond},
+		{MaxDequeueCount: -1},
+		{RetryDelay: -time.Second},
+		{MinPollInterval: time.Second, MaxPollInterval: time.Millisecond},
+	} {
+		_, err := azqueue.NewProcessor(client, handler, &options)
+		require.Error(t, err, "%+v", options)
+	}
+	_, err = azqueue.NewProcessor(client, handler, nil)
+	require.NoError(t, err)
+
+	// the name of the default poison queue would be longer than 63 characters
+	longName := strings.Repeat("q", 57)
+	longClient := newFakeQueueClient(t, newQueueTransport(longName), longName)
+	_, err = azqueue.NewProcessor(longClient, handler, nil)
+	require.ErrorContains(t, err, "set PoisonQueue")
+	_, err = azqueue.NewProcessor(longClient, handler, &azqueue.ProcessorOptions{PoisonQueue: client})
+	require.NoError(t, err)
+	_, err = azqueue.NewProcessor(newFakeQueueClient(t, newQueueTransport(longName[:56]), longName[:56]), handler, nil)
+	require.NoError(t, err)
+}
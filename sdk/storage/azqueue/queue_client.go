@@ -8,6 +8,7 @@ package azqueue
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -45,6 +46,14 @@ func (q *QueueClient) sharedKey() *SharedKeyCredential {
 	return base.SharedKeyComposite((*base.CompositeClient[generated.QueueClient, generated.MessagesClient])(q))
 }
 
+func (q *QueueClient) options() *ClientOptions {
+	return base.OptionsComposite((*base.CompositeClient[generated.QueueClient, generated.MessagesClient])(q))
+}
+
+func (q *QueueClient) payloads() *sync.Map {
+	return base.Payloads((*base.CompositeClient[generated.QueueClient, generated.MessagesClient])(q))
+}
+
 // URL returns the URL endpoint used by the ServiceClient object.
 func (q *QueueClient) URL() string {
 	return q.queueClient().Endpoint()
@@ -59,7 +68,7 @@ func NewQueueClient(queueURL string, cred azcore.TokenCredential, options *Clien
 	conOptions := shared.GetClientOptions(options)
 	authPolicy := shared.NewStorageChallengePolicy(cred, audience, conOptions.InsecureAllowCredentialWithHTTP)
 	pl := runtime.NewPipeline(exported.ModuleName, exported.ModuleVersion, runtime.PipelineOptions{PerRetry: []policy.Policy{authPolicy}}, &conOptions.ClientOptions)
-	return (*QueueClient)(base.NewQueueClient(queueURL, pl, nil)), nil
+	return (*QueueClient)(base.NewQueueClient(queueURL, pl, nil, conOptions)), nil
 }
 
 // NewQueueClientWithNoCredential creates an instance of QueueClient with the specified values.
@@ -70,7 +79,7 @@ func NewQueueClientWithNoCredential(queueURL string, options *ClientOptions) (*Q
 	conOptions := shared.GetClientOptions(options)
 	pl := runtime.NewPipeline(exported.ModuleName, exported.ModuleVersion, runtime.PipelineOptions{}, &conOptions.ClientOptions)
 
-	return (*QueueClient)(base.NewQueueClient(queueURL, pl, nil)), nil
+	return (*QueueClient)(base.NewQueueClient(queueURL, pl, nil, conOptions)), nil
 }
 
 // NewQueueClientWithSharedKeyCredential creates an instance of ServiceClient with the specified values.
@@ -83,7 +92,7 @@ func NewQueueClientWithSharedKeyCredential(queueURL string, cred *SharedKeyCrede
 	conOptions.PerRetryPolicies = append(conOptions.PerRetryPolicies, authPolicy)
 	pl := runtime.NewPipeline(exported.ModuleName, exported.ModuleVersion, runtime.PipelineOptions{}, &conOptions.ClientOptions)
 
-	return (*QueueClient)(base.NewQueueClient(queueURL, pl, cred)), nil
+	return (*QueueClient)(base.NewQueueClient(queueURL, pl, cred, conOptions)), nil
 }
 
 // NewQueueClientFromConnectionString creates an instance of ServiceClient with the specified values.
@@ -161,63 +170,112 @@ func (q *QueueClient) SetAccessPolicy(ctx context.Context, o *SetAccessPolicyOpt
 }
 
 // EnqueueMessage adds a message to the queue.
+// The content is encoded with ClientOptions.MessageEncoding, and stored in a blob if it's too large and
+// ClientOptions.ClaimCheck is specified.
 // For more information, see https://learn.microsoft.com/en-us/rest/api/storageservices/put-message.
 func (q *QueueClient) EnqueueMessage(ctx context.Context, content string, o *EnqueueMessageOptions) (EnqueueMessagesResponse, error) {
 	opts := o.format()
-	message := generated.QueueMessage{MessageText: &content}
+	text, blob, err := q.encodeMessage(ctx, content)
+	if err != nil {
+		return EnqueueMessagesResponse{}, err
+	}
+	message := generated.QueueMessage{MessageText: &text}
 	resp, err := q.messagesClient().Enqueue(ctx, message, opts)
+	if err != nil && blob != "" {
+		_ = q.deletePayload(ctx, blob)
+	}
 	return resp, err
 }
 
 // DequeueMessage removes one message from the queue.
+// The text of the message is decoded as described in DequeueMessages.
 // For more information, see https://learn.microsoft.com/en-us/rest/api/storageservices/get-messages.
 func (q *QueueClient) DequeueMessage(ctx context.Context, o *DequeueMessageOptions) (DequeueMessagesResponse, error) {
 	opts := o.format()
 	resp, err := q.messagesClient().Dequeue(ctx, opts)
-	return resp, err
+	if err != nil {
+		return resp, err
+	}
+	return resp, q.decodeDequeuedMessages(ctx, resp.Messages)
 }
 
 // UpdateMessage updates a message from the queue with the given popReceipt.
+// The content is encoded as described in EnqueueMessage. If the previous content of the message was stored in a
+// blob when it was dequeued with this client, and the message hasn't become visible since, the blob is deleted.
 // For more information, see https://learn.microsoft.com/en-us/rest/api/storageservices/update-message.
 func (q *QueueClient) UpdateMessage(ctx context.Context, messageID string, popReceipt string, content string, o *UpdateMessageOptions) (UpdateMessageResponse, error) {
+	text, blob, err := q.encodeMessage(ctx, content)
+	if err != nil {
+		return UpdateMessageResponse{}, err
+	}
+	resp, err := q.updateMessage(ctx, messageID, popReceipt, text, o)
+	if err != nil {
+		if blob != "" {
+			_ = q.deletePayload(ctx, blob)
+		}
+		return resp, err
+	}
+	return resp, q.replacePayload(ctx, messageID, blob, text, timeOrZero(resp.TimeNextVisible))
+}
+
+func (q *QueueClient) updateMessage(ctx context.Context, messageID string, popReceipt string, text string, o *UpdateMessageOptions) (UpdateMessageResponse, error) {
 	opts := o.format()
-	message := generated.QueueMessage{MessageText: &content}
+	message := generated.QueueMessage{MessageText: &text}
 	messageClient := generated.NewMessageIDClient(q.getMessageIDURL(messageID), q.queueClient().Pipeline())
 	resp, err := messageClient.Update(ctx, popReceipt, message, opts)
 	return resp, err
 }
 
 // DeleteMessage deletes message from queue with the given popReceipt.
+// If the content of the message was stored in a blob when it was dequeued with this client, and the message hasn't
+// become visible since, the blob is deleted.
 // For more information, see https://learn.microsoft.com/en-us/rest/api/storageservices/delete-message2.
 func (q *QueueClient) DeleteMessage(ctx context.Context, messageID string, popReceipt string, o *DeleteMessageOptions) (DeleteMessageResponse, error) {
 	opts := o.format()
 	messageClient := generated.NewMessageIDClient(q.getMessageIDURL(messageID), q.queueClient().Pipeline())
 	resp, err := messageClient.Delete(ctx, popReceipt, opts)
-	return resp, err
+	if err != nil {
+		return resp, err
+	}
+	return resp, q.replacePayload(ctx, messageID, "", "", time.Time{})
 }
 
 // PeekMessage peeks the first message from the queue.
+// The text of the message is decoded as described in DequeueMessages.
 // For more information, see https://learn.microsoft.com/en-us/rest/api/storageservices/peek-messages.
 func (q *QueueClient) PeekMessage(ctx context.Context, o *PeekMessageOptions) (PeekMessagesResponse, error) {
 	opts := o.format()
 	resp, err := q.messagesClient().Peek(ctx, opts)
-	return resp, err
+	if err != nil {
+		return resp, err
+	}
+	return resp, q.decodePeekedMessages(ctx, resp.Messages)
 }
 
 // DequeueMessages removes one or more messages from the queue.
+// The text of the messages is decoded with ClientOptions.MessageEncoding, and the content of the messages that
+// reference blobs is read from the blobs if ClientOptions.ClaimCheck is specified. If some messages can't be decoded,
+// their text is left unchanged, and an error is returned along with the response.
 // For more information, see https://learn.microsoft.com/en-us/rest/api/storageservices/get-messages.
 func (q *QueueClient) DequeueMessages(ctx context.Context, o *DequeueMessagesOptions) (DequeueMessagesResponse, error) {
 	opts := o.format()
 	resp, err := q.messagesClient().Dequeue(ctx, opts)
-	return resp, err
+	if err != nil {
+		return resp, err
+	}
+	return resp, q.decodeDequeuedMessages(ctx, resp.Messages)
 }
 
 // PeekMessages peeks one or more messages from the queue
+// The text of the messages is decoded as described in DequeueMessages.
 // For more information, see https://learn.microsoft.com/en-us/rest/api/storageservices/peek-messages.
 func (q *QueueClient) PeekMessages(ctx context.Context, o *PeekMessagesOptions) (PeekMessagesResponse, error) {
 	opts := o.format()
 	resp, err := q.messagesClient().Peek(ctx, opts)
-	return resp, err
+	if err != nil {
+		return resp, err
+	}
+	return resp, q.decodePeekedMessages(ctx, resp.Messages)
 }
 
 // ClearMessages deletes all messages from the queue.
@@ -39,7 +39,7 @@ func NewServiceClient(serviceURL string, cred azcore.TokenCredential, options *C
 	conOptions.PerRetryPolicies = append(conOptions.PerRetryPolicies, authPolicy)
 	pl := runtime.NewPipeline(exported.ModuleName, exported.ModuleVersion, runtime.PipelineOptions{}, &conOptions.ClientOptions)
 
-	return (*ServiceClient)(base.NewServiceClient(serviceURL, pl, nil)), nil
+	return (*ServiceClient)(base.NewServiceClient(serviceURL, pl, nil, conOptions)), nil
 }
 
 // NewServiceClientWithNoCredential creates an instance of ServiceClient with the specified values.
@@ -50,7 +50,7 @@ func NewServiceClientWithNoCredential(serviceURL string, options *ClientOptions)
 	conOptions := shared.GetClientOptions(options)
 	pl := runtime.NewPipeline(exported.ModuleName, exported.ModuleVersion, runtime.PipelineOptions{}, &conOptions.ClientOptions)
 
-	return (*ServiceClient)(base.NewServiceClient(serviceURL, pl, nil)), nil
+	return (*ServiceClient)(base.NewServiceClient(serviceURL, pl, nil, conOptions)), nil
 }
 
 // NewServiceClientWithSharedKeyCredential creates an instance of ServiceClient with the specified values.
@@ -63,7 +63,7 @@ func NewServiceClientWithSharedKeyCredential(serviceURL string, cred *SharedKeyC
 	conOptions.PerRetryPolicies = append(conOptions.PerRetryPolicies, authPolicy)
 	pl := runtime.NewPipeline(exported.ModuleName, exported.ModuleVersion, runtime.PipelineOptions{}, &conOptions.ClientOptions)
 
-	return (*ServiceClient)(base.NewServiceClient(serviceURL, pl, cred)), nil
+	return (*ServiceClient)(base.NewServiceClient(serviceURL, pl, cred, conOptions)), nil
 }
 
 // NewServiceClientFromConnectionString creates an instance of ServiceClient with the specified values.
@@ -94,6 +94,10 @@ func (s *ServiceClient) sharedKey() *SharedKeyCredential {
 	return base.SharedKey((*base.Client[generated.ServiceClient])(s))
 }
 
+func (s *ServiceClient) options() *ClientOptions {
+	return base.Options((*base.Client[generated.ServiceClient])(s))
+}
+
 // URL returns the URL endpoint used by the ServiceClient object.
 func (s *ServiceClient) URL() string {
 	return s.generated().Endpoint()
@@ -169,7 +173,7 @@ func (s *ServiceClient) NewListQueuesPager(o *ListQueuesOptions) *runtime.Pager[
 func (s *ServiceClient) NewQueueClient(queueName string) *QueueClient {
 	queueName = url.PathEscape(queueName)
 	queueURL := runtime.JoinPaths(s.URL(), queueName)
-	return (*QueueClient)(base.NewQueueClient(queueURL, s.generated().Pipeline(), s.sharedKey()))
+	return (*QueueClient)(base.NewQueueClient(queueURL, s.generated().Pipeline(), s.sharedKey(), s.options()))
 }
 
 // CreateQueue creates a new queue within a storage account. If a queue with the same name already exists, the operation fails.
@@ -177,7 +181,7 @@ func (s *ServiceClient) NewQueueClient(queueName string) *QueueClient {
 func (s *ServiceClient) CreateQueue(ctx context.Context, queueName string, options *CreateOptions) (CreateResponse, error) {
 	queueName = url.PathEscape(queueName)
 	queueURL := runtime.JoinPaths(s.URL(), queueName)
-	qC := (*QueueClient)(base.NewQueueClient(queueURL, s.generated().Pipeline(), s.sharedKey()))
+	qC := (*QueueClient)(base.NewQueueClient(queueURL, s.generated().Pipeline(), s.sharedKey(), s.options()))
 	return qC.Create(ctx, options)
 }
 
@@ -186,7 +190,7 @@ func (s *ServiceClient) CreateQueue(ctx context.Context, queueName string, optio
 func (s *ServiceClient) DeleteQueue(ctx context.Context, queueName string, options *DeleteOptions) (DeleteResponse, error) {
 	queueName = url.PathEscape(queueName)
 	queueURL := runtime.JoinPaths(s.URL(), queueName)
-	qC := (*QueueClient)(base.NewQueueClient(queueURL, s.generated().Pipeline(), s.sharedKey()))
+	qC := (*QueueClient)(base.NewQueueClient(queueURL, s.generated().Pipeline(), s.sharedKey(), s.options()))
 	return qC.Delete(ctx, options)
 }
 